@@ -4,7 +4,9 @@ import (
 	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"sort"
 	"strings"
@@ -19,6 +21,7 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// MAX_BODY_SIZE is the default maximum size of a request body.
 const MAX_BODY_SIZE = 5 * 1024 * 1024
 
 type queryRequest struct {
@@ -39,6 +42,7 @@ type httpServer struct {
 	signerKey        *ecdsa.PrivateKey
 	pendingResponses *PendingResponses
 	loggingMap       *LoggingMap
+	maxBodySize      int64
 }
 
 func (s *httpServer) handleQuery(w http.ResponseWriter, r *http.Request) {
@@ -57,11 +61,26 @@ func (s *httpServer) handleQuery(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 	allQueryRequestsReceived.Inc()
 
-	// Decode the body first. This is because the library seems to hang if we receive a large body and return without decoding it.
-	// This could be a slight waste of resources, but should not be a DoS risk because we cap the max body size.
+	// Read the body first. This is because the library seems to hang if we receive a large body and return without reading it.
+	// This could be a slight waste of resources, but should not be a DoS risk because we cap the max body size. Oversized
+	// bodies are rejected before we attempt to deserialize them.
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.logger.Error("request body too large", zap.Int64("maxBodySize", s.maxBodySize))
+			http.Error(w, fmt.Sprintf("request body too large, the limit is %d bytes", s.maxBodySize), http.StatusRequestEntityTooLarge)
+			invalidQueryRequestReceived.WithLabelValues("body_too_large").Inc()
+			return
+		}
+		s.logger.Error("failed to read body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		invalidQueryRequestReceived.WithLabelValues("failed_to_read_body").Inc()
+		return
+	}
 
 	var q queryRequest
-	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE)).Decode(&q)
+	err = json.Unmarshal(body, &q)
 	if err != nil {
 		s.logger.Error("failed to decode body", zap.Error(err))
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -216,7 +235,7 @@ func (s *httpServer) handleQuery(w http.ResponseWriter, r *http.Request) {
 	s.pendingResponses.Remove(pendingResponse)
 }
 
-func NewHTTPServer(addr string, t *pubsub.Topic, permissions *Permissions, signerKey *ecdsa.PrivateKey, p *PendingResponses, logger *zap.Logger, env common.Environment, loggingMap *LoggingMap) *http.Server {
+func NewHTTPServer(addr string, t *pubsub.Topic, permissions *Permissions, signerKey *ecdsa.PrivateKey, p *PendingResponses, logger *zap.Logger, env common.Environment, loggingMap *LoggingMap, maxBodySize int64) *http.Server {
 	s := &httpServer{
 		topic:            t,
 		permissions:      permissions,
@@ -225,6 +244,7 @@ func NewHTTPServer(addr string, t *pubsub.Topic, permissions *Permissions, signe
 		logger:           logger,
 		env:              env,
 		loggingMap:       loggingMap,
+		maxBodySize:      maxBodySize,
 	}
 	r := mux.NewRouter()
 	r.HandleFunc("/v1/query", s.handleQuery).Methods("PUT", "POST", "OPTIONS")
@@ -0,0 +1,46 @@
+package ccq
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestHandleQueryRejectsOversizedBody(t *testing.T) {
+	s := &httpServer{
+		logger:      zap.NewNop(),
+		env:         common.GoTest,
+		maxBodySize: 100,
+	}
+
+	body := `{"bytes":"` + strings.Repeat("00", 100) + `","signature":""}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	s.handleQuery(rr, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Equal(t, "request body too large, the limit is 100 bytes\n", rr.Body.String())
+}
+
+func TestHandleQueryAcceptsBodyWithinLimit(t *testing.T) {
+	s := &httpServer{
+		logger:      zap.NewNop(),
+		env:         common.GoTest,
+		maxBodySize: 100,
+	}
+
+	// The body is within the limit, so the request should get as far as the API key check.
+	body := `{"bytes":"00","signature":"00"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	s.handleQuery(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "api key is missing\n", rr.Body.String())
+}
@@ -172,139 +172,147 @@ func runP2P(
 			switch m := msg.Message.(type) {
 			case *gossipv1.GossipMessage_SignedQueryResponse:
 				logger.Debug("query response received", zap.Any("response", m.SignedQueryResponse))
-				peerId := envelope.GetFrom().String()
-				queryResponsesReceived.WithLabelValues(peerId).Inc()
-				var queryResponse query.QueryResponsePublication
-				err := queryResponse.Unmarshal(m.SignedQueryResponse.QueryResponse)
+				signedResponses, err := query.UnbatchResponses(m.SignedQueryResponse)
 				if err != nil {
-					logger.Error("failed to unmarshal response", zap.Error(err))
-					inboundP2pError.WithLabelValues("failed_to_unmarshal_response").Inc()
+					logger.Error("failed to unbatch responses", zap.Error(err))
+					inboundP2pError.WithLabelValues("failed_to_unbatch_responses").Inc()
 					continue
 				}
-				for _, pcr := range queryResponse.PerChainResponses {
-					queryResponsesReceivedByChainAndPeerID.WithLabelValues(pcr.ChainId.String(), peerId).Inc()
-				}
-				requestSignature := hex.EncodeToString(queryResponse.Request.Signature)
-				logger.Info("query response received from gossip", zap.String("peerId", peerId), zap.Any("requestId", requestSignature))
-				if loggingMap.ShouldLogResponse(requestSignature) {
-					var queryRequest query.QueryRequest
-					if err := queryRequest.Unmarshal(queryResponse.Request.QueryRequest); err == nil {
-						logger.Info("logging response", zap.String("peerId", peerId), zap.Any("requestId", requestSignature), zap.Any("request", queryRequest), zap.Any("response", queryResponse))
-					} else {
-						logger.Error("logging response (failed to unmarshal request)", zap.String("peerId", peerId), zap.Any("requestId", requestSignature), zap.Any("response", queryResponse))
+				peerId := envelope.GetFrom().String()
+				for _, signedResponse := range signedResponses {
+					queryResponsesReceived.WithLabelValues(peerId).Inc()
+					var queryResponse query.QueryResponsePublication
+					err := queryResponse.Unmarshal(signedResponse.QueryResponse)
+					if err != nil {
+						logger.Error("failed to unmarshal response", zap.Error(err))
+						inboundP2pError.WithLabelValues("failed_to_unmarshal_response").Inc()
+						continue
 					}
-				}
-				// Check that we're handling the request for this response
-				pendingResponse := pendingResponses.Get(requestSignature)
-				if pendingResponse == nil {
-					// This will happen for responses that come in after quorum is reached.
-					logger.Debug("skipping query response for unknown request", zap.String("signature", requestSignature))
-					continue
-				}
-				// Make sure that the request bytes match
-				if !bytes.Equal(queryResponse.Request.QueryRequest, pendingResponse.req.QueryRequest) ||
-					!bytes.Equal(queryResponse.Request.Signature, pendingResponse.req.Signature) {
-					continue
-				}
-				digest := query.GetQueryResponseDigestFromBytes(m.SignedQueryResponse.QueryResponse)
-				signerBytes, err := ethCrypto.Ecrecover(digest.Bytes(), m.SignedQueryResponse.Signature)
-				if err != nil {
-					logger.Error("failed to verify signature on response",
-						zap.String("digest", digest.Hex()),
-						zap.String("signature", hex.EncodeToString(m.SignedQueryResponse.Signature)),
-						zap.Error(err))
-					inboundP2pError.WithLabelValues("failed_to_verify_signature").Inc()
-					continue
-				}
-				signerAddress := ethCommon.BytesToAddress(ethCrypto.Keccak256(signerBytes[1:])[12:])
-				keyIdx, hasKeyIdx := guardianSet.KeyIndex(signerAddress)
-
-				if hasKeyIdx {
-					if _, ok := responses[requestSignature]; !ok {
-						responses[requestSignature] = make(map[ethCommon.Hash][]GuardianSignature)
+					for _, pcr := range queryResponse.PerChainResponses {
+						queryResponsesReceivedByChainAndPeerID.WithLabelValues(pcr.ChainId.String(), peerId).Inc()
 					}
-					found := false
-					for _, gs := range responses[requestSignature][digest] {
-						if gs.Index == keyIdx {
-							found = true
-							break
+					requestSignature := hex.EncodeToString(queryResponse.Request.Signature)
+					logger.Info("query response received from gossip", zap.String("peerId", peerId), zap.Any("requestId", requestSignature))
+					if loggingMap.ShouldLogResponse(requestSignature) {
+						var queryRequest query.QueryRequest
+						if err := queryRequest.Unmarshal(queryResponse.Request.QueryRequest); err == nil {
+							logger.Info("logging response", zap.String("peerId", peerId), zap.Any("requestId", requestSignature), zap.Any("request", queryRequest), zap.Any("response", queryResponse))
+						} else {
+							logger.Error("logging response (failed to unmarshal request)", zap.String("peerId", peerId), zap.Any("requestId", requestSignature), zap.Any("response", queryResponse))
 						}
 					}
-					if found {
-						// Already handled the response from this guardian
+					// Check that we're handling the request for this response
+					pendingResponse := pendingResponses.Get(requestSignature)
+					if pendingResponse == nil {
+						// This will happen for responses that come in after quorum is reached.
+						logger.Debug("skipping query response for unknown request", zap.String("signature", requestSignature))
 						continue
 					}
-					responses[requestSignature][digest] = append(responses[requestSignature][digest], GuardianSignature{
-						Index:     keyIdx,
-						Signature: hex.EncodeToString(m.SignedQueryResponse.Signature),
-					})
-					// quorum is reached when a super-majority of guardians have signed a response with the same digest
-					numSigners := len(responses[requestSignature][digest])
-					if numSigners >= quorum {
-						s := &SignedResponse{
-							Response:   &queryResponse,
-							Signatures: responses[requestSignature][digest],
-						}
-						delete(responses, requestSignature)
-						select {
-						case pendingResponse.ch <- s:
-							logger.Info("quorum reached, forwarded query response",
-								zap.String("peerId", peerId),
-								zap.String("userId", pendingResponse.userName),
-								zap.Any("requestId", requestSignature),
-								zap.Int("numSigners", numSigners),
-								zap.Int("quorum", quorum),
-							)
-						default:
-							logger.Error("failed to write query response to channel, dropping it", zap.String("peerId", peerId), zap.Any("requestId", requestSignature))
-							// Leave the request in the pending map. It will get cleaned up if it times out.
+					// Make sure that the request bytes match
+					if !bytes.Equal(queryResponse.Request.QueryRequest, pendingResponse.req.QueryRequest) ||
+						!bytes.Equal(queryResponse.Request.Signature, pendingResponse.req.Signature) {
+						continue
+					}
+					digest := query.GetQueryResponseDigestFromBytes(signedResponse.QueryResponse)
+					signerBytes, err := ethCrypto.Ecrecover(digest.Bytes(), signedResponse.Signature)
+					if err != nil {
+						logger.Error("failed to verify signature on response",
+							zap.String("digest", digest.Hex()),
+							zap.String("signature", hex.EncodeToString(signedResponse.Signature)),
+							zap.Error(err))
+						inboundP2pError.WithLabelValues("failed_to_verify_signature").Inc()
+						continue
+					}
+					signerAddress := ethCommon.BytesToAddress(ethCrypto.Keccak256(signerBytes[1:])[12:])
+					keyIdx, hasKeyIdx := guardianSet.KeyIndex(signerAddress)
+
+					if hasKeyIdx {
+						if _, ok := responses[requestSignature]; !ok {
+							responses[requestSignature] = make(map[ethCommon.Hash][]GuardianSignature)
 						}
-					} else {
-						// Proxy should return early if quorum is no longer possible - i.e maxMatchingResponses + outstandingResponses < quorum
-						var totalSigners, maxMatchingResponses int
-						for _, signers := range responses[requestSignature] {
-							totalSigners += len(signers)
-							if len(signers) > maxMatchingResponses {
-								maxMatchingResponses = len(signers)
+						found := false
+						for _, gs := range responses[requestSignature][digest] {
+							if gs.Index == keyIdx {
+								found = true
+								break
 							}
 						}
-						outstandingResponses := len(guardianSet.Keys) - totalSigners
-						if maxMatchingResponses+outstandingResponses < quorum {
-							quorumNotMetByUser.WithLabelValues(pendingResponse.userName).Inc()
-							failedQueriesByUser.WithLabelValues(pendingResponse.userName).Inc()
+						if found {
+							// Already handled the response from this guardian
+							continue
+						}
+						responses[requestSignature][digest] = append(responses[requestSignature][digest], GuardianSignature{
+							Index:     keyIdx,
+							Signature: hex.EncodeToString(signedResponse.Signature),
+						})
+						// quorum is reached when a super-majority of guardians have signed a response with the same digest
+						numSigners := len(responses[requestSignature][digest])
+						if numSigners >= quorum {
+							s := &SignedResponse{
+								Response:   &queryResponse,
+								Signatures: responses[requestSignature][digest],
+							}
 							delete(responses, requestSignature)
 							select {
-							case pendingResponse.errCh <- &ErrorEntry{err: fmt.Errorf("quorum not met"), status: http.StatusBadRequest}:
-								logger.Info("query failed, quorum not met",
+							case pendingResponse.ch <- s:
+								logger.Info("quorum reached, forwarded query response",
 									zap.String("peerId", peerId),
 									zap.String("userId", pendingResponse.userName),
 									zap.Any("requestId", requestSignature),
 									zap.Int("numSigners", numSigners),
-									zap.Int("maxMatchingResponses", maxMatchingResponses),
-									zap.Int("outstandingResponses", outstandingResponses),
 									zap.Int("quorum", quorum),
 								)
 							default:
-								logger.Error("failed to write query error response to channel, dropping it", zap.String("peerId", peerId), zap.Any("requestId", requestSignature))
+								logger.Error("failed to write query response to channel, dropping it", zap.String("peerId", peerId), zap.Any("requestId", requestSignature))
 								// Leave the request in the pending map. It will get cleaned up if it times out.
 							}
 						} else {
-							logger.Info("waiting for more query responses",
-								zap.String("peerId", peerId),
-								zap.String("userId", pendingResponse.userName),
-								zap.Any("requestId", requestSignature),
-								zap.Int("numSigners", numSigners),
-								zap.Int("maxMatchingResponses", maxMatchingResponses),
-								zap.Int("outstandingResponses", outstandingResponses),
-								zap.Int("quorum", quorum),
-							)
+							// Proxy should return early if quorum is no longer possible - i.e maxMatchingResponses + outstandingResponses < quorum
+							var totalSigners, maxMatchingResponses int
+							for _, signers := range responses[requestSignature] {
+								totalSigners += len(signers)
+								if len(signers) > maxMatchingResponses {
+									maxMatchingResponses = len(signers)
+								}
+							}
+							outstandingResponses := len(guardianSet.Keys) - totalSigners
+							if maxMatchingResponses+outstandingResponses < quorum {
+								quorumNotMetByUser.WithLabelValues(pendingResponse.userName).Inc()
+								failedQueriesByUser.WithLabelValues(pendingResponse.userName).Inc()
+								delete(responses, requestSignature)
+								select {
+								case pendingResponse.errCh <- &ErrorEntry{err: fmt.Errorf("quorum not met"), status: http.StatusBadRequest}:
+									logger.Info("query failed, quorum not met",
+										zap.String("peerId", peerId),
+										zap.String("userId", pendingResponse.userName),
+										zap.Any("requestId", requestSignature),
+										zap.Int("numSigners", numSigners),
+										zap.Int("maxMatchingResponses", maxMatchingResponses),
+										zap.Int("outstandingResponses", outstandingResponses),
+										zap.Int("quorum", quorum),
+									)
+								default:
+									logger.Error("failed to write query error response to channel, dropping it", zap.String("peerId", peerId), zap.Any("requestId", requestSignature))
+									// Leave the request in the pending map. It will get cleaned up if it times out.
+								}
+							} else {
+								logger.Info("waiting for more query responses",
+									zap.String("peerId", peerId),
+									zap.String("userId", pendingResponse.userName),
+									zap.Any("requestId", requestSignature),
+									zap.Int("numSigners", numSigners),
+									zap.Int("maxMatchingResponses", maxMatchingResponses),
+									zap.Int("outstandingResponses", outstandingResponses),
+									zap.Int("quorum", quorum),
+								)
+							}
 						}
+					} else {
+						logger.Warn("received observation by unknown guardian - is our guardian set outdated?",
+							zap.String("digest", digest.Hex()), zap.String("address", signerAddress.Hex()),
+						)
+						inboundP2pError.WithLabelValues("unknown_guardian").Inc()
 					}
-				} else {
-					logger.Warn("received observation by unknown guardian - is our guardian set outdated?",
-						zap.String("digest", digest.Hex()), zap.String("address", signerAddress.Hex()),
-					)
-					inboundP2pError.WithLabelValues("unknown_guardian").Inc()
 				}
 			default:
 				// Since CCQ gossip is isolated, this really shouldn't happen.
@@ -34,11 +34,12 @@ type (
 	}
 
 	AllowedCall struct {
-		EthCall             *EthCall             `json:"ethCall"`
-		EthCallByTimestamp  *EthCallByTimestamp  `json:"ethCallByTimestamp"`
-		EthCallWithFinality *EthCallWithFinality `json:"ethCallWithFinality"`
-		SolanaAccount       *SolanaAccount       `json:"solAccount"`
-		SolanaPda           *SolanaPda           `json:"solPDA"`
+		EthCall                 *EthCall                 `json:"ethCall"`
+		EthCallByTimestamp      *EthCallByTimestamp      `json:"ethCallByTimestamp"`
+		EthCallWithFinality     *EthCallWithFinality     `json:"ethCallWithFinality"`
+		EthCallWithPrecondition *EthCallWithPrecondition `json:"ethCallWithPrecondition"`
+		SolanaAccount           *SolanaAccount           `json:"solAccount"`
+		SolanaPda               *SolanaPda               `json:"solPDA"`
 	}
 
 	EthCall struct {
@@ -59,6 +60,12 @@ type (
 		Call            string `json:"call"`
 	}
 
+	EthCallWithPrecondition struct {
+		Chain           int    `json:"chain"`
+		ContractAddress string `json:"contractAddress"`
+		Call            string `json:"call"`
+	}
+
 	SolanaAccount struct {
 		Chain   int    `json:"chain"`
 		Account string `json:"account"`
@@ -234,6 +241,11 @@ func parseConfig(byteValue []byte, allowAnything bool) (PermissionsMap, error) {
 				chain = ac.EthCallWithFinality.Chain
 				contractAddressStr = ac.EthCallWithFinality.ContractAddress
 				callStr = ac.EthCallWithFinality.Call
+			} else if ac.EthCallWithPrecondition != nil {
+				callType = "ethCallWithPrecondition"
+				chain = ac.EthCallWithPrecondition.Chain
+				contractAddressStr = ac.EthCallWithPrecondition.ContractAddress
+				callStr = ac.EthCallWithPrecondition.Call
 			} else if ac.SolanaAccount != nil {
 				// We assume the account is base58, but if it starts with "0x" it should be 32 bytes of hex.
 				account := ac.SolanaAccount.Account
@@ -275,7 +287,7 @@ func parseConfig(byteValue []byte, allowAnything bool) (PermissionsMap, error) {
 				}
 				callKey = fmt.Sprintf("solPDA:%d:%s", ac.SolanaPda.Chain, pa)
 			} else {
-				return nil, fmt.Errorf(`unsupported call type for user "%s", must be "ethCall", "ethCallByTimestamp", "ethCallWithFinality", "solAccount" or "solPDA"`, user.UserName)
+				return nil, fmt.Errorf(`unsupported call type for user "%s", must be "ethCall", "ethCallByTimestamp", "ethCallWithFinality", "ethCallWithPrecondition", "solAccount" or "solPDA"`, user.UserName)
 			}
 
 			if callKey == "" {
@@ -48,6 +48,7 @@ var (
 	monitorPeers           *bool
 	gossipAdvertiseAddress *string
 	allowAnything          *bool
+	maxBodySize            *int64
 )
 
 const DEV_NETWORK_ID = "/wormhole/dev"
@@ -71,6 +72,7 @@ func init() {
 	monitorPeers = QueryServerCmd.Flags().Bool("monitorPeers", false, "Should monitor bootstrap peers and attempt to reconnect")
 	gossipAdvertiseAddress = QueryServerCmd.Flags().String("gossipAdvertiseAddress", "", "External IP to advertize on P2P (use if behind a NAT or running in k8s)")
 	allowAnything = QueryServerCmd.Flags().Bool("allowAnything", false, `Should allow API keys with the "allowAnything" flag (only allowed in testnet and devnet)`)
+	maxBodySize = QueryServerCmd.Flags().Int64("maxBodySize", MAX_BODY_SIZE, "Maximum size in bytes of a query request body")
 
 	// The default health check monitoring is every five seconds, with a five second timeout, and you have to miss two, for 20 seconds total.
 	shutdownDelay1 = QueryServerCmd.Flags().Uint("shutdownDelay1", 25, "Seconds to delay after disabling health check on shutdown")
@@ -163,6 +165,9 @@ func runQueryServer(cmd *cobra.Command, args []string) {
 	if *ethContract == "" {
 		logger.Fatal("Please specify --ethContract")
 	}
+	if *maxBodySize <= 0 {
+		logger.Fatal("The --maxBodySize must be greater than zero")
+	}
 
 	if *allowAnything {
 		if env != common.TestNet && env != common.UnsafeDevNet {
@@ -207,7 +212,7 @@ func runQueryServer(cmd *cobra.Command, args []string) {
 
 	// Start the HTTP server
 	go func() {
-		s := NewHTTPServer(*listenAddr, p2p.topic_req, permissions, signerKey, pendingResponses, logger, env, loggingMap)
+		s := NewHTTPServer(*listenAddr, p2p.topic_req, permissions, signerKey, pendingResponses, logger, env, loggingMap, *maxBodySize)
 		logger.Sugar().Infof("Server listening on %s", *listenAddr)
 		err := s.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
@@ -110,6 +110,11 @@ func validateRequest(logger *zap.Logger, env common.Environment, perms *Permissi
 			status, err = validateCallData(logger, permsForUser, "ethCallByTimestamp", pcq.ChainId, q.CallData)
 		case *query.EthCallWithFinalityQueryRequest:
 			status, err = validateCallData(logger, permsForUser, "ethCallWithFinality", pcq.ChainId, q.CallData)
+		case *query.EthCallWithPreconditionQueryRequest:
+			status, err = validateCallData(logger, permsForUser, "ethCallWithPrecondition", pcq.ChainId, q.CallData)
+		case *query.EthCallByTimestampListQueryRequest:
+			// A timestamp list query is the same call at several timestamps, so it is covered by the by timestamp permissions.
+			status, err = validateCallData(logger, permsForUser, "ethCallByTimestamp", pcq.ChainId, q.CallData)
 		case *query.SolanaAccountQueryRequest:
 			status, err = validateSolanaAccountQuery(logger, permsForUser, "solAccount", pcq.ChainId, q)
 		case *query.SolanaPdaQueryRequest:
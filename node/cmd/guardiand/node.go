@@ -37,6 +37,7 @@ import (
 	"github.com/certusone/wormhole/node/pkg/devnet"
 	"github.com/certusone/wormhole/node/pkg/node"
 	"github.com/certusone/wormhole/node/pkg/p2p"
+	"github.com/certusone/wormhole/node/pkg/query"
 	"github.com/certusone/wormhole/node/pkg/supervisor"
 	promremotew "github.com/certusone/wormhole/node/pkg/telemetry/prom_remote_write"
 	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
@@ -231,12 +232,64 @@ var (
 
 	chainGovernorEnabled *bool
 
-	ccqEnabled           *bool
-	ccqAllowedRequesters *string
-	ccqP2pPort           *uint
-	ccqP2pBootstrap      *string
-	ccqAllowedPeers      *string
-	ccqBackfillCache     *bool
+	ccqEnabled            *bool
+	ccqAllowedRequesters  *string
+	ccqAllowedReqFile     *string
+	ccqP2pPort            *uint
+	ccqP2pBootstrap       *string
+	ccqAllowedPeers       *string
+	ccqBackfillCache      *bool
+	ccqCallConcurrency    *int
+	ccqLogsPageSizes      *string
+	ccqNatsURL            *string
+	ccqNatsSubject        *string
+	ccqLocalSinkPath      *string
+	ccqMonotonicNonce     *bool
+	ccqDisabledQueryTypes *string
+	ccqBandwidthQuota     *uint64
+	ccqBandwidthWindow    *time.Duration
+	ccqNearDupThreshold   *int
+	ccqNearDupWindow      *time.Duration
+	ccqRequireAllWatched  *bool
+	ccqDefaultRetries     *uint
+	ccqMaxRetries         *uint
+	ccqMaxTotalCalls      *int
+	ccqCoalesce           *bool
+	ccqCoalescePerChain   *bool
+	ccqMaxPerChainQueries *int
+	ccqMaxCallsPerQuery   *int
+	ccqMaxRequestSize     *int
+	ccqMaxCallDataSize    *string
+	ccqRequestTimeouts    *string
+	ccqTimeoutPerCall     *time.Duration
+	ccqMaxScaledTimeout   *time.Duration
+	ccqChainWeights       *string
+	ccqCachedResultMaxAge *time.Duration
+	ccqIncludeReceiveTime *bool
+	ccqCancelOnFatalError *bool
+	ccqSigningScheme      *string
+	ccqSigningKeyPath     *string
+	ccqSlaQueryTime       *time.Duration
+	ccqSignerLogLevels    *string
+	ccqEnforceEnvironment *bool
+	ccqMaxNonceSigners    *int
+	ccqNonceReplayWindow  *time.Duration
+	ccqNonceWrapWindow    *uint32
+	ccqReplayCacheSize    *int
+	ccqReplayCacheWindow  *time.Duration
+	ccqSystemAddresses    *string
+	ccqDetectIdentical    *bool
+	ccqDeferSyncing       *bool
+	ccqRejectDupCallData  *bool
+	ccqRetryIntervals     *bool
+	ccqPublishAttempts    *bool
+	ccqBatchWindow        *time.Duration
+	ccqGossipMetadata     *bool
+	ccqPeerAgreement      *time.Duration
+	ccqMaxResponseSize    *int
+	ccqLegacyDigest       *string
+	ccqLegacyDigestWindow *time.Duration
+	ccqRegistrationGrace  *time.Duration
 
 	gatewayRelayerContract      *string
 	gatewayRelayerKeyPath       *string
@@ -433,11 +486,63 @@ func init() {
 	chainGovernorEnabled = NodeCmd.Flags().Bool("chainGovernorEnabled", false, "Run the chain governor")
 
 	ccqEnabled = NodeCmd.Flags().Bool("ccqEnabled", false, "Enable cross chain query support")
-	ccqAllowedRequesters = NodeCmd.Flags().String("ccqAllowedRequesters", "", "Comma separated list of signers allowed to submit cross chain queries")
+	ccqAllowedRequesters = NodeCmd.Flags().String("ccqAllowedRequesters", "", "Comma separated list of signers allowed to submit cross chain queries, each optionally followed by a requests per second limit and the query types it may use, e.g. 0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe:10:types=1/11")
+	ccqAllowedReqFile = NodeCmd.Flags().String("ccqAllowedRequestersFile", "", "File containing the signers allowed to submit cross chain queries, in the same format as ccqAllowedRequesters but optionally one per line. It is reloaded on SIGHUP. May not be used with ccqAllowedRequesters (optional)")
 	ccqP2pPort = NodeCmd.Flags().Uint("ccqP2pPort", 8996, "CCQ P2P UDP listener port")
 	ccqP2pBootstrap = NodeCmd.Flags().String("ccqP2pBootstrap", "", "CCQ P2P bootstrap peers (optional for mainnet or testnet, overrides default, required for unsafeDevMode)")
 	ccqAllowedPeers = NodeCmd.Flags().String("ccqAllowedPeers", "", "CCQ allowed P2P peers (comma-separated)")
 	ccqBackfillCache = NodeCmd.Flags().Bool("ccqBackfillCache", true, "Should EVM chains backfill CCQ timestamp cache on startup")
+	ccqCallConcurrency = NodeCmd.Flags().Int("ccqCallConcurrency", 1, "Maximum number of sub-batches the calls of a CCQ query are split into and executed concurrently by EVM watchers")
+	ccqLogsPageSizes = NodeCmd.Flags().String("ccqLogsPageSizes", "", "Comma separated list of the maximum number of blocks read by a single eth_getLogs call of a CCQ eth_logs query, in the form chain:blocks, e.g. polygon:100. Longer ranges are read in pages, unlisted chains are not paginated (optional)")
+	ccqNatsURL = NodeCmd.Flags().String("ccqNatsURL", "", "NATS server URL to which CCQ responses are also published (optional)")
+	ccqNatsSubject = NodeCmd.Flags().String("ccqNatsSubject", "ccq.responses", "NATS subject to which CCQ responses are published")
+	ccqLocalSinkPath = NodeCmd.Flags().String("ccqLocalSinkPath", "", "File to which every CCQ response must also be written before it is considered published (optional)")
+	ccqMonotonicNonce = NodeCmd.Flags().Bool("ccqMonotonicNonce", false, "Reject CCQ requests unless the nonce is greater than the last one accepted from the same signer")
+	ccqDisabledQueryTypes = NodeCmd.Flags().String("ccqDisabledQueryTypes", "", "Comma separated list of CCQ query types to disable on specific chains, in the form chain:queryType, e.g. bsc:2 (optional)")
+	ccqBandwidthQuota = NodeCmd.Flags().Uint64("ccqBandwidthQuota", 0, "Maximum number of CCQ response bytes served to a single signer per ccqBandwidthWindow, zero means unlimited")
+	ccqBandwidthWindow = NodeCmd.Flags().Duration("ccqBandwidthWindow", time.Hour, "Window over which ccqBandwidthQuota is enforced")
+	ccqNearDupThreshold = NodeCmd.Flags().Int("ccqNearDuplicateThreshold", 0, "Maximum number of near duplicate CCQ requests (same chains, contracts and functions) accepted from a single signer per ccqNearDuplicateWindow, zero means unlimited")
+	ccqNearDupWindow = NodeCmd.Flags().Duration("ccqNearDuplicateWindow", time.Minute, "Window over which ccqNearDuplicateThreshold is enforced")
+	ccqRequireAllWatched = NodeCmd.Flags().Bool("ccqRequireAllWatched", false, "Reject the whole CCQ request up front if any of the chains in it do not have a watcher")
+	ccqDefaultRetries = NodeCmd.Flags().Uint("ccqDefaultRetries", 0, "Number of times each CCQ per chain query is retried if the request does not specify a retry budget, zero means retry until the request times out")
+	ccqMaxRetries = NodeCmd.Flags().Uint("ccqMaxRetries", 0, "Maximum number of times each CCQ per chain query is retried, including when the request specifies a retry budget, zero means unlimited")
+	ccqMaxTotalCalls = NodeCmd.Flags().Int("ccqMaxTotalCalls", 0, "Maximum number of calls allowed across all of the per chain queries in a single CCQ request, zero means unlimited")
+	ccqCoalesce = NodeCmd.Flags().Bool("ccqCoalesceIdenticalRequests", false, "Answer a CCQ request that is byte-identical to one from another signer that is already in flight with the same results, rather than querying the chains again")
+	ccqCoalescePerChain = NodeCmd.Flags().Bool("ccqCoalesceIdenticalPerChainQueries", false, "Answer a CCQ per chain query that is identical to one of another request that is already in flight with the same response, rather than querying the chain again")
+	ccqMaxPerChainQueries = NodeCmd.Flags().Int("ccqMaxPerChainQueries", 0, "Maximum number of per chain queries allowed in a single CCQ request, zero means unlimited")
+	ccqMaxCallsPerQuery = NodeCmd.Flags().Int("ccqMaxCallsPerQuery", 0, "Maximum number of calls allowed in a single per chain query of a CCQ request, zero means unlimited")
+	ccqMaxRequestSize = NodeCmd.Flags().Int("ccqMaxRequestSize", 0, "Maximum size in bytes of a serialized CCQ request, zero means unlimited")
+	ccqMaxCallDataSize = NodeCmd.Flags().String("ccqMaxCallDataSize", "", "Comma separated list of the maximum CCQ call data size in bytes in the form chain:bytes, e.g. polygon:4096. Unlisted chains have no limit (optional)")
+	ccqRequestTimeouts = NodeCmd.Flags().String("ccqRequestTimeouts", "", "Comma separated list of CCQ request timeouts for slow chains in the form chain:duration, e.g. ethereum:2m. Unlisted chains use the default timeout (optional)")
+	ccqTimeoutPerCall = NodeCmd.Flags().Duration("ccqRequestTimeoutPerCall", 0, "Amount by which the timeout of a CCQ per chain query is extended for each call after the first, zero disables scaling. Requires --ccqMaxScaledRequestTimeout")
+	ccqMaxScaledTimeout = NodeCmd.Flags().Duration("ccqMaxScaledRequestTimeout", 0, "Maximum timeout of a CCQ per chain query after it has been extended by --ccqRequestTimeoutPerCall")
+	ccqCachedResultMaxAge = NodeCmd.Flags().Duration("ccqCachedResultMaxAge", 0, "Maximum age of a cached CCQ result that may be served in place of a watcher failure to requests that allow it, zero disables result caching")
+	ccqIncludeReceiveTime = NodeCmd.Flags().Bool("ccqIncludeReceiveTime", false, "Include the time each CCQ request was received, and the time its response was assembled, in the CCQ response metadata")
+	ccqCancelOnFatalError = NodeCmd.Flags().Bool("ccqCancelOnFatalError", false, "Cancel the remaining per chain queries of a CCQ request as soon as one of them fails fatally")
+	ccqSigningScheme = NodeCmd.Flags().String("ccqResponseSigningScheme", query.ResponseSigningSchemeSecp256k1, "Signature scheme used to sign CCQ responses, either secp256k1 (using the guardian key) or ed25519. Note that the CCQ proxy only accepts secp256k1 responses")
+	ccqSigningKeyPath = NodeCmd.Flags().String("ccqResponseSigningKeyPath", "", "Path to the file containing the hex encoded key seed for the ed25519 CCQ response signing scheme")
+	ccqSlaQueryTime = NodeCmd.Flags().Duration("ccqSlaQueryTimeEstimate", 0, "Expected time for a watcher worker to answer a CCQ per chain query, used to fail requests with an SLA tier fast if the backlog means it cannot be met, zero disables the check")
+	ccqSignerLogLevels = NodeCmd.Flags().String("ccqSignerLogLevels", "", "Comma separated list of log level overrides for the processing of CCQ requests from specific signers in the form signer:level, e.g. 0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe:debug (optional)")
+	ccqEnforceEnvironment = NodeCmd.Flags().Bool("ccqEnforceRequestEnvironment", false, "Reject CCQ requests that declare a different environment than the one the guardian is running in, reporting the reason rather than treating them as coming from an unknown signer")
+	ccqMaxNonceSigners = NodeCmd.Flags().Int("ccqMaxNonceTrackedSigners", 0, "Maximum number of signers whose last nonce is tracked for ccqMonotonicNonce, zero means unlimited")
+	ccqNonceReplayWindow = NodeCmd.Flags().Duration("ccqNonceReplayWindow", time.Hour, "Minimum time a signer's last nonce is tracked before it may be evicted to make room for another signer, if ccqMaxNonceTrackedSigners is set")
+	ccqReplayCacheSize = NodeCmd.Flags().Int("ccqReplayCacheSize", query.DefaultReplayCacheSize, "Maximum number of recently accepted CCQ requests remembered to reject resubmissions of the same signed request, the oldest are evicted once it is full")
+	ccqReplayCacheWindow = NodeCmd.Flags().Duration("ccqReplayCacheWindow", query.DefaultReplayCacheWindow, "How long an accepted CCQ request is remembered to reject resubmissions of the same signed request")
+	ccqNonceWrapWindow = NodeCmd.Flags().Uint32("ccqNonceWrapWindow", 0, "If non-zero, lets nonces wrap around past the maximum uint32 for ccqMonotonicNonce, accepting a nonce that is at most this far ahead of the last one. At most 2^31 (optional)")
+	ccqSystemAddresses = NodeCmd.Flags().String("ccqSystemAddresses", "", "Comma separated list of addresses that CCQ calls may not target in the form chain:address, e.g. polygon:0x0000000000000000000000000000000000001010, where the address may be \"precompiles\" for the standard EVM precompiles (optional)")
+	ccqDetectIdentical = NodeCmd.Flags().Bool("ccqDetectIdenticalCalls", false, "Log and count CCQ calls that a request makes with the same target and call data on more than one chain")
+	ccqDeferSyncing = NodeCmd.Flags().Bool("ccqDeferWhileNodeSyncing", false, "Retry CCQ queries for chains whose node is syncing until the request times out, rather than rejecting them with NodeSyncing")
+	ccqRejectDupCallData = NodeCmd.Flags().Bool("ccqRejectDuplicateCallData", false, "Reject CCQ requests where a per chain query contains the same call, with the same target and call data, more than once")
+	ccqRetryIntervals = NodeCmd.Flags().Bool("ccqIncludeRetryIntervals", false, "Include the intervals actually waited before each timed retry of a per chain query in the CCQ response metadata")
+	ccqPublishAttempts = NodeCmd.Flags().Bool("ccqIncludePublishAttempts", false, "Include the number of attempts needed to publish each CCQ response to p2p in the response metadata")
+	ccqBatchWindow = NodeCmd.Flags().Duration("ccqResponseBatchWindow", 0, "Window within which CCQ responses are batched into a single p2p message, zero publishes each one individually. Only enable once consumers support batches (optional)")
+	ccqGossipMetadata = NodeCmd.Flags().Bool("ccqIncludeGossipMetadata", false, "Include the CCQ response topic and the number of peers on it in the CCQ response metadata, for network debugging")
+	ccqPeerAgreement = NodeCmd.Flags().Duration("ccqPeerAgreementWindow", 0, "If non-zero, read the CCQ responses published by other guardians, and include in the metadata of each response how many were seen publishing the same response within this window. Advisory only (optional)")
+	ccqMaxResponseSize = NodeCmd.Flags().Int("ccqMaxResponseSize", 0, "Maximum size in bytes of a serialized CCQ response, larger responses are rejected rather than published to p2p, zero means unlimited")
+	ccqLegacyDigest = NodeCmd.Flags().String("ccqLegacyRequestDigest", "", "Legacy CCQ request digest scheme that is also accepted during a migration, currently only without_domains (optional)")
+	ccqLegacyDigestWindow = NodeCmd.Flags().Duration("ccqLegacyRequestDigestWindow", 0, "How long after startup CCQ requests signed using ccqLegacyRequestDigest are accepted")
+	ccqRegistrationGrace = NodeCmd.Flags().Duration("ccqWatcherRegistrationGracePeriod", 0, "How long a CCQ request for a chain whose watcher has not started yet is held rather than rejected, zero rejects it straight away")
+	ccqChainWeights = NodeCmd.Flags().String("ccqChainWeights", "", "Comma separated list of CCQ scheduling weights in the form chain:weight, e.g. polygon:10. Queries for higher weight chains are dispatched first, unlisted chains have a weight of zero (optional)")
 	gossipAdvertiseAddress = NodeCmd.Flags().String("gossipAdvertiseAddress", "", "External IP to advertize on Guardian and CCQ p2p (use if behind a NAT or running in k8s)")
 
 	gatewayRelayerContract = NodeCmd.Flags().String("gatewayRelayerContract", "", "Address of the smart contract on wormchain to receive relayed VAAs")
@@ -1084,6 +1189,7 @@ func runNode(cmd *cobra.Command, args []string) {
 			Contract:               *ethContract,
 			GuardianSetUpdateChain: true,
 			CcqBackfillCache:       *ccqBackfillCache,
+			CcqCallConcurrency:     *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1091,11 +1197,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(bscRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "bsc",
-			ChainID:          vaa.ChainIDBSC,
-			Rpc:              *bscRPC,
-			Contract:         *bscContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "bsc",
+			ChainID:            vaa.ChainIDBSC,
+			Rpc:                *bscRPC,
+			Contract:           *bscContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1103,11 +1210,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(polygonRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "polygon",
-			ChainID:          vaa.ChainIDPolygon,
-			Rpc:              *polygonRPC,
-			Contract:         *polygonContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "polygon",
+			ChainID:            vaa.ChainIDPolygon,
+			Rpc:                *polygonRPC,
+			Contract:           *polygonContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1115,11 +1223,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(avalancheRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "avalanche",
-			ChainID:          vaa.ChainIDAvalanche,
-			Rpc:              *avalancheRPC,
-			Contract:         *avalancheContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "avalanche",
+			ChainID:            vaa.ChainIDAvalanche,
+			Rpc:                *avalancheRPC,
+			Contract:           *avalancheContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1127,11 +1236,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(oasisRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "oasis",
-			ChainID:          vaa.ChainIDOasis,
-			Rpc:              *oasisRPC,
-			Contract:         *oasisContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "oasis",
+			ChainID:            vaa.ChainIDOasis,
+			Rpc:                *oasisRPC,
+			Contract:           *oasisContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1139,11 +1249,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(fantomRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "fantom",
-			ChainID:          vaa.ChainIDFantom,
-			Rpc:              *fantomRPC,
-			Contract:         *fantomContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "fantom",
+			ChainID:            vaa.ChainIDFantom,
+			Rpc:                *fantomRPC,
+			Contract:           *fantomContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1151,11 +1262,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(karuraRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "karura",
-			ChainID:          vaa.ChainIDKarura,
-			Rpc:              *karuraRPC,
-			Contract:         *karuraContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "karura",
+			ChainID:            vaa.ChainIDKarura,
+			Rpc:                *karuraRPC,
+			Contract:           *karuraContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1163,11 +1275,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(acalaRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "acala",
-			ChainID:          vaa.ChainIDAcala,
-			Rpc:              *acalaRPC,
-			Contract:         *acalaContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "acala",
+			ChainID:            vaa.ChainIDAcala,
+			Rpc:                *acalaRPC,
+			Contract:           *acalaContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1175,11 +1288,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(klaytnRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "klaytn",
-			ChainID:          vaa.ChainIDKlaytn,
-			Rpc:              *klaytnRPC,
-			Contract:         *klaytnContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "klaytn",
+			ChainID:            vaa.ChainIDKlaytn,
+			Rpc:                *klaytnRPC,
+			Contract:           *klaytnContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1187,11 +1301,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(celoRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "celo",
-			ChainID:          vaa.ChainIDCelo,
-			Rpc:              *celoRPC,
-			Contract:         *celoContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "celo",
+			ChainID:            vaa.ChainIDCelo,
+			Rpc:                *celoRPC,
+			Contract:           *celoContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1199,11 +1314,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(moonbeamRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "moonbeam",
-			ChainID:          vaa.ChainIDMoonbeam,
-			Rpc:              *moonbeamRPC,
-			Contract:         *moonbeamContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "moonbeam",
+			ChainID:            vaa.ChainIDMoonbeam,
+			Rpc:                *moonbeamRPC,
+			Contract:           *moonbeamContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1217,6 +1333,7 @@ func runNode(cmd *cobra.Command, args []string) {
 			Contract:            *arbitrumContract,
 			L1FinalizerRequired: "eth",
 			CcqBackfillCache:    *ccqBackfillCache,
+			CcqCallConcurrency:  *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1224,11 +1341,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(optimismRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "optimism",
-			ChainID:          vaa.ChainIDOptimism,
-			Rpc:              *optimismRPC,
-			Contract:         *optimismContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "optimism",
+			ChainID:            vaa.ChainIDOptimism,
+			Rpc:                *optimismRPC,
+			Contract:           *optimismContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1236,11 +1354,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(baseRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "base",
-			ChainID:          vaa.ChainIDBase,
-			Rpc:              *baseRPC,
-			Contract:         *baseContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "base",
+			ChainID:            vaa.ChainIDBase,
+			Rpc:                *baseRPC,
+			Contract:           *baseContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1248,11 +1367,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(scrollRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "scroll",
-			ChainID:          vaa.ChainIDScroll,
-			Rpc:              *scrollRPC,
-			Contract:         *scrollContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "scroll",
+			ChainID:            vaa.ChainIDScroll,
+			Rpc:                *scrollRPC,
+			Contract:           *scrollContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1260,11 +1380,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(mantleRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "mantle",
-			ChainID:          vaa.ChainIDMantle,
-			Rpc:              *mantleRPC,
-			Contract:         *mantleContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "mantle",
+			ChainID:            vaa.ChainIDMantle,
+			Rpc:                *mantleRPC,
+			Contract:           *mantleContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1272,11 +1393,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(blastRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "blast",
-			ChainID:          vaa.ChainIDBlast,
-			Rpc:              *blastRPC,
-			Contract:         *blastContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "blast",
+			ChainID:            vaa.ChainIDBlast,
+			Rpc:                *blastRPC,
+			Contract:           *blastContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1284,11 +1406,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(xlayerRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "xlayer",
-			ChainID:          vaa.ChainIDXLayer,
-			Rpc:              *xlayerRPC,
-			Contract:         *xlayerContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "xlayer",
+			ChainID:            vaa.ChainIDXLayer,
+			Rpc:                *xlayerRPC,
+			Contract:           *xlayerContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1301,6 +1424,7 @@ func runNode(cmd *cobra.Command, args []string) {
 			Rpc:                 *lineaRPC,
 			Contract:            *lineaContract,
 			CcqBackfillCache:    *ccqBackfillCache,
+			CcqCallConcurrency:  *ccqCallConcurrency,
 			LineaRollUpUrl:      *lineaRollUpUrl,
 			LineaRollUpContract: *lineaRollUpContract,
 		}
@@ -1310,11 +1434,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 	if shouldStart(berachainRPC) {
 		wc := &evm.WatcherConfig{
-			NetworkID:        "berachain",
-			ChainID:          vaa.ChainIDBerachain,
-			Rpc:              *berachainRPC,
-			Contract:         *berachainContract,
-			CcqBackfillCache: *ccqBackfillCache,
+			NetworkID:          "berachain",
+			ChainID:            vaa.ChainIDBerachain,
+			Rpc:                *berachainRPC,
+			Contract:           *berachainContract,
+			CcqBackfillCache:   *ccqBackfillCache,
+			CcqCallConcurrency: *ccqCallConcurrency,
 		}
 
 		watcherConfigs = append(watcherConfigs, wc)
@@ -1469,11 +1594,12 @@ func runNode(cmd *cobra.Command, args []string) {
 	if env == common.TestNet || env == common.UnsafeDevNet {
 		if shouldStart(sepoliaRPC) {
 			wc := &evm.WatcherConfig{
-				NetworkID:        "sepolia",
-				ChainID:          vaa.ChainIDSepolia,
-				Rpc:              *sepoliaRPC,
-				Contract:         *sepoliaContract,
-				CcqBackfillCache: *ccqBackfillCache,
+				NetworkID:          "sepolia",
+				ChainID:            vaa.ChainIDSepolia,
+				Rpc:                *sepoliaRPC,
+				Contract:           *sepoliaContract,
+				CcqBackfillCache:   *ccqBackfillCache,
+				CcqCallConcurrency: *ccqCallConcurrency,
 			}
 
 			watcherConfigs = append(watcherConfigs, wc)
@@ -1481,11 +1607,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 		if shouldStart(holeskyRPC) {
 			wc := &evm.WatcherConfig{
-				NetworkID:        "holesky",
-				ChainID:          vaa.ChainIDHolesky,
-				Rpc:              *holeskyRPC,
-				Contract:         *holeskyContract,
-				CcqBackfillCache: *ccqBackfillCache,
+				NetworkID:          "holesky",
+				ChainID:            vaa.ChainIDHolesky,
+				Rpc:                *holeskyRPC,
+				Contract:           *holeskyContract,
+				CcqBackfillCache:   *ccqBackfillCache,
+				CcqCallConcurrency: *ccqCallConcurrency,
 			}
 
 			watcherConfigs = append(watcherConfigs, wc)
@@ -1493,11 +1620,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 		if shouldStart(arbitrumSepoliaRPC) {
 			wc := &evm.WatcherConfig{
-				NetworkID:        "arbitrum_sepolia",
-				ChainID:          vaa.ChainIDArbitrumSepolia,
-				Rpc:              *arbitrumSepoliaRPC,
-				Contract:         *arbitrumSepoliaContract,
-				CcqBackfillCache: *ccqBackfillCache,
+				NetworkID:          "arbitrum_sepolia",
+				ChainID:            vaa.ChainIDArbitrumSepolia,
+				Rpc:                *arbitrumSepoliaRPC,
+				Contract:           *arbitrumSepoliaContract,
+				CcqBackfillCache:   *ccqBackfillCache,
+				CcqCallConcurrency: *ccqCallConcurrency,
 			}
 
 			watcherConfigs = append(watcherConfigs, wc)
@@ -1505,11 +1633,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 		if shouldStart(baseSepoliaRPC) {
 			wc := &evm.WatcherConfig{
-				NetworkID:        "base_sepolia",
-				ChainID:          vaa.ChainIDBaseSepolia,
-				Rpc:              *baseSepoliaRPC,
-				Contract:         *baseSepoliaContract,
-				CcqBackfillCache: *ccqBackfillCache,
+				NetworkID:          "base_sepolia",
+				ChainID:            vaa.ChainIDBaseSepolia,
+				Rpc:                *baseSepoliaRPC,
+				Contract:           *baseSepoliaContract,
+				CcqBackfillCache:   *ccqBackfillCache,
+				CcqCallConcurrency: *ccqCallConcurrency,
 			}
 
 			watcherConfigs = append(watcherConfigs, wc)
@@ -1517,11 +1646,12 @@ func runNode(cmd *cobra.Command, args []string) {
 
 		if shouldStart(optimismSepoliaRPC) {
 			wc := &evm.WatcherConfig{
-				NetworkID:        "optimism_sepolia",
-				ChainID:          vaa.ChainIDOptimismSepolia,
-				Rpc:              *optimismSepoliaRPC,
-				Contract:         *optimismSepoliaContract,
-				CcqBackfillCache: *ccqBackfillCache,
+				NetworkID:          "optimism_sepolia",
+				ChainID:            vaa.ChainIDOptimismSepolia,
+				Rpc:                *optimismSepoliaRPC,
+				Contract:           *optimismSepoliaContract,
+				CcqBackfillCache:   *ccqBackfillCache,
+				CcqCallConcurrency: *ccqCallConcurrency,
 			}
 
 			watcherConfigs = append(watcherConfigs, wc)
@@ -1529,17 +1659,28 @@ func runNode(cmd *cobra.Command, args []string) {
 
 		if shouldStart(polygonSepoliaRPC) {
 			wc := &evm.WatcherConfig{
-				NetworkID:        "polygon_sepolia",
-				ChainID:          vaa.ChainIDPolygonSepolia,
-				Rpc:              *polygonSepoliaRPC,
-				Contract:         *polygonSepoliaContract,
-				CcqBackfillCache: *ccqBackfillCache,
+				NetworkID:          "polygon_sepolia",
+				ChainID:            vaa.ChainIDPolygonSepolia,
+				Rpc:                *polygonSepoliaRPC,
+				Contract:           *polygonSepoliaContract,
+				CcqBackfillCache:   *ccqBackfillCache,
+				CcqCallConcurrency: *ccqCallConcurrency,
 			}
 
 			watcherConfigs = append(watcherConfigs, wc)
 		}
 	}
 
+	ccqPageSizes, err := query.ParseLogsPageSizes(*ccqLogsPageSizes)
+	if err != nil {
+		logger.Fatal("failed to parse --ccqLogsPageSizes", zap.Error(err))
+	}
+	for _, wc := range watcherConfigs {
+		if evmConfig, ok := wc.(*evm.WatcherConfig); ok {
+			evmConfig.CcqLogsPageSize = ccqPageSizes[evmConfig.ChainID]
+		}
+	}
+
 	var ibcWatcherConfig *node.IbcWatcherConfig = nil
 	if shouldStart(ibcWS) {
 		ibcWatcherConfig = &node.IbcWatcherConfig{
@@ -1550,6 +1691,127 @@ func runNode(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	ccqQueryTypeFlags, err := query.ParseDisabledQueryTypes(*ccqDisabledQueryTypes)
+	if err != nil {
+		logger.Fatal("failed to parse --ccqDisabledQueryTypes", zap.Error(err))
+	}
+
+	ccqWeights, err := query.ParseChainWeights(*ccqChainWeights)
+	if err != nil {
+		logger.Fatal("failed to parse --ccqChainWeights", zap.Error(err))
+	}
+
+	ccqCallDataLimits, err := query.ParseCallDataLimits(*ccqMaxCallDataSize)
+	if err != nil {
+		logger.Fatal("failed to parse --ccqMaxCallDataSize", zap.Error(err))
+	}
+
+	ccqTimeouts, err := query.ParseRequestTimeouts(*ccqRequestTimeouts)
+	if err != nil {
+		logger.Fatal("failed to parse --ccqRequestTimeouts", zap.Error(err))
+	}
+
+	ccqLogLevels, err := query.ParseSignerLogLevels(*ccqSignerLogLevels)
+	if err != nil {
+		logger.Fatal("failed to parse --ccqSignerLogLevels", zap.Error(err))
+	}
+
+	ccqSysAddrs, err := query.ParseSystemAddresses(*ccqSystemAddresses)
+	if err != nil {
+		logger.Fatal("failed to parse --ccqSystemAddresses", zap.Error(err))
+	}
+
+	ccqResponseSigner, err := query.NewResponseSigner(*ccqSigningScheme, gk, *ccqSigningKeyPath)
+	if err != nil {
+		logger.Fatal("failed to create ccq response signer", zap.Error(err))
+	}
+
+	ccqRequestLimits := query.RequestLimits{
+		MaxPerChainQueries: *ccqMaxPerChainQueries,
+		MaxCallsPerQuery:   *ccqMaxCallsPerQuery,
+		MaxRequestSize:     *ccqMaxRequestSize,
+	}
+
+	queryHandlerConfig := query.HandlerConfig{
+		EnforceMonotonicNonce:      *ccqMonotonicNonce,
+		QueryTypeFlags:             ccqQueryTypeFlags,
+		BandwidthQuotaBytes:        *ccqBandwidthQuota,
+		BandwidthQuotaWindow:       *ccqBandwidthWindow,
+		NearDuplicateThreshold:     *ccqNearDupThreshold,
+		NearDuplicateWindow:        *ccqNearDupWindow,
+		RequireAllChainsWatched:    *ccqRequireAllWatched,
+		DefaultRetryBudget:         *ccqDefaultRetries,
+		MaxRetryBudget:             *ccqMaxRetries,
+		MaxTotalCalls:              *ccqMaxTotalCalls,
+		CoalesceIdenticalRequests:  *ccqCoalesce,
+		RequestLimits:              ccqRequestLimits,
+		MaxCallDataSize:            ccqCallDataLimits,
+		RequestTimeouts:            ccqTimeouts,
+		RequestTimeoutPerCall:      *ccqTimeoutPerCall,
+		MaxScaledRequestTimeout:    *ccqMaxScaledTimeout,
+		ChainWeights:               ccqWeights,
+		CachedResultMaxAge:         *ccqCachedResultMaxAge,
+		IncludeReceiveTime:         *ccqIncludeReceiveTime,
+		CancelOnFatalError:         *ccqCancelOnFatalError,
+		ResponseSigner:             ccqResponseSigner,
+		SlaQueryTimeEstimate:       *ccqSlaQueryTime,
+		SignerLogLevels:            ccqLogLevels,
+		EnforceRequestEnvironment:  *ccqEnforceEnvironment,
+		MaxNonceTrackedSigners:     *ccqMaxNonceSigners,
+		NonceReplayWindow:          *ccqNonceReplayWindow,
+		NonceWrapWindow:            *ccqNonceWrapWindow,
+		ReplayCacheSize:            *ccqReplayCacheSize,
+		ReplayCacheWindow:          *ccqReplayCacheWindow,
+		SystemAddresses:            ccqSysAddrs,
+		DetectIdenticalCalls:       *ccqDetectIdentical,
+		DeferWhileNodeSyncing:      *ccqDeferSyncing,
+		RejectDuplicateCallData:    *ccqRejectDupCallData,
+		IncludeRetryIntervals:      *ccqRetryIntervals,
+		IncludePublishAttempts:     *ccqPublishAttempts,
+		ResponseBatchWindow:        *ccqBatchWindow,
+		MaxResponsePublicationSize: *ccqMaxResponseSize,
+		LegacyRequestDigest:        query.RequestDigestScheme(*ccqLegacyDigest),
+	}
+	queryHandlerConfig.WatcherRegistrationGracePeriod = *ccqRegistrationGrace
+	queryHandlerConfig.CoalesceIdenticalPerChainQueries = *ccqCoalescePerChain
+	if *ccqLegacyDigest != "" {
+		if *ccqLegacyDigestWindow <= 0 {
+			logger.Fatal("--ccqLegacyRequestDigestWindow must be set if --ccqLegacyRequestDigest is set")
+		}
+		queryHandlerConfig.LegacyRequestDigestUntil = time.Now().Add(*ccqLegacyDigestWindow)
+	}
+	if *ccqAllowedReqFile != "" {
+		if *ccqAllowedRequesters != "" {
+			logger.Fatal("--ccqAllowedRequesters and --ccqAllowedRequestersFile may not both be set")
+		}
+		ccqReloadC := make(chan os.Signal, 1)
+		signal.Notify(ccqReloadC, syscall.SIGHUP)
+		queryHandlerConfig.AllowedRequestersFile = *ccqAllowedReqFile
+		queryHandlerConfig.ReloadAllowedRequestersC = ccqReloadC
+	}
+	if *ccqEnabled && *ccqNatsURL != "" {
+		natsPublisher, err := query.NewNatsPublisher(logger, *ccqNatsURL, *ccqNatsSubject)
+		if err != nil {
+			logger.Fatal("failed to create ccq nats publisher", zap.Error(err))
+		}
+		defer natsPublisher.Close()
+		queryHandlerConfig.Publisher = natsPublisher
+	}
+	if *ccqEnabled && *ccqLocalSinkPath != "" {
+		localSink, err := query.NewFileResponseSink(*ccqLocalSinkPath)
+		if err != nil {
+			logger.Fatal("failed to create ccq local sink", zap.Error(err))
+		}
+		defer localSink.Close()
+		queryHandlerConfig.LocalSink = localSink
+	}
+	if *ccqGossipMetadata {
+		queryHandlerConfig.GossipStatus = query.NewGossipStatus()
+	}
+	if *ccqPeerAgreement > 0 {
+		queryHandlerConfig.PeerAgreement = query.NewPeerAgreement(*ccqPeerAgreement)
+	}
+
 	guardianNode := node.NewGuardianNode(
 		env,
 		gk,
@@ -1561,7 +1823,7 @@ func runNode(cmd *cobra.Command, args []string) {
 		node.GuardianOptionAccountant(*accountantWS, *accountantContract, *accountantCheckEnabled, accountantWormchainConn, *accountantNttContract, accountantNttWormchainConn),
 		node.GuardianOptionGovernor(*chainGovernorEnabled),
 		node.GuardianOptionGatewayRelayer(*gatewayRelayerContract, gatewayRelayerWormchainConn),
-		node.GuardianOptionQueryHandler(*ccqEnabled, *ccqAllowedRequesters),
+		node.GuardianOptionQueryHandler(*ccqEnabled, *ccqAllowedRequesters, queryHandlerConfig),
 		node.GuardianOptionAdminService(*adminSocketPath, ethRPC, ethContract, rpcMap),
 		node.GuardianOptionP2P(p2pKey, *p2pNetworkID, *p2pBootstrap, *nodeName, *disableHeartbeatVerify, *p2pPort, *ccqP2pBootstrap, *ccqP2pPort, *ccqAllowedPeers, *gossipAdvertiseAddress, ibc.GetFeatures),
 		node.GuardianOptionStatusServer(*statusAddr),
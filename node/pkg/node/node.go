@@ -91,6 +91,9 @@ type G struct {
 	signedQueryReqC           channelPair[*gossipv1.SignedQueryRequest]
 	queryResponseC            channelPair[*query.PerChainQueryResponseInternal]
 	queryResponsePublicationC channelPair[*query.QueryResponsePublication]
+
+	// registerQueryWatchersOnStart is set if the query handler is created without the watchers, which register with it as they start.
+	registerQueryWatchersOnStart bool
 }
 
 func NewGuardianNode(
@@ -228,6 +231,19 @@ func (g *G) Run(rootCtxCancel context.CancelFunc, options ...*GuardianOption) su
 	}
 }
 
+// registerQueryWatcherOnStart wraps the runnable of a watcher so that, if the query handler is created without the watchers, the watcher
+// registers its query request channel with the handler each time it starts. Registering again after a restart has no effect.
+func (g *G) registerQueryWatcherOnStart(chainID vaa.ChainID, queryReqC chan *query.PerChainQueryInternal, runnable supervisor.Runnable) supervisor.Runnable {
+	return func(ctx context.Context) error {
+		if g.registerQueryWatchersOnStart && g.queryHandler != nil {
+			if err := g.queryHandler.RegisterWatcher(ctx, chainID, queryReqC); err != nil {
+				return fmt.Errorf("failed to register the watcher with the query handler: %w", err)
+			}
+		}
+		return runnable(ctx)
+	}
+}
+
 type channelPair[T any] struct {
 	readC  <-chan T
 	writeC chan<- T
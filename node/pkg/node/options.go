@@ -23,6 +23,7 @@ import (
 	"github.com/certusone/wormhole/node/pkg/watchers/ibc"
 	"github.com/certusone/wormhole/node/pkg/watchers/interfaces"
 	"github.com/certusone/wormhole/node/pkg/wormconn"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/gorilla/mux"
 	libp2p_crypto "github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -55,6 +56,13 @@ func GuardianOptionP2P(p2pKey libp2p_crypto.PrivKey, networkId, bootstrapPeers,
 			// Add the gossip advertisement address
 			components.GossipAdvertiseAddress = gossipAdvertiseAddress
 
+			if g.queryHandler != nil {
+				components.CcqResponseSigner = g.queryHandler.ResponseSigner()
+				components.CcqResponseBatchWindow = g.queryHandler.ResponseBatchWindow()
+				components.CcqGossipStatus = g.queryHandler.GossipStatus()
+				components.CcqPeerAgreement = g.queryHandler.PeerAgreement()
+			}
+
 			g.runnables["p2p"] = p2p.Run(
 				g.obsvC,
 				g.obsvReqC.writeC,
@@ -89,7 +97,7 @@ func GuardianOptionP2P(p2pKey libp2p_crypto.PrivKey, networkId, bootstrapPeers,
 }
 
 // GuardianOptionQueryHandler configures the Cross Chain Query module.
-func GuardianOptionQueryHandler(ccqEnabled bool, allowedRequesters string) *GuardianOption {
+func GuardianOptionQueryHandler(ccqEnabled bool, allowedRequesters string, config query.HandlerConfig) *GuardianOption {
 	return &GuardianOption{
 		name: "query",
 		f: func(ctx context.Context, logger *zap.Logger, g *G) error {
@@ -98,14 +106,26 @@ func GuardianOptionQueryHandler(ccqEnabled bool, allowedRequesters string) *Guar
 				return nil
 			}
 
+			config.GuardianAddress = ethcrypto.PubkeyToAddress(g.gk.PublicKey)
+			config.GuardianSetState = g.gst
+
+			// If requests may wait for watchers that are still starting, each watcher registers with the handler once it starts, rather
+			// than the handler assuming that they are all running.
+			chainQueryReqC := g.chainQueryReqC
+			if config.WatcherRegistrationGracePeriod != 0 {
+				chainQueryReqC = make(map[vaa.ChainID]chan *query.PerChainQueryInternal)
+				g.registerQueryWatchersOnStart = true
+			}
+
 			g.queryHandler = query.NewQueryHandler(
 				logger,
 				g.env,
 				allowedRequesters,
 				g.signedQueryReqC.readC,
-				g.chainQueryReqC,
+				chainQueryReqC,
 				g.queryResponseC.readC,
 				g.queryResponsePublicationC.writeC,
+				config,
 			)
 
 			return nil
@@ -410,6 +430,10 @@ func GuardianOptionWatchers(watcherConfigs []watchers.WatcherConfig, ibcWatcherC
 					return fmt.Errorf("error creating watcher: %w", err)
 				}
 
+				if queryReqC, exists := g.chainQueryReqC[wc.GetChainID()]; exists {
+					runnable = g.registerQueryWatcherOnStart(wc.GetChainID(), queryReqC, runnable)
+				}
+
 				g.runnablesWithScissors[watcherName] = runnable
 				watchers[wc.GetNetworkID()] = l1finalizer
 			}
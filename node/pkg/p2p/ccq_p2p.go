@@ -6,10 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/certusone/wormhole/node/pkg/common"
 	"github.com/certusone/wormhole/node/pkg/query"
-	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"google.golang.org/protobuf/proto"
@@ -43,6 +43,7 @@ type ccqP2p struct {
 	th_req        *pubsub.Topic
 	th_resp       *pubsub.Topic
 	sub           *pubsub.Subscription
+	sub_resp      *pubsub.Subscription
 	allowedPeers  map[string]struct{}
 	p2pComponents *Components
 }
@@ -144,6 +145,9 @@ func (ccq *ccqP2p) run(
 	if err != nil {
 		return fmt.Errorf("failed to join topic_resp: %w", err)
 	}
+	if ccq.p2pComponents.CcqGossipStatus != nil {
+		ccq.p2pComponents.CcqGossipStatus.Register(topic_resp, func() int { return len(ccq.th_resp.ListPeers()) })
+	}
 
 	// We only want to accept messages from peers in the allow list.
 	err = ps.RegisterTopicValidator(topic_req, func(ctx context.Context, from peer.ID, msg *pubsub.Message) bool {
@@ -177,6 +181,18 @@ func (ccq *ccqP2p) run(
 		return ccq.publisher(ctx, gk, queryResponseReadC)
 	})
 
+	// If the query handler reports agreement with other guardians, we also need to read the responses they publish.
+	if ccq.p2pComponents.CcqPeerAgreement != nil {
+		ccq.sub_resp, err = ccq.th_resp.Subscribe(pubsub.WithBufferSize(1024))
+		if err != nil {
+			return fmt.Errorf("failed to subscribe topic_resp: %w", err)
+		}
+
+		common.StartRunnable(ctx, errC, false, "ccqp2p_peer_responses", func(ctx context.Context) error {
+			return ccq.peerResponseListener(ctx, ccq.p2pComponents.CcqPeerAgreement)
+		})
+	}
+
 	ccq.logger.Info("Node has been started", zap.String("peer_id", ccq.h.ID().String()), zap.String("addrs", fmt.Sprintf("%v", ccq.h.Addrs())))
 	return nil
 }
@@ -192,6 +208,9 @@ func (ccq *ccqP2p) close() {
 	}
 
 	ccq.sub.Cancel()
+	if ccq.sub_resp != nil {
+		ccq.sub_resp.Cancel()
+	}
 
 	if err := ccq.h.Close(); err != nil {
 		ccq.logger.Error("error closing the host", zap.Error(err))
@@ -235,50 +254,122 @@ func (ccq *ccqP2p) listener(ctx context.Context, signedQueryReqC chan<- *gossipv
 	}
 }
 
+// peerResponseListener feeds the responses published by other guardians to the peer agreement tracker. Our own responses are delivered too, but they are never counted.
+func (ccq *ccqP2p) peerResponseListener(ctx context.Context, peerAgreement *query.PeerAgreement) error {
+	for {
+		envelope, err := ccq.sub_resp.Next(ctx) // Note: sub_resp.Next(ctx) will return an error once ctx is canceled
+		if err != nil {
+			return fmt.Errorf("failed to receive pubsub message: %w", err)
+		}
+
+		var msg gossipv1.GossipMessage
+		if err := proto.Unmarshal(envelope.Data, &msg); err != nil {
+			ccqP2pMessagesReceived.WithLabelValues("invalid_response").Inc()
+			continue
+		}
+
+		m, ok := msg.Message.(*gossipv1.GossipMessage_SignedQueryResponse)
+		if !ok {
+			ccqP2pMessagesReceived.WithLabelValues("unknown_response").Inc()
+			continue
+		}
+
+		ccqP2pMessagesReceived.WithLabelValues("peer_response").Inc()
+		if err := peerAgreement.ObserveSignedResponse(m.SignedQueryResponse, time.Now()); err != nil {
+			ccq.logger.Debug("failed to observe peer query response", zap.String("from", envelope.GetFrom().String()), zap.Error(err))
+		}
+	}
+}
+
 func (ccq *ccqP2p) publisher(ctx context.Context, gk *ecdsa.PrivateKey, queryResponseReadC <-chan *query.QueryResponsePublication) error {
+	signer := ccq.p2pComponents.CcqResponseSigner
+	if signer == nil {
+		signer = query.NewSecp256k1ResponseSigner(gk)
+	}
+	ccq.logger.Info("signing query responses", zap.String("scheme", signer.Scheme()))
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case msg := <-queryResponseReadC:
-			msgBytes, err := msg.Marshal()
-			if err != nil {
-				ccq.logger.Error("failed to marshal query response", zap.Error(err))
-				continue
+			batch := []*query.QueryResponsePublication{msg}
+			if window := ccq.p2pComponents.CcqResponseBatchWindow; window > 0 {
+				batch = query.CollectResponseBatch(ctx, msg, queryResponseReadC, window)
 			}
-			digest := query.GetQueryResponseDigestFromBytes(msgBytes)
-			sig, err := ethcrypto.Sign(digest.Bytes(), gk)
-			if err != nil {
-				panic(err)
-			}
-			envelope := &gossipv1.GossipMessage{
-				Message: &gossipv1.GossipMessage_SignedQueryResponse{
-					SignedQueryResponse: &gossipv1.SignedQueryResponse{
-						QueryResponse: msgBytes,
-						Signature:     sig,
-					},
-				},
-			}
-			b, err := proto.Marshal(envelope)
-			if err != nil {
-				panic(err)
+
+			msgs := make([]*query.QueryResponsePublication, 0, len(batch))
+			signedResponses := make([]*gossipv1.SignedQueryResponse, 0, len(batch))
+			for _, msg := range batch {
+				msgBytes, err := msg.Marshal()
+				if err != nil {
+					ccq.logger.Error("failed to marshal query response", zap.Error(err))
+					continue
+				}
+				digest := query.GetQueryResponseDigestFromBytes(msgBytes)
+				sig, err := signer.Sign(digest)
+				if err != nil {
+					panic(err)
+				}
+				msgs = append(msgs, msg)
+				signedResponses = append(signedResponses, &gossipv1.SignedQueryResponse{
+					QueryResponse: msgBytes,
+					Signature:     sig,
+				})
 			}
-			err = ccq.th_resp.Publish(ctx, b)
-			if err != nil {
-				ccq.logger.Error("failed to publish query response",
-					zap.String("requestSignature", msg.Signature()),
-					zap.Any("query_response", msg),
-					zap.Any("signature", sig),
-					zap.Error(err),
-				)
-			} else {
-				ccqP2pMessagesSent.Inc()
-				ccq.logger.Info("published signed query response", //TODO: Change to Debug
-					zap.String("requestSignature", msg.Signature()),
-					zap.Any("query_response", msg),
-					zap.Any("signature", sig),
-				)
+
+			// Publish the responses in as many messages as it takes to keep each one under the maximum pubsub message size.
+			start := 0
+			for _, group := range query.SplitResponseBatch(signedResponses, query.MaxResponseBatchBytes) {
+				ccq.publishResponses(ctx, msgs[start:start+len(group)], group)
+				start += len(group)
 			}
 		}
 	}
 }
+
+// publishResponses publishes a set of signed query responses to p2p in a single message, batching them if there is more than one.
+// The msgs parameter is parallel to signedResponses, and is only used for logging.
+func (ccq *ccqP2p) publishResponses(ctx context.Context, msgs []*query.QueryResponsePublication, signedResponses []*gossipv1.SignedQueryResponse) {
+	signedResponse := signedResponses[0]
+	if len(signedResponses) > 1 {
+		var err error
+		signedResponse, err = query.MarshalResponseBatch(signedResponses)
+		if err != nil {
+			ccq.logger.Error("failed to batch query responses", zap.Int("numResponses", len(signedResponses)), zap.Error(err))
+			return
+		}
+	}
+
+	envelope := &gossipv1.GossipMessage{
+		Message: &gossipv1.GossipMessage_SignedQueryResponse{
+			SignedQueryResponse: signedResponse,
+		},
+	}
+	b, err := proto.Marshal(envelope)
+	if err != nil {
+		panic(err)
+	}
+	err = ccq.th_resp.Publish(ctx, b)
+	for idx, msg := range msgs {
+		if err != nil {
+			ccq.logger.Error("failed to publish query response",
+				zap.String("requestSignature", msg.Signature()),
+				zap.Any("query_response", msg),
+				zap.Any("signature", signedResponses[idx].Signature),
+				zap.Int("batchSize", len(msgs)),
+				zap.Error(err),
+			)
+		} else {
+			ccq.logger.Info("published signed query response", //TODO: Change to Debug
+				zap.String("requestSignature", msg.Signature()),
+				zap.Any("query_response", msg),
+				zap.Any("signature", signedResponses[idx].Signature),
+				zap.Int("batchSize", len(msgs)),
+			)
+		}
+	}
+	if err == nil {
+		ccqP2pMessagesSent.Inc()
+	}
+}
@@ -120,6 +120,14 @@ type Components struct {
 	GossipParams pubsub.GossipSubParams
 	// GossipAdvertiseAddress is an override for the external IP advertised via p2p to other peers.
 	GossipAdvertiseAddress string
+	// CcqResponseSigner is used to sign CCQ responses. If it is nil, they are signed with the guardian key using secp256k1.
+	CcqResponseSigner query.ResponseSigner
+	// CcqResponseBatchWindow, if non-zero, is the window within which CCQ responses are batched into a single pubsub message.
+	CcqResponseBatchWindow time.Duration
+	// CcqGossipStatus, if set, is registered with the CCQ response topic, so the query handler can record how responses are gossiped.
+	CcqGossipStatus *query.GossipStatus
+	// CcqPeerAgreement, if set, causes the CCQ response topic to be subscribed to, and is fed with the responses published by other guardians.
+	CcqPeerAgreement *query.PeerAgreement
 }
 
 func (f *Components) ListeningAddresses() []string {
@@ -0,0 +1,45 @@
+package query
+
+// LoadSignal reports the resource pressure on the guardian, so the query handler can shed new requests while the node is overloaded.
+// Load is called from the handler routine as each request is received, so it must be cheap and must not block.
+type LoadSignal interface {
+	// Load returns the current CPU and memory utilization, each as a fraction between zero and one.
+	Load() (cpu float64, memory float64)
+}
+
+// admissionControl is the query handler side of the load signal. It decides whether a newly received request should be shed.
+type admissionControl struct {
+	signal        LoadSignal
+	maxCpuLoad    float64
+	maxMemoryLoad float64
+}
+
+// newAdmissionControl creates the admission control. It returns nil if no load signal is configured, or neither threshold is set.
+func newAdmissionControl(signal LoadSignal, maxCpuLoad float64, maxMemoryLoad float64) *admissionControl {
+	if signal == nil || (maxCpuLoad <= 0 && maxMemoryLoad <= 0) {
+		return nil
+	}
+
+	return &admissionControl{
+		signal:        signal,
+		maxCpuLoad:    maxCpuLoad,
+		maxMemoryLoad: maxMemoryLoad,
+	}
+}
+
+// overloaded returns true if the load signal reports CPU or memory utilization at or above its threshold, along with the reported utilization.
+// It may be called on a nil object, in which case it never reports an overload.
+func (a *admissionControl) overloaded() (bool, float64, float64) {
+	if a == nil {
+		return false, 0, 0
+	}
+
+	cpu, memory := a.signal.Load()
+	if a.maxCpuLoad > 0 && cpu >= a.maxCpuLoad {
+		return true, cpu, memory
+	}
+	if a.maxMemoryLoad > 0 && memory >= a.maxMemoryLoad {
+		return true, cpu, memory
+	}
+	return false, cpu, memory
+}
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLoadSignal is a load signal whose utilization can be changed by the test.
+type mockLoadSignal struct {
+	mutex  sync.Mutex
+	cpu    float64
+	memory float64
+}
+
+func (s *mockLoadSignal) Load() (float64, float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.cpu, s.memory
+}
+
+func (s *mockLoadSignal) set(cpu float64, memory float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cpu = cpu
+	s.memory = memory
+}
+
+func TestAdmissionControlThresholds(t *testing.T) {
+	signal := &mockLoadSignal{}
+	assert.Nil(t, newAdmissionControl(nil, 0.9, 0.9))
+	assert.Nil(t, newAdmissionControl(signal, 0, 0))
+
+	// A nil admission control never reports an overload.
+	var nilAdmission *admissionControl
+	overloaded, _, _ := nilAdmission.overloaded()
+	assert.False(t, overloaded)
+
+	// Only the memory threshold is set, so CPU pressure is ignored.
+	admission := newAdmissionControl(signal, 0, 0.8)
+	signal.set(1.0, 0.5)
+	overloaded, _, _ = admission.overloaded()
+	assert.False(t, overloaded)
+	signal.set(0.1, 0.8)
+	overloaded, cpu, memory := admission.overloaded()
+	assert.True(t, overloaded)
+	assert.Equal(t, 0.1, cpu)
+	assert.Equal(t, 0.8, memory)
+}
+
+func TestOverloadShedsNewRequestsButNotInFlightOnes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	signal := &mockLoadSignal{}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{LoadSignal: signal, MaxCpuLoad: 0.9, MaxMemoryLoad: 0.9})
+
+	// Submit a request while there is no pressure, and keep it in flight by having polygon retry.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.setRetries(vaa.ChainIDPolygon, 1000)
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.Eventually(t, func() bool { return md.getRequestsPerChain(vaa.ChainIDPolygon) > 0 }, requestTimeoutForTest, pollIntervalForTest)
+
+	// Once the CPU is under pressure, a new request is shed without being sent to the watcher.
+	signal.set(0.95, 0.1)
+	newPerChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 2)}
+	newSignedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, newPerChainQueries)
+	md.signedQueryReqWriteC <- newSignedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, Overloaded, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	// The request that was already in flight is still retried, and completes while the pressure remains.
+	time.Sleep(retryIntervalForTest)
+	md.setRetries(vaa.ChainIDPolygon, 0)
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Greater(t, md.getRequestsPerChain(vaa.ChainIDPolygon), 1)
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ReadAllowedRequestersFile reads an allowed requesters list from a file, in the same format as `--ccqAllowedRequesters`, except that the
+// entries may also be separated by newlines. Blank lines and lines starting with # are ignored. The list is not validated.
+func ReadAllowedRequestersFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read allowed requesters file: %w", err)
+	}
+
+	entries := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, entry := range strings.Split(line, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return strings.Join(entries, ","), nil
+}
+
+// reloadAllowedRequesters rereads HandlerConfig.AllowedRequestersFile each time HandlerConfig.ReloadAllowedRequestersC is signaled, and
+// replaces the allowed requesters with its contents. If the file cannot be read or the list is invalid, the error is logged and the
+// current list is kept.
+func (qh *QueryHandler) reloadAllowedRequesters(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-qh.config.ReloadAllowedRequestersC:
+			qh.logger.Info("reloading the allowed requesters", zap.String("allowedRequestersFile", qh.config.AllowedRequestersFile))
+			allowedRequestorsStr, err := ReadAllowedRequestersFile(qh.config.AllowedRequestersFile)
+			if err == nil {
+				err = qh.UpdateAllowedRequesters(ctx, allowedRequestorsStr)
+			}
+			if err != nil {
+				qh.logger.Error("failed to reload the allowed requesters, keeping the current list", zap.String("allowedRequestersFile", qh.config.AllowedRequestersFile), zap.Error(err))
+				allowedRequestersReloadFailures.Inc()
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package query
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAllowedRequestersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowed_requesters")
+	require.NoError(t, os.WriteFile(path, []byte("# Production requesters\n0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe:10\n\n 0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBf, 0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FC0\n"), 0600))
+
+	allowedRequestorsStr, err := ReadAllowedRequestersFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe:10,0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBf,0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FC0", allowedRequestorsStr)
+
+	_, err = ReadAllowedRequestersFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestReloadAllowedRequestersAcceptsPreviouslyDeniedSigner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	path := filepath.Join(t.TempDir(), "allowed_requesters")
+	require.NoError(t, os.WriteFile(path, []byte(testSigner+"\n"), 0600))
+
+	// Use the config from a real query handler so that its reload routine reaches the handler under test.
+	reloadC := make(chan os.Signal)
+	qh := NewQueryHandler(logger, common.GoTest, "", nil, nil, nil, nil, HandlerConfig{AllowedRequestersFile: path, ReloadAllowedRequestersC: reloadC})
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, qh.config)
+	go func() {
+		assert.NoError(t, qh.reloadAllowedRequesters(ctx))
+	}()
+
+	// reload signals a reload, then signals again, which is only received once the first reload has finished, since the channel is unbuffered.
+	reload := func() {
+		reloadC <- syscall.SIGHUP
+		reloadC <- syscall.SIGHUP
+	}
+
+	newSk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	newSigner := ethCrypto.PubkeyToAddress(newSk.PublicKey)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, newSk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// The new signer is not allowed yet, so its request should be dropped.
+	md.signedQueryReqWriteC <- signedQueryRequest
+	assert.Nil(t, md.waitForResponse())
+
+	// A malformed list should be rejected, leaving the current one in place.
+	require.NoError(t, os.WriteFile(path, []byte(testSigner+"\n"+newSigner.Hex()+":fast\n"), 0600))
+	reload()
+
+	signedQueryRequest, _ = createSignedQueryRequestForTesting(t, newSk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	assert.Nil(t, md.waitForResponse())
+
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// Once the file lists the new signer, it should be accepted after a reload.
+	md.resetState()
+	md.setExpectedResults(expectedResults)
+	require.NoError(t, os.WriteFile(path, []byte(testSigner+"\n"+newSigner.Hex()+"\n"), 0600))
+	reload()
+
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, newSk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	queryResponsePublication = md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// bandwidthQuota tracks the number of response bytes served to each signer in the current window. It is only accessed from the query handler routine.
+type bandwidthQuota struct {
+	limit  uint64
+	window time.Duration
+	usage  map[ethCommon.Address]*bandwidthUsage
+}
+
+// bandwidthUsage is the number of response bytes served to a single signer since the start of its current window.
+type bandwidthUsage struct {
+	windowStart time.Time
+	bytes       uint64
+}
+
+// newBandwidthQuota creates a bandwidth quota tracker. It returns nil if the limit is zero, meaning bandwidth is not limited.
+func newBandwidthQuota(limit uint64, window time.Duration) *bandwidthQuota {
+	if limit == 0 {
+		return nil
+	}
+
+	return &bandwidthQuota{
+		limit:  limit,
+		window: window,
+		usage:  make(map[ethCommon.Address]*bandwidthUsage),
+	}
+}
+
+// currentUsage returns the usage for the signer, starting a new window if the previous one has expired.
+func (bq *bandwidthQuota) currentUsage(signer ethCommon.Address, now time.Time) *bandwidthUsage {
+	usage, exists := bq.usage[signer]
+	if !exists || !now.Before(usage.windowStart.Add(bq.window)) {
+		usage = &bandwidthUsage{windowStart: now}
+		bq.usage[signer] = usage
+	}
+	return usage
+}
+
+// exceeded returns true if the signer has already been served at least the quota in the current window. A nil object is never exceeded.
+func (bq *bandwidthQuota) exceeded(signer ethCommon.Address, now time.Time) bool {
+	if bq == nil {
+		return false
+	}
+	return bq.currentUsage(signer, now).bytes >= bq.limit
+}
+
+// record adds the size of a response to the signer's usage for the current window. A nil object does nothing.
+func (bq *bandwidthQuota) record(signer ethCommon.Address, numBytes int, now time.Time) {
+	if bq == nil {
+		return
+	}
+	bq.currentUsage(signer, now).bytes += uint64(numBytes)
+}
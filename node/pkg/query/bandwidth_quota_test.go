@@ -0,0 +1,34 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthQuotaIsPerSignerAndResetsPerWindow(t *testing.T) {
+	bq := newBandwidthQuota(100, time.Minute)
+	signer1 := ethCommon.HexToAddress("0x1")
+	signer2 := ethCommon.HexToAddress("0x2")
+	now := time.Now()
+
+	assert.False(t, bq.exceeded(signer1, now))
+	bq.record(signer1, 99, now)
+	assert.False(t, bq.exceeded(signer1, now))
+	bq.record(signer1, 1, now)
+	assert.True(t, bq.exceeded(signer1, now))
+	assert.False(t, bq.exceeded(signer2, now))
+
+	// The window is measured from when the signer was first seen.
+	assert.True(t, bq.exceeded(signer1, now.Add(time.Minute-time.Nanosecond)))
+	assert.False(t, bq.exceeded(signer1, now.Add(time.Minute)))
+}
+
+func TestNilBandwidthQuotaIsNeverExceeded(t *testing.T) {
+	bq := newBandwidthQuota(0, time.Minute)
+	assert.Nil(t, bq)
+	bq.record(ethCommon.HexToAddress("0x1"), 1000, time.Now())
+	assert.False(t, bq.exceeded(ethCommon.HexToAddress("0x1"), time.Now()))
+}
@@ -0,0 +1,97 @@
+package query
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// ChainBlock identifies a block that was used to produce one or more of the per chain responses in a query response.
+type ChainBlock struct {
+	ChainId     vaa.ChainID
+	BlockNumber uint64
+
+	// BlockHash is the hash of the block, or the block hash associated with the slot on Solana.
+	BlockHash []byte
+	BlockTime time.Time
+}
+
+// BlockSummary lists the blocks used across all of the per chain responses, so that consumers can check the consistency of a batch without
+// walking each response. Entries are grouped by chain, in the order each chain first appears in the response, and each block is only listed
+// once per chain. For by timestamp queries, only the target blocks are listed. It is derived from the signed responses, so it is not marshaled,
+// but the query handler copies it to ResponseMetadata.BlockSummary when the response is assembled.
+func (msg *QueryResponsePublication) BlockSummary() []*ChainBlock {
+	chainOrder := []vaa.ChainID{}
+	perChain := make(map[vaa.ChainID][]*ChainBlock)
+	for _, resp := range msg.PerChainResponses {
+		if _, exists := perChain[resp.ChainId]; !exists {
+			chainOrder = append(chainOrder, resp.ChainId)
+			perChain[resp.ChainId] = []*ChainBlock{}
+		}
+
+		for _, block := range ResponseBlocks(resp.Response) {
+			block.ChainId = resp.ChainId
+			if !containsBlock(perChain[resp.ChainId], block) {
+				perChain[resp.ChainId] = append(perChain[resp.ChainId], block)
+			}
+		}
+	}
+
+	summary := []*ChainBlock{}
+	for _, chainID := range chainOrder {
+		summary = append(summary, perChain[chainID]...)
+	}
+	return summary
+}
+
+// ResponseBlocks returns the blocks used to produce a response, leaving the chain ID unset. It returns every target block of a by timestamp
+// list query, and both blocks of a storage diff query. It returns nil if the response does not identify a block. This is the only place
+// that knows where each response type keeps its block, so a new response type only needs to be added here.
+func ResponseBlocks(response ChainSpecificResponse) []*ChainBlock {
+	switch resp := response.(type) {
+	case *EthCallQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.BlockNumber, BlockHash: resp.Hash.Bytes(), BlockTime: resp.Time}}
+	case *EthCallByTimestampQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.TargetBlockNumber, BlockHash: resp.TargetBlockHash.Bytes(), BlockTime: resp.TargetBlockTime}}
+	case *EthCallWithFinalityQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.BlockNumber, BlockHash: resp.Hash.Bytes(), BlockTime: resp.Time}}
+	case *EthCallWithPreconditionQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.BlockNumber, BlockHash: resp.Hash.Bytes(), BlockTime: resp.Time}}
+	case *EthCallByTimestampListQueryResponse:
+		blocks := []*ChainBlock{}
+		for _, entry := range resp.Responses {
+			blocks = append(blocks, ResponseBlocks(entry)...)
+		}
+		return blocks
+	case *EthTxProofQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.BlockNumber, BlockHash: resp.BlockHash.Bytes(), BlockTime: resp.BlockTime}}
+	case *EthStorageDiffQueryResponse:
+		return []*ChainBlock{
+			{BlockNumber: resp.FromBlockNumber, BlockHash: resp.FromBlockHash.Bytes(), BlockTime: resp.FromBlockTime},
+			{BlockNumber: resp.ToBlockNumber, BlockHash: resp.ToBlockHash.Bytes(), BlockTime: resp.ToBlockTime},
+		}
+	case *EthBlockProbeQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.BlockNumber, BlockHash: resp.BlockHash.Bytes(), BlockTime: resp.BlockTime}}
+	case *EthLogsQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.BlockNumber, BlockHash: resp.BlockHash.Bytes(), BlockTime: resp.BlockTime}}
+	case *EthStorageQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.BlockNumber, BlockHash: resp.BlockHash.Bytes(), BlockTime: resp.BlockTime}}
+	case *SolanaAccountQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.SlotNumber, BlockHash: resp.BlockHash[:], BlockTime: resp.BlockTime}}
+	case *SolanaPdaQueryResponse:
+		return []*ChainBlock{{BlockNumber: resp.SlotNumber, BlockHash: resp.BlockHash[:], BlockTime: resp.BlockTime}}
+	default:
+		return nil
+	}
+}
+
+// containsBlock returns true if the list already contains a block with the same number and hash.
+func containsBlock(blocks []*ChainBlock, block *ChainBlock) bool {
+	for _, b := range blocks {
+		if b.BlockNumber == block.BlockNumber && bytes.Equal(b.BlockHash, block.BlockHash) {
+			return true
+		}
+	}
+	return false
+}
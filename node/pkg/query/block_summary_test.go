@@ -0,0 +1,68 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+
+	"github.com/stretchr/testify/assert"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+func TestBlockSummaryListsTheBlocksOfEachChain(t *testing.T) {
+	ethHash := ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2")
+	ethTime := time.UnixMicro(1700000000000000)
+	solHash := [SolanaPublicKeyLength]byte{1, 2, 3}
+	solTime := time.UnixMicro(1700000010000000)
+
+	resp := &QueryResponsePublication{PerChainResponses: []*PerChainQueryResponse{
+		{ChainId: vaa.ChainIDEthereum, Response: &EthCallQueryResponse{BlockNumber: 1000, Hash: ethHash, Time: ethTime}},
+		{ChainId: vaa.ChainIDSolana, Response: &SolanaAccountQueryResponse{SlotNumber: 2000, BlockHash: solHash, BlockTime: solTime}},
+		// A second query on the same block is only listed once.
+		{ChainId: vaa.ChainIDEthereum, Response: &EthCallWithFinalityQueryResponse{BlockNumber: 1000, Hash: ethHash, Time: ethTime}},
+	}}
+
+	assert.Equal(t, []*ChainBlock{
+		{ChainId: vaa.ChainIDEthereum, BlockNumber: 1000, BlockHash: ethHash.Bytes(), BlockTime: ethTime},
+		{ChainId: vaa.ChainIDSolana, BlockNumber: 2000, BlockHash: solHash[:], BlockTime: solTime},
+	}, resp.BlockSummary())
+}
+
+func TestBlockSummaryGroupsByChain(t *testing.T) {
+	hash1 := ethCommon.HexToHash("0x1")
+	hash2 := ethCommon.HexToHash("0x2")
+	hash3 := ethCommon.HexToHash("0x3")
+
+	resp := &QueryResponsePublication{PerChainResponses: []*PerChainQueryResponse{
+		{ChainId: vaa.ChainIDPolygon, Response: &EthCallQueryResponse{BlockNumber: 1000, Hash: hash1}},
+		{ChainId: vaa.ChainIDBSC, Response: &EthCallByTimestampListQueryResponse{Responses: []*EthCallByTimestampQueryResponse{
+			{TargetBlockNumber: 500, TargetBlockHash: hash2, FollowingBlockNumber: 501},
+			{TargetBlockNumber: 600, TargetBlockHash: hash3, FollowingBlockNumber: 601},
+		}}},
+		{ChainId: vaa.ChainIDPolygon, Response: &EthCallQueryResponse{BlockNumber: 1001, Hash: hash2}},
+	}}
+
+	summary := resp.BlockSummary()
+	assert.Equal(t, 4, len(summary))
+	assert.Equal(t, []vaa.ChainID{vaa.ChainIDPolygon, vaa.ChainIDPolygon, vaa.ChainIDBSC, vaa.ChainIDBSC},
+		[]vaa.ChainID{summary[0].ChainId, summary[1].ChainId, summary[2].ChainId, summary[3].ChainId})
+	assert.Equal(t, []uint64{1000, 1001, 500, 600},
+		[]uint64{summary[0].BlockNumber, summary[1].BlockNumber, summary[2].BlockNumber, summary[3].BlockNumber})
+	assert.Equal(t, hash3.Bytes(), summary[3].BlockHash)
+}
+
+func TestResponseBlockIsTheMostRecentOfResponseBlocks(t *testing.T) {
+	toTime := time.UnixMicro(1700000010000000)
+	resp := &EthStorageDiffQueryResponse{FromBlockNumber: 1000, FromBlockTime: time.UnixMicro(1700000000000000), ToBlockNumber: 1005, ToBlockTime: toTime}
+	assert.Equal(t, 2, len(ResponseBlocks(resp)))
+
+	blockNumber, blockTime, ok := ResponseBlock(resp)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1005), blockNumber)
+	assert.Equal(t, toTime, blockTime)
+
+	_, _, ok = ResponseBlock(&EthCallByTimestampListQueryResponse{})
+	assert.False(t, ok)
+}
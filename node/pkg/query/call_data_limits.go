@@ -0,0 +1,67 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// CallDataLimits is the maximum size in bytes of the data of a single call on each chain. Chains that are not listed have no limit.
+type CallDataLimits map[vaa.ChainID]int
+
+// oversizedCall returns the index and size of the first call in a per chain query whose data is larger than the limit for its chain, and
+// false if there is none, or the query type does not have calls. It may be called on a nil object.
+func (l CallDataLimits) oversizedCall(pcq *PerChainQueryRequest) (int, int, bool) {
+	limit, exists := l[pcq.ChainId]
+	if !exists {
+		return 0, 0, false
+	}
+
+	q, ok := pcq.Query.(interface{ CallDataList() []*EthCallData })
+	if !ok {
+		return 0, 0, false
+	}
+
+	for callIdx, cd := range q.CallDataList() {
+		if len(cd.Data) > limit {
+			return callIdx, len(cd.Data), true
+		}
+	}
+	return 0, 0, false
+}
+
+// ParseCallDataLimits parses a comma separated list of "chain:bytes" entries, such as "polygon:4096,bsc:1024". The chain is the chain name
+// and the limit is a positive integer. An empty string returns nil, meaning there are no limits.
+func ParseCallDataLimits(str string) (CallDataLimits, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	limits := make(CallDataLimits)
+	for _, entry := range strings.Split(str, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`invalid call data limit "%s", must be "chain:bytes"`, entry)
+		}
+
+		chainID, err := vaa.ChainIDFromString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid chain in call data limit "%s": %w`, entry, err)
+		}
+
+		if _, exists := limits[chainID]; exists {
+			return nil, fmt.Errorf(`duplicate chain in call data limit "%s"`, entry)
+		}
+
+		limit, err := strconv.ParseUint(fields[1], 10, 31)
+		if err != nil || limit == 0 {
+			return nil, fmt.Errorf(`invalid size in call data limit "%s", must be a positive integer`, entry)
+		}
+
+		limits[chainID] = int(limit)
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCallDataLimits(t *testing.T) {
+	limits, err := ParseCallDataLimits("polygon:4096, bsc:1024")
+	require.NoError(t, err)
+	assert.Equal(t, CallDataLimits{vaa.ChainIDPolygon: 4096, vaa.ChainIDBSC: 1024}, limits)
+
+	limits, err = ParseCallDataLimits("")
+	require.NoError(t, err)
+	assert.Nil(t, limits)
+}
+
+func TestParseCallDataLimitsInvalidEntries(t *testing.T) {
+	for _, str := range []string{"polygon", "polygon:1:2", "notAChain:1", "polygon:notANumber", "polygon:-1", "polygon:0", "polygon:1,polygon:2"} {
+		_, err := ParseCallDataLimits(str)
+		assert.Error(t, err, str)
+	}
+}
+
+func TestOversizedCallDataIsRejectedBeforeDispatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	limits := CallDataLimits{vaa.ChainIDBSC: 64}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{MaxCallDataSize: limits})
+
+	// The second call on bsc has more data than the limit.
+	perChainQuery := createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 2)
+	perChainQuery.Query.(*EthCallQueryRequest).CallData[1].Data = make([]byte, 65)
+	callIdx, size, oversized := limits.oversizedCall(perChainQuery)
+	require.True(t, oversized)
+	assert.Equal(t, 1, callIdx)
+	assert.Equal(t, 65, size)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2), perChainQuery}
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, CallDataTooLarge, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	// The same call is allowed on a chain without a limit.
+	md.resetState()
+	perChainQuery = createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)
+	perChainQuery.Query.(*EthCallQueryRequest).CallData[1].Data = make([]byte, 65)
+	perChainQueries = []*PerChainQueryRequest{perChainQuery}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
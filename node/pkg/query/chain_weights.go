@@ -0,0 +1,93 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// ChainWeights assigns a scheduling weight to each chain. When the handler dispatches per chain queries, those for chains with a higher
+// weight are sent to their watchers first. Chains that are not listed have a weight of zero. Queries with the same weight are dispatched
+// in the order they were received.
+type ChainWeights map[vaa.ChainID]uint
+
+// weight returns the weight of the specified chain. It may be called on a nil object.
+func (w ChainWeights) weight(chainID vaa.ChainID) uint {
+	return w[chainID]
+}
+
+// dispatchOrder returns the per chain queries of a single request in the order they should be dispatched. It does not modify the slice that is passed in.
+func (w ChainWeights) dispatchOrder(queries []*perChainQuery) []*perChainQuery {
+	ordered := append([]*perChainQuery{}, queries...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return w.weight(ordered[i].req.Request.ChainId) > w.weight(ordered[j].req.Request.ChainId)
+	})
+	return ordered
+}
+
+// pendingRetry is a per chain query that is due to be retried.
+type pendingRetry struct {
+	pq         *pendingQuery
+	requestIdx int
+}
+
+// sortRetries orders the retries collected across all of the pending queries in the order they should be dispatched. Requests with a tighter
+// SLA tier go first, then higher weight chains. Within a weight, older requests go first.
+func (w ChainWeights) sortRetries(retries []pendingRetry) {
+	sort.Slice(retries, func(i, j int) bool {
+		left, right := retries[i], retries[j]
+		// Higher SLA tiers have tighter deadlines.
+		if left.pq.request.SlaTier != right.pq.request.SlaTier {
+			return left.pq.request.SlaTier > right.pq.request.SlaTier
+		}
+		leftWeight := w.weight(left.pq.queries[left.requestIdx].req.Request.ChainId)
+		rightWeight := w.weight(right.pq.queries[right.requestIdx].req.Request.ChainId)
+		if leftWeight != rightWeight {
+			return leftWeight > rightWeight
+		}
+		if !left.pq.receiveTime.Equal(right.pq.receiveTime) {
+			return left.pq.receiveTime.Before(right.pq.receiveTime)
+		}
+		if left.pq.requestID != right.pq.requestID {
+			return left.pq.requestID < right.pq.requestID
+		}
+		return left.requestIdx < right.requestIdx
+	})
+}
+
+// ParseChainWeights parses a comma separated list of "chain:weight" entries, such as "polygon:10,bsc:1". The chain is the chain name
+// and the weight is a non-negative integer. An empty string returns nil, meaning all chains have the same weight.
+func ParseChainWeights(str string) (ChainWeights, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	weights := make(ChainWeights)
+	for _, entry := range strings.Split(str, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`invalid chain weight "%s", must be "chain:weight"`, entry)
+		}
+
+		chainID, err := vaa.ChainIDFromString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid chain in chain weight "%s": %w`, entry, err)
+		}
+
+		if _, exists := weights[chainID]; exists {
+			return nil, fmt.Errorf(`duplicate chain in chain weight "%s"`, entry)
+		}
+
+		weight, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid weight in chain weight "%s": %w`, entry, err)
+		}
+
+		weights[chainID] = uint(weight)
+	}
+
+	return weights, nil
+}
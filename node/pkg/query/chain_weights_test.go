@@ -0,0 +1,142 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChainWeights(t *testing.T) {
+	weights, err := ParseChainWeights("polygon:10, bsc:1")
+	require.NoError(t, err)
+	assert.Equal(t, uint(10), weights.weight(vaa.ChainIDPolygon))
+	assert.Equal(t, uint(1), weights.weight(vaa.ChainIDBSC))
+	assert.Equal(t, uint(0), weights.weight(vaa.ChainIDEthereum))
+
+	weights, err = ParseChainWeights("")
+	require.NoError(t, err)
+	assert.Nil(t, weights)
+	assert.Equal(t, uint(0), weights.weight(vaa.ChainIDPolygon))
+}
+
+func TestParseChainWeightsInvalidEntries(t *testing.T) {
+	for _, str := range []string{"polygon", "polygon:1:2", "notAChain:1", "polygon:notANumber", "polygon:-1", "polygon:1,polygon:2"} {
+		_, err := ParseChainWeights(str)
+		assert.Error(t, err, str)
+	}
+}
+
+// receivePerChainQueryForTest reads the next per chain query dispatched on the channel. It will eventually timeout if nothing is dispatched.
+func receivePerChainQueryForTest(t *testing.T, chainQueryReqC <-chan *PerChainQueryInternal) *PerChainQueryInternal {
+	t.Helper()
+	select {
+	case pcqr := <-chainQueryReqC:
+		return pcqr
+	case <-time.After(50 * pollIntervalForTest):
+		require.FailNow(t, "timed out waiting for a per chain query to be dispatched")
+		return nil
+	}
+}
+
+func TestHigherWeightChainsAreDispatchedFirstUnderContention(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	sk, err := common.LoadGuardianKey("dev.guardian.key", true)
+	require.NoError(t, err)
+	allowedRequestors, _, err := parseAllowedRequesters(testSigner)
+	require.NoError(t, err)
+
+	// Both chains share a single dispatch channel with room for two queries, so they are competing for the same capacity.
+	sharedC := make(chan *PerChainQueryInternal, 2)
+	chainQueryReqC := map[vaa.ChainID]chan *PerChainQueryInternal{vaa.ChainIDPolygon: sharedC, vaa.ChainIDBSC: sharedC}
+	signedQueryReqReadC, signedQueryReqWriteC := makeChannelPair[*gossipv1.SignedQueryRequest](SignedQueryRequestChannelSize)
+	queryResponseReadC, queryResponseWriteC := makeChannelPair[*PerChainQueryResponseInternal](0)
+	queryResponsePublicationReadC, queryResponsePublicationWriteC := makeChannelPair[*QueryResponsePublication](1)
+
+	config := HandlerConfig{ChainWeights: ChainWeights{vaa.ChainIDPolygon: 10, vaa.ChainIDBSC: 1}, MaxRetryInterval: retryIntervalForTest}
+	go func() {
+		// Use a longer request timeout, since the test relies on several rounds of retries.
+		err := handleQueryRequestsImpl(ctx, logger, signedQueryReqReadC, chainQueryReqC, allowedRequestors,
+			queryResponseReadC, queryResponsePublicationWriteC, common.GoTest, time.Second, retryIntervalForTest, auditIntervalForTest, config)
+		assert.NoError(t, err)
+	}()
+
+	// The BSC queries come first in the request.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 1),
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9124", 3),
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 4),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, sk, perChainQueries)
+	requestID := hex.EncodeToString(signedQueryRequest.Signature) + ":" + QueryRequestDigest(common.GoTest, signedQueryRequest.QueryRequest).String()
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+
+	signedQueryReqWriteC <- signedQueryRequest
+
+	// Wait for the channel to fill up, so the initial dispatch is complete before anything is read.
+	require.Eventually(t, func() bool { return len(sharedC) == cap(sharedC) }, time.Second, pollIntervalForTest)
+
+	// While the polygon queries are outstanding, they should take all of the capacity, both initially and on retries.
+	for count := 0; count < 4; count++ {
+		pcqr := receivePerChainQueryForTest(t, sharedC)
+		assert.Equal(t, vaa.ChainIDPolygon, pcqr.Request.ChainId)
+	}
+
+	// Answer the polygon queries, after which the BSC queries should be dispatched.
+	for _, requestIdx := range []int{1, 3} {
+		queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, requestIdx, vaa.ChainIDPolygon, QuerySuccess, expectedResults[requestIdx].Response)
+	}
+
+	bscRequestIdxs := map[int]struct{}{}
+	for len(bscRequestIdxs) < 2 {
+		pcqr := receivePerChainQueryForTest(t, sharedC)
+		if pcqr.Request.ChainId == vaa.ChainIDBSC {
+			bscRequestIdxs[pcqr.RequestIdx] = struct{}{}
+		}
+	}
+	assert.Equal(t, map[int]struct{}{0: {}, 2: {}}, bscRequestIdxs)
+
+	for _, requestIdx := range []int{0, 2} {
+		queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, requestIdx, vaa.ChainIDBSC, QuerySuccess, expectedResults[requestIdx].Response)
+	}
+
+	select {
+	case queryResponsePublication := <-queryResponsePublicationReadC:
+		assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	case <-time.After(time.Second):
+		require.FailNow(t, "timed out waiting for the response to be published")
+	}
+}
+
+func TestDispatchOrderIsStableWithinAWeight(t *testing.T) {
+	queries := []*perChainQuery{}
+	for idx, chainID := range []vaa.ChainID{vaa.ChainIDBSC, vaa.ChainIDEthereum, vaa.ChainIDPolygon, vaa.ChainIDBSC, vaa.ChainIDEthereum} {
+		queries = append(queries, &perChainQuery{req: &PerChainQueryInternal{RequestIdx: idx, Request: &PerChainQueryRequest{ChainId: chainID}}})
+	}
+
+	dispatchedIdxs := func(weights ChainWeights) []int {
+		idxs := []int{}
+		for _, pcq := range weights.dispatchOrder(queries) {
+			idxs = append(idxs, pcq.req.RequestIdx)
+		}
+		return idxs
+	}
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, dispatchedIdxs(nil))
+	assert.Equal(t, []int{2, 1, 4, 0, 3}, dispatchedIdxs(ChainWeights{vaa.ChainIDPolygon: 5, vaa.ChainIDEthereum: 2}))
+
+	// The slice that was passed in should not be modified.
+	assert.Equal(t, 0, queries[0].req.RequestIdx)
+}
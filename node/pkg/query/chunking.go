@@ -0,0 +1,154 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// ChunkedResponsePublisher is a ResponsePublisher whose messages are limited in size. A serialized response that is larger than
+// MaxMessageSize is split into chunks, which are published in order using PublishChunk. Where possible, a chunk ends between the results
+// of a per chain response, so a large result set, such as the logs of an eth_logs query, is delivered a number of whole results at a time.
+// Consumers can use a ResponseReassembler to rebuild the response.
+type ChunkedResponsePublisher interface {
+	ResponsePublisher
+
+	// MaxMessageSize returns the largest response that may be published as a single message. Zero means there is no limit.
+	MaxMessageSize() int
+
+	// PublishChunk publishes one chunk of a serialized response. The key is the hex encoded signature of the request.
+	PublishChunk(ctx context.Context, key string, chunk *ResponseChunk) error
+}
+
+// ResponseChunk is one piece of a serialized query response that was too large to be published as a single message.
+type ResponseChunk struct {
+	// Sequence is the position of the chunk in the response, starting at zero.
+	Sequence uint16
+
+	// Final is set on the last chunk of the response.
+	Final bool
+
+	// Data is this chunk of the serialized response.
+	Data []byte
+}
+
+// resultSplitter is implemented by the chain specific responses whose results may be delivered in separate chunks.
+type resultSplitter interface {
+	// resultOffsets returns the offset of each result in the serialized response, in ascending order.
+	resultOffsets() []int
+}
+
+// perChainResponseHeaderSize is the size of the chain ID, query type and length that precede a chain specific response in a serialized per chain response.
+const perChainResponseHeaderSize = 2 + 1 + 4
+
+// resultBoundaries returns the offsets in a serialized response at which a chunk may end without splitting a result. These are the start and end
+// of each per chain response and, for a per chain response whose results may be split, the start of each result. The offsets are in ascending order.
+func resultBoundaries(respPub *QueryResponsePublication) ([]int, error) {
+	// The per chain responses follow the version, source, signature, request length, request and the number of per chain responses.
+	offset := 1 + 2 + len(respPub.Request.Signature) + 4 + len(respPub.Request.QueryRequest) + 1
+
+	boundaries := []int{}
+	for idx, pcr := range respPub.PerChainResponses {
+		boundaries = append(boundaries, offset)
+		pcrBytes, err := pcr.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal per chain response %d: %w", idx, err)
+		}
+		if splitter, ok := pcr.Response.(resultSplitter); ok {
+			for _, resultOffset := range splitter.resultOffsets() {
+				boundaries = append(boundaries, offset+perChainResponseHeaderSize+resultOffset)
+			}
+		}
+		offset += len(pcrBytes)
+	}
+
+	return append(boundaries, offset), nil
+}
+
+// splitResponse splits a serialized response into chunks of at most chunkSize bytes. Each chunk ends at the last of the boundaries that fits in it,
+// so that results are not split across chunks. If no boundary fits, the chunk is filled. It returns an error if that would take too many chunks.
+func splitResponse(response []byte, chunkSize int, boundaries []int) ([]*ResponseChunk, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size: %d", chunkSize)
+	}
+
+	chunks := []*ResponseChunk{}
+	nextBoundary := 0
+	for offset := 0; offset < len(response); {
+		if len(chunks) > math.MaxUint16 {
+			return nil, fmt.Errorf("response of %d bytes would take too many chunks", len(response))
+		}
+
+		end := offset + chunkSize
+		if end >= len(response) {
+			end = len(response)
+		} else {
+			for nextBoundary < len(boundaries) && boundaries[nextBoundary] <= offset {
+				nextBoundary++
+			}
+			lastBoundary := 0
+			for nextBoundary < len(boundaries) && boundaries[nextBoundary] <= end {
+				lastBoundary = boundaries[nextBoundary]
+				nextBoundary++
+			}
+			if lastBoundary != 0 {
+				end = lastBoundary
+			}
+		}
+
+		chunks = append(chunks, &ResponseChunk{
+			Sequence: uint16(len(chunks)),
+			Final:    end == len(response),
+			Data:     response[offset:end],
+		})
+		offset = end
+	}
+
+	return chunks, nil
+}
+
+// ResponseReassembler rebuilds serialized responses from the chunks published by a ChunkedResponsePublisher. It is not thread safe.
+type ResponseReassembler struct {
+	pending map[string][]byte
+	nextSeq map[string]uint16
+}
+
+// NewResponseReassembler creates a response reassembler.
+func NewResponseReassembler() *ResponseReassembler {
+	return &ResponseReassembler{
+		pending: make(map[string][]byte),
+		nextSeq: make(map[string]uint16),
+	}
+}
+
+// Add adds a chunk of the response with the specified key. The chunks of a response must be added in order. Once the final chunk
+// is added, it returns the full serialized response, otherwise it returns nil. If a chunk is out of order, the partial response is
+// discarded and an error is returned.
+func (rr *ResponseReassembler) Add(key string, chunk *ResponseChunk) ([]byte, error) {
+	if chunk.Sequence != rr.nextSeq[key] {
+		expected := rr.nextSeq[key]
+		rr.Discard(key)
+		return nil, fmt.Errorf("received chunk %d of response %s, expected chunk %d", chunk.Sequence, key, expected)
+	}
+
+	rr.pending[key] = append(rr.pending[key], chunk.Data...)
+	if !chunk.Final {
+		rr.nextSeq[key] = chunk.Sequence + 1
+		return nil, nil
+	}
+
+	response := rr.pending[key]
+	rr.Discard(key)
+	return response, nil
+}
+
+// Discard drops the partial response with the specified key, for example if the rest of it is never received.
+func (rr *ResponseReassembler) Discard(key string) {
+	delete(rr.pending, key)
+	delete(rr.nextSeq, key)
+}
+
+// NumPending returns the number of responses that have been partially received.
+func (rr *ResponseReassembler) NumPending() int {
+	return len(rr.pending)
+}
@@ -0,0 +1,261 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// createLargeQueryResponseForTesting creates a response where the first per chain response has a large result set.
+func createLargeQueryResponseForTesting(t *testing.T) *QueryResponsePublication {
+	t.Helper()
+	respPub := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+	resp := respPub.PerChainResponses[0].Response.(*EthCallQueryResponse)
+	resp.Results = [][]byte{}
+	for idx := 0; idx < 2; idx++ {
+		resp.Results = append(resp.Results, bytes.Repeat([]byte(fmt.Sprintf("Result %d|", idx)), 5000))
+	}
+	return respPub
+}
+
+func TestLargeResponseIsSplitIntoChunksAndReassembled(t *testing.T) {
+	respPub := createLargeQueryResponseForTesting(t)
+	respBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+	require.Greater(t, len(respBytes), 90000)
+
+	chunkSize := 4096
+	chunks, err := splitResponse(respBytes, chunkSize, nil)
+	require.NoError(t, err)
+	require.Equal(t, (len(respBytes)+chunkSize-1)/chunkSize, len(chunks))
+
+	rr := NewResponseReassembler()
+	var reassembled []byte
+	for idx, chunk := range chunks {
+		assert.Equal(t, uint16(idx), chunk.Sequence)
+		assert.Equal(t, idx == len(chunks)-1, chunk.Final)
+		if !chunk.Final {
+			assert.Equal(t, chunkSize, len(chunk.Data))
+		}
+
+		reassembled, err = rr.Add("key", chunk)
+		require.NoError(t, err)
+		if !chunk.Final {
+			assert.Nil(t, reassembled)
+			assert.Equal(t, 1, rr.NumPending())
+		}
+	}
+
+	require.Equal(t, respBytes, reassembled)
+	assert.Equal(t, 0, rr.NumPending())
+
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(reassembled))
+	assert.True(t, respPub.Equal(&respPub2))
+}
+
+// createLargeEthLogsQueryResponseForTesting creates an eth_logs response with a large set of logs of varying sizes.
+func createLargeEthLogsQueryResponseForTesting(t *testing.T, numLogs int) *QueryResponsePublication {
+	t.Helper()
+	respPub := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+	queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "0x28d9640")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	respPub.Request.QueryRequest = queryRequestBytes
+
+	resp := &EthLogsQueryResponse{
+		BlockNumber: 0x28d9640,
+		BlockHash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		BlockTime:   timeForTest(t, time.Now()),
+		FromBlock:   0x28d9630,
+	}
+	for idx := 0; idx < numLogs; idx++ {
+		resp.Logs = append(resp.Logs, &EthLog{
+			BlockNumber: 0x28d9630 + uint64(idx/40),
+			TxHash:      ethCommon.BigToHash(big.NewInt(int64(idx + 1))),
+			LogIndex:    uint32(idx % 40),
+			Topics:      []ethCommon.Hash{ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")},
+			Data:        bytes.Repeat([]byte{byte(idx)}, 100+(idx%7)*50),
+		})
+	}
+	respPub.PerChainResponses = []*PerChainQueryResponse{{ChainId: vaa.ChainIDPolygon, Response: resp}}
+	return respPub
+}
+
+func TestEthLogsResultOffsetsMatchTheSerializedLogs(t *testing.T) {
+	resp := createLargeEthLogsQueryResponseForTesting(t, 10).PerChainResponses[0].Response.(*EthLogsQueryResponse)
+	offsets := resp.resultOffsets()
+	require.Equal(t, len(resp.Logs), len(offsets))
+
+	// The offset of each log is the size of a response containing only the logs before it.
+	for idx := range resp.Logs {
+		partial := &EthLogsQueryResponse{BlockNumber: resp.BlockNumber, BlockHash: resp.BlockHash, BlockTime: resp.BlockTime, FromBlock: resp.FromBlock, Logs: resp.Logs[:idx]}
+		partialBytes, err := partial.Marshal()
+		require.NoError(t, err)
+		assert.Equal(t, len(partialBytes), offsets[idx])
+	}
+}
+
+func TestLargeEthLogsResponseIsChunkedBetweenLogsAndReassembled(t *testing.T) {
+	respPub := createLargeEthLogsQueryResponseForTesting(t, 500)
+	respBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+	require.Greater(t, len(respBytes), 100000)
+
+	boundaries, err := resultBoundaries(respPub)
+	require.NoError(t, err)
+	require.Equal(t, 500+2, len(boundaries))
+	isBoundary := map[int]bool{}
+	for _, boundary := range boundaries {
+		isBoundary[boundary] = true
+	}
+
+	chunkSize := 4096
+	chunks, err := splitResponse(respBytes, chunkSize, boundaries)
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), len(respBytes)/chunkSize)
+
+	rr := NewResponseReassembler()
+	var reassembled []byte
+	offset := 0
+	for idx, chunk := range chunks {
+		assert.Equal(t, uint16(idx), chunk.Sequence)
+		assert.Equal(t, idx == len(chunks)-1, chunk.Final)
+		assert.LessOrEqual(t, len(chunk.Data), chunkSize)
+
+		// Every chunk but the last should end between two logs.
+		offset += len(chunk.Data)
+		if !chunk.Final {
+			assert.True(t, isBoundary[offset], "chunk %d ends at %d, which is not between logs", idx, offset)
+		}
+
+		reassembled, err = rr.Add("key", chunk)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, respBytes, reassembled)
+
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(reassembled))
+	require.True(t, respPub.Equal(&respPub2))
+
+	// The logs should be complete and in order.
+	logs := respPub2.PerChainResponses[0].Response.(*EthLogsQueryResponse).Logs
+	require.Equal(t, 500, len(logs))
+	for idx, log := range logs {
+		assert.Equal(t, uint32(idx%40), log.LogIndex)
+		assert.Equal(t, bytes.Repeat([]byte{byte(idx)}, 100+(idx%7)*50), log.Data)
+	}
+}
+
+func TestResponseReassemblerRejectsOutOfOrderChunks(t *testing.T) {
+	chunks, err := splitResponse(bytes.Repeat([]byte{0x01}, 100), 10, nil)
+	require.NoError(t, err)
+
+	rr := NewResponseReassembler()
+	_, err = rr.Add("key", chunks[0])
+	require.NoError(t, err)
+	_, err = rr.Add("key", chunks[2])
+	require.EqualError(t, err, "received chunk 2 of response key, expected chunk 1")
+
+	// The partial response should have been discarded.
+	assert.Equal(t, 0, rr.NumPending())
+}
+
+// mockChunkedPublisher is a ChunkedResponsePublisher that records the chunks it is asked to publish.
+type mockChunkedPublisher struct {
+	mockPublisher
+	maxMessageSize int
+	chunkKeys      []string
+	chunks         []*ResponseChunk
+}
+
+func (mp *mockChunkedPublisher) MaxMessageSize() int {
+	return mp.maxMessageSize
+}
+
+func (mp *mockChunkedPublisher) PublishChunk(_ context.Context, key string, chunk *ResponseChunk) error {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+	mp.chunkKeys = append(mp.chunkKeys, key)
+	mp.chunks = append(mp.chunks, chunk)
+	return nil
+}
+
+// waitForFinalChunk waits for the mock publisher to receive a final chunk, and returns the chunks received so far.
+func (mp *mockChunkedPublisher) waitForFinalChunk() ([]string, []*ResponseChunk) {
+	for count := 0; count < 50; count++ {
+		time.Sleep(pollIntervalForTest)
+		mp.mutex.Lock()
+		if len(mp.chunks) != 0 && mp.chunks[len(mp.chunks)-1].Final {
+			defer mp.mutex.Unlock()
+			return append([]string{}, mp.chunkKeys...), append([]*ResponseChunk{}, mp.chunks...)
+		}
+		mp.mutex.Unlock()
+	}
+	return nil, nil
+}
+
+func TestExternalPublisherPublishesLargeResponseInChunks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisher := &mockChunkedPublisher{maxMessageSize: 16384}
+	ep := newExternalPublisher(zap.NewNop(), publisher)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, ep.run(ctx))
+	}()
+
+	// A response that fits in a single message is published as is.
+	smallRespPub := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+	ep.post(smallRespPub)
+	_, published := publisher.waitForPublish()
+	require.NotNil(t, published)
+	smallRespBytes, err := smallRespPub.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, smallRespBytes, published)
+
+	// A large response is published in chunks, which can be reassembled.
+	largeRespPub := createLargeQueryResponseForTesting(t)
+	largeRespPub.Request.Signature[0] = 0x01
+	ep.post(largeRespPub)
+	keys, chunks := publisher.waitForFinalChunk()
+	require.NotNil(t, chunks)
+	assert.Greater(t, len(chunks), 1)
+
+	rr := NewResponseReassembler()
+	var reassembled []byte
+	for idx, chunk := range chunks {
+		assert.Equal(t, hex.EncodeToString(largeRespPub.Request.Signature), keys[idx])
+		assert.LessOrEqual(t, len(chunk.Data), publisher.maxMessageSize)
+		reassembled, err = rr.Add(keys[idx], chunk)
+		require.NoError(t, err)
+	}
+
+	largeRespBytes, err := largeRespPub.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, largeRespBytes, reassembled)
+
+	// The large response should not have been published as a single message.
+	publisher.mutex.Lock()
+	assert.Equal(t, 1, len(publisher.responses))
+	publisher.mutex.Unlock()
+
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,213 @@
+package query
+
+import (
+	"fmt"
+
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// inFlightRequests tracks the requests that have been dispatched to the watchers and not answered yet, by the hash of their marshaled payload,
+// so that a byte-identical request from another signer can be coalesced with one of them rather than being dispatched again. Since the payload
+// includes the nonce, only requests that are exactly the same are coalesced. It is nil if coalescing is not enabled, and is only accessed from
+// the query handler routine.
+type inFlightRequests map[ethCommon.Hash]*pendingQuery
+
+// newInFlightRequests returns an empty set of in flight requests if coalescing is enabled, and nil otherwise.
+func newInFlightRequests(enabled bool) inFlightRequests {
+	if !enabled {
+		return nil
+	}
+	return make(inFlightRequests)
+}
+
+// payloadHash returns the key used to coalesce a request.
+func payloadHash(signedRequest *gossipv1.SignedQueryRequest) ethCommon.Hash {
+	return ethCrypto.Keccak256Hash(signedRequest.QueryRequest)
+}
+
+// add records that a request has been dispatched. It may be called on a nil object.
+func (f inFlightRequests) add(pq *pendingQuery) {
+	if f == nil {
+		return
+	}
+	f[payloadHash(pq.signedRequest)] = pq
+}
+
+// leader returns the in flight request that a request with the same payload may be coalesced with, or nil if there is none. A request is only
+// a leader while it is still pending and has not been answered.
+func (f inFlightRequests) leader(signedRequest *gossipv1.SignedQueryRequest, pendingQueries map[string]*pendingQuery) *pendingQuery {
+	if f == nil {
+		return nil
+	}
+	hash := payloadHash(signedRequest)
+	leader, exists := f[hash]
+	if !exists {
+		return nil
+	}
+	if pendingQueries[leader.requestID] != leader || leader.respPub != nil {
+		delete(f, hash)
+		return nil
+	}
+	return leader
+}
+
+// prune drops the requests that are no longer in flight. It may be called on a nil object.
+func (f inFlightRequests) prune(pendingQueries map[string]*pendingQuery) {
+	for hash, leader := range f {
+		if pendingQueries[leader.requestID] != leader || leader.respPub != nil {
+			delete(f, hash)
+		}
+	}
+}
+
+// follow coalesces the request with an identical in flight request, so it is published with the results of that request instead of being dispatched.
+func (pq *pendingQuery) follow(leader *pendingQuery) {
+	pq.leader = leader
+	leader.followers = append(leader.followers, pq)
+}
+
+// leaderGone returns true if the request is coalesced with a request that has been dropped without being answered, in which case it must be
+// dispatched on its own.
+func (pq *pendingQuery) leaderGone(pendingQueries map[string]*pendingQuery) bool {
+	return pq.leader != nil && pendingQueries[pq.leader.requestID] != pq.leader
+}
+
+// adoptResults copies the per chain results of the leader into a coalesced request, and detaches it from the leader, so that it can be published.
+func (pq *pendingQuery) adoptResults() {
+	leader := pq.leader
+	copy(pq.responses, leader.responses)
+	for requestIdx, pcq := range pq.queries {
+		leaderPcq := leader.queries[requestIdx]
+		pcq.failed = leaderPcq.failed
+		pcq.retryHistory = leaderPcq.retryHistory
+		pcq.retryIntervals = leaderPcq.retryIntervals
+	}
+	pq.leader = nil
+}
+
+// perChainQueryRef identifies a per chain query of a pending request.
+type perChainQueryRef struct {
+	pq         *pendingQuery
+	requestIdx int
+}
+
+// query returns the per chain query being referred to.
+func (ref *perChainQueryRef) query() *perChainQuery {
+	return ref.pq.queries[ref.requestIdx]
+}
+
+// inFlightPerChainQueries tracks the per chain queries that have been dispatched to the watchers and not answered yet, by their coalescing key,
+// so that an identical per chain query in another request waits for the result rather than being dispatched again. It is nil if per chain
+// query coalescing is not enabled, and is only accessed from the query handler routine.
+type inFlightPerChainQueries map[string]*perChainQueryRef
+
+// newInFlightPerChainQueries returns an empty set of in flight per chain queries if coalescing is enabled, and nil otherwise.
+func newInFlightPerChainQueries(enabled bool) inFlightPerChainQueries {
+	if !enabled {
+		return nil
+	}
+	return make(inFlightPerChainQueries)
+}
+
+// perChainQueryKey returns the key used to coalesce a per chain query. Besides the query itself, it includes the fields of the request that
+// affect how the response is produced or accepted, so that only per chain queries that can share a response are coalesced.
+func perChainQueryKey(req *PerChainQueryInternal) (string, error) {
+	key, err := resultCacheKey(req.Request)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%t:%t:%d:", req.AllowRevertedCalls, req.PreferSpeed, req.Request.MaxBlockAge) + key, nil
+}
+
+// add records that a per chain query has been dispatched. It may be called on a nil object.
+func (f inFlightPerChainQueries) add(pq *pendingQuery, requestIdx int) {
+	if f == nil {
+		return
+	}
+	if key, err := perChainQueryKey(pq.queries[requestIdx].req); err == nil {
+		f[key] = &perChainQueryRef{pq: pq, requestIdx: requestIdx}
+	}
+}
+
+// leader returns the in flight per chain query of another request that the specified per chain query may be coalesced with, or nil if there
+// is none. A per chain query is only a leader while its request is still pending, and it has neither been answered nor failed.
+func (f inFlightPerChainQueries) leader(pq *pendingQuery, requestIdx int, pendingQueries map[string]*pendingQuery) *perChainQueryRef {
+	if f == nil {
+		return nil
+	}
+	key, err := perChainQueryKey(pq.queries[requestIdx].req)
+	if err != nil {
+		return nil
+	}
+	leader, exists := f[key]
+	if !exists {
+		return nil
+	}
+	if !leader.inFlight(pendingQueries) {
+		delete(f, key)
+		return nil
+	}
+	if leader.pq == pq {
+		return nil
+	}
+	return leader
+}
+
+// prune drops the per chain queries that are no longer in flight. It may be called on a nil object.
+func (f inFlightPerChainQueries) prune(pendingQueries map[string]*pendingQuery) {
+	for key, leader := range f {
+		if !leader.inFlight(pendingQueries) {
+			delete(f, key)
+		}
+	}
+}
+
+// inFlight returns true if the per chain query is still waiting for a response from a watcher.
+func (ref *perChainQueryRef) inFlight(pendingQueries map[string]*pendingQuery) bool {
+	return pendingQueries[ref.pq.requestID] == ref.pq && ref.pq.respPub == nil && ref.pq.isPending(ref.requestIdx) && ref.query().leader == nil
+}
+
+// followPerChainQuery coalesces a per chain query with an identical in flight per chain query of another request, so it is answered with the
+// response to that query instead of being dispatched.
+func (pq *pendingQuery) followPerChainQuery(requestIdx int, leader *perChainQueryRef) {
+	pq.queries[requestIdx].leader = leader
+	leaderPcq := leader.query()
+	leaderPcq.followers = append(leaderPcq.followers, perChainQueryRef{pq: pq, requestIdx: requestIdx})
+}
+
+// leaderGone returns true if the per chain query is coalesced with a per chain query that will not be answered, because its request was
+// dropped or it failed, in which case it must be dispatched on its own.
+func (pcq *perChainQuery) leaderGone(pendingQueries map[string]*pendingQuery) bool {
+	return pcq.leader != nil && (pendingQueries[pcq.leader.pq.requestID] != pcq.leader.pq || pcq.leader.query().failed)
+}
+
+// answerFollowers answers the per chain queries of other requests that are coalesced with one of the per chain queries of this request, using
+// its response. It returns the requests that no longer have any pending per chain queries, so that they can be published.
+func (pq *pendingQuery) answerFollowers(requestIdx int, pendingQueries map[string]*pendingQuery) []*pendingQuery {
+	pcq := pq.queries[requestIdx]
+	resp := pq.responses[requestIdx]
+	answered := []*pendingQuery{}
+	for _, follower := range pcq.followers {
+		followerPcq := follower.query()
+		if pendingQueries[follower.pq.requestID] != follower.pq || followerPcq.leader == nil || followerPcq.leader.pq != pq || !follower.pq.isPending(follower.requestIdx) {
+			continue
+		}
+
+		followerPcq.leader = nil
+		followerResp := *resp
+		followerResp.RequestID = follower.pq.requestID
+		followerResp.RequestIdx = follower.requestIdx
+		if resp.Metadata != nil {
+			metadata := *resp.Metadata
+			followerResp.Metadata = &metadata
+		}
+		follower.pq.responses[follower.requestIdx] = &followerResp
+		if follower.pq.numPendingRequests() == 0 {
+			answered = append(answered, follower.pq)
+		}
+	}
+	pcq.followers = nil
+	return answered
+}
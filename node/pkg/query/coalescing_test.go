@@ -0,0 +1,147 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdenticalConcurrentRequestsAreDispatchedOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	otherSk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+
+	// Use the config from a real query handler so that both signers can be allowed.
+	qh := NewQueryHandler(logger, common.GoTest, testSigner, nil, nil, nil, nil, HandlerConfig{CoalesceIdenticalRequests: true})
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, qh.config)
+	require.NoError(t, qh.UpdateAllowedRequesters(ctx, testSigner+","+ethCrypto.PubkeyToAddress(otherSk.PublicKey).Hex()))
+
+	// Both requesters sign exactly the same payload.
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce: nonce,
+		PerChainQueries: []*PerChainQueryRequest{
+			createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+			createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		},
+	}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+	otherSignedQueryRequest := signQueryRequestForTesting(t, otherSk, queryRequest)
+	require.Equal(t, signedQueryRequest.QueryRequest, otherSignedQueryRequest.QueryRequest)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Holding the lock of the mock stalls the watchers once they have picked up the first request, so the second one arrives while it is in flight.
+	md.mutex.Lock()
+	md.signedQueryReqWriteC <- signedQueryRequest
+	md.signedQueryReqWriteC <- otherSignedQueryRequest
+	require.Eventually(t, func() bool { return len(md.signedQueryReqWriteC) == 0 }, time.Second, pollIntervalForTest)
+	md.mutex.Unlock()
+
+	require.Eventually(t, func() bool { return len(md.getPublications()) == 2 }, time.Second, pollIntervalForTest)
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	// Each requester gets a response over its own signed request.
+	publications := md.getPublications()
+	signatures := map[string]struct{}{}
+	for _, pub := range publications {
+		signatures[pub.Signature()] = struct{}{}
+		if SignedQueryRequestEqual(pub.Request, signedQueryRequest) {
+			assert.True(t, validateResponseForTest(t, pub, signedQueryRequest, queryRequest, expectedResults))
+		} else {
+			assert.True(t, validateResponseForTest(t, pub, otherSignedQueryRequest, queryRequest, expectedResults))
+		}
+	}
+	assert.Len(t, signatures, 2)
+}
+
+func TestCoalescedRequestIsDispatchedWhenLeaderIsDropped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	otherSk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	otherSigner := ethCrypto.PubkeyToAddress(otherSk.PublicKey)
+
+	cancelSignerC := make(chan ethCommon.Address, 1)
+	qh := NewQueryHandler(logger, common.GoTest, testSigner, nil, nil, nil, nil, HandlerConfig{CoalesceIdenticalRequests: true, CancelSignerC: cancelSignerC})
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, qh.config)
+	require.NoError(t, qh.UpdateAllowedRequesters(ctx, testSigner+","+otherSigner.Hex()))
+
+	nonce += 1
+	queryRequest := &QueryRequest{Nonce: nonce, PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+	otherSignedQueryRequest := signQueryRequestForTesting(t, otherSk, queryRequest)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// The watcher ignores the first request, so the second one is coalesced with it. Then the first requester is cancelled, and the watcher
+	// starts answering again.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	md.signedQueryReqWriteC <- otherSignedQueryRequest
+	require.Eventually(t, func() bool { return md.getRequestsPerChain(vaa.ChainIDPolygon) >= 1 }, time.Second, pollIntervalForTest)
+	require.Eventually(t, func() bool { return len(md.signedQueryReqWriteC) == 0 }, time.Second, pollIntervalForTest)
+	cancelSignerC <- ethCrypto.PubkeyToAddress(md.sk.PublicKey)
+	require.Eventually(t, func() bool { return md.getFailure() != nil }, time.Second, pollIntervalForTest)
+	md.clearRetries(vaa.ChainIDPolygon)
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, otherSignedQueryRequest, queryRequest, expectedResults))
+	assert.Len(t, md.getPublications(), 1)
+}
+
+func TestIdenticalPerChainQueriesInConcurrentRequestsAreDispatchedOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{CoalesceIdenticalPerChainQueries: true})
+
+	// The requests are different, but share the same polygon query.
+	polygonQuery := createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:           nonce,
+		PerChainQueries: []*PerChainQueryRequest{polygonQuery, createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3)},
+	}
+	nonce += 1
+	otherQueryRequest := &QueryRequest{Nonce: nonce, PerChainQueries: []*PerChainQueryRequest{polygonQuery}}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+	otherSignedQueryRequest := signQueryRequestForTesting(t, md.sk, otherQueryRequest)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Holding the lock of the mock stalls the watchers once they have picked up the first request, so the second one arrives while it is in flight.
+	md.mutex.Lock()
+	md.signedQueryReqWriteC <- signedQueryRequest
+	md.signedQueryReqWriteC <- otherSignedQueryRequest
+	require.Eventually(t, func() bool { return len(md.signedQueryReqWriteC) == 0 }, time.Second, pollIntervalForTest)
+	md.mutex.Unlock()
+
+	require.Eventually(t, func() bool { return len(md.getPublications()) == 2 }, time.Second, pollIntervalForTest)
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	for _, pub := range md.getPublications() {
+		if SignedQueryRequestEqual(pub.Request, signedQueryRequest) {
+			assert.True(t, validateResponseForTest(t, pub, signedQueryRequest, queryRequest, expectedResults))
+		} else {
+			assert.True(t, validateResponseForTest(t, pub, otherSignedQueryRequest, otherQueryRequest, expectedResults[:1]))
+		}
+	}
+}
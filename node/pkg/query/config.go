@@ -0,0 +1,272 @@
+package query
+
+import (
+	"os"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// HandlerConfig contains the optional settings for the query handler. The zero value gives the default behavior.
+type HandlerConfig struct {
+	// Publisher, if set, is also sent every query response published to p2p. Publishing to it never blocks the query handler.
+	Publisher ResponsePublisher
+
+	// EnforceMonotonicNonce causes requests to be rejected with BadNonce unless the nonce is greater than the last one accepted from the same signer.
+	// Any uint32 nonce is valid, including zero, so a signer that has used math.MaxUint32 can not submit any more requests unless NonceWrapWindow is set.
+	EnforceMonotonicNonce bool
+
+	// NonceWrapWindow, if non-zero, lets the nonces of a signer wrap around when EnforceMonotonicNonce is set, which suits requesters that derive
+	// their nonces from timestamps or counters that overflow. A nonce is then accepted if it is at most this far ahead of the last one accepted
+	// from the signer, counting past math.MaxUint32 back to zero, so a nonce that jumps further ahead is rejected with BadNonce. It may not be
+	// greater than 2^31, since beyond that a nonce slightly behind the last one would count as ahead of it.
+	NonceWrapWindow uint32
+
+	// MaxNonceTrackedSigners, if non-zero, bounds the number of signers whose last nonce is tracked when EnforceMonotonicNonce is set. To make room
+	// for a new signer, the least recently used signers are evicted once their last request is older than NonceReplayWindow, after which a replay
+	// of their earlier requests is no longer detected. If every tracked signer is still within the window, requests from a new signer are rejected
+	// with NonceTrackerFull. NonceReplayWindow must be set.
+	MaxNonceTrackedSigners int
+	NonceReplayWindow      time.Duration
+
+	// ReplayCacheSize is the maximum number of recently accepted requests whose request ID is remembered, so that a resubmission of the same
+	// signed request is rejected with ReplayedRequest. Unlike EnforceMonotonicNonce, this is always enabled, since it never rejects a different
+	// request, even one with the same signer and nonce. Once the cache is full, the oldest entries are evicted. Zero means DefaultReplayCacheSize.
+	// ReplayCacheWindow is how long an entry is remembered, and zero means DefaultReplayCacheWindow.
+	ReplayCacheSize   int
+	ReplayCacheWindow time.Duration
+
+	// LocalSink, if set, must accept every query response in addition to p2p. A publication is only complete once both have accepted it.
+	// Whichever one fails is retried each audit interval until the request times out.
+	LocalSink ResponseSink
+
+	// FailureC, if set, is sent a QueryFailure for each rejected request that has a FailureReason. Sends to it never block.
+	FailureC chan<- *QueryFailure
+
+	// FailoverChainQueryReqC lists, for each chain, additional watchers in failover order. Queries are always sent to the primary
+	// watcher first. They are only sent to the next watcher in the list if the previous one returns a fatal error.
+	FailoverChainQueryReqC map[vaa.ChainID][]chan *PerChainQueryInternal
+
+	// RotateWatchersOnTimeout lists the chains whose queries are sent to the next watcher in FailoverChainQueryReqC when they are retried because
+	// the current watcher received the query but did not respond within the retry interval, so that a stuck provider is routed around. The watcher that timed out is moved
+	// to the end of the list, so every watcher is tried in turn. It has no effect on chains without failover watchers.
+	RotateWatchersOnTimeout map[vaa.ChainID]struct{}
+
+	// QueryTypeFlags, if set, is checked for each per chain query. Requests containing a query type that is disabled on that chain are rejected
+	// with QueryTypeDisabled. It may be updated while the handler is running.
+	QueryTypeFlags *QueryTypeFlags
+
+	// BandwidthQuotaBytes, if non-zero, is the number of response bytes that may be served to a single signer per BandwidthQuotaWindow. Once a signer
+	// has been served that many bytes, its requests are rejected with BandwidthQuotaExceeded until its window expires. BandwidthQuotaWindow must be set.
+	BandwidthQuotaBytes  uint64
+	BandwidthQuotaWindow time.Duration
+
+	// NearDuplicateThreshold, if non-zero, is the number of near duplicate requests a single signer may send per NearDuplicateWindow. Requests are near
+	// duplicates if they target the same chains, query types, contracts and functions, even if the rest of the call data differs. Once a signer reaches
+	// the threshold, further near duplicates are rejected with NearDuplicateFlood until the earlier ones age out of the window. NearDuplicateWindow must be set.
+	NearDuplicateThreshold int
+	NearDuplicateWindow    time.Duration
+
+	// RequireAllChainsWatched causes every chain in a request to be checked before anything is dispatched. If any of them do not have a watcher,
+	// the whole request is rejected with ChainsNotWatched, listing all of the missing chains.
+	RequireAllChainsWatched bool
+
+	// MaxTotalCalls, if non-zero, is the maximum number of calls allowed across all of the per chain queries in a single request.
+	// Requests with more calls are rejected with TooManyCalls. See QueryRequest.TotalCalls.
+	MaxTotalCalls int
+
+	// CoalesceIdenticalRequests causes a request whose payload is byte-identical to one from another signer that is already in flight to be
+	// published with the results of that request, rather than being dispatched to the watchers again. Each requester still gets a response
+	// over its own signed request. If the request being followed is dropped without being answered, the coalesced requests are dispatched on
+	// their own at the next audit.
+	CoalesceIdenticalRequests bool
+
+	// CoalesceIdenticalPerChainQueries causes a per chain query that is identical to one of another request that is already in flight to be
+	// answered with the response to that query, rather than being dispatched to the watchers again, even if the requests differ otherwise.
+	// If the per chain query being followed fails or its request is dropped, the coalesced queries are dispatched on their own at the next audit.
+	CoalesceIdenticalPerChainQueries bool
+
+	// RequestLimits limits the number of per chain queries in a request, the number of calls in each of them, and the size of the serialized
+	// request. Requests exceeding them are rejected with TooManyPerChainQueries, TooManyCalls or RequestTooLarge. See QueryRequest.ValidateLimits.
+	RequestLimits RequestLimits
+
+	// MaxCallDataSize, if set, is the maximum size in bytes of the data of a single call on each chain. Requests with a larger call are rejected
+	// with CallDataTooLarge. See ParseCallDataLimits.
+	MaxCallDataSize CallDataLimits
+
+	// RequestTimeouts, if set, overrides the global request timeout for the per chain queries on the listed chains, so that a request including a
+	// slow chain is not dropped before it can answer, without extending the timeout of fast chains. See ParseRequestTimeouts.
+	RequestTimeouts RequestTimeouts
+
+	// RequestTimeoutPerCall, if non-zero, extends the timeout of each per chain query by this much for every call after the first, so that per chain
+	// queries with many calls are not dropped before a watcher can reasonably answer them. The extended timeout is capped at MaxScaledRequestTimeout,
+	// which must be set, but is never less than the timeout for the chain. See PerChainQueryRequest.NumCalls.
+	RequestTimeoutPerCall   time.Duration
+	MaxScaledRequestTimeout time.Duration
+
+	// MaxRetryInterval is the ceiling of the exponential backoff between timed retries of a per chain query. The first retry waits for the
+	// retry interval, and each one after that waits twice as long as the previous one, plus jitter, until the ceiling is reached. The backoff
+	// is reset when the watcher returns a successful response that still needs to be retried. Zero means DefaultMaxRetryInterval.
+	MaxRetryInterval time.Duration
+
+	// DefaultRetryBudget, if non-zero, is the number of times each per chain query is retried before the handler stops retrying it and lets the request
+	// time out. It is used when the request does not specify its own retry budget. Zero means retry until the request times out.
+	DefaultRetryBudget uint
+
+	// MaxRetryBudget, if non-zero, caps the retry budget, including one specified in the request. It must not be less than DefaultRetryBudget.
+	MaxRetryBudget uint
+
+	// CancelSignerC, if set, allows an operator to cancel all of the in-flight requests from a signer, such as a compromised requester, in one action.
+	// For each address read from it, those requests are dropped and a Cancelled failure is published for each of them.
+	CancelSignerC <-chan ethCommon.Address
+
+	// CachedResultMaxAge, if non-zero, enables caching of the most recent successful response to each per chain query. If a watcher returns a fatal
+	// error for a request that sets AllowCachedResults, and a cached response no older than this exists, it is served instead, with ServedFromCache
+	// set in the response metadata.
+	CachedResultMaxAge time.Duration
+
+	// PreloadC, if set, allows an operator to warm the result cache with known queries during off-peak times. Each request read from it is
+	// executed like a received request, but is never signed or published. Its results are cached, and until they are older than CachedResultMaxAge,
+	// identical per chain queries in received requests are answered from the cache without being dispatched to the watchers. Only eth_call queries
+	// can be preloaded, and they should be for finalized blocks. It requires CachedResultMaxAge to be set.
+	PreloadC <-chan *QueryRequest
+
+	// IncludeReceiveTime causes the time the handler first saw each request, and the time its response was assembled, to be included in
+	// the response metadata, so latency can be attributed across systems. The receive time is always logged.
+	IncludeReceiveTime bool
+
+	// CancelOnFatalError causes the per chain queries of a request to be cancelled as soon as one of them fails fatally, since the request can no
+	// longer succeed. Watchers stop working on the cancelled queries, including any that are still waiting for a worker. The per chain queries of
+	// requests that are dropped for any other reason, such as a timeout or being cancelled using CancelSignerC, are cancelled the same way.
+	CancelOnFatalError bool
+
+	// ResponseSigner, if set, is used to sign the query responses published over p2p. Otherwise they are signed with the guardian key
+	// using secp256k1. See NewResponseSigner.
+	ResponseSigner ResponseSigner
+
+	// GossipStatus, if set, is registered with by the p2p layer, and is used to record the response topic and the number of peers on it in the
+	// metadata of each response as it is published. See ResponseMetadata.Gossip.
+	GossipStatus *GossipStatus
+
+	// PeerAgreement, if set, is fed by the p2p layer with the responses other guardians publish, and is used to record in the metadata of
+	// each response how many of them were seen publishing the same response. See ResponseMetadata.MatchingPeers.
+	PeerAgreement *PeerAgreement
+
+	// ResponseBatchWindow, if non-zero, causes the responses that become ready within this window of each other to be published to p2p as a single
+	// batched message, rather than one message each, to reduce chattiness under high throughput. Each response in the batch keeps its own signature.
+	// Consumers must use UnbatchResponses to read them, so it should only be enabled once they support batches. Batches are also limited to
+	// MaxResponseBatchBytes, see SplitResponseBatch and MarshalResponseBatch.
+	ResponseBatchWindow time.Duration
+
+	// GuardianAddress, if set, is the address of the guardian key, and GuardianSetState is the guardian set state of the node. They are filled in
+	// by the node so that each response can identify the guardian that produced it. See ResponseMetadata.GuardianAddress.
+	GuardianAddress  ethCommon.Address
+	GuardianSetState *common.GuardianSetState
+
+	// SlaQueryTimeEstimate, if non-zero, is the expected time for a watcher worker to answer a single per chain query. Before the per chain
+	// queries of a request with an SlaTier are dispatched, including on a retry, the handler uses it and the number of queries already awaiting
+	// a response on each chain to estimate when they will be answered. If that is after the deadline of the tier, the request is failed fast
+	// with SlaCannotBeMet. Zero disables the check, so the tier only affects the order in which retries are dispatched.
+	SlaQueryTimeEstimate time.Duration
+
+	// SignerLogLevels, if set, overrides the log level used while processing the requests of the listed signers. See SignerLogLevels.
+	SignerLogLevels SignerLogLevels
+
+	// EnforceRequestEnvironment causes requests that declare an environment other than the one the guardian is running in to be rejected
+	// with WrongEnvironment. That includes requests signed for the declared environment, which would otherwise be dropped as coming from an
+	// unknown signer. Requests that do not declare an environment are not affected.
+	EnforceRequestEnvironment bool
+
+	// RequestPricer and BalanceProvider, if set, are used by operators running a paid service. Once a request has passed validation and the
+	// other checks, its price is computed and compared to the balance of the signer. Requests the signer cannot afford are rejected with
+	// InsufficientBalance. The price of the request is charged when it is dispatched, and the price of its response when that is published.
+	// They must be set together.
+	RequestPricer   RequestPricer
+	BalanceProvider BalanceProvider
+
+	// SystemAddresses, if set, lists addresses on each chain that may not be the target of a call, such as precompiles. Requests with a call to
+	// one of them are rejected with SystemAddressNotQueryable. See ParseSystemAddresses.
+	SystemAddresses SystemAddresses
+
+	// DetectIdenticalCalls causes the handler to look for calls that a request makes, with the same target and call data, on more than one chain.
+	// Each one is logged as a single consolidated entry listing the chains, and counted in a metric. The per chain queries are still executed
+	// independently. See IdenticalCalls.
+	DetectIdenticalCalls bool
+
+	// RejectDuplicateCallData causes requests where a single per chain query contains the same call, with the same target and call data, more than
+	// once to be rejected with DuplicateCallData, since that is most likely a bug in the requester. See DuplicateCall.
+	RejectDuplicateCallData bool
+
+	// PostPublishHooks, if set, are called in order with each query response after it has been published to p2p. They are called in their own
+	// routine, so they never block the handler. A hook that panics is logged and skipped.
+	PostPublishHooks []PostPublishHook
+
+	// DeferWhileNodeSyncing causes per chain queries that a watcher reports cannot be answered because its node is still syncing to be retried
+	// each retry interval until the request times out, rather than the request being rejected with NodeSyncing straight away.
+	DeferWhileNodeSyncing bool
+
+	// IncludeRetryIntervals causes the intervals the handler actually waited before each timed retry of a per chain query to be included in
+	// the response metadata, so requesters debugging latency can see the retry schedule that was applied. See PerChainResponseMetadata.RetryIntervals.
+	IncludeRetryIntervals bool
+
+	// IncludePublishAttempts causes the number of attempts the handler needed to publish each response to p2p to be included in the response
+	// metadata. The attempts are always counted in a metric. See ResponseMetadata.PublishAttempts.
+	IncludePublishAttempts bool
+
+	// ChainWeights, if set, determines the order in which per chain queries are dispatched to the watchers, both when a request is received and
+	// when queries are retried. Queries for chains with a higher weight are dispatched first. See ChainWeights.
+	ChainWeights ChainWeights
+
+	// LoadSignal, if set, is checked as each request is received. While it reports CPU utilization of at least MaxCpuLoad, or memory utilization
+	// of at least MaxMemoryLoad, new requests are rejected with Overloaded. Requests that were already accepted continue to be processed.
+	// A threshold of zero is not checked.
+	LoadSignal    LoadSignal
+	MaxCpuLoad    float64
+	MaxMemoryLoad float64
+
+	// KeyRotationC, if set, signals when a rotation of the key used to sign responses starts (true) and completes (false). While a rotation is in
+	// progress, new requests are rejected with KeyRotationInProgress, so they are not answered with a key that is about to be retired. Requests that
+	// were already accepted are still processed.
+	KeyRotationC <-chan bool
+
+	// SignerAvailability, if set, is checked as each request is received. While it reports that the key used to sign responses can not be
+	// accessed, new requests are rejected with SigningUnavailable, rather than being processed only to hang waiting for a signature. They are
+	// accepted again as soon as it reports that the key is available. If it is not set, but ResponseSigner implements SignerAvailability,
+	// the response signer is checked.
+	SignerAvailability SignerAvailability
+
+	// WatcherRegistrationGracePeriod, if non-zero, is how long a request that targets a chain whose watcher has not registered yet is held,
+	// rather than being rejected straight away. This covers requests that arrive while a watcher is still starting up. If the watchers of all
+	// of its chains register within the period, using QueryHandler.RegisterWatcher, the request is then processed as if it had just arrived.
+	// Otherwise it is rejected with ChainsNotWatched. Only chains that support queries are waited for. When it is set, the guardian registers
+	// each watcher as it starts, rather than creating the handler with all of them.
+	WatcherRegistrationGracePeriod time.Duration
+
+	// MaxResponsePublicationSize, if non-zero, is the maximum size in bytes of a serialized query response. If the response assembled for a
+	// request would be larger, the request is rejected with ResponseTooLarge rather than publishing an oversized message to p2p.
+	MaxResponsePublicationSize int
+
+	// LegacyRequestDigest, if set, is an earlier digest scheme that requesters may still be signing while they migrate to the current one. Until
+	// LegacyRequestDigestUntil, which must then be set, a request whose signature does not recover to an allowed requestor using the current
+	// digest is also checked against the legacy digest. Each request accepted that way is logged and counted in a metric, so operators can
+	// tell when the migration is complete. After that time, only the current digest is accepted.
+	LegacyRequestDigest      RequestDigestScheme
+	LegacyRequestDigestUntil time.Time
+
+	// AllowedRequestersFile, if set, is a file containing the allowed requesters list, which is used instead of the list passed to NewQueryHandler.
+	// Each time ReloadAllowedRequestersC is signaled, such as on SIGHUP, the file is read again and replaces the list, so it can be changed without
+	// restarting the guardian. If the new list cannot be read or is invalid, the error is logged and the current list is kept. See ReadAllowedRequestersFile.
+	AllowedRequestersFile    string
+	ReloadAllowedRequestersC <-chan os.Signal
+
+	// allowedRequestorsUpdateC is created by NewQueryHandler. It is used by QueryHandler.UpdateAllowedRequesters.
+	allowedRequestorsUpdateC <-chan allowedRequestersUpdate
+
+	// requesterRestrictions is set by QueryHandler.Start from the restrictions in the allowed requesters list.
+	requesterRestrictions requesterRestrictions
+
+	// watcherRegistrationC is created by NewQueryHandler. It is used by QueryHandler.RegisterWatcher.
+	watcherRegistrationC <-chan watcherRegistration
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+)
+
+// FailureReason indicates why a query request was rejected. The values are also used as metric labels.
+type FailureReason string
+
+const (
+	// BadNonce means the nonce was not greater than the last one accepted from the same signer, or, if nonces may wrap around, was not
+	// within the wrap window ahead of it.
+	BadNonce FailureReason = "bad_nonce"
+
+	// ReplayedRequest means the same signed request was accepted within the replay cache window. See HandlerConfig.ReplayCacheSize.
+	ReplayedRequest FailureReason = "replayed_request"
+
+	// QueryTypeDisabled means one of the per chain queries uses a query type that is currently disabled on that chain.
+	QueryTypeDisabled FailureReason = "query_type_disabled"
+
+	// RateLimited means the signer has a requests per second limit in the allowed requesters list, and has already used it up.
+	RateLimited FailureReason = "rate_limited"
+
+	// QueryTypeNotPermitted means the signer is restricted to certain query types in the allowed requesters list, and one of the per chain
+	// queries uses a different one.
+	QueryTypeNotPermitted FailureReason = "query_type_not_permitted"
+
+	// BandwidthQuotaExceeded means the signer has already been served its quota of response bytes for the current window.
+	BandwidthQuotaExceeded FailureReason = "bandwidth_quota_exceeded"
+
+	// ChainsNotWatched means the request targets one or more chains that do not have a watcher. MissingChains lists them.
+	ChainsNotWatched FailureReason = "chains_not_watched"
+
+	// NearDuplicateFlood means the signer has already sent the configured number of near duplicates of this request in the current window.
+	NearDuplicateFlood FailureReason = "near_duplicate_flood"
+
+	// TooManyCalls means the total number of calls across all of the per chain queries in the request, or the number of calls in a single
+	// per chain query, exceeds the configured maximum.
+	TooManyCalls FailureReason = "too_many_calls"
+
+	// TooManyPerChainQueries means the request contains more per chain queries than the configured maximum.
+	TooManyPerChainQueries FailureReason = "too_many_per_chain_queries"
+
+	// RequestTooLarge means the serialized request is larger than the configured maximum.
+	RequestTooLarge FailureReason = "request_too_large"
+
+	// WatcherGone means the channel to the watcher for one of the per chain queries was closed, and there were no failover watchers left.
+	// MissingChains lists the chain.
+	WatcherGone FailureReason = "watcher_gone"
+
+	// Cancelled means the request was still in flight when an operator cancelled all requests from its signer.
+	Cancelled FailureReason = "cancelled"
+
+	// SlaCannotBeMet means the request has an SLA tier whose deadline cannot be met, given the work already waiting on the watchers.
+	SlaCannotBeMet FailureReason = "sla_cannot_be_met"
+
+	// WrongEnvironment means the request declares that it is intended for a different environment than the one the guardian is running in.
+	WrongEnvironment FailureReason = "wrong_environment"
+
+	// InsufficientBalance means the balance of the signer is less than the price of the request.
+	InsufficientBalance FailureReason = "insufficient_balance"
+
+	// BalanceUnavailable means the balance of the signer could not be looked up, so the request could not be priced.
+	BalanceUnavailable FailureReason = "balance_unavailable"
+
+	// NonceTrackerFull means monotonic nonces are enforced, the signer is not yet tracked, and every tracked signer is still within the replay window.
+	NonceTrackerFull FailureReason = "nonce_tracker_full"
+
+	// SystemAddressNotQueryable means one of the calls in the request targets an address that is configured as a system address on its chain.
+	SystemAddressNotQueryable FailureReason = "system_address_not_queryable"
+
+	// NotFound means a watcher reported that the block or account queried by one of the per chain queries does not exist. NotFoundQuery identifies it.
+	NotFound FailureReason = "not_found"
+
+	// NodeSyncing means a watcher reported that the node for one of the chains is still syncing, so the state needed by the request may not be
+	// available yet. MissingChains lists the chain.
+	NodeSyncing FailureReason = "node_syncing"
+
+	// Overloaded means the load signal reported CPU or memory utilization above the configured threshold when the request was received.
+	Overloaded FailureReason = "overloaded"
+
+	// DuplicateCallData means one of the per chain queries contains the same call, with the same target and call data, more than once.
+	DuplicateCallData FailureReason = "duplicate_call_data"
+
+	// KeyRotationInProgress means the request was received while the key used to sign responses was being rotated.
+	KeyRotationInProgress FailureReason = "key_rotation_in_progress"
+
+	// CallDataTooLarge means one of the calls in the request has more data than the configured maximum for its chain.
+	CallDataTooLarge FailureReason = "call_data_too_large"
+
+	// SigningUnavailable means the request was received while the key used to sign responses could not be accessed, such as when a remote signer is down.
+	SigningUnavailable FailureReason = "signing_unavailable"
+
+	// RequestUnmarshalFailed means the request could not be unmarshaled, including when a per chain query fails validation while being read,
+	// such as an eth_storage query without any slots.
+	RequestUnmarshalFailed FailureReason = "failed_to_unmarshal_request"
+
+	// InvalidRequest means the request was unmarshaled but failed validation.
+	InvalidRequest FailureReason = "invalid_request"
+
+	// ResponseTooLarge means every per chain query was answered, but the serialized response would be larger than the configured maximum.
+	ResponseTooLarge FailureReason = "response_too_large"
+)
+
+// QueryFailure is published when a query request is rejected by the handler.
+type QueryFailure struct {
+	RequestID string
+	Signer    ethCommon.Address
+	Reason    FailureReason
+
+	// MissingChains is only populated when the reason is ChainsNotWatched, WatcherGone or NodeSyncing.
+	MissingChains []vaa.ChainID
+
+	// NotFoundQuery is only populated when the reason is NotFound.
+	NotFoundQuery *NotFoundQuery
+}
+
+// NotFoundQuery identifies the per chain query whose block or account does not exist.
+type NotFoundQuery struct {
+	RequestIdx int
+	ChainId    vaa.ChainID
+
+	// Error describes what was not found, if the watcher reported it.
+	Error string
+
+	// RpcError is the error code and message returned by the RPC node, if the watcher reported one.
+	RpcError *RpcError
+}
+
+// reportFailure pegs the invalid request metric for the specified reason and, if a failure channel is configured, publishes the failure to it.
+// Publishing the failure never blocks. If the channel is full, the failure is dropped.
+func reportFailure(qLogger *zap.Logger, failureC chan<- *QueryFailure, requestID string, signer ethCommon.Address, reason FailureReason) {
+	publishFailure(qLogger, failureC, &QueryFailure{RequestID: requestID, Signer: signer, Reason: reason})
+}
+
+// publishFailure is the same as reportFailure, but allows the caller to populate the optional fields of the failure.
+func publishFailure(qLogger *zap.Logger, failureC chan<- *QueryFailure, failure *QueryFailure) {
+	invalidQueryRequestReceived.WithLabelValues(string(failure.Reason)).Inc()
+	if failureC == nil {
+		return
+	}
+
+	select {
+	case failureC <- failure:
+	default:
+		qLogger.Warn("failed to publish query failure, dropping it", zap.String("requestID", failure.RequestID), zap.String("reason", string(failure.Reason)))
+	}
+}
@@ -0,0 +1,49 @@
+package query
+
+import (
+	"sync"
+)
+
+// GossipMetadata describes how a query response was gossiped. See ResponseMetadata.Gossip.
+type GossipMetadata struct {
+	// Topic is the pubsub topic the response was published on.
+	Topic string
+
+	// NumPeers is the number of peers on the topic when the response was published.
+	NumPeers int
+}
+
+// GossipStatus is shared between the query handler and the p2p layer, so the handler can record how each response is gossiped for network
+// debugging. The p2p layer registers the response topic once it has joined it. It is safe for concurrent use.
+type GossipStatus struct {
+	mutex    sync.Mutex
+	topic    string
+	numPeers func() int
+}
+
+// NewGossipStatus creates a gossip status that has not been registered yet.
+func NewGossipStatus() *GossipStatus {
+	return &GossipStatus{}
+}
+
+// Register is called by the p2p layer with the topic that query responses are published on, and a function returning the current number of peers on it.
+func (gs *GossipStatus) Register(topic string, numPeers func() int) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.topic = topic
+	gs.numPeers = numPeers
+}
+
+// metadata returns the current gossip metadata, or nil if the p2p layer has not registered yet. It may be called on a nil object.
+func (gs *GossipStatus) metadata() *GossipMetadata {
+	if gs == nil {
+		return nil
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if gs.numPeers == nil {
+		return nil
+	}
+	return &GossipMetadata{Topic: gs.topic, NumPeers: gs.numPeers()}
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// HashAlgorithm is the hash function a requester asks the guardian to use for any hashing done while assembling the response, such as the
+// Merkle root of the per chain responses, so it matches what the consumer can verify cheaply. It is carried in the query request, so it is
+// covered by the request signature and by the response digest. It does not affect the response digest itself, which is fixed by the signing scheme.
+type HashAlgorithm uint8
+
+const (
+	// Keccak256HashAlgorithm is the default.
+	Keccak256HashAlgorithm HashAlgorithm = 0
+
+	// Sha256HashAlgorithm uses sha256.
+	Sha256HashAlgorithm HashAlgorithm = 1
+)
+
+// Validate verifies that the algorithm is one that is supported.
+func (h HashAlgorithm) Validate() error {
+	switch h {
+	case Keccak256HashAlgorithm, Sha256HashAlgorithm:
+		return nil
+	default:
+		return fmt.Errorf("unsupported hash algorithm: %d", h)
+	}
+}
+
+// String returns the name of the algorithm.
+func (h HashAlgorithm) String() string {
+	switch h {
+	case Keccak256HashAlgorithm:
+		return "keccak256"
+	case Sha256HashAlgorithm:
+		return "sha256"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(h))
+	}
+}
+
+// Hash returns the hash of the concatenation of the data using the algorithm.
+func (h HashAlgorithm) Hash(data ...[]byte) common.Hash {
+	if h == Sha256HashAlgorithm {
+		hasher := sha256.New()
+		for _, b := range data {
+			hasher.Write(b)
+		}
+		return common.BytesToHash(hasher.Sum(nil))
+	}
+	return crypto.Keccak256Hash(data...)
+}
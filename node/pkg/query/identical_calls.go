@@ -0,0 +1,121 @@
+package query
+
+import (
+	"encoding/hex"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+)
+
+// IdenticalCallGroup is a call, identified by its target and call data, that a request makes on more than one chain.
+type IdenticalCallGroup struct {
+	To   []byte
+	Data []byte
+
+	// Calls lists every occurrence of the call in the request, in request order.
+	Calls []IdenticalCallRef
+}
+
+// IdenticalCallRef identifies a single call within a request.
+type IdenticalCallRef struct {
+	ChainId    vaa.ChainID
+	RequestIdx int
+	CallIdx    int
+}
+
+// numChains returns the number of distinct chains the call is made on.
+func (g *IdenticalCallGroup) numChains() int {
+	chains := make(map[vaa.ChainID]struct{})
+	for _, ref := range g.Calls {
+		chains[ref.ChainId] = struct{}{}
+	}
+	return len(chains)
+}
+
+// chainNames returns the names of the chains the call is made on, in request order, for logging.
+func (g *IdenticalCallGroup) chainNames() []string {
+	names := []string{}
+	seen := make(map[vaa.ChainID]struct{})
+	for _, ref := range g.Calls {
+		if _, exists := seen[ref.ChainId]; !exists {
+			seen[ref.ChainId] = struct{}{}
+			names = append(names, ref.ChainId.String())
+		}
+	}
+	return names
+}
+
+// IdenticalCalls returns the calls that the request makes, with the same target and call data, on more than one chain, in the order
+// each first appears. Calls that are only repeated on a single chain are not included. The per chain queries are still executed
+// independently, so each chain answers the call against its own state.
+func IdenticalCalls(queryRequest *QueryRequest) []*IdenticalCallGroup {
+	order := []ethCommon.Hash{}
+	groups := make(map[ethCommon.Hash]*IdenticalCallGroup)
+	for requestIdx, pcq := range queryRequest.PerChainQueries {
+		q, ok := pcq.Query.(interface{ CallDataList() []*EthCallData })
+		if !ok {
+			continue
+		}
+
+		for callIdx, cd := range q.CallDataList() {
+			// The target has a fixed length, so the key is unambiguous.
+			key := ethCrypto.Keccak256Hash(cd.To, cd.Data)
+			group, exists := groups[key]
+			if !exists {
+				group = &IdenticalCallGroup{To: cd.To, Data: cd.Data}
+				groups[key] = group
+				order = append(order, key)
+			}
+			group.Calls = append(group.Calls, IdenticalCallRef{ChainId: pcq.ChainId, RequestIdx: requestIdx, CallIdx: callIdx})
+		}
+	}
+
+	ret := []*IdenticalCallGroup{}
+	for _, key := range order {
+		if groups[key].numChains() > 1 {
+			ret = append(ret, groups[key])
+		}
+	}
+	return ret
+}
+
+// DuplicateCall returns the indexes of the first call in a per chain query that has the same target and call data as an earlier call in the
+// same query, along with that earlier call. It returns false if every call is unique, or if the query type does not have calls.
+func DuplicateCall(pcq *PerChainQueryRequest) (int, int, bool) {
+	q, ok := pcq.Query.(interface{ CallDataList() []*EthCallData })
+	if !ok {
+		return 0, 0, false
+	}
+
+	seen := make(map[ethCommon.Hash]int)
+	for callIdx, cd := range q.CallDataList() {
+		// The target has a fixed length, so the key is unambiguous.
+		key := ethCrypto.Keccak256Hash(cd.To, cd.Data)
+		if firstIdx, exists := seen[key]; exists {
+			return firstIdx, callIdx, true
+		}
+		seen[key] = callIdx
+	}
+	return 0, 0, false
+}
+
+// logIdenticalCalls logs a consolidated view of the calls the request makes on more than one chain, and returns how many there are.
+func logIdenticalCalls(qLogger *zap.Logger, requestID string, queryRequest *QueryRequest) int {
+	groups := IdenticalCalls(queryRequest)
+	for _, group := range groups {
+		selector := group.Data
+		if len(selector) > 4 {
+			selector = selector[:4]
+		}
+		qLogger.Info("request makes the same call on multiple chains",
+			zap.String("requestID", requestID),
+			zap.String("to", hex.EncodeToString(group.To)),
+			zap.String("selector", hex.EncodeToString(selector)),
+			zap.Strings("chains", group.chainNames()),
+			zap.Int("numCalls", len(group.Calls)),
+		)
+	}
+	return len(groups)
+}
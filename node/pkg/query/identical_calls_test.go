@@ -0,0 +1,118 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createIdenticalCallQueries creates eth_call queries on polygon and bsc, where the second call on polygon is the same as the first call on bsc.
+func createIdenticalCallQueries(t *testing.T) []*PerChainQueryRequest {
+	t.Helper()
+	polygonQuery := createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)
+	bscQuery := createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 2)
+	polygonCallData := polygonQuery.Query.(*EthCallQueryRequest).CallData
+	bscCallData := bscQuery.Query.(*EthCallQueryRequest).CallData
+	bscCallData[0] = &EthCallData{To: polygonCallData[1].To, Data: polygonCallData[1].Data}
+	return []*PerChainQueryRequest{polygonQuery, bscQuery}
+}
+
+func TestIdenticalCallsAcrossChains(t *testing.T) {
+	perChainQueries := createIdenticalCallQueries(t)
+
+	// Repeat both polygon calls on another block. The first one is still only made on a single chain, so it is not included.
+	perChainQueries = append(perChainQueries, createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2))
+
+	groups := IdenticalCalls(&QueryRequest{PerChainQueries: perChainQueries})
+	require.Equal(t, 1, len(groups))
+	assert.Equal(t, perChainQueries[0].Query.(*EthCallQueryRequest).CallData[1].To, groups[0].To)
+	assert.Equal(t, perChainQueries[0].Query.(*EthCallQueryRequest).CallData[1].Data, groups[0].Data)
+	assert.Equal(t, []IdenticalCallRef{
+		{ChainId: vaa.ChainIDPolygon, RequestIdx: 0, CallIdx: 1},
+		{ChainId: vaa.ChainIDBSC, RequestIdx: 1, CallIdx: 0},
+		{ChainId: vaa.ChainIDPolygon, RequestIdx: 2, CallIdx: 1},
+	}, groups[0].Calls)
+
+	// Query types without calls are ignored.
+	assert.Empty(t, IdenticalCalls(createSolanaAccountQueryRequestForTesting(t)))
+}
+
+func TestIdenticalCallsAcrossChainsAreExecutedIndependently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	observedCore, observedLogs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(observedCore)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{DetectIdenticalCalls: true})
+
+	// Each chain returns its own result for the identical call.
+	perChainQueries := createIdenticalCallQueries(t)
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	expectedResults[1].Response.(*EthCallQueryResponse).Results[0] = []byte("bsc specific result")
+	require.NotEqual(t, expectedResults[0].Response.(*EthCallQueryResponse).Results[1], expectedResults[1].Response.(*EthCallQueryResponse).Results[0])
+	md.setExpectedResults(expectedResults)
+
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	// The identical call was logged once, listing both chains.
+	entries := observedLogs.FilterMessage("request makes the same call on multiple chains").All()
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, []interface{}{vaa.ChainIDPolygon.String(), vaa.ChainIDBSC.String()}, entries[0].ContextMap()["chains"])
+}
+
+func TestDuplicateCall(t *testing.T) {
+	perChainQuery := createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 3)
+	_, _, found := DuplicateCall(perChainQuery)
+	assert.False(t, found)
+
+	// The same target with different call data is not a duplicate.
+	callData := perChainQuery.Query.(*EthCallQueryRequest).CallData
+	callData[2] = &EthCallData{To: callData[0].To, Data: []byte("different call data")}
+	_, _, found = DuplicateCall(perChainQuery)
+	assert.False(t, found)
+
+	callData[2] = &EthCallData{To: callData[1].To, Data: callData[1].Data}
+	firstIdx, dupIdx, found := DuplicateCall(perChainQuery)
+	require.True(t, found)
+	assert.Equal(t, 1, firstIdx)
+	assert.Equal(t, 2, dupIdx)
+
+	// Query types without calls never have duplicates.
+	_, _, found = DuplicateCall(createSolanaAccountQueryRequestForTesting(t).PerChainQueries[0])
+	assert.False(t, found)
+}
+
+func TestDuplicateCallDataIsRejectedWhenEnabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{RejectDuplicateCallData: true})
+
+	// The second call on bsc repeats the first one.
+	perChainQuery := createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 2)
+	callData := perChainQuery.Query.(*EthCallQueryRequest).CallData
+	callData[1] = &EthCallData{To: callData[0].To, Data: callData[0].Data}
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2), perChainQuery}
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, DuplicateCallData, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDBSC))
+}
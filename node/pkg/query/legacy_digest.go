@@ -0,0 +1,58 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// RequestDigestScheme identifies an earlier way of computing the digest of a query request, which requesters that have not migrated yet
+// may still sign. See HandlerConfig.LegacyRequestDigest.
+type RequestDigestScheme string
+
+const (
+	// NoLegacyRequestDigest means only the current digest is accepted.
+	NoLegacyRequestDigest RequestDigestScheme = ""
+
+	// RequestDigestWithoutDomains is the digest used before the domain tags of query types were included, which is just the signing prefix
+	// for the environment followed by the request. It only differs from the current digest for requests that use a query type with a digest domain.
+	RequestDigestWithoutDomains RequestDigestScheme = "without_domains"
+)
+
+// Validate verifies that the digest scheme is one that is supported.
+func (s RequestDigestScheme) Validate() error {
+	switch s {
+	case NoLegacyRequestDigest, RequestDigestWithoutDomains:
+		return nil
+	default:
+		return fmt.Errorf("unsupported legacy request digest scheme: %s", s)
+	}
+}
+
+// digest returns the digest of a marshaled query request using this scheme. It must not be called on NoLegacyRequestDigest.
+func (s RequestDigestScheme) digest(env common.Environment, b []byte) ethCommon.Hash {
+	return ethCrypto.Keccak256Hash(append(queryRequestPrefix(env), b...))
+}
+
+// legacyDigestSigner recovers the signer of a request using the legacy digest scheme. It returns the signer and true if it is an allowed
+// requestor, meaning the request was signed over the legacy digest.
+func legacyDigestSigner(
+	scheme RequestDigestScheme,
+	env common.Environment,
+	signedRequest *gossipv1.SignedQueryRequest,
+	allowedRequestors map[ethCommon.Address]struct{},
+) (ethCommon.Address, bool) {
+	digest := scheme.digest(env, signedRequest.QueryRequest)
+	signerBytes, err := ethCrypto.Ecrecover(digest.Bytes(), signedRequest.Signature)
+	if err != nil {
+		return ethCommon.Address{}, false
+	}
+
+	signer := ethCommon.BytesToAddress(ethCrypto.Keccak256(signerBytes[1:])[12:])
+	_, allowed := allowedRequestors[signer]
+	return signer, allowed
+}
@@ -0,0 +1,76 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createLegacySignedQueryRequestForTesting creates a request using a query type with a digest domain, so that its legacy digest differs from
+// the current one, and signs it over the legacy digest.
+func createLegacySignedQueryRequestForTesting(t *testing.T, md *mockData) *gossipv1.SignedQueryRequest {
+	t.Helper()
+	registerMockDomainQueryTypes(t)
+
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:           nonce,
+		PerChainQueries: []*PerChainQueryRequest{{ChainId: vaa.ChainIDPolygon, Query: &mockDomainQuery{mockCustomQuery{Value: 42}, mockDomainQueryTypeA}}},
+	}
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	legacyDigest := RequestDigestWithoutDomains.digest(common.UnsafeDevNet, queryRequestBytes)
+	require.NotEqual(t, QueryRequestDigest(common.UnsafeDevNet, queryRequestBytes), legacyDigest)
+	sig, err := ethCrypto.Sign(legacyDigest.Bytes(), md.sk)
+	require.NoError(t, err)
+
+	md.setExpectedResults([]PerChainQueryResponse{{ChainId: vaa.ChainIDPolygon, Response: &mockDomainQuery{mockCustomQuery{Value: 84}, mockDomainQueryTypeA}}})
+	return &gossipv1.SignedQueryRequest{QueryRequest: queryRequestBytes, Signature: sig}
+}
+
+func TestLegacyDigestIsAcceptedDuringMigrationWindow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		LegacyRequestDigest:      RequestDigestWithoutDomains,
+		LegacyRequestDigestUntil: time.Now().Add(time.Hour),
+	})
+
+	signedQueryRequest := createLegacySignedQueryRequestForTesting(t, md)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, uint32(84), queryResponsePublication.PerChainResponses[0].Response.(*mockDomainQuery).Value)
+}
+
+func TestLegacyDigestIsRejectedAfterMigrationWindow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		LegacyRequestDigest:      RequestDigestWithoutDomains,
+		LegacyRequestDigestUntil: time.Now().Add(-time.Second),
+	})
+
+	signedQueryRequest := createLegacySignedQueryRequestForTesting(t, md)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The request recovers to an unknown signer using the current digest, so it is dropped.
+	assert.Nil(t, md.waitForResponse())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
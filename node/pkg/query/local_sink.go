@@ -0,0 +1,58 @@
+package query
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ResponseSink is the interface that must be implemented by a local store that must accept every query response in addition to p2p.
+// Unlike a ResponsePublisher, a publication is not considered complete until the sink accepts it, and failed stores are retried.
+type ResponseSink interface {
+	// Store stores the query response. It is called from the query handler routine, so it should not block for long.
+	Store(respPub *QueryResponsePublication) error
+}
+
+// FileResponseSink is a ResponseSink that appends each query response to a file as a line of hex, so that the responses can be verified later.
+type FileResponseSink struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewFileResponseSink opens the specified file for appending, creating it if necessary.
+func NewFileResponseSink(path string) (*FileResponseSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("local sink path may not be empty")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local sink file: %w", err)
+	}
+
+	return &FileResponseSink{file: file}, nil
+}
+
+// Store implements the ResponseSink interface. The response is synced to disk before it returns.
+func (fs *FileResponseSink) Store(respPub *QueryResponsePublication) error {
+	bytes, err := respPub.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal query response: %w", err)
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if _, err := fs.file.WriteString(hex.EncodeToString(bytes) + "\n"); err != nil {
+		return fmt.Errorf("failed to write query response: %w", err)
+	}
+
+	return fs.file.Sync()
+}
+
+// Close closes the file.
+func (fs *FileResponseSink) Close() error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.file.Close()
+}
@@ -0,0 +1,41 @@
+package query
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileResponseSinkAppendsResponses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.txt")
+	sink, err := NewFileResponseSink(path)
+	require.NoError(t, err)
+
+	respPub := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+	require.NoError(t, sink.Store(respPub))
+	require.NoError(t, sink.Store(respPub))
+	require.NoError(t, sink.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Equal(t, 2, len(lines))
+
+	respBytes, err := hex.DecodeString(lines[1])
+	require.NoError(t, err)
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(respBytes))
+	assert.True(t, respPub.Equal(&respPub2))
+}
+
+func TestNewFileResponseSinkRequiresPath(t *testing.T) {
+	_, err := NewFileResponseSink("")
+	assert.EqualError(t, err, "local sink path may not be empty")
+}
@@ -0,0 +1,51 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// LogsPageSizes limits the number of blocks an EVM watcher reads with a single eth_getLogs call when answering an eth_logs query, for chains
+// whose RPC providers cap the size of a log response. A longer range is read in pages, which are concatenated in order, so this is transparent
+// to the requester. Chains that are not listed read the whole range with a single call.
+type LogsPageSizes map[vaa.ChainID]uint64
+
+// ParseLogsPageSizes parses a comma separated list of "chain:blocks" entries, such as "polygon:100,bsc:50". The chain is the chain name
+// and the number of blocks must be positive. An empty string returns nil, meaning no chain is paginated.
+func ParseLogsPageSizes(str string) (LogsPageSizes, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	pageSizes := make(LogsPageSizes)
+	for _, entry := range strings.Split(str, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`invalid logs page size "%s", must be "chain:blocks"`, entry)
+		}
+
+		chainID, err := vaa.ChainIDFromString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid chain in logs page size "%s": %w`, entry, err)
+		}
+
+		if _, exists := pageSizes[chainID]; exists {
+			return nil, fmt.Errorf(`duplicate chain in logs page size "%s"`, entry)
+		}
+
+		pageSize, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid number of blocks in logs page size "%s": %w`, entry, err)
+		}
+		if pageSize == 0 {
+			return nil, fmt.Errorf(`number of blocks in logs page size "%s" must be positive`, entry)
+		}
+
+		pageSizes[chainID] = pageSize
+	}
+
+	return pageSizes, nil
+}
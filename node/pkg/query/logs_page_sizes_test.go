@@ -0,0 +1,28 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogsPageSizes(t *testing.T) {
+	pageSizes, err := ParseLogsPageSizes("polygon:100, bsc:50")
+	require.NoError(t, err)
+	assert.Equal(t, LogsPageSizes{vaa.ChainIDPolygon: 100, vaa.ChainIDBSC: 50}, pageSizes)
+	assert.Equal(t, uint64(0), pageSizes[vaa.ChainIDEthereum])
+
+	pageSizes, err = ParseLogsPageSizes("")
+	require.NoError(t, err)
+	assert.Nil(t, pageSizes)
+}
+
+func TestParseLogsPageSizesInvalidEntries(t *testing.T) {
+	for _, str := range []string{"polygon", "polygon:1:2", "notAChain:1", "polygon:notANumber", "polygon:-1", "polygon:0", "polygon:1,polygon:2"} {
+		_, err := ParseLogsPageSizes(str)
+		assert.Error(t, err, str)
+	}
+}
@@ -0,0 +1,144 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The leaves and interior nodes of the response Merkle tree are hashed with different prefixes, so an interior node can never be
+// presented as a leaf.
+const (
+	merkleLeafPrefix byte = 0x00
+	merkleNodePrefix byte = 0x01
+)
+
+// PerChainResponseLeaf returns the Merkle leaf for a per chain response, which is the keccak256 of its serialized form.
+func PerChainResponseLeaf(perChainResponse *PerChainQueryResponse) (common.Hash, error) {
+	return Keccak256HashAlgorithm.PerChainResponseLeaf(perChainResponse)
+}
+
+// PerChainResponseLeaf returns the Merkle leaf for a per chain response, which is the hash of its serialized form using the algorithm.
+func (h HashAlgorithm) PerChainResponseLeaf(perChainResponse *PerChainQueryResponse) (common.Hash, error) {
+	respBytes, err := perChainResponse.Marshal()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to marshal per chain response: %w", err)
+	}
+	return h.Hash([]byte{merkleLeafPrefix}, respBytes), nil
+}
+
+// merkleParent returns the interior node for a pair of children.
+func (h HashAlgorithm) merkleParent(left common.Hash, right common.Hash) common.Hash {
+	return h.Hash([]byte{merkleNodePrefix}, left.Bytes(), right.Bytes())
+}
+
+// merkleNextLevel returns the level of the tree above the specified one. If the level has an odd number of nodes, the last one
+// is carried up unchanged.
+func (h HashAlgorithm) merkleNextLevel(level []common.Hash) []common.Hash {
+	next := make([]common.Hash, 0, (len(level)+1)/2)
+	for idx := 0; idx < len(level); idx += 2 {
+		if idx+1 < len(level) {
+			next = append(next, h.merkleParent(level[idx], level[idx+1]))
+		} else {
+			next = append(next, level[idx])
+		}
+	}
+	return next
+}
+
+// perChainResponseLeaves returns the Merkle leaves for all of the per chain responses in the publication, in order.
+func (msg *QueryResponsePublication) perChainResponseLeaves() ([]common.Hash, error) {
+	if len(msg.PerChainResponses) == 0 {
+		return nil, fmt.Errorf("response does not contain any per chain responses")
+	}
+
+	leaves := make([]common.Hash, 0, len(msg.PerChainResponses))
+	for idx, perChainResponse := range msg.PerChainResponses {
+		leaf, err := msg.HashAlgorithm.PerChainResponseLeaf(perChainResponse)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute leaf for per chain response %d: %w", idx, err)
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves, nil
+}
+
+// MerkleRoot returns the root of a Merkle tree over the per chain responses, in order, using the hash algorithm of the publication.
+func (msg *QueryResponsePublication) MerkleRoot() (common.Hash, error) {
+	level, err := msg.perChainResponseLeaves()
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	for len(level) > 1 {
+		level = msg.HashAlgorithm.merkleNextLevel(level)
+	}
+
+	return level[0], nil
+}
+
+// MerkleProof returns the inclusion proof for the per chain response at the specified index. It is the list of sibling hashes
+// from the leaf up to the root, skipping any level where the node has no sibling.
+func (msg *QueryResponsePublication) MerkleProof(responseIdx int) ([]common.Hash, error) {
+	level, err := msg.perChainResponseLeaves()
+	if err != nil {
+		return nil, err
+	}
+	if responseIdx < 0 || responseIdx >= len(level) {
+		return nil, fmt.Errorf("invalid per chain response index %d, there are %d responses", responseIdx, len(level))
+	}
+
+	proof := []common.Hash{}
+	idx := responseIdx
+	for len(level) > 1 {
+		sibling := idx ^ 1
+		if sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+
+		level = msg.HashAlgorithm.merkleNextLevel(level)
+		idx /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof verifies that the per chain response is at the specified index of a publication with the specified number of
+// per chain responses and the specified Merkle root, computed using keccak256.
+func VerifyMerkleProof(root common.Hash, perChainResponse *PerChainQueryResponse, responseIdx int, numResponses int, proof []common.Hash) bool {
+	return Keccak256HashAlgorithm.VerifyMerkleProof(root, perChainResponse, responseIdx, numResponses, proof)
+}
+
+// VerifyMerkleProof verifies that the per chain response is at the specified index of a publication with the specified number of
+// per chain responses and the specified Merkle root, computed using the algorithm.
+func (h HashAlgorithm) VerifyMerkleProof(root common.Hash, perChainResponse *PerChainQueryResponse, responseIdx int, numResponses int, proof []common.Hash) bool {
+	if responseIdx < 0 || responseIdx >= numResponses {
+		return false
+	}
+
+	node, err := h.PerChainResponseLeaf(perChainResponse)
+	if err != nil {
+		return false
+	}
+
+	idx := responseIdx
+	levelSize := numResponses
+	for levelSize > 1 {
+		sibling := idx ^ 1
+		if sibling < levelSize {
+			if len(proof) == 0 {
+				return false
+			}
+			if idx%2 == 0 {
+				node = h.merkleParent(node, proof[0])
+			} else {
+				node = h.merkleParent(proof[0], node)
+			}
+			proof = proof[1:]
+		}
+		idx /= 2
+		levelSize = (levelSize + 1) / 2
+	}
+
+	return len(proof) == 0 && node == root
+}
@@ -0,0 +1,219 @@
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// createResponsePublicationForMerkleTest creates a publication with the specified number of distinct per chain responses.
+func createResponsePublicationForMerkleTest(numResponses int) *QueryResponsePublication {
+	respPub := &QueryResponsePublication{}
+	for idx := 0; idx < numResponses; idx++ {
+		respPub.PerChainResponses = append(respPub.PerChainResponses, &PerChainQueryResponse{
+			ChainId: vaa.ChainIDPolygon,
+			Response: &EthCallQueryResponse{
+				BlockNumber: uint64(1000 + idx),
+				Hash:        ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				Time:        time.UnixMicro(1699999999000000),
+				Results:     [][]byte{[]byte(fmt.Sprintf("Result %d", idx))},
+			},
+		})
+	}
+	return respPub
+}
+
+func TestMerkleProofsVerifyAgainstRoot(t *testing.T) {
+	for _, numResponses := range []int{1, 2, 3, 4, 5, 7} {
+		respPub := createResponsePublicationForMerkleTest(numResponses)
+		root, err := respPub.MerkleRoot()
+		require.NoError(t, err)
+
+		for idx, perChainResponse := range respPub.PerChainResponses {
+			proof, err := respPub.MerkleProof(idx)
+			require.NoError(t, err)
+			assert.True(t, VerifyMerkleProof(root, perChainResponse, idx, numResponses, proof), "numResponses: %d, idx: %d", numResponses, idx)
+
+			// The proof should not verify the response at any other position.
+			for otherIdx := 0; otherIdx < numResponses; otherIdx++ {
+				if otherIdx != idx {
+					assert.False(t, VerifyMerkleProof(root, perChainResponse, otherIdx, numResponses, proof), "numResponses: %d, idx: %d, otherIdx: %d", numResponses, idx, otherIdx)
+				}
+			}
+		}
+	}
+}
+
+func TestMerkleRootOfSingleResponseIsItsLeaf(t *testing.T) {
+	respPub := createResponsePublicationForMerkleTest(1)
+	root, err := respPub.MerkleRoot()
+	require.NoError(t, err)
+	leaf, err := PerChainResponseLeaf(respPub.PerChainResponses[0])
+	require.NoError(t, err)
+	assert.Equal(t, leaf, root)
+
+	proof, err := respPub.MerkleProof(0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(proof))
+}
+
+func TestMerkleProofRejectsTamperedInput(t *testing.T) {
+	respPub := createResponsePublicationForMerkleTest(5)
+	root, err := respPub.MerkleRoot()
+	require.NoError(t, err)
+	proof, err := respPub.MerkleProof(2)
+	require.NoError(t, err)
+	require.True(t, VerifyMerkleProof(root, respPub.PerChainResponses[2], 2, 5, proof))
+
+	// A modified result.
+	tampered := createResponsePublicationForMerkleTest(5).PerChainResponses[2]
+	tampered.Response.(*EthCallQueryResponse).Results[0] = []byte("Tampered")
+	assert.False(t, VerifyMerkleProof(root, tampered, 2, 5, proof))
+
+	// The wrong number of responses.
+	assert.False(t, VerifyMerkleProof(root, respPub.PerChainResponses[2], 2, 4, proof))
+
+	// A truncated proof and a proof with an extra entry.
+	assert.False(t, VerifyMerkleProof(root, respPub.PerChainResponses[2], 2, 5, proof[:len(proof)-1]))
+	assert.False(t, VerifyMerkleProof(root, respPub.PerChainResponses[2], 2, 5, append(proof, root)))
+
+	// An invalid index.
+	assert.False(t, VerifyMerkleProof(root, respPub.PerChainResponses[2], 5, 5, proof))
+	assert.False(t, VerifyMerkleProof(root, respPub.PerChainResponses[2], -1, 5, proof))
+
+	// An interior node should not be accepted as a leaf.
+	leaves, err := respPub.perChainResponseLeaves()
+	require.NoError(t, err)
+	assert.NotEqual(t, Keccak256HashAlgorithm.merkleParent(leaves[0], leaves[1]), root)
+}
+
+func TestMerkleRootRequiresResponses(t *testing.T) {
+	respPub := createResponsePublicationForMerkleTest(0)
+	_, err := respPub.MerkleRoot()
+	assert.EqualError(t, err, "response does not contain any per chain responses")
+
+	respPub = createResponsePublicationForMerkleTest(3)
+	_, err = respPub.MerkleProof(3)
+	assert.EqualError(t, err, "invalid per chain response index 3, there are 3 responses")
+}
+
+func TestPublishedResponseCanBeVerifiedAgainstMerkleRoot(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 1),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	require.NotNil(t, queryResponsePublication.Metadata)
+	root := queryResponsePublication.Metadata.MerkleRoot
+	require.NotEqual(t, ethCommon.Hash{}, root)
+
+	// The root is derived from the signed bytes, so a client can recompute it from the response it received.
+	respBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	var received QueryResponsePublication
+	require.NoError(t, received.Unmarshal(respBytes))
+	receivedRoot, err := received.MerkleRoot()
+	require.NoError(t, err)
+	assert.Equal(t, root, receivedRoot)
+
+	// Each per chain response can be verified against the root on its own.
+	numResponses := len(received.PerChainResponses)
+	for idx, perChainResponse := range received.PerChainResponses {
+		proof, err := queryResponsePublication.MerkleProof(idx)
+		require.NoError(t, err)
+		assert.True(t, VerifyMerkleProof(root, perChainResponse, idx, numResponses, proof), idx)
+	}
+}
+
+func TestMerkleRootUsesRequestedHashAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	hashFuncs := map[HashAlgorithm]func(data ...[]byte) []byte{
+		Keccak256HashAlgorithm: ethCrypto.Keccak256,
+		Sha256HashAlgorithm: func(data ...[]byte) []byte {
+			hasher := sha256.New()
+			for _, b := range data {
+				hasher.Write(b)
+			}
+			return hasher.Sum(nil)
+		},
+	}
+
+	roots := map[HashAlgorithm]ethCommon.Hash{}
+	for hashAlgorithm, hashFunc := range hashFuncs {
+		md.resetState()
+		queryRequest := &QueryRequest{
+			Nonce:                 uint32(100 + hashAlgorithm),
+			ResponseSchemaVersion: ResponseSchemaVersion2,
+			HashAlgorithm:         hashAlgorithm,
+			PerChainQueries: []*PerChainQueryRequest{
+				createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+				createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+			},
+		}
+		signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+		expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+		md.setExpectedResults(expectedResults)
+
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		queryResponsePublication := md.waitForResponse()
+		require.NotNil(t, queryResponsePublication)
+		assert.Equal(t, hashAlgorithm, queryResponsePublication.HashAlgorithm)
+
+		// Recompute the root directly with the chosen hash function.
+		leaves := []byte{}
+		for _, perChainResponse := range queryResponsePublication.PerChainResponses {
+			respBytes, err := perChainResponse.Marshal()
+			require.NoError(t, err)
+			leaves = append(leaves, hashFunc([]byte{merkleLeafPrefix}, respBytes)...)
+		}
+		expectedRoot := ethCommon.BytesToHash(hashFunc([]byte{merkleNodePrefix}, leaves))
+		assert.Equal(t, expectedRoot, queryResponsePublication.Metadata.MerkleRoot, hashAlgorithm.String())
+
+		// The signed response carries the same root, and a client reading it uses the requested algorithm.
+		respBytes, err := queryResponsePublication.Marshal()
+		require.NoError(t, err)
+		assert.Equal(t, expectedRoot.Bytes(), respBytes[len(respBytes)-len(expectedRoot):])
+		var received QueryResponsePublication
+		require.NoError(t, received.Unmarshal(respBytes))
+		assert.Equal(t, hashAlgorithm, received.HashAlgorithm)
+
+		proof, err := received.MerkleProof(1)
+		require.NoError(t, err)
+		assert.True(t, hashAlgorithm.VerifyMerkleProof(expectedRoot, received.PerChainResponses[1], 1, 2, proof))
+
+		roots[hashAlgorithm] = expectedRoot
+	}
+
+	assert.NotEqual(t, roots[Keccak256HashAlgorithm], roots[Sha256HashAlgorithm])
+}
@@ -0,0 +1,223 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// PerChainResponseMetadata contains information about how a per chain response was produced. It is populated by the watcher.
+// It is not part of the signed response, so it is never published on p2p.
+type PerChainResponseMetadata struct {
+	// RpcNode identifies the RPC node that produced the result. It is a host name or label, never a full URL.
+	RpcNode string
+
+	// BlockTimeLag is how far the timestamp of the block used for the response was behind wall-clock time when the watcher produced the response.
+	BlockTimeLag time.Duration
+
+	// BlocksBehindHead is how many blocks the block used for the response was behind the latest block seen by the watcher. It is zero if the
+	// watcher has not seen any blocks yet.
+	BlocksBehindHead uint64
+
+	// ServedFromCache is set if the watcher returned a fatal error, and the request allowed a recent cached result to be served instead.
+	// The rest of the metadata describes how the cached result was originally produced.
+	ServedFromCache bool
+
+	// Error describes why the watcher could not answer the query. It is only set on unsuccessful responses, and only by watchers that report it.
+	Error string
+
+	// RpcError is the error code and message returned by the RPC node, if the watcher could not answer the query because the node returned
+	// an error. Like Error, it is only set on unsuccessful responses, and only by watchers that report it.
+	RpcError *RpcError
+
+	// RetryHistory lists the unsuccessful attempts at the query that preceded the successful one, in order. It is filled in by the query
+	// handler when the response is assembled, and is empty if the query succeeded on the first attempt.
+	RetryHistory []*RetryAttempt
+
+	// RetryIntervals lists how long the query handler waited after each delivery of the query to a watcher before retrying it, in order.
+	// It is the interval actually applied, so it includes the granularity of the retry audit on top of the configured retry interval.
+	// It is filled in by the query handler when the response is assembled, and only if HandlerConfig.IncludeRetryIntervals is set. It is empty if
+	// the query was never retried on a timer.
+	RetryIntervals []time.Duration
+}
+
+// RetryAttempt describes an unsuccessful attempt at a per chain query.
+type RetryAttempt struct {
+	// Attempt is the number of the attempt, starting at one.
+	Attempt int
+
+	// Status is the status returned by the watcher. A response for a block that was older than the max block age of the query is
+	// recorded as QueryRetryNeeded.
+	Status QueryStatus
+
+	// Error describes why the attempt failed, if known.
+	Error string
+
+	// RpcError is the error code and message returned by the RPC node, if the attempt failed because the node returned an error.
+	RpcError *RpcError
+}
+
+// RpcError is an error returned by the RPC node of a watcher, so that requesters can handle specific conditions. The codes are chain specific.
+// For instance, EVM nodes return JSON-RPC error codes, where -32000 to -32099 are reserved for implementation defined errors.
+type RpcError struct {
+	Code    int
+	Message string
+}
+
+// rpcCodedError is implemented by the errors that carry an RPC error code, such as the ones returned by the go-ethereum RPC client.
+type rpcCodedError interface {
+	error
+	ErrorCode() int
+}
+
+// NewRpcError returns the RPC error wrapped by err, or nil if err does not carry an RPC error code.
+func NewRpcError(err error) *RpcError {
+	var codedErr rpcCodedError
+	if !errors.As(err, &codedErr) {
+		return nil
+	}
+	return &RpcError{Code: codedErr.ErrorCode(), Message: codedErr.Error()}
+}
+
+// servedFromCache returns true if the response was served from the result cache. It may be called on a nil object.
+func (md *PerChainResponseMetadata) servedFromCache() bool {
+	return md != nil && md.ServedFromCache
+}
+
+// SetChainHeadLag fills in BlockTimeLag and BlocksBehindHead for a successful response, given the latest block number seen by the watcher.
+// It does nothing if the response does not identify a block.
+func (md *PerChainResponseMetadata) SetChainHeadLag(response ChainSpecificResponse, headBlockNumber uint64, now time.Time) {
+	blockNumber, blockTime, ok := ResponseBlock(response)
+	if !ok {
+		return
+	}
+
+	md.BlockTimeLag = now.Sub(blockTime)
+	if headBlockNumber > blockNumber {
+		md.BlocksBehindHead = headBlockNumber - blockNumber
+	} else {
+		md.BlocksBehindHead = 0
+	}
+}
+
+// ResponseBlock returns the number and time of the most recent of the blocks returned by ResponseBlocks. For a by timestamp query, that is the
+// target block, and for a by timestamp list query, it is the most recent of the target blocks. The last return value is false if the response
+// does not identify a block.
+func ResponseBlock(response ChainSpecificResponse) (uint64, time.Time, bool) {
+	var latest *ChainBlock
+	for _, block := range ResponseBlocks(response) {
+		if latest == nil || block.BlockNumber > latest.BlockNumber {
+			latest = block
+		}
+	}
+	if latest == nil {
+		return 0, time.Time{}, false
+	}
+	return latest.BlockNumber, latest.BlockTime, true
+}
+
+// errorString returns the error reported by the watcher. It may be called on a nil object.
+func (md *PerChainResponseMetadata) errorString() string {
+	if md == nil {
+		return ""
+	}
+	return md.Error
+}
+
+// rpcError returns the RPC error reported by the watcher. It may be called on a nil object.
+func (md *PerChainResponseMetadata) rpcError() *RpcError {
+	if md == nil {
+		return nil
+	}
+	return md.RpcError
+}
+
+// withRetryHistory returns the metadata with RetryHistory and RetryIntervals set. If the metadata is nil and there were retries, it returns new metadata.
+func (md *PerChainResponseMetadata) withRetryHistory(retryHistory []*RetryAttempt, retryIntervals []time.Duration) *PerChainResponseMetadata {
+	if len(retryHistory) == 0 && len(retryIntervals) == 0 {
+		return md
+	}
+
+	if md == nil {
+		md = &PerChainResponseMetadata{}
+	}
+	md.RetryHistory = retryHistory
+	md.RetryIntervals = retryIntervals
+	return md
+}
+
+// ResponseMetadata contains local information about how a query response was produced. It is not part of the signed response.
+type ResponseMetadata struct {
+	// PerChain is parallel to QueryResponsePublication.PerChainResponses. An entry may be nil if the watcher did not provide any metadata.
+	PerChain []*PerChainResponseMetadata
+
+	// BlockSummary lists the blocks used across all of the per chain responses, as returned by QueryResponsePublication.BlockSummary. It is
+	// filled in when the response is assembled.
+	BlockSummary []*ChainBlock
+
+	// MerkleRoot is the root of the Merkle tree over the per chain responses, computed when the response is assembled. Since the per chain
+	// responses are covered by the guardian signatures, it allows a single response to be verified using a proof from MerkleProof.
+	MerkleRoot ethCommon.Hash
+
+	// ReceiveTime is when the query handler first saw the request, and PublishTime is when it assembled the response for publication.
+	// They are only set if HandlerConfig.IncludeReceiveTime is set.
+	ReceiveTime time.Time
+	PublishTime time.Time
+
+	// GuardianAddress is the address of the guardian that produced the response, and GuardianIndex is its index in the guardian set with index
+	// GuardianSetIndex, so that consumers aggregating responses from several guardians can count them towards a quorum. GuardianIndex is -1 if
+	// the guardian set is not known yet, or does not contain the guardian. They are only set if HandlerConfig.GuardianAddress is set.
+	GuardianAddress  ethCommon.Address
+	GuardianSetIndex uint32
+	GuardianIndex    int
+
+	// PublishAttempts is the number of attempts the handler needed to publish the response to p2p. Attempts fail while the p2p channel is full.
+	// It is only set if HandlerConfig.IncludePublishAttempts is set.
+	PublishAttempts int
+
+	// Sequence is assigned as the response is published to p2p. It starts at one and increases by one with each response published by the
+	// guardian since the handler started, so consumers aggregating a stream of responses can detect gaps and reordering. It is only set on
+	// the response passed to the local sink if the sink accepted it after p2p.
+	Sequence uint64
+
+	// Gossip describes how the response was gossiped, for network debugging. It is only set if HandlerConfig.GossipStatus is set, and the p2p
+	// layer has registered with it.
+	Gossip *GossipMetadata
+
+	// MatchingPeers is the number of other guardians in the current guardian set that this guardian saw publish exactly the same response on
+	// gossip before it published its own. It is advisory only, since peers that respond later are not counted. It is only set if
+	// HandlerConfig.PeerAgreement is set.
+	MatchingPeers int
+}
+
+// setGuardian fills in the guardian address, and looks up its index in the current guardian set.
+func (md *ResponseMetadata) setGuardian(addr ethCommon.Address, gst *common.GuardianSetState) {
+	md.GuardianAddress = addr
+	md.GuardianIndex = -1
+	if gst == nil {
+		return
+	}
+
+	gs := gst.Get()
+	if gs == nil {
+		return
+	}
+
+	md.GuardianSetIndex = gs.Index
+	if idx, exists := gs.KeyIndex(addr); exists {
+		md.GuardianIndex = idx
+	}
+}
+
+// RpcNodeLabel returns the label used to identify an RPC node in the response metadata. It is the host name from the URL, so that
+// things like API keys embedded in the path, query string or user info are never exposed. If the URL cannot be parsed, it returns "unknown".
+func RpcNodeLabel(rpcUrl string) string {
+	u, err := url.Parse(rpcUrl)
+	if err != nil || u.Hostname() == "" {
+		return "unknown"
+	}
+	return u.Hostname()
+}
@@ -48,6 +48,54 @@ var (
 			Help: "Total number of fatal query responses received by chain",
 		}, []string{"chain_name"})
 
+	notFoundQueryResponsesReceivedByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_not_found_query_responses_received_by_chain",
+			Help: "Total number of not found query responses received by chain",
+		}, []string{"chain_name"})
+
+	nodeSyncingQueryResponsesReceivedByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_node_syncing_query_responses_received_by_chain",
+			Help: "Total number of node syncing query responses received by chain",
+		}, []string{"chain_name"})
+
+	invalidQueryResponsesReceived = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_invalid_query_responses_received_by_reason",
+			Help: "Total number of watcher responses dropped because they did not match an outstanding per chain query by reason",
+		}, []string{"reason"})
+
+	watcherFailoversByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_watcher_failovers_by_chain",
+			Help: "Total number of times a per chain query failed over to the next watcher by chain",
+		}, []string{"chain_name"})
+
+	watcherRotationsByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_watcher_rotations_by_chain",
+			Help: "Total number of times a per chain query was retried on the next watcher because the current one did not respond by chain",
+		}, []string{"chain_name"})
+
+	staleQueryResponsesReceivedByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_stale_query_responses_received_by_chain",
+			Help: "Total number of successful query responses that were retried because their block was older than the requested max block age by chain",
+		}, []string{"chain_name"})
+
+	preloadedResultsServedByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_preloaded_results_served_by_chain",
+			Help: "Total number of per chain queries answered from preloaded results without being dispatched to the watcher by chain",
+		}, []string{"chain_name"})
+
+	cachedResultsServedByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_cached_results_served_by_chain",
+			Help: "Total number of times a cached result was served in place of a fatal error by chain",
+		}, []string{"chain_name"})
+
 	queryResponsesPublished = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "ccq_guardian_total_query_responses_published",
@@ -60,6 +108,92 @@ var (
 			Help: "Total number of query requests that timed out",
 		})
 
+	externalResponsesPublished = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_external_query_responses_published",
+			Help: "Total number of query responses published to the external publisher",
+		})
+
+	externalResponsesChunked = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_external_query_responses_chunked",
+			Help: "Total number of query responses published to the external publisher in chunks because they were too large for a single message",
+		})
+
+	externalPublishFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_external_publish_failures_by_reason",
+			Help: "Total number of query responses that could not be published to the external publisher by reason",
+		}, []string{"reason"})
+
+	postPublishHookFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_post_publish_hook_failures_by_reason",
+			Help: "Total number of query responses that could not be passed to a post publish hook by reason",
+		}, []string{"reason"})
+
+	localSinkStoreFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_local_sink_store_failures",
+			Help: "Total number of attempts to store a query response in the local sink that failed and will be retried",
+		})
+
+	identicalCallsAcrossChains = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_identical_calls_across_chains",
+			Help: "Total number of calls made with the same target and call data on more than one chain of a request",
+		})
+
+	balanceChargeFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_balance_charge_failures",
+			Help: "Total number of attempts to charge the balance of a requester for a query request or response that failed",
+		})
+
+	queryResponsePublishAttempts = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "ccq_guardian_query_response_publish_attempts",
+			Help:    "Number of attempts needed to publish each query response to p2p",
+			Buckets: []float64{1.0, 2.0, 3.0, 5.0, 10.0, 20.0, 50.0},
+		})
+
+	queryLatencyByChain = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ccq_guardian_query_latency_in_ms",
+			Help:    "Time from when a query request was received until each of its per chain queries was answered successfully in ms by chain",
+			Buckets: []float64{1.0, 5.0, 10.0, 100.0, 250.0, 500.0, 1000.0, 5000.0, 10000.0, 30000.0, 60000.0},
+		}, []string{"chain_name"})
+
+	allowedRequestersReloadFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_allowed_requesters_reload_failures",
+			Help: "Total number of times reloading the allowed requesters file failed, leaving the previous list in place",
+		})
+
+	coalescedQueryRequests = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_coalesced_query_requests",
+			Help: "Total number of query requests that were coalesced with an identical request already in flight rather than being dispatched",
+		})
+
+	coalescedPerChainQueriesByChain = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_coalesced_per_chain_queries_by_chain",
+			Help: "Total number of per chain queries that were coalesced with an identical per chain query already in flight rather than being dispatched, by chain",
+		}, []string{"chain_name"})
+
+	legacyDigestRequestsAccepted = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ccq_guardian_total_legacy_digest_requests_accepted",
+			Help: "Total number of query requests accepted because they were signed using the legacy request digest",
+		})
+
+	pendingQueryRequests = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ccq_guardian_pending_query_requests",
+			Help: "Current number of query requests in flight, including those whose responses are waiting to be published",
+		})
+
 	TotalWatcherTime = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "ccq_guardian_total_watcher_query_time_in_ms",
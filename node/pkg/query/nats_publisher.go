@@ -0,0 +1,87 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// NatsPublisher is a ResponsePublisher that publishes query responses to a NATS subject.
+type NatsPublisher struct {
+	logger  *zap.Logger
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNatsPublisher connects to the specified NATS server and returns a publisher for the specified subject.
+// If the server is not reachable at start up, the client keeps trying to connect in the background.
+func NewNatsPublisher(logger *zap.Logger, url string, subject string) (*NatsPublisher, error) {
+	if url == "" {
+		return nil, fmt.Errorf("nats url may not be empty")
+	}
+	if subject == "" {
+		return nil, fmt.Errorf("nats subject may not be empty")
+	}
+
+	logger = logger.With(zap.String("component", "ccqnats"))
+	conn, err := nats.Connect(url,
+		nats.Name("guardiand_ccq"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn("disconnected from nats server", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			logger.Info("reconnected to nats server", zap.String("url", c.ConnectedUrlRedacted()))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats server: %w", err)
+	}
+
+	return &NatsPublisher{
+		logger:  logger,
+		conn:    conn,
+		subject: subject,
+	}, nil
+}
+
+// Publish implements the ResponsePublisher interface. The key is passed in the message header.
+func (np *NatsPublisher) Publish(_ context.Context, key string, response []byte) error {
+	msg := nats.NewMsg(np.subject)
+	msg.Header.Set("Ccq-Request-Signature", key)
+	msg.Data = response
+	return np.conn.PublishMsg(msg)
+}
+
+// natsHeaderAllowance is the space left in each message for the headers when a response is published in chunks.
+const natsHeaderAllowance = 1024
+
+// MaxMessageSize implements the ChunkedResponsePublisher interface. It is based on the max payload of the server, leaving room for the headers.
+// It returns zero, meaning there is no limit, until the client has connected to the server.
+func (np *NatsPublisher) MaxMessageSize() int {
+	maxPayload := int(np.conn.MaxPayload())
+	if maxPayload <= natsHeaderAllowance {
+		return 0
+	}
+	return maxPayload - natsHeaderAllowance
+}
+
+// PublishChunk implements the ChunkedResponsePublisher interface. The chunk sequence number and whether it is the final chunk are passed in
+// message headers, along with the key.
+func (np *NatsPublisher) PublishChunk(_ context.Context, key string, chunk *ResponseChunk) error {
+	msg := nats.NewMsg(np.subject)
+	msg.Header.Set("Ccq-Request-Signature", key)
+	msg.Header.Set("Ccq-Chunk-Sequence", strconv.FormatUint(uint64(chunk.Sequence), 10))
+	msg.Header.Set("Ccq-Chunk-Final", strconv.FormatBool(chunk.Final))
+	msg.Data = chunk.Data
+	return np.conn.PublishMsg(msg)
+}
+
+// Close flushes any buffered responses and closes the connection to the NATS server.
+func (np *NatsPublisher) Close() {
+	np.conn.Close()
+}
@@ -0,0 +1,114 @@
+package query
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// nearDuplicateThrottle tracks, for each signer, the near duplicate requests it has sent in the current window. Two requests are near duplicates
+// if they have the same fingerprint, see requestFingerprint. It is only accessed from the query handler routine.
+type nearDuplicateThrottle struct {
+	threshold int
+	window    time.Duration
+	seen      map[ethCommon.Address]map[ethCommon.Hash][]time.Time
+}
+
+// newNearDuplicateThrottle creates a near duplicate throttle. It returns nil if the threshold is zero, meaning near duplicates are not throttled.
+func newNearDuplicateThrottle(threshold int, window time.Duration) *nearDuplicateThrottle {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &nearDuplicateThrottle{
+		threshold: threshold,
+		window:    window,
+		seen:      make(map[ethCommon.Address]map[ethCommon.Hash][]time.Time),
+	}
+}
+
+// prune drops the signer's entries that are no longer in the window.
+func (ndt *nearDuplicateThrottle) prune(signer ethCommon.Address, now time.Time) {
+	fingerprints, exists := ndt.seen[signer]
+	if !exists {
+		return
+	}
+
+	cutoff := now.Add(-ndt.window)
+	for fingerprint, times := range fingerprints {
+		idx := 0
+		for idx < len(times) && !times[idx].After(cutoff) {
+			idx++
+		}
+		if idx == len(times) {
+			delete(fingerprints, fingerprint)
+		} else {
+			fingerprints[fingerprint] = times[idx:]
+		}
+	}
+
+	if len(fingerprints) == 0 {
+		delete(ndt.seen, signer)
+	}
+}
+
+// exceeded returns true if the signer has already sent at least the threshold of requests with this fingerprint in the current window.
+// A nil object is never exceeded.
+func (ndt *nearDuplicateThrottle) exceeded(signer ethCommon.Address, fingerprint ethCommon.Hash, now time.Time) bool {
+	if ndt == nil {
+		return false
+	}
+	ndt.prune(signer, now)
+	return len(ndt.seen[signer][fingerprint]) >= ndt.threshold
+}
+
+// record adds a request with this fingerprint to the signer's entries for the current window. A nil object does nothing.
+func (ndt *nearDuplicateThrottle) record(signer ethCommon.Address, fingerprint ethCommon.Hash, now time.Time) {
+	if ndt == nil {
+		return
+	}
+	fingerprints, exists := ndt.seen[signer]
+	if !exists {
+		fingerprints = make(map[ethCommon.Hash][]time.Time)
+		ndt.seen[signer] = fingerprints
+	}
+	fingerprints[fingerprint] = append(fingerprints[fingerprint], now)
+}
+
+// requestFingerprint returns a hash that is the same for requests that only differ trivially. For each per chain query it covers the chain
+// and the query type. For EVM queries, it also covers the contract address and the function selector of each call, but not the block, the timestamps
+// or the rest of the call data. Other query types are covered in full. The nonce and the request options are never covered.
+func requestFingerprint(queryRequest *QueryRequest) (ethCommon.Hash, error) {
+	buf := []byte{}
+	for _, perChainQuery := range queryRequest.PerChainQueries {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(perChainQuery.ChainId))
+		buf = append(buf, uint8(perChainQuery.Query.Type()))
+
+		if q, ok := perChainQuery.Query.(interface{ CallDataList() []*EthCallData }); ok {
+			callData := q.CallDataList()
+			buf = binary.BigEndian.AppendUint32(buf, uint32(len(callData)))
+			for _, cd := range callData {
+				selector := cd.Data
+				if len(selector) > 4 {
+					selector = selector[:4]
+				}
+				buf = append(buf, cd.To...)
+				buf = append(buf, uint8(len(selector)))
+				buf = append(buf, selector...)
+			}
+			continue
+		}
+
+		queryBytes, err := perChainQuery.Query.Marshal()
+		if err != nil {
+			return ethCommon.Hash{}, fmt.Errorf("failed to marshal per chain query: %w", err)
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(queryBytes)))
+		buf = append(buf, queryBytes...)
+	}
+
+	return ethCrypto.Keccak256Hash(buf), nil
+}
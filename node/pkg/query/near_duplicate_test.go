@@ -0,0 +1,73 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+func TestNearDuplicateThrottleIsPerSignerAndFingerprint(t *testing.T) {
+	ndt := newNearDuplicateThrottle(2, time.Minute)
+	signer1 := ethCommon.HexToAddress("0x1")
+	signer2 := ethCommon.HexToAddress("0x2")
+	fingerprint1 := ethCommon.HexToHash("0x1")
+	fingerprint2 := ethCommon.HexToHash("0x2")
+	now := time.Now()
+
+	assert.False(t, ndt.exceeded(signer1, fingerprint1, now))
+	ndt.record(signer1, fingerprint1, now)
+	assert.False(t, ndt.exceeded(signer1, fingerprint1, now))
+	ndt.record(signer1, fingerprint1, now.Add(time.Second))
+	assert.True(t, ndt.exceeded(signer1, fingerprint1, now.Add(time.Second)))
+	assert.False(t, ndt.exceeded(signer1, fingerprint2, now.Add(time.Second)))
+	assert.False(t, ndt.exceeded(signer2, fingerprint1, now.Add(time.Second)))
+
+	// Entries age out of the window one at a time.
+	assert.True(t, ndt.exceeded(signer1, fingerprint1, now.Add(time.Minute-time.Nanosecond)))
+	assert.False(t, ndt.exceeded(signer1, fingerprint1, now.Add(time.Minute)))
+	assert.Equal(t, 1, len(ndt.seen[signer1][fingerprint1]))
+
+	// Once everything has aged out, the signer is dropped.
+	assert.False(t, ndt.exceeded(signer1, fingerprint1, now.Add(time.Minute+time.Second)))
+	assert.Equal(t, 0, len(ndt.seen))
+}
+
+func TestNilNearDuplicateThrottleIsNeverExceeded(t *testing.T) {
+	ndt := newNearDuplicateThrottle(0, time.Minute)
+	assert.Nil(t, ndt)
+	ndt.record(ethCommon.HexToAddress("0x1"), ethCommon.HexToHash("0x1"), time.Now())
+	assert.False(t, ndt.exceeded(ethCommon.HexToAddress("0x1"), ethCommon.HexToHash("0x1"), time.Now()))
+}
+
+func TestRequestFingerprintIgnoresTrivialDifferences(t *testing.T) {
+	fingerprint := func(perChainQueries ...*PerChainQueryRequest) ethCommon.Hash {
+		t.Helper()
+		fp, err := requestFingerprint(&QueryRequest{Nonce: nonce, PerChainQueries: perChainQueries})
+		require.NoError(t, err)
+		return fp
+	}
+
+	base := fingerprint(createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2))
+
+	// A different block and different arguments after the function selector are near duplicates.
+	pcq := createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)
+	pcq.Query.(*EthCallQueryRequest).CallData[1].Data = append([]byte("Call"), []byte("different arguments")...)
+	assert.Equal(t, base, fingerprint(pcq))
+
+	// A different contract, function selector, number of calls, chain or query type are not.
+	pcq = createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)
+	pcq.Query.(*EthCallQueryRequest).CallData[1].To = []byte("Some other contract!")
+	assert.NotEqual(t, base, fingerprint(pcq))
+
+	pcq = createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)
+	pcq.Query.(*EthCallQueryRequest).CallData[1].Data = []byte("Othr")
+	assert.NotEqual(t, base, fingerprint(pcq))
+
+	assert.NotEqual(t, base, fingerprint(createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 3)))
+	assert.NotEqual(t, base, fingerprint(createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9630", 2)))
+	assert.NotEqual(t, base, fingerprint(createPerChainQueryForEthCallWithFinality(t, vaa.ChainIDPolygon, "0x28d9630", "finalized", 2)))
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"container/list"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// nonceTracker tracks the last nonce accepted from each signer, so that requests that do not increase it can be rejected as replays.
+// If it has a maximum size, the least recently used signers are evicted to make room for new ones, but only once their last request
+// is older than the replay window. If all of the tracked signers are still within the window, there is no room for a new signer, and
+// its requests are rejected, rather than allowing replays from an evicted signer. It is only accessed from the query handler routine.
+type nonceTracker struct {
+	maxSize int
+	window  time.Duration
+	entries map[ethCommon.Address]*list.Element
+
+	// wrapWindow, if non-zero, allows nonces to wrap around. See HandlerConfig.NonceWrapWindow.
+	wrapWindow uint32
+
+	// lru holds a *nonceEntry for each tracked signer, the most recently used at the front.
+	lru *list.List
+}
+
+// nonceEntry is the last nonce accepted from a signer, and when it was accepted.
+type nonceEntry struct {
+	signer   ethCommon.Address
+	nonce    uint32
+	lastSeen time.Time
+}
+
+// newNonceTracker creates a nonce tracker. A max size of zero means the number of signers tracked is not limited, in which case entries
+// are never evicted and the window is not used. A wrap window of zero means nonces may not wrap around.
+func newNonceTracker(maxSize int, window time.Duration, wrapWindow uint32) *nonceTracker {
+	return &nonceTracker{
+		maxSize:    maxSize,
+		window:     window,
+		entries:    make(map[ethCommon.Address]*list.Element),
+		lru:        list.New(),
+		wrapWindow: wrapWindow,
+	}
+}
+
+// replayed returns true, and the last nonce accepted from the signer, if the nonce is not greater than it. If the tracker has a wrap window,
+// a nonce is instead greater if it is at most the wrap window ahead of the last one, counting past math.MaxUint32 back to zero. A nil object
+// never detects replays.
+func (nt *nonceTracker) replayed(signer ethCommon.Address, nonce uint32) (uint32, bool) {
+	if nt == nil {
+		return 0, false
+	}
+	elem, exists := nt.entries[signer]
+	if !exists {
+		return 0, false
+	}
+	lastNonce := elem.Value.(*nonceEntry).nonce
+	if nt.wrapWindow != 0 {
+		ahead := nonce - lastNonce
+		return lastNonce, ahead == 0 || ahead > nt.wrapWindow
+	}
+	return lastNonce, nonce <= lastNonce
+}
+
+// full returns true if the signer is not already tracked, and there is no room to track it, even after evicting the signers that are
+// outside the replay window. A nil object is never full.
+func (nt *nonceTracker) full(signer ethCommon.Address, now time.Time) bool {
+	if nt == nil || nt.maxSize == 0 {
+		return false
+	}
+	if _, exists := nt.entries[signer]; exists {
+		return false
+	}
+	nt.evictExpired(now)
+	return len(nt.entries) >= nt.maxSize
+}
+
+// record sets the last nonce accepted from the signer, making it the most recently used. The caller must have checked there is room
+// for the signer using full. A nil object does nothing.
+func (nt *nonceTracker) record(signer ethCommon.Address, nonce uint32, now time.Time) {
+	if nt == nil {
+		return
+	}
+	if elem, exists := nt.entries[signer]; exists {
+		entry := elem.Value.(*nonceEntry)
+		entry.nonce = nonce
+		entry.lastSeen = now
+		nt.lru.MoveToFront(elem)
+		return
+	}
+	nt.entries[signer] = nt.lru.PushFront(&nonceEntry{signer: signer, nonce: nonce, lastSeen: now})
+}
+
+// evictExpired drops the least recently used signers whose last request is no longer in the replay window.
+func (nt *nonceTracker) evictExpired(now time.Time) {
+	cutoff := now.Add(-nt.window)
+	for elem := nt.lru.Back(); elem != nil; elem = nt.lru.Back() {
+		entry := elem.Value.(*nonceEntry)
+		if entry.lastSeen.After(cutoff) {
+			return
+		}
+		nt.lru.Remove(elem)
+		delete(nt.entries, entry.signer)
+	}
+}
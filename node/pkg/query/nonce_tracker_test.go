@@ -0,0 +1,223 @@
+package query
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+)
+
+func TestNonceTrackerEvictsOnlySignersOutsideTheWindow(t *testing.T) {
+	nt := newNonceTracker(3, time.Minute, 0)
+	now := time.Now()
+	signers := []ethCommon.Address{}
+	for idx := 1; idx <= 5; idx++ {
+		signers = append(signers, ethCommon.BigToAddress(big.NewInt(int64(idx))))
+	}
+
+	// Fill the tracker, with the first signer outside the window by the time the fourth one arrives.
+	for idx := 0; idx < 3; idx++ {
+		require.False(t, nt.full(signers[idx], now.Add(time.Duration(idx)*20*time.Second)))
+		nt.record(signers[idx], 10, now.Add(time.Duration(idx)*20*time.Second))
+	}
+
+	// Adding a fourth evicts the first, which is the only one outside the window.
+	fourthTime := now.Add(time.Minute + time.Second)
+	require.False(t, nt.full(signers[3], fourthTime))
+	nt.record(signers[3], 10, fourthTime)
+	assert.Equal(t, 3, len(nt.entries))
+	assert.NotContains(t, nt.entries, signers[0])
+
+	// The recent signers survive, so their replays are still caught.
+	for _, signer := range signers[1:4] {
+		lastNonce, replayed := nt.replayed(signer, 10)
+		assert.True(t, replayed)
+		assert.Equal(t, uint32(10), lastNonce)
+		_, replayed = nt.replayed(signer, 11)
+		assert.False(t, replayed)
+	}
+
+	// A fifth signer does not fit, since everyone else is still within the window, so nobody is evicted.
+	assert.True(t, nt.full(signers[4], fourthTime))
+	assert.Equal(t, 3, len(nt.entries))
+
+	// Using a signer again makes it the most recently used, so it outlives signers that were added after it.
+	nt.record(signers[1], 11, now.Add(90*time.Second))
+	fifthTime := now.Add(102 * time.Second)
+	require.False(t, nt.full(signers[4], fifthTime))
+	nt.record(signers[4], 10, fifthTime)
+	assert.Contains(t, nt.entries, signers[1])
+	assert.NotContains(t, nt.entries, signers[2])
+	_, replayed := nt.replayed(signers[1], 11)
+	assert.True(t, replayed)
+
+	// The evicted signer is no longer tracked, since it is outside the replay window.
+	_, replayed = nt.replayed(signers[0], 10)
+	assert.False(t, replayed)
+}
+
+func TestUnlimitedNonceTrackerNeverEvicts(t *testing.T) {
+	nt := newNonceTracker(0, 0, 0)
+	now := time.Now()
+	for idx := 1; idx <= 100; idx++ {
+		signer := ethCommon.BigToAddress(big.NewInt(int64(idx)))
+		require.False(t, nt.full(signer, now.Add(time.Duration(idx)*time.Hour)))
+		nt.record(signer, 1, now.Add(time.Duration(idx)*time.Hour))
+	}
+	assert.Equal(t, 100, len(nt.entries))
+}
+
+func TestNilNonceTrackerNeverDetectsReplays(t *testing.T) {
+	var nt *nonceTracker
+	nt.record(ethCommon.HexToAddress("0x1"), 1, time.Now())
+	_, replayed := nt.replayed(ethCommon.HexToAddress("0x1"), 1)
+	assert.False(t, replayed)
+	assert.False(t, nt.full(ethCommon.HexToAddress("0x1"), time.Now()))
+}
+
+func TestNonceTrackerWrapWindow(t *testing.T) {
+	signer := ethCommon.HexToAddress("0x1")
+	tests := []struct {
+		name       string
+		wrapWindow uint32
+		lastNonce  uint32
+		nonce      uint32
+		replayed   bool
+	}{
+		{"no wrap window, next nonce", 0, math.MaxUint32 - 1, math.MaxUint32, false},
+		{"no wrap window, wrapped nonce", 0, math.MaxUint32, 0, true},
+		{"no wrap window, large jump", 0, 1, math.MaxUint32, false},
+		{"wrapped nonce", 10, math.MaxUint32, 0, false},
+		{"wrapped to the edge of the window", 10, math.MaxUint32 - 1, 8, false},
+		{"wrapped past the window", 10, math.MaxUint32 - 1, 9, true},
+		{"same nonce", 10, math.MaxUint32, math.MaxUint32, true},
+		{"earlier nonce", 10, 0, math.MaxUint32, true},
+		{"jump past the window", 10, 1, 12, true},
+		{"half the range", 1 << 31, 0, 1 << 31, false},
+		{"just behind", 1 << 31, 0, 1<<31 + 1, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			nt := newNonceTracker(0, 0, tc.wrapWindow)
+			nt.record(signer, tc.lastNonce, time.Now())
+			lastNonce, replayed := nt.replayed(signer, tc.nonce)
+			assert.Equal(t, tc.lastNonce, lastNonce)
+			assert.Equal(t, tc.replayed, replayed)
+		})
+	}
+}
+
+func TestNonceWrapsAroundWhenWrapWindowConfigured(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		EnforceMonotonicNonce: true,
+		NonceWrapWindow:       100,
+	})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+
+	// Near the maximum, then wrapping around to zero, are all accepted.
+	for _, n := range []uint32{math.MaxUint32 - 1, math.MaxUint32, 0} {
+		md.resetState()
+		md.setExpectedResults(expectedResults)
+		signedQueryRequest, _ := createSignedQueryRequestWithNonceForTesting(t, md.sk, n, perChainQueries)
+		md.signedQueryReqWriteC <- signedQueryRequest
+		require.NotNil(t, md.waitForResponse(), n)
+	}
+
+	// A nonce from before the wrap is now behind.
+	md.resetState()
+	md.setExpectedResults(expectedResults)
+	signedQueryRequest, _ := createSignedQueryRequestWithNonceForTesting(t, md.sk, math.MaxUint32, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BadNonce, failure.Reason)
+}
+
+func TestNonceDoesNotWrapAroundByDefault(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{EnforceMonotonicNonce: true})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	signedQueryRequest, _ := createSignedQueryRequestWithNonceForTesting(t, md.sk, math.MaxUint32, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	md.resetState()
+	signedQueryRequest, _ = createSignedQueryRequestWithNonceForTesting(t, md.sk, 0, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BadNonce, failure.Reason)
+}
+
+func TestFullNonceTrackerRejectsNewSigners(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	otherSk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+
+	// The tracker only has room for one signer, which remains in the window for the whole test. Use the config from a real query handler
+	// so that both signers can be allowed.
+	qh := NewQueryHandler(logger, common.GoTest, testSigner, nil, nil, nil, nil, HandlerConfig{
+		EnforceMonotonicNonce:  true,
+		MaxNonceTrackedSigners: 1,
+		NonceReplayWindow:      time.Hour,
+	})
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, qh.config)
+	require.NoError(t, qh.UpdateAllowedRequesters(ctx, testSigner+","+ethCrypto.PubkeyToAddress(otherSk.PublicKey).Hex()))
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	// A replay is still caught.
+	md.resetState()
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BadNonce, failure.Reason)
+
+	// A request from another signer is rejected, rather than evicting the first signer.
+	md.resetState()
+	md.setExpectedResults(expectedResults)
+	otherSignedQueryRequest, _ := createSignedQueryRequestForTesting(t, otherSk, perChainQueries)
+	md.signedQueryReqWriteC <- otherSignedQueryRequest
+	failure = md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, NonceTrackerFull, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// So the first signer's replays are still caught.
+	md.resetState()
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	failure = md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BadNonce, failure.Reason)
+}
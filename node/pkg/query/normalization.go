@@ -0,0 +1,95 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ResultNormalization specifies how the guardian normalizes the EVM call results in a response before signing it. It is requested in the
+// query request, so it is covered by the request digest. It does not apply to Solana results.
+type ResultNormalization uint8
+
+const (
+	// NoNormalization returns the results exactly as they were returned by the RPC node.
+	NoNormalization ResultNormalization = 0
+
+	// TrimLeadingZeros removes all leading zero bytes from each result, so a result of zero becomes empty.
+	TrimLeadingZeros ResultNormalization = 1
+
+	// PadTo32Bytes left pads each non-empty result that is shorter than 32 bytes with zeros. Longer results are unchanged.
+	PadTo32Bytes ResultNormalization = 2
+)
+
+// resultWordLength is the length to which results are padded by PadTo32Bytes.
+const resultWordLength = 32
+
+// Validate verifies that the normalization is one that is supported.
+func (n ResultNormalization) Validate() error {
+	switch n {
+	case NoNormalization, TrimLeadingZeros, PadTo32Bytes:
+		return nil
+	default:
+		return fmt.Errorf("unsupported result normalization: %d", n)
+	}
+}
+
+// normalizeResult applies the normalization to a single result. It never modifies the result that is passed in.
+func (n ResultNormalization) normalizeResult(result []byte) []byte {
+	switch n {
+	case TrimLeadingZeros:
+		return bytes.TrimLeft(result, "\x00")
+	case PadTo32Bytes:
+		if len(result) == 0 || len(result) >= resultWordLength {
+			return result
+		}
+		padded := make([]byte, resultWordLength)
+		copy(padded[resultWordLength-len(result):], result)
+		return padded
+	default:
+		return result
+	}
+}
+
+// normalizeResults applies the normalization to every result, returning a new slice.
+func (n ResultNormalization) normalizeResults(results [][]byte) [][]byte {
+	normalized := make([][]byte, 0, len(results))
+	for _, result := range results {
+		normalized = append(normalized, n.normalizeResult(result))
+	}
+	return normalized
+}
+
+// normalizeResponse applies the normalization to every result in a chain specific response. It returns a copy of the response,
+// so the response that is passed in is not modified. Responses that do not contain EVM call results are returned unchanged.
+func (n ResultNormalization) normalizeResponse(response ChainSpecificResponse) ChainSpecificResponse {
+	if n == NoNormalization {
+		return response
+	}
+
+	switch resp := response.(type) {
+	case *EthCallQueryResponse:
+		normalized := *resp
+		normalized.Results = n.normalizeResults(resp.Results)
+		return &normalized
+	case *EthCallByTimestampQueryResponse:
+		normalized := *resp
+		normalized.Results = n.normalizeResults(resp.Results)
+		return &normalized
+	case *EthCallWithFinalityQueryResponse:
+		normalized := *resp
+		normalized.Results = n.normalizeResults(resp.Results)
+		return &normalized
+	case *EthCallWithPreconditionQueryResponse:
+		normalized := *resp
+		normalized.Results = n.normalizeResults(resp.Results)
+		return &normalized
+	case *EthCallByTimestampListQueryResponse:
+		normalized := &EthCallByTimestampListQueryResponse{}
+		for _, entry := range resp.Responses {
+			normalized.Responses = append(normalized.Responses, n.normalizeResponse(entry).(*EthCallByTimestampQueryResponse))
+		}
+		return normalized
+	default:
+		return response
+	}
+}
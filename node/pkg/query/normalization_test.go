@@ -0,0 +1,52 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultNormalizationOfSingleResults(t *testing.T) {
+	tests := []struct {
+		label         string
+		normalization ResultNormalization
+		input         []byte
+		expected      []byte
+	}{
+		{"none leaves padding", NoNormalization, []byte{0, 0, 1}, []byte{0, 0, 1}},
+		{"trim removes padding", TrimLeadingZeros, []byte{0, 0, 1, 0}, []byte{1, 0}},
+		{"trim of zero is empty", TrimLeadingZeros, []byte{0, 0}, nil},
+		{"pad short result", PadTo32Bytes, []byte{1, 2}, append(make([]byte, 30), 1, 2)},
+		{"pad leaves long result", PadTo32Bytes, make([]byte, 40), make([]byte, 40)},
+		{"pad leaves empty result", PadTo32Bytes, []byte{}, []byte{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.normalization.normalizeResult(tc.input))
+		})
+	}
+}
+
+func TestResultNormalizationDoesNotModifyOriginalResponse(t *testing.T) {
+	resp := &EthCallByTimestampListQueryResponse{
+		Responses: []*EthCallByTimestampQueryResponse{
+			{TargetBlockNumber: 1, Results: [][]byte{{1}, {0, 2}}},
+			{TargetBlockNumber: 2, Results: [][]byte{{3}}},
+		},
+	}
+
+	normalized := PadTo32Bytes.normalizeResponse(resp).(*EthCallByTimestampListQueryResponse)
+	for _, entry := range normalized.Responses {
+		for _, result := range entry.Results {
+			assert.Equal(t, 32, len(result))
+		}
+	}
+	assert.Equal(t, uint64(2), normalized.Responses[1].TargetBlockNumber)
+	assert.Equal(t, [][]byte{{1}, {0, 2}}, resp.Responses[0].Results)
+}
+
+func TestResultNormalizationValidate(t *testing.T) {
+	assert.NoError(t, TrimLeadingZeros.Validate())
+	assert.EqualError(t, ResultNormalization(3).Validate(), "unsupported result normalization: 3")
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// PeerAgreement is shared between the query handler and the p2p layer. The p2p layer records the signed responses that other guardians
+// publish on gossip, so the handler can report how many of them it saw publishing exactly the same response as its own. This is advisory
+// only, it is not consensus, since a guardian only counts the peer responses it happened to see before publishing. It is safe for concurrent use.
+type PeerAgreement struct {
+	mutex     sync.Mutex
+	window    time.Duration
+	lastPrune time.Time
+
+	// responses maps the digest of a response to the guardians seen signing it.
+	responses map[ethCommon.Hash]*peerResponse
+}
+
+// peerResponse tracks the guardians that signed a response with a given digest.
+type peerResponse struct {
+	firstSeen time.Time
+	signers   map[ethCommon.Address]struct{}
+}
+
+// NewPeerAgreement creates a peer agreement tracker that remembers each peer response for the specified window.
+func NewPeerAgreement(window time.Duration) *PeerAgreement {
+	return &PeerAgreement{
+		window:    window,
+		responses: make(map[ethCommon.Hash]*peerResponse),
+	}
+}
+
+// ObserveSignedResponse records a signed query response read from p2p, which may be a batch. The signer of each response is recovered from
+// its signature. Responses signed using a scheme that is not recoverable end up with a signer outside of the guardian set, so they are never counted.
+func (pa *PeerAgreement) ObserveSignedResponse(msg *gossipv1.SignedQueryResponse, now time.Time) error {
+	responses, err := UnbatchResponses(msg)
+	if err != nil {
+		return err
+	}
+
+	for idx, resp := range responses {
+		digest := GetQueryResponseDigestFromBytes(resp.QueryResponse)
+		pubKey, err := ethCrypto.Ecrecover(digest.Bytes(), resp.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to recover signer of response %d: %w", idx, err)
+		}
+		pa.observe(ethCommon.BytesToAddress(ethCrypto.Keccak256(pubKey[1:])[12:]), digest, now)
+	}
+
+	return nil
+}
+
+// observe records that a guardian signed the response with the specified digest.
+func (pa *PeerAgreement) observe(signer ethCommon.Address, digest ethCommon.Hash, now time.Time) {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	if now.Sub(pa.lastPrune) >= pa.window {
+		for d, resp := range pa.responses {
+			if now.Sub(resp.firstSeen) > pa.window {
+				delete(pa.responses, d)
+			}
+		}
+		pa.lastPrune = now
+	}
+
+	resp, exists := pa.responses[digest]
+	if !exists {
+		resp = &peerResponse{firstSeen: now, signers: make(map[ethCommon.Address]struct{})}
+		pa.responses[digest] = resp
+	}
+	resp.signers[signer] = struct{}{}
+}
+
+// matchingPeers returns the number of guardians in the current guardian set, other than self, seen signing the response with the specified
+// digest within the window. It returns zero if the guardian set is not known yet. It may be called on a nil object.
+func (pa *PeerAgreement) matchingPeers(digest ethCommon.Hash, self ethCommon.Address, gst *common.GuardianSetState, now time.Time) int {
+	if pa == nil || gst == nil {
+		return 0
+	}
+	gs := gst.Get()
+	if gs == nil {
+		return 0
+	}
+
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	resp, exists := pa.responses[digest]
+	if !exists || now.Sub(resp.firstSeen) > pa.window {
+		return 0
+	}
+
+	count := 0
+	for signer := range resp.signers {
+		if _, inSet := gs.KeyIndex(signer); inSet && signer != self {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseMetadataReportsMatchingPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	gk, err := common.LoadGuardianKey("dev.guardian.key", true)
+	require.NoError(t, err)
+	guardianAddr := ethCrypto.PubkeyToAddress(gk.PublicKey)
+
+	peers := make([]ResponseSigner, 3)
+	keys := []ethCommon.Address{guardianAddr}
+	for idx := range peers {
+		peerKey, err := ethCrypto.GenerateKey()
+		require.NoError(t, err)
+		peers[idx] = NewSecp256k1ResponseSigner(peerKey)
+		keys = append(keys, ethCrypto.PubkeyToAddress(peerKey.PublicKey))
+	}
+	outsiderKey, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	outsider := NewSecp256k1ResponseSigner(outsiderKey)
+	self := NewSecp256k1ResponseSigner(gk)
+
+	gst := common.NewGuardianSetState(nil)
+	gst.Set(&common.GuardianSet{Keys: keys, Index: 4})
+	peerAgreement := NewPeerAgreement(time.Minute)
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{GuardianAddress: guardianAddr, GuardianSetState: gst, PeerAgreement: peerAgreement})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Build the response the peers publish, which is the same one the handler will produce.
+	matchingResp := &QueryResponsePublication{Request: signedQueryRequest, Nonce: queryRequest.Nonce}
+	for idx := range expectedResults {
+		matchingResp.PerChainResponses = append(matchingResp.PerChainResponses, &expectedResults[idx])
+	}
+
+	otherResults := createExpectedResultsForTest(t, []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)})
+	otherResp := &QueryResponsePublication{Request: signedQueryRequest, Nonce: queryRequest.Nonce, PerChainResponses: []*PerChainQueryResponse{&otherResults[0]}}
+
+	// Simulate the gossip feed. Two peers agree, one of them in a batch, one peer disagrees, and neither a guardian outside of the set nor
+	// our own response is counted.
+	batch, err := MarshalResponseBatch([]*gossipv1.SignedQueryResponse{signResponseForTest(t, peers[1], matchingResp), signResponseForTest(t, peers[2], otherResp)})
+	require.NoError(t, err)
+	now := time.Now()
+	for _, msg := range []*gossipv1.SignedQueryResponse{
+		signResponseForTest(t, peers[0], matchingResp),
+		batch,
+		signResponseForTest(t, outsider, matchingResp),
+		signResponseForTest(t, self, matchingResp),
+	} {
+		require.NoError(t, peerAgreement.ObserveSignedResponse(msg, now))
+	}
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 2, queryResponsePublication.Metadata.MatchingPeers)
+}
+
+func TestPeerAgreementForgetsResponsesAfterWindow(t *testing.T) {
+	peerKey, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	peer := NewSecp256k1ResponseSigner(peerKey)
+	gst := common.NewGuardianSetState(nil)
+	gst.Set(&common.GuardianSet{Keys: []ethCommon.Address{ethCrypto.PubkeyToAddress(peerKey.PublicKey)}})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, peerKey, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	resp := &QueryResponsePublication{Request: signedQueryRequest, PerChainResponses: []*PerChainQueryResponse{&expectedResults[0]}}
+	digest, err := resp.SigningDigest()
+	require.NoError(t, err)
+
+	peerAgreement := NewPeerAgreement(time.Minute)
+	now := time.Now()
+	require.NoError(t, peerAgreement.ObserveSignedResponse(signResponseForTest(t, peer, resp), now))
+
+	assert.Equal(t, 1, peerAgreement.matchingPeers(digest, ethCommon.Address{}, gst, now.Add(time.Minute)))
+	assert.Equal(t, 0, peerAgreement.matchingPeers(digest, ethCommon.Address{}, gst, now.Add(time.Minute+time.Second)))
+
+	// Nothing is counted before the guardian set is known.
+	assert.Equal(t, 0, peerAgreement.matchingPeers(digest, ethCommon.Address{}, common.NewGuardianSetState(nil), now))
+
+	// Observing a later response prunes the old one.
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)}
+	signedQueryRequest, _ = createSignedQueryRequestForTesting(t, peerKey, perChainQueries)
+	expectedResults = createExpectedResultsForTest(t, perChainQueries)
+	resp = &QueryResponsePublication{Request: signedQueryRequest, PerChainResponses: []*PerChainQueryResponse{&expectedResults[0]}}
+	require.NoError(t, peerAgreement.ObserveSignedResponse(signResponseForTest(t, peer, resp), now.Add(2*time.Minute)))
+	assert.Len(t, peerAgreement.responses, 1)
+}
@@ -0,0 +1,77 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// PostPublishHook is called after a query response has been published to p2p, so an operator can run custom actions, such as sending metrics
+// to another system or notifications. The response is shared with the rest of the guardian, so it must not be modified.
+type PostPublishHook func(respPub *QueryResponsePublication)
+
+// postPublishHooks is the query handler side of the post publish hooks. The hooks are called in their own routine, in order, so that a slow
+// hook never delays the handler. A hook that panics is logged and skipped, without affecting the other hooks or later responses.
+type postPublishHooks struct {
+	logger *zap.Logger
+	hooks  []PostPublishHook
+	pubC   chan *QueryResponsePublication
+}
+
+// newPostPublishHooks creates the post publish hooks. It returns nil if no hooks are configured.
+func newPostPublishHooks(logger *zap.Logger, hooks []PostPublishHook) *postPublishHooks {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	return &postPublishHooks{
+		logger: logger,
+		hooks:  hooks,
+		pubC:   make(chan *QueryResponsePublication, QueryResponsePublicationChannelSize),
+	}
+}
+
+// post queues a published response to be passed to the hooks. It never blocks. If the queue is full, the response is dropped.
+// It may be called on a nil object, in which case it does nothing.
+func (h *postPublishHooks) post(respPub *QueryResponsePublication) {
+	if h == nil {
+		return
+	}
+
+	select {
+	case h.pubC <- respPub:
+	default:
+		h.logger.Warn("post publish hook queue is full, dropping query response", zap.String("signature", respPub.Signature()))
+		postPublishHookFailures.WithLabelValues("queue_full").Inc()
+	}
+}
+
+// run reads queued responses and calls each of the hooks with them. It only returns when the context is canceled.
+func (h *postPublishHooks) run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case respPub := <-h.pubC:
+			for idx, hook := range h.hooks {
+				if err := callPostPublishHook(hook, respPub); err != nil {
+					h.logger.Error("post publish hook panicked", zap.Int("hookIdx", idx), zap.String("signature", respPub.Signature()), zap.Error(err))
+					postPublishHookFailures.WithLabelValues("panic").Inc()
+				}
+			}
+		}
+	}
+}
+
+// callPostPublishHook calls a single hook, converting a panic into an error.
+func callPostPublishHook(hook PostPublishHook, respPub *QueryResponsePublication) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	hook(respPub)
+	return nil
+}
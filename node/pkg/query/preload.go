@@ -0,0 +1,73 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+)
+
+// preloadRequestIDPrefix identifies the pending queries used to preload the result cache. A signed request ID never has this prefix.
+const preloadRequestIDPrefix = "preload:"
+
+// newPreloadQuery creates the pending query used to preload the result cache with the results of a query request. Only eth_call queries made
+// against a specific block can be preloaded. The request is never signed or published, and any failure is reported with
+// the guardian as the signer.
+func newPreloadQuery(
+	qLogger *zap.Logger,
+	env common.Environment,
+	queryRequest *QueryRequest,
+	chainQueryReqC map[vaa.ChainID]chan *PerChainQueryInternal,
+	config HandlerConfig,
+	receiveTime time.Time,
+) (*pendingQuery, error) {
+	if err := queryRequest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid query request: %w", err)
+	}
+
+	fingerprint, err := QueryFingerprint(env, queryRequest)
+	if err != nil {
+		return nil, err
+	}
+	requestID := preloadRequestIDPrefix + fingerprint.String()
+
+	queries := []*perChainQuery{}
+	for requestIdx, pcq := range queryRequest.PerChainQueries {
+		ecq, ok := pcq.Query.(*EthCallQueryRequest)
+		if !ok {
+			return nil, fmt.Errorf("per chain query %d is not an eth_call query", requestIdx)
+		}
+		if ecq.Finality != "" {
+			return nil, fmt.Errorf("per chain query %d uses a finality rather than a specific block", requestIdx)
+		}
+
+		channel, exists := chainQueryReqC[pcq.ChainId]
+		if !exists {
+			return nil, fmt.Errorf("per chain query %d is for chain %s, which is not watched", requestIdx, pcq.ChainId)
+		}
+
+		queries = append(queries, &perChainQuery{
+			req: &PerChainQueryInternal{
+				RequestID:  requestID,
+				RequestIdx: requestIdx,
+				Request:    pcq,
+			},
+			channel:          channel,
+			failoverChannels: config.FailoverChainQueryReqC[pcq.ChainId],
+		})
+	}
+
+	return &pendingQuery{
+		request:     queryRequest,
+		requestID:   requestID,
+		signer:      config.GuardianAddress,
+		receiveTime: receiveTime,
+		logger:      qLogger,
+		queries:     queries,
+		responses:   make([]*PerChainQueryResponseInternal, len(queries)),
+		retryBudget: effectiveRetryBudget(config, queryRequest.RetryBudget),
+		preload:     true,
+	}, nil
+}
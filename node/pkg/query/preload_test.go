@@ -0,0 +1,65 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreloadedResultsAreServedWithoutDispatching(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	observedCore, observedLogs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(observedCore)
+
+	preloadC := make(chan *QueryRequest, 1)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{CachedResultMaxAge: time.Minute, PreloadC: preloadC})
+
+	// Preload an eth_call query against a specific block.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	preloadC <- &QueryRequest{Nonce: 1, PerChainQueries: perChainQueries}
+
+	require.Eventually(t, func() bool {
+		return observedLogs.FilterMessage("preloaded query results into the cache").Len() == 1
+	}, requestTimeoutForTest, pollIntervalForTest)
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// The preloaded results were not published.
+	assert.Nil(t, md.getQueryResponsePublication())
+
+	// A real request for the same query is answered from the cache, without being dispatched to the watcher.
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	require.Equal(t, 1, len(queryResponsePublication.Metadata.PerChain))
+	assert.True(t, queryResponsePublication.Metadata.PerChain[0].ServedFromCache)
+}
+
+func TestPreloadRejectsQueriesThatAreNotPinnedToABlock(t *testing.T) {
+	chainQueryReqC := map[vaa.ChainID]chan *PerChainQueryInternal{vaa.ChainIDSolana: make(chan *PerChainQueryInternal)}
+	queryRequest := createSolanaAccountQueryRequestForTesting(t)
+	_, err := newPreloadQuery(zap.NewNop(), common.GoTest, queryRequest, chainQueryReqC, HandlerConfig{}, time.Now())
+	require.ErrorContains(t, err, "is not an eth_call query")
+
+	// Neither are eth_call queries that specify a finality.
+	chainQueryReqC = map[vaa.ChainID]chan *PerChainQueryInternal{vaa.ChainIDPolygon: make(chan *PerChainQueryInternal)}
+	pcq := createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "", 1)
+	pcq.Query.(*EthCallQueryRequest).Finality = "finalized"
+	_, err = newPreloadQuery(zap.NewNop(), common.GoTest, &QueryRequest{Nonce: 1, PerChainQueries: []*PerChainQueryRequest{pcq}}, chainQueryReqC, HandlerConfig{}, time.Now())
+	require.ErrorContains(t, err, "uses a finality rather than a specific block")
+}
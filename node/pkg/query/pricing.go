@@ -0,0 +1,96 @@
+package query
+
+import (
+	"fmt"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// RequestPricer is the interface that must be implemented to compute the price of query requests, for an operator running a paid service.
+// Prices are in whatever unit the BalanceProvider uses.
+type RequestPricer interface {
+	// RequestPrice returns the price of a request that has passed validation. The signer must have at least this balance for the request
+	// to be accepted, and it is charged once the request is dispatched.
+	RequestPrice(queryRequest *QueryRequest) uint64
+
+	// ResponsePrice returns the additional price of a response of the specified size in bytes. It is charged once the response is published.
+	ResponsePrice(responseSize int) uint64
+}
+
+// BalanceProvider is the interface that must be implemented to look up and charge the balances of requesters. Its methods are called from
+// the query handler routine, so they should not block for long.
+type BalanceProvider interface {
+	// Balance returns the current balance of the signer.
+	Balance(signer ethCommon.Address) (uint64, error)
+
+	// Charge deducts an amount from the balance of the signer. The request ID identifies what the charge is for.
+	Charge(signer ethCommon.Address, requestID string, amount uint64) error
+}
+
+// LinearRequestPricer is a RequestPricer that charges a fixed amount for each per chain query, for each call (see QueryRequest.TotalCalls)
+// and for each byte of the response.
+type LinearRequestPricer struct {
+	PerChainQuery   uint64
+	PerCall         uint64
+	PerResponseByte uint64
+}
+
+// RequestPrice implements the RequestPricer interface.
+func (p *LinearRequestPricer) RequestPrice(queryRequest *QueryRequest) uint64 {
+	return uint64(len(queryRequest.PerChainQueries))*p.PerChainQuery + uint64(queryRequest.TotalCalls())*p.PerCall
+}
+
+// ResponsePrice implements the RequestPricer interface.
+func (p *LinearRequestPricer) ResponsePrice(responseSize int) uint64 {
+	return uint64(responseSize) * p.PerResponseByte
+}
+
+// requestPricing is the query handler side of a RequestPricer and BalanceProvider. It is only accessed from the query handler routine.
+type requestPricing struct {
+	pricer   RequestPricer
+	balances BalanceProvider
+}
+
+// newRequestPricing creates the request pricing. It returns nil if pricing is not configured. Either both or neither of the pricer and
+// the balance provider must be set.
+func newRequestPricing(pricer RequestPricer, balances BalanceProvider) (*requestPricing, error) {
+	if pricer == nil && balances == nil {
+		return nil, nil
+	}
+
+	if pricer == nil || balances == nil {
+		return nil, fmt.Errorf("the request pricer and the balance provider must be configured together")
+	}
+
+	return &requestPricing{pricer: pricer, balances: balances}, nil
+}
+
+// price returns the price of a request, and the balance of its signer. A nil object always returns zero for both.
+func (rp *requestPricing) price(signer ethCommon.Address, queryRequest *QueryRequest) (price uint64, balance uint64, err error) {
+	if rp == nil {
+		return 0, 0, nil
+	}
+
+	balance, err = rp.balances.Balance(signer)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to look up balance: %w", err)
+	}
+
+	return rp.pricer.RequestPrice(queryRequest), balance, nil
+}
+
+// charge deducts the price of a dispatched request from the balance of its signer. A nil object does nothing.
+func (rp *requestPricing) charge(pq *pendingQuery, amount uint64) error {
+	if rp == nil || amount == 0 {
+		return nil
+	}
+	return rp.balances.Charge(pq.signer, pq.requestID, amount)
+}
+
+// chargeResponse deducts the price of a published response of the specified size from the balance of the signer. A nil object does nothing.
+func (rp *requestPricing) chargeResponse(pq *pendingQuery, responseSize int) error {
+	if rp == nil {
+		return nil
+	}
+	return rp.charge(pq, rp.pricer.ResponsePrice(responseSize))
+}
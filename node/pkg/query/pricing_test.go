@@ -0,0 +1,130 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockBalanceProvider is a BalanceProvider that keeps balances in memory and records the charges made against them.
+type mockBalanceProvider struct {
+	mutex    sync.Mutex
+	balances map[ethCommon.Address]uint64
+	charges  map[string]uint64
+}
+
+func newMockBalanceProvider() *mockBalanceProvider {
+	return &mockBalanceProvider{balances: make(map[ethCommon.Address]uint64), charges: make(map[string]uint64)}
+}
+
+func (bp *mockBalanceProvider) setBalance(signer ethCommon.Address, balance uint64) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+	bp.balances[signer] = balance
+}
+
+func (bp *mockBalanceProvider) totalCharged() uint64 {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+	total := uint64(0)
+	for _, amount := range bp.charges {
+		total += amount
+	}
+	return total
+}
+
+func (bp *mockBalanceProvider) Balance(signer ethCommon.Address) (uint64, error) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+	return bp.balances[signer], nil
+}
+
+func (bp *mockBalanceProvider) Charge(signer ethCommon.Address, requestID string, amount uint64) error {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+	if bp.balances[signer] < amount {
+		return fmt.Errorf("balance of %s is too low", signer.Hex())
+	}
+	bp.balances[signer] -= amount
+	bp.charges[requestID] += amount
+	return nil
+}
+
+func TestLinearRequestPricer(t *testing.T) {
+	pricer := &LinearRequestPricer{PerChainQuery: 10, PerCall: 1, PerResponseByte: 2}
+	queryRequest := &QueryRequest{PerChainQueries: []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}}
+	assert.Equal(t, uint64(25), pricer.RequestPrice(queryRequest))
+	assert.Equal(t, uint64(200), pricer.ResponsePrice(100))
+}
+
+func TestNewRequestPricingRequiresBothPricerAndBalanceProvider(t *testing.T) {
+	pricing, err := newRequestPricing(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, pricing)
+
+	_, err = newRequestPricing(&LinearRequestPricer{}, nil)
+	assert.Error(t, err)
+
+	_, err = newRequestPricing(nil, newMockBalanceProvider())
+	assert.Error(t, err)
+}
+
+func TestRequestIsOnlyAcceptedIfTheSignerCanAffordIt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	balances := newMockBalanceProvider()
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		RequestPricer:   &LinearRequestPricer{PerChainQuery: 10, PerCall: 1, PerResponseByte: 1},
+		BalanceProvider: balances,
+	})
+	signer := ethCommon.HexToAddress(testSigner)
+
+	// One per chain query with two calls costs 12.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+
+	// A signer that cannot afford the request is rejected without it being dispatched.
+	balances.setBalance(signer, 11)
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, InsufficientBalance, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Zero(t, balances.totalCharged())
+
+	// A signer that can afford it is charged for both the request and the response.
+	md.resetState()
+	balances.setBalance(signer, 1000)
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Nil(t, md.getFailure())
+
+	respBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return balances.totalCharged() == uint64(12+len(respBytes))
+	}, time.Second, pollIntervalForTest)
+}
@@ -0,0 +1,112 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ResponsePublisher is the interface that must be implemented to publish query responses to an external message bus,
+// in addition to publishing them on p2p.
+type ResponsePublisher interface {
+	// Publish publishes the serialized query response. The key is the hex encoded signature of the request.
+	Publish(ctx context.Context, key string, response []byte) error
+}
+
+// externalPublisher is the query handler side of a ResponsePublisher. It decouples the handler from the publisher
+// so that a slow or unavailable message bus never delays the handler.
+type externalPublisher struct {
+	logger    *zap.Logger
+	publisher ResponsePublisher
+	pubC      chan *QueryResponsePublication
+}
+
+// newExternalPublisher creates an external publisher. It returns nil if no publisher is configured.
+func newExternalPublisher(logger *zap.Logger, publisher ResponsePublisher) *externalPublisher {
+	if publisher == nil {
+		return nil
+	}
+
+	return &externalPublisher{
+		logger:    logger,
+		publisher: publisher,
+		pubC:      make(chan *QueryResponsePublication, QueryResponsePublicationChannelSize),
+	}
+}
+
+// post queues a response to be published. It never blocks. If the queue is full, the response is dropped.
+// It may be called on a nil object, in which case it does nothing.
+func (ep *externalPublisher) post(respPub *QueryResponsePublication) {
+	if ep == nil {
+		return
+	}
+
+	select {
+	case ep.pubC <- respPub:
+	default:
+		ep.logger.Warn("external publisher queue is full, dropping query response", zap.String("signature", hex.EncodeToString(respPub.Request.Signature)))
+		externalPublishFailures.WithLabelValues("queue_full").Inc()
+	}
+}
+
+// run reads queued responses and publishes them. It only returns when the context is canceled.
+func (ep *externalPublisher) run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case respPub := <-ep.pubC:
+			key := hex.EncodeToString(respPub.Request.Signature)
+			bytes, err := respPub.Marshal()
+			if err != nil {
+				ep.logger.Error("failed to marshal query response for external publisher", zap.String("signature", key), zap.Error(err))
+				externalPublishFailures.WithLabelValues("failed_to_marshal").Inc()
+				continue
+			}
+
+			if err := ep.publish(ctx, key, respPub, bytes); err != nil {
+				ep.logger.Error("failed to publish query response to external publisher", zap.String("signature", key), zap.Error(err))
+				externalPublishFailures.WithLabelValues("failed_to_publish").Inc()
+				continue
+			}
+
+			externalResponsesPublished.Inc()
+		}
+	}
+}
+
+// publish publishes a serialized response. If the publisher limits the size of a message and the response is too large, it is published in chunks,
+// which end between results where possible.
+func (ep *externalPublisher) publish(ctx context.Context, key string, respPub *QueryResponsePublication, bytes []byte) error {
+	chunkedPublisher, ok := ep.publisher.(ChunkedResponsePublisher)
+	if !ok {
+		return ep.publisher.Publish(ctx, key, bytes)
+	}
+
+	maxMessageSize := chunkedPublisher.MaxMessageSize()
+	if maxMessageSize == 0 || len(bytes) <= maxMessageSize {
+		return ep.publisher.Publish(ctx, key, bytes)
+	}
+
+	boundaries, err := resultBoundaries(respPub)
+	if err != nil {
+		return err
+	}
+
+	chunks, err := splitResponse(bytes, maxMessageSize, boundaries)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		if err := chunkedPublisher.PublishChunk(ctx, key, chunk); err != nil {
+			return fmt.Errorf("failed to publish chunk %d of %d: %w", chunk.Sequence, len(chunks), err)
+		}
+	}
+
+	ep.logger.Info("published query response in chunks", zap.String("signature", key), zap.Int("responseSize", len(bytes)), zap.Int("numChunks", len(chunks)))
+	externalResponsesChunked.Inc()
+	return nil
+}
@@ -2,7 +2,6 @@ package query
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"strings"
 	"time"
@@ -22,9 +21,16 @@ const (
 	// RequestTimeout indicates how long before a request is considered to have timed out.
 	RequestTimeout = 1 * time.Minute
 
-	// RetryInterval specifies how long we will wait between retry intervals.
+	// RetryInterval specifies how long we will wait before the first retry of a per chain query. Later retries back off from it.
 	RetryInterval = 10 * time.Second
 
+	// DefaultMaxRetryInterval is the ceiling of the retry backoff if HandlerConfig.MaxRetryInterval is not set.
+	DefaultMaxRetryInterval = 30 * time.Second
+
+	// DefaultReplayCacheSize and DefaultReplayCacheWindow bound the replay cache if HandlerConfig.ReplayCacheSize and ReplayCacheWindow are not set.
+	DefaultReplayCacheSize   = 100000
+	DefaultReplayCacheWindow = time.Hour
+
 	// AuditInterval specifies how often to audit the list of pending queries.
 	AuditInterval = time.Second
 
@@ -49,15 +55,23 @@ func NewQueryHandler(
 	chainQueryReqC map[vaa.ChainID]chan *PerChainQueryInternal,
 	queryResponseReadC <-chan *PerChainQueryResponseInternal,
 	queryResponseWriteC chan<- *QueryResponsePublication,
+	config HandlerConfig,
 ) *QueryHandler {
+	allowedRequestorsUpdateC := make(chan allowedRequestersUpdate)
+	config.allowedRequestorsUpdateC = allowedRequestorsUpdateC
+	watcherRegistrationC := make(chan watcherRegistration)
+	config.watcherRegistrationC = watcherRegistrationC
 	return &QueryHandler{
-		logger:               logger.With(zap.String("component", "ccq")),
-		env:                  env,
-		allowedRequestorsStr: allowedRequestorsStr,
-		signedQueryReqC:      signedQueryReqC,
-		chainQueryReqC:       chainQueryReqC,
-		queryResponseReadC:   queryResponseReadC,
-		queryResponseWriteC:  queryResponseWriteC,
+		logger:                   logger.With(zap.String("component", "ccq")),
+		env:                      env,
+		allowedRequestorsStr:     allowedRequestorsStr,
+		signedQueryReqC:          signedQueryReqC,
+		chainQueryReqC:           chainQueryReqC,
+		queryResponseReadC:       queryResponseReadC,
+		queryResponseWriteC:      queryResponseWriteC,
+		allowedRequestorsUpdateC: allowedRequestorsUpdateC,
+		watcherRegistrationC:     watcherRegistrationC,
+		config:                   config,
 	}
 }
 
@@ -77,6 +91,13 @@ type (
 		queryResponseReadC   <-chan *PerChainQueryResponseInternal
 		queryResponseWriteC  chan<- *QueryResponsePublication
 		allowedRequestors    map[ethCommon.Address]struct{}
+		config               HandlerConfig
+
+		// allowedRequestorsUpdateC is used to replace the set of allowed requestors while the handler is running.
+		allowedRequestorsUpdateC chan<- allowedRequestersUpdate
+
+		// watcherRegistrationC is used to add the channel of a watcher that starts after the handler.
+		watcherRegistrationC chan<- watcherRegistration
 	}
 
 	// pendingQuery is the cache entry for a given query.
@@ -84,12 +105,37 @@ type (
 		signedRequest *gossipv1.SignedQueryRequest
 		request       *QueryRequest
 		requestID     string
+		signer        ethCommon.Address
 		receiveTime   time.Time
 		queries       []*perChainQuery
 		responses     []*PerChainQueryResponseInternal
 
-		// respPub is only populated when we need to retry sending the response to p2p.
-		respPub *QueryResponsePublication
+		// preload is set if the request was read from HandlerConfig.PreloadC. Its results are only cached, not published.
+		preload bool
+
+		// logger is the handler logger, with the log level configured for the signer of the request, if any, applied.
+		logger *zap.Logger
+
+		// retryBudget is the number of times each per chain query may be retried. Zero means retry until the request times out.
+		retryBudget uint
+
+		// cancel cancels the context shared by the per chain queries. It is called when the request is removed from the pending queries, see
+		// removePendingQuery. It is nil if the handler is not configured to cancel requests on a fatal error.
+		cancel context.CancelFunc
+
+		// respPub is populated once all of the per chain responses have been received. The pending query is kept until the response
+		// has been accepted by p2p and, if configured, the local sink. The flags track which of them have already accepted it.
+		respPub        *QueryResponsePublication
+		publishedToP2p bool
+		storedInSink   bool
+
+		// publishAttempts is the number of times the response has been sent to p2p, including the attempt that was accepted.
+		publishAttempts int
+
+		// leader is set while this request is coalesced with an identical request from another signer that is already in flight. Its per chain
+		// queries are not dispatched, it is published with the results of the leader instead. followers are the requests coalesced with this one.
+		leader    *pendingQuery
+		followers []*pendingQuery
 	}
 
 	// perChainQuery is the data associated with a single per chain query in a query request.
@@ -97,6 +143,34 @@ type (
 		req            *PerChainQueryInternal
 		channel        chan *PerChainQueryInternal
 		lastUpdateTime time.Time
+
+		// numForwards is the number of times this query has been delivered to the current watcher. Only deliveries beyond the first count against the retry budget.
+		numForwards uint
+
+		// failoverChannels are the remaining watchers to be tried, in order, if the current one returns a fatal error.
+		failoverChannels []chan *PerChainQueryInternal
+
+		// retryHistory records the unsuccessful responses received for this query, across all of its watchers.
+		retryHistory []*RetryAttempt
+
+		// awaitingResponse is set while the current watcher has been delivered this query but has not responded, so a retry can tell whether the watcher timed out.
+		awaitingResponse bool
+
+		// retryIntervals records how long the handler actually waited after each delivery of this query before retrying it.
+		retryIntervals []time.Duration
+
+		// backoffInterval is the current interval between timed retries of this query, and retryWait is that interval with jitter applied.
+		// They are zero until the query is first retried on a timer, meaning the base retry interval. See backOff.
+		backoffInterval time.Duration
+		retryWait       time.Duration
+
+		// failed is set once this query has failed in a request that allows partial results. It is no longer retried, and its response is omitted.
+		failed bool
+
+		// leader is set while this query is coalesced with an identical per chain query of another request that is already in flight. It is
+		// not dispatched, it is answered with the response to the leader instead. followers are the per chain queries coalesced with this one.
+		leader    *perChainQueryRef
+		followers []perChainQueryRef
 	}
 
 	PerChainConfig struct {
@@ -157,7 +231,14 @@ func (qh *QueryHandler) Start(ctx context.Context) error {
 	qh.logger.Debug("entering Start", zap.String("enforceFlag", qh.allowedRequestorsStr))
 
 	var err error
-	qh.allowedRequestors, err = parseAllowedRequesters(qh.allowedRequestorsStr)
+	if qh.config.AllowedRequestersFile != "" {
+		qh.allowedRequestorsStr, err = ReadAllowedRequestersFile(qh.config.AllowedRequestersFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	qh.allowedRequestors, qh.config.requesterRestrictions, err = parseAllowedRequesters(qh.allowedRequestorsStr)
 	if err != nil {
 		return fmt.Errorf("failed to parse allowed requesters: %w", err)
 	}
@@ -166,12 +247,70 @@ func (qh *QueryHandler) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start query handler routine: %w", err)
 	}
 
+	if qh.config.AllowedRequestersFile != "" && qh.config.ReloadAllowedRequestersC != nil {
+		if err := supervisor.Run(ctx, "query_allowed_requesters_reload", common.WrapWithScissors(qh.reloadAllowedRequesters, "query_allowed_requesters_reload")); err != nil {
+			return fmt.Errorf("failed to start allowed requesters reload routine: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// UpdateAllowedRequesters replaces the set of signers allowed to submit queries while the handler is running. The list is validated the same way as
+// `--ccqAllowedRequesters`, and it must not be empty. Their restrictions are replaced too, which refills the bucket of every rate limited signer.
+// It returns once the handler has switched to the new set, so it applies to every request processed after that. Requests that are already in flight
+// are not affected.
+func (qh *QueryHandler) UpdateAllowedRequesters(ctx context.Context, allowedRequestorsStr string) error {
+	allowedRequestors, restrictions, err := parseAllowedRequesters(allowedRequestorsStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse allowed requesters: %w", err)
+	}
+
+	select {
+	case qh.allowedRequestorsUpdateC <- allowedRequestersUpdate{allowedRequestors: allowedRequestors, restrictions: restrictions}:
+		qh.logger.Info("updated the allowed requesters", zap.String("allowedRequesters", allowedRequestorsStr))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RegisterWatcher adds the query request channel of a watcher that started after the handler. Requests for its chain that arrived while it
+// was starting are held for up to HandlerConfig.WatcherRegistrationGracePeriod, and are processed once it registers. It returns once the
+// handler has added the channel.
+func (qh *QueryHandler) RegisterWatcher(ctx context.Context, chainID vaa.ChainID, queryReqC chan *PerChainQueryInternal) error {
+	select {
+	case qh.watcherRegistrationC <- watcherRegistration{chainID: chainID, queryReqC: queryReqC}:
+		qh.logger.Info("registered watcher", zap.Stringer("chainID", chainID))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResponseSigner returns the configured response signer, or nil if responses should be signed with the guardian key using secp256k1.
+func (qh *QueryHandler) ResponseSigner() ResponseSigner {
+	return qh.config.ResponseSigner
+}
+
+// GossipStatus returns the gossip status the p2p layer should register with, or nil if gossip metadata is not included in the responses.
+func (qh *QueryHandler) GossipStatus() *GossipStatus {
+	return qh.config.GossipStatus
+}
+
+// PeerAgreement returns the peer agreement tracker to be fed with peer responses by the p2p layer, or nil if agreement is not reported.
+func (qh *QueryHandler) PeerAgreement() *PeerAgreement {
+	return qh.config.PeerAgreement
+}
+
+// ResponseBatchWindow returns the window within which responses are batched into a single p2p message, or zero if they are published individually.
+func (qh *QueryHandler) ResponseBatchWindow() time.Duration {
+	return qh.config.ResponseBatchWindow
+}
+
 // handleQueryRequests multiplexes observation requests to the appropriate chain
 func (qh *QueryHandler) handleQueryRequests(ctx context.Context) error {
-	return handleQueryRequestsImpl(ctx, qh.logger, qh.signedQueryReqC, qh.chainQueryReqC, qh.allowedRequestors, qh.queryResponseReadC, qh.queryResponseWriteC, qh.env, RequestTimeout, RetryInterval, AuditInterval)
+	return handleQueryRequestsImpl(ctx, qh.logger, qh.signedQueryReqC, qh.chainQueryReqC, qh.allowedRequestors, qh.queryResponseReadC, qh.queryResponseWriteC, qh.env, RequestTimeout, RetryInterval, AuditInterval, qh.config)
 }
 
 // handleQueryRequestsImpl allows instantiating the handler in the test environment with shorter timeout and retry parameters.
@@ -187,12 +326,111 @@ func handleQueryRequestsImpl(
 	requestTimeoutImpl time.Duration,
 	retryIntervalImpl time.Duration,
 	auditIntervalImpl time.Duration,
+	config HandlerConfig,
 ) error {
 	qLogger := logger.With(zap.String("component", "ccqhandler"))
 	qLogger.Info("cross chain queries are enabled", zap.Any("allowedRequestors", allowedRequestors), zap.String("env", string(env)))
 
 	pendingQueries := make(map[string]*pendingQuery) // Key is requestID.
 
+	// restrictions is replaced along with the allowed requestors.
+	restrictions := config.requesterRestrictions
+
+	// lastNonces is nil if monotonic nonces are not being enforced.
+	var lastNonces *nonceTracker
+	if config.EnforceMonotonicNonce {
+		if config.MaxNonceTrackedSigners != 0 && config.NonceReplayWindow <= 0 {
+			return fmt.Errorf("nonce replay window must be set if the number of nonce tracked signers is limited")
+		}
+		if config.NonceWrapWindow > 1<<31 {
+			return fmt.Errorf("nonce wrap window may not be greater than 2^31")
+		}
+		lastNonces = newNonceTracker(config.MaxNonceTrackedSigners, config.NonceReplayWindow, config.NonceWrapWindow)
+	}
+
+	if config.ReplayCacheSize < 0 || config.ReplayCacheWindow < 0 {
+		return fmt.Errorf("replay cache size and window may not be negative")
+	}
+	replayCacheSize := config.ReplayCacheSize
+	if replayCacheSize == 0 {
+		replayCacheSize = DefaultReplayCacheSize
+	}
+	replayCacheWindow := config.ReplayCacheWindow
+	if replayCacheWindow == 0 {
+		replayCacheWindow = DefaultReplayCacheWindow
+	}
+	recentRequests := newReplayCache(replayCacheSize, replayCacheWindow)
+
+	if config.BandwidthQuotaBytes != 0 && config.BandwidthQuotaWindow <= 0 {
+		return fmt.Errorf("bandwidth quota window must be set if the bandwidth quota is enabled")
+	}
+
+	if config.NearDuplicateThreshold != 0 && config.NearDuplicateWindow <= 0 {
+		return fmt.Errorf("near duplicate window must be set if the near duplicate threshold is enabled")
+	}
+
+	if config.MaxRetryBudget != 0 && config.DefaultRetryBudget > config.MaxRetryBudget {
+		return fmt.Errorf("default retry budget may not be greater than the max retry budget")
+	}
+
+	if err := config.LegacyRequestDigest.Validate(); err != nil {
+		return err
+	}
+	if config.LegacyRequestDigest != NoLegacyRequestDigest && config.LegacyRequestDigestUntil.IsZero() {
+		return fmt.Errorf("legacy request digest end time must be set if a legacy request digest is accepted")
+	}
+
+	if config.RequestTimeoutPerCall != 0 && config.MaxScaledRequestTimeout <= 0 {
+		return fmt.Errorf("max scaled request timeout must be set if the request timeout is scaled by the number of calls")
+	}
+	maxRetryInterval := config.MaxRetryInterval
+	if maxRetryInterval == 0 {
+		maxRetryInterval = DefaultMaxRetryInterval
+	}
+
+	timeouts := requestTimeoutPolicy{
+		defaultTimeout: requestTimeoutImpl,
+		chainTimeouts:  config.RequestTimeouts,
+		perCall:        config.RequestTimeoutPerCall,
+		maxScaled:      config.MaxScaledRequestTimeout,
+	}
+
+	// bwQuota is nil if bandwidth is not limited.
+	bwQuota := newBandwidthQuota(config.BandwidthQuotaBytes, config.BandwidthQuotaWindow)
+
+	// resultCache is nil if results are not cached.
+	resultCache := newResultCache(config.CachedResultMaxAge)
+
+	// ndThrottle is nil if near duplicates are not throttled.
+	ndThrottle := newNearDuplicateThrottle(config.NearDuplicateThreshold, config.NearDuplicateWindow)
+
+	// admission is nil if requests are never shed under load.
+	admission := newAdmissionControl(config.LoadSignal, config.MaxCpuLoad, config.MaxMemoryLoad)
+
+	// keyRotationInProgress is set while the response signing key is being rotated. See HandlerConfig.KeyRotationC.
+	keyRotationInProgress := false
+
+	// signerAvailability is nil if the response signing key is always assumed to be available.
+	signerAvailability := config.SignerAvailability
+	if signerAvailability == nil {
+		signerAvailability, _ = config.ResponseSigner.(SignerAvailability)
+	}
+
+	// pricing is nil if requests are not priced.
+	pricing, err := newRequestPricing(config.RequestPricer, config.BalanceProvider)
+	if err != nil {
+		return err
+	}
+
+	// Watchers may register after the handler has started, so work on a copy of the channels rather than the caller's map.
+	if config.watcherRegistrationC != nil {
+		channels := make(map[vaa.ChainID]chan *PerChainQueryInternal, len(chainQueryReqC))
+		for chainID, channel := range chainQueryReqC {
+			channels[chainID] = channel
+		}
+		chainQueryReqC = channels
+	}
+
 	// Create the set of chains for which CCQ is actually enabled. Those are the ones in the config for which we actually have a watcher enabled.
 	supportedChains := make(map[vaa.ChainID]struct{})
 	for chainID, config := range perChainConfig {
@@ -208,14 +446,91 @@ func handleQueryRequestsImpl(
 		}
 	}
 
+	// If an external publisher is configured, it runs in its own routine so that it can never block the handler.
+	extPub := newExternalPublisher(qLogger, config.Publisher)
+	var extPubErrC chan error
+	if extPub != nil {
+		qLogger.Info("external query response publisher is enabled")
+		extPubErrC = make(chan error, 1)
+		common.RunWithScissors(ctx, extPubErrC, "query_external_publisher", extPub.run)
+	}
+
+	// Likewise for the post publish hooks.
+	hooks := newPostPublishHooks(qLogger, config.PostPublishHooks)
+	var hooksErrC chan error
+	if hooks != nil {
+		qLogger.Info("post publish hooks are enabled", zap.Int("numHooks", len(config.PostPublishHooks)))
+		hooksErrC = make(chan error, 1)
+		common.RunWithScissors(ctx, hooksErrC, "query_post_publish_hooks", hooks.run)
+	}
+
+	// lastSequence is the sequence number of the last response published to p2p. See ResponseMetadata.Sequence.
+	var lastSequence uint64
+
+	// inFlight is nil if identical requests are not coalesced.
+	inFlight := newInFlightRequests(config.CoalesceIdenticalRequests)
+
+	// inFlightPerChain is nil if identical per chain queries are not coalesced.
+	inFlightPerChain := newInFlightPerChainQueries(config.CoalesceIdenticalPerChainQueries)
+
+	// publishFollowers publishes the requests that were coalesced with a request that has just been answered, each with its own signed request.
+	publishFollowers := func(leader *pendingQuery) {
+		for _, follower := range leader.followers {
+			if pendingQueries[follower.requestID] != follower || follower.leader != leader {
+				continue
+			}
+			follower.adoptResults()
+			follower.logger.Info("publishing the results of a coalesced request", zap.String("requestID", follower.requestID), zap.String("leaderRequestID", leader.requestID))
+			var done bool
+			if follower.request.AllowPartialResults {
+				done = follower.publishPartialResults(follower.logger, config, queryResponseWriteC, &lastSequence, extPub, bwQuota, pricing, hooks)
+			} else {
+				done = !follower.createResponsePublication(follower.logger, config) ||
+					follower.publishResponse(follower.logger, queryResponseWriteC, config.LocalSink, config.IncludePublishAttempts, &lastSequence, config.GossipStatus, extPub, bwQuota, pricing, hooks)
+			}
+			if done {
+				removePendingQuery(pendingQueries, follower.requestID)
+			}
+		}
+		leader.followers = nil
+	}
+
+	// publishAnswered publishes a request whose last outstanding per chain query has been answered, along with any requests coalesced with it.
+	publishAnswered := func(pq *pendingQuery) {
+		if pq.preload {
+			pq.logger.Info("preloaded query results into the cache", zap.String("requestID", pq.requestID))
+			removePendingQuery(pendingQueries, pq.requestID)
+			return
+		}
+
+		// Build the overall query response publication, and send it to be published. If any destination does not accept it, it will be retried next interval.
+		if !pq.createResponsePublication(pq.logger, config) {
+			removePendingQuery(pendingQueries, pq.requestID)
+		} else if pq.publishResponse(pq.logger, queryResponseWriteC, config.LocalSink, config.IncludePublishAttempts, &lastSequence, config.GossipStatus, extPub, bwQuota, pricing, hooks) {
+			removePendingQuery(pendingQueries, pq.requestID)
+		}
+		publishFollowers(pq)
+	}
+
+	// grace is nil if requests for chains whose watchers have not registered are not held. Released requests are read along with the inbound ones.
+	var grace *registrationGrace
+	grace, signedQueryReqC = newRegistrationGrace(ctx, config.WatcherRegistrationGracePeriod, signedQueryReqC)
+
 	ticker := time.NewTicker(auditIntervalImpl)
 	defer ticker.Stop()
 
 	for {
+		pendingQueryRequests.Set(float64(len(pendingQueries)))
 		select {
 		case <-ctx.Done():
 			return nil
 
+		case err := <-extPubErrC:
+			return fmt.Errorf("external query response publisher failed: %w", err)
+
+		case err := <-hooksErrC:
+			return fmt.Errorf("query post publish hooks failed: %w", err)
+
 		case signedRequest := <-signedQueryReqC: // Inbound query request.
 			// requestor validation happens here
 			// request type validation is currently handled by the watcher
@@ -227,46 +542,237 @@ func handleQueryRequestsImpl(
 			// - valid "block" strings
 
 			allQueryRequestsReceived.Inc()
+			receiveTime := time.Now()
 			digest := QueryRequestDigest(env, signedRequest.QueryRequest)
+			requestID := queryRequestID(signedRequest.Signature, digest)
 
-			// It's possible that the signature alone is not unique, and the digest alone is not unique, but the combination should be.
-			requestID := hex.EncodeToString(signedRequest.Signature) + ":" + digest.String()
+			// Every log line emitted while processing the request carries its short correlation ID, and so do the log lines of the watchers.
+			correlationID := queryCorrelationID(requestID)
+			cLogger := qLogger.With(zap.String("requestId", correlationID))
 
-			qLogger.Info("received a query request", zap.String("requestID", requestID))
+			cLogger.Info("received a query request", zap.String("requestID", requestID), zap.Stringer("receiveTime", receiveTime))
 
 			signerBytes, err := ethCrypto.Ecrecover(digest.Bytes(), signedRequest.Signature)
 			if err != nil {
-				qLogger.Error("failed to recover public key", zap.String("requestID", requestID))
+				cLogger.Error("failed to recover public key", zap.String("requestID", requestID))
 				invalidQueryRequestReceived.WithLabelValues("failed_to_recover_public_key").Inc()
 				continue
 			}
 
 			signerAddress := ethCommon.BytesToAddress(ethCrypto.Keccak256(signerBytes[1:])[12:])
 
+			// During a digest migration, a requestor may still be signing the legacy digest.
+			if _, exists := allowedRequestors[signerAddress]; !exists && config.LegacyRequestDigest != NoLegacyRequestDigest && receiveTime.Before(config.LegacyRequestDigestUntil) {
+				if legacySigner, allowed := legacyDigestSigner(config.LegacyRequestDigest, env, signedRequest, allowedRequestors); allowed {
+					cLogger.Warn("accepting request signed with the legacy request digest",
+						zap.String("requestor", legacySigner.Hex()),
+						zap.String("requestID", requestID),
+						zap.String("legacyRequestDigest", string(config.LegacyRequestDigest)),
+						zap.Stringer("legacyRequestDigestUntil", config.LegacyRequestDigestUntil),
+					)
+					legacyDigestRequestsAccepted.Inc()
+					signerAddress = legacySigner
+				}
+			}
+
+			// Use the log level configured for this signer, if any, for the rest of the processing of the request.
+			rLogger := config.SignerLogLevels.logger(cLogger, signerAddress)
+
 			if _, exists := allowedRequestors[signerAddress]; !exists {
-				qLogger.Debug("invalid requestor", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID))
+				if config.EnforceRequestEnvironment {
+					if signer, declaredEnv, wrongEnv := wrongEnvironmentSigner(env, signedRequest, allowedRequestors); wrongEnv {
+						cLogger.Warn("request was signed for a different environment, dropping it",
+							zap.String("requestor", signer.Hex()),
+							zap.String("requestID", requestID),
+							zap.Stringer("requestEnvironment", declaredEnv),
+							zap.Stringer("guardianEnvironment", RequestEnvironmentFor(env)),
+						)
+						reportFailure(cLogger, config.FailureC, requestID, signer, WrongEnvironment)
+						continue
+					}
+				}
+				rLogger.Debug("invalid requestor", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID))
 				invalidQueryRequestReceived.WithLabelValues("invalid_requestor").Inc()
 				continue
 			}
 
 			// Make sure this is not a duplicate request. TODO: Should we do something smarter here than just dropping the duplicate?
 			if oldReq, exists := pendingQueries[requestID]; exists {
-				qLogger.Warn("dropping duplicate query request", zap.String("requestID", requestID), zap.Stringer("origRecvTime", oldReq.receiveTime))
+				rLogger.Warn("dropping duplicate query request", zap.String("requestID", requestID), zap.Stringer("origRecvTime", oldReq.receiveTime))
 				invalidQueryRequestReceived.WithLabelValues("duplicate_request").Inc()
 				continue
 			}
 
+			// Shed new requests before doing any work on them if the node is under resource pressure.
+			if overloaded, cpu, memory := admission.overloaded(); overloaded {
+				rLogger.Warn("node is overloaded, dropping request",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+					zap.Float64("cpuLoad", cpu),
+					zap.Float64("memoryLoad", memory),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, Overloaded)
+				continue
+			}
+
+			if keyRotationInProgress {
+				rLogger.Warn("response signing key is being rotated, dropping request", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID))
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, KeyRotationInProgress)
+				continue
+			}
+
+			if signerAvailability != nil {
+				if err := signerAvailability.Available(); err != nil {
+					rLogger.Warn("response signing key is unavailable, dropping request", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Error(err))
+					reportFailure(rLogger, config.FailureC, requestID, signerAddress, SigningUnavailable)
+					continue
+				}
+			}
+
 			var queryRequest QueryRequest
 			err = queryRequest.Unmarshal(signedRequest.QueryRequest)
 			if err != nil {
-				qLogger.Error("failed to unmarshal query request", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Error(err))
-				invalidQueryRequestReceived.WithLabelValues("failed_to_unmarshal_request").Inc()
+				rLogger.Error("failed to unmarshal query request", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Error(err))
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, RequestUnmarshalFailed)
 				continue
 			}
 
 			if err := queryRequest.Validate(); err != nil {
-				qLogger.Error("received invalid message", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Error(err))
-				invalidQueryRequestReceived.WithLabelValues("invalid_request").Inc()
+				rLogger.Error("received invalid message", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Error(err))
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, InvalidRequest)
+				continue
+			}
+
+			if err := queryRequest.ValidateLimits(config.RequestLimits, len(signedRequest.QueryRequest)); err != nil {
+				rLogger.Warn("request exceeds a request limit, dropping it", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Error(err))
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, limitFailureReason(err))
+				continue
+			}
+
+			if missingChains := unregisteredChains(queryRequest.PerChainQueries, chainQueryReqC); len(missingChains) != 0 {
+				if grace.hold(signedRequest, requestID, signerAddress, missingChains, receiveTime) {
+					rLogger.Info("request targets chains whose watchers have not registered yet, holding it", zap.String("requestID", requestID), zap.Any("missingChains", missingChains))
+					continue
+				}
+			}
+
+			// The request is only rate limited once it is not held, so a held request uses up a single token when it is released. A duplicate is
+			// dropped above without using up a token, since it is never processed.
+			if !restrictions.allow(signerAddress, receiveTime) {
+				rLogger.Warn("requestor exceeded its rate limit, dropping request", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID))
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, RateLimited)
+				continue
+			}
+
+			if config.EnforceRequestEnvironment && queryRequest.Environment != UnspecifiedEnvironment && queryRequest.Environment != RequestEnvironmentFor(env) {
+				rLogger.Warn("request declares a different environment, dropping it",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+					zap.Stringer("requestEnvironment", queryRequest.Environment),
+					zap.Stringer("guardianEnvironment", RequestEnvironmentFor(env)),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, WrongEnvironment)
+				continue
+			}
+
+			lastNonce, replayed := lastNonces.replayed(signerAddress, queryRequest.Nonce)
+			if replayed {
+				rLogger.Error("nonce is not greater than the last one accepted from this signer, dropping request",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+					zap.Uint32("nonce", queryRequest.Nonce),
+					zap.Uint32("lastNonce", lastNonce),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, BadNonce)
+				continue
+			}
+			if queryRequest.Nonce < lastNonce {
+				rLogger.Info("nonce has wrapped around",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+					zap.Uint32("nonce", queryRequest.Nonce),
+					zap.Uint32("lastNonce", lastNonce),
+				)
+			}
+
+			if recentRequests.replayed(requestID, receiveTime) {
+				rLogger.Error("the same signed request was accepted recently, dropping request",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+					zap.Uint32("nonce", queryRequest.Nonce),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, ReplayedRequest)
+				continue
+			}
+
+			if lastNonces.full(signerAddress, receiveTime) {
+				rLogger.Error("no room to track the nonces of this signer, dropping request",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+					zap.Int("maxNonceTrackedSigners", config.MaxNonceTrackedSigners),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, NonceTrackerFull)
+				continue
+			}
+
+			if bwQuota.exceeded(signerAddress, time.Now()) {
+				rLogger.Warn("requestor has exceeded its bandwidth quota for the current window, dropping request",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, BandwidthQuotaExceeded)
+				continue
+			}
+
+			if config.MaxTotalCalls != 0 {
+				if totalCalls := queryRequest.TotalCalls(); totalCalls > config.MaxTotalCalls {
+					rLogger.Warn("request contains too many calls, dropping it", zap.String("requestID", requestID), zap.Int("totalCalls", totalCalls), zap.Int("maxTotalCalls", config.MaxTotalCalls))
+					reportFailure(rLogger, config.FailureC, requestID, signerAddress, TooManyCalls)
+					continue
+				}
+			}
+
+			if config.RequireAllChainsWatched {
+				if missingChains := unwatchedChains(queryRequest.PerChainQueries, supportedChains, chainQueryReqC); len(missingChains) != 0 {
+					rLogger.Warn("request targets chains that are not watched, dropping request", zap.String("requestID", requestID), zap.Any("missingChains", missingChains))
+					publishFailure(rLogger, config.FailureC, &QueryFailure{RequestID: requestID, Signer: signerAddress, Reason: ChainsNotWatched, MissingChains: missingChains})
+					continue
+				}
+			}
+
+			var fingerprint ethCommon.Hash
+			if ndThrottle != nil {
+				fingerprint, err = requestFingerprint(&queryRequest)
+				if err != nil {
+					rLogger.Error("failed to compute request fingerprint", zap.String("requestID", requestID), zap.Error(err))
+					invalidQueryRequestReceived.WithLabelValues("failed_to_compute_fingerprint").Inc()
+					continue
+				}
+
+				if ndThrottle.exceeded(signerAddress, fingerprint, time.Now()) {
+					rLogger.Warn("requestor is flooding near duplicate requests, dropping request",
+						zap.String("requestor", signerAddress.Hex()),
+						zap.String("requestID", requestID),
+					)
+					reportFailure(rLogger, config.FailureC, requestID, signerAddress, NearDuplicateFlood)
+					continue
+				}
+			}
+
+			price, balance, err := pricing.price(signerAddress, &queryRequest)
+			if err != nil {
+				rLogger.Error("failed to price request, dropping it", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Error(err))
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, BalanceUnavailable)
+				continue
+			}
+			if price > balance {
+				rLogger.Warn("requestor cannot afford request, dropping it",
+					zap.String("requestor", signerAddress.Hex()),
+					zap.String("requestID", requestID),
+					zap.Uint64("price", price),
+					zap.Uint64("balance", balance),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, InsufficientBalance)
 				continue
 			}
 
@@ -274,12 +780,11 @@ func handleQueryRequestsImpl(
 			errorFound := false
 			queries := []*perChainQuery{}
 			responses := make([]*PerChainQueryResponseInternal, len(queryRequest.PerChainQueries))
-			receiveTime := time.Now()
 
 			for requestIdx, pcq := range queryRequest.PerChainQueries {
 				chainID := vaa.ChainID(pcq.ChainId)
 				if _, exists := supportedChains[chainID]; !exists {
-					qLogger.Debug("chain does not support cross chain queries", zap.String("requestID", requestID), zap.Stringer("chainID", chainID))
+					rLogger.Debug("chain does not support cross chain queries", zap.String("requestID", requestID), zap.Stringer("chainID", chainID))
 					invalidQueryRequestReceived.WithLabelValues("chain_does_not_support_ccq").Inc()
 					errorFound = true
 					break
@@ -287,19 +792,75 @@ func handleQueryRequestsImpl(
 
 				channel, channelExists := chainQueryReqC[chainID]
 				if !channelExists {
-					qLogger.Debug("unknown chain ID for query request, dropping it", zap.String("requestID", requestID), zap.Stringer("chain_id", chainID))
+					rLogger.Debug("unknown chain ID for query request, dropping it", zap.String("requestID", requestID), zap.Stringer("chain_id", chainID))
 					invalidQueryRequestReceived.WithLabelValues("failed_to_look_up_channel").Inc()
 					errorFound = true
 					break
 				}
 
+				if !config.QueryTypeFlags.IsEnabled(chainID, pcq.Query.Type()) {
+					rLogger.Warn("query type is disabled on this chain, dropping request", zap.String("requestID", requestID), zap.Stringer("chainID", chainID), zap.Uint8("queryType", uint8(pcq.Query.Type())))
+					reportFailure(rLogger, config.FailureC, requestID, signerAddress, QueryTypeDisabled)
+					errorFound = true
+					break
+				}
+
+				if !restrictions.permitsQueryType(signerAddress, pcq.Query.Type()) {
+					rLogger.Warn("requestor is not permitted to use this query type, dropping request",
+						zap.String("requestor", signerAddress.Hex()),
+						zap.String("requestID", requestID),
+						zap.Uint8("queryType", uint8(pcq.Query.Type())),
+					)
+					reportFailure(rLogger, config.FailureC, requestID, signerAddress, QueryTypeNotPermitted)
+					errorFound = true
+					break
+				}
+
+				if target, denied := config.SystemAddresses.deniedTarget(pcq); denied {
+					rLogger.Warn("query calls a system address on this chain, dropping request", zap.String("requestID", requestID), zap.Stringer("chainID", chainID), zap.String("target", target.Hex()))
+					reportFailure(rLogger, config.FailureC, requestID, signerAddress, SystemAddressNotQueryable)
+					errorFound = true
+					break
+				}
+
+				if callIdx, size, oversized := config.MaxCallDataSize.oversizedCall(pcq); oversized {
+					rLogger.Warn("query contains a call with too much data, dropping request",
+						zap.String("requestID", requestID),
+						zap.Stringer("chainID", chainID),
+						zap.Int("callIdx", callIdx),
+						zap.Int("size", size),
+						zap.Int("maxCallDataSize", config.MaxCallDataSize[chainID]),
+					)
+					reportFailure(rLogger, config.FailureC, requestID, signerAddress, CallDataTooLarge)
+					errorFound = true
+					break
+				}
+
+				if config.RejectDuplicateCallData {
+					if firstIdx, dupIdx, found := DuplicateCall(pcq); found {
+						rLogger.Warn("query contains the same call more than once, dropping request",
+							zap.String("requestID", requestID),
+							zap.Stringer("chainID", chainID),
+							zap.Int("firstCallIdx", firstIdx),
+							zap.Int("duplicateCallIdx", dupIdx),
+						)
+						reportFailure(rLogger, config.FailureC, requestID, signerAddress, DuplicateCallData)
+						errorFound = true
+						break
+					}
+				}
+
 				queries = append(queries, &perChainQuery{
 					req: &PerChainQueryInternal{
-						RequestID:  requestID,
-						RequestIdx: requestIdx,
-						Request:    pcq,
+						RequestID:          requestID,
+						RequestIdx:         requestIdx,
+						Request:            pcq,
+						AllowRevertedCalls: queryRequest.AllowRevertedCalls,
+						PreferSpeed:        queryRequest.PreferSpeed,
+						CorrelationID:      correlationID,
 					},
-					channel: channel,
+					channel:          channel,
+					failoverChannels: config.FailoverChainQueryReqC[chainID],
 				})
 			}
 
@@ -308,181 +869,844 @@ func handleQueryRequestsImpl(
 			}
 
 			validQueryRequestsReceived.Inc()
+			if config.DetectIdenticalCalls {
+				identicalCallsAcrossChains.Add(float64(logIdenticalCalls(rLogger, requestID, &queryRequest)))
+			}
+			ndThrottle.record(signerAddress, fingerprint, receiveTime)
+
+			// If configured, the per chain queries share a context that is cancelled if the request fails, so the watchers can stop working on them.
+			var cancel context.CancelFunc
+			if config.CancelOnFatalError {
+				var reqCtx context.Context
+				reqCtx, cancel = context.WithCancel(context.Background())
+				for _, pcq := range queries {
+					pcq.req.ctx = reqCtx
+				}
+			}
 
 			// Create the pending query and add it to the cache.
 			pq := &pendingQuery{
 				signedRequest: signedRequest,
 				request:       &queryRequest,
 				requestID:     requestID,
+				signer:        signerAddress,
 				receiveTime:   receiveTime,
+				logger:        rLogger,
 				queries:       queries,
 				responses:     responses,
+				retryBudget:   effectiveRetryBudget(config, queryRequest.RetryBudget),
+				cancel:        cancel,
+			}
+
+			// A request that is byte-identical to one from another signer that is already in flight is published with its results, rather than
+			// being dispatched again.
+			if leader := inFlight.leader(signedRequest, pendingQueries); leader != nil {
+				rLogger.Info("request is identical to one already in flight, coalescing it", zap.String("requestID", requestID), zap.String("leaderRequestID", leader.requestID))
+				coalescedQueryRequests.Inc()
+				pq.follow(leader)
+				lastNonces.record(signerAddress, queryRequest.Nonce, receiveTime)
+				recentRequests.record(requestID, receiveTime)
+				pendingQueries[requestID] = pq
+				if err := pricing.charge(pq, price); err != nil {
+					rLogger.Error("failed to charge requestor for request", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Uint64("price", price), zap.Error(err))
+					balanceChargeFailures.Inc()
+				}
+				continue
 			}
-			pendingQueries[requestID] = pq
 
-			// Forward the requests to the watchers.
+			// Per chain queries whose results were preloaded are answered from the cache, rather than being dispatched to the watchers.
 			for _, pcq := range pq.queries {
-				pcq.ccqForwardToWatcher(qLogger, pq.receiveTime)
+				if cachedResp := resultCache.preloadedResponse(pcq.req, receiveTime); cachedResp != nil {
+					rLogger.Info("answering per chain query from preloaded results", zap.String("requestID", requestID), zap.Int("requestIdx", pcq.req.RequestIdx))
+					preloadedResultsServedByChain.WithLabelValues(pcq.req.Request.ChainId.String()).Inc()
+					pq.responses[pcq.req.RequestIdx] = cachedResp
+				}
+			}
+
+			if config.SlaQueryTimeEstimate != 0 && pq.slaCannotBeMet(pq.queries, chainBacklog(pendingQueries), config.SlaQueryTimeEstimate, receiveTime) {
+				rLogger.Warn("request cannot be answered within its sla tier given the current backlog, dropping request",
+					zap.String("requestID", requestID),
+					zap.Uint8("slaTier", uint8(queryRequest.SlaTier)),
+					zap.Duration("deadline", queryRequest.SlaTier.Deadline()),
+				)
+				reportFailure(rLogger, config.FailureC, requestID, signerAddress, SlaCannotBeMet)
+				if cancel != nil {
+					cancel()
+				}
+				continue
+			}
+
+			// Only now is the request accepted, so a request rejected above does not use up its nonce, and may be retried as is.
+			lastNonces.record(signerAddress, queryRequest.Nonce, receiveTime)
+			recentRequests.record(requestID, receiveTime)
+			pendingQueries[requestID] = pq
+
+			// Forward the requests to the watchers, highest weight chains first.
+			for _, pcq := range config.ChainWeights.dispatchOrder(pq.queries) {
+				if pq.responses[pcq.req.RequestIdx] != nil {
+					continue
+				}
+				// A per chain query that is identical to one of another request that is already in flight is answered with its response.
+				if leader := inFlightPerChain.leader(pq, pcq.req.RequestIdx, pendingQueries); leader != nil {
+					rLogger.Info("per chain query is identical to one already in flight, coalescing it",
+						zap.String("requestID", requestID),
+						zap.Int("requestIdx", pcq.req.RequestIdx),
+						zap.String("leaderRequestID", leader.pq.requestID),
+						zap.Int("leaderRequestIdx", leader.requestIdx),
+					)
+					coalescedPerChainQueriesByChain.WithLabelValues(pcq.req.Request.ChainId.String()).Inc()
+					pq.followPerChainQuery(pcq.req.RequestIdx, leader)
+					continue
+				}
+				if !pcq.ccqForwardToAvailableWatcher(rLogger, pq.receiveTime) {
+					reportWatcherGone(rLogger, config.FailureC, pq, pcq)
+					removePendingQuery(pendingQueries, requestID)
+					break
+				}
+				inFlightPerChain.add(pq, pcq.req.RequestIdx)
+			}
+
+			if _, exists := pendingQueries[requestID]; exists {
+				inFlight.add(pq)
+				if err := pricing.charge(pq, price); err != nil {
+					rLogger.Error("failed to charge requestor for request", zap.String("requestor", signerAddress.Hex()), zap.String("requestID", requestID), zap.Uint64("price", price), zap.Error(err))
+					balanceChargeFailures.Inc()
+				}
+
+				// If every per chain query was answered from preloaded results, the request can be published straight away.
+				if pq.numPendingRequests() == 0 {
+					rLogger.Info("all per chain queries were answered from preloaded results, ready to publish", zap.String("requestID", requestID))
+					if !pq.createResponsePublication(rLogger, config) {
+						removePendingQuery(pendingQueries, requestID)
+					} else if pq.publishResponse(rLogger, queryResponseWriteC, config.LocalSink, config.IncludePublishAttempts, &lastSequence, config.GossipStatus, extPub, bwQuota, pricing, hooks) {
+						removePendingQuery(pendingQueries, requestID)
+					}
+				}
 			}
 
 		case resp := <-queryResponseReadC: // Response from a watcher.
+			rLogger := qLogger
+			if pq, exists := pendingQueries[resp.RequestID]; exists {
+				rLogger = pq.logger
+				if reason := pq.invalidResponseReason(resp); reason != "" {
+					rLogger.Warn("received a response that does not match an outstanding per chain query, dropping it",
+						zap.String("requestID", resp.RequestID),
+						zap.Int("requestIdx", resp.RequestIdx),
+						zap.Stringer("chainID", resp.ChainId),
+						zap.Int("status", int(resp.Status)),
+						zap.String("reason", reason),
+					)
+					invalidQueryResponsesReceived.WithLabelValues(reason).Inc()
+					continue
+				}
+
+				if resp.Status == QueryFatalError {
+					if cachedResp := resultCache.fallbackResponse(pq, resp, time.Now()); cachedResp != nil {
+						rLogger.Warn("received a fatal error response, serving a cached result instead", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+						cachedResultsServedByChain.WithLabelValues(resp.ChainId.String()).Inc()
+						resp = cachedResp
+					}
+				}
+			}
+
 			if resp.Status == QuerySuccess {
 				successfulQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
 				if resp.Response == nil {
-					qLogger.Error("received a successful query response with no results, dropping it!", zap.String("requestID", resp.RequestID))
+					rLogger.Error("received a successful query response with no results, dropping it!", zap.String("requestID", resp.RequestID))
 					continue
 				}
 
 				pq, exists := pendingQueries[resp.RequestID]
 				if !exists {
-					qLogger.Warn("received a success response with no outstanding query, dropping it", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+					rLogger.Warn("received a success response with no outstanding query, dropping it", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
 					continue
 				}
 
-				if resp.RequestIdx >= len(pq.responses) {
-					qLogger.Error("received a response with an invalid index", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+				// If the block used is older than the requester allows, treat it like a retry needed response. Cached results are exempt,
+				// since the requester has already accepted that they may be stale.
+				if blockAge, stale := pq.staleBlockAge(resp, time.Now()); stale {
+					rLogger.Info("received a response for a block that is too old, will retry next interval",
+						zap.String("requestID", resp.RequestID),
+						zap.Int("requestIdx", resp.RequestIdx),
+						zap.Duration("blockAge", blockAge),
+						zap.Uint32("maxBlockAge", pq.request.PerChainQueries[resp.RequestIdx].MaxBlockAge),
+					)
+					staleQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
+					pq.queries[resp.RequestIdx].resetBackoff()
+					pq.queries[resp.RequestIdx].recordAttempt(QueryRetryNeeded, fmt.Sprintf("block is %s old, which is older than the max block age", blockAge.Round(time.Second)), nil)
 					continue
 				}
 
 				// Store the result, which will mark this per-chain query as completed.
 				pq.responses[resp.RequestIdx] = resp
+				queryLatencyByChain.WithLabelValues(resp.ChainId.String()).Observe(float64(time.Since(pq.receiveTime).Milliseconds()))
+				// Results that may be inconsistent, because the requester preferred speed, are not cached, so they are never served to other requesters.
+				if !pq.request.PreferSpeed {
+					if err := resultCache.store(pq.request.PerChainQueries[resp.RequestIdx], resp, time.Now(), pq.preload); err != nil {
+						rLogger.Error("failed to cache per chain query result", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Error(err))
+					}
+				}
+
+				// Answer the identical per chain queries of other requests, publishing any of them that are now complete.
+				for _, answered := range pq.answerFollowers(resp.RequestIdx, pendingQueries) {
+					answered.logger.Info("coalesced per chain query was answered, ready to publish", zap.String("requestID", answered.requestID), zap.String("leaderRequestID", pq.requestID))
+					publishAnswered(answered)
+				}
 
 				// If we still have other outstanding per chain queries for this request, keep waiting.
 				numStillPending := pq.numPendingRequests()
 				if numStillPending > 0 {
-					qLogger.Info("received a per chain query response, still waiting for more", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Int("numStillPending", numStillPending))
+					rLogger.Info("received a per chain query response, still waiting for more", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Int("numStillPending", numStillPending))
 					continue
 				} else {
-					qLogger.Info("received final per chain query response, ready to publish", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+					rLogger.Info("received final per chain query response, ready to publish",
+						zap.String("requestID", resp.RequestID),
+						zap.Int("requestIdx", resp.RequestIdx),
+						zap.Stringer("receiveTime", pq.receiveTime),
+						zap.Duration("latency", time.Since(pq.receiveTime)),
+					)
 				}
 
-				// Build the list of per chain response publications and the overall query response publication.
-				responses := []*PerChainQueryResponse{}
-				for _, resp := range pq.responses {
-					if resp == nil {
-						qLogger.Error("unexpected null response in pending query!", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+				publishAnswered(pq)
+			} else if resp.Status == QueryRetryNeeded {
+				retryNeededQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
+				if pq, exists := pendingQueries[resp.RequestID]; exists {
+					pq.queries[resp.RequestIdx].recordAttempt(resp.Status, resp.Metadata.errorString(), resp.Metadata.rpcError())
+					rLogger.Warn("query failed, will retry next interval", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+				} else {
+					rLogger.Warn("received a retry needed response with no outstanding query, dropping it", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+				}
+			} else if resp.Status == QueryFatalError {
+				fatalQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
+				if pq, exists := pendingQueries[resp.RequestID]; exists {
+					pcq := pq.queries[resp.RequestIdx]
+					pcq.recordAttempt(resp.Status, resp.Metadata.errorString(), resp.Metadata.rpcError())
+					if pcq.failover() {
+						rLogger.Warn("received a fatal error response, failing over to the next watcher", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Int("numRemainingFailovers", len(pcq.failoverChannels)))
+						watcherFailoversByChain.WithLabelValues(resp.ChainId.String()).Inc()
+						if !pcq.ccqForwardToAvailableWatcher(rLogger, time.Now()) {
+							reportWatcherGone(rLogger, config.FailureC, pq, pcq)
+							removePendingQuery(pendingQueries, resp.RequestID)
+						}
 						continue
 					}
-
-					responses = append(responses, &PerChainQueryResponse{
-						ChainId:  resp.ChainId,
-						Response: resp.Response,
+					if pq.request.AllowPartialResults {
+						pcq.failed = true
+						numStillPending := pq.numPendingRequests()
+						rLogger.Warn("received a fatal error response, omitting the per chain query from the partial results", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Int("numStillPending", numStillPending))
+						if numStillPending == 0 {
+							if pq.publishPartialResults(rLogger, config, queryResponseWriteC, &lastSequence, extPub, bwQuota, pricing, hooks) {
+								removePendingQuery(pendingQueries, resp.RequestID)
+							}
+							publishFollowers(pq)
+						}
+						continue
+					}
+					if pq.cancel != nil {
+						rLogger.Info("cancelling the remaining per chain queries of a failed request", zap.String("requestID", resp.RequestID), zap.Int("numStillPending", pq.numPendingRequests()-1))
+					}
+				}
+				rLogger.Error("received a fatal error response, dropping the whole request", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+				removePendingQuery(pendingQueries, resp.RequestID)
+			} else if resp.Status == QueryNotFound {
+				notFoundQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
+				if pq, exists := pendingQueries[resp.RequestID]; exists {
+					rLogger.Warn("received a not found response, dropping the whole request", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.String("error", resp.Metadata.errorString()))
+					publishFailure(rLogger, config.FailureC, &QueryFailure{
+						RequestID:     pq.requestID,
+						Signer:        pq.signer,
+						Reason:        NotFound,
+						NotFoundQuery: &NotFoundQuery{RequestIdx: resp.RequestIdx, ChainId: resp.ChainId, Error: resp.Metadata.errorString(), RpcError: resp.Metadata.rpcError()},
 					})
+					removePendingQuery(pendingQueries, resp.RequestID)
+				} else {
+					rLogger.Warn("received a not found response with no outstanding query, dropping it", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+				}
+			} else if resp.Status == QueryNodeSyncing {
+				nodeSyncingQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
+				if pq, exists := pendingQueries[resp.RequestID]; exists {
+					pq.queries[resp.RequestIdx].recordAttempt(resp.Status, resp.Metadata.errorString(), resp.Metadata.rpcError())
+					if config.DeferWhileNodeSyncing {
+						rLogger.Warn("node is syncing, will retry next interval", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Stringer("chainID", resp.ChainId))
+						continue
+					}
+					rLogger.Warn("node is syncing, dropping the whole request", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Stringer("chainID", resp.ChainId))
+					publishFailure(rLogger, config.FailureC, &QueryFailure{RequestID: pq.requestID, Signer: pq.signer, Reason: NodeSyncing, MissingChains: []vaa.ChainID{resp.ChainId}})
+					removePendingQuery(pendingQueries, resp.RequestID)
+				} else {
+					rLogger.Warn("received a node syncing response with no outstanding query, dropping it", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
 				}
+			} else {
+				rLogger.Error("received an unexpected query status, dropping the whole request", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Int("status", int(resp.Status)))
+				removePendingQuery(pendingQueries, resp.RequestID)
+			}
+
+		case update := <-config.allowedRequestorsUpdateC: // Operator request to replace the allow list.
+			allowedRequestors = update.allowedRequestors
+			restrictions = update.restrictions
+			qLogger.Info("allowed requestors updated", zap.Any("allowedRequestors", allowedRequestors))
 
-				respPub := &QueryResponsePublication{
-					Request:           pq.signedRequest,
-					PerChainResponses: responses,
+		case reg := <-config.watcherRegistrationC: // A watcher that started after the handler.
+			if existing, exists := chainQueryReqC[reg.chainID]; exists {
+				if existing == reg.queryReqC {
+					// The watcher restarted.
+					continue
 				}
+				qLogger.Warn("a watcher is already registered for the chain, ignoring the registration", zap.Stringer("chainID", reg.chainID))
+				continue
+			}
 
-				// Send the response to be published.
-				select {
-				case queryResponseWriteC <- respPub:
-					qLogger.Info("forwarded query response to p2p", zap.String("requestID", resp.RequestID))
-					queryResponsesPublished.Inc()
-					delete(pendingQueries, resp.RequestID)
-				default:
-					qLogger.Warn("failed to publish query response to p2p, will retry publishing next interval", zap.String("requestID", resp.RequestID))
-					pq.respPub = respPub
+			chainQueryReqC[reg.chainID] = reg.queryReqC
+			if GetPerChainConfig(reg.chainID).NumWorkers > 0 {
+				supportedChains[reg.chainID] = struct{}{}
+				totalRequestsByChain.WithLabelValues(reg.chainID.String()).Add(0)
+			}
+			qLogger.Info("watcher registered, queries supported on chain", zap.Stringer("chainID", reg.chainID))
+			grace.release(chainQueryReqC)
+
+		case signer := <-config.CancelSignerC: // Operator request to cancel everything from a signer.
+			numCancelled := 0
+			for reqId, pq := range pendingQueries {
+				if pq.signer == signer {
+					reportFailure(qLogger, config.FailureC, reqId, signer, Cancelled)
+					removePendingQuery(pendingQueries, reqId)
+					numCancelled++
 				}
-			} else if resp.Status == QueryRetryNeeded {
-				retryNeededQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
-				if _, exists := pendingQueries[resp.RequestID]; exists {
-					qLogger.Warn("query failed, will retry next interval", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
-				} else {
-					qLogger.Warn("received a retry needed response with no outstanding query, dropping it", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
+			}
+			qLogger.Warn("cancelled all in-flight query requests from signer", zap.String("signer", signer.Hex()), zap.Int("numCancelled", numCancelled))
+
+		case inProgress := <-config.KeyRotationC: // Signal that a rotation of the response signing key has started or completed.
+			keyRotationInProgress = inProgress
+			qLogger.Info("response signing key rotation status changed", zap.Bool("inProgress", inProgress))
+
+		case queryRequest := <-config.PreloadC: // Operator request to preload the result cache.
+			if resultCache == nil {
+				qLogger.Error("cannot preload query results because the result cache is not enabled")
+				continue
+			}
+
+			pq, err := newPreloadQuery(qLogger, env, queryRequest, chainQueryReqC, config, time.Now())
+			if err != nil {
+				qLogger.Error("failed to preload query request", zap.Error(err))
+				continue
+			}
+			if _, exists := pendingQueries[pq.requestID]; exists {
+				qLogger.Info("query request is already being preloaded", zap.String("requestID", pq.requestID))
+				continue
+			}
+
+			qLogger.Info("preloading query request", zap.String("requestID", pq.requestID), zap.Int("numPerChainQueries", len(pq.queries)))
+			pendingQueries[pq.requestID] = pq
+			for _, pcq := range config.ChainWeights.dispatchOrder(pq.queries) {
+				if !pcq.ccqForwardToAvailableWatcher(qLogger, pq.receiveTime) {
+					reportWatcherGone(qLogger, config.FailureC, pq, pcq)
+					removePendingQuery(pendingQueries, pq.requestID)
+					break
 				}
-			} else if resp.Status == QueryFatalError {
-				fatalQueryResponsesReceivedByChain.WithLabelValues(resp.ChainId.String()).Inc()
-				qLogger.Error("received a fatal error response, dropping the whole request", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx))
-				delete(pendingQueries, resp.RequestID)
-			} else {
-				qLogger.Error("received an unexpected query status, dropping the whole request", zap.String("requestID", resp.RequestID), zap.Int("requestIdx", resp.RequestIdx), zap.Int("status", int(resp.Status)))
-				delete(pendingQueries, resp.RequestID)
 			}
 
 		case <-ticker.C: // Retry audit timer.
 			now := time.Now()
+			resultCache.prune(now)
+			inFlight.prune(pendingQueries)
+			inFlightPerChain.prune(pendingQueries)
+			for _, hr := range grace.expire(now) {
+				missingChains := hr.stillMissing(chainQueryReqC)
+				qLogger.Warn("watchers did not register within the grace period, dropping request", zap.String("requestID", hr.requestID), zap.Any("missingChains", missingChains))
+				publishFailure(qLogger, config.FailureC, &QueryFailure{RequestID: hr.requestID, Signer: hr.signer, Reason: ChainsNotWatched, MissingChains: missingChains})
+			}
+			retries := []pendingRetry{}
 			for reqId, pq := range pendingQueries {
-				timeout := pq.receiveTime.Add(requestTimeoutImpl)
-				qLogger.Debug("audit", zap.String("requestId", reqId), zap.Stringer("receiveTime", pq.receiveTime), zap.Stringer("timeout", timeout))
-				if timeout.Before(now) {
-					qLogger.Debug("query request timed out, dropping it", zap.String("requestId", reqId), zap.Stringer("receiveTime", pq.receiveTime))
+				pq.logger.Debug("audit", zap.String("requestId", reqId), zap.Stringer("receiveTime", pq.receiveTime))
+				if pq.timedOut(now, timeouts) {
+					pq.logger.Debug("query request timed out, dropping it", zap.String("requestId", reqId), zap.Stringer("receiveTime", pq.receiveTime))
 					queryRequestsTimedOut.Inc()
-					delete(pendingQueries, reqId)
+					removePendingQuery(pendingQueries, reqId)
+				} else if pq.leader != nil {
+					if !pq.leaderGone(pendingQueries) {
+						continue
+					}
+					// The request it was coalesced with was dropped without being answered, so dispatch it on its own.
+					pq.logger.Info("coalesced request is being dispatched on its own", zap.String("requestId", reqId), zap.String("leaderRequestID", pq.leader.requestID))
+					pq.leader = nil
+					for _, pcq := range config.ChainWeights.dispatchOrder(pq.queries) {
+						if !pcq.ccqForwardToAvailableWatcher(pq.logger, now) {
+							reportWatcherGone(pq.logger, config.FailureC, pq, pcq)
+							removePendingQuery(pendingQueries, reqId)
+							break
+						}
+					}
 				} else {
 					if pq.respPub != nil {
-						// Resend the response to be published.
-						select {
-						case queryResponseWriteC <- pq.respPub:
-							qLogger.Info("resend of query response to p2p succeeded", zap.String("requestID", reqId))
-							queryResponsesPublished.Inc()
-							delete(pendingQueries, reqId)
-						default:
-							qLogger.Warn("resend of query response to p2p failed again, will keep retrying", zap.String("requestID", reqId))
+						// Resend the response to whichever destinations have not accepted it yet.
+						if pq.publishResponse(pq.logger, queryResponseWriteC, config.LocalSink, config.IncludePublishAttempts, &lastSequence, config.GossipStatus, extPub, bwQuota, pricing, hooks) {
+							removePendingQuery(pendingQueries, reqId)
 						}
 					} else {
 						for requestIdx, pcq := range pq.queries {
-							if pq.responses[requestIdx] == nil && pcq.lastUpdateTime.Add(retryIntervalImpl).Before(now) {
-								qLogger.Info("retrying query request",
-									zap.String("requestId", reqId),
-									zap.Int("requestIdx", requestIdx),
-									zap.Stringer("receiveTime", pq.receiveTime),
-									zap.Stringer("lastUpdateTime", pcq.lastUpdateTime),
-									zap.String("chainID", pq.queries[requestIdx].req.Request.ChainId.String()),
-								)
-								pcq.ccqForwardToWatcher(qLogger, now)
+							if pcq.leader != nil && pq.isPending(requestIdx) {
+								if !pcq.leaderGone(pendingQueries) {
+									continue
+								}
+								// The per chain query it was coalesced with will not be answered, so dispatch it on its own.
+								pq.logger.Info("coalesced per chain query is being dispatched on its own", zap.String("requestID", reqId), zap.Int("requestIdx", requestIdx), zap.String("leaderRequestID", pcq.leader.pq.requestID))
+								pcq.leader = nil
+								if !pcq.ccqForwardToAvailableWatcher(pq.logger, now) {
+									reportWatcherGone(pq.logger, config.FailureC, pq, pcq)
+									removePendingQuery(pendingQueries, reqId)
+									break
+								}
+								inFlightPerChain.add(pq, requestIdx)
+								continue
+							}
+							if pq.isPending(requestIdx) && pcq.retryDue(now, retryIntervalImpl) {
+								if pq.retryBudget != 0 && pcq.numForwards > pq.retryBudget {
+									if pq.request.AllowPartialResults {
+										pcq.failed = true
+										pq.logger.Warn("retry budget exhausted, omitting the per chain query from the partial results", zap.String("requestId", reqId), zap.Int("requestIdx", requestIdx))
+										if pq.numPendingRequests() == 0 {
+											if pq.publishPartialResults(pq.logger, config, queryResponseWriteC, &lastSequence, extPub, bwQuota, pricing, hooks) {
+												removePendingQuery(pendingQueries, reqId)
+											}
+											publishFollowers(pq)
+										}
+										continue
+									}
+									pq.logger.Debug("retry budget exhausted, waiting for query request to time out",
+										zap.String("requestId", reqId),
+										zap.Int("requestIdx", requestIdx),
+										zap.Uint("retryBudget", pq.retryBudget),
+									)
+									continue
+								}
+								retries = append(retries, pendingRetry{pq: pq, requestIdx: requestIdx})
 							}
 						}
 					}
 				}
 			}
+
+			// Dispatch the retries across all of the pending queries, tightest sla tiers and highest weight chains first.
+			config.ChainWeights.sortRetries(retries)
+			var backlog map[vaa.ChainID]int
+			if config.SlaQueryTimeEstimate != 0 && len(retries) != 0 {
+				backlog = chainBacklog(pendingQueries)
+			}
+			for _, retry := range retries {
+				pq, pcq := retry.pq, retry.pq.queries[retry.requestIdx]
+				if _, exists := pendingQueries[pq.requestID]; !exists {
+					// An earlier per chain query in this request found that its watchers are gone, or that its sla cannot be met.
+					continue
+				}
+				if pq.slaCannotBeMet([]*perChainQuery{pcq}, backlog, config.SlaQueryTimeEstimate, now) {
+					pq.logger.Warn("retry cannot be answered within the sla tier of the request given the current backlog, dropping request",
+						zap.String("requestId", pq.requestID),
+						zap.Int("requestIdx", retry.requestIdx),
+						zap.Uint8("slaTier", uint8(pq.request.SlaTier)),
+						zap.Stringer("receiveTime", pq.receiveTime),
+					)
+					reportFailure(pq.logger, config.FailureC, pq.requestID, pq.signer, SlaCannotBeMet)
+					removePendingQuery(pendingQueries, pq.requestID)
+					continue
+				}
+				pq.logger.Info("retrying query request",
+					zap.String("requestId", pq.requestID),
+					zap.Int("requestIdx", retry.requestIdx),
+					zap.Stringer("receiveTime", pq.receiveTime),
+					zap.Stringer("lastUpdateTime", pcq.lastUpdateTime),
+					zap.String("chainID", pcq.req.Request.ChainId.String()),
+				)
+				pcq.retryIntervals = append(pcq.retryIntervals, now.Sub(pcq.lastUpdateTime))
+				pcq.backOff(retryIntervalImpl, maxRetryInterval)
+				if _, rotate := config.RotateWatchersOnTimeout[pcq.req.Request.ChainId]; rotate && pcq.awaitingResponse && pcq.rotate() {
+					pq.logger.Warn("watcher did not respond, rotating the retry to the next watcher",
+						zap.String("requestId", pq.requestID),
+						zap.Int("requestIdx", retry.requestIdx),
+						zap.String("chainID", pcq.req.Request.ChainId.String()),
+					)
+					watcherRotationsByChain.WithLabelValues(pcq.req.Request.ChainId.String()).Inc()
+				}
+				if !pcq.ccqForwardToAvailableWatcher(pq.logger, now) {
+					reportWatcherGone(pq.logger, config.FailureC, pq, pcq)
+					removePendingQuery(pendingQueries, pq.requestID)
+				}
+			}
 		}
 	}
 }
 
-// parseAllowedRequesters parses a comma separated list of allowed requesters into a map to be used for look ups.
-func parseAllowedRequesters(ccqAllowedRequesters string) (map[ethCommon.Address]struct{}, error) {
+// parseAllowedRequesters parses a comma separated list of allowed requesters into a map to be used for look ups. Each entry may optionally
+// be followed by a requests per second limit and the query types the requester may use, each separated by a colon, such as
+// "0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe:10:types=1/11", which are returned as its restrictions. Entries without them are not restricted.
+func parseAllowedRequesters(ccqAllowedRequesters string) (map[ethCommon.Address]struct{}, requesterRestrictions, error) {
 	if ccqAllowedRequesters == "" {
-		return nil, fmt.Errorf("if cross chain query is enabled `--ccqAllowedRequesters` must be specified")
+		return nil, nil, fmt.Errorf("if cross chain query is enabled `--ccqAllowedRequesters` must be specified")
 	}
 
 	var nullAddr ethCommon.Address
 	result := make(map[ethCommon.Address]struct{})
+	restrictions := make(requesterRestrictions)
 	for _, str := range strings.Split(ccqAllowedRequesters, ",") {
-		addr := ethCommon.BytesToAddress(ethCommon.Hex2Bytes(strings.TrimPrefix(str, "0x")))
+		fields := strings.Split(str, ":")
+		addr := ethCommon.BytesToAddress(ethCommon.Hex2Bytes(strings.TrimPrefix(fields[0], "0x")))
 		if addr == nullAddr {
-			return nil, fmt.Errorf("invalid value in `--ccqAllowedRequesters`: `%s`", str)
+			return nil, nil, fmt.Errorf("invalid value in `--ccqAllowedRequesters`: `%s`", str)
 		}
 		result[addr] = struct{}{}
+
+		restriction, err := parseRequesterRestriction(fields[1:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid restriction in `--ccqAllowedRequesters`: `%s`: %w", str, err)
+		}
+		if restriction != nil {
+			restrictions[addr] = restriction
+		} else {
+			delete(restrictions, addr)
+		}
 	}
 
 	if len(result) <= 0 {
-		return nil, fmt.Errorf("no allowed requestors specified, ccqAllowedRequesters: `%s`", ccqAllowedRequesters)
+		return nil, nil, fmt.Errorf("no allowed requestors specified, ccqAllowedRequesters: `%s`", ccqAllowedRequesters)
 	}
 
-	return result, nil
+	return result, restrictions, nil
 }
 
 // ccqForwardToWatcher submits a query request to the appropriate watcher. It updates the request object if the write succeeds.
 // If the write fails, it does not update the last update time, which will cause a retry next interval (until it times out)
-func (pcq *perChainQuery) ccqForwardToWatcher(qLogger *zap.Logger, receiveTime time.Time) {
+// It returns false if the watcher's channel has been closed, meaning the watcher is gone and the query will never be answered by it.
+func (pcq *perChainQuery) ccqForwardToWatcher(qLogger *zap.Logger, receiveTime time.Time) (watcherAvailable bool) {
+	// Sending on a closed channel panics, which is the only way for the sender to detect that the watcher has gone away.
+	defer func() {
+		if r := recover(); r != nil {
+			qLogger.Error("watcher channel is closed", zap.String("requestID", pcq.req.RequestID), zap.Stringer("chainID", pcq.req.Request.ChainId), zap.Any("error", r))
+			watcherAvailable = false
+		}
+	}()
+
 	select {
 	// TODO: only send the query request itself and reassemble in this module
 	case pcq.channel <- pcq.req:
 		qLogger.Debug("forwarded query request to watcher", zap.String("requestID", pcq.req.RequestID), zap.Stringer("chainID", pcq.req.Request.ChainId))
 		totalRequestsByChain.WithLabelValues(pcq.req.Request.ChainId.String()).Inc()
+		pcq.numForwards++
+		pcq.awaitingResponse = true
 	default:
 		qLogger.Warn("failed to send query request to watcher, will retry next interval", zap.String("requestID", pcq.req.RequestID), zap.Stringer("chain_id", pcq.req.Request.ChainId))
+		pcq.awaitingResponse = false
 	}
 	pcq.lastUpdateTime = receiveTime
+	return true
+}
+
+// ccqForwardToAvailableWatcher forwards the query to its watcher, failing over to the next watcher for each one whose channel has been closed.
+// It returns false if all of the watchers for the chain are gone.
+func (pcq *perChainQuery) ccqForwardToAvailableWatcher(qLogger *zap.Logger, receiveTime time.Time) bool {
+	for !pcq.ccqForwardToWatcher(qLogger, receiveTime) {
+		if !pcq.failover() {
+			return false
+		}
+		qLogger.Warn("watcher is gone, failing over to the next watcher", zap.String("requestID", pcq.req.RequestID), zap.Int("numRemainingFailovers", len(pcq.failoverChannels)))
+		watcherFailoversByChain.WithLabelValues(pcq.req.Request.ChainId.String()).Inc()
+	}
+	return true
+}
+
+// reportWatcherGone publishes a WatcherGone failure for a request containing a per chain query whose watchers are all gone.
+func reportWatcherGone(qLogger *zap.Logger, failureC chan<- *QueryFailure, pq *pendingQuery, pcq *perChainQuery) {
+	qLogger.Error("all watchers for a per chain query are gone, dropping the whole request", zap.String("requestID", pq.requestID), zap.Stringer("chainID", pcq.req.Request.ChainId))
+	publishFailure(qLogger, failureC, &QueryFailure{RequestID: pq.requestID, Signer: pq.signer, Reason: WatcherGone, MissingChains: []vaa.ChainID{pcq.req.Request.ChainId}})
+}
+
+// failover switches the per chain query to the next watcher in the failover list. It returns false if there are no more watchers to try.
+func (pcq *perChainQuery) failover() bool {
+	if len(pcq.failoverChannels) == 0 {
+		return false
+	}
+
+	pcq.channel = pcq.failoverChannels[0]
+	pcq.failoverChannels = pcq.failoverChannels[1:]
+	pcq.numForwards = 0
+	return true
+}
+
+// rotate switches the per chain query to the next watcher in the failover list, and moves the current watcher to the end of the list. Unlike
+// failover, it keeps the number of forwards, so the retry budget applies across all of the watchers. It returns false if there is only one watcher.
+func (pcq *perChainQuery) rotate() bool {
+	if len(pcq.failoverChannels) == 0 {
+		return false
+	}
+
+	// The failover list is shared with the handler config, so it must not be modified in place.
+	next := pcq.failoverChannels[0]
+	pcq.failoverChannels = append(append([]chan *PerChainQueryInternal{}, pcq.failoverChannels[1:]...), pcq.channel)
+	pcq.channel = next
+	return true
+}
+
+// recordAttempt adds an unsuccessful response to the retry history of the per chain query, and notes that the watcher responded.
+func (pcq *perChainQuery) recordAttempt(status QueryStatus, errStr string, rpcErr *RpcError) {
+	pcq.awaitingResponse = false
+	pcq.retryHistory = append(pcq.retryHistory, &RetryAttempt{Attempt: len(pcq.retryHistory) + 1, Status: status, Error: errStr, RpcError: rpcErr})
+}
+
+// removePendingQuery removes a request from the pending queries, whether it was answered or dropped, and cancels the context shared by its
+// per chain queries, so the watchers stop working on any that are still outstanding.
+func removePendingQuery(pendingQueries map[string]*pendingQuery, requestID string) {
+	if pq, exists := pendingQueries[requestID]; exists && pq.cancel != nil {
+		pq.cancel()
+	}
+	delete(pendingQueries, requestID)
+}
+
+// unwatchedChains returns the chains targeted by the per chain queries that do not support queries or do not have a watcher. Each chain is only listed once.
+func unwatchedChains(perChainQueries []*PerChainQueryRequest, supportedChains map[vaa.ChainID]struct{}, chainQueryReqC map[vaa.ChainID]chan *PerChainQueryInternal) []vaa.ChainID {
+	missingChains := []vaa.ChainID{}
+	seen := make(map[vaa.ChainID]struct{})
+	for _, pcq := range perChainQueries {
+		chainID := pcq.ChainId
+		if _, exists := seen[chainID]; exists {
+			continue
+		}
+		seen[chainID] = struct{}{}
+
+		_, supported := supportedChains[chainID]
+		_, watched := chainQueryReqC[chainID]
+		if !supported || !watched {
+			missingChains = append(missingChains, chainID)
+		}
+	}
+
+	return missingChains
+}
+
+// effectiveRetryBudget returns the retry budget to be used for a request. The requested budget is used if it is specified, otherwise the default is used.
+// Either way, it is clamped to the configured maximum. Zero means retry until the request times out.
+func effectiveRetryBudget(config HandlerConfig, requested uint8) uint {
+	budget := config.DefaultRetryBudget
+	if requested != 0 {
+		budget = uint(requested)
+	}
+
+	if config.MaxRetryBudget != 0 && (budget == 0 || budget > config.MaxRetryBudget) {
+		budget = config.MaxRetryBudget
+	}
+
+	return budget
+}
+
+// recordResponseSize adds the size of a published response to the bandwidth used by the signer of the request, and charges the signer for it.
+// It does nothing if bandwidth is not limited and requests are not priced.
+func recordResponseSize(qLogger *zap.Logger, bwQuota *bandwidthQuota, pricing *requestPricing, pq *pendingQuery, respPub *QueryResponsePublication) {
+	if bwQuota == nil && pricing == nil {
+		return
+	}
+
+	respBytes, err := respPub.Marshal()
+	if err != nil {
+		qLogger.Error("failed to marshal query response to determine its size", zap.String("requestID", pq.requestID), zap.Error(err))
+		return
+	}
+
+	bwQuota.record(pq.signer, len(respBytes), time.Now())
+	if err := pricing.chargeResponse(pq, len(respBytes)); err != nil {
+		qLogger.Error("failed to charge requestor for response", zap.String("requestor", pq.signer.Hex()), zap.String("requestID", pq.requestID), zap.Int("responseSize", len(respBytes)), zap.Error(err))
+		balanceChargeFailures.Inc()
+	}
+}
+
+// createResponsePublication builds the overall query response publication from the per chain responses, which must all have been received,
+// or, if the request allows partial results, failed, and stores it in the pending query. It returns false if the response is larger than
+// HandlerConfig.MaxResponsePublicationSize, in which case the request has been rejected with ResponseTooLarge and should be dropped.
+func (pq *pendingQuery) createResponsePublication(qLogger *zap.Logger, config HandlerConfig) bool {
+	responses := []*PerChainQueryResponse{}
+	metadata := &ResponseMetadata{}
+	if config.IncludeReceiveTime {
+		metadata.ReceiveTime = pq.receiveTime
+		metadata.PublishTime = time.Now()
+	}
+	if config.GuardianAddress != (ethCommon.Address{}) {
+		metadata.setGuardian(config.GuardianAddress, config.GuardianSetState)
+	}
+	var statuses []PerChainResultStatus
+	for requestIdx, resp := range pq.responses {
+		if resp == nil {
+			if !pq.queries[requestIdx].failed {
+				qLogger.Error("unexpected null response in pending query!", zap.String("requestID", pq.requestID), zap.Int("requestIdx", requestIdx))
+			}
+			if pq.request.AllowPartialResults {
+				statuses = append(statuses, PerChainResultFailed)
+			}
+			continue
+		}
+		if pq.request.AllowPartialResults {
+			statuses = append(statuses, PerChainResultSucceeded)
+		}
+
+		responses = append(responses, &PerChainQueryResponse{
+			ChainId:  resp.ChainId,
+			Response: pq.request.ResultNormalization.normalizeResponse(pq.request.PerChainQueries[requestIdx].filterResponse(resp.Response)),
+		})
+		var retryIntervals []time.Duration
+		if config.IncludeRetryIntervals {
+			retryIntervals = pq.queries[requestIdx].retryIntervals
+		}
+		metadata.PerChain = append(metadata.PerChain, resp.Metadata.withRetryHistory(pq.queries[requestIdx].retryHistory, retryIntervals))
+	}
+
+	pq.respPub = &QueryResponsePublication{
+		Request:           pq.signedRequest,
+		PerChainResponses: responses,
+		SchemaVersion:     pq.request.ResponseSchemaVersion,
+		HashAlgorithm:     pq.request.HashAlgorithm,
+		Nonce:             pq.request.Nonce,
+		PreferSpeed:       pq.request.PreferSpeed,
+		Statuses:          statuses,
+		Metadata:          metadata,
+	}
+
+	pq.respPub.Metadata.BlockSummary = pq.respPub.BlockSummary()
+
+	if root, err := pq.respPub.MerkleRoot(); err != nil {
+		qLogger.Error("failed to compute merkle root of response", zap.String("requestID", pq.requestID), zap.Error(err))
+	} else {
+		pq.respPub.Metadata.MerkleRoot = root
+	}
+
+	if config.MaxResponsePublicationSize != 0 || config.PeerAgreement != nil {
+		respBytes, err := pq.respPub.Marshal()
+		if err != nil {
+			qLogger.Error("failed to marshal query response", zap.String("requestID", pq.requestID), zap.Error(err))
+		} else {
+			if config.MaxResponsePublicationSize != 0 && len(respBytes) > config.MaxResponsePublicationSize {
+				qLogger.Error("query response is too large to publish, dropping request",
+					zap.String("requestID", pq.requestID),
+					zap.Int("responseSize", len(respBytes)),
+					zap.Int("maxResponsePublicationSize", config.MaxResponsePublicationSize),
+				)
+				reportFailure(qLogger, config.FailureC, pq.requestID, pq.signer, ResponseTooLarge)
+				return false
+			}
+			if config.PeerAgreement != nil {
+				digest := GetQueryResponseDigestFromBytes(respBytes)
+				metadata.MatchingPeers = config.PeerAgreement.matchingPeers(digest, config.GuardianAddress, config.GuardianSetState, time.Now())
+			}
+		}
+	}
+
+	return true
+}
+
+// publishResponse sends the response to p2p and, if a sink is configured, to the local sink, skipping whichever has already accepted it.
+// It returns true once all of them have accepted it, meaning the publication is complete and the pending query may be deleted.
+// The response sent to p2p records the next sequence number after lastSequence in its metadata, which is only consumed if p2p accepts it.
+// If includePublishAttempts is set, it also records the number of attempts, and if gossip is set, how the response was gossiped.
+func (pq *pendingQuery) publishResponse(
+	qLogger *zap.Logger,
+	queryResponseWriteC chan<- *QueryResponsePublication,
+	sink ResponseSink,
+	includePublishAttempts bool,
+	lastSequence *uint64,
+	gossip *GossipStatus,
+	extPub *externalPublisher,
+	bwQuota *bandwidthQuota,
+	pricing *requestPricing,
+	hooks *postPublishHooks,
+) bool {
+	if !pq.publishedToP2p {
+		pq.publishAttempts++
+		publishAttempts := 0
+		if includePublishAttempts {
+			publishAttempts = pq.publishAttempts
+		}
+		respPub := pq.respPub.forPublication(*lastSequence+1, publishAttempts, gossip.metadata())
+
+		select {
+		case queryResponseWriteC <- respPub:
+			*lastSequence++
+			qLogger.Info("forwarded query response to p2p",
+				zap.String("requestID", pq.requestID),
+				zap.Int("publishAttempts", pq.publishAttempts),
+				zap.Uint64("sequence", *lastSequence),
+			)
+			queryResponsesPublished.Inc()
+			queryResponsePublishAttempts.Observe(float64(pq.publishAttempts))
+			extPub.post(respPub)
+			hooks.post(respPub)
+			recordResponseSize(qLogger, bwQuota, pricing, pq, respPub)
+			pq.publishedToP2p = true
+			pq.respPub = respPub
+		default:
+			qLogger.Warn("failed to publish query response to p2p, will retry publishing next interval", zap.String("requestID", pq.requestID))
+		}
+	}
+
+	if sink != nil && !pq.storedInSink {
+		if err := sink.Store(pq.respPub); err != nil {
+			qLogger.Warn("failed to store query response in local sink, will retry next interval", zap.String("requestID", pq.requestID), zap.Error(err))
+			localSinkStoreFailures.Inc()
+		} else {
+			pq.storedInSink = true
+		}
+	}
+
+	return pq.publishedToP2p && (sink == nil || pq.storedInSink)
+}
+
+// forPublication returns a copy of the response whose metadata records its sequence number, the number of attempts needed to publish it
+// to p2p and how it was gossiped. The original is not modified, since it may already have been passed to the local sink.
+func (respPub *QueryResponsePublication) forPublication(sequence uint64, publishAttempts int, gossip *GossipMetadata) *QueryResponsePublication {
+	ret := *respPub
+	ret.Metadata = &ResponseMetadata{}
+	if respPub.Metadata != nil {
+		*ret.Metadata = *respPub.Metadata
+	}
+	ret.Metadata.Sequence = sequence
+	ret.Metadata.PublishAttempts = publishAttempts
+	ret.Metadata.Gossip = gossip
+	return &ret
+}
+
+// invalidResponseReason checks that a watcher response matches a per chain query in this request that is still awaiting a response.
+// It returns the reason the response is invalid, which is also used as a metric label, or the empty string if it is valid.
+func (pq *pendingQuery) invalidResponseReason(resp *PerChainQueryResponseInternal) string {
+	if resp.RequestIdx < 0 || resp.RequestIdx >= len(pq.queries) {
+		return "request_idx_out_of_range"
+	}
+
+	if resp.ChainId != pq.queries[resp.RequestIdx].req.Request.ChainId {
+		return "chain_id_mismatch"
+	}
+
+	if !pq.isPending(resp.RequestIdx) {
+		return "already_answered"
+	}
+
+	return ""
+}
+
+// staleBlockAge returns the age of the block used for a successful per chain response, and true if it is older than the max block age of
+// the per chain query. Responses served from the cache and responses that do not identify a block are never considered stale.
+func (pq *pendingQuery) staleBlockAge(resp *PerChainQueryResponseInternal, now time.Time) (time.Duration, bool) {
+	maxBlockAge := pq.request.PerChainQueries[resp.RequestIdx].MaxBlockAge
+	if maxBlockAge == 0 || resp.Metadata.servedFromCache() {
+		return 0, false
+	}
+
+	_, blockTime, ok := ResponseBlock(resp.Response)
+	if !ok {
+		return 0, false
+	}
+
+	blockAge := now.Sub(blockTime)
+	return blockAge, blockAge > time.Duration(maxBlockAge)*time.Second
 }
 
 // numPendingRequests returns the number of per chain queries in a request that are still awaiting responses. Zero means the request can now be published.
 func (pq *pendingQuery) numPendingRequests() int {
 	numPending := 0
-	for _, resp := range pq.responses {
-		if resp == nil {
+	for requestIdx := range pq.responses {
+		if pq.isPending(requestIdx) {
 			numPending += 1
 		}
 	}
@@ -490,6 +1714,47 @@ func (pq *pendingQuery) numPendingRequests() int {
 	return numPending
 }
 
+// isPending returns true if a per chain query has neither been answered nor, in a request that allows partial results, failed.
+func (pq *pendingQuery) isPending(requestIdx int) bool {
+	return pq.responses[requestIdx] == nil && !pq.queries[requestIdx].failed
+}
+
+// publishPartialResults is called once none of the per chain queries in a request that allows partial results are still pending. It publishes
+// the responses of the ones that succeeded. It returns true if the pending query may be deleted, either because the publication is complete, or
+// because there is nothing to publish, since every per chain query failed or the request is only a preload.
+func (pq *pendingQuery) publishPartialResults(
+	qLogger *zap.Logger,
+	config HandlerConfig,
+	queryResponseWriteC chan<- *QueryResponsePublication,
+	lastSequence *uint64,
+	extPub *externalPublisher,
+	bwQuota *bandwidthQuota,
+	pricing *requestPricing,
+	hooks *postPublishHooks,
+) bool {
+	if pq.preload {
+		qLogger.Info("preloaded partial query results into the cache", zap.String("requestID", pq.requestID))
+		return true
+	}
+
+	numSucceeded := 0
+	for _, resp := range pq.responses {
+		if resp != nil {
+			numSucceeded++
+		}
+	}
+	if numSucceeded == 0 {
+		qLogger.Error("every per chain query failed, dropping the whole request", zap.String("requestID", pq.requestID))
+		return true
+	}
+
+	qLogger.Info("publishing partial query results", zap.String("requestID", pq.requestID), zap.Int("numSucceeded", numSucceeded), zap.Int("numFailed", len(pq.responses)-numSucceeded))
+	if !pq.createResponsePublication(qLogger, config) {
+		return true
+	}
+	return pq.publishResponse(qLogger, queryResponseWriteC, config.LocalSink, config.IncludePublishAttempts, lastSequence, config.GossipStatus, extPub, bwQuota, pricing, hooks)
+}
+
 // StartWorkers is used by the watchers to start the query handler worker routines.
 func StartWorkers(
 	ctx context.Context,
@@ -509,8 +1774,15 @@ func StartWorkers(
 				case <-ctx.Done():
 					return nil
 				case queryRequest := <-queryReqC:
+					reqCtx, cancel := queryRequest.Context(ctx)
+					if reqCtx.Err() != nil {
+						logger.Debug("CONCURRENT: skipping cancelled query request", zap.Int("worker", workerId), zap.String("requestID", queryRequest.ID()))
+						cancel()
+						continue
+					}
 					logger.Debug("CONCURRENT: processing query request", zap.Int("worker", workerId))
-					w.QueryHandler(ctx, queryRequest)
+					w.QueryHandler(reqCtx, queryRequest)
+					cancel()
 					logger.Debug("CONCURRENT: finished processing query request", zap.Int("worker", workerId))
 				}
 			}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"math"
@@ -20,18 +21,27 @@ import (
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 const (
 	testSigner = "beFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe"
 
 	// Magic retry values used to cause special behavior in the watchers.
-	fatalError  = math.MaxInt
-	ignoreQuery = math.MaxInt - 1
+	fatalError       = math.MaxInt
+	ignoreQuery      = math.MaxInt - 1
+	ignoreAllQueries = math.MaxInt - 2
+	closeWatcher     = math.MaxInt - 3
+	notFound         = math.MaxInt - 4
+	nodeSyncing      = math.MaxInt - 5
 
 	// Speed things up for testing purposes.
 	requestTimeoutForTest = 100 * time.Millisecond
@@ -43,7 +53,7 @@ const (
 var (
 	nonce = uint32(0)
 
-	watcherChainsForTest = []vaa.ChainID{vaa.ChainIDPolygon, vaa.ChainIDBSC, vaa.ChainIDArbitrum}
+	watcherChainsForTest = []vaa.ChainID{vaa.ChainIDPolygon, vaa.ChainIDBSC, vaa.ChainIDArbitrum, vaa.ChainIDSolana}
 )
 
 // createPerChainQueryForEthCall creates a per chain query for an eth_call for use in tests. The To and Data fields are meaningless gibberish, not ABI.
@@ -132,6 +142,55 @@ func createPerChainQueryForEthCallWithFinality(
 	}
 }
 
+// createPerChainQueryForEthCallByTimestampList creates a per chain query for an eth_call_by_timestamp_list for use in tests. The To and Data fields are meaningless gibberish, not ABI.
+func createPerChainQueryForEthCallByTimestampList(
+	t *testing.T,
+	chainId vaa.ChainID,
+	timestamps []uint64,
+	numCalls int,
+) *PerChainQueryRequest {
+	t.Helper()
+	ethCallData := []*EthCallData{}
+	for count := 0; count < numCalls; count++ {
+		ethCallData = append(ethCallData, &EthCallData{
+			To:   []byte(fmt.Sprintf("%-20s", fmt.Sprintf("To for %d:%d", chainId, count))),
+			Data: []byte(fmt.Sprintf("CallData for %d:%d", chainId, count)),
+		})
+	}
+
+	callRequest := &EthCallByTimestampListQueryRequest{
+		TargetTimestamps: timestamps,
+		CallData:         ethCallData,
+	}
+
+	return &PerChainQueryRequest{
+		ChainId: chainId,
+		Query:   callRequest,
+	}
+}
+
+// createPerChainQueryForSolanaAccount creates a per chain query for a sol_account for use in tests. The accounts are meaningless gibberish.
+func createPerChainQueryForSolanaAccount(
+	t *testing.T,
+	numAccounts int,
+) *PerChainQueryRequest {
+	t.Helper()
+	accounts := [][SolanaPublicKeyLength]byte{}
+	for count := 0; count < numAccounts; count++ {
+		account := [SolanaPublicKeyLength]byte{}
+		copy(account[:], fmt.Sprintf("Account %d", count))
+		accounts = append(accounts, account)
+	}
+
+	return &PerChainQueryRequest{
+		ChainId: vaa.ChainIDSolana,
+		Query: &SolanaAccountQueryRequest{
+			Commitment: "finalized",
+			Accounts:   accounts,
+		},
+	}
+}
+
 // createSignedQueryRequestForTesting creates a query request object and signs it using the specified key.
 func createSignedQueryRequestForTesting(
 	t *testing.T,
@@ -140,11 +199,28 @@ func createSignedQueryRequestForTesting(
 ) (*gossipv1.SignedQueryRequest, *QueryRequest) {
 	t.Helper()
 	nonce += 1
+	return createSignedQueryRequestWithNonceForTesting(t, sk, nonce, perChainQueries)
+}
+
+// createSignedQueryRequestWithNonceForTesting creates a query request object with the specified nonce and signs it using the specified key.
+func createSignedQueryRequestWithNonceForTesting(
+	t *testing.T,
+	sk *ecdsa.PrivateKey,
+	nonce uint32,
+	perChainQueries []*PerChainQueryRequest,
+) (*gossipv1.SignedQueryRequest, *QueryRequest) {
+	t.Helper()
 	queryRequest := &QueryRequest{
 		Nonce:           nonce,
 		PerChainQueries: perChainQueries,
 	}
 
+	return signQueryRequestForTesting(t, sk, queryRequest), queryRequest
+}
+
+// signQueryRequestForTesting marshals a query request object and signs it using the specified key.
+func signQueryRequestForTesting(t *testing.T, sk *ecdsa.PrivateKey, queryRequest *QueryRequest) *gossipv1.SignedQueryRequest {
+	t.Helper()
 	queryRequestBytes, err := queryRequest.Marshal()
 	if err != nil {
 		panic(err)
@@ -156,12 +232,10 @@ func createSignedQueryRequestForTesting(
 		panic(err)
 	}
 
-	signedQueryRequest := &gossipv1.SignedQueryRequest{
+	return &gossipv1.SignedQueryRequest{
 		QueryRequest: queryRequestBytes,
 		Signature:    sig,
 	}
-
-	return signedQueryRequest, queryRequest
 }
 
 // createExpectedResultsForTest generates an array of the results expected for a request. These results are returned by the watcher, and used to validate the response.
@@ -172,7 +246,7 @@ func createExpectedResultsForTest(t *testing.T, perChainQueries []*PerChainQuery
 		switch req := pcq.Query.(type) {
 		case *EthCallQueryRequest:
 			now := time.Now()
-			blockNum, err := strconv.ParseUint(strings.TrimPrefix(req.BlockId, "0x"), 16, 64)
+			blockNum, err := strconv.ParseUint(strings.TrimPrefix(NormalizeBlockId(req.BlockId), "0x"), 16, 64)
 			if err != nil {
 				panic("invalid blockNum!")
 			}
@@ -191,7 +265,7 @@ func createExpectedResultsForTest(t *testing.T, perChainQueries []*PerChainQuery
 			})
 		case *EthCallByTimestampQueryRequest:
 			now := time.Now()
-			blockNum, err := strconv.ParseUint(strings.TrimPrefix(req.TargetBlockIdHint, "0x"), 16, 64)
+			blockNum, err := strconv.ParseUint(strings.TrimPrefix(NormalizeBlockId(req.TargetBlockIdHint), "0x"), 16, 64)
 			if err != nil {
 				panic("invalid blockNum!")
 			}
@@ -213,7 +287,7 @@ func createExpectedResultsForTest(t *testing.T, perChainQueries []*PerChainQuery
 			})
 		case *EthCallWithFinalityQueryRequest:
 			now := time.Now()
-			blockNum, err := strconv.ParseUint(strings.TrimPrefix(req.BlockId, "0x"), 16, 64)
+			blockNum, err := strconv.ParseUint(strings.TrimPrefix(NormalizeBlockId(req.BlockId), "0x"), 16, 64)
 			if err != nil {
 				panic("invalid blockNum!")
 			}
@@ -230,6 +304,86 @@ func createExpectedResultsForTest(t *testing.T, perChainQueries []*PerChainQuery
 				ChainId:  pcq.ChainId,
 				Response: resp,
 			})
+		case *EthCallByTimestampListQueryRequest:
+			resp := &EthCallByTimestampListQueryResponse{}
+			for _, timestamp := range req.TargetTimestamps {
+				blockNum := timestamp / 1000000
+				entry := &EthCallByTimestampQueryResponse{
+					TargetBlockNumber:    blockNum,
+					TargetBlockHash:      ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+					TargetBlockTime:      timeForTest(t, time.UnixMicro(int64(timestamp))),
+					FollowingBlockNumber: blockNum + 1,
+					FollowingBlockHash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e3"),
+					FollowingBlockTime:   timeForTest(t, time.UnixMicro(int64(timestamp)).Add(10*time.Second)),
+					Results:              [][]byte{},
+				}
+				for _, cd := range req.CallData {
+					entry.Results = append(entry.Results, []byte(hex.EncodeToString(cd.To)+":"+hex.EncodeToString(cd.Data)))
+				}
+				resp.Responses = append(resp.Responses, entry)
+			}
+			expectedResults = append(expectedResults, PerChainQueryResponse{
+				ChainId:  pcq.ChainId,
+				Response: resp,
+			})
+		case *EthLogsQueryRequest:
+			fromBlockNum, err := ParseBlockNumber(req.FromBlock)
+			if err != nil {
+				panic("invalid blockNum!")
+			}
+			toBlockNum, err := ParseBlockNumber(req.ToBlock)
+			if err != nil {
+				panic("invalid blockNum!")
+			}
+			resp := &EthLogsQueryResponse{
+				FromBlock:   fromBlockNum,
+				BlockNumber: toBlockNum,
+				BlockHash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				BlockTime:   timeForTest(t, time.Now()),
+			}
+			log := &EthLog{
+				BlockNumber: toBlockNum,
+				TxHash:      ethCommon.HexToHash("0x1111bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				Data:        req.Address,
+			}
+			for _, topic := range req.Topics {
+				log.Topics = append(log.Topics, ethCommon.BytesToHash(topic))
+			}
+			resp.Logs = append(resp.Logs, log)
+			expectedResults = append(expectedResults, PerChainQueryResponse{
+				ChainId:  pcq.ChainId,
+				Response: resp,
+			})
+		case *EthStorageQueryRequest:
+			resp := &EthStorageQueryResponse{
+				BlockNumber: 0x28d9630,
+				BlockHash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				BlockTime:   timeForTest(t, time.Now()),
+			}
+			for _, slot := range req.Slots {
+				resp.Values = append(resp.Values, ethCrypto.Keccak256Hash(req.Contract, slot.Bytes()))
+			}
+			expectedResults = append(expectedResults, PerChainQueryResponse{
+				ChainId:  pcq.ChainId,
+				Response: resp,
+			})
+		case *SolanaAccountQueryRequest:
+			resp := &SolanaAccountQueryResponse{
+				SlotNumber: 2000,
+				BlockTime:  timeForTest(t, time.Now()),
+				BlockHash:  ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+			}
+			for _, account := range req.Accounts {
+				resp.Results = append(resp.Results, SolanaAccountResult{
+					Lamports: 1000,
+					Owner:    ethCommon.HexToHash("0x1111bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+					Data:     account[:],
+				})
+			}
+			expectedResults = append(expectedResults, PerChainQueryResponse{
+				ChainId:  pcq.ChainId,
+				Response: resp,
+			})
 		default:
 			panic("Invalid call data type!")
 		}
@@ -259,7 +413,7 @@ func validateResponseForTest(
 }
 
 func TestParseAllowedRequestersSuccess(t *testing.T) {
-	ccqAllowedRequestersList, err := parseAllowedRequesters(testSigner)
+	ccqAllowedRequestersList, _, err := parseAllowedRequesters(testSigner)
 	require.NoError(t, err)
 	require.NotNil(t, ccqAllowedRequestersList)
 	require.Equal(t, 1, len(ccqAllowedRequestersList))
@@ -269,7 +423,7 @@ func TestParseAllowedRequestersSuccess(t *testing.T) {
 	_, exists = ccqAllowedRequestersList[ethCommon.BytesToAddress(ethCommon.Hex2Bytes("beFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBf"))]
 	require.False(t, exists)
 
-	ccqAllowedRequestersList, err = parseAllowedRequesters("beFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe,beFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBf")
+	ccqAllowedRequestersList, _, err = parseAllowedRequesters("beFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe,beFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBf")
 	require.NoError(t, err)
 	require.NotNil(t, ccqAllowedRequestersList)
 	require.Equal(t, 2, len(ccqAllowedRequestersList))
@@ -281,21 +435,67 @@ func TestParseAllowedRequestersSuccess(t *testing.T) {
 }
 
 func TestParseAllowedRequestersFailsIfParameterEmpty(t *testing.T) {
-	ccqAllowedRequestersList, err := parseAllowedRequesters("")
+	ccqAllowedRequestersList, _, err := parseAllowedRequesters("")
 	require.Error(t, err)
 	require.Nil(t, ccqAllowedRequestersList)
 
-	ccqAllowedRequestersList, err = parseAllowedRequesters(",")
+	ccqAllowedRequestersList, _, err = parseAllowedRequesters(",")
 	require.Error(t, err)
 	require.Nil(t, ccqAllowedRequestersList)
 }
 
 func TestParseAllowedRequestersFailsIfInvalidParameter(t *testing.T) {
-	ccqAllowedRequestersList, err := parseAllowedRequesters("Hello")
+	ccqAllowedRequestersList, _, err := parseAllowedRequesters("Hello")
 	require.Error(t, err)
 	require.Nil(t, ccqAllowedRequestersList)
 }
 
+func TestUpdateAllowedRequestersAcceptsPreviouslyDeniedSigner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	// Use the config from a real query handler so that its update method reaches the handler under test.
+	qh := NewQueryHandler(logger, common.GoTest, testSigner, nil, nil, nil, nil, HandlerConfig{})
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, qh.config)
+
+	newSk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	newSigner := ethCrypto.PubkeyToAddress(newSk.PublicKey)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, newSk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// The new signer is not allowed yet, so its request should be dropped.
+	md.signedQueryReqWriteC <- signedQueryRequest
+	assert.Nil(t, md.waitForResponse())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// An empty list should be rejected, leaving the current set in place.
+	assert.Error(t, qh.UpdateAllowedRequesters(ctx, ""))
+	assert.Error(t, qh.UpdateAllowedRequesters(ctx, ","))
+
+	require.NoError(t, qh.UpdateAllowedRequesters(ctx, testSigner+","+newSigner.Hex()))
+
+	// The same signer should now be accepted.
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, newSk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// The original signer should still be accepted.
+	md.resetState()
+	md.setExpectedResults(expectedResults)
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	queryResponsePublication = md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
 // mockData is the data structure used to mock up the query handler environment.
 type mockData struct {
 	sk *ecdsa.PrivateKey
@@ -311,11 +511,22 @@ type mockData struct {
 	queryResponsePublicationReadC  <-chan *QueryResponsePublication
 	queryResponsePublicationWriteC chan<- *QueryResponsePublication
 
+	failureReadC  <-chan *QueryFailure
+	failureWriteC chan<- *QueryFailure
+
 	mutex                    sync.Mutex
 	queryResponsePublication *QueryResponsePublication
+	publications             []*QueryResponsePublication
+	failure                  *QueryFailure
+	failures                 []*QueryFailure
 	expectedResults          []PerChainQueryResponse
 	requestsPerChain         map[vaa.ChainID]int
 	retriesPerChain          map[vaa.ChainID]int
+	rpcNodesPerChain         map[vaa.ChainID]string
+	rpcErrorsPerChain        map[vaa.ChainID]*RpcError
+	lastRequestPerChain      map[vaa.ChainID]*PerChainQueryInternal
+	staleResultsPerChain     map[vaa.ChainID]int
+	requestTimesPerChain     map[vaa.ChainID][]time.Time
 }
 
 // resetState() is used to reset mock data between queries in the same test.
@@ -323,9 +534,17 @@ func (md *mockData) resetState() {
 	md.mutex.Lock()
 	defer md.mutex.Unlock()
 	md.queryResponsePublication = nil
+	md.publications = nil
+	md.failure = nil
+	md.failures = nil
 	md.expectedResults = nil
 	md.requestsPerChain = make(map[vaa.ChainID]int)
 	md.retriesPerChain = make(map[vaa.ChainID]int)
+	md.rpcNodesPerChain = make(map[vaa.ChainID]string)
+	md.rpcErrorsPerChain = make(map[vaa.ChainID]*RpcError)
+	md.lastRequestPerChain = make(map[vaa.ChainID]*PerChainQueryInternal)
+	md.staleResultsPerChain = make(map[vaa.ChainID]int)
+	md.requestTimesPerChain = make(map[vaa.ChainID][]time.Time)
 }
 
 // setExpectedResults sets the results to be returned by the watchers.
@@ -337,12 +556,29 @@ func (md *mockData) setExpectedResults(expectedResults []PerChainQueryResponse)
 
 // setRetries allows a test to specify how many times a given watcher should retry before returning success.
 // If the count is the special value `fatalError`, the watcher will return QueryFatalError.
+// If the count is the special value `notFound`, the watcher will return QueryNotFound.
+// If the count is the special value `nodeSyncing`, the watcher will return QueryNodeSyncing until the count is changed.
+// If the count is the special value `closeWatcher`, the watcher will close its channel and exit when it receives the next query.
 func (md *mockData) setRetries(chainId vaa.ChainID, count int) {
 	md.mutex.Lock()
 	defer md.mutex.Unlock()
 	md.retriesPerChain[chainId] = count
 }
 
+// setRpcNode allows a test to specify the RPC node label a given watcher should report in the response metadata.
+func (md *mockData) setRpcNode(chainId vaa.ChainID, rpcNode string) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	md.rpcNodesPerChain[chainId] = rpcNode
+}
+
+// setRpcError allows a test to specify the RPC error a given watcher should report in the metadata of its unsuccessful responses.
+func (md *mockData) setRpcError(chainId vaa.ChainID, rpcErr *RpcError) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	md.rpcErrorsPerChain[chainId] = rpcErr
+}
+
 // incrementRequestsPerChainAlreadyLocked is used by the watchers to keep track of how many times they were invoked in a given test.
 func (md *mockData) incrementRequestsPerChainAlreadyLocked(chainId vaa.ChainID) {
 	if val, exists := md.requestsPerChain[chainId]; exists {
@@ -350,6 +586,21 @@ func (md *mockData) incrementRequestsPerChainAlreadyLocked(chainId vaa.ChainID)
 	} else {
 		md.requestsPerChain[chainId] = 1
 	}
+	md.requestTimesPerChain[chainId] = append(md.requestTimesPerChain[chainId], time.Now())
+}
+
+// getRequestTimes returns the times at which the given watcher was invoked in a given test.
+func (md *mockData) getRequestTimes(chainId vaa.ChainID) []time.Time {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	return append([]time.Time{}, md.requestTimesPerChain[chainId]...)
+}
+
+// clearRetries allows a test to go back to having the watcher for a chain respond successfully.
+func (md *mockData) clearRetries(chainId vaa.ChainID) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	delete(md.retriesPerChain, chainId)
 }
 
 // getQueryResponsePublication returns the latest query response publication received by the mock.
@@ -359,6 +610,27 @@ func (md *mockData) getQueryResponsePublication() *QueryResponsePublication {
 	return md.queryResponsePublication
 }
 
+// getPublications returns all of the query response publications received by the mock, in order.
+func (md *mockData) getPublications() []*QueryResponsePublication {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	return append([]*QueryResponsePublication{}, md.publications...)
+}
+
+// getFailure returns the latest query failure received by the mock.
+func (md *mockData) getFailure() *QueryFailure {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	return md.failure
+}
+
+// getFailures returns all of the query failures received by the mock, in order.
+func (md *mockData) getFailures() []*QueryFailure {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	return append([]*QueryFailure{}, md.failures...)
+}
+
 // getRequestsPerChain returns the count of the number of times the given watcher was invoked in a given test.
 func (md *mockData) getRequestsPerChain(chainId vaa.ChainID) int {
 	md.mutex.Lock()
@@ -370,12 +642,43 @@ func (md *mockData) getRequestsPerChain(chainId vaa.ChainID) int {
 }
 
 // shouldIgnoreAlreadyLocked is used by the watchers to see if they should ignore a query (causing a retry).
+// setStaleResults makes the watcher for a chain answer the next count successful queries using a block that is an hour older than expected.
+func (md *mockData) setStaleResults(chainId vaa.ChainID, count int) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	md.staleResultsPerChain[chainId] = count
+}
+
+// staleResultAlreadyLocked returns a copy of an eth_call result using an older block, if the watcher for the chain is configured to return one.
+// Otherwise it returns the result unchanged.
+func (md *mockData) staleResultAlreadyLocked(chainId vaa.ChainID, results ChainSpecificResponse) ChainSpecificResponse {
+	resp, ok := results.(*EthCallQueryResponse)
+	if !ok || md.staleResultsPerChain[chainId] == 0 {
+		return results
+	}
+
+	md.staleResultsPerChain[chainId]--
+	staleResp := *resp
+	staleResp.Time = resp.Time.Add(-time.Hour)
+	return &staleResp
+}
+
+// getLastRequest returns the last per chain query received by the watcher for a chain, or nil if there has not been one.
+func (md *mockData) getLastRequest(chainId vaa.ChainID) *PerChainQueryInternal {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+	return md.lastRequestPerChain[chainId]
+}
+
 func (md *mockData) shouldIgnoreAlreadyLocked(chainId vaa.ChainID) bool {
 	if val, exists := md.retriesPerChain[chainId]; exists {
 		if val == ignoreQuery {
 			delete(md.retriesPerChain, chainId)
 			return true
 		}
+		if val == ignoreAllQueries {
+			return true
+		}
 	}
 	return false
 }
@@ -386,6 +689,12 @@ func (md *mockData) getStatusAlreadyLocked(chainId vaa.ChainID) QueryStatus {
 		if val == fatalError {
 			return QueryFatalError
 		}
+		if val == notFound {
+			return QueryNotFound
+		}
+		if val == nodeSyncing {
+			return QueryNodeSyncing
+		}
 		val -= 1
 		if val > 0 {
 			md.retriesPerChain[chainId] = val
@@ -400,14 +709,19 @@ func (md *mockData) getStatusAlreadyLocked(chainId vaa.ChainID) QueryStatus {
 // createQueryHandlerForTest creates the query handler mock environment, including the set of watchers and the response listener.
 // Most tests will use this function to set up the mock.
 func createQueryHandlerForTest(t *testing.T, ctx context.Context, logger *zap.Logger, chains []vaa.ChainID) *mockData {
-	md := createQueryHandlerForTestWithoutPublisher(t, ctx, logger, chains)
+	return createQueryHandlerForTestWithConfig(t, ctx, logger, chains, HandlerConfig{})
+}
+
+// createQueryHandlerForTestWithConfig is the same as createQueryHandlerForTest, but allows the test to specify the optional handler config.
+func createQueryHandlerForTestWithConfig(t *testing.T, ctx context.Context, logger *zap.Logger, chains []vaa.ChainID, config HandlerConfig) *mockData {
+	md := createQueryHandlerForTestWithoutPublisher(t, ctx, logger, chains, config)
 	md.startResponseListener(ctx)
 	return md
 }
 
 // createQueryHandlerForTestWithoutPublisher creates the query handler mock environment, including the set of watchers but not the response listener.
 // This function can be invoked directly to test retries of response publication (by delaying the start of the response listener).
-func createQueryHandlerForTestWithoutPublisher(t *testing.T, ctx context.Context, logger *zap.Logger, chains []vaa.ChainID) *mockData {
+func createQueryHandlerForTestWithoutPublisher(t *testing.T, ctx context.Context, logger *zap.Logger, chains []vaa.ChainID, config HandlerConfig) *mockData {
 	md := mockData{}
 	var err error
 
@@ -415,7 +729,7 @@ func createQueryHandlerForTestWithoutPublisher(t *testing.T, ctx context.Context
 	require.NoError(t, err)
 	require.NotNil(t, md.sk)
 
-	ccqAllowedRequestersList, err := parseAllowedRequesters(testSigner)
+	ccqAllowedRequestersList, _, err := parseAllowedRequesters(testSigner)
 	require.NoError(t, err)
 
 	// Inbound observation requests from the p2p service (for all chains)
@@ -433,44 +747,95 @@ func createQueryHandlerForTestWithoutPublisher(t *testing.T, ctx context.Context
 	// Query responses from query handler to p2p
 	md.queryResponsePublicationReadC, md.queryResponsePublicationWriteC = makeChannelPair[*QueryResponsePublication](0)
 
+	// Query failures from query handler
+	md.failureReadC, md.failureWriteC = makeChannelPair[*QueryFailure](10)
+	config.FailureC = md.failureWriteC
+
+	// Retry at a fixed interval unless the test is exercising the backoff, so that tests relying on several retries stay quick.
+	if config.MaxRetryInterval == 0 {
+		config.MaxRetryInterval = retryIntervalForTest
+	}
+
 	md.resetState()
 
 	go func() {
 		err := handleQueryRequestsImpl(ctx, logger, md.signedQueryReqReadC, md.chainQueryReqC, ccqAllowedRequestersList,
-			md.queryResponseReadC, md.queryResponsePublicationWriteC, common.GoTest, requestTimeoutForTest, retryIntervalForTest, auditIntervalForTest)
+			md.queryResponseReadC, md.queryResponsePublicationWriteC, common.GoTest, requestTimeoutForTest, retryIntervalForTest, auditIntervalForTest, config)
 		assert.NoError(t, err)
 	}()
 
-	// Create a routine for each configured watcher. It will take a per chain query and return the corresponding expected result.
-	// It also pegs a counter of the number of requests the watcher received, for verification purposes.
-	for chainId := range md.chainQueryReqC {
-		go func(chainId vaa.ChainID, chainQueryReqC <-chan *PerChainQueryInternal) {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case pcqr := <-chainQueryReqC:
-					require.Equal(t, chainId, pcqr.Request.ChainId)
-					md.mutex.Lock()
-					md.incrementRequestsPerChainAlreadyLocked(chainId)
-					if md.shouldIgnoreAlreadyLocked(chainId) {
-						logger.Info("watcher ignoring query", zap.String("chainId", chainId.String()), zap.Int("requestIdx", pcqr.RequestIdx))
-					} else {
-						results := md.expectedResults[pcqr.RequestIdx].Response
-						status := md.getStatusAlreadyLocked(chainId)
-						logger.Info("watcher returning", zap.String("chainId", chainId.String()), zap.Int("requestIdx", pcqr.RequestIdx), zap.Int("status", int(status)))
-						queryResponse := CreatePerChainQueryResponseInternal(pcqr.RequestID, pcqr.RequestIdx, pcqr.Request.ChainId, status, results)
-						md.queryResponseWriteC <- queryResponse
-					}
-					md.mutex.Unlock()
-				}
+	// Create a routine to record query failures.
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case failure := <-md.failureReadC:
+				md.mutex.Lock()
+				md.failure = failure
+				md.failures = append(md.failures, failure)
+				md.mutex.Unlock()
 			}
-		}(chainId, md.chainQueryReqC[chainId])
+		}
+	}()
+
+	// Create a routine for each configured watcher.
+	for chainId, chainQueryReqC := range md.chainQueryReqC {
+		md.startMockWatcher(t, ctx, logger, chainId, chainQueryReqC)
 	}
 
 	return &md
 }
 
+// startMockWatcher creates a routine for a watcher. It will take a per chain query and return the corresponding expected result.
+// It also pegs a counter of the number of requests the watcher received, for verification purposes.
+func (md *mockData) startMockWatcher(t *testing.T, ctx context.Context, logger *zap.Logger, chainId vaa.ChainID, chainQueryReqC chan *PerChainQueryInternal) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case pcqr := <-chainQueryReqC:
+				require.Equal(t, chainId, pcqr.Request.ChainId)
+				md.mutex.Lock()
+				md.incrementRequestsPerChainAlreadyLocked(chainId)
+				md.lastRequestPerChain[chainId] = pcqr
+				if md.retriesPerChain[chainId] == closeWatcher {
+					// Simulate the watcher going away. The channel is closed before asking for a retry, so the handler cannot retry before it is closed.
+					logger.Info("watcher closing its channel", zap.String("chainId", chainId.String()), zap.String("requestId", pcqr.CorrelationID), zap.Int("requestIdx", pcqr.RequestIdx))
+					close(chainQueryReqC)
+					md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(pcqr.RequestID, pcqr.RequestIdx, pcqr.Request.ChainId, QueryRetryNeeded, md.expectedResults[pcqr.RequestIdx].Response)
+					md.mutex.Unlock()
+					return
+				}
+				if md.shouldIgnoreAlreadyLocked(chainId) {
+					logger.Info("watcher ignoring query", zap.String("chainId", chainId.String()), zap.String("requestId", pcqr.CorrelationID), zap.Int("requestIdx", pcqr.RequestIdx))
+				} else {
+					results := md.expectedResults[pcqr.RequestIdx].Response
+					status := md.getStatusAlreadyLocked(chainId)
+					if status == QuerySuccess {
+						results = md.staleResultAlreadyLocked(chainId, results)
+					}
+					logger.Info("watcher returning", zap.String("chainId", chainId.String()), zap.String("requestId", pcqr.CorrelationID), zap.Int("requestIdx", pcqr.RequestIdx), zap.Int("status", int(status)))
+					queryResponse := CreatePerChainQueryResponseInternal(pcqr.RequestID, pcqr.RequestIdx, pcqr.Request.ChainId, status, results)
+					rpcNode, rpcNodeExists := md.rpcNodesPerChain[chainId]
+					if rpcNodeExists {
+						queryResponse.Metadata = &PerChainResponseMetadata{RpcNode: rpcNode}
+					}
+					if status != QuerySuccess {
+						queryResponse.Metadata = &PerChainResponseMetadata{
+							Error:    fmt.Sprintf("mock watcher returned status %d on request %d", status, md.requestsPerChain[chainId]),
+							RpcError: md.rpcErrorsPerChain[chainId],
+						}
+					}
+					md.queryResponseWriteC <- queryResponse
+				}
+				md.mutex.Unlock()
+			}
+		}
+	}()
+}
+
 // startResponseListener starts the response listener routine. It is called as part of the standard mock environment set up. Or, it can be used
 // along with `createQueryHandlerForTestWithoutPublisher“ to test retries of response publication (by delaying the start of the response listener).
 func (md *mockData) startResponseListener(ctx context.Context) {
@@ -482,6 +847,7 @@ func (md *mockData) startResponseListener(ctx context.Context) {
 			case qrp := <-md.queryResponsePublicationReadC:
 				md.mutex.Lock()
 				md.queryResponsePublication = qrp
+				md.publications = append(md.publications, qrp)
 				md.mutex.Unlock()
 			}
 		}
@@ -500,6 +866,18 @@ func (md *mockData) waitForResponse() *QueryResponsePublication {
 	return nil
 }
 
+// waitForFailure is used by the tests to wait for a query failure. It will eventually timeout if the query does not fail.
+func (md *mockData) waitForFailure() *QueryFailure {
+	for count := 0; count < 50; count++ {
+		time.Sleep(pollIntervalForTest)
+		ret := md.getFailure()
+		if ret != nil {
+			return ret
+		}
+	}
+	return nil
+}
+
 // TestInvalidQueries tests all the obvious reasons why a query may fail (aside from watcher failures).
 func TestInvalidQueries(t *testing.T) {
 	ctx := context.Background()
@@ -538,6 +916,8 @@ func TestSingleEthCallQueryShouldSucceed(t *testing.T) {
 	logger := zap.NewNop()
 
 	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+	successesBefore := testutil.ToFloat64(successfulQueryResponsesReceivedByChain.WithLabelValues(vaa.ChainIDPolygon.String()))
+	latenciesBefore := queryLatencySampleCountForTest(t, vaa.ChainIDPolygon)
 
 	// Create the request and the expected results. Give the expected results to the mock.
 	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
@@ -554,39 +934,117 @@ func TestSingleEthCallQueryShouldSucceed(t *testing.T) {
 
 	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
 	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// The outcome and latency should have been recorded for the chain.
+	assert.Greater(t, testutil.ToFloat64(successfulQueryResponsesReceivedByChain.WithLabelValues(vaa.ChainIDPolygon.String())), successesBefore)
+	assert.Greater(t, queryLatencySampleCountForTest(t, vaa.ChainIDPolygon), latenciesBefore)
 }
 
-func TestSingleEthCallByTimestampQueryShouldSucceed(t *testing.T) {
-	ctx := context.Background()
+// queryLatencySampleCountForTest returns the number of query latencies recorded for a chain.
+func queryLatencySampleCountForTest(t *testing.T, chainID vaa.ChainID) uint64 {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, queryLatencyByChain.WithLabelValues(chainID.String()).(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestSolanaAccountQueryShouldSucceed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	logger := zap.NewNop()
 
 	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
 
-	// Create the request and the expected results. Give the expected results to the mock.
-	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDPolygon, "0x28d9630", "0x28d9631", 2)}
+	// Create a request that reads Solana accounts alongside an EVM chain, so each is routed to its own watcher.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForSolanaAccount(t, 3),
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+	}
 	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
 	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
 	md.setExpectedResults(expectedResults)
 
-	// Submit the query request to the handler.
 	md.signedQueryReqWriteC <- signedQueryRequest
 
-	// Wait until we receive a response or timeout.
 	queryResponsePublication := md.waitForResponse()
 	require.NotNil(t, queryResponsePublication)
 
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDSolana))
 	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
 	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
 }
 
-func TestSingleEthCallWithFinalityQueryShouldSucceed(t *testing.T) {
+// signQueryRequestBytesForTesting signs the serialized form of a query request using the specified key. It allows tests to submit requests
+// that would be rejected by QueryRequest.Marshal.
+func signQueryRequestBytesForTesting(t *testing.T, sk *ecdsa.PrivateKey, queryRequestBytes []byte) *gossipv1.SignedQueryRequest {
+	t.Helper()
+	digest := QueryRequestDigest(common.UnsafeDevNet, queryRequestBytes)
+	sig, err := ethCrypto.Sign(digest.Bytes(), sk)
+	require.NoError(t, err)
+	return &gossipv1.SignedQueryRequest{QueryRequest: queryRequestBytes, Signature: sig}
+}
+
+func TestSolanaAccountQueryWithUnsupportedCommitmentIsRejected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Swap the commitment for one of the same length that the guardians do not support.
+	nonce += 1
+	queryRequest := &QueryRequest{Nonce: nonce, PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForSolanaAccount(t, 2)}}
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	queryRequestBytes = bytes.Replace(queryRequestBytes, []byte("finalized"), []byte("confirmed"), 1)
+	var decoded QueryRequest
+	assert.ErrorContains(t, decoded.Unmarshal(queryRequestBytes), `commitment must be "finalized"`)
+
+	md.signedQueryReqWriteC <- signQueryRequestBytesForTesting(t, md.sk, queryRequestBytes)
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, RequestUnmarshalFailed, failure.Reason)
+	assert.Nil(t, md.waitForResponse())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDSolana))
+}
+
+func TestSolanaAccountQueryWithTooManyAccountsIsRejected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Append one more account than is allowed to the end of the only per chain query, adjusting its account count and length to match.
+	nonce += 1
+	queryRequest := &QueryRequest{Nonce: nonce, PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForSolanaAccount(t, SolanaMaxAccountsPerQuery)}}
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	numAccountsIdx := len(queryRequestBytes) - SolanaMaxAccountsPerQuery*SolanaPublicKeyLength - 1
+	queryRequestBytes[numAccountsIdx]++
+	queryRequestBytes = append(queryRequestBytes, make([]byte, SolanaPublicKeyLength)...)
+	binary.BigEndian.PutUint32(queryRequestBytes[9:13], binary.BigEndian.Uint32(queryRequestBytes[9:13])+SolanaPublicKeyLength)
+	var decoded QueryRequest
+	assert.ErrorContains(t, decoded.Unmarshal(queryRequestBytes), "too many account entries")
+
+	md.signedQueryReqWriteC <- signQueryRequestBytesForTesting(t, md.sk, queryRequestBytes)
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, RequestUnmarshalFailed, failure.Reason)
+	assert.Nil(t, md.waitForResponse())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDSolana))
+}
+
+func TestSingleEthCallByTimestampQueryShouldSucceed(t *testing.T) {
 	ctx := context.Background()
 	logger := zap.NewNop()
 
 	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
 
 	// Create the request and the expected results. Give the expected results to the mock.
-	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCallWithFinality(t, vaa.ChainIDPolygon, "0x28d9630", "safe", 2)}
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDPolygon, "0x28d9630", "0x28d9631", 2)}
 	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
 	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
 	md.setExpectedResults(expectedResults)
@@ -602,18 +1060,14 @@ func TestSingleEthCallWithFinalityQueryShouldSucceed(t *testing.T) {
 	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
 }
 
-func TestBatchOfMultipleQueryTypesShouldSucceed(t *testing.T) {
+func TestSingleEthCallWithFinalityQueryShouldSucceed(t *testing.T) {
 	ctx := context.Background()
 	logger := zap.NewNop()
 
 	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
 
 	// Create the request and the expected results. Give the expected results to the mock.
-	perChainQueries := []*PerChainQueryRequest{
-		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
-		createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDBSC, "0x28d9123", "0x28d9124", 3),
-		createPerChainQueryForEthCallWithFinality(t, vaa.ChainIDArbitrum, "0x28d9123", "finalized", 3),
-	}
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCallWithFinality(t, vaa.ChainIDPolygon, "0x28d9630", "safe", 2)}
 	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
 	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
 	md.setExpectedResults(expectedResults)
@@ -626,39 +1080,137 @@ func TestBatchOfMultipleQueryTypesShouldSucceed(t *testing.T) {
 	require.NotNil(t, queryResponsePublication)
 
 	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
-	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
-	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDArbitrum))
 	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
 }
 
-func TestQueryWithLimitedRetriesShouldSucceed(t *testing.T) {
+func TestSingleEthCallByTimestampListQueryShouldSucceed(t *testing.T) {
 	ctx := context.Background()
 	logger := zap.NewNop()
 
 	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
 
 	// Create the request and the expected results. Give the expected results to the mock.
-	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	timestamps := []uint64{1700000000000000, 1700000060000000, 1700000120000000}
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCallByTimestampList(t, vaa.ChainIDPolygon, timestamps, 2)}
 	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
 	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
 	md.setExpectedResults(expectedResults)
 
-	// Make it retry a couple of times, but not enough to make it fail.
-	retries := 2
-	md.setRetries(vaa.ChainIDPolygon, retries)
-
 	// Submit the query request to the handler.
 	md.signedQueryReqWriteC <- signedQueryRequest
 
-	// The request should eventually succeed.
+	// Wait until we receive a response or timeout.
 	queryResponsePublication := md.waitForResponse()
 	require.NotNil(t, queryResponsePublication)
 
-	assert.Equal(t, retries+1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
 	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// There should be a resolved block and a set of results for each timestamp.
+	resp := queryResponsePublication.PerChainResponses[0].Response.(*EthCallByTimestampListQueryResponse)
+	require.Equal(t, len(timestamps), len(resp.Responses))
+	for idx, timestamp := range timestamps {
+		assert.Equal(t, timestamp/1000000, resp.Responses[idx].TargetBlockNumber)
+		assert.Equal(t, 2, len(resp.Responses[idx].Results))
+	}
 }
 
-func TestQueryWithRetryDueToTimeoutShouldSucceed(t *testing.T) {
+func TestBatchOfMultipleQueryTypesShouldSucceed(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Create the request and the expected results. Give the expected results to the mock.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDBSC, "0x28d9123", "0x28d9124", 3),
+		createPerChainQueryForEthCallWithFinality(t, vaa.ChainIDArbitrum, "0x28d9123", "finalized", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Submit the query request to the handler.
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// Wait until we receive a response or timeout.
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDArbitrum))
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestQueryWithLimitedRetriesShouldSucceed(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Create the request and the expected results. Give the expected results to the mock.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Make it retry a couple of times, but not enough to make it fail.
+	retries := 2
+	md.setRetries(vaa.ChainIDPolygon, retries)
+
+	// Submit the query request to the handler.
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The request should eventually succeed.
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+
+	assert.Equal(t, retries+1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestRetriesBackOffUntilQuerySucceeds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		MaxRetryInterval: 8 * retryIntervalForTest,
+		RequestTimeouts:  RequestTimeouts{vaa.ChainIDPolygon: 10 * requestTimeoutForTest},
+	})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Make the watcher time out until it has been invoked a few times, then let it answer.
+	const numAttempts = 5
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.Eventually(t, func() bool {
+		return md.getRequestsPerChain(vaa.ChainIDPolygon) >= numAttempts
+	}, 5*requestTimeoutForTest, pollIntervalForTest)
+	md.clearRetries(vaa.ChainIDPolygon)
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// Each retry should wait at least twice as long as the previous one, up to the ceiling, so the gaps between the attempts grow. The times
+	// are recorded when the watcher picks up each attempt, so allow for the previous one having been picked up late.
+	requestTimes := md.getRequestTimes(vaa.ChainIDPolygon)
+	require.GreaterOrEqual(t, len(requestTimes), numAttempts)
+	for idx := 1; idx < numAttempts; idx++ {
+		minGap := min(retryIntervalForTest<<(idx-1), 8*retryIntervalForTest) - retryIntervalForTest/2
+		assert.GreaterOrEqual(t, requestTimes[idx].Sub(requestTimes[idx-1]), minGap, idx)
+	}
+	assert.Greater(t, requestTimes[numAttempts-1].Sub(requestTimes[numAttempts-2]), requestTimes[1].Sub(requestTimes[0]))
+}
+
+func TestQueryWithRetryDueToTimeoutShouldSucceed(t *testing.T) {
 	ctx := context.Background()
 	logger := zap.NewNop()
 
@@ -767,6 +1319,7 @@ func TestFatalErrorOnPerChainQueryShouldCauseRequestToFail(t *testing.T) {
 
 	// Make BSC return a fatal error.
 	md.setRetries(vaa.ChainIDBSC, fatalError)
+	fatalErrorsBefore := testutil.ToFloat64(fatalQueryResponsesReceivedByChain.WithLabelValues(vaa.ChainIDBSC.String()))
 
 	// Submit the query request to the handler.
 	md.signedQueryReqWriteC <- signedQueryRequest
@@ -777,13 +1330,196 @@ func TestFatalErrorOnPerChainQueryShouldCauseRequestToFail(t *testing.T) {
 
 	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
 	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+	assert.Greater(t, testutil.ToFloat64(fatalQueryResponsesReceivedByChain.WithLabelValues(vaa.ChainIDBSC.String())), fatalErrorsBefore)
+}
+
+func TestFatalErrorOnPerChainQueryWithPartialResultsShouldPublishTheOthers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Create a request that allows partial results, and the expected results. Give the expected results to the mock.
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:               nonce,
+		AllowPartialResults: true,
+		PerChainQueries: []*PerChainQueryRequest{
+			createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+			createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		},
+	}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Make BSC return a fatal error.
+	md.setRetries(vaa.ChainIDBSC, fatalError)
+
+	// Submit the query request to the handler.
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The response should only contain the Polygon results, and record that BSC failed.
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	require.Len(t, queryResponsePublication.PerChainResponses, 1)
+	assert.True(t, queryResponsePublication.PerChainResponses[0].Equal(&expectedResults[0]))
+	assert.Equal(t, []PerChainResultStatus{PerChainResultSucceeded, PerChainResultFailed}, queryResponsePublication.Statuses)
+
+	// The statuses are signed, so they must survive a round trip through the wire format.
+	respPubBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(respPubBytes))
+	assert.True(t, queryResponsePublication.Equal(&respPub2))
+
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+}
+
+func TestFatalErrorOnEveryPerChainQueryWithPartialResultsShouldCauseRequestToFail(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:               nonce,
+		AllowPartialResults: true,
+		PerChainQueries: []*PerChainQueryRequest{
+			createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+			createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		},
+	}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+
+	// Make both chains return a fatal error, so there is nothing to publish.
+	md.setRetries(vaa.ChainIDPolygon, fatalError)
+	md.setRetries(vaa.ChainIDBSC, fatalError)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+	assert.Nil(t, md.waitForResponse())
+}
+
+func TestExhaustedRetryBudgetWithPartialResultsShouldPublishTheOthers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:               nonce,
+		RetryBudget:         1,
+		AllowPartialResults: true,
+		PerChainQueries: []*PerChainQueryRequest{
+			createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+			createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		},
+	}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Make Polygon never respond, so it exhausts its retry budget well before the request times out.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	require.Len(t, queryResponsePublication.PerChainResponses, 1)
+	assert.True(t, queryResponsePublication.PerChainResponses[0].Equal(&expectedResults[1]))
+	assert.Equal(t, []PerChainResultStatus{PerChainResultFailed, PerChainResultSucceeded}, queryResponsePublication.Statuses)
+	assert.Equal(t, 2, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestResponsesWithMismatchedRequestIdxAreDropped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Create the request and the expected results. Give the expected results to the mock.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Make Polygon never respond, so the test can provide the response itself.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+
+	// Submit the query request to the handler.
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// Wait until both watchers have been invoked, which means the BSC response has been received.
+	require.Eventually(t, func() bool {
+		return md.getRequestsPerChain(vaa.ChainIDPolygon) > 0 && md.getRequestsPerChain(vaa.ChainIDBSC) > 0
+	}, requestTimeoutForTest/2, pollIntervalForTest)
+
+	requestID := hex.EncodeToString(signedQueryRequest.Signature) + ":" + QueryRequestDigest(common.GoTest, signedQueryRequest.QueryRequest).String()
+
+	// None of these responses match an outstanding per chain query, so they should all be dropped without affecting the request.
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 2, vaa.ChainIDPolygon, QuerySuccess, expectedResults[0].Response)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, -1, vaa.ChainIDPolygon, QuerySuccess, expectedResults[0].Response)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 0, vaa.ChainIDBSC, QuerySuccess, expectedResults[1].Response)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 7, vaa.ChainIDPolygon, QueryFatalError, nil)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 1, vaa.ChainIDBSC, QueryFatalError, nil)
+
+	// Now provide the valid Polygon response. The request should complete with the expected results.
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 0, vaa.ChainIDPolygon, QuerySuccess, expectedResults[0].Response)
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestQueryFingerprintMatchesHandlerRequestID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	// Limit the number of calls so the request is rejected, which reports the request ID the handler used.
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{MaxTotalCalls: 1})
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+
+	fingerprint, err := QueryFingerprint(common.GoTest, queryRequest)
+	require.NoError(t, err)
+
+	// An identical request, built separately, has the same fingerprint.
+	var identicalRequest QueryRequest
+	require.NoError(t, identicalRequest.Unmarshal(signedQueryRequest.QueryRequest))
+	identicalFingerprint, err := QueryFingerprint(common.GoTest, &identicalRequest)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, identicalFingerprint)
+
+	// The fingerprint is bound to the environment.
+	otherFingerprint, err := QueryFingerprint(common.MainNet, queryRequest)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, otherFingerprint)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, TooManyCalls, failure.Reason)
+	assert.Equal(t, queryRequestID(signedQueryRequest.Signature, fingerprint), failure.RequestID)
 }
 
 func TestPublishRetrySucceeds(t *testing.T) {
 	ctx := context.Background()
 	logger := zap.NewNop()
 
-	md := createQueryHandlerForTestWithoutPublisher(t, ctx, logger, watcherChainsForTest)
+	md := createQueryHandlerForTestWithoutPublisher(t, ctx, logger, watcherChainsForTest, HandlerConfig{})
 
 	// Create the request and the expected results. Give the expected results to the mock.
 	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
@@ -811,10 +1547,2044 @@ func TestPublishRetrySucceeds(t *testing.T) {
 	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
 }
 
-func TestPerChainConfigValid(t *testing.T) {
-	for chainID, config := range perChainConfig {
-		if config.NumWorkers <= 0 {
-			assert.Equal(t, "", fmt.Sprintf(`perChainConfig for "%s" has an invalid NumWorkers: %d`, chainID.String(), config.NumWorkers))
-		}
+func TestPublishAttemptsAreIncludedInResponseMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithoutPublisher(t, ctx, logger, watcherChainsForTest, HandlerConfig{IncludePublishAttempts: true})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// Nothing is listening for published responses yet, so the first attempts fail.
+	time.Sleep(retryIntervalForTest * 3)
+	md.startResponseListener(ctx)
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Greater(t, queryResponsePublication.Metadata.PublishAttempts, 1)
+}
+
+func TestPublishedResponsesHaveIncreasingSequenceNumbers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	for count := 0; count < 5; count++ {
+		perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+		md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+		md.signedQueryReqWriteC <- signedQueryRequest
+		require.Eventually(t, func() bool {
+			return len(md.getPublications()) == count+1
+		}, requestTimeoutForTest, pollIntervalForTest)
+	}
+
+	var lastSequence uint64
+	for _, respPub := range md.getPublications() {
+		require.NotNil(t, respPub.Metadata)
+		assert.Greater(t, respPub.Metadata.Sequence, lastSequence)
+		lastSequence = respPub.Metadata.Sequence
+	}
+	assert.Equal(t, uint64(5), lastSequence)
+}
+
+func TestGossipMetadataIsIncludedInResponseMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	gossip := NewGossipStatus()
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{GossipStatus: gossip})
+
+	submit := func() *QueryResponsePublication {
+		md.resetState()
+		perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+		md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+		md.signedQueryReqWriteC <- signedQueryRequest
+		queryResponsePublication := md.waitForResponse()
+		require.NotNil(t, queryResponsePublication)
+		require.NotNil(t, queryResponsePublication.Metadata)
+		return queryResponsePublication
 	}
+
+	// Until the p2p layer has registered, there is no gossip metadata.
+	assert.Nil(t, submit().Metadata.Gossip)
+
+	// Simulate the p2p layer joining the response topic, and the number of peers on it changing.
+	numPeers := 3
+	gossip.Register("test/ccq/ccq_resp", func() int { return numPeers })
+	assert.Equal(t, &GossipMetadata{Topic: "test/ccq/ccq_resp", NumPeers: 3}, submit().Metadata.Gossip)
+
+	numPeers = 7
+	assert.Equal(t, &GossipMetadata{Topic: "test/ccq/ccq_resp", NumPeers: 7}, submit().Metadata.Gossip)
+}
+
+func TestMonotonicNonceRejectsLowerNonce(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{EnforceMonotonicNonce: true})
+
+	// Submit a request with a higher nonce. It should succeed.
+	nonce += 10
+	highNonce := nonce
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestWithNonceForTesting(t, md.sk, highNonce, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	// Submit a request with a lower nonce from the same signer. It should be rejected.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestWithNonceForTesting(t, md.sk, highNonce-5, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BadNonce, failure.Reason)
+	assert.Equal(t, ethCommon.HexToAddress(testSigner), failure.Signer)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// Reusing the last accepted nonce should also be rejected.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9632", 2)}
+	signedQueryRequest, _ = createSignedQueryRequestWithNonceForTesting(t, md.sk, highNonce, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure = md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BadNonce, failure.Reason)
+}
+
+func TestReplayedRequestIsRejectedWhenMonotonicNonceEnforced(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		EnforceMonotonicNonce:  true,
+		MaxNonceTrackedSigners: 10,
+		NonceReplayWindow:      time.Hour,
+	})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	// Once the original has been answered, resubmitting the exact same signed request, as an attacker capturing it off gossip could, must not
+	// produce another response.
+	md.resetState()
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BadNonce, failure.Reason)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestLowerNonceAllowedWhenMonotonicNonceNotEnforced(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	nonce += 10
+	highNonce := nonce
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestWithNonceForTesting(t, md.sk, highNonce, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestWithNonceForTesting(t, md.sk, highNonce-5, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+	assert.Nil(t, md.getFailure())
+}
+
+func TestDisabledQueryTypeIsRejectedOnlyOnThatChain(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	// There is no logs query type yet, so use eth_call_by_timestamp to simulate an incident affecting a single query type on BSC.
+	flags := &QueryTypeFlags{}
+	flags.Disable(vaa.ChainIDBSC, EthCallByTimestampQueryRequestType)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{QueryTypeFlags: flags})
+
+	// An eth_call_by_timestamp on BSC should be rejected.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDBSC, "0x28d9630", "0x28d9631", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, QueryTypeDisabled, failure.Reason)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	// An eth_call on BSC should still succeed.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// An eth_call_by_timestamp on another chain should still succeed.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDPolygon, "0x28d9630", "0x28d9631", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults = createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication = md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// Re-enabling the query type at runtime should allow it again on BSC.
+	flags.Enable(vaa.ChainIDBSC, EthCallByTimestampQueryRequestType)
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDBSC, "0x28d9630", "0x28d9631", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults = createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication = md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestBandwidthQuotaRejectsRequestsUntilWindowResets(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	const quota = 1000
+	const window = 500 * time.Millisecond
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{BandwidthQuotaBytes: quota, BandwidthQuotaWindow: window})
+
+	// Submit a request with a large response. It should succeed, but use up the quota.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 20)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	respBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(respBytes), quota)
+
+	// The next request should be rejected.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, BandwidthQuotaExceeded, failure.Reason)
+	assert.Equal(t, ethCommon.HexToAddress(testSigner), failure.Signer)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// Once the window resets, requests should be accepted again. The window started before the first request was accepted, so it has expired after this.
+	time.Sleep(window)
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9632", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication = md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestNearDuplicateFloodIsThrottled(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	const threshold = 3
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{NearDuplicateThreshold: threshold, NearDuplicateWindow: time.Minute})
+
+	// nearDuplicateQuery queries the same contract and function each time, only varying the block and the arguments.
+	nearDuplicateQuery := func(count int) []*PerChainQueryRequest {
+		pcq := createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, fmt.Sprintf("0x%x", 0x28d9630+count), 1)
+		pcq.Query.(*EthCallQueryRequest).CallData[0].Data = append([]byte("Call"), []byte(fmt.Sprintf("arguments %d", count))...)
+		return []*PerChainQueryRequest{pcq}
+	}
+
+	// The requests up to the threshold should be accepted.
+	for count := 0; count < threshold; count++ {
+		md.resetState()
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, nearDuplicateQuery(count))
+		expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+		md.setExpectedResults(expectedResults)
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		queryResponsePublication := md.waitForResponse()
+		require.NotNil(t, queryResponsePublication, count)
+		assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	}
+
+	// Once the flood reaches the threshold, further near duplicates should be rejected without being dispatched.
+	for count := threshold; count < threshold+3; count++ {
+		md.resetState()
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, nearDuplicateQuery(count))
+		md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		failure := md.waitForFailure()
+		require.NotNil(t, failure, count)
+		assert.Equal(t, NearDuplicateFlood, failure.Reason)
+		assert.Equal(t, ethCommon.HexToAddress(testSigner), failure.Signer)
+		assert.Nil(t, md.getQueryResponsePublication())
+		assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	}
+
+	// A request for a different contract is not a near duplicate, so it should still be accepted.
+	md.resetState()
+	perChainQueries := nearDuplicateQuery(0)
+	perChainQueries[0].Query.(*EthCallQueryRequest).CallData[0].To = []byte("Some other contract!")
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestRequireAllChainsWatchedRejectsWholeBatch(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{RequireAllChainsWatched: true})
+
+	// Ethereum supports queries, but there is no watcher for it in the test environment.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDEthereum, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, ChainsNotWatched, failure.Reason)
+	assert.Equal(t, []vaa.ChainID{vaa.ChainIDEthereum}, failure.MissingChains)
+
+	// Nothing should have been dispatched, not even to the watched chain.
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestClosedWatcherChannelFailsRequestWithWatcherGone(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+
+	// Make polygon keep asking for retries, and then go away mid-retry.
+	md.setRetries(vaa.ChainIDPolygon, 1000)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	require.Eventually(t, func() bool {
+		return md.getRequestsPerChain(vaa.ChainIDPolygon) > 1
+	}, requestTimeoutForTest/2, pollIntervalForTest)
+	start := time.Now()
+	md.setRetries(vaa.ChainIDPolygon, closeWatcher)
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, WatcherGone, failure.Reason)
+	assert.Equal(t, []vaa.ChainID{vaa.ChainIDPolygon}, failure.MissingChains)
+	assert.Less(t, time.Since(start), requestTimeoutForTest)
+	assert.Nil(t, md.getQueryResponsePublication())
+}
+
+func TestMaxTotalCallsRejectsBatchOverTheCap(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{MaxTotalCalls: 4})
+
+	// Each per chain query is within the limit, but the batch has five calls in total.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, TooManyCalls, failure.Reason)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	// A batch at the cap should succeed.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 2),
+	}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestRequestedRetryBudgetIsClampedToServerMax(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	const defaultRetryBudget = 1
+	const maxRetryBudget = 3
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{DefaultRetryBudget: defaultRetryBudget, MaxRetryBudget: maxRetryBudget})
+
+	// Make polygon retry so many times that every request times out.
+	md.setRetries(vaa.ChainIDPolygon, 1000)
+
+	// A request without a retry budget should be retried the default number of times.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	require.Nil(t, md.waitForResponse())
+	defaultRequests := md.getRequestsPerChain(vaa.ChainIDPolygon)
+	assert.Equal(t, defaultRetryBudget+1, defaultRequests)
+
+	// A request asking for more retries than the server allows should be retried more than the default, but only up to the server max.
+	nonce += 1
+	queryRequest = &QueryRequest{
+		Nonce:           nonce,
+		RetryBudget:     10,
+		PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)},
+	}
+	signedQueryRequest = signQueryRequestForTesting(t, md.sk, queryRequest)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	require.Nil(t, md.waitForResponse())
+	assert.Equal(t, maxRetryBudget+1, md.getRequestsPerChain(vaa.ChainIDPolygon)-defaultRequests)
+}
+
+func TestEffectiveRetryBudget(t *testing.T) {
+	assert.Equal(t, uint(0), effectiveRetryBudget(HandlerConfig{}, 0))
+	assert.Equal(t, uint(5), effectiveRetryBudget(HandlerConfig{}, 5))
+	assert.Equal(t, uint(2), effectiveRetryBudget(HandlerConfig{DefaultRetryBudget: 2}, 0))
+	assert.Equal(t, uint(4), effectiveRetryBudget(HandlerConfig{DefaultRetryBudget: 2, MaxRetryBudget: 8}, 4))
+	assert.Equal(t, uint(8), effectiveRetryBudget(HandlerConfig{DefaultRetryBudget: 2, MaxRetryBudget: 8}, 20))
+	assert.Equal(t, uint(8), effectiveRetryBudget(HandlerConfig{MaxRetryBudget: 8}, 0))
+}
+
+func TestCancelBySignerStopsAllInFlightRequests(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	cancelSignerC := make(chan ethCommon.Address)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{CancelSignerC: cancelSignerC, CancelOnFatalError: true})
+
+	// Make polygon never respond, so both requests stay in flight until they are cancelled.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+
+	signedQueryRequest1, queryRequest1 := createSignedQueryRequestForTesting(t, md.sk, []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)})
+	signedQueryRequest2, queryRequest2 := createSignedQueryRequestForTesting(t, md.sk, []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)})
+	md.setExpectedResults(createExpectedResultsForTest(t, append(queryRequest1.PerChainQueries, queryRequest2.PerChainQueries...)))
+	md.signedQueryReqWriteC <- signedQueryRequest1
+	md.signedQueryReqWriteC <- signedQueryRequest2
+
+	require.Eventually(t, func() bool {
+		return md.getRequestsPerChain(vaa.ChainIDPolygon) >= 2
+	}, requestTimeoutForTest/2, pollIntervalForTest)
+
+	signer := ethCommon.HexToAddress(testSigner)
+	cancelSignerC <- signer
+
+	require.Eventually(t, func() bool {
+		return len(md.getFailures()) == 2
+	}, requestTimeoutForTest/2, pollIntervalForTest)
+
+	requestIDs := map[string]struct{}{}
+	for _, failure := range md.getFailures() {
+		assert.Equal(t, Cancelled, failure.Reason)
+		assert.Equal(t, signer, failure.Signer)
+		requestIDs[failure.RequestID] = struct{}{}
+	}
+	assert.Equal(t, 2, len(requestIDs))
+
+	// The context a watcher would use for the polygon queries should be cancelled, so their RPC calls are aborted.
+	polygonRequest := md.getLastRequest(vaa.ChainIDPolygon)
+	require.NotNil(t, polygonRequest)
+	reqCtx, reqCancel := polygonRequest.Context(ctx)
+	defer reqCancel()
+	select {
+	case <-reqCtx.Done():
+	case <-time.After(requestTimeoutForTest / 2):
+		assert.Fail(t, "per chain query context was not cancelled")
+	}
+
+	// Neither request should be retried after being cancelled.
+	numRequests := md.getRequestsPerChain(vaa.ChainIDPolygon)
+	time.Sleep(5 * retryIntervalForTest)
+	assert.Equal(t, numRequests, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Nil(t, md.getQueryResponsePublication())
+}
+
+func TestTimedOutRequestIsCancelled(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{CancelOnFatalError: true})
+
+	// Make polygon never respond, so the request times out.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)})
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	require.Eventually(t, func() bool {
+		return md.getLastRequest(vaa.ChainIDPolygon) != nil
+	}, requestTimeoutForTest/2, pollIntervalForTest)
+
+	// Once the request times out, the context a watcher would use for the polygon query should be cancelled, so it stops retrying it.
+	reqCtx, reqCancel := md.getLastRequest(vaa.ChainIDPolygon).Context(ctx)
+	defer reqCancel()
+	select {
+	case <-reqCtx.Done():
+	case <-time.After(5 * requestTimeoutForTest):
+		assert.Fail(t, "per chain query context was not cancelled")
+	}
+	assert.Nil(t, md.getQueryResponsePublication())
+}
+
+func TestResultNormalizationIsAppliedToAllResults(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:               nonce,
+		ResultNormalization: TrimLeadingZeros,
+		PerChainQueries: []*PerChainQueryRequest{
+			createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+			createPerChainQueryForEthCallByTimestamp(t, vaa.ChainIDBSC, "0x28d9123", "0x28d9124", 3),
+		},
+	}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+
+	// Make the watchers return left padded results.
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	paddedResults := func(numResults int) [][]byte {
+		results := [][]byte{}
+		for idx := 0; idx < numResults; idx++ {
+			result := make([]byte, 32)
+			result[31] = byte(idx + 1)
+			results = append(results, result)
+		}
+		return results
+	}
+	expectedResults[0].Response.(*EthCallQueryResponse).Results = paddedResults(2)
+	expectedResults[1].Response.(*EthCallByTimestampQueryResponse).Results = paddedResults(3)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	require.Equal(t, 2, len(queryResponsePublication.PerChainResponses))
+
+	results := append([][]byte{}, queryResponsePublication.PerChainResponses[0].Response.(*EthCallQueryResponse).Results...)
+	results = append(results, queryResponsePublication.PerChainResponses[1].Response.(*EthCallByTimestampQueryResponse).Results...)
+	require.Equal(t, 5, len(results))
+	assert.Equal(t, [][]byte{{1}, {2}, {1}, {2}, {3}}, results)
+}
+
+func TestRequestsAreRejectedDuringKeyRotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	keyRotationC := make(chan bool)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{KeyRotationC: keyRotationC})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// While the rotation is in progress, new requests are rejected without being sent to the watcher.
+	keyRotationC <- true
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, KeyRotationInProgress, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// Once the rotation completes, requests are accepted again.
+	keyRotationC <- false
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+// mockSigner is a ResponseSigner whose key can be made unavailable, like a remote signer that is down.
+type mockSigner struct {
+	mutex       sync.Mutex
+	unavailable bool
+}
+
+func (s *mockSigner) Scheme() string {
+	return "mock"
+}
+
+func (s *mockSigner) Sign(digest ethCommon.Hash) ([]byte, error) {
+	return digest.Bytes(), nil
+}
+
+func (s *mockSigner) Available() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.unavailable {
+		return fmt.Errorf("remote signer is down")
+	}
+	return nil
+}
+
+func (s *mockSigner) setUnavailable(unavailable bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unavailable = unavailable
+}
+
+func TestRequestsAreRejectedWhileSigningIsUnavailable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	signer := &mockSigner{}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{ResponseSigner: signer})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// While the signer is unavailable, new requests are rejected without being sent to the watcher.
+	signer.setUnavailable(true)
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, SigningUnavailable, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// Once the signer recovers, requests are accepted again.
+	signer.setUnavailable(false)
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
+
+func TestResponseIsAssembledInRequestedSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	for _, schemaVersion := range []ResponseSchemaVersion{ResponseSchemaVersion1, ResponseSchemaVersion2} {
+		md.resetState()
+		nonce += 1
+		queryRequest := &QueryRequest{
+			Nonce:                 nonce,
+			ResponseSchemaVersion: schemaVersion,
+			PerChainQueries: []*PerChainQueryRequest{
+				createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+				createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+			},
+		}
+		signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+		expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+		md.setExpectedResults(expectedResults)
+
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		queryResponsePublication := md.waitForResponse()
+		require.NotNil(t, queryResponsePublication)
+		assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+		assert.Equal(t, schemaVersion, queryResponsePublication.SchemaVersion)
+
+		respBytes, err := queryResponsePublication.Marshal()
+		require.NoError(t, err)
+		assert.Equal(t, uint8(schemaVersion), respBytes[0])
+
+		var unmarshaled QueryResponsePublication
+		require.NoError(t, unmarshaled.Unmarshal(respBytes))
+		assert.True(t, queryResponsePublication.Equal(&unmarshaled))
+
+		// The version 1 layout should end with the last per chain response. The version 2 layout should end with the merkle root.
+		lastResp, err := queryResponsePublication.PerChainResponses[1].Marshal()
+		require.NoError(t, err)
+		if schemaVersion == ResponseSchemaVersion1 {
+			assert.True(t, bytes.HasSuffix(respBytes, lastResp))
+		} else {
+			root, err := queryResponsePublication.MerkleRoot()
+			require.NoError(t, err)
+			assert.True(t, bytes.HasSuffix(respBytes, append(lastResp, root.Bytes()...)))
+		}
+	}
+
+	// A request for an unsupported version cannot even be marshaled, so build it by hand.
+	md.resetState()
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:           nonce,
+		PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)},
+	}
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	unsupportedBytes := append([]byte{MSG_VERSION_WITH_OPTIONS}, queryRequestBytes[1:5]...)
+	unsupportedBytes = append(unsupportedBytes, 1, uint8(ResponseSchemaVersionOption), 9)
+	unsupportedBytes = append(unsupportedBytes, queryRequestBytes[5:]...)
+	digest := QueryRequestDigest(common.UnsafeDevNet, unsupportedBytes)
+	sig, err := ethCrypto.Sign(digest.Bytes(), md.sk)
+	require.NoError(t, err)
+	md.signedQueryReqWriteC <- &gossipv1.SignedQueryRequest{QueryRequest: unsupportedBytes, Signature: sig}
+
+	assert.Nil(t, md.waitForResponse())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+// mockFailingSink is a ResponseSink that fails a specified number of times before accepting responses.
+type mockFailingSink struct {
+	mutex             sync.Mutex
+	failuresRemaining int
+	numCalls          int
+	stored            *QueryResponsePublication
+}
+
+func (s *mockFailingSink) Store(respPub *QueryResponsePublication) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.numCalls++
+	if s.failuresRemaining > 0 {
+		s.failuresRemaining--
+		return fmt.Errorf("local sink is unavailable")
+	}
+	s.stored = respPub
+	return nil
+}
+
+func (s *mockFailingSink) getState() (int, *QueryResponsePublication) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.numCalls, s.stored
+}
+
+func TestLocalSinkIsRetriedUntilItAcceptsTheResponse(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	const numFailures = 3
+	sink := &mockFailingSink{failuresRemaining: numFailures}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{LocalSink: sink})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The response goes to p2p even though the local sink rejects it.
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// The local sink should be retried until it accepts the same response.
+	require.Eventually(t, func() bool {
+		_, stored := sink.getState()
+		return stored != nil
+	}, requestTimeoutForTest, pollIntervalForTest)
+
+	numCalls, stored := sink.getState()
+	assert.Equal(t, numFailures+1, numCalls)
+	assert.Equal(t, queryResponsePublication, stored)
+
+	// Once both have accepted the response, the local sink should not be called again.
+	time.Sleep(5 * auditIntervalForTest)
+	numCalls, _ = sink.getState()
+	assert.Equal(t, numFailures+1, numCalls)
+}
+
+func TestEthLogsQueryIsDispatchedToWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	transferTopic := ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	perChainQueries := []*PerChainQueryRequest{
+		{
+			ChainId: vaa.ChainIDPolygon,
+			Query: &EthLogsQueryRequest{
+				FromBlock: "0x28d9630",
+				ToBlock:   "0x28d9640",
+				Address:   ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599").Bytes(),
+				Topics:    [][]byte{transferTopic.Bytes()},
+			},
+		},
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// The response must survive a round trip unchanged, so the digest a client signs against is stable.
+	respBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	var received QueryResponsePublication
+	require.NoError(t, received.Unmarshal(respBytes))
+	assert.True(t, queryResponsePublication.Equal(&received))
+
+	digest, err := queryResponsePublication.SigningDigest()
+	require.NoError(t, err)
+	receivedDigest, err := received.SigningDigest()
+	require.NoError(t, err)
+	assert.Equal(t, digest, receivedDigest)
+}
+
+func TestEthLogsQueryWithResultFilterOnlyPublishesMatchingLogs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	transferTopic := ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	perChainQueries := []*PerChainQueryRequest{
+		{
+			ChainId: vaa.ChainIDPolygon,
+			Query: &EthLogsQueryRequest{
+				FromBlock: "0x28d9630",
+				ToBlock:   "0x28d9640",
+				Address:   ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599").Bytes(),
+				Topics:    [][]byte{transferTopic.Bytes()},
+			},
+			ResultFilter: "topic1 == 0x42",
+		},
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+
+	// The watcher returns two logs, only one of which matches the filter.
+	resp := expectedResults[0].Response.(*EthLogsQueryResponse)
+	matching := *resp.Logs[0]
+	matching.LogIndex = 1
+	matching.Topics = []ethCommon.Hash{transferTopic, ethCommon.BytesToHash([]byte{0x42})}
+	resp.Logs[0].Topics = append(resp.Logs[0].Topics, ethCommon.BytesToHash([]byte{0x43}))
+	resp.Logs = append(resp.Logs, &matching)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	require.Len(t, queryResponsePublication.PerChainResponses, 1)
+	published, ok := queryResponsePublication.PerChainResponses[0].Response.(*EthLogsQueryResponse)
+	require.True(t, ok)
+	require.Len(t, published.Logs, 1)
+	assert.Equal(t, uint32(1), published.Logs[0].LogIndex)
+	assert.Equal(t, resp.BlockNumber, published.BlockNumber)
+	assert.Nil(t, md.getFailure())
+}
+
+func TestResponseLargerThanMaxPublicationSizeIsRejected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	maxSize := 1024
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{MaxResponsePublicationSize: maxSize})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+
+	// Force an oversized response by having the watcher return a result that is larger than the limit on its own.
+	expectedResults[0].Response.(*EthCallQueryResponse).Results[0] = make([]byte, maxSize)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, ResponseTooLarge, failure.Reason)
+	assert.Equal(t, ethCommon.HexToAddress(testSigner), failure.Signer)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// A response within the limit is published as normal.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults = createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Nil(t, md.getFailure())
+}
+
+func TestEthStorageQueryIsDispatchedToWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		{
+			ChainId: vaa.ChainIDPolygon,
+			Query: &EthStorageQueryRequest{
+				BlockId:  "0x28d9630",
+				Contract: ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599").Bytes(),
+				Slots:    []ethCommon.Hash{ethCommon.HexToHash("0x00"), ethCommon.HexToHash("0x01")},
+			},
+		},
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestEthStorageQueryWithNoSlotsIsRejectedWithFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// A request without any slots can not be marshaled, so marshal it with one and then remove it. The per chain query length is at
+	// offset 9, after the version, nonce, number of queries, chain and query type.
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce: nonce,
+		PerChainQueries: []*PerChainQueryRequest{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Query: &EthStorageQueryRequest{
+					BlockId:  "0x28d9630",
+					Contract: ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599").Bytes(),
+					Slots:    []ethCommon.Hash{ethCommon.HexToHash("0x00")},
+				},
+			},
+		},
+	}
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	queryRequestBytes = queryRequestBytes[:len(queryRequestBytes)-ethCommon.HashLength]
+	queryRequestBytes[len(queryRequestBytes)-1] = 0
+	binary.BigEndian.PutUint32(queryRequestBytes[9:13], binary.BigEndian.Uint32(queryRequestBytes[9:13])-ethCommon.HashLength)
+
+	digest := QueryRequestDigest(common.UnsafeDevNet, queryRequestBytes)
+	sig, err := ethCrypto.Sign(digest.Bytes(), md.sk)
+	require.NoError(t, err)
+	md.signedQueryReqWriteC <- &gossipv1.SignedQueryRequest{QueryRequest: queryRequestBytes, Signature: sig}
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, RequestUnmarshalFailed, failure.Reason)
+	assert.Nil(t, md.waitForResponse())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestResponseContainsRequestNonce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	signedQueryRequest, queryRequest := createSignedQueryRequestWithNonceForTesting(t, md.sk, 4242, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, uint32(4242), queryResponsePublication.Nonce)
+
+	// The nonce is also populated when the published response is unmarshaled.
+	respBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	var unmarshaled QueryResponsePublication
+	require.NoError(t, unmarshaled.Unmarshal(respBytes))
+	assert.Equal(t, uint32(4242), unmarshaled.Nonce)
+}
+
+func TestResponseMetadataIdentifiesGuardian(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	gk, err := common.LoadGuardianKey("dev.guardian.key", true)
+	require.NoError(t, err)
+	guardianAddr := ethCrypto.PubkeyToAddress(gk.PublicKey)
+	gst := common.NewGuardianSetState(nil)
+	gst.Set(&common.GuardianSet{Keys: []ethCommon.Address{ethCommon.HexToAddress("0x01"), guardianAddr}, Index: 4})
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{GuardianAddress: guardianAddr, GuardianSetState: gst})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	require.NotNil(t, queryResponsePublication.Metadata)
+	assert.Equal(t, guardianAddr, queryResponsePublication.Metadata.GuardianAddress)
+	assert.Equal(t, uint32(4), queryResponsePublication.Metadata.GuardianSetIndex)
+	assert.Equal(t, 1, queryResponsePublication.Metadata.GuardianIndex)
+
+	// A guardian that is not in the current set has no index.
+	md.resetState()
+	gst.Set(&common.GuardianSet{Keys: []ethCommon.Address{ethCommon.HexToAddress("0x01")}, Index: 5})
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9631", 2)}
+	signedQueryRequest, _ = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication = md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.Equal(t, guardianAddr, queryResponsePublication.Metadata.GuardianAddress)
+	assert.Equal(t, uint32(5), queryResponsePublication.Metadata.GuardianSetIndex)
+	assert.Equal(t, -1, queryResponsePublication.Metadata.GuardianIndex)
+}
+
+func TestResponseMetadataContainsRpcNode(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Create the request and the expected results. Give the expected results to the mock.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Only polygon reports an RPC node.
+	md.setRpcNode(vaa.ChainIDPolygon, "polygon-rpc.example.com")
+
+	// Submit the query request to the handler.
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	require.NotNil(t, queryResponsePublication.Metadata)
+	require.Equal(t, 2, len(queryResponsePublication.Metadata.PerChain))
+	require.NotNil(t, queryResponsePublication.Metadata.PerChain[0])
+	assert.Equal(t, "polygon-rpc.example.com", queryResponsePublication.Metadata.PerChain[0].RpcNode)
+	assert.Nil(t, queryResponsePublication.Metadata.PerChain[1])
+
+	// The metadata should not be part of the signed bytes.
+	respBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	var unmarshaled QueryResponsePublication
+	require.NoError(t, unmarshaled.Unmarshal(respBytes))
+	assert.Nil(t, unmarshaled.Metadata)
+	assert.True(t, queryResponsePublication.Equal(&unmarshaled))
+}
+
+func TestResponseMetadataContainsBlockSummary(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 1),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// The summary should list the block used on each chain, in request order.
+	require.NotNil(t, queryResponsePublication.Metadata)
+	summary := queryResponsePublication.Metadata.BlockSummary
+	require.Equal(t, 2, len(summary))
+	for idx, chainID := range []vaa.ChainID{vaa.ChainIDPolygon, vaa.ChainIDBSC} {
+		resp := queryResponsePublication.PerChainResponses[idx].Response.(*EthCallQueryResponse)
+		assert.Equal(t, chainID, summary[idx].ChainId)
+		assert.Equal(t, resp.BlockNumber, summary[idx].BlockNumber)
+		assert.Equal(t, resp.Hash.Bytes(), summary[idx].BlockHash)
+	}
+	assert.Equal(t, queryResponsePublication.BlockSummary(), summary)
+}
+
+// startFailoverWatcherForTest starts a mock secondary watcher that always succeeds. It returns a function that reports
+// how many requests the primary watcher had received each time the secondary was invoked.
+func startFailoverWatcherForTest(t *testing.T, ctx context.Context, md *mockData, chainId vaa.ChainID, secondaryC <-chan *PerChainQueryInternal) func() []int {
+	t.Helper()
+	var mutex sync.Mutex
+	primaryRequestsSeen := []int{}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case pcqr := <-secondaryC:
+				primaryRequests := md.getRequestsPerChain(chainId)
+				mutex.Lock()
+				primaryRequestsSeen = append(primaryRequestsSeen, primaryRequests)
+				mutex.Unlock()
+
+				md.mutex.Lock()
+				results := md.expectedResults[pcqr.RequestIdx].Response
+				md.mutex.Unlock()
+				md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(pcqr.RequestID, pcqr.RequestIdx, pcqr.Request.ChainId, QuerySuccess, results)
+			}
+		}
+	}()
+
+	return func() []int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]int{}, primaryRequestsSeen...)
+	}
+}
+
+func TestFailoverToSecondaryWatcherAfterPrimaryFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	secondaryC := make(chan *PerChainQueryInternal)
+	config := HandlerConfig{FailoverChainQueryReqC: map[vaa.ChainID][]chan *PerChainQueryInternal{vaa.ChainIDPolygon: {secondaryC}}}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, config)
+	getPrimaryRequestsSeen := startFailoverWatcherForTest(t, ctx, md, vaa.ChainIDPolygon, secondaryC)
+
+	// Create the request and the expected results. Give the expected results to the mock.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Make the primary return a fatal error.
+	md.setRetries(vaa.ChainIDPolygon, fatalError)
+
+	// Submit the query request to the handler.
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The request should be answered by the secondary.
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// The secondary should have been invoked exactly once, after the primary had already been invoked (and failed).
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, []int{1}, getPrimaryRequestsSeen())
+}
+
+func TestFailoverWatcherNotUsedIfPrimarySucceeds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	secondaryC := make(chan *PerChainQueryInternal)
+	config := HandlerConfig{FailoverChainQueryReqC: map[vaa.ChainID][]chan *PerChainQueryInternal{vaa.ChainIDPolygon: {secondaryC}}}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, config)
+	getPrimaryRequestsSeen := startFailoverWatcherForTest(t, ctx, md, vaa.ChainIDPolygon, secondaryC)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Make the primary retry a couple of times, which should not trigger a failover.
+	md.setRetries(vaa.ChainIDPolygon, 2)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	assert.Equal(t, 3, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 0, len(getPrimaryRequestsSeen()))
+}
+
+func TestRetryRotatesToNextWatcherAfterTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	secondaryC := make(chan *PerChainQueryInternal)
+	config := HandlerConfig{
+		FailoverChainQueryReqC:  map[vaa.ChainID][]chan *PerChainQueryInternal{vaa.ChainIDPolygon: {secondaryC}},
+		RotateWatchersOnTimeout: map[vaa.ChainID]struct{}{vaa.ChainIDPolygon: {}},
+	}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, config)
+	getPrimaryRequestsSeen := startFailoverWatcherForTest(t, ctx, md, vaa.ChainIDPolygon, secondaryC)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// The primary never responds, so the retry should go to the secondary, which succeeds.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, []int{1}, getPrimaryRequestsSeen())
+}
+
+func TestRetryDoesNotRotateWatchersIfNotConfigured(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	// Rotation is only enabled for BSC, so the polygon query keeps going to the primary until the request times out.
+	secondaryC := make(chan *PerChainQueryInternal)
+	config := HandlerConfig{
+		FailoverChainQueryReqC:  map[vaa.ChainID][]chan *PerChainQueryInternal{vaa.ChainIDPolygon: {secondaryC}},
+		RotateWatchersOnTimeout: map[vaa.ChainID]struct{}{vaa.ChainIDBSC: {}},
+	}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, config)
+	getPrimaryRequestsSeen := startFailoverWatcherForTest(t, ctx, md, vaa.ChainIDPolygon, secondaryC)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	assert.Nil(t, md.waitForResponse())
+	assert.Less(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 0, len(getPrimaryRequestsSeen()))
+}
+
+func TestRotateMovesCurrentWatcherToEndOfList(t *testing.T) {
+	primaryC, secondaryC, tertiaryC := make(chan *PerChainQueryInternal), make(chan *PerChainQueryInternal), make(chan *PerChainQueryInternal)
+	failoverChannels := []chan *PerChainQueryInternal{secondaryC, tertiaryC}
+	pcq := &perChainQuery{channel: primaryC, failoverChannels: failoverChannels, numForwards: 2}
+
+	require.True(t, pcq.rotate())
+	assert.Equal(t, secondaryC, pcq.channel)
+	assert.Equal(t, []chan *PerChainQueryInternal{tertiaryC, primaryC}, pcq.failoverChannels)
+	assert.Equal(t, uint(2), pcq.numForwards)
+
+	require.True(t, pcq.rotate())
+	require.True(t, pcq.rotate())
+	assert.Equal(t, primaryC, pcq.channel)
+
+	// The list from the config must not be modified.
+	assert.Equal(t, []chan *PerChainQueryInternal{secondaryC, tertiaryC}, failoverChannels)
+
+	assert.False(t, (&perChainQuery{channel: primaryC}).rotate())
+}
+
+// mockPublisher is a ResponsePublisher that records what it is asked to publish.
+type mockPublisher struct {
+	mutex     sync.Mutex
+	keys      []string
+	responses [][]byte
+}
+
+func (mp *mockPublisher) Publish(_ context.Context, key string, response []byte) error {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+	mp.keys = append(mp.keys, key)
+	mp.responses = append(mp.responses, response)
+	return nil
+}
+
+// waitForPublish waits for the mock publisher to receive a response. It will eventually timeout if nothing is published.
+func (mp *mockPublisher) waitForPublish() (string, []byte) {
+	for count := 0; count < 50; count++ {
+		time.Sleep(pollIntervalForTest)
+		mp.mutex.Lock()
+		if len(mp.responses) != 0 {
+			defer mp.mutex.Unlock()
+			return mp.keys[0], mp.responses[0]
+		}
+		mp.mutex.Unlock()
+	}
+	return "", nil
+}
+
+func TestExternalPublisherReceivesResponse(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	publisher := &mockPublisher{}
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{Publisher: publisher})
+
+	// Create the request and the expected results. Give the expected results to the mock.
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Submit the query request to the handler.
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The response should be published to p2p.
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// The same serialized response should be published to the external publisher.
+	key, published := publisher.waitForPublish()
+	require.NotNil(t, published)
+	assert.Equal(t, hex.EncodeToString(signedQueryRequest.Signature), key)
+
+	expectedBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, expectedBytes, published)
+}
+
+func TestPostPublishHooksAreInvokedOncePerSuccessfulQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	// The first hook always panics, which must not stop the second one from recording every publication.
+	var mutex sync.Mutex
+	published := []*QueryResponsePublication{}
+	hooks := []PostPublishHook{
+		func(*QueryResponsePublication) { panic("hook failed") },
+		func(respPub *QueryResponsePublication) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			published = append(published, respPub)
+		},
+	}
+	getPublished := func() []*QueryResponsePublication {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]*QueryResponsePublication{}, published...)
+	}
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{PostPublishHooks: hooks})
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+
+	signatures := []string{}
+	for count := 0; count < 2; count++ {
+		md.resetState()
+		md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+		signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+		md.signedQueryReqWriteC <- signedQueryRequest
+		require.NotNil(t, md.waitForResponse())
+		signatures = append(signatures, hex.EncodeToString(signedQueryRequest.Signature))
+	}
+
+	// A failed query is not passed to the hooks.
+	md.resetState()
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	md.setRetries(vaa.ChainIDPolygon, fatalError)
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	assert.Nil(t, md.waitForResponse())
+
+	require.Eventually(t, func() bool { return len(getPublished()) >= 2 }, time.Second, pollIntervalForTest)
+	recorded := getPublished()
+	require.Equal(t, 2, len(recorded))
+	for idx, respPub := range recorded {
+		assert.Equal(t, signatures[idx], respPub.Signature())
+	}
+}
+
+func TestPerChainConfigValid(t *testing.T) {
+	for chainID, config := range perChainConfig {
+		if config.NumWorkers <= 0 {
+			assert.Equal(t, "", fmt.Sprintf(`perChainConfig for "%s" has an invalid NumWorkers: %d`, chainID.String(), config.NumWorkers))
+		}
+	}
+}
+
+func TestCachedResultIsServedWhenWatcherFailsFatally(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{CachedResultMaxAge: time.Minute})
+
+	createRequest := func(allowCachedResults bool) (*gossipv1.SignedQueryRequest, *QueryRequest) {
+		nonce += 1
+		queryRequest := &QueryRequest{
+			Nonce:              nonce,
+			AllowCachedResults: allowCachedResults,
+			PerChainQueries: []*PerChainQueryRequest{
+				createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+				createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+			},
+		}
+		return signQueryRequestForTesting(t, md.sk, queryRequest), queryRequest
+	}
+
+	// The first request succeeds, which caches the results.
+	signedQueryRequest, queryRequest := createRequest(false)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// If the request does not allow cached results, a fatal error still fails it.
+	md.resetState()
+	md.setRetries(vaa.ChainIDBSC, fatalError)
+	signedQueryRequest, queryRequest = createRequest(false)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.Nil(t, md.waitForResponse())
+
+	// If it does, the cached result is served in place of the fatal error, and flagged in the metadata.
+	md.resetState()
+	md.setRetries(vaa.ChainIDBSC, fatalError)
+	signedQueryRequest, queryRequest = createRequest(true)
+	liveResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(liveResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The polygon result is live, but the BSC result is the one cached from the first request.
+	queryResponsePublication = md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, []PerChainQueryResponse{liveResults[0], expectedResults[1]}))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	require.NotNil(t, queryResponsePublication.Metadata)
+	require.Equal(t, 2, len(queryResponsePublication.Metadata.PerChain))
+	assert.False(t, queryResponsePublication.Metadata.PerChain[0].servedFromCache())
+	require.NotNil(t, queryResponsePublication.Metadata.PerChain[1])
+	assert.True(t, queryResponsePublication.Metadata.PerChain[1].ServedFromCache)
+}
+
+func TestCachedResultIsNotServedOnceExpired(t *testing.T) {
+	rc := newResultCache(time.Minute)
+	pcq := createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3)
+	pq := &pendingQuery{
+		request: &QueryRequest{AllowCachedResults: true, PerChainQueries: []*PerChainQueryRequest{pcq}},
+		queries: []*perChainQuery{{req: &PerChainQueryInternal{RequestIdx: 0, Request: pcq}}},
+	}
+	fatalResp := CreatePerChainQueryResponseInternal("requestID", 0, vaa.ChainIDBSC, QueryFatalError, nil)
+	now := time.Now()
+
+	assert.Nil(t, rc.fallbackResponse(pq, fatalResp, now))
+
+	expectedResults := createExpectedResultsForTest(t, pq.request.PerChainQueries)
+	require.NoError(t, rc.store(pcq, CreatePerChainQueryResponseInternal("earlier", 0, vaa.ChainIDBSC, QuerySuccess, expectedResults[0].Response), now, false))
+
+	cachedResp := rc.fallbackResponse(pq, fatalResp, now.Add(time.Minute))
+	require.NotNil(t, cachedResp)
+	assert.Equal(t, "requestID", cachedResp.RequestID)
+	assert.Equal(t, QuerySuccess, cachedResp.Status)
+	assert.True(t, cachedResp.Metadata.ServedFromCache)
+
+	// A response served from the cache is not cached again, so it does not extend the life of the entry.
+	require.NoError(t, rc.store(pcq, cachedResp, now.Add(time.Minute), false))
+	assert.Nil(t, rc.fallbackResponse(pq, fatalResp, now.Add(time.Minute+time.Nanosecond)))
+
+	rc.prune(now.Add(time.Minute + time.Nanosecond))
+	assert.Equal(t, 0, len(rc.entries))
+
+	// A nil cache never serves anything.
+	assert.Nil(t, newResultCache(0).fallbackResponse(pq, fatalResp, now))
+}
+
+func TestReceiveTimeIsIncludedInResponseMetadata(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	for _, includeReceiveTime := range []bool{false, true} {
+		md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{IncludeReceiveTime: includeReceiveTime})
+
+		perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+		expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+		md.setExpectedResults(expectedResults)
+
+		submitTime := time.Now()
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		queryResponsePublication := md.waitForResponse()
+		require.NotNil(t, queryResponsePublication)
+		assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+		require.NotNil(t, queryResponsePublication.Metadata)
+
+		if !includeReceiveTime {
+			assert.True(t, queryResponsePublication.Metadata.ReceiveTime.IsZero())
+			assert.True(t, queryResponsePublication.Metadata.PublishTime.IsZero())
+			continue
+		}
+
+		receiveTime := queryResponsePublication.Metadata.ReceiveTime
+		publishTime := queryResponsePublication.Metadata.PublishTime
+		require.False(t, receiveTime.IsZero())
+		assert.False(t, receiveTime.Before(submitTime))
+		assert.True(t, receiveTime.Before(publishTime))
+		assert.False(t, publishTime.After(time.Now()))
+	}
+}
+
+func TestFatalErrorCancelsSiblingPerChainQueries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	for _, cancelOnFatalError := range []bool{false, true} {
+		md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{CancelOnFatalError: cancelOnFatalError})
+
+		perChainQueries := []*PerChainQueryRequest{
+			createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+			createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		}
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+		expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+		md.setExpectedResults(expectedResults)
+
+		// Polygon keeps asking for retries. Once it has received the query, BSC fails fatally, which dooms the request. BSC does not fail
+		// straight away, since the request could be dropped before Polygon is ever sent the query.
+		md.setRetries(vaa.ChainIDPolygon, 1000)
+		md.setRetries(vaa.ChainIDBSC, 1000)
+
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		var polygonRequest *PerChainQueryInternal
+		for count := 0; count < 50 && polygonRequest == nil; count++ {
+			time.Sleep(pollIntervalForTest)
+			polygonRequest = md.getLastRequest(vaa.ChainIDPolygon)
+		}
+		require.NotNil(t, polygonRequest)
+		md.setRetries(vaa.ChainIDBSC, fatalError)
+
+		// The context a watcher would use for the Polygon query should be cancelled promptly, well before the request would time out.
+		reqCtx, reqCancel := polygonRequest.Context(ctx)
+		select {
+		case <-reqCtx.Done():
+			assert.True(t, cancelOnFatalError)
+		case <-time.After(requestTimeoutForTest / 2):
+			assert.False(t, cancelOnFatalError)
+		}
+		reqCancel()
+
+		// Polygon should not be retried once the request has failed. A retry sent just before the failure may still be arriving.
+		time.Sleep(retryIntervalForTest)
+		numPolygonRequests := md.getRequestsPerChain(vaa.ChainIDPolygon)
+		time.Sleep(5 * retryIntervalForTest)
+		assert.Equal(t, numPolygonRequests, md.getRequestsPerChain(vaa.ChainIDPolygon))
+		assert.Nil(t, md.getQueryResponsePublication())
+	}
+}
+
+func TestStaleBlockIsRetriedUntilFreshEnough(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Only the Polygon query has a max block age.
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	perChainQueries[0].MaxBlockAge = 60
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Polygon retries once, then answers using a stale block twice, before answering using a fresh block. BSC answers using a stale block,
+	// which is accepted since its query does not have a max block age.
+	md.setRetries(vaa.ChainIDPolygon, 1)
+	md.setStaleResults(vaa.ChainIDPolygon, 2)
+	md.setStaleResults(vaa.ChainIDBSC, 1)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.Equal(t, 4, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	polygonResp := queryResponsePublication.PerChainResponses[0].Response.(*EthCallQueryResponse)
+	assert.True(t, polygonResp.Equal(expectedResults[0].Response.(*EthCallQueryResponse)))
+	bscResp := queryResponsePublication.PerChainResponses[1].Response.(*EthCallQueryResponse)
+	assert.Equal(t, expectedResults[1].Response.(*EthCallQueryResponse).Time.Add(-time.Hour), bscResp.Time)
+}
+
+func TestDecimalBlockIdResolvesToTheSameBlockAsHex(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	var blockNumbers []uint64
+	for _, blockId := range []string{"0x28d9630", "d:42833456"} {
+		md.resetState()
+		perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, blockId, 2)}
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+		expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+		md.setExpectedResults(expectedResults)
+
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		queryResponsePublication := md.waitForResponse()
+		require.NotNil(t, queryResponsePublication)
+		assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+		blockNumbers = append(blockNumbers, queryResponsePublication.PerChainResponses[0].Response.(*EthCallQueryResponse).BlockNumber)
+	}
+
+	assert.Equal(t, uint64(42833456), blockNumbers[0])
+	assert.Equal(t, blockNumbers[0], blockNumbers[1])
+}
+
+func TestTightSlaFailsFastUnderBacklogWhileLooseSlaSucceeds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	// Each Polygon worker is expected to take a second per query, so a backlog of ten queries across its five workers means a new query
+	// should take three seconds. That meets the standard tier, but not the fast one.
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{SlaQueryTimeEstimate: time.Second})
+	md.setExpectedResults(createExpectedResultsForTest(t, []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}))
+
+	// Build up a backlog of best effort requests that the Polygon watcher does not answer. The handler processes requests in order,
+	// so these are all pending by the time it sees the requests below.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+	for count := 0; count < 10; count++ {
+		signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)})
+		md.signedQueryReqWriteC <- signedQueryRequest
+	}
+
+	createSlaRequest := func(slaTier SlaTier) *gossipv1.SignedQueryRequest {
+		nonce += 1
+		return signQueryRequestForTesting(t, md.sk, &QueryRequest{
+			Nonce:           nonce,
+			SlaTier:         slaTier,
+			PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)},
+		})
+	}
+
+	// The fast request should fail right away, without being dispatched.
+	start := time.Now()
+	md.signedQueryReqWriteC <- createSlaRequest(SlaTierFast)
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, SlaCannotBeMet, failure.Reason)
+	assert.Less(t, time.Since(start), requestTimeoutForTest)
+
+	// The standard request should be dispatched and, once the watcher starts answering, succeed.
+	standardRequest := createSlaRequest(SlaTierStandard)
+	md.signedQueryReqWriteC <- standardRequest
+	md.setRetries(vaa.ChainIDPolygon, 1)
+
+	require.Eventually(t, func() bool {
+		for _, respPub := range md.getPublications() {
+			if bytes.Equal(standardRequest.Signature, respPub.Request.Signature) {
+				return true
+			}
+		}
+		return false
+	}, requestTimeoutForTest, pollIntervalForTest)
+	assert.Equal(t, 1, len(md.getFailures()))
+}
+
+func TestRetryHistoryIsIncludedInResponseMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// Polygon retries twice before succeeding, while BSC succeeds right away.
+	md.setRetries(vaa.ChainIDPolygon, 2)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 3, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	require.NotNil(t, queryResponsePublication.Metadata)
+	require.Equal(t, 2, len(queryResponsePublication.Metadata.PerChain))
+	require.NotNil(t, queryResponsePublication.Metadata.PerChain[0])
+	assert.Equal(t, []*RetryAttempt{
+		{Attempt: 1, Status: QueryRetryNeeded, Error: "mock watcher returned status 0 on request 1"},
+		{Attempt: 2, Status: QueryRetryNeeded, Error: "mock watcher returned status 0 on request 2"},
+	}, queryResponsePublication.Metadata.PerChain[0].RetryHistory)
+	assert.Nil(t, queryResponsePublication.Metadata.PerChain[1])
+}
+
+func TestRpcErrorIsPropagatedToResponseAndFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	// The Polygon RPC node reports a rate limit once before succeeding.
+	rateLimited := &RpcError{Code: -32005, Message: "limit exceeded"}
+	md.setRpcError(vaa.ChainIDPolygon, rateLimited)
+	md.setRetries(vaa.ChainIDPolygon, 1)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	require.NotNil(t, queryResponsePublication.Metadata.PerChain[0])
+	require.Len(t, queryResponsePublication.Metadata.PerChain[0].RetryHistory, 1)
+	assert.Equal(t, rateLimited, queryResponsePublication.Metadata.PerChain[0].RetryHistory[0].RpcError)
+
+	// The BSC RPC node reports that the block does not exist.
+	md.resetState()
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	unknownBlock := &RpcError{Code: -32001, Message: "resource not found"}
+	md.setRpcError(vaa.ChainIDBSC, unknownBlock)
+	md.setRetries(vaa.ChainIDBSC, notFound)
+	signedQueryRequest, _ = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, NotFound, failure.Reason)
+	require.NotNil(t, failure.NotFoundQuery)
+	assert.Equal(t, unknownBlock, failure.NotFoundQuery.RpcError)
+}
+
+func TestCorrelationIDIsLoggedByHandlerAndWatchers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	observedCore, observedLogs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(observedCore)
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	requestID := hex.EncodeToString(signedQueryRequest.Signature) + ":" + QueryRequestDigest(common.GoTest, signedQueryRequest.QueryRequest).String()
+	correlationID := queryCorrelationID(requestID)
+	assert.Len(t, correlationID, 16)
+
+	// The correlation ID is passed to the watchers, which log it along with the full ID of the per chain query.
+	for requestIdx, chainID := range []vaa.ChainID{vaa.ChainIDPolygon, vaa.ChainIDBSC} {
+		pcqi := md.getLastRequest(chainID)
+		require.NotNil(t, pcqi)
+		assert.Equal(t, correlationID, pcqi.CorrelationID)
+		assert.Equal(t, fmt.Sprintf("%s:%d", requestID, requestIdx), pcqi.ID())
+	}
+
+	// Every log line about the request carries it, from both the handler and the watchers.
+	correlated := observedLogs.FilterField(zap.String("requestId", correlationID))
+	for _, msg := range []string{"received a query request", "received final per chain query response, ready to publish", "watcher returning"} {
+		assert.NotZero(t, correlated.FilterMessage(msg).Len(), msg)
+	}
+	assert.Equal(t, observedLogs.FilterField(zap.String("requestID", requestID)).Len(), correlated.FilterField(zap.String("requestID", requestID)).Len())
+}
+
+func TestRetryIntervalsAreIncludedInResponseMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{IncludeRetryIntervals: true})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.setRetries(vaa.ChainIDPolygon, 2)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+
+	// Each retry waited at least the configured retry interval, and not much more than one extra audit interval, allowing for scheduling delays.
+	// The query may also have been retried while the mock watcher was still answering, so there may be more intervals than unsuccessful attempts.
+	require.NotNil(t, queryResponsePublication.Metadata.PerChain[0])
+	retryIntervals := queryResponsePublication.Metadata.PerChain[0].RetryIntervals
+	require.GreaterOrEqual(t, len(retryIntervals), 2)
+	for _, interval := range retryIntervals {
+		assert.GreaterOrEqual(t, interval, retryIntervalForTest)
+		assert.Less(t, interval, retryIntervalForTest+auditIntervalForTest+requestTimeoutForTest/2)
+	}
+}
+
+func TestRequestForAnotherEnvironmentFailsWithWrongEnvironment(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+
+	for _, enforce := range []bool{false, true} {
+		md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{EnforceRequestEnvironment: enforce})
+		md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+
+		// A request for mainnet, signed for mainnet, submitted to a devnet guardian.
+		nonce += 1
+		queryRequest := &QueryRequest{Nonce: nonce, Environment: MainNetEnvironment, PerChainQueries: perChainQueries}
+		queryRequestBytes, err := queryRequest.Marshal()
+		require.NoError(t, err)
+		sig, err := ethCrypto.Sign(QueryRequestDigest(common.MainNet, queryRequestBytes).Bytes(), md.sk)
+		require.NoError(t, err)
+		md.signedQueryReqWriteC <- &gossipv1.SignedQueryRequest{QueryRequest: queryRequestBytes, Signature: sig}
+
+		if enforce {
+			failure := md.waitForFailure()
+			require.NotNil(t, failure)
+			assert.Equal(t, WrongEnvironment, failure.Reason)
+			assert.Equal(t, ethCommon.HexToAddress(testSigner), failure.Signer)
+		} else {
+			// Without enforcement, the request is dropped as coming from an unknown signer, without a failure being reported.
+			assert.Nil(t, md.waitForFailure())
+		}
+		assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+		// A request for mainnet that was signed for devnet is also rejected when enforcing.
+		md.resetState()
+		md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+		nonce += 1
+		signedQueryRequest := signQueryRequestForTesting(t, md.sk, &QueryRequest{Nonce: nonce, Environment: MainNetEnvironment, PerChainQueries: perChainQueries})
+		md.signedQueryReqWriteC <- signedQueryRequest
+		if enforce {
+			failure := md.waitForFailure()
+			require.NotNil(t, failure)
+			assert.Equal(t, WrongEnvironment, failure.Reason)
+			assert.Nil(t, md.getQueryResponsePublication())
+		} else {
+			require.NotNil(t, md.waitForResponse())
+		}
+
+		// A request for devnet is accepted.
+		md.resetState()
+		md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+		nonce += 1
+		md.signedQueryReqWriteC <- signQueryRequestForTesting(t, md.sk, &QueryRequest{Nonce: nonce, Environment: DevNetEnvironment, PerChainQueries: perChainQueries})
+		require.NotNil(t, md.waitForResponse())
+	}
+}
+
+func TestNotFoundResponseIsReportedWithNotFoundMarker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+
+	// The BSC watcher reports that what was queried does not exist.
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	md.setRetries(vaa.ChainIDBSC, notFound)
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, NotFound, failure.Reason)
+	require.NotNil(t, failure.NotFoundQuery)
+	assert.Equal(t, 1, failure.NotFoundQuery.RequestIdx)
+	assert.Equal(t, vaa.ChainIDBSC, failure.NotFoundQuery.ChainId)
+	assert.Equal(t, fmt.Sprintf("mock watcher returned status %d on request 1", QueryNotFound), failure.NotFoundQuery.Error)
+	assert.Nil(t, md.getQueryResponsePublication())
+
+	// It is not retried.
+	time.Sleep(5 * retryIntervalForTest)
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+
+	// A fatal error is not reported as not found.
+	md.resetState()
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	md.setRetries(vaa.ChainIDBSC, fatalError)
+	signedQueryRequest, _ = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	assert.Nil(t, md.waitForFailure())
+	assert.Nil(t, md.getQueryResponsePublication())
+}
+
+func TestNodeSyncingRejectsRequestWithNodeSyncing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	md.setRetries(vaa.ChainIDBSC, nodeSyncing)
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, NodeSyncing, failure.Reason)
+	assert.Equal(t, []vaa.ChainID{vaa.ChainIDBSC}, failure.MissingChains)
+	assert.Nil(t, md.getQueryResponsePublication())
+}
+
+func TestNodeSyncingIsRetriedWhenDeferred(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{DeferWhileNodeSyncing: true})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.setRetries(vaa.ChainIDBSC, nodeSyncing)
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// Once the node has caught up, the query succeeds.
+	require.Eventually(t, func() bool { return md.getRequestsPerChain(vaa.ChainIDBSC) >= 2 }, time.Second, pollIntervalForTest)
+	md.setRetries(vaa.ChainIDBSC, 0)
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Nil(t, md.getFailure())
+	assert.Equal(t, QueryNodeSyncing, queryResponsePublication.Metadata.PerChain[0].RetryHistory[0].Status)
+}
+
+func TestPreferSpeedIsPassedToWatchersAndFlaggedInResponse(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	nonce += 1
+	queryRequest := &QueryRequest{
+		Nonce:           nonce,
+		PreferSpeed:     true,
+		PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)},
+	}
+	signedQueryRequest := signQueryRequestForTesting(t, md.sk, queryRequest)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.True(t, queryResponsePublication.PreferSpeed)
+
+	pcqi := md.getLastRequest(vaa.ChainIDPolygon)
+	require.NotNil(t, pcqi)
+	assert.True(t, pcqi.PreferSpeed)
 }
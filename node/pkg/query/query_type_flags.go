@@ -0,0 +1,86 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// QueryTypeFlags tracks which query types are disabled on which chains. It is safe to update while the query handler is running,
+// which allows operators to disable a single query type on a single chain during an incident without disabling the whole chain.
+// The zero value has every query type enabled on every chain.
+type QueryTypeFlags struct {
+	mutex    sync.RWMutex
+	disabled map[vaa.ChainID]map[ChainSpecificQueryType]struct{}
+}
+
+// Disable disables the specified query type on the specified chain. Requests containing it will be rejected with QueryTypeDisabled.
+func (f *QueryTypeFlags) Disable(chainID vaa.ChainID, queryType ChainSpecificQueryType) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.disabled == nil {
+		f.disabled = make(map[vaa.ChainID]map[ChainSpecificQueryType]struct{})
+	}
+	if _, exists := f.disabled[chainID]; !exists {
+		f.disabled[chainID] = make(map[ChainSpecificQueryType]struct{})
+	}
+	f.disabled[chainID][queryType] = struct{}{}
+}
+
+// Enable re-enables the specified query type on the specified chain.
+func (f *QueryTypeFlags) Enable(chainID vaa.ChainID, queryType ChainSpecificQueryType) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.disabled[chainID], queryType)
+}
+
+// IsEnabled returns true if the specified query type is enabled on the specified chain. A nil object has everything enabled.
+func (f *QueryTypeFlags) IsEnabled(chainID vaa.ChainID, queryType ChainSpecificQueryType) bool {
+	if f == nil {
+		return true
+	}
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	_, disabled := f.disabled[chainID][queryType]
+	return !disabled
+}
+
+// ParseDisabledQueryTypes parses a comma separated list of "chain:queryType" entries, such as "bsc:2,polygon:3", and returns
+// a QueryTypeFlags object with those query types disabled. The chain is the chain name and the query type is the numeric
+// query type. An empty string returns an object with everything enabled.
+func ParseDisabledQueryTypes(str string) (*QueryTypeFlags, error) {
+	flags := &QueryTypeFlags{}
+	if str == "" {
+		return flags, nil
+	}
+
+	for _, entry := range strings.Split(str, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`invalid disabled query type "%s", must be "chain:queryType"`, entry)
+		}
+
+		chainID, err := vaa.ChainIDFromString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid chain in disabled query type "%s": %w`, entry, err)
+		}
+
+		qt, err := strconv.ParseUint(fields[1], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid query type in disabled query type "%s": %w`, entry, err)
+		}
+
+		queryType := ChainSpecificQueryType(qt)
+		if err := ValidatePerChainQueryRequestType(queryType); err != nil {
+			return nil, fmt.Errorf(`invalid query type in disabled query type "%s": %w`, entry, err)
+		}
+
+		flags.Disable(chainID, queryType)
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,41 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNilQueryTypeFlagsHasEverythingEnabled(t *testing.T) {
+	var flags *QueryTypeFlags
+	assert.True(t, flags.IsEnabled(vaa.ChainIDBSC, EthCallQueryRequestType))
+}
+
+func TestParseDisabledQueryTypes(t *testing.T) {
+	flags, err := ParseDisabledQueryTypes("bsc:2, polygon:3")
+	require.NoError(t, err)
+
+	assert.False(t, flags.IsEnabled(vaa.ChainIDBSC, EthCallByTimestampQueryRequestType))
+	assert.True(t, flags.IsEnabled(vaa.ChainIDBSC, EthCallQueryRequestType))
+	assert.False(t, flags.IsEnabled(vaa.ChainIDPolygon, EthCallWithFinalityQueryRequestType))
+	assert.True(t, flags.IsEnabled(vaa.ChainIDEthereum, EthCallByTimestampQueryRequestType))
+
+	flags.Enable(vaa.ChainIDBSC, EthCallByTimestampQueryRequestType)
+	assert.True(t, flags.IsEnabled(vaa.ChainIDBSC, EthCallByTimestampQueryRequestType))
+}
+
+func TestParseDisabledQueryTypesEmptyString(t *testing.T) {
+	flags, err := ParseDisabledQueryTypes("")
+	require.NoError(t, err)
+	assert.True(t, flags.IsEnabled(vaa.ChainIDBSC, EthCallQueryRequestType))
+}
+
+func TestParseDisabledQueryTypesInvalidEntries(t *testing.T) {
+	for _, str := range []string{"bsc", "bsc:2:3", "notAChain:2", "bsc:notANumber", "bsc:99"} {
+		_, err := ParseDisabledQueryTypes(str)
+		assert.Error(t, err, str)
+	}
+}
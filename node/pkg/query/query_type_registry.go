@@ -0,0 +1,150 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// QueryTypeHandler describes a chain specific query type. Registering one allows a new query type to be unmarshaled, validated and
+// compared without modifying the core type switches. Per chain queries are dispatched to the watcher for their chain, which must
+// know how to handle the new type.
+type QueryTypeHandler struct {
+	// Name is used in error messages.
+	Name string
+
+	// NewRequest returns an empty request of this type, which is then unmarshaled.
+	NewRequest func() ChainSpecificQuery
+
+	// NewResponse returns an empty response of this type, which is then unmarshaled.
+	NewResponse func() ChainSpecificResponse
+
+	// DigestDomain, if set, is a domain tag that is included in the digest of any request containing this query type, so that a signature
+	// over the request cannot be valid for a request of another type. It may be at most 255 bytes. The built in types do not set it, so the
+	// digests of requests that only use them are unchanged. See QueryRequestDigest.
+	DigestDomain string
+}
+
+var (
+	queryTypeRegistryLock sync.RWMutex
+	queryTypeRegistry     = make(map[ChainSpecificQueryType]*QueryTypeHandler)
+)
+
+func init() {
+	builtIns := map[ChainSpecificQueryType]*QueryTypeHandler{
+		EthCallQueryRequestType: {
+			Name:        "eth call",
+			NewRequest:  func() ChainSpecificQuery { return &EthCallQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthCallQueryResponse{} },
+		},
+		EthCallByTimestampQueryRequestType: {
+			Name:        "eth call by timestamp",
+			NewRequest:  func() ChainSpecificQuery { return &EthCallByTimestampQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthCallByTimestampQueryResponse{} },
+		},
+		EthCallWithFinalityQueryRequestType: {
+			Name:        "eth call with finality",
+			NewRequest:  func() ChainSpecificQuery { return &EthCallWithFinalityQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthCallWithFinalityQueryResponse{} },
+		},
+		EthCallWithPreconditionQueryRequestType: {
+			Name:        "eth call with precondition",
+			NewRequest:  func() ChainSpecificQuery { return &EthCallWithPreconditionQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthCallWithPreconditionQueryResponse{} },
+		},
+		EthCallByTimestampListQueryRequestType: {
+			Name:        "eth call by timestamp list",
+			NewRequest:  func() ChainSpecificQuery { return &EthCallByTimestampListQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthCallByTimestampListQueryResponse{} },
+		},
+		EthTxProofQueryRequestType: {
+			Name:        "eth tx proof",
+			NewRequest:  func() ChainSpecificQuery { return &EthTxProofQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthTxProofQueryResponse{} },
+		},
+		EthStorageDiffQueryRequestType: {
+			Name:        "eth storage diff",
+			NewRequest:  func() ChainSpecificQuery { return &EthStorageDiffQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthStorageDiffQueryResponse{} },
+		},
+		EthBlockProbeQueryRequestType: {
+			Name:        "eth block probe",
+			NewRequest:  func() ChainSpecificQuery { return &EthBlockProbeQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthBlockProbeQueryResponse{} },
+		},
+		EthLogsQueryRequestType: {
+			Name:        "eth logs",
+			NewRequest:  func() ChainSpecificQuery { return &EthLogsQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthLogsQueryResponse{} },
+		},
+		EthStorageQueryRequestType: {
+			Name:        "eth storage",
+			NewRequest:  func() ChainSpecificQuery { return &EthStorageQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &EthStorageQueryResponse{} },
+		},
+		SolanaAccountQueryRequestType: {
+			Name:        "solana account query",
+			NewRequest:  func() ChainSpecificQuery { return &SolanaAccountQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &SolanaAccountQueryResponse{} },
+		},
+		SolanaPdaQueryRequestType: {
+			Name:        "solana PDA query",
+			NewRequest:  func() ChainSpecificQuery { return &SolanaPdaQueryRequest{} },
+			NewResponse: func() ChainSpecificResponse { return &SolanaPdaQueryResponse{} },
+		},
+	}
+
+	for queryType, handler := range builtIns {
+		if err := RegisterQueryType(queryType, handler); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterQueryType adds a query type to the registry. It returns an error if the query type is already registered.
+func RegisterQueryType(queryType ChainSpecificQueryType, handler *QueryTypeHandler) error {
+	if handler == nil || handler.NewRequest == nil || handler.NewResponse == nil {
+		return fmt.Errorf("query type %d must provide a request and response constructor", queryType)
+	}
+	if len(handler.DigestDomain) > math.MaxUint8 {
+		return fmt.Errorf("query type %d digest domain is too long", queryType)
+	}
+
+	queryTypeRegistryLock.Lock()
+	defer queryTypeRegistryLock.Unlock()
+	if _, exists := queryTypeRegistry[queryType]; exists {
+		return fmt.Errorf("query type %d is already registered", queryType)
+	}
+	queryTypeRegistry[queryType] = handler
+	return nil
+}
+
+// lookUpQueryType returns the handler for a query type, or nil if it is not registered.
+func lookUpQueryType(queryType ChainSpecificQueryType) *QueryTypeHandler {
+	queryTypeRegistryLock.RLock()
+	defer queryTypeRegistryLock.RUnlock()
+	return queryTypeRegistry[queryType]
+}
+
+// digestDomain returns the digest domain tag of a query type, or an empty string if it does not have one.
+func digestDomain(queryType ChainSpecificQueryType) string {
+	if handler := lookUpQueryType(queryType); handler != nil {
+		return handler.DigestDomain
+	}
+	return ""
+}
+
+// marshaledEqual compares two chain specific objects by their serialized form. It is used for registered query types that
+// the core Equal methods do not know about. Objects that cannot be marshaled are never equal.
+func marshaledEqual(left interface{ Marshal() ([]byte, error) }, right interface{ Marshal() ([]byte, error) }) bool {
+	leftBytes, err := left.Marshal()
+	if err != nil {
+		return false
+	}
+	rightBytes, err := right.Marshal()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(leftBytes, rightBytes)
+}
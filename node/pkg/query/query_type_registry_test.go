@@ -0,0 +1,206 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCustomQueryRequestType is a query type that is not built in. It is registered by the tests that use it.
+const mockCustomQueryRequestType ChainSpecificQueryType = 200
+
+// mockCustomQuery is a trivial query containing a single value. It is used for both the request and the response.
+type mockCustomQuery struct {
+	Value uint32
+}
+
+func (q *mockCustomQuery) Type() ChainSpecificQueryType {
+	return mockCustomQueryRequestType
+}
+
+func (q *mockCustomQuery) Marshal() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, q.Value)
+	return buf.Bytes(), nil
+}
+
+func (q *mockCustomQuery) Unmarshal(data []byte) error {
+	return q.UnmarshalFromReader(bytes.NewReader(data))
+}
+
+func (q *mockCustomQuery) UnmarshalFromReader(reader *bytes.Reader) error {
+	return binary.Read(reader, binary.BigEndian, &q.Value)
+}
+
+func (q *mockCustomQuery) Validate() error {
+	return nil
+}
+
+var registerMockCustomQueryTypeOnce sync.Once
+
+// registerMockCustomQueryType registers the mock custom query type. The registry is global, so it is only registered once per test run.
+func registerMockCustomQueryType(t *testing.T) {
+	t.Helper()
+	registerMockCustomQueryTypeOnce.Do(func() {
+		require.NoError(t, RegisterQueryType(mockCustomQueryRequestType, &QueryTypeHandler{
+			Name:        "mock custom",
+			NewRequest:  func() ChainSpecificQuery { return &mockCustomQuery{} },
+			NewResponse: func() ChainSpecificResponse { return &mockCustomQuery{} },
+		}))
+	})
+}
+
+func TestBuiltInQueryTypesAreRegistered(t *testing.T) {
+	for _, queryType := range []ChainSpecificQueryType{EthCallQueryRequestType, EthCallByTimestampQueryRequestType, SolanaPdaQueryRequestType} {
+		assert.NotNil(t, lookUpQueryType(queryType), queryType)
+	}
+
+	err := RegisterQueryType(EthCallQueryRequestType, &QueryTypeHandler{
+		Name:        "duplicate",
+		NewRequest:  func() ChainSpecificQuery { return &EthCallQueryRequest{} },
+		NewResponse: func() ChainSpecificResponse { return &EthCallQueryResponse{} },
+	})
+	assert.EqualError(t, err, "query type 1 is already registered")
+}
+
+func TestRegisterQueryTypeRequiresConstructors(t *testing.T) {
+	err := RegisterQueryType(201, &QueryTypeHandler{Name: "incomplete"})
+	assert.EqualError(t, err, "query type 201 must provide a request and response constructor")
+	assert.Error(t, ValidatePerChainQueryRequestType(201))
+}
+
+func TestCustomQueryTypeFlowsThroughHandler(t *testing.T) {
+	registerMockCustomQueryType(t)
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{{ChainId: vaa.ChainIDPolygon, Query: &mockCustomQuery{Value: 42}}}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := []PerChainQueryResponse{{ChainId: vaa.ChainIDPolygon, Response: &mockCustomQuery{Value: 84}}}
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// The response should also survive a round trip through the wire format.
+	respBytes, err := queryResponsePublication.Marshal()
+	require.NoError(t, err)
+	var respPub QueryResponsePublication
+	require.NoError(t, respPub.Unmarshal(respBytes))
+	assert.True(t, queryResponsePublication.Equal(&respPub))
+	assert.Equal(t, uint32(84), respPub.PerChainResponses[0].Response.(*mockCustomQuery).Value)
+}
+
+// mockDomainQuery is the same as mockCustomQuery, except that its type is configurable, so that several query types can share the same layout.
+type mockDomainQuery struct {
+	mockCustomQuery
+	queryType ChainSpecificQueryType
+}
+
+func (q *mockDomainQuery) Type() ChainSpecificQueryType {
+	return q.queryType
+}
+
+const (
+	mockDomainQueryTypeA ChainSpecificQueryType = 202
+	mockDomainQueryTypeB ChainSpecificQueryType = 203
+)
+
+var registerMockDomainQueryTypesOnce sync.Once
+
+// registerMockDomainQueryTypes registers two query types with the same layout but different digest domains.
+func registerMockDomainQueryTypes(t *testing.T) {
+	t.Helper()
+	registerMockDomainQueryTypesOnce.Do(func() {
+		for queryType, domain := range map[ChainSpecificQueryType]string{mockDomainQueryTypeA: "mock_domain_a", mockDomainQueryTypeB: "mock_domain_b"} {
+			queryType := queryType
+			require.NoError(t, RegisterQueryType(queryType, &QueryTypeHandler{
+				Name:         domain,
+				NewRequest:   func() ChainSpecificQuery { return &mockDomainQuery{queryType: queryType} },
+				NewResponse:  func() ChainSpecificResponse { return &mockDomainQuery{queryType: queryType} },
+				DigestDomain: domain,
+			}))
+		}
+	})
+}
+
+func TestDigestDomainSeparatesQueryTypes(t *testing.T) {
+	registerMockDomainQueryTypes(t)
+
+	marshalRequest := func(queryType ChainSpecificQueryType) []byte {
+		t.Helper()
+		queryRequest := &QueryRequest{
+			Nonce:           1,
+			PerChainQueries: []*PerChainQueryRequest{{ChainId: vaa.ChainIDPolygon, Query: &mockDomainQuery{mockCustomQuery{Value: 42}, queryType}}},
+		}
+		bytes, err := queryRequest.Marshal()
+		require.NoError(t, err)
+		return bytes
+	}
+
+	// The two requests are identical except for the query type.
+	requestA := marshalRequest(mockDomainQueryTypeA)
+	requestB := marshalRequest(mockDomainQueryTypeB)
+	digestA := QueryRequestDigest(common.GoTest, requestA)
+	digestB := QueryRequestDigest(common.GoTest, requestB)
+	assert.NotEqual(t, digestA, digestB)
+
+	// The digests should include the domain tags.
+	prefix := []byte("devnet_query_request_0000000000000|query_type_domains|")
+	expectedA := ethCrypto.Keccak256Hash(append(append(prefix, append([]byte{1, byte(mockDomainQueryTypeA), 13}, "mock_domain_a"...)...), requestA...))
+	assert.Equal(t, expectedA, digestA)
+
+	// A signature over one request should not verify as the same signer over the other.
+	sk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	signer := ethCrypto.PubkeyToAddress(sk.PublicKey)
+	sig, err := ethCrypto.Sign(digestA.Bytes(), sk)
+	require.NoError(t, err)
+
+	pubKey, err := ethCrypto.SigToPub(digestA.Bytes(), sig)
+	require.NoError(t, err)
+	assert.Equal(t, signer, ethCrypto.PubkeyToAddress(*pubKey))
+
+	pubKey, err = ethCrypto.SigToPub(digestB.Bytes(), sig)
+	require.NoError(t, err)
+	assert.NotEqual(t, signer, ethCrypto.PubkeyToAddress(*pubKey))
+}
+
+func TestDigestIsUnchangedForQueryTypesWithoutDomains(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	expected := ethCrypto.Keccak256Hash(append([]byte("mainnet_query_request_000000000000|"), queryRequestBytes...))
+	assert.Equal(t, expected, QueryRequestDigest(common.MainNet, queryRequestBytes))
+
+	// A request that cannot be parsed still gets a digest, so it can be rejected later on.
+	assert.Equal(t, ethCrypto.Keccak256Hash([]byte("mainnet_query_request_000000000000|junk")), QueryRequestDigest(common.MainNet, []byte("junk")))
+}
+
+func TestRegisterQueryTypeRejectsLongDigestDomain(t *testing.T) {
+	err := RegisterQueryType(204, &QueryTypeHandler{
+		Name:         "long domain",
+		NewRequest:   func() ChainSpecificQuery { return &mockCustomQuery{} },
+		NewResponse:  func() ChainSpecificResponse { return &mockCustomQuery{} },
+		DigestDomain: string(make([]byte, 256)),
+	})
+	assert.EqualError(t, err, "query type 204 digest domain is too long")
+}
@@ -0,0 +1,66 @@
+package query
+
+import (
+	"container/list"
+	"time"
+)
+
+// replayCache remembers the IDs of the requests accepted recently, so that a signed request captured off gossip can not be replayed once the
+// original has been answered. Since the request ID is derived from the signature and the digest, only a resubmission of the exact same signed
+// request is caught. Different requests that happen to share a signer and a nonce, like those the proxy signs on behalf of its clients, are
+// not affected. It has a hard size cap, beyond which the oldest entries are evicted, so a burst of requests can never lock out new ones. It is
+// only accessed from the query handler routine.
+type replayCache struct {
+	maxSize int
+	window  time.Duration
+	entries map[string]*list.Element
+
+	// lru holds a *replayEntry for each recently accepted request, the most recent at the front.
+	lru *list.List
+}
+
+// replayEntry is a recently accepted request, and when it was accepted.
+type replayEntry struct {
+	requestID string
+	lastSeen  time.Time
+}
+
+// newReplayCache creates a replay cache that holds at most maxSize entries, each for at most the window.
+func newReplayCache(maxSize int, window time.Duration) *replayCache {
+	return &replayCache{
+		maxSize: maxSize,
+		window:  window,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// replayed returns true if a request with the same ID was accepted within the window.
+func (rc *replayCache) replayed(requestID string, now time.Time) bool {
+	elem, exists := rc.entries[requestID]
+	if !exists {
+		return false
+	}
+	return elem.Value.(*replayEntry).lastSeen.After(now.Add(-rc.window))
+}
+
+// record adds the ID of an accepted request, making it the most recent. It drops the entries that are outside the window, and then, if the
+// cache is still full, the oldest ones.
+func (rc *replayCache) record(requestID string, now time.Time) {
+	if elem, exists := rc.entries[requestID]; exists {
+		elem.Value.(*replayEntry).lastSeen = now
+		rc.lru.MoveToFront(elem)
+		return
+	}
+
+	cutoff := now.Add(-rc.window)
+	for elem := rc.lru.Back(); elem != nil; elem = rc.lru.Back() {
+		if len(rc.entries) < rc.maxSize && elem.Value.(*replayEntry).lastSeen.After(cutoff) {
+			break
+		}
+		rc.lru.Remove(elem)
+		delete(rc.entries, elem.Value.(*replayEntry).requestID)
+	}
+
+	rc.entries[requestID] = rc.lru.PushFront(&replayEntry{requestID: requestID, lastSeen: now})
+}
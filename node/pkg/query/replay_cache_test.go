@@ -0,0 +1,124 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+)
+
+func TestReplayCacheEvictsTheOldestEntryWhenFull(t *testing.T) {
+	rc := newReplayCache(3, time.Hour)
+	now := time.Now()
+
+	for idx, requestID := range []string{"req1", "req2", "req3"} {
+		rc.record(requestID, now.Add(time.Duration(idx)*time.Second))
+	}
+	assert.True(t, rc.replayed("req2", now.Add(5*time.Second)))
+	assert.False(t, rc.replayed("req4", now.Add(5*time.Second)))
+
+	// Adding a fourth evicts the oldest, even though it is still within the window, rather than rejecting the new one.
+	rc.record("req4", now.Add(5*time.Second))
+	assert.Equal(t, 3, len(rc.entries))
+	assert.False(t, rc.replayed("req1", now.Add(5*time.Second)))
+	for _, requestID := range []string{"req2", "req3", "req4"} {
+		assert.True(t, rc.replayed(requestID, now.Add(5*time.Second)))
+	}
+}
+
+func TestReplayCacheForgetsEntriesOutsideTheWindow(t *testing.T) {
+	rc := newReplayCache(10, time.Minute)
+	now := time.Now()
+
+	rc.record("req1", now)
+	rc.record("req2", now.Add(30*time.Second))
+	assert.True(t, rc.replayed("req1", now.Add(59*time.Second)))
+	assert.False(t, rc.replayed("req1", now.Add(time.Minute)))
+
+	// Expired entries are dropped as new ones are recorded.
+	rc.record("req3", now.Add(time.Minute+time.Second))
+	assert.Equal(t, 2, len(rc.entries))
+	assert.NotContains(t, rc.entries, "req1")
+}
+
+func TestReplayedRequestIsRejectedByDefault(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	// Once the original has been answered, resubmitting the exact same signed request must not produce another response, even though
+	// monotonic nonces are not enforced.
+	md.resetState()
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, ReplayedRequest, failure.Reason)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestDifferentRequestsWithTheSameSignerAndNonceAreNotReplays(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTest(t, ctx, logger, watcherChainsForTest)
+
+	// Like the proxy signing the requests of two clients that both use the same nonce.
+	for _, block := range []string{"0x28d9630", "0x28d9631"} {
+		md.resetState()
+		perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, block, 2)}
+		signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+		expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+		md.setExpectedResults(expectedResults)
+		md.signedQueryReqWriteC <- signedQueryRequest
+
+		queryResponsePublication := md.waitForResponse()
+		require.NotNil(t, queryResponsePublication)
+		assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	}
+}
+
+func TestRejectedRequestMayBeRetriedAsIs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	// A backlog of ten queries means a new Polygon query can not meet the fast tier. See TestTightSlaFailsFastUnderBacklogWhileLooseSlaSucceeds.
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{SlaQueryTimeEstimate: time.Second, EnforceMonotonicNonce: true})
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+	for count := 0; count < 10; count++ {
+		nonce += 1
+		md.signedQueryReqWriteC <- signQueryRequestForTesting(t, md.sk, &QueryRequest{
+			Nonce:           nonce,
+			PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)},
+		})
+	}
+
+	nonce += 1
+	fastRequest := signQueryRequestForTesting(t, md.sk, &QueryRequest{
+		Nonce:           nonce,
+		SlaTier:         SlaTierFast,
+		PerChainQueries: []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)},
+	})
+	md.signedQueryReqWriteC <- fastRequest
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, SlaCannotBeMet, failure.Reason)
+
+	// The rejected request was never accepted, so neither its request ID nor its nonce were used up.
+	md.signedQueryReqWriteC <- fastRequest
+	require.Eventually(t, func() bool { return len(md.getFailures()) == 2 }, requestTimeoutForTest, pollIntervalForTest)
+	assert.Equal(t, SlaCannotBeMet, md.getFailures()[1].Reason)
+}
@@ -2,9 +2,12 @@ package query
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 
 	"github.com/certusone/wormhole/node/pkg/common"
@@ -20,10 +23,101 @@ import (
 // MSG_VERSION is the current version of the CCQ message protocol.
 const MSG_VERSION uint8 = 1
 
+// MSG_VERSION_WITH_OPTIONS is the version of the CCQ request format that carries a list of request options after the nonce.
+// Requests are only marshaled with this version when at least one option is set, so that all other requests remain readable by older parsers.
+const MSG_VERSION_WITH_OPTIONS uint8 = 2
+
+// RequestOptionType identifies an option in a version 2 query request. Each option has a single byte value, which may not be zero.
+// Options must be listed in increasing order of type, and each type may only appear once, so that each request has only one valid encoding.
+type RequestOptionType uint8
+
+const (
+	// RetryBudgetOption carries QueryRequest.RetryBudget.
+	RetryBudgetOption RequestOptionType = 1
+
+	// ResultNormalizationOption carries QueryRequest.ResultNormalization.
+	ResultNormalizationOption RequestOptionType = 2
+
+	// ResponseSchemaVersionOption carries QueryRequest.ResponseSchemaVersion.
+	ResponseSchemaVersionOption RequestOptionType = 3
+
+	// AllowCachedResultsOption carries QueryRequest.AllowCachedResults. The only valid value is one.
+	AllowCachedResultsOption RequestOptionType = 4
+
+	// MaxBlockAgeOption indicates that the per chain queries are followed by the PerChainQueryRequest.MaxBlockAge of each of them.
+	// The only valid value is one.
+	MaxBlockAgeOption RequestOptionType = 5
+
+	// SlaTierOption carries QueryRequest.SlaTier.
+	SlaTierOption RequestOptionType = 6
+
+	// EnvironmentOption carries QueryRequest.Environment.
+	EnvironmentOption RequestOptionType = 7
+
+	// HashAlgorithmOption carries QueryRequest.HashAlgorithm.
+	HashAlgorithmOption RequestOptionType = 8
+
+	// AllowRevertedCallsOption carries QueryRequest.AllowRevertedCalls. The only valid value is one.
+	AllowRevertedCallsOption RequestOptionType = 9
+
+	// AllowPartialResultsOption carries QueryRequest.AllowPartialResults. The only valid value is one.
+	AllowPartialResultsOption RequestOptionType = 10
+
+	// ResultFiltersOption indicates that the per chain queries, and their max block ages if present, are followed by the
+	// PerChainQueryRequest.ResultFilter of each of them. The only valid value is one.
+	ResultFiltersOption RequestOptionType = 11
+
+	// PreferSpeedOption carries QueryRequest.PreferSpeed. The only valid value is one.
+	PreferSpeedOption RequestOptionType = 12
+)
+
+// DecimalBlockIdPrefix may be used in place of 0x to give a block number in decimal, for example "d:42000000". The watchers convert
+// such a block id to hex before using it. Block hashes must always be given in hex.
+const DecimalBlockIdPrefix = "d:"
+
 // QueryRequest defines a cross chain query request to be submitted to the guardians.
 // It is the payload of the SignedQueryRequest gossip message.
 type QueryRequest struct {
-	Nonce           uint32
+	Nonce uint32
+
+	// RetryBudget is the number of times the guardian should retry each per chain query before giving up. Zero means use the guardian's default.
+	// The guardian clamps it to its configured maximum.
+	RetryBudget uint8
+
+	// ResultNormalization specifies how the guardian normalizes the EVM call results in the response. Zero means the results are not normalized.
+	ResultNormalization ResultNormalization
+
+	// ResponseSchemaVersion pins the layout of the response. Zero means the original layout. Requests for an unsupported version are rejected.
+	ResponseSchemaVersion ResponseSchemaVersion
+
+	// AllowCachedResults allows the guardian to serve a recent cached result for a per chain query if its watcher returns a fatal error,
+	// rather than failing the request. This only has an effect if the guardian has result caching enabled.
+	AllowCachedResults bool
+
+	// SlaTier is the latency the requester asks the guardian to meet. Zero means best effort. Requests for an unsupported tier are rejected.
+	SlaTier SlaTier
+
+	// Environment declares the environment the request is intended for. Zero means it is not declared. A guardian that enforces it rejects
+	// requests for a different environment with WrongEnvironment, rather than treating them as coming from an unknown signer.
+	Environment RequestEnvironment
+
+	// HashAlgorithm selects the hash function used for any hashing done while assembling the response, such as its Merkle root. Zero means
+	// keccak256. Requests for an unsupported algorithm are rejected.
+	HashAlgorithm HashAlgorithm
+
+	// AllowRevertedCalls allows individual calls in an eth_call query to revert without failing the per chain query. The response then carries
+	// a status for each call, and the result of a reverted call is its revert data.
+	AllowRevertedCalls bool
+
+	// AllowPartialResults allows the guardian to publish a response when some of the per chain queries fail, rather than dropping the request.
+	// The response then carries a status for each per chain query, and only contains the responses of the ones that succeeded.
+	AllowPartialResults bool
+
+	// PreferSpeed tells the guardian that the requester accepts a possibly inconsistent answer in exchange for lower latency. The watchers then
+	// skip the checks that ensure that the results were all read from the same block and that the block was not reorged out while it was read,
+	// rather than retrying when they fail. Since it is part of the signed request, it is also flagged in the response.
+	PreferSpeed bool
+
 	PerChainQueries []*PerChainQueryRequest
 }
 
@@ -34,6 +128,14 @@ type PerChainQueryRequest struct {
 
 	// Query is the chain specific query data.
 	Query ChainSpecificQuery
+
+	// MaxBlockAge is the maximum age, in seconds, of the block used to answer the query. If the watcher answers using an older block,
+	// the guardian retries until it gets a fresh enough one or the request times out. Zero means there is no limit.
+	MaxBlockAge uint32
+
+	// ResultFilter, if set, is applied by the guardian to the logs returned by an eth_logs query before the response is signed, so only the logs
+	// that match it are returned. It is not supported on other query types. See parseResultFilter for the syntax, such as "topic2 == 0x42".
+	ResultFilter string
 }
 
 // ChainSpecificQuery is the interface that must be implemented by a chain specific query.
@@ -54,8 +156,14 @@ const EthCallQueryRequestType ChainSpecificQueryType = 1
 // EthCallQueryRequest implements ChainSpecificQuery for an EVM eth_call query request.
 type EthCallQueryRequest struct {
 	// BlockId identifies the block to be queried. It must be a hex string starting with 0x. It may be a block number or a block hash.
+	// A block number may also be given in decimal, prefixed with d: (see DecimalBlockIdPrefix). It must be empty if Finality is set.
 	BlockId string
 
+	// Finality is optional. If it is set, the watcher resolves the block tag to the block it currently refers to, and performs the calls on that
+	// block. Valid values are "latest", "safe" and "finalized". Since guardians may resolve the tag to different blocks, their responses may not
+	// match. On the wire, it follows an empty block id.
+	Finality string
+
 	// CallData is an array of specific queries to be performed on the specified block, in a single RPC call.
 	CallData []*EthCallData
 }
@@ -92,6 +200,7 @@ const EthCallWithFinalityQueryRequestType ChainSpecificQueryType = 3
 // EthCallWithFinalityQueryRequest implements ChainSpecificQuery for an EVM eth_call_with_finality query request.
 type EthCallWithFinalityQueryRequest struct {
 	// BlockId identifies the block to be queried. It must be a hex string starting with 0x. It may be a block number or a block hash.
+	// A block number may also be given in decimal, prefixed with d: (see DecimalBlockIdPrefix).
 	BlockId string
 
 	// Finality is required. It identifies the level of finality the block must reach before the query is performed. Valid values are "finalized" and "safe".
@@ -116,6 +225,137 @@ type EthCallData struct {
 
 const EvmContractAddressLength = 20
 
+// EthCallWithPreconditionQueryRequestType is the type of an EVM eth_call_with_precondition query request.
+const EthCallWithPreconditionQueryRequestType ChainSpecificQueryType = 6
+
+// EthCallWithPreconditionQueryRequest implements ChainSpecificQuery for an EVM eth_call_with_precondition query request.
+// The first call is the precondition. The remaining calls are only executed if the result of the precondition call matches ExpectedResult.
+// Otherwise, they are returned with a status of PreconditionFailed.
+type EthCallWithPreconditionQueryRequest struct {
+	// BlockId identifies the block to be queried. It must be a hex string starting with 0x. It may be a block number or a block hash.
+	// A block number may also be given in decimal, prefixed with d: (see DecimalBlockIdPrefix).
+	BlockId string
+
+	// ExpectedResult is the value the precondition call must return for the remaining calls to be executed.
+	ExpectedResult []byte
+
+	// CallData is an array of specific queries to be performed on the specified block. The first entry is the precondition call.
+	CallData []*EthCallData
+}
+
+func (ecr *EthCallWithPreconditionQueryRequest) CallDataList() []*EthCallData {
+	return ecr.CallData
+}
+
+// EthCallByTimestampListQueryRequestType is the type of an EVM eth_call_by_timestamp_list query request.
+const EthCallByTimestampListQueryRequestType ChainSpecificQueryType = 7
+
+// EthCallByTimestampListMaxTimestamps is the maximum number of timestamps allowed in an eth_call_by_timestamp_list query request.
+const EthCallByTimestampListMaxTimestamps = 10
+
+// EthCallByTimestampListQueryRequest implements ChainSpecificQuery for an EVM eth_call_by_timestamp_list query request.
+// It is like eth_call_by_timestamp, except that the same calls are resolved at each of several timestamps. The blocks are always
+// looked up by the watcher, so there are no block id hints.
+type EthCallByTimestampListQueryRequest struct {
+	// TargetTimestamps specifies the desired timestamps in microseconds. They must be in strictly increasing order.
+	TargetTimestamps []uint64
+
+	// CallData is an array of specific queries to be performed at each of the timestamps.
+	CallData []*EthCallData
+}
+
+func (ecr *EthCallByTimestampListQueryRequest) CallDataList() []*EthCallData {
+	return ecr.CallData
+}
+
+// EthTxProofQueryRequestType is the type of an EVM eth_tx_proof query request.
+const EthTxProofQueryRequestType ChainSpecificQueryType = 8
+
+// EthTxProofQueryRequest implements ChainSpecificQuery for an EVM eth_tx_proof query request. It returns a Merkle-Patricia proof that
+// the transaction is included in the transactions trie of the block that contains it.
+type EthTxProofQueryRequest struct {
+	// TxHash is the hash of the transaction to be proven.
+	TxHash ethCommon.Hash
+}
+
+// EthStorageDiffQueryRequestType is the type of an EVM eth_storage_diff query request.
+const EthStorageDiffQueryRequestType ChainSpecificQueryType = 9
+
+// EthStorageDiffQueryRequest implements ChainSpecificQuery for an EVM eth_storage_diff query request. It reads a set of storage slots of a
+// contract at two blocks, and reports the value of each one at both blocks, and whether it changed.
+type EthStorageDiffQueryRequest struct {
+	// FromBlockId and ToBlockId identify the blocks to be compared. Each must be a hex string starting with 0x. It may be a block number or a block hash.
+	// A block number may also be given in decimal, prefixed with d: (see DecimalBlockIdPrefix).
+	FromBlockId string
+	ToBlockId   string
+
+	// Contract is the address of the contract whose storage is read.
+	Contract []byte
+
+	// Slots are the storage slots to be compared.
+	Slots []ethCommon.Hash
+}
+
+// EthBlockProbeQueryRequestType is the type of an EVM eth_block_probe query request.
+const EthBlockProbeQueryRequestType ChainSpecificQueryType = 10
+
+// EthBlockProbeQueryRequest implements ChainSpecificQuery for an EVM eth_block_probe query request. It is a lightweight liveness probe that
+// resolves the current block and returns its header, without executing any call.
+type EthBlockProbeQueryRequest struct {
+	// BlockTag identifies the block to be resolved. Valid values are "latest", "safe" and "finalized".
+	BlockTag string
+}
+
+// EthLogsQueryRequestType is the type of an EVM eth_logs query request.
+const EthLogsQueryRequestType ChainSpecificQueryType = 11
+
+// MaxEthLogsBlockRange is the maximum number of blocks an eth_logs query may span. Watchers fail queries over a larger range with QueryFatalError.
+const MaxEthLogsBlockRange = 1000
+
+// MaxEthLogsTopics is the maximum number of topics an EVM log can have, and so the maximum number of topic filters in an eth_logs query.
+const MaxEthLogsTopics = 4
+
+// EthLogsQueryRequest implements ChainSpecificQuery for an EVM eth_logs query request. It reads the logs emitted by a contract over a range of blocks.
+type EthLogsQueryRequest struct {
+	// FromBlock and ToBlock are the first and last blocks of the range, inclusive. Each must be a block number as a hex string starting with 0x,
+	// or in decimal prefixed with d: (see DecimalBlockIdPrefix). Block hashes and tags are not allowed, so the range is the same on every guardian.
+	FromBlock string
+	ToBlock   string
+
+	// Address is the address of the contract whose logs are read.
+	Address []byte
+
+	// Topics filters the logs by topic. Entry i is either empty, matching any value of the i'th topic, or the 32 byte value it must be equal to.
+	Topics [][]byte
+
+	// MaxWidenedSpan, if non-zero, asks the guardians to widen the range once if it contains no matching logs. The range is widened backwards,
+	// so that it spans MaxWidenedSpan blocks ending at ToBlock, and is read again. The response reports the range that was actually read.
+	// It must be larger than the number of blocks in the range, and at most MaxEthLogsBlockRange.
+	MaxWidenedSpan uint32
+
+	// MinResults, if non-zero, is the minimum number of logs the requester expects, which catches a node that is behind. The logs are counted
+	// before any result filter is applied. If fewer are found, the query is retried, or failed if FailBelowMinResults is set.
+	MinResults          uint32
+	FailBelowMinResults bool
+}
+
+// EthStorageQueryRequestType is the type of an EVM eth_storage query request.
+const EthStorageQueryRequestType ChainSpecificQueryType = 12
+
+// EthStorageQueryRequest implements ChainSpecificQuery for an EVM eth_storage query request. It reads a set of storage slots of a contract at
+// a single block, without calling the contract.
+type EthStorageQueryRequest struct {
+	// BlockId identifies the block to be queried. It must be a hex string starting with 0x. It may be a block number or a block hash.
+	// A block number may also be given in decimal, prefixed with d: (see DecimalBlockIdPrefix).
+	BlockId string
+
+	// Contract is the address of the contract whose storage is read.
+	Contract []byte
+
+	// Slots are the storage slots to be read.
+	Slots []ethCommon.Hash
+}
+
 ////////////////////////////////// Solana Queries ////////////////////////////////////////////////
 
 // SolanaAccountQueryRequestType is the type of a Solana sol_account query request.
@@ -204,24 +444,155 @@ type PerChainQueryInternal struct {
 	RequestID  string
 	RequestIdx int
 	Request    *PerChainQueryRequest
+
+	// AllowRevertedCalls is copied from the query request, so the watcher can return reverted calls rather than failing the query.
+	AllowRevertedCalls bool
+
+	// PreferSpeed is copied from the query request, so the watcher can skip its consistency checks.
+	PreferSpeed bool
+
+	// CorrelationID is the short ID the query handler logs as requestId while processing the request. The watchers log it along with ID, so
+	// their log lines can be tied to the ones of the handler.
+	CorrelationID string
+
+	// ctx is cancelled by the query handler once the request can no longer succeed. It is nil if the handler is not configured to cancel requests.
+	ctx context.Context
+}
+
+// validBlockIdForm returns true if the block id starts with 0x, or is a decimal block number starting with DecimalBlockIdPrefix.
+func validBlockIdForm(blockId string) bool {
+	if strings.HasPrefix(blockId, "0x") {
+		return true
+	}
+	_, ok := parseDecimalBlockId(blockId)
+	return ok
+}
+
+// parseDecimalBlockId returns the block number of a block id starting with DecimalBlockIdPrefix, and false if it is not a valid decimal block id.
+func parseDecimalBlockId(blockId string) (uint64, bool) {
+	digits, found := strings.CutPrefix(blockId, DecimalBlockIdPrefix)
+	if !found {
+		return 0, false
+	}
+	blockNum, err := strconv.ParseUint(digits, 10, 64)
+	return blockNum, err == nil
+}
+
+// ParseBlockNumber returns the block number of a block id that is a hex number starting with 0x, or a decimal number starting with
+// DecimalBlockIdPrefix. It returns an error for any other block id, including block hashes.
+func ParseBlockNumber(blockId string) (uint64, error) {
+	if blockNum, ok := parseDecimalBlockId(blockId); ok {
+		return blockNum, nil
+	}
+	digits, found := strings.CutPrefix(blockId, "0x")
+	if !found || len(digits) == 0 || len(digits) > 16 {
+		return 0, fmt.Errorf("block id must be a hex number starting with 0x, or a decimal number starting with d:")
+	}
+	blockNum, err := strconv.ParseUint(digits, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("block id must be a hex number starting with 0x, or a decimal number starting with d:")
+	}
+	return blockNum, nil
+}
+
+// NormalizeBlockId converts a decimal block id to the equivalent hex block id. Any other block id is returned unchanged.
+func NormalizeBlockId(blockId string) string {
+	if blockNum, ok := parseDecimalBlockId(blockId); ok {
+		return fmt.Sprintf("0x%x", blockNum)
+	}
+	return blockId
 }
 
 func (pcqi *PerChainQueryInternal) ID() string {
 	return fmt.Sprintf("%s:%d", pcqi.RequestID, pcqi.RequestIdx)
 }
 
-// QueryRequestDigest returns the query signing prefix based on the environment.
+// Context returns a context derived from the parent that is also cancelled if the query handler gives up on the request, so that the watcher
+// can stop working on it. The returned cancel function must be called once the watcher is done with the request.
+func (pcqi *PerChainQueryInternal) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if pcqi.ctx == nil {
+		return ctx, cancel
+	}
+
+	stop := context.AfterFunc(pcqi.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// QueryFingerprint returns a stable fingerprint of a query request for the specified environment. It is the digest that the requester signs,
+// and the handler combines it with the signature to detect duplicate requests, so requesters can also use it as a local cache key. Requests that
+// only differ in their nonce have different fingerprints.
+func QueryFingerprint(env common.Environment, queryRequest *QueryRequest) (ethCommon.Hash, error) {
+	b, err := queryRequest.Marshal()
+	if err != nil {
+		return ethCommon.Hash{}, fmt.Errorf("failed to marshal query request: %w", err)
+	}
+	return QueryRequestDigest(env, b), nil
+}
+
+// queryRequestID returns the key the handler uses to track a request and detect duplicates. It's possible that the signature alone is not unique,
+// and the fingerprint alone is not unique, but the combination should be.
+func queryRequestID(signature []byte, fingerprint ethCommon.Hash) string {
+	return hex.EncodeToString(signature) + ":" + fingerprint.String()
+}
+
+// queryCorrelationID returns the short ID used to tie together the log lines emitted while processing a request. It is the first eight bytes of
+// the hash of the request ID, hex encoded. Unlike a prefix of the digest, it differs between signers of the same request.
+func queryCorrelationID(requestID string) string {
+	return hex.EncodeToString(ethCrypto.Keccak256([]byte(requestID))[:8])
+}
+
+// queryTypeDomainsPrefix separates the query type domain tags from the request in the digest.
+var queryTypeDomainsPrefix = []byte("query_type_domains|")
+
+// QueryRequestDigest returns the digest of a marshaled query request, using the signing prefix based on the environment. If any of the per chain
+// queries use a query type with a digest domain, the domain tags of all of the per chain queries are also included, so the signature is bound to
+// the query types. Otherwise, including when the request cannot be parsed, the digest is just the prefix and the request.
 func QueryRequestDigest(env common.Environment, b []byte) ethCommon.Hash {
-	var queryRequestPrefix []byte
+	prefix := queryRequestPrefix(env)
+	if domains := queryTypeDomains(b); domains != nil {
+		prefix = append(append(prefix, queryTypeDomainsPrefix...), domains...)
+	}
+
+	return ethCrypto.Keccak256Hash(append(prefix, b...))
+}
+
+// queryRequestPrefix returns the signing prefix of a query request for the environment.
+func queryRequestPrefix(env common.Environment) []byte {
 	if env == common.MainNet {
-		queryRequestPrefix = []byte("mainnet_query_request_000000000000|")
+		return []byte("mainnet_query_request_000000000000|")
 	} else if env == common.TestNet {
-		queryRequestPrefix = []byte("testnet_query_request_000000000000|")
-	} else {
-		queryRequestPrefix = []byte("devnet_query_request_0000000000000|")
+		return []byte("testnet_query_request_000000000000|")
+	}
+	return []byte("devnet_query_request_0000000000000|")
+}
+
+// queryTypeDomains returns the domain tags of the per chain queries in a marshaled query request, or nil if none of them have one.
+// For each per chain query, it contains the query type, followed by the length of the tag and the tag itself.
+func queryTypeDomains(b []byte) []byte {
+	var queryRequest QueryRequest
+	if err := queryRequest.Unmarshal(b); err != nil {
+		return nil
+	}
+
+	found := false
+	buf := []byte{uint8(len(queryRequest.PerChainQueries))}
+	for _, perChainQuery := range queryRequest.PerChainQueries {
+		domain := digestDomain(perChainQuery.Query.Type())
+		if domain != "" {
+			found = true
+		}
+		buf = append(buf, uint8(perChainQuery.Query.Type()), uint8(len(domain)))
+		buf = append(buf, domain...)
 	}
 
-	return ethCrypto.Keccak256Hash(append(queryRequestPrefix, b...))
+	if !found {
+		return nil
+	}
+	return buf
 }
 
 // PostSignedQueryRequest posts a signed query request to the specified channel.
@@ -257,8 +628,19 @@ func (queryRequest *QueryRequest) Marshal() ([]byte, error) {
 
 	buf := new(bytes.Buffer)
 
-	vaa.MustWrite(buf, binary.BigEndian, MSG_VERSION)        // version
-	vaa.MustWrite(buf, binary.BigEndian, queryRequest.Nonce) // uint32
+	options := queryRequest.options()
+	if len(options) == 0 {
+		vaa.MustWrite(buf, binary.BigEndian, MSG_VERSION)        // version
+		vaa.MustWrite(buf, binary.BigEndian, queryRequest.Nonce) // uint32
+	} else {
+		vaa.MustWrite(buf, binary.BigEndian, MSG_VERSION_WITH_OPTIONS) // version
+		vaa.MustWrite(buf, binary.BigEndian, queryRequest.Nonce)       // uint32
+		vaa.MustWrite(buf, binary.BigEndian, uint8(len(options)))
+		for _, option := range options {
+			vaa.MustWrite(buf, binary.BigEndian, option.optionType)
+			vaa.MustWrite(buf, binary.BigEndian, option.value)
+		}
+	}
 
 	vaa.MustWrite(buf, binary.BigEndian, uint8(len(queryRequest.PerChainQueries)))
 	for _, perChainQuery := range queryRequest.PerChainQueries {
@@ -269,6 +651,19 @@ func (queryRequest *QueryRequest) Marshal() ([]byte, error) {
 		buf.Write(pcqBuf)
 	}
 
+	if queryRequest.hasMaxBlockAge() {
+		for _, perChainQuery := range queryRequest.PerChainQueries {
+			vaa.MustWrite(buf, binary.BigEndian, perChainQuery.MaxBlockAge)
+		}
+	}
+
+	if queryRequest.hasResultFilters() {
+		for _, perChainQuery := range queryRequest.PerChainQueries {
+			vaa.MustWrite(buf, binary.BigEndian, uint8(len(perChainQuery.ResultFilter)))
+			buf.Write([]byte(perChainQuery.ResultFilter))
+		}
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -285,7 +680,7 @@ func (queryRequest *QueryRequest) UnmarshalFromReader(reader *bytes.Reader) erro
 		return fmt.Errorf("failed to read message version: %w", err)
 	}
 
-	if version != MSG_VERSION {
+	if version != MSG_VERSION && version != MSG_VERSION_WITH_OPTIONS {
 		return fmt.Errorf("unsupported message version: %d", version)
 	}
 
@@ -293,6 +688,14 @@ func (queryRequest *QueryRequest) UnmarshalFromReader(reader *bytes.Reader) erro
 		return fmt.Errorf("failed to read request nonce: %w", err)
 	}
 
+	hasMaxBlockAge, hasResultFilters := false, false
+	if version == MSG_VERSION_WITH_OPTIONS {
+		var err error
+		if hasMaxBlockAge, hasResultFilters, err = queryRequest.unmarshalOptions(reader); err != nil {
+			return err
+		}
+	}
+
 	numPerChainQueries := uint8(0)
 	if err := binary.Read(reader, binary.BigEndian, &numPerChainQueries); err != nil {
 		return fmt.Errorf("failed to read number of per chain queries: %w", err)
@@ -307,6 +710,41 @@ func (queryRequest *QueryRequest) UnmarshalFromReader(reader *bytes.Reader) erro
 		queryRequest.PerChainQueries = append(queryRequest.PerChainQueries, &perChainQuery)
 	}
 
+	if hasMaxBlockAge {
+		for _, perChainQuery := range queryRequest.PerChainQueries {
+			if err := binary.Read(reader, binary.BigEndian, &perChainQuery.MaxBlockAge); err != nil {
+				return fmt.Errorf("failed to read max block age: %w", err)
+			}
+		}
+
+		// The option must only be present if at least one per chain query has a max block age, so that each request has only one valid encoding.
+		if !queryRequest.hasMaxBlockAge() {
+			return fmt.Errorf("max block age option is set but no per chain query has a max block age")
+		}
+	}
+
+	if hasResultFilters {
+		for _, perChainQuery := range queryRequest.PerChainQueries {
+			filterLen := uint8(0)
+			if err := binary.Read(reader, binary.BigEndian, &filterLen); err != nil {
+				return fmt.Errorf("failed to read result filter len: %w", err)
+			}
+			if filterLen == 0 {
+				continue
+			}
+			filter := make([]byte, filterLen)
+			if n, err := reader.Read(filter[:]); err != nil || n != int(filterLen) {
+				return fmt.Errorf("failed to read result filter [%d]: %w", n, err)
+			}
+			perChainQuery.ResultFilter = string(filter)
+		}
+
+		// As with the max block age, the option must only be present if at least one per chain query has a result filter.
+		if !queryRequest.hasResultFilters() {
+			return fmt.Errorf("result filters option is set but no per chain query has a result filter")
+		}
+	}
+
 	if reader.Len() != 0 {
 		return fmt.Errorf("excess bytes in unmarshal")
 	}
@@ -318,6 +756,180 @@ func (queryRequest *QueryRequest) UnmarshalFromReader(reader *bytes.Reader) erro
 	return nil
 }
 
+// TotalCalls returns the number of EVM calls performed by all of the per chain queries in the request. A by timestamp list query
+// performs its calls once for each timestamp. Solana queries do not contain any calls.
+func (queryRequest *QueryRequest) TotalCalls() int {
+	total := 0
+	for _, perChainQuery := range queryRequest.PerChainQueries {
+		total += perChainQuery.NumCalls()
+	}
+	return total
+}
+
+// NumCalls returns the number of EVM calls performed by the per chain query. See QueryRequest.TotalCalls.
+func (perChainQuery *PerChainQueryRequest) NumCalls() int {
+	q, ok := perChainQuery.Query.(interface{ CallDataList() []*EthCallData })
+	if !ok {
+		return 0
+	}
+	numCalls := len(q.CallDataList())
+	if listQuery, ok := perChainQuery.Query.(*EthCallByTimestampListQueryRequest); ok {
+		numCalls *= len(listQuery.TargetTimestamps)
+	}
+	return numCalls
+}
+
+// requestOption is a single option in a version 2 query request.
+type requestOption struct {
+	optionType RequestOptionType
+	value      uint8
+}
+
+// options returns the options that are set in the request, in the order they must be marshaled.
+func (queryRequest *QueryRequest) options() []requestOption {
+	options := []requestOption{}
+	if queryRequest.RetryBudget != 0 {
+		options = append(options, requestOption{RetryBudgetOption, queryRequest.RetryBudget})
+	}
+	if queryRequest.ResultNormalization != NoNormalization {
+		options = append(options, requestOption{ResultNormalizationOption, uint8(queryRequest.ResultNormalization)})
+	}
+	if queryRequest.ResponseSchemaVersion != DefaultResponseSchemaVersion {
+		options = append(options, requestOption{ResponseSchemaVersionOption, uint8(queryRequest.ResponseSchemaVersion)})
+	}
+	if queryRequest.AllowCachedResults {
+		options = append(options, requestOption{AllowCachedResultsOption, 1})
+	}
+	if queryRequest.hasMaxBlockAge() {
+		options = append(options, requestOption{MaxBlockAgeOption, 1})
+	}
+	if queryRequest.SlaTier != SlaTierBestEffort {
+		options = append(options, requestOption{SlaTierOption, uint8(queryRequest.SlaTier)})
+	}
+	if queryRequest.Environment != UnspecifiedEnvironment {
+		options = append(options, requestOption{EnvironmentOption, uint8(queryRequest.Environment)})
+	}
+	if queryRequest.HashAlgorithm != Keccak256HashAlgorithm {
+		options = append(options, requestOption{HashAlgorithmOption, uint8(queryRequest.HashAlgorithm)})
+	}
+	if queryRequest.AllowRevertedCalls {
+		options = append(options, requestOption{AllowRevertedCallsOption, 1})
+	}
+	if queryRequest.AllowPartialResults {
+		options = append(options, requestOption{AllowPartialResultsOption, 1})
+	}
+	if queryRequest.hasResultFilters() {
+		options = append(options, requestOption{ResultFiltersOption, 1})
+	}
+	if queryRequest.PreferSpeed {
+		options = append(options, requestOption{PreferSpeedOption, 1})
+	}
+	return options
+}
+
+// hasMaxBlockAge returns true if any of the per chain queries has a max block age.
+func (queryRequest *QueryRequest) hasMaxBlockAge() bool {
+	for _, perChainQuery := range queryRequest.PerChainQueries {
+		if perChainQuery.MaxBlockAge != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasResultFilters returns true if any of the per chain queries has a result filter.
+func (queryRequest *QueryRequest) hasResultFilters() bool {
+	for _, perChainQuery := range queryRequest.PerChainQueries {
+		if perChainQuery.ResultFilter != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalOptions reads the options from a version 2 query request and sets the corresponding fields. It returns whether the per chain queries
+// are followed by their max block ages and by their result filters.
+func (queryRequest *QueryRequest) unmarshalOptions(reader *bytes.Reader) (hasMaxBlockAge bool, hasResultFilters bool, err error) {
+	numOptions := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numOptions); err != nil {
+		return false, false, fmt.Errorf("failed to read number of request options: %w", err)
+	}
+
+	// An empty option list must be encoded using the original version, so that each request has only one valid encoding.
+	if numOptions == 0 {
+		return false, false, fmt.Errorf("a version %d request must contain at least one option", MSG_VERSION_WITH_OPTIONS)
+	}
+
+	prevType := RequestOptionType(0)
+	for count := 0; count < int(numOptions); count++ {
+		option := requestOption{}
+		if err := binary.Read(reader, binary.BigEndian, &option.optionType); err != nil {
+			return false, false, fmt.Errorf("failed to read request option type: %w", err)
+		}
+		if err := binary.Read(reader, binary.BigEndian, &option.value); err != nil {
+			return false, false, fmt.Errorf("failed to read request option value: %w", err)
+		}
+
+		if option.optionType <= prevType {
+			return false, false, fmt.Errorf("request options must be in increasing order of type")
+		}
+		prevType = option.optionType
+
+		if option.value == 0 {
+			return false, false, fmt.Errorf("request option %d may not be zero", option.optionType)
+		}
+
+		switch option.optionType {
+		case RetryBudgetOption:
+			queryRequest.RetryBudget = option.value
+		case ResultNormalizationOption:
+			queryRequest.ResultNormalization = ResultNormalization(option.value)
+		case ResponseSchemaVersionOption:
+			queryRequest.ResponseSchemaVersion = ResponseSchemaVersion(option.value)
+		case AllowCachedResultsOption:
+			if option.value != 1 {
+				return false, false, fmt.Errorf("invalid value for the allow cached results option: %d", option.value)
+			}
+			queryRequest.AllowCachedResults = true
+		case MaxBlockAgeOption:
+			if option.value != 1 {
+				return false, false, fmt.Errorf("invalid value for the max block age option: %d", option.value)
+			}
+			hasMaxBlockAge = true
+		case SlaTierOption:
+			queryRequest.SlaTier = SlaTier(option.value)
+		case EnvironmentOption:
+			queryRequest.Environment = RequestEnvironment(option.value)
+		case HashAlgorithmOption:
+			queryRequest.HashAlgorithm = HashAlgorithm(option.value)
+		case AllowRevertedCallsOption:
+			if option.value != 1 {
+				return false, false, fmt.Errorf("invalid value for the allow reverted calls option: %d", option.value)
+			}
+			queryRequest.AllowRevertedCalls = true
+		case AllowPartialResultsOption:
+			if option.value != 1 {
+				return false, false, fmt.Errorf("invalid value for the allow partial results option: %d", option.value)
+			}
+			queryRequest.AllowPartialResults = true
+		case ResultFiltersOption:
+			if option.value != 1 {
+				return false, false, fmt.Errorf("invalid value for the result filters option: %d", option.value)
+			}
+			hasResultFilters = true
+		case PreferSpeedOption:
+			if option.value != 1 {
+				return false, false, fmt.Errorf("invalid value for the prefer speed option: %d", option.value)
+			}
+			queryRequest.PreferSpeed = true
+		default:
+			return false, false, fmt.Errorf("unsupported request option: %d", option.optionType)
+		}
+	}
+
+	return hasMaxBlockAge, hasResultFilters, nil
+}
+
 // Validate does basic validation on a received query request.
 func (queryRequest *QueryRequest) Validate() error {
 	// Nothing to validate on the Nonce.
@@ -327,6 +939,21 @@ func (queryRequest *QueryRequest) Validate() error {
 	if len(queryRequest.PerChainQueries) > math.MaxUint8 {
 		return fmt.Errorf("too many per chain queries")
 	}
+	if err := queryRequest.ResultNormalization.Validate(); err != nil {
+		return err
+	}
+	if err := queryRequest.ResponseSchemaVersion.Validate(); err != nil {
+		return err
+	}
+	if err := queryRequest.SlaTier.Validate(); err != nil {
+		return err
+	}
+	if err := queryRequest.Environment.Validate(); err != nil {
+		return err
+	}
+	if err := queryRequest.HashAlgorithm.Validate(); err != nil {
+		return err
+	}
 	for idx, perChainQuery := range queryRequest.PerChainQueries {
 		if err := perChainQuery.Validate(); err != nil {
 			return fmt.Errorf("failed to validate per chain query %d: %w", idx, err)
@@ -340,6 +967,36 @@ func (left *QueryRequest) Equal(right *QueryRequest) bool {
 	if left.Nonce != right.Nonce {
 		return false
 	}
+	if left.RetryBudget != right.RetryBudget {
+		return false
+	}
+	if left.ResultNormalization != right.ResultNormalization {
+		return false
+	}
+	if left.ResponseSchemaVersion != right.ResponseSchemaVersion {
+		return false
+	}
+	if left.AllowCachedResults != right.AllowCachedResults {
+		return false
+	}
+	if left.SlaTier != right.SlaTier {
+		return false
+	}
+	if left.Environment != right.Environment {
+		return false
+	}
+	if left.HashAlgorithm != right.HashAlgorithm {
+		return false
+	}
+	if left.AllowRevertedCalls != right.AllowRevertedCalls {
+		return false
+	}
+	if left.AllowPartialResults != right.AllowPartialResults {
+		return false
+	}
+	if left.PreferSpeed != right.PreferSpeed {
+		return false
+	}
 	if len(left.PerChainQueries) != len(right.PerChainQueries) {
 		return false
 	}
@@ -403,47 +1060,29 @@ func (perChainQuery *PerChainQueryRequest) UnmarshalFromReader(reader *bytes.Rea
 		return err
 	}
 
-	// Skip the query length.
+	// The query length is used to verify that the chain specific query does not contain any unexpected fields.
 	var queryLength uint32
 	if err := binary.Read(reader, binary.BigEndian, &queryLength); err != nil {
 		return fmt.Errorf("failed to read query length: %w", err)
 	}
+	startLen := reader.Len()
 
-	switch queryType {
-	case EthCallQueryRequestType:
-		q := EthCallQueryRequest{}
-		if err := q.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal eth call request: %w", err)
-		}
-		perChainQuery.Query = &q
-	case EthCallByTimestampQueryRequestType:
-		q := EthCallByTimestampQueryRequest{}
-		if err := q.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal eth call by timestamp request: %w", err)
-		}
-		perChainQuery.Query = &q
-	case EthCallWithFinalityQueryRequestType:
-		q := EthCallWithFinalityQueryRequest{}
-		if err := q.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal eth call with finality request: %w", err)
-		}
-		perChainQuery.Query = &q
-	case SolanaAccountQueryRequestType:
-		q := SolanaAccountQueryRequest{}
-		if err := q.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal solana account query request: %w", err)
-		}
-		perChainQuery.Query = &q
-	case SolanaPdaQueryRequestType:
-		q := SolanaPdaQueryRequest{}
-		if err := q.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal solana PDA query request: %w", err)
-		}
-		perChainQuery.Query = &q
-	default:
+	handler := lookUpQueryType(queryType)
+	if handler == nil {
 		return fmt.Errorf("unsupported query type: %d", queryType)
 	}
 
+	q := handler.NewRequest()
+	if err := q.UnmarshalFromReader(reader); err != nil {
+		return fmt.Errorf("failed to unmarshal %s request: %w", handler.Name, err)
+	}
+	perChainQuery.Query = q
+
+	// A query with trailing fields could be an attempt to pass something like a value or nonce that would imply a state changing call.
+	if bytesRead := startLen - reader.Len(); bytesRead != int(queryLength) {
+		return fmt.Errorf("query length mismatch, expected %d bytes, read %d", queryLength, bytesRead)
+	}
+
 	return nil
 }
 
@@ -466,12 +1105,21 @@ func (perChainQuery *PerChainQueryRequest) Validate() error {
 		return fmt.Errorf("chain specific query is invalid: %w", err)
 	}
 
+	if perChainQuery.ResultFilter != "" {
+		if perChainQuery.Query.Type() != EthLogsQueryRequestType {
+			return fmt.Errorf("result filters are only supported on eth_logs queries")
+		}
+		if _, err := parseResultFilter(perChainQuery.ResultFilter); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// ValidatePerChainQueryRequestType verifies that the query type is registered.
 func ValidatePerChainQueryRequestType(qt ChainSpecificQueryType) error {
-	if qt != EthCallQueryRequestType && qt != EthCallByTimestampQueryRequestType && qt != EthCallWithFinalityQueryRequestType &&
-		qt != SolanaAccountQueryRequestType && qt != SolanaPdaQueryRequestType {
+	if lookUpQueryType(qt) == nil {
 		return fmt.Errorf("invalid query request type: %d", qt)
 	}
 	return nil
@@ -483,6 +1131,14 @@ func (left *PerChainQueryRequest) Equal(right *PerChainQueryRequest) bool {
 		return false
 	}
 
+	if left.MaxBlockAge != right.MaxBlockAge {
+		return false
+	}
+
+	if left.ResultFilter != right.ResultFilter {
+		return false
+	}
+
 	if left.Query == nil && right.Query == nil {
 		return true
 	}
@@ -517,7 +1173,56 @@ func (left *PerChainQueryRequest) Equal(right *PerChainQueryRequest) bool {
 		default:
 			panic("unsupported query type on right, must be eth_call_with_finality")
 		}
-	case *SolanaAccountQueryRequest:
+	case *EthCallWithPreconditionQueryRequest:
+		switch rightQuery := right.Query.(type) {
+		case *EthCallWithPreconditionQueryRequest:
+			return leftQuery.Equal(rightQuery)
+		default:
+			panic("unsupported query type on right, must be eth_call_with_precondition")
+		}
+	case *EthCallByTimestampListQueryRequest:
+		switch rightQuery := right.Query.(type) {
+		case *EthCallByTimestampListQueryRequest:
+			return leftQuery.Equal(rightQuery)
+		default:
+			panic("unsupported query type on right, must be eth_call_by_timestamp_list")
+		}
+	case *EthTxProofQueryRequest:
+		switch rightQuery := right.Query.(type) {
+		case *EthTxProofQueryRequest:
+			return leftQuery.Equal(rightQuery)
+		default:
+			panic("unsupported query type on right, must be eth_tx_proof")
+		}
+	case *EthStorageDiffQueryRequest:
+		switch rightQuery := right.Query.(type) {
+		case *EthStorageDiffQueryRequest:
+			return leftQuery.Equal(rightQuery)
+		default:
+			panic("unsupported query type on right, must be eth_storage_diff")
+		}
+	case *EthBlockProbeQueryRequest:
+		switch rightQuery := right.Query.(type) {
+		case *EthBlockProbeQueryRequest:
+			return leftQuery.Equal(rightQuery)
+		default:
+			panic("unsupported query type on right, must be eth_block_probe")
+		}
+	case *EthLogsQueryRequest:
+		switch rightQuery := right.Query.(type) {
+		case *EthLogsQueryRequest:
+			return leftQuery.Equal(rightQuery)
+		default:
+			panic("unsupported query type on right, must be eth_logs")
+		}
+	case *EthStorageQueryRequest:
+		switch rightQuery := right.Query.(type) {
+		case *EthStorageQueryRequest:
+			return leftQuery.Equal(rightQuery)
+		default:
+			panic("unsupported query type on right, must be eth_storage")
+		}
+	case *SolanaAccountQueryRequest:
 		switch rightQuery := right.Query.(type) {
 		case *SolanaAccountQueryRequest:
 			return leftQuery.Equal(rightQuery)
@@ -532,7 +1237,8 @@ func (left *PerChainQueryRequest) Equal(right *PerChainQueryRequest) bool {
 			panic("unsupported query type on right, must be sol_pda")
 		}
 	default:
-		panic("unsupported query type on left")
+		// This is a registered query type, so compare the serialized queries.
+		return marshaledEqual(left.Query, right.Query)
 	}
 }
 
@@ -555,6 +1261,11 @@ func (ecd *EthCallQueryRequest) Marshal() ([]byte, error) {
 	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.BlockId)))
 	buf.Write([]byte(ecd.BlockId))
 
+	if ecd.Finality != "" {
+		vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.Finality)))
+		buf.Write([]byte(ecd.Finality))
+	}
+
 	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecd.CallData)))
 	for _, callData := range ecd.CallData {
 		buf.Write(callData.To)
@@ -583,6 +1294,20 @@ func (ecd *EthCallQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error
 	}
 	ecd.BlockId = string(blockId[:])
 
+	// An empty block id is followed by the finality.
+	if blockIdLen == 0 {
+		finalityLen := uint32(0)
+		if err := binary.Read(reader, binary.BigEndian, &finalityLen); err != nil {
+			return fmt.Errorf("failed to read finality len: %w", err)
+		}
+
+		finality := make([]byte, finalityLen)
+		if n, err := reader.Read(finality[:]); err != nil || n != int(finalityLen) {
+			return fmt.Errorf("failed to read finality [%d]: %w", n, err)
+		}
+		ecd.Finality = string(finality[:])
+	}
+
 	numCallData := uint8(0)
 	if err := binary.Read(reader, binary.BigEndian, &numCallData); err != nil {
 		return fmt.Errorf("failed to read number of call data entries: %w", err)
@@ -619,8 +1344,15 @@ func (ecd *EthCallQueryRequest) Validate() error {
 	if len(ecd.BlockId) > math.MaxUint32 {
 		return fmt.Errorf("block id too long")
 	}
-	if !strings.HasPrefix(ecd.BlockId, "0x") {
-		return fmt.Errorf("block id must be a hex number or hash starting with 0x")
+	if ecd.Finality != "" {
+		if ecd.BlockId != "" {
+			return fmt.Errorf("block id and finality may not both be set")
+		}
+		if ecd.Finality != "latest" && ecd.Finality != "safe" && ecd.Finality != "finalized" {
+			return fmt.Errorf(`finality must be "latest", "safe" or "finalized", is "%s"`, ecd.Finality)
+		}
+	} else if !validBlockIdForm(ecd.BlockId) {
+		return fmt.Errorf("block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
 	}
 	if len(ecd.CallData) <= 0 {
 		return fmt.Errorf("does not contain any call data")
@@ -651,6 +1383,9 @@ func (left *EthCallQueryRequest) Equal(right *EthCallQueryRequest) bool {
 	if left.BlockId != right.BlockId {
 		return false
 	}
+	if left.Finality != right.Finality {
+		return false
+	}
 	if len(left.CallData) != len(right.CallData) {
 		return false
 	}
@@ -775,14 +1510,14 @@ func (ecd *EthCallByTimestampQueryRequest) Validate() error {
 	if (ecd.TargetBlockIdHint == "") != (ecd.FollowingBlockIdHint == "") {
 		return fmt.Errorf("if either the target or following block id is unset, they both must be unset")
 	}
-	if ecd.TargetBlockIdHint != "" && !strings.HasPrefix(ecd.TargetBlockIdHint, "0x") {
-		return fmt.Errorf("target block id must be a hex number or hash starting with 0x")
+	if ecd.TargetBlockIdHint != "" && !validBlockIdForm(ecd.TargetBlockIdHint) {
+		return fmt.Errorf("target block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
 	}
 	if len(ecd.FollowingBlockIdHint) > math.MaxUint32 {
 		return fmt.Errorf("following block id hint too long")
 	}
-	if ecd.FollowingBlockIdHint != "" && !strings.HasPrefix(ecd.FollowingBlockIdHint, "0x") {
-		return fmt.Errorf("following block id must be a hex number or hash starting with 0x")
+	if ecd.FollowingBlockIdHint != "" && !validBlockIdForm(ecd.FollowingBlockIdHint) {
+		return fmt.Errorf("following block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
 	}
 	if len(ecd.CallData) <= 0 {
 		return fmt.Errorf("does not contain any call data")
@@ -934,8 +1669,8 @@ func (ecd *EthCallWithFinalityQueryRequest) Validate() error {
 	if ecd.BlockId == "" {
 		return fmt.Errorf("block id is required")
 	}
-	if !strings.HasPrefix(ecd.BlockId, "0x") {
-		return fmt.Errorf("block id must be a hex number or hash starting with 0x")
+	if !validBlockIdForm(ecd.BlockId) {
+		return fmt.Errorf("block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
 	}
 	if len(ecd.Finality) > math.MaxUint32 {
 		return fmt.Errorf("finality too long")
@@ -993,6 +1728,828 @@ func (left *EthCallWithFinalityQueryRequest) Equal(right *EthCallWithFinalityQue
 	return true
 }
 
+//
+// Implementation of EthCallWithPreconditionQueryRequest, which implements the ChainSpecificQuery interface.
+//
+
+func (e *EthCallWithPreconditionQueryRequest) Type() ChainSpecificQueryType {
+	return EthCallWithPreconditionQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_call_with_precondition request.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecd *EthCallWithPreconditionQueryRequest) Marshal() ([]byte, error) {
+	if err := ecd.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.BlockId)))
+	buf.Write([]byte(ecd.BlockId))
+
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.ExpectedResult)))
+	buf.Write(ecd.ExpectedResult)
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecd.CallData)))
+	for _, callData := range ecd.CallData {
+		buf.Write(callData.To)
+		vaa.MustWrite(buf, binary.BigEndian, uint32(len(callData.Data)))
+		buf.Write(callData.Data)
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_call_with_precondition query from a byte array
+func (ecd *EthCallWithPreconditionQueryRequest) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecd.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_call_with_precondition query from a byte array
+func (ecd *EthCallWithPreconditionQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error {
+	blockIdLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &blockIdLen); err != nil {
+		return fmt.Errorf("failed to read block id len: %w", err)
+	}
+
+	blockId := make([]byte, blockIdLen)
+	if n, err := reader.Read(blockId[:]); err != nil || n != int(blockIdLen) {
+		return fmt.Errorf("failed to read block id [%d]: %w", n, err)
+	}
+	ecd.BlockId = string(blockId[:])
+
+	expectedResultLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &expectedResultLen); err != nil {
+		return fmt.Errorf("failed to read expected result len: %w", err)
+	}
+
+	expectedResult := make([]byte, expectedResultLen)
+	if expectedResultLen != 0 {
+		if n, err := reader.Read(expectedResult[:]); err != nil || n != int(expectedResultLen) {
+			return fmt.Errorf("failed to read expected result [%d]: %w", n, err)
+		}
+	}
+	ecd.ExpectedResult = expectedResult
+
+	numCallData := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numCallData); err != nil {
+		return fmt.Errorf("failed to read number of call data entries: %w", err)
+	}
+
+	for count := 0; count < int(numCallData); count++ {
+		to := [EvmContractAddressLength]byte{}
+		if n, err := reader.Read(to[:]); err != nil || n != EvmContractAddressLength {
+			return fmt.Errorf("failed to read call To [%d]: %w", n, err)
+		}
+
+		dataLen := uint32(0)
+		if err := binary.Read(reader, binary.BigEndian, &dataLen); err != nil {
+			return fmt.Errorf("failed to read call Data len: %w", err)
+		}
+		data := make([]byte, dataLen)
+		if n, err := reader.Read(data[:]); err != nil || n != int(dataLen) {
+			return fmt.Errorf("failed to read call data [%d]: %w", n, err)
+		}
+
+		callData := &EthCallData{
+			To:   to[:],
+			Data: data[:],
+		}
+
+		ecd.CallData = append(ecd.CallData, callData)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_call_with_precondition query.
+func (ecd *EthCallWithPreconditionQueryRequest) Validate() error {
+	if len(ecd.BlockId) > math.MaxUint32 {
+		return fmt.Errorf("block id too long")
+	}
+	if !validBlockIdForm(ecd.BlockId) {
+		return fmt.Errorf("block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
+	}
+	if len(ecd.ExpectedResult) > math.MaxUint32 {
+		return fmt.Errorf("expected result too long")
+	}
+	if len(ecd.CallData) < 2 {
+		return fmt.Errorf("must contain a precondition call and at least one dependent call")
+	}
+	if len(ecd.CallData) > math.MaxUint8 {
+		return fmt.Errorf("too many call data entries")
+	}
+	for _, callData := range ecd.CallData {
+		if callData.To == nil || len(callData.To) <= 0 {
+			return fmt.Errorf("no call data to")
+		}
+		if len(callData.To) != EvmContractAddressLength {
+			return fmt.Errorf("invalid length for To contract")
+		}
+		if callData.Data == nil || len(callData.Data) <= 0 {
+			return fmt.Errorf("no call data data")
+		}
+		if len(callData.Data) > math.MaxUint32 {
+			return fmt.Errorf("call data data too long")
+		}
+	}
+
+	return nil
+}
+
+// Equal verifies that two EVM eth_call_with_precondition queries are equal.
+func (left *EthCallWithPreconditionQueryRequest) Equal(right *EthCallWithPreconditionQueryRequest) bool {
+	if left.BlockId != right.BlockId {
+		return false
+	}
+	if !bytes.Equal(left.ExpectedResult, right.ExpectedResult) {
+		return false
+	}
+	if len(left.CallData) != len(right.CallData) {
+		return false
+	}
+	for idx := range left.CallData {
+		if !bytes.Equal(left.CallData[idx].To, right.CallData[idx].To) {
+			return false
+		}
+		if !bytes.Equal(left.CallData[idx].Data, right.CallData[idx].Data) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthCallByTimestampListQueryRequest, which implements the ChainSpecificQuery interface.
+//
+
+func (e *EthCallByTimestampListQueryRequest) Type() ChainSpecificQueryType {
+	return EthCallByTimestampListQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_call_by_timestamp_list request.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecd *EthCallByTimestampListQueryRequest) Marshal() ([]byte, error) {
+	if err := ecd.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecd.TargetTimestamps)))
+	for _, timestamp := range ecd.TargetTimestamps {
+		vaa.MustWrite(buf, binary.BigEndian, timestamp)
+	}
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecd.CallData)))
+	for _, callData := range ecd.CallData {
+		buf.Write(callData.To)
+		vaa.MustWrite(buf, binary.BigEndian, uint32(len(callData.Data)))
+		buf.Write(callData.Data)
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_call_by_timestamp_list query from a byte array
+func (ecd *EthCallByTimestampListQueryRequest) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecd.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_call_by_timestamp_list query from a byte array
+func (ecd *EthCallByTimestampListQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error {
+	numTimestamps := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numTimestamps); err != nil {
+		return fmt.Errorf("failed to read number of timestamps: %w", err)
+	}
+
+	for count := 0; count < int(numTimestamps); count++ {
+		timestamp := uint64(0)
+		if err := binary.Read(reader, binary.BigEndian, &timestamp); err != nil {
+			return fmt.Errorf("failed to read timestamp: %w", err)
+		}
+		ecd.TargetTimestamps = append(ecd.TargetTimestamps, timestamp)
+	}
+
+	numCallData := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numCallData); err != nil {
+		return fmt.Errorf("failed to read number of call data entries: %w", err)
+	}
+
+	for count := 0; count < int(numCallData); count++ {
+		to := [EvmContractAddressLength]byte{}
+		if n, err := reader.Read(to[:]); err != nil || n != EvmContractAddressLength {
+			return fmt.Errorf("failed to read call To [%d]: %w", n, err)
+		}
+
+		dataLen := uint32(0)
+		if err := binary.Read(reader, binary.BigEndian, &dataLen); err != nil {
+			return fmt.Errorf("failed to read call Data len: %w", err)
+		}
+		data := make([]byte, dataLen)
+		if n, err := reader.Read(data[:]); err != nil || n != int(dataLen) {
+			return fmt.Errorf("failed to read call data [%d]: %w", n, err)
+		}
+
+		callData := &EthCallData{
+			To:   to[:],
+			Data: data[:],
+		}
+
+		ecd.CallData = append(ecd.CallData, callData)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_call_by_timestamp_list query.
+func (ecd *EthCallByTimestampListQueryRequest) Validate() error {
+	if len(ecd.TargetTimestamps) <= 0 {
+		return fmt.Errorf("does not contain any timestamps")
+	}
+	if len(ecd.TargetTimestamps) > EthCallByTimestampListMaxTimestamps {
+		return fmt.Errorf("too many timestamps, may not exceed %d", EthCallByTimestampListMaxTimestamps)
+	}
+	for idx, timestamp := range ecd.TargetTimestamps {
+		if timestamp == 0 {
+			return fmt.Errorf("target timestamp may not be zero")
+		}
+		if idx > 0 && timestamp <= ecd.TargetTimestamps[idx-1] {
+			return fmt.Errorf("target timestamps must be in strictly increasing order")
+		}
+	}
+	if len(ecd.CallData) <= 0 {
+		return fmt.Errorf("does not contain any call data")
+	}
+	if len(ecd.CallData) > math.MaxUint8 {
+		return fmt.Errorf("too many call data entries")
+	}
+	for _, callData := range ecd.CallData {
+		if callData.To == nil || len(callData.To) <= 0 {
+			return fmt.Errorf("no call data to")
+		}
+		if len(callData.To) != EvmContractAddressLength {
+			return fmt.Errorf("invalid length for To contract")
+		}
+		if callData.Data == nil || len(callData.Data) <= 0 {
+			return fmt.Errorf("no call data data")
+		}
+		if len(callData.Data) > math.MaxUint32 {
+			return fmt.Errorf("call data data too long")
+		}
+	}
+
+	return nil
+}
+
+// Equal verifies that two EVM eth_call_by_timestamp_list queries are equal.
+func (left *EthCallByTimestampListQueryRequest) Equal(right *EthCallByTimestampListQueryRequest) bool {
+	if len(left.TargetTimestamps) != len(right.TargetTimestamps) {
+		return false
+	}
+	for idx := range left.TargetTimestamps {
+		if left.TargetTimestamps[idx] != right.TargetTimestamps[idx] {
+			return false
+		}
+	}
+	if len(left.CallData) != len(right.CallData) {
+		return false
+	}
+	for idx := range left.CallData {
+		if !bytes.Equal(left.CallData[idx].To, right.CallData[idx].To) {
+			return false
+		}
+		if !bytes.Equal(left.CallData[idx].Data, right.CallData[idx].Data) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthTxProofQueryRequest, which implements the ChainSpecificQuery interface.
+//
+
+func (e *EthTxProofQueryRequest) Type() ChainSpecificQueryType {
+	return EthTxProofQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_tx_proof request.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecd *EthTxProofQueryRequest) Marshal() ([]byte, error) {
+	if err := ecd.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(ecd.TxHash[:])
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_tx_proof query from a byte array
+func (ecd *EthTxProofQueryRequest) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecd.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_tx_proof query from a byte array
+func (ecd *EthTxProofQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error {
+	txHash := ethCommon.Hash{}
+	if n, err := reader.Read(txHash[:]); err != nil || n != ethCommon.HashLength {
+		return fmt.Errorf("failed to read tx hash [%d]: %w", n, err)
+	}
+	ecd.TxHash = txHash
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_tx_proof query.
+func (ecd *EthTxProofQueryRequest) Validate() error {
+	if ecd.TxHash == (ethCommon.Hash{}) {
+		return fmt.Errorf("tx hash may not be zero")
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_tx_proof queries are equal.
+func (left *EthTxProofQueryRequest) Equal(right *EthTxProofQueryRequest) bool {
+	return left.TxHash == right.TxHash
+}
+
+//
+// Implementation of EthStorageDiffQueryRequest, which implements the ChainSpecificQuery interface.
+//
+
+func (e *EthStorageDiffQueryRequest) Type() ChainSpecificQueryType {
+	return EthStorageDiffQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_storage_diff request.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecd *EthStorageDiffQueryRequest) Marshal() ([]byte, error) {
+	if err := ecd.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.FromBlockId)))
+	buf.Write([]byte(ecd.FromBlockId))
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.ToBlockId)))
+	buf.Write([]byte(ecd.ToBlockId))
+	buf.Write(ecd.Contract)
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecd.Slots)))
+	for _, slot := range ecd.Slots {
+		buf.Write(slot[:])
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_storage_diff query from a byte array
+func (ecd *EthStorageDiffQueryRequest) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecd.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_storage_diff query from a byte array
+func (ecd *EthStorageDiffQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error {
+	fromBlockIdLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &fromBlockIdLen); err != nil {
+		return fmt.Errorf("failed to read from block id len: %w", err)
+	}
+
+	fromBlockId := make([]byte, fromBlockIdLen)
+	if n, err := reader.Read(fromBlockId[:]); err != nil || n != int(fromBlockIdLen) {
+		return fmt.Errorf("failed to read from block id [%d]: %w", n, err)
+	}
+	ecd.FromBlockId = string(fromBlockId[:])
+
+	toBlockIdLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &toBlockIdLen); err != nil {
+		return fmt.Errorf("failed to read to block id len: %w", err)
+	}
+
+	toBlockId := make([]byte, toBlockIdLen)
+	if n, err := reader.Read(toBlockId[:]); err != nil || n != int(toBlockIdLen) {
+		return fmt.Errorf("failed to read to block id [%d]: %w", n, err)
+	}
+	ecd.ToBlockId = string(toBlockId[:])
+
+	contract := [EvmContractAddressLength]byte{}
+	if n, err := reader.Read(contract[:]); err != nil || n != EvmContractAddressLength {
+		return fmt.Errorf("failed to read contract [%d]: %w", n, err)
+	}
+	ecd.Contract = contract[:]
+
+	numSlots := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numSlots); err != nil {
+		return fmt.Errorf("failed to read number of slots: %w", err)
+	}
+
+	for count := 0; count < int(numSlots); count++ {
+		slot := ethCommon.Hash{}
+		if n, err := reader.Read(slot[:]); err != nil || n != ethCommon.HashLength {
+			return fmt.Errorf("failed to read slot [%d]: %w", n, err)
+		}
+		ecd.Slots = append(ecd.Slots, slot)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_storage_diff query.
+func (ecd *EthStorageDiffQueryRequest) Validate() error {
+	if len(ecd.FromBlockId) > math.MaxUint32 {
+		return fmt.Errorf("from block id too long")
+	}
+	if !validBlockIdForm(ecd.FromBlockId) {
+		return fmt.Errorf("from block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
+	}
+	if len(ecd.ToBlockId) > math.MaxUint32 {
+		return fmt.Errorf("to block id too long")
+	}
+	if !validBlockIdForm(ecd.ToBlockId) {
+		return fmt.Errorf("to block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
+	}
+	if len(ecd.Contract) != EvmContractAddressLength {
+		return fmt.Errorf("invalid length for contract")
+	}
+	if len(ecd.Slots) <= 0 {
+		return fmt.Errorf("does not contain any slots")
+	}
+	if len(ecd.Slots) > math.MaxUint8 {
+		return fmt.Errorf("too many slots")
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_storage_diff queries are equal.
+func (left *EthStorageDiffQueryRequest) Equal(right *EthStorageDiffQueryRequest) bool {
+	if left.FromBlockId != right.FromBlockId || left.ToBlockId != right.ToBlockId {
+		return false
+	}
+	if !bytes.Equal(left.Contract, right.Contract) {
+		return false
+	}
+	if len(left.Slots) != len(right.Slots) {
+		return false
+	}
+	for idx := range left.Slots {
+		if left.Slots[idx] != right.Slots[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthBlockProbeQueryRequest, which implements the ChainSpecificQuery interface.
+//
+
+func (e *EthBlockProbeQueryRequest) Type() ChainSpecificQueryType {
+	return EthBlockProbeQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_block_probe request.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecd *EthBlockProbeQueryRequest) Marshal() ([]byte, error) {
+	if err := ecd.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.BlockTag)))
+	buf.Write([]byte(ecd.BlockTag))
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_block_probe query from a byte array
+func (ecd *EthBlockProbeQueryRequest) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecd.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_block_probe query from a byte array
+func (ecd *EthBlockProbeQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error {
+	blockTagLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &blockTagLen); err != nil {
+		return fmt.Errorf("failed to read block tag len: %w", err)
+	}
+
+	blockTag := make([]byte, blockTagLen)
+	if n, err := reader.Read(blockTag[:]); err != nil || n != int(blockTagLen) {
+		return fmt.Errorf("failed to read block tag [%d]: %w", n, err)
+	}
+	ecd.BlockTag = string(blockTag[:])
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_block_probe query.
+func (ecd *EthBlockProbeQueryRequest) Validate() error {
+	if ecd.BlockTag != "latest" && ecd.BlockTag != "safe" && ecd.BlockTag != "finalized" {
+		return fmt.Errorf(`block tag must be "latest", "safe" or "finalized", is "%s"`, ecd.BlockTag)
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_block_probe queries are equal.
+func (left *EthBlockProbeQueryRequest) Equal(right *EthBlockProbeQueryRequest) bool {
+	return left.BlockTag == right.BlockTag
+}
+
+//
+// Implementation of EthLogsQueryRequest, which implements the ChainSpecificQuery interface.
+//
+
+func (e *EthLogsQueryRequest) Type() ChainSpecificQueryType {
+	return EthLogsQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_logs request.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecd *EthLogsQueryRequest) Marshal() ([]byte, error) {
+	if err := ecd.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.FromBlock)))
+	buf.Write([]byte(ecd.FromBlock))
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.ToBlock)))
+	buf.Write([]byte(ecd.ToBlock))
+	buf.Write(ecd.Address)
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecd.Topics)))
+	for _, topic := range ecd.Topics {
+		vaa.MustWrite(buf, binary.BigEndian, uint8(len(topic)))
+		buf.Write(topic)
+	}
+
+	vaa.MustWrite(buf, binary.BigEndian, ecd.MaxWidenedSpan)
+	vaa.MustWrite(buf, binary.BigEndian, ecd.MinResults)
+	failBelowMinResults := uint8(0)
+	if ecd.FailBelowMinResults {
+		failBelowMinResults = 1
+	}
+	vaa.MustWrite(buf, binary.BigEndian, failBelowMinResults)
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_logs query from a byte array
+func (ecd *EthLogsQueryRequest) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecd.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_logs query from a byte array
+func (ecd *EthLogsQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error {
+	fromBlockLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &fromBlockLen); err != nil {
+		return fmt.Errorf("failed to read from block len: %w", err)
+	}
+
+	fromBlock := make([]byte, fromBlockLen)
+	if n, err := reader.Read(fromBlock[:]); err != nil || n != int(fromBlockLen) {
+		return fmt.Errorf("failed to read from block [%d]: %w", n, err)
+	}
+	ecd.FromBlock = string(fromBlock[:])
+
+	toBlockLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &toBlockLen); err != nil {
+		return fmt.Errorf("failed to read to block len: %w", err)
+	}
+
+	toBlock := make([]byte, toBlockLen)
+	if n, err := reader.Read(toBlock[:]); err != nil || n != int(toBlockLen) {
+		return fmt.Errorf("failed to read to block [%d]: %w", n, err)
+	}
+	ecd.ToBlock = string(toBlock[:])
+
+	address := [EvmContractAddressLength]byte{}
+	if n, err := reader.Read(address[:]); err != nil || n != EvmContractAddressLength {
+		return fmt.Errorf("failed to read address [%d]: %w", n, err)
+	}
+	ecd.Address = address[:]
+
+	numTopics := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numTopics); err != nil {
+		return fmt.Errorf("failed to read number of topics: %w", err)
+	}
+
+	ecd.Topics = nil
+	for count := 0; count < int(numTopics); count++ {
+		topicLen := uint8(0)
+		if err := binary.Read(reader, binary.BigEndian, &topicLen); err != nil {
+			return fmt.Errorf("failed to read topic len: %w", err)
+		}
+		if topicLen != 0 && topicLen != ethCommon.HashLength {
+			return fmt.Errorf("invalid length for topic %d: %d", count, topicLen)
+		}
+
+		topic := make([]byte, topicLen)
+		if topicLen != 0 {
+			if n, err := reader.Read(topic[:]); err != nil || n != int(topicLen) {
+				return fmt.Errorf("failed to read topic [%d]: %w", n, err)
+			}
+		}
+		ecd.Topics = append(ecd.Topics, topic)
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &ecd.MaxWidenedSpan); err != nil {
+		return fmt.Errorf("failed to read max widened span: %w", err)
+	}
+	if err := binary.Read(reader, binary.BigEndian, &ecd.MinResults); err != nil {
+		return fmt.Errorf("failed to read min results: %w", err)
+	}
+	failBelowMinResults := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &failBelowMinResults); err != nil {
+		return fmt.Errorf("failed to read fail below min results: %w", err)
+	}
+	if failBelowMinResults > 1 {
+		return fmt.Errorf("invalid value for fail below min results: %d", failBelowMinResults)
+	}
+	ecd.FailBelowMinResults = failBelowMinResults == 1
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_logs query.
+func (ecd *EthLogsQueryRequest) Validate() error {
+	if len(ecd.FromBlock) > math.MaxUint32 {
+		return fmt.Errorf("from block too long")
+	}
+	fromBlockNum, err := ParseBlockNumber(ecd.FromBlock)
+	if err != nil {
+		return fmt.Errorf("invalid from block: %w", err)
+	}
+	if len(ecd.ToBlock) > math.MaxUint32 {
+		return fmt.Errorf("to block too long")
+	}
+	toBlockNum, err := ParseBlockNumber(ecd.ToBlock)
+	if err != nil {
+		return fmt.Errorf("invalid to block: %w", err)
+	}
+	if toBlockNum < fromBlockNum {
+		return fmt.Errorf("to block may not be before from block")
+	}
+	if len(ecd.Address) != EvmContractAddressLength {
+		return fmt.Errorf("invalid length for address")
+	}
+	if len(ecd.Topics) > MaxEthLogsTopics {
+		return fmt.Errorf("too many topics")
+	}
+	for idx, topic := range ecd.Topics {
+		if len(topic) != 0 && len(topic) != ethCommon.HashLength {
+			return fmt.Errorf("invalid length for topic %d", idx)
+		}
+	}
+	if ecd.MaxWidenedSpan != 0 {
+		if uint64(ecd.MaxWidenedSpan) <= toBlockNum-fromBlockNum+1 {
+			return fmt.Errorf("max widened span must be larger than the range")
+		}
+		if ecd.MaxWidenedSpan > MaxEthLogsBlockRange {
+			return fmt.Errorf("max widened span may not exceed %d blocks", MaxEthLogsBlockRange)
+		}
+	}
+	if ecd.FailBelowMinResults && ecd.MinResults == 0 {
+		return fmt.Errorf("fail below min results requires min results")
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_logs queries are equal.
+func (left *EthLogsQueryRequest) Equal(right *EthLogsQueryRequest) bool {
+	if left.FromBlock != right.FromBlock || left.ToBlock != right.ToBlock || left.MaxWidenedSpan != right.MaxWidenedSpan {
+		return false
+	}
+	if left.MinResults != right.MinResults || left.FailBelowMinResults != right.FailBelowMinResults {
+		return false
+	}
+	if !bytes.Equal(left.Address, right.Address) {
+		return false
+	}
+	if len(left.Topics) != len(right.Topics) {
+		return false
+	}
+	for idx := range left.Topics {
+		if !bytes.Equal(left.Topics[idx], right.Topics[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthStorageQueryRequest, which implements the ChainSpecificQuery interface.
+//
+
+func (e *EthStorageQueryRequest) Type() ChainSpecificQueryType {
+	return EthStorageQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_storage request.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecd *EthStorageQueryRequest) Marshal() ([]byte, error) {
+	if err := ecd.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecd.BlockId)))
+	buf.Write([]byte(ecd.BlockId))
+	buf.Write(ecd.Contract)
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecd.Slots)))
+	for _, slot := range ecd.Slots {
+		buf.Write(slot[:])
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_storage query from a byte array
+func (ecd *EthStorageQueryRequest) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecd.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_storage query from a byte array
+func (ecd *EthStorageQueryRequest) UnmarshalFromReader(reader *bytes.Reader) error {
+	blockIdLen := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &blockIdLen); err != nil {
+		return fmt.Errorf("failed to read block id len: %w", err)
+	}
+
+	blockId := make([]byte, blockIdLen)
+	if n, err := reader.Read(blockId[:]); err != nil || n != int(blockIdLen) {
+		return fmt.Errorf("failed to read block id [%d]: %w", n, err)
+	}
+	ecd.BlockId = string(blockId[:])
+
+	contract := [EvmContractAddressLength]byte{}
+	if n, err := reader.Read(contract[:]); err != nil || n != EvmContractAddressLength {
+		return fmt.Errorf("failed to read contract [%d]: %w", n, err)
+	}
+	ecd.Contract = contract[:]
+
+	numSlots := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numSlots); err != nil {
+		return fmt.Errorf("failed to read number of slots: %w", err)
+	}
+
+	for count := 0; count < int(numSlots); count++ {
+		slot := ethCommon.Hash{}
+		if n, err := reader.Read(slot[:]); err != nil || n != ethCommon.HashLength {
+			return fmt.Errorf("failed to read slot [%d]: %w", n, err)
+		}
+		ecd.Slots = append(ecd.Slots, slot)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_storage query.
+func (ecd *EthStorageQueryRequest) Validate() error {
+	if len(ecd.BlockId) > math.MaxUint32 {
+		return fmt.Errorf("block id too long")
+	}
+	if !validBlockIdForm(ecd.BlockId) {
+		return fmt.Errorf("block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
+	}
+	if len(ecd.Contract) != EvmContractAddressLength {
+		return fmt.Errorf("invalid length for contract")
+	}
+	if len(ecd.Slots) <= 0 {
+		return fmt.Errorf("does not contain any slots")
+	}
+	if len(ecd.Slots) > math.MaxUint8 {
+		return fmt.Errorf("too many slots")
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_storage queries are equal.
+func (left *EthStorageQueryRequest) Equal(right *EthStorageQueryRequest) bool {
+	if left.BlockId != right.BlockId {
+		return false
+	}
+	if !bytes.Equal(left.Contract, right.Contract) {
+		return false
+	}
+	if len(left.Slots) != len(right.Slots) {
+		return false
+	}
+	for idx := range left.Slots {
+		if left.Slots[idx] != right.Slots[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
 //
 // Implementation of SolanaAccountQueryRequest, which implements the ChainSpecificQuery interface.
 //
@@ -0,0 +1,107 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// RequestEnvironment identifies the environment a query request is intended for. It matches the domain used by QueryRequestDigest,
+// so a requester that declares it allows the guardian to explain why a request signed for a different environment was rejected.
+type RequestEnvironment uint8
+
+const (
+	// UnspecifiedEnvironment means the requester did not declare an environment.
+	UnspecifiedEnvironment RequestEnvironment = 0
+
+	// MainNetEnvironment is a request for mainnet guardians.
+	MainNetEnvironment RequestEnvironment = 1
+
+	// TestNetEnvironment is a request for testnet guardians.
+	TestNetEnvironment RequestEnvironment = 2
+
+	// DevNetEnvironment is a request for any other guardians, such as a local devnet.
+	DevNetEnvironment RequestEnvironment = 3
+)
+
+// RequestEnvironmentFor returns the request environment served by a guardian running in the specified environment.
+func RequestEnvironmentFor(env common.Environment) RequestEnvironment {
+	switch env {
+	case common.MainNet:
+		return MainNetEnvironment
+	case common.TestNet:
+		return TestNetEnvironment
+	default:
+		return DevNetEnvironment
+	}
+}
+
+// Validate verifies that the environment is one that is supported.
+func (e RequestEnvironment) Validate() error {
+	switch e {
+	case UnspecifiedEnvironment, MainNetEnvironment, TestNetEnvironment, DevNetEnvironment:
+		return nil
+	default:
+		return fmt.Errorf("unsupported request environment: %d", e)
+	}
+}
+
+// digestEnvironment returns an environment that produces the same request digest as this one. It must not be called on UnspecifiedEnvironment.
+func (e RequestEnvironment) digestEnvironment() common.Environment {
+	switch e {
+	case MainNetEnvironment:
+		return common.MainNet
+	case TestNetEnvironment:
+		return common.TestNet
+	default:
+		return common.UnsafeDevNet
+	}
+}
+
+// String returns the name of the environment, for logging.
+func (e RequestEnvironment) String() string {
+	switch e {
+	case UnspecifiedEnvironment:
+		return "unspecified"
+	case MainNetEnvironment:
+		return "mainnet"
+	case TestNetEnvironment:
+		return "testnet"
+	case DevNetEnvironment:
+		return "devnet"
+	default:
+		return fmt.Sprintf("unknown(%d)", e)
+	}
+}
+
+// wrongEnvironmentSigner is used when the signer of a request, as recovered for the guardian's environment, is not allowed. If the request
+// declares a different environment, and was signed for that environment by an allowed signer, it returns that signer and environment, and true.
+// In that case the request was not meant for this guardian, rather than being from an unknown signer.
+func wrongEnvironmentSigner(env common.Environment, signedRequest *gossipv1.SignedQueryRequest, allowedRequestors map[ethCommon.Address]struct{}) (ethCommon.Address, RequestEnvironment, bool) {
+	var queryRequest QueryRequest
+	if err := queryRequest.Unmarshal(signedRequest.QueryRequest); err != nil {
+		return ethCommon.Address{}, UnspecifiedEnvironment, false
+	}
+
+	declared := queryRequest.Environment
+	if declared == UnspecifiedEnvironment || declared == RequestEnvironmentFor(env) {
+		return ethCommon.Address{}, UnspecifiedEnvironment, false
+	}
+
+	digest := QueryRequestDigest(declared.digestEnvironment(), signedRequest.QueryRequest)
+	signerBytes, err := ethCrypto.Ecrecover(digest.Bytes(), signedRequest.Signature)
+	if err != nil {
+		return ethCommon.Address{}, UnspecifiedEnvironment, false
+	}
+
+	signer := ethCommon.BytesToAddress(ethCrypto.Keccak256(signerBytes[1:])[12:])
+	if _, exists := allowedRequestors[signer]; !exists {
+		return ethCommon.Address{}, UnspecifiedEnvironment, false
+	}
+
+	return signer, declared, true
+}
@@ -0,0 +1,64 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTooManyPerChainQueries is returned by QueryRequest.ValidateLimits when a request has more per chain queries than the limit.
+	ErrTooManyPerChainQueries = errors.New("too many per chain queries")
+
+	// ErrTooManyCalls is returned by QueryRequest.ValidateLimits when a single per chain query performs more calls than the limit.
+	ErrTooManyCalls = errors.New("too many calls in a per chain query")
+
+	// ErrRequestTooLarge is returned by QueryRequest.ValidateLimits when the serialized request is larger than the limit.
+	ErrRequestTooLarge = errors.New("request too large")
+)
+
+// RequestLimits are limits on the shape of a query request, which let each network tune how much work a single request may ask for.
+// A zero field means there is no limit.
+type RequestLimits struct {
+	// MaxPerChainQueries is the maximum number of per chain queries in a request.
+	MaxPerChainQueries int
+
+	// MaxCallsPerQuery is the maximum number of calls performed by a single per chain query. See PerChainQueryRequest.NumCalls.
+	MaxCallsPerQuery int
+
+	// MaxRequestSize is the maximum size in bytes of the serialized request.
+	MaxRequestSize int
+}
+
+// ValidateLimits verifies that a query request, whose serialized form is requestSize bytes long, is within the limits. The returned error wraps
+// ErrTooManyPerChainQueries, ErrTooManyCalls or ErrRequestTooLarge, with the details of which limit was exceeded.
+func (queryRequest *QueryRequest) ValidateLimits(limits RequestLimits, requestSize int) error {
+	if limits.MaxRequestSize != 0 && requestSize > limits.MaxRequestSize {
+		return fmt.Errorf("%w: request is %d bytes, the maximum is %d", ErrRequestTooLarge, requestSize, limits.MaxRequestSize)
+	}
+
+	if limits.MaxPerChainQueries != 0 && len(queryRequest.PerChainQueries) > limits.MaxPerChainQueries {
+		return fmt.Errorf("%w: request contains %d per chain queries, the maximum is %d", ErrTooManyPerChainQueries, len(queryRequest.PerChainQueries), limits.MaxPerChainQueries)
+	}
+
+	if limits.MaxCallsPerQuery != 0 {
+		for idx, perChainQuery := range queryRequest.PerChainQueries {
+			if numCalls := perChainQuery.NumCalls(); numCalls > limits.MaxCallsPerQuery {
+				return fmt.Errorf("%w: per chain query %d performs %d calls, the maximum is %d", ErrTooManyCalls, idx, numCalls, limits.MaxCallsPerQuery)
+			}
+		}
+	}
+
+	return nil
+}
+
+// limitFailureReason returns the failure reason reported for an error returned by QueryRequest.ValidateLimits.
+func limitFailureReason(err error) FailureReason {
+	switch {
+	case errors.Is(err, ErrTooManyPerChainQueries):
+		return TooManyPerChainQueries
+	case errors.Is(err, ErrRequestTooLarge):
+		return RequestTooLarge
+	default:
+		return TooManyCalls
+	}
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLimits(t *testing.T) {
+	queryRequest := &QueryRequest{
+		PerChainQueries: []*PerChainQueryRequest{
+			createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+			createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		},
+	}
+	requestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	requestSize := len(requestBytes)
+
+	tests := []struct {
+		name        string
+		limits      RequestLimits
+		expectedErr error
+		reason      FailureReason
+	}{
+		{name: "no limits", limits: RequestLimits{}},
+		{name: "at the limits", limits: RequestLimits{MaxPerChainQueries: 2, MaxCallsPerQuery: 3, MaxRequestSize: requestSize}},
+		{name: "too many per chain queries", limits: RequestLimits{MaxPerChainQueries: 1}, expectedErr: ErrTooManyPerChainQueries, reason: TooManyPerChainQueries},
+		{name: "too many calls in one query", limits: RequestLimits{MaxCallsPerQuery: 2}, expectedErr: ErrTooManyCalls, reason: TooManyCalls},
+		{name: "request too large", limits: RequestLimits{MaxRequestSize: requestSize - 1}, expectedErr: ErrRequestTooLarge, reason: RequestTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := queryRequest.ValidateLimits(tc.limits, requestSize)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.reason, limitFailureReason(err))
+		})
+	}
+}
+
+func TestRequestExceedingLimitsIsRejected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{RequestLimits: RequestLimits{MaxCallsPerQuery: 2}})
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, TooManyCalls, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDBSC))
+}
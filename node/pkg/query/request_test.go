@@ -1,12 +1,15 @@
 package query
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/certusone/wormhole/node/pkg/common"
 	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
 	"github.com/wormhole-foundation/wormhole/sdk/vaa"
 
@@ -105,6 +108,281 @@ func TestQueryRequestMarshalUnmarshal(t *testing.T) {
 	assert.True(t, queryRequest.Equal(&queryRequest2))
 }
 
+func TestQueryRequestWithRetryBudgetMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.RetryBudget = 5
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, MSG_VERSION_WITH_OPTIONS, queryRequestBytes[0])
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(5), queryRequest2.RetryBudget)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	// A request without a retry budget should still use the original version.
+	queryRequest.RetryBudget = 0
+	queryRequestBytes, err = queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, MSG_VERSION, queryRequestBytes[0])
+}
+
+func TestQueryRequestUnmarshalWithZeroRetryBudgetShouldFail(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.RetryBudget = 5
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	// The retry budget option follows the version, the nonce, the number of options and the option type.
+	queryRequestBytes[7] = 0
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	assert.EqualError(t, err, "request option 1 may not be zero")
+}
+
+func TestQueryRequestWithResultNormalizationMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.RetryBudget = 5
+	queryRequest.ResultNormalization = PadTo32Bytes
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 2, 1, 5, 2, 2}, queryRequestBytes[:10])
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+	assert.Equal(t, PadTo32Bytes, queryRequest2.ResultNormalization)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	// The normalization is part of the signed bytes, so changing it changes the digest.
+	queryRequest.ResultNormalization = TrimLeadingZeros
+	queryRequestBytes2, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.NotEqual(t, QueryRequestDigest(common.UnsafeDevNet, queryRequestBytes), QueryRequestDigest(common.UnsafeDevNet, queryRequestBytes2))
+}
+
+func TestQueryRequestWithResponseSchemaVersionMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.ResponseSchemaVersion = ResponseSchemaVersion1
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	// Pinning a version is different from not specifying one, so it is carried as an option.
+	assert.Equal(t, MSG_VERSION_WITH_OPTIONS, queryRequestBytes[0])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.Equal(t, ResponseSchemaVersion1, queryRequest2.ResponseSchemaVersion)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest.ResponseSchemaVersion = 3
+	_, err = queryRequest.Marshal()
+	assert.EqualError(t, err, "unsupported response schema version: 3")
+}
+
+func TestQueryRequestWithAllowCachedResultsMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.AllowCachedResults = true
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, MSG_VERSION_WITH_OPTIONS, queryRequestBytes[0])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.True(t, queryRequest2.AllowCachedResults)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.AllowCachedResults = false
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestQueryRequestWithMaxBlockAgeMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.PerChainQueries[1].MaxBlockAge = 30
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, MSG_VERSION_WITH_OPTIONS, queryRequestBytes[0])
+
+	// The max block ages follow the per chain queries.
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 30, 0, 0, 0, 0}, queryRequestBytes[len(queryRequestBytes)-12:])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.Equal(t, uint32(0), queryRequest2.PerChainQueries[0].MaxBlockAge)
+	assert.Equal(t, uint32(30), queryRequest2.PerChainQueries[1].MaxBlockAge)
+	assert.Equal(t, uint32(0), queryRequest2.PerChainQueries[2].MaxBlockAge)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.PerChainQueries[1].MaxBlockAge = 31
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+
+	// The option may not be set unless at least one per chain query has a max block age.
+	queryRequestBytes[len(queryRequestBytes)-5] = 0
+	var queryRequest3 QueryRequest
+	assert.EqualError(t, queryRequest3.Unmarshal(queryRequestBytes), "max block age option is set but no per chain query has a max block age")
+}
+
+func TestQueryRequestWithSlaTierMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.SlaTier = SlaTierFast
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 1, 6, 2}, queryRequestBytes[:8])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.Equal(t, SlaTierFast, queryRequest2.SlaTier)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.SlaTier = SlaTierStandard
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestQueryRequestWithEnvironmentMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.Environment = MainNetEnvironment
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 1, 7, 1}, queryRequestBytes[:8])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.Equal(t, MainNetEnvironment, queryRequest2.Environment)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.Environment = DevNetEnvironment
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestQueryRequestWithHashAlgorithmMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.HashAlgorithm = Sha256HashAlgorithm
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 1, 8, 1}, queryRequestBytes[:8])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.Equal(t, Sha256HashAlgorithm, queryRequest2.HashAlgorithm)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	// The algorithm is covered by the request digest.
+	queryRequest2.HashAlgorithm = Keccak256HashAlgorithm
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+	queryRequestBytes2, err := queryRequest2.Marshal()
+	require.NoError(t, err)
+	assert.NotEqual(t, QueryRequestDigest(common.UnsafeDevNet, queryRequestBytes), QueryRequestDigest(common.UnsafeDevNet, queryRequestBytes2))
+}
+
+func TestQueryRequestWithAllowRevertedCallsMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.AllowRevertedCalls = true
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 1, 9, 1}, queryRequestBytes[:8])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.True(t, queryRequest2.AllowRevertedCalls)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.AllowRevertedCalls = false
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestQueryRequestWithAllowPartialResultsMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.AllowPartialResults = true
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 1, 10, 1}, queryRequestBytes[:8])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.True(t, queryRequest2.AllowPartialResults)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.AllowPartialResults = false
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestQueryRequestWithPreferSpeedMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.PreferSpeed = true
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 1, 12, 1}, queryRequestBytes[:8])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.True(t, queryRequest2.PreferSpeed)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.PreferSpeed = false
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestQueryRequestWithInvalidOptionsShouldFail(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.RetryBudget = 5
+	queryRequest.ResultNormalization = TrimLeadingZeros
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	tests := []struct {
+		label    string
+		options  []byte
+		errorStr string
+	}{
+		{"no options", []byte{0}, "a version 2 request must contain at least one option"},
+		{"out of order", []byte{2, 2, 1, 1, 5}, "request options must be in increasing order of type"},
+		{"duplicate", []byte{2, 1, 5, 1, 5}, "request options must be in increasing order of type"},
+		{"unsupported option", []byte{1, 255, 1}, "unsupported request option: 255"},
+		{"unsupported normalization", []byte{1, 2, 7}, "unmarshaled request failed validation: unsupported result normalization: 7"},
+		{"unsupported hash algorithm", []byte{1, 8, 2}, "unmarshaled request failed validation: unsupported hash algorithm: 2"},
+		{"unsupported schema version", []byte{1, 3, 9}, "unmarshaled request failed validation: unsupported response schema version: 9"},
+		{"invalid allow cached results", []byte{1, 4, 2}, "invalid value for the allow cached results option: 2"},
+		{"invalid allow reverted calls", []byte{1, 9, 2}, "invalid value for the allow reverted calls option: 2"},
+		{"invalid allow partial results", []byte{1, 10, 2}, "invalid value for the allow partial results option: 2"},
+		{"invalid result filters", []byte{1, 11, 2}, "invalid value for the result filters option: 2"},
+		{"invalid max block age", []byte{1, 5, 2}, "invalid value for the max block age option: 2"},
+		{"missing max block ages", []byte{1, 5, 1}, "failed to read max block age: EOF"},
+		{"unsupported sla tier", []byte{1, 6, 3}, "unmarshaled request failed validation: unsupported sla tier: 3"},
+		{"unsupported environment", []byte{1, 7, 4}, "unmarshaled request failed validation: unsupported request environment: 4"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			// Replace the options, which follow the version and the nonce, leaving the per chain queries intact.
+			data := append([]byte{}, queryRequestBytes[:5]...)
+			data = append(data, tc.options...)
+			data = append(data, queryRequestBytes[10:]...)
+
+			var queryRequest2 QueryRequest
+			err := queryRequest2.Unmarshal(data)
+			assert.EqualError(t, err, tc.errorStr)
+		})
+	}
+}
+
+func TestQueryRequestTotalCalls(t *testing.T) {
+	// Three per chain queries with two calls each.
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	assert.Equal(t, 6, queryRequest.TotalCalls())
+
+	// A by timestamp list query performs its calls once per timestamp.
+	queryRequest.PerChainQueries = append(queryRequest.PerChainQueries, &PerChainQueryRequest{
+		ChainId: vaa.ChainIDPolygon,
+		Query: &EthCallByTimestampListQueryRequest{
+			TargetTimestamps: []uint64{1697216322000000, 1697216323000000, 1697216324000000},
+			CallData:         queryRequest.PerChainQueries[0].Query.(*EthCallQueryRequest).CallData,
+		},
+	})
+	assert.Equal(t, 12, queryRequest.TotalCalls())
+}
+
 func TestQueryRequestUnmarshalWithExtraBytesShouldFail(t *testing.T) {
 	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
 	queryRequestBytes, err := queryRequest.Marshal()
@@ -116,6 +394,41 @@ func TestQueryRequestUnmarshalWithExtraBytesShouldFail(t *testing.T) {
 	assert.EqualError(t, err, "excess bytes in unmarshal")
 }
 
+func TestEthCallQueryWithStateChangingFieldsShouldFail(t *testing.T) {
+	to, _ := hex.DecodeString("0d500b1d8e8ef31e21c99d1db9a6444d3adf1270")
+	callRequest := &EthCallQueryRequest{
+		BlockId: "0x28d9630",
+		CallData: []*EthCallData{
+			{
+				To:   to,
+				Data: []byte("CallToSomething"),
+			},
+		},
+	}
+	queryBytes, err := callRequest.Marshal()
+	require.NoError(t, err)
+
+	// Append a value and a nonce to the call, as they would be for a transaction, and fix up the query length to cover them.
+	value := [32]byte{31: 1}
+	nonce := [8]byte{7: 42}
+	queryBytes = append(queryBytes, value[:]...)
+	queryBytes = append(queryBytes, nonce[:]...)
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, MSG_VERSION)
+	vaa.MustWrite(buf, binary.BigEndian, uint32(1))
+	vaa.MustWrite(buf, binary.BigEndian, uint8(1))
+	vaa.MustWrite(buf, binary.BigEndian, vaa.ChainIDPolygon)
+	vaa.MustWrite(buf, binary.BigEndian, EthCallQueryRequestType)
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(queryBytes)))
+	buf.Write(queryBytes)
+
+	var queryRequest QueryRequest
+	err = queryRequest.Unmarshal(buf.Bytes())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "query length mismatch")
+}
+
 func TestMarshalOfQueryRequestWithNoPerChainQueriesShouldFail(t *testing.T) {
 	queryRequest := &QueryRequest{
 		Nonce: 1,
@@ -245,6 +558,93 @@ func TestMarshalOfQueryRequestWithTooManyCallDataEntriesShouldFail(t *testing.T)
 	require.Error(t, err)
 }
 
+func TestEthCallQueryBlockIdForms(t *testing.T) {
+	tests := []struct {
+		blockId    string
+		valid      bool
+		normalized string
+	}{
+		{"0x28d9630", true, "0x28d9630"},
+		{"0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2", true, "0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"},
+		{"d:42833456", true, "0x28d9630"},
+		{"d:0", true, "0x0"},
+		{"d:", false, "d:"},
+		{"d:-1", false, "d:-1"},
+		{"d:0x28d9630", false, "d:0x28d9630"},
+		{"d:18446744073709551616", false, "d:18446744073709551616"},
+		{"42833456", false, "42833456"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.blockId, func(t *testing.T) {
+			assert.Equal(t, tc.normalized, NormalizeBlockId(tc.blockId))
+
+			queryRequest := &QueryRequest{
+				Nonce: 1,
+				PerChainQueries: []*PerChainQueryRequest{
+					{
+						ChainId: vaa.ChainIDPolygon,
+						Query: &EthCallQueryRequest{
+							BlockId:  tc.blockId,
+							CallData: []*EthCallData{{To: make([]byte, EvmContractAddressLength), Data: []byte("This can't be zero length")}},
+						},
+					},
+				},
+			}
+			_, err := queryRequest.Marshal()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, "block id must be a hex number or hash starting with 0x, or a decimal number starting with d:")
+			}
+		})
+	}
+}
+
+func TestEthCallQueryFinality(t *testing.T) {
+	tests := []struct {
+		name     string
+		blockId  string
+		finality string
+		errText  string
+	}{
+		{name: "latest", finality: "latest"},
+		{name: "safe", finality: "safe"},
+		{name: "finalized", finality: "finalized"},
+		{name: "unknown finality", finality: "pending", errText: `finality must be "latest", "safe" or "finalized", is "pending"`},
+		{name: "block id and finality", blockId: "0x28d9630", finality: "finalized", errText: "block id and finality may not both be set"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			queryRequest := &QueryRequest{
+				Nonce: 1,
+				PerChainQueries: []*PerChainQueryRequest{
+					{
+						ChainId: vaa.ChainIDPolygon,
+						Query: &EthCallQueryRequest{
+							BlockId:  tc.blockId,
+							Finality: tc.finality,
+							CallData: []*EthCallData{{To: make([]byte, EvmContractAddressLength), Data: []byte("This can't be zero length")}},
+						},
+					},
+				},
+			}
+			queryRequestBytes, err := queryRequest.Marshal()
+			if tc.errText != "" {
+				assert.ErrorContains(t, err, tc.errText)
+				return
+			}
+			require.NoError(t, err)
+
+			var queryRequest2 QueryRequest
+			require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+			assert.True(t, queryRequest.Equal(&queryRequest2))
+			assert.Equal(t, tc.finality, queryRequest2.PerChainQueries[0].Query.(*EthCallQueryRequest).Finality)
+		})
+	}
+}
+
 func TestMarshalOfEthCallQueryWithNilToShouldFail(t *testing.T) {
 	perChainQuery := &PerChainQueryRequest{
 		ChainId: vaa.ChainIDPolygon,
@@ -726,6 +1126,444 @@ func TestMarshalOfEthCallWithFinalityQueryWithSafeShouldSucceed(t *testing.T) {
 	require.NoError(t, err)
 }
 
+///////////// Eth Call With Precondition Query tests /////////////////////
+
+func createEthCallWithPreconditionQueryRequestForTesting(t *testing.T) *QueryRequest {
+	t.Helper()
+	to, err := hex.DecodeString("0d500b1d8e8ef31e21c99d1db9a6444d3adf1270")
+	require.NoError(t, err)
+
+	perChainQuery := &PerChainQueryRequest{
+		ChainId: vaa.ChainIDPolygon,
+		Query: &EthCallWithPreconditionQueryRequest{
+			BlockId:        "0x28d9630",
+			ExpectedResult: ethCommon.LeftPadBytes([]byte{0x01}, 32),
+			CallData: []*EthCallData{
+				{
+					To:   to,
+					Data: []byte("precondition"),
+				},
+				{
+					To:   to,
+					Data: []byte("dependent"),
+				},
+			},
+		},
+	}
+
+	return &QueryRequest{
+		Nonce:           1,
+		PerChainQueries: []*PerChainQueryRequest{perChainQuery},
+	}
+}
+
+func TestEthCallWithPreconditionQueryRequestMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthCallWithPreconditionQueryRequestForTesting(t)
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestMarshalOfEthCallWithPreconditionQueryWithOnlyOneCallShouldFail(t *testing.T) {
+	queryRequest := createEthCallWithPreconditionQueryRequestForTesting(t)
+	req := queryRequest.PerChainQueries[0].Query.(*EthCallWithPreconditionQueryRequest)
+	req.CallData = req.CallData[:1]
+
+	_, err := queryRequest.Marshal()
+	require.Error(t, err)
+}
+
+///////////// End of Eth Call With Precondition Query tests //////////////
+
+///////////// Eth Call By Timestamp List Query tests /////////////////////
+
+func createEthCallByTimestampListQueryRequestForTesting(t *testing.T, timestamps []uint64) *QueryRequest {
+	t.Helper()
+	to, err := hex.DecodeString("0d500b1d8e8ef31e21c99d1db9a6444d3adf1270")
+	require.NoError(t, err)
+
+	perChainQuery := &PerChainQueryRequest{
+		ChainId: vaa.ChainIDPolygon,
+		Query: &EthCallByTimestampListQueryRequest{
+			TargetTimestamps: timestamps,
+			CallData: []*EthCallData{
+				{
+					To:   to,
+					Data: []byte("This can't be zero length"),
+				},
+			},
+		},
+	}
+
+	return &QueryRequest{
+		Nonce:           1,
+		PerChainQueries: []*PerChainQueryRequest{perChainQuery},
+	}
+}
+
+func TestEthCallByTimestampListQueryRequestMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthCallByTimestampListQueryRequestForTesting(t, []uint64{1700000000000000, 1700000060000000, 1700000120000000})
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestMarshalOfEthCallByTimestampListQueryWithTooManyTimestampsShouldFail(t *testing.T) {
+	timestamps := []uint64{}
+	for count := 0; count <= EthCallByTimestampListMaxTimestamps; count++ {
+		timestamps = append(timestamps, uint64(1700000000000000+count))
+	}
+	queryRequest := createEthCallByTimestampListQueryRequestForTesting(t, timestamps)
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "too many timestamps")
+}
+
+func TestMarshalOfEthCallByTimestampListQueryWithUnorderedTimestampsShouldFail(t *testing.T) {
+	queryRequest := createEthCallByTimestampListQueryRequestForTesting(t, []uint64{1700000060000000, 1700000000000000})
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "strictly increasing")
+}
+
+///////////// End of Eth Call By Timestamp List Query tests //////////////
+
+///////////// Eth Tx Proof Query tests ///////////////////////////////////
+
+func createEthTxProofQueryRequestForTesting(t *testing.T, txHash ethCommon.Hash) *QueryRequest {
+	t.Helper()
+	return &QueryRequest{
+		Nonce: 1,
+		PerChainQueries: []*PerChainQueryRequest{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Query:   &EthTxProofQueryRequest{TxHash: txHash},
+			},
+		},
+	}
+}
+
+func TestEthTxProofQueryRequestMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthTxProofQueryRequestForTesting(t, ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"))
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestMarshalOfEthTxProofQueryWithZeroTxHashShouldFail(t *testing.T) {
+	queryRequest := createEthTxProofQueryRequestForTesting(t, ethCommon.Hash{})
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "tx hash may not be zero")
+}
+
+///////////// End of Eth Tx Proof Query tests ////////////////////////////
+
+///////////// Eth Storage Diff Query tests ///////////////////////////////
+
+func createEthStorageDiffQueryRequestForTesting(t *testing.T, fromBlockId string, toBlockId string) *QueryRequest {
+	t.Helper()
+	return &QueryRequest{
+		Nonce: 1,
+		PerChainQueries: []*PerChainQueryRequest{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Query: &EthStorageDiffQueryRequest{
+					FromBlockId: fromBlockId,
+					ToBlockId:   toBlockId,
+					Contract:    ethCommon.HexToAddress("0x0d500b1d8e8ef31e21c99d1db9a6444d3adf1270").Bytes(),
+					Slots:       []ethCommon.Hash{ethCommon.HexToHash("0x00"), ethCommon.HexToHash("0x01"), ethCommon.HexToHash("0x02")},
+				},
+			},
+		},
+	}
+}
+
+func TestEthStorageDiffQueryRequestMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthStorageDiffQueryRequestForTesting(t, "0x28d9630", "0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestMarshalOfEthStorageDiffQueryWithInvalidBlockIdShouldFail(t *testing.T) {
+	queryRequest := createEthStorageDiffQueryRequestForTesting(t, "0x28d9630", "latest")
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "to block id must be a hex number or hash starting with 0x")
+}
+
+func TestMarshalOfEthStorageDiffQueryWithNoSlotsShouldFail(t *testing.T) {
+	queryRequest := createEthStorageDiffQueryRequestForTesting(t, "0x28d9630", "0x28d9631")
+	queryRequest.PerChainQueries[0].Query.(*EthStorageDiffQueryRequest).Slots = nil
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "does not contain any slots")
+}
+
+///////////// End of Eth Storage Diff Query tests ////////////////////////
+
+///////////// Eth Block Probe Query tests ////////////////////////////////
+
+func createEthBlockProbeQueryRequestForTesting(t *testing.T, blockTag string) *QueryRequest {
+	t.Helper()
+	return &QueryRequest{
+		Nonce: 1,
+		PerChainQueries: []*PerChainQueryRequest{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Query:   &EthBlockProbeQueryRequest{BlockTag: blockTag},
+			},
+		},
+	}
+}
+
+func TestEthBlockProbeQueryRequestMarshalUnmarshal(t *testing.T) {
+	for _, blockTag := range []string{"latest", "safe", "finalized"} {
+		queryRequest := createEthBlockProbeQueryRequestForTesting(t, blockTag)
+		queryRequestBytes, err := queryRequest.Marshal()
+		require.NoError(t, err)
+
+		var queryRequest2 QueryRequest
+		err = queryRequest2.Unmarshal(queryRequestBytes)
+		require.NoError(t, err)
+
+		assert.True(t, queryRequest.Equal(&queryRequest2))
+	}
+}
+
+func TestMarshalOfEthBlockProbeQueryWithInvalidBlockTagShouldFail(t *testing.T) {
+	for _, blockTag := range []string{"", "pending", "0x28d9630"} {
+		queryRequest := createEthBlockProbeQueryRequestForTesting(t, blockTag)
+		_, err := queryRequest.Marshal()
+		require.ErrorContains(t, err, "block tag must be", blockTag)
+	}
+}
+
+///////////// End of Eth Block Probe Query tests /////////////////////////
+
+///////////// Eth Logs Query tests ///////////////////////////////////////
+
+func createEthLogsQueryRequestForTesting(t *testing.T, fromBlock string, toBlock string) *QueryRequest {
+	t.Helper()
+	address, err := vaa.StringToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	require.NoError(t, err)
+	transferTopic := ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	return &QueryRequest{
+		Nonce: 1,
+		PerChainQueries: []*PerChainQueryRequest{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Query: &EthLogsQueryRequest{
+					FromBlock: fromBlock,
+					ToBlock:   toBlock,
+					Address:   address.Bytes()[12:],
+					Topics:    [][]byte{transferTopic.Bytes(), {}, ethCommon.LeftPadBytes([]byte{0x42}, 32)},
+				},
+			},
+		},
+	}
+}
+
+func TestEthLogsQueryRequestMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "d:42833500")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestEthLogsQueryRequestWithMaxWidenedSpanMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "0x28d9640")
+	queryRequest.PerChainQueries[0].Query.(*EthLogsQueryRequest).MaxWidenedSpan = 100
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+	assert.Equal(t, uint32(100), queryRequest2.PerChainQueries[0].Query.(*EthLogsQueryRequest).MaxWidenedSpan)
+}
+
+func TestEthLogsQueryRequestWithMinResultsMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "0x28d9640")
+	req := queryRequest.PerChainQueries[0].Query.(*EthLogsQueryRequest)
+	req.MinResults = 5
+	req.FailBelowMinResults = true
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+	req2 := queryRequest2.PerChainQueries[0].Query.(*EthLogsQueryRequest)
+	assert.Equal(t, uint32(5), req2.MinResults)
+	assert.True(t, req2.FailBelowMinResults)
+}
+
+func TestEthLogsQueryRequestWithResultFilterMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "0x28d9640")
+	queryRequest.PerChainQueries[0].MaxBlockAge = 30
+	queryRequest.PerChainQueries[0].ResultFilter = "data0 == 0x42"
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{MSG_VERSION_WITH_OPTIONS, 0, 0, 0, 1, 2, 5, 1, 11, 1}, queryRequestBytes[:10])
+
+	// The result filters follow the max block ages.
+	assert.Equal(t, append([]byte{0, 0, 0, 30, 13}, "data0 == 0x42"...), queryRequestBytes[len(queryRequestBytes)-18:])
+
+	var queryRequest2 QueryRequest
+	require.NoError(t, queryRequest2.Unmarshal(queryRequestBytes))
+	assert.Equal(t, "data0 == 0x42", queryRequest2.PerChainQueries[0].ResultFilter)
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+
+	queryRequest2.PerChainQueries[0].ResultFilter = "data0 == 0x43"
+	assert.False(t, queryRequest.Equal(&queryRequest2))
+
+	// The option may not be set unless at least one per chain query has a result filter.
+	queryRequestBytes = append(queryRequestBytes[:len(queryRequestBytes)-14], 0)
+	var queryRequest3 QueryRequest
+	assert.EqualError(t, queryRequest3.Unmarshal(queryRequestBytes), "result filters option is set but no per chain query has a result filter")
+}
+
+func TestQueryRequestWithInvalidResultFilterShouldFail(t *testing.T) {
+	queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "0x28d9640")
+	queryRequest.PerChainQueries[0].ResultFilter = "topic4 == 0x42"
+	_, err := queryRequest.Marshal()
+	assert.ErrorContains(t, err, `invalid index in result filter field "topic4"`)
+
+	// Only logs can be filtered.
+	queryRequest = createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.PerChainQueries[0].ResultFilter = "topic0 == 0x42"
+	_, err = queryRequest.Marshal()
+	assert.ErrorContains(t, err, "result filters are only supported on eth_logs queries")
+}
+
+func TestMarshalOfInvalidEthLogsQueryShouldFail(t *testing.T) {
+	tests := []struct {
+		label    string
+		modify   func(req *EthLogsQueryRequest)
+		errorStr string
+	}{
+		{"block hash", func(req *EthLogsQueryRequest) {
+			req.FromBlock = "0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"
+		}, "invalid from block"},
+		{"block tag", func(req *EthLogsQueryRequest) { req.ToBlock = "latest" }, "invalid to block"},
+		{"to before from", func(req *EthLogsQueryRequest) { req.ToBlock = "0x28d962f" }, "to block may not be before from block"},
+		{"bad address", func(req *EthLogsQueryRequest) { req.Address = req.Address[1:] }, "invalid length for address"},
+		{"bad topic", func(req *EthLogsQueryRequest) { req.Topics[2] = []byte{0x42} }, "invalid length for topic 2"},
+		{"too many topics", func(req *EthLogsQueryRequest) { req.Topics = append(req.Topics, []byte{}, []byte{}) }, "too many topics"},
+		{"widened span not wider", func(req *EthLogsQueryRequest) { req.MaxWidenedSpan = 17 }, "max widened span must be larger than the range"},
+		{"widened span too large", func(req *EthLogsQueryRequest) { req.MaxWidenedSpan = MaxEthLogsBlockRange + 1 }, "max widened span may not exceed"},
+		{"fail without min results", func(req *EthLogsQueryRequest) { req.FailBelowMinResults = true }, "fail below min results requires min results"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "0x28d9640")
+			tc.modify(queryRequest.PerChainQueries[0].Query.(*EthLogsQueryRequest))
+			_, err := queryRequest.Marshal()
+			require.ErrorContains(t, err, tc.errorStr)
+		})
+	}
+}
+
+func TestParseBlockNumber(t *testing.T) {
+	blockNum, err := ParseBlockNumber("0x28d9630")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x28d9630), blockNum)
+
+	blockNum, err = ParseBlockNumber("d:42833456")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42833456), blockNum)
+
+	for _, blockId := range []string{"", "0x", "latest", "28d9630", "0xzz", "0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"} {
+		_, err := ParseBlockNumber(blockId)
+		assert.Error(t, err, blockId)
+	}
+}
+
+///////////// End of Eth Logs Query tests ////////////////////////////////
+
+///////////// Eth Storage Query tests ////////////////////////////////////
+
+func createEthStorageQueryRequestForTesting(t *testing.T, blockId string) *QueryRequest {
+	t.Helper()
+	return &QueryRequest{
+		Nonce: 1,
+		PerChainQueries: []*PerChainQueryRequest{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Query: &EthStorageQueryRequest{
+					BlockId:  blockId,
+					Contract: ethCommon.HexToAddress("0x0d500b1d8e8ef31e21c99d1db9a6444d3adf1270").Bytes(),
+					Slots:    []ethCommon.Hash{ethCommon.HexToHash("0x00"), ethCommon.HexToHash("0x01"), ethCommon.HexToHash("0x02")},
+				},
+			},
+		},
+	}
+}
+
+func TestEthStorageQueryRequestMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthStorageQueryRequestForTesting(t, "0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	var queryRequest2 QueryRequest
+	err = queryRequest2.Unmarshal(queryRequestBytes)
+	require.NoError(t, err)
+
+	assert.True(t, queryRequest.Equal(&queryRequest2))
+}
+
+func TestMarshalOfEthStorageQueryWithInvalidBlockIdShouldFail(t *testing.T) {
+	queryRequest := createEthStorageQueryRequestForTesting(t, "latest")
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "block id must be a hex number or hash starting with 0x")
+}
+
+func TestMarshalOfEthStorageQueryWithNoSlotsShouldFail(t *testing.T) {
+	queryRequest := createEthStorageQueryRequestForTesting(t, "0x28d9630")
+	queryRequest.PerChainQueries[0].Query.(*EthStorageQueryRequest).Slots = nil
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "does not contain any slots")
+}
+
+func TestMarshalOfEthStorageQueryWithTooManySlotsShouldFail(t *testing.T) {
+	queryRequest := createEthStorageQueryRequestForTesting(t, "0x28d9630")
+	query := queryRequest.PerChainQueries[0].Query.(*EthStorageQueryRequest)
+	for count := 0; count < 300; count++ {
+		query.Slots = append(query.Slots, ethCommon.Hash{})
+	}
+	_, err := queryRequest.Marshal()
+	require.ErrorContains(t, err, "too many slots")
+}
+
+///////////// End of Eth Storage Query tests /////////////////////////////
+
 ///////////// Solana Account Query tests /////////////////////////////////
 
 func createSolanaAccountQueryRequestForTesting(t *testing.T) *QueryRequest {
@@ -0,0 +1,101 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// RequestTimeouts is the time allowed for the per chain queries on each chain to be answered, measured from when the request is received.
+// Chains that are not listed use the global request timeout.
+type RequestTimeouts map[vaa.ChainID]time.Duration
+
+// forChain returns the timeout for a chain, or the default if the chain is not listed. It may be called on a nil object.
+func (t RequestTimeouts) forChain(chainID vaa.ChainID, defaultTimeout time.Duration) time.Duration {
+	if timeout, exists := t[chainID]; exists {
+		return timeout
+	}
+	return defaultTimeout
+}
+
+// ParseRequestTimeouts parses a comma separated list of "chain:duration" entries, such as "ethereum:2m,solana:30s". The chain is the chain name
+// and the duration is a positive Go duration. An empty string returns nil, meaning every chain uses the global request timeout.
+func ParseRequestTimeouts(str string) (RequestTimeouts, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	timeouts := make(RequestTimeouts)
+	for _, entry := range strings.Split(str, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`invalid request timeout "%s", must be "chain:duration"`, entry)
+		}
+
+		chainID, err := vaa.ChainIDFromString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid chain in request timeout "%s": %w`, entry, err)
+		}
+
+		if _, exists := timeouts[chainID]; exists {
+			return nil, fmt.Errorf(`duplicate chain in request timeout "%s"`, entry)
+		}
+
+		timeout, err := time.ParseDuration(fields[1])
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf(`invalid duration in request timeout "%s", must be a positive duration`, entry)
+		}
+
+		timeouts[chainID] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// requestTimeoutPolicy determines the timeout of each per chain query. See HandlerConfig.RequestTimeouts and HandlerConfig.RequestTimeoutPerCall.
+type requestTimeoutPolicy struct {
+	defaultTimeout time.Duration
+	chainTimeouts  RequestTimeouts
+	perCall        time.Duration
+	maxScaled      time.Duration
+}
+
+// forQuery returns the timeout for a per chain query. It is the timeout for its chain, extended by perCall for every call after the first,
+// up to maxScaled.
+func (p requestTimeoutPolicy) forQuery(req *PerChainQueryRequest) time.Duration {
+	timeout := p.chainTimeouts.forChain(req.ChainId, p.defaultTimeout)
+	if p.perCall == 0 {
+		return timeout
+	}
+
+	numCalls := req.NumCalls()
+	if numCalls <= 1 || timeout >= p.maxScaled {
+		return timeout
+	}
+	if int64(numCalls-1) >= int64(p.maxScaled-timeout)/int64(p.perCall) {
+		return p.maxScaled
+	}
+	return timeout + time.Duration(numCalls-1)*p.perCall
+}
+
+// timedOut returns true if the request should be dropped. While it is waiting for responses, that is once any per chain query that has not been
+// answered has passed its timeout. Once the response has been assembled, publishing it is retried until the longest timeout of its per chain
+// queries has passed.
+func (pq *pendingQuery) timedOut(now time.Time, timeouts requestTimeoutPolicy) bool {
+	var longest time.Duration
+	for requestIdx, pcq := range pq.queries {
+		timeout := timeouts.forQuery(pcq.req.Request)
+		if pq.respPub == nil && pq.isPending(requestIdx) && pq.receiveTime.Add(timeout).Before(now) {
+			return true
+		}
+		if timeout > longest {
+			longest = timeout
+		}
+	}
+	if longest == 0 {
+		longest = timeouts.defaultTimeout
+	}
+	return pq.receiveTime.Add(longest).Before(now)
+}
@@ -0,0 +1,152 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequestTimeouts(t *testing.T) {
+	timeouts, err := ParseRequestTimeouts("ethereum:2m, solana:30s")
+	require.NoError(t, err)
+	assert.Equal(t, RequestTimeouts{vaa.ChainIDEthereum: 2 * time.Minute, vaa.ChainIDSolana: 30 * time.Second}, timeouts)
+	assert.Equal(t, 2*time.Minute, timeouts.forChain(vaa.ChainIDEthereum, RequestTimeout))
+	assert.Equal(t, RequestTimeout, timeouts.forChain(vaa.ChainIDPolygon, RequestTimeout))
+
+	timeouts, err = ParseRequestTimeouts("")
+	require.NoError(t, err)
+	assert.Nil(t, timeouts)
+	assert.Equal(t, RequestTimeout, timeouts.forChain(vaa.ChainIDPolygon, RequestTimeout))
+}
+
+func TestParseRequestTimeoutsInvalidEntries(t *testing.T) {
+	for _, str := range []string{"ethereum", "ethereum:1m:2", "notAChain:1m", "ethereum:notADuration", "ethereum:10", "ethereum:-1s", "ethereum:0s", "ethereum:1m,ethereum:2m"} {
+		_, err := ParseRequestTimeouts(str)
+		assert.Error(t, err, str)
+	}
+}
+
+// submitSlowChainRequestForTest submits a request for a fast chain (Polygon) and a slow chain (BSC) with the given number of calls, where the
+// watcher for the given chain never responds, so the test can provide its response itself. It returns the request ID used by the handler.
+func submitSlowChainRequestForTest(t *testing.T, md *mockData, silentChain vaa.ChainID, numBscCalls int) (string, []PerChainQueryResponse) {
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", numBscCalls),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.setRetries(silentChain, ignoreAllQueries)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.Eventually(t, func() bool {
+		return md.getRequestsPerChain(vaa.ChainIDPolygon) > 0 && md.getRequestsPerChain(vaa.ChainIDBSC) > 0
+	}, requestTimeoutForTest/2, pollIntervalForTest)
+
+	requestID := hex.EncodeToString(signedQueryRequest.Signature) + ":" + QueryRequestDigest(common.GoTest, signedQueryRequest.QueryRequest).String()
+	return requestID, expectedResults
+}
+
+func TestSlowChainIsAnsweredAfterGlobalTimeoutWithinItsOwnTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		RequestTimeouts: RequestTimeouts{vaa.ChainIDBSC: 4 * requestTimeoutForTest},
+	})
+
+	// Polygon answers straight away, but BSC only answers once the global timeout has passed.
+	requestID, expectedResults := submitSlowChainRequestForTest(t, md, vaa.ChainIDBSC, 3)
+	time.Sleep(2 * requestTimeoutForTest)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 1, vaa.ChainIDBSC, QuerySuccess, expectedResults[1].Response)
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	require.Len(t, queryResponsePublication.PerChainResponses, 2)
+	assert.True(t, queryResponsePublication.PerChainResponses[1].Equal(&expectedResults[1]))
+}
+
+func TestFastChainStillTimesOutWhenSlowChainHasLongerTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		RequestTimeouts: RequestTimeouts{vaa.ChainIDBSC: 4 * requestTimeoutForTest},
+	})
+
+	// BSC answers straight away, but Polygon, which uses the global timeout, does not answer until after it has passed.
+	requestID, expectedResults := submitSlowChainRequestForTest(t, md, vaa.ChainIDPolygon, 3)
+	time.Sleep(2 * requestTimeoutForTest)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 0, vaa.ChainIDPolygon, QuerySuccess, expectedResults[0].Response)
+
+	assert.Nil(t, md.waitForResponse())
+}
+
+func TestRequestTimeoutIsScaledByNumberOfCalls(t *testing.T) {
+	policy := requestTimeoutPolicy{
+		defaultTimeout: time.Minute,
+		chainTimeouts:  RequestTimeouts{vaa.ChainIDEthereum: 3 * time.Minute},
+		perCall:        time.Second,
+		maxScaled:      2 * time.Minute,
+	}
+
+	assert.Equal(t, time.Minute, policy.forQuery(createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 1)))
+	assert.Equal(t, time.Minute+9*time.Second, policy.forQuery(createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 10)))
+	assert.Equal(t, 2*time.Minute, policy.forQuery(createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 200)))
+
+	// The cap never reduces the timeout configured for the chain.
+	assert.Equal(t, 3*time.Minute, policy.forQuery(createPerChainQueryForEthCall(t, vaa.ChainIDEthereum, "0x28d9630", 200)))
+
+	// Scaling is disabled by default.
+	policy.perCall = 0
+	assert.Equal(t, time.Minute, policy.forQuery(createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 200)))
+}
+
+func TestRequestWithManyCallsIsAnsweredWithinScaledTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		RequestTimeoutPerCall:   requestTimeoutForTest / 4,
+		MaxScaledRequestTimeout: 4 * requestTimeoutForTest,
+	})
+
+	// BSC has enough calls to be given the max scaled timeout, and only answers once the base timeout has passed.
+	requestID, expectedResults := submitSlowChainRequestForTest(t, md, vaa.ChainIDBSC, 20)
+	time.Sleep(2 * requestTimeoutForTest)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 1, vaa.ChainIDBSC, QuerySuccess, expectedResults[1].Response)
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	require.Len(t, queryResponsePublication.PerChainResponses, 2)
+	assert.True(t, queryResponsePublication.PerChainResponses[1].Equal(&expectedResults[1]))
+}
+
+func TestRequestWithFewCallsTimesOutWithoutScaling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{
+		RequestTimeoutPerCall:   requestTimeoutForTest / 4,
+		MaxScaledRequestTimeout: 4 * requestTimeoutForTest,
+	})
+
+	// With a single call, BSC only gets the base timeout, which has passed by the time it answers.
+	requestID, expectedResults := submitSlowChainRequestForTest(t, md, vaa.ChainIDBSC, 1)
+	time.Sleep(2 * requestTimeoutForTest)
+	md.queryResponseWriteC <- CreatePerChainQueryResponseInternal(requestID, 1, vaa.ChainIDBSC, QuerySuccess, expectedResults[1].Response)
+
+	assert.Nil(t, md.waitForResponse())
+}
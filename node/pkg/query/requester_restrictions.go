@@ -0,0 +1,115 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"golang.org/x/time/rate"
+)
+
+// requesterRestriction limits what an allowed requester may do. See parseAllowedRequesters.
+type requesterRestriction struct {
+	// limiter, if set, is a token bucket holding enough tokens for one second of requests, so short bursts are allowed.
+	limiter *rate.Limiter
+
+	// queryTypes, if set, are the only query types the requester may use.
+	queryTypes map[ChainSpecificQueryType]struct{}
+}
+
+// requesterRestrictions holds the restrictions of each allowed requester that has any in the allow list. Requesters that are not listed
+// are not restricted. It is only accessed from the query handler routine.
+type requesterRestrictions map[ethCommon.Address]*requesterRestriction
+
+// allowedRequestersUpdate is a parsed allow list that replaces the current one while the handler is running. See QueryHandler.UpdateAllowedRequesters.
+type allowedRequestersUpdate struct {
+	allowedRequestors map[ethCommon.Address]struct{}
+	restrictions      requesterRestrictions
+}
+
+// parseRequesterRestriction parses the fields that follow the address in an allow list entry. Each one is either a requests per second
+// limit, such as "10", or the query types the requester may use, such as "types=1/11". It returns nil if there are no fields.
+func parseRequesterRestriction(fields []string) (*requesterRestriction, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	restriction := &requesterRestriction{}
+	for _, field := range fields {
+		if typesStr, found := strings.CutPrefix(field, "types="); found {
+			if restriction.queryTypes != nil {
+				return nil, fmt.Errorf("query types may only be specified once")
+			}
+			queryTypes, err := parsePermittedQueryTypes(typesStr)
+			if err != nil {
+				return nil, err
+			}
+			restriction.queryTypes = queryTypes
+			continue
+		}
+
+		if restriction.limiter != nil {
+			return nil, fmt.Errorf("rate may only be specified once")
+		}
+		rps, err := parseRequesterRate(field)
+		if err != nil {
+			return nil, err
+		}
+		restriction.limiter = newRequesterLimiter(rps)
+	}
+
+	return restriction, nil
+}
+
+// parsePermittedQueryTypes parses a slash separated list of numeric query types, such as "1/11".
+func parsePermittedQueryTypes(str string) (map[ChainSpecificQueryType]struct{}, error) {
+	queryTypes := make(map[ChainSpecificQueryType]struct{})
+	for _, qtStr := range strings.Split(str, "/") {
+		qt, err := strconv.ParseUint(qtStr, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid query type "%s"`, qtStr)
+		}
+		queryType := ChainSpecificQueryType(qt)
+		if err := ValidatePerChainQueryRequestType(queryType); err != nil {
+			return nil, err
+		}
+		queryTypes[queryType] = struct{}{}
+	}
+	return queryTypes, nil
+}
+
+// parseRequesterRate parses the requests per second limit of an allow list entry, which must be a positive number.
+func parseRequesterRate(str string) (float64, error) {
+	rps, err := strconv.ParseFloat(str, 64)
+	if err != nil || rps <= 0 || math.IsInf(rps, 0) {
+		return 0, fmt.Errorf("rate must be a positive number of requests per second")
+	}
+	return rps, nil
+}
+
+// newRequesterLimiter creates the token bucket for a requester allowed the specified number of requests per second.
+func newRequesterLimiter(rps float64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(rps), int(math.Ceil(rps)))
+}
+
+// allow takes a token from the bucket of the requester, returning false if it is empty. Requesters without a rate are always allowed.
+func (r requesterRestrictions) allow(signer ethCommon.Address, now time.Time) bool {
+	restriction, exists := r[signer]
+	if !exists || restriction.limiter == nil {
+		return true
+	}
+	return restriction.limiter.AllowN(now, 1)
+}
+
+// permitsQueryType returns false if the requester is restricted to other query types.
+func (r requesterRestrictions) permitsQueryType(signer ethCommon.Address, queryType ChainSpecificQueryType) bool {
+	restriction, exists := r[signer]
+	if !exists || restriction.queryTypes == nil {
+		return true
+	}
+	_, permitted := restriction.queryTypes[queryType]
+	return permitted
+}
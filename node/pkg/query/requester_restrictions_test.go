@@ -0,0 +1,191 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowedRequestersWithMixedRates(t *testing.T) {
+	rated := ethCommon.HexToAddress("0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe")
+	unrated := ethCommon.HexToAddress("0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBf")
+	fractional := ethCommon.HexToAddress("0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FC0")
+
+	allowedRequestors, restrictions, err := parseAllowedRequesters(rated.Hex() + ":10," + unrated.Hex() + "," + fractional.Hex() + ":0.5")
+	require.NoError(t, err)
+	assert.Equal(t, map[ethCommon.Address]struct{}{rated: {}, unrated: {}, fractional: {}}, allowedRequestors)
+
+	require.Len(t, restrictions, 2)
+	assert.Equal(t, rate.Limit(10), restrictions[rated].limiter.Limit())
+	assert.Equal(t, 10, restrictions[rated].limiter.Burst())
+	assert.Equal(t, rate.Limit(0.5), restrictions[fractional].limiter.Limit())
+	assert.Equal(t, 1, restrictions[fractional].limiter.Burst())
+	assert.NotContains(t, restrictions, unrated)
+
+	// Plain addresses are not restricted.
+	_, restrictions, err = parseAllowedRequesters(rated.Hex() + "," + unrated.Hex())
+	require.NoError(t, err)
+	assert.Empty(t, restrictions)
+}
+
+func TestParseAllowedRequestersWithQueryTypes(t *testing.T) {
+	callsOnly := ethCommon.HexToAddress("0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe")
+	ratedCallsAndLogs := ethCommon.HexToAddress("0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBf")
+
+	_, restrictions, err := parseAllowedRequesters(callsOnly.Hex() + ":types=1," + ratedCallsAndLogs.Hex() + ":types=1/11:5")
+	require.NoError(t, err)
+
+	assert.Nil(t, restrictions[callsOnly].limiter)
+	assert.Equal(t, map[ChainSpecificQueryType]struct{}{EthCallQueryRequestType: {}}, restrictions[callsOnly].queryTypes)
+	assert.Equal(t, rate.Limit(5), restrictions[ratedCallsAndLogs].limiter.Limit())
+	assert.Equal(t, map[ChainSpecificQueryType]struct{}{EthCallQueryRequestType: {}, EthLogsQueryRequestType: {}}, restrictions[ratedCallsAndLogs].queryTypes)
+
+	assert.True(t, restrictions.permitsQueryType(callsOnly, EthCallQueryRequestType))
+	assert.False(t, restrictions.permitsQueryType(callsOnly, EthLogsQueryRequestType))
+	assert.True(t, restrictions.permitsQueryType(ratedCallsAndLogs, EthLogsQueryRequestType))
+
+	// Requesters without restrictions may use any query type.
+	assert.True(t, restrictions.permitsQueryType(ethCommon.HexToAddress("0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FC0"), EthLogsQueryRequestType))
+}
+
+func TestParseAllowedRequestersFailsIfInvalidRate(t *testing.T) {
+	for _, str := range []string{
+		testSigner + ":",
+		testSigner + ":0",
+		testSigner + ":-1",
+		testSigner + ":fast",
+		testSigner + ":+Inf",
+		testSigner + ":10:20",
+		testSigner + ":types=",
+		testSigner + ":types=1/",
+		testSigner + ":types=255",
+		testSigner + ":types=1:types=2",
+		":10",
+	} {
+		allowedRequestors, restrictions, err := parseAllowedRequesters(str)
+		assert.Error(t, err, str)
+		assert.Nil(t, allowedRequestors, str)
+		assert.Nil(t, restrictions, str)
+	}
+}
+
+func TestRequesterRateLimitAllowsBurstThenRefills(t *testing.T) {
+	signer := ethCommon.HexToAddress(testSigner)
+	_, restrictions, err := parseAllowedRequesters(testSigner + ":2")
+	require.NoError(t, err)
+
+	now := time.Now()
+	assert.True(t, restrictions.allow(signer, now))
+	assert.True(t, restrictions.allow(signer, now))
+	assert.False(t, restrictions.allow(signer, now))
+
+	// A token is added every half second.
+	assert.True(t, restrictions.allow(signer, now.Add(500*time.Millisecond)))
+
+	// Signers without a limit are always allowed.
+	var noRestrictions requesterRestrictions
+	assert.True(t, noRestrictions.allow(signer, now))
+}
+
+func TestRequestOverRateLimitIsDropped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	_, restrictions, err := parseAllowedRequesters(testSigner + ":1")
+	require.NoError(t, err)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{requesterRestrictions: restrictions})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+
+	// The bucket holds a single token, so a second request straight away should be dropped without being retried.
+	md.resetState()
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, RateLimited, failure.Reason)
+	assert.Equal(t, ethCommon.HexToAddress(testSigner), failure.Signer)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestDuplicateRequestDoesNotUseUpRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	_, restrictions, err := parseAllowedRequesters(testSigner + ":2")
+	require.NoError(t, err)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{requesterRestrictions: restrictions})
+
+	// Keep the requests pending, so that the resubmission is a duplicate.
+	md.setRetries(vaa.ChainIDPolygon, ignoreAllQueries)
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// The bucket holds two tokens, so another request should still be accepted.
+	signedQueryRequest, _ = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	require.Eventually(t, func() bool { return md.getRequestsPerChain(vaa.ChainIDPolygon) == 2 }, requestTimeoutForTest, pollIntervalForTest)
+	assert.Empty(t, md.getFailures())
+}
+
+func TestRequesterRestrictedToEthCallCannotSubmitLogsQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	_, restrictions, err := parseAllowedRequesters(testSigner + ":types=1")
+	require.NoError(t, err)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{requesterRestrictions: restrictions})
+
+	perChainQueries := []*PerChainQueryRequest{
+		{
+			ChainId: vaa.ChainIDPolygon,
+			Query: &EthLogsQueryRequest{
+				FromBlock: "0x28d9630",
+				ToBlock:   "0x28d9640",
+				Address:   ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599").Bytes(),
+			},
+		},
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, QueryTypeNotPermitted, failure.Reason)
+	assert.Equal(t, ethCommon.HexToAddress(testSigner), failure.Signer)
+	assert.Nil(t, md.getQueryResponsePublication())
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// The same requester may still submit an eth_call.
+	md.resetState()
+	perChainQueries = []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	signedQueryRequest, queryRequest = createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+	md.signedQueryReqWriteC <- signedQueryRequest
+	require.NotNil(t, md.waitForResponse())
+	assert.Nil(t, md.getFailure())
+}
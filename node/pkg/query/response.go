@@ -11,6 +11,9 @@ import (
 	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/wormhole-foundation/wormhole/sdk/vaa"
 )
 
@@ -26,6 +29,14 @@ const (
 
 	// QueryFatalError means the query failed, and there is no point in retrying it.
 	QueryFatalError QueryStatus = -1
+
+	// QueryNotFound means the block or account being queried does not exist. Like QueryFatalError it is not retried, but the request is
+	// reported as failing with NotFound, so the requester can tell that something does not exist from the query failing.
+	QueryNotFound QueryStatus = 2
+
+	// QueryNodeSyncing means the node used by the watcher is still syncing, so the state needed by the query may not be available yet.
+	// The request is rejected with NodeSyncing, unless the handler is configured to defer it until the node has caught up.
+	QueryNodeSyncing QueryStatus = 3
 )
 
 // This is the query response returned from the watcher to the query handler.
@@ -35,6 +46,9 @@ type PerChainQueryResponseInternal struct {
 	ChainId    vaa.ChainID
 	Status     QueryStatus
 	Response   ChainSpecificResponse
+
+	// Metadata is optional. It is not part of the signed response.
+	Metadata *PerChainResponseMetadata
 }
 
 // CreatePerChainQueryResponseInternal creates a PerChainQueryResponseInternal and returns a pointer to it.
@@ -54,8 +68,43 @@ var queryResponsePrefix = []byte("query_response_0000000000000000000|")
 type QueryResponsePublication struct {
 	Request           *gossipv1.SignedQueryRequest
 	PerChainResponses []*PerChainQueryResponse
+
+	// SchemaVersion is the layout used to marshal the response. It must match the version requested in the query request.
+	SchemaVersion ResponseSchemaVersion
+
+	// HashAlgorithm is the algorithm used for the Merkle root of the per chain responses. It must match the algorithm requested in the query
+	// request. It is not marshaled, since it is already part of the request. It is populated by the query handler and by Unmarshal.
+	HashAlgorithm HashAlgorithm
+
+	// Nonce is the nonce of the query request, so a requester with many requests in flight can match each response to its request. It is not
+	// marshaled, since it is already part of the request. It is populated by the query handler and by Unmarshal.
+	Nonce uint32
+
+	// PreferSpeed flags a response to a request that asked the guardian to prefer speed over consistency, so the results of each per chain
+	// query may not all have been read from the same block. It is not marshaled, since it is already part of the request. It is populated by
+	// the query handler and by Unmarshal.
+	PreferSpeed bool
+
+	// Statuses is parallel to the per chain queries of the request. It is only set if the request allows partial results, in which case it
+	// indicates which of the per chain queries failed, and PerChainResponses only contains the responses of the ones that succeeded, in order.
+	// It is marshaled after the per chain responses, so it is covered by the signature.
+	Statuses []PerChainResultStatus
+
+	// Metadata is only populated locally by the query handler. It is not marshaled, so it is not signed or published on p2p.
+	Metadata *ResponseMetadata
 }
 
+// PerChainResultStatus indicates whether a per chain query in a request that allows partial results succeeded.
+type PerChainResultStatus uint8
+
+const (
+	// PerChainResultSucceeded means the per chain query succeeded, and its response is included.
+	PerChainResultSucceeded PerChainResultStatus = 0
+
+	// PerChainResultFailed means the per chain query failed, and its response is omitted.
+	PerChainResultFailed PerChainResultStatus = 1
+)
+
 // PerChainQueryResponse represents a query response for a single chain.
 type PerChainQueryResponse struct {
 	// ChainId indicates which chain this query was destine for.
@@ -82,6 +131,10 @@ type EthCallQueryResponse struct {
 
 	// Results is the array of responses matching CallData in EthCallQueryRequest
 	Results [][]byte
+
+	// Statuses is parallel to Results. It is only set if the request allows reverted calls, in which case it indicates which calls reverted.
+	// It is marshaled after the results, so responses to other requests are unchanged.
+	Statuses []EthCallResultStatus
 }
 
 // EthCallByTimestampQueryResponse implements ChainSpecificResponse for an EVM eth_call_by_timestamp query response.
@@ -107,6 +160,128 @@ type EthCallWithFinalityQueryResponse struct {
 	Results [][]byte
 }
 
+// EthCallResultStatus indicates whether a call in an EVM eth_call or eth_call_with_precondition query was executed.
+type EthCallResultStatus uint8
+
+const (
+	// EthCallResultExecuted means the call was executed and the result is valid.
+	EthCallResultExecuted EthCallResultStatus = 0
+
+	// PreconditionFailed means the call was skipped because the precondition did not hold. The result is empty.
+	PreconditionFailed EthCallResultStatus = 1
+
+	// EthCallReverted means the call reverted. The result is the revert data, which may be empty.
+	EthCallReverted EthCallResultStatus = 2
+)
+
+// EthCallWithPreconditionQueryResponse implements ChainSpecificResponse for an EVM eth_call_with_precondition query response.
+type EthCallWithPreconditionQueryResponse struct {
+	BlockNumber uint64
+	Hash        common.Hash
+	Time        time.Time
+
+	// Results is the array of responses matching CallData in EthCallWithPreconditionQueryRequest. The first entry is always the result of the precondition call.
+	Results [][]byte
+
+	// Statuses is parallel to Results. It indicates which calls were skipped because the precondition did not hold.
+	Statuses []EthCallResultStatus
+}
+
+// EthCallByTimestampListQueryResponse implements ChainSpecificResponse for an EVM eth_call_by_timestamp_list query response.
+type EthCallByTimestampListQueryResponse struct {
+	// Responses is parallel to TargetTimestamps in EthCallByTimestampListQueryRequest. Each entry contains the blocks the timestamp
+	// resolved to and the results of the calls on the target block.
+	Responses []*EthCallByTimestampQueryResponse
+}
+
+// EthTxProofQueryResponse implements ChainSpecificResponse for an EVM eth_tx_proof query response.
+type EthTxProofQueryResponse struct {
+	// BlockNumber, BlockHash and BlockTime identify the block that contains the transaction.
+	BlockNumber uint64
+	BlockHash   common.Hash
+	BlockTime   time.Time
+
+	// TransactionsRoot is the root of the transactions trie from the block header.
+	TransactionsRoot common.Hash
+
+	// TxIndex is the position of the transaction in the block. The key in the transactions trie is its RLP encoding.
+	TxIndex uint32
+
+	// Proof is the list of trie nodes on the path from TransactionsRoot to the transaction, starting with the root node.
+	// The value at the end of the path is the binary encoding of the transaction.
+	Proof [][]byte
+}
+
+// EthStorageDiffQueryResponse implements ChainSpecificResponse for an EVM eth_storage_diff query response.
+type EthStorageDiffQueryResponse struct {
+	// FromBlockNumber, FromBlockHash and FromBlockTime identify the first block that was compared.
+	FromBlockNumber uint64
+	FromBlockHash   common.Hash
+	FromBlockTime   time.Time
+
+	// ToBlockNumber, ToBlockHash and ToBlockTime identify the second block that was compared.
+	ToBlockNumber uint64
+	ToBlockHash   common.Hash
+	ToBlockTime   time.Time
+
+	// Slots has one entry for each slot in the request, in the same order.
+	Slots []*EthStorageSlotDiff
+}
+
+// EthStorageSlotDiff is the result for a single slot in an eth_storage_diff query response.
+type EthStorageSlotDiff struct {
+	// OldValue is the value of the slot at the from block, and NewValue is its value at the to block.
+	OldValue common.Hash
+	NewValue common.Hash
+
+	// Changed is set if OldValue and NewValue differ. NewValue is only included in the binary encoding if it is set.
+	Changed bool
+}
+
+// EthBlockProbeQueryResponse implements ChainSpecificResponse for an EVM eth_block_probe query response.
+type EthBlockProbeQueryResponse struct {
+	// BlockNumber, BlockHash and BlockTime identify the block the tag resolved to.
+	BlockNumber uint64
+	BlockHash   common.Hash
+	BlockTime   time.Time
+}
+
+// EthLogsQueryResponse implements ChainSpecificResponse for an EVM eth_logs query response.
+type EthLogsQueryResponse struct {
+	// BlockNumber, BlockHash and BlockTime identify the last block of the range, which the logs were observed at.
+	BlockNumber uint64
+	BlockHash   common.Hash
+	BlockTime   time.Time
+
+	// FromBlock is the first block of the range that was read. It is before the requested from block if the range was widened.
+	FromBlock uint64
+
+	// Logs are the logs in the range that match the request, in the order they were emitted. They were all emitted by the requested address.
+	Logs []*EthLog
+}
+
+// EthLog is a single log in an eth_logs query response.
+type EthLog struct {
+	// BlockNumber, TxHash and LogIndex identify where the log was emitted. LogIndex is the index of the log in the block.
+	BlockNumber uint64
+	TxHash      common.Hash
+	LogIndex    uint32
+
+	// Topics and Data are the contents of the log.
+	Topics []common.Hash
+	Data   []byte
+}
+
+// EthStorageQueryResponse implements ChainSpecificResponse for an EVM eth_storage query response.
+type EthStorageQueryResponse struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+	BlockTime   time.Time
+
+	// Values has the value of each slot in the request, in the same order.
+	Values []common.Hash
+}
+
 // SolanaAccountQueryResponse implements ChainSpecificResponse for a Solana sol_account query response.
 type SolanaAccountQueryResponse struct {
 	// SlotNumber is the slot number returned by the sol_account query
@@ -188,7 +363,8 @@ func (msg *QueryResponsePublication) Marshal() ([]byte, error) {
 
 	buf := new(bytes.Buffer)
 
-	vaa.MustWrite(buf, binary.BigEndian, uint8(1)) // version
+	schemaVersion := msg.SchemaVersion.effective()
+	vaa.MustWrite(buf, binary.BigEndian, uint8(schemaVersion)) // version
 
 	// Source
 	// TODO: support writing off-chain and on-chain requests
@@ -215,6 +391,22 @@ func (msg *QueryResponsePublication) Marshal() ([]byte, error) {
 		buf.Write(pcrBuf)
 	}
 
+	// Per chain statuses. Validate has checked that they are present if and only if the request allows partial results.
+	if msg.Statuses != nil {
+		vaa.MustWrite(buf, binary.BigEndian, uint8(len(msg.Statuses)))
+		for _, status := range msg.Statuses {
+			vaa.MustWrite(buf, binary.BigEndian, status)
+		}
+	}
+
+	if schemaVersion >= ResponseSchemaVersion2 {
+		root, err := msg.MerkleRoot()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute merkle root: %w", err)
+		}
+		buf.Write(root.Bytes())
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -227,7 +419,8 @@ func (msg *QueryResponsePublication) Unmarshal(data []byte) error {
 		return fmt.Errorf("failed to read message version: %w", err)
 	}
 
-	if version != 1 {
+	msg.SchemaVersion = ResponseSchemaVersion(version)
+	if version == uint8(DefaultResponseSchemaVersion) || msg.SchemaVersion.Validate() != nil {
 		return fmt.Errorf("unsupported message version: %d", version)
 	}
 
@@ -272,6 +465,9 @@ func (msg *QueryResponsePublication) Unmarshal(data []byte) error {
 	}
 	signedQueryRequest.QueryRequest = queryRequestBytes
 	msg.Request = signedQueryRequest
+	msg.Nonce = queryRequest.Nonce
+	msg.HashAlgorithm = queryRequest.HashAlgorithm
+	msg.PreferSpeed = queryRequest.PreferSpeed
 
 	// Responses
 	numPerChainResponses := uint8(0)
@@ -288,6 +484,35 @@ func (msg *QueryResponsePublication) Unmarshal(data []byte) error {
 		msg.PerChainResponses = append(msg.PerChainResponses, &pcr)
 	}
 
+	if queryRequest.AllowPartialResults {
+		numStatuses := uint8(0)
+		if err := binary.Read(reader, binary.BigEndian, &numStatuses); err != nil {
+			return fmt.Errorf("failed to read number of per chain statuses: %w", err)
+		}
+		msg.Statuses = make([]PerChainResultStatus, 0, numStatuses)
+		for count := 0; count < int(numStatuses); count++ {
+			var status PerChainResultStatus
+			if err := binary.Read(reader, binary.BigEndian, &status); err != nil {
+				return fmt.Errorf("failed to read per chain status: %w", err)
+			}
+			msg.Statuses = append(msg.Statuses, status)
+		}
+	}
+
+	if msg.SchemaVersion >= ResponseSchemaVersion2 {
+		root := common.Hash{}
+		if n, err := reader.Read(root[:]); err != nil || n != len(root) {
+			return fmt.Errorf("failed to read merkle root [%d]: %w", n, err)
+		}
+		expectedRoot, err := msg.MerkleRoot()
+		if err != nil {
+			return fmt.Errorf("failed to compute merkle root: %w", err)
+		}
+		if root != expectedRoot {
+			return fmt.Errorf("merkle root does not match the per chain responses")
+		}
+	}
+
 	if reader.Len() != 0 {
 		return fmt.Errorf("excess bytes in unmarshal")
 	}
@@ -311,22 +536,63 @@ func (msg *QueryResponsePublication) Validate() error {
 		return fmt.Errorf("query request is invalid: %w", err)
 	}
 
+	if err := msg.SchemaVersion.Validate(); err != nil {
+		return err
+	}
+	if msg.SchemaVersion.effective() != queryRequest.ResponseSchemaVersion.effective() {
+		return fmt.Errorf("response schema version %d does not match the requested version %d", msg.SchemaVersion.effective(), queryRequest.ResponseSchemaVersion.effective())
+	}
+	if msg.HashAlgorithm != queryRequest.HashAlgorithm {
+		return fmt.Errorf("response hash algorithm %s does not match the requested algorithm %s", msg.HashAlgorithm, queryRequest.HashAlgorithm)
+	}
+	if msg.PreferSpeed != queryRequest.PreferSpeed {
+		return fmt.Errorf("response prefer speed flag does not match the request")
+	}
+
 	if len(msg.PerChainResponses) <= 0 {
 		return fmt.Errorf("response does not contain any per chain responses")
 	}
 	if len(msg.PerChainResponses) > math.MaxUint8 {
 		return fmt.Errorf("too many per chain responses")
 	}
-	if len(msg.PerChainResponses) != len(queryRequest.PerChainQueries) {
+
+	// Work out which query each response is for. Unless the request allows partial results, there is a response for every query.
+	queryIdxs := make([]int, 0, len(queryRequest.PerChainQueries))
+	if queryRequest.AllowPartialResults {
+		if len(msg.Statuses) != len(queryRequest.PerChainQueries) {
+			return fmt.Errorf("number of statuses does not match number of queries")
+		}
+		for idx, status := range msg.Statuses {
+			switch status {
+			case PerChainResultSucceeded:
+				queryIdxs = append(queryIdxs, idx)
+			case PerChainResultFailed:
+			default:
+				return fmt.Errorf("invalid status for query %d: %d", idx, status)
+			}
+		}
+	} else {
+		if msg.Statuses != nil {
+			return fmt.Errorf("response contains statuses but the request does not allow partial results")
+		}
+		for idx := range queryRequest.PerChainQueries {
+			queryIdxs = append(queryIdxs, idx)
+		}
+	}
+
+	if len(msg.PerChainResponses) != len(queryIdxs) {
 		return fmt.Errorf("number of responses does not match number of queries")
 	}
 	for idx, pcr := range msg.PerChainResponses {
 		if err := pcr.Validate(); err != nil {
 			return fmt.Errorf("failed to validate per chain query %d: %w", idx, err)
 		}
-		if pcr.Response.Type() != queryRequest.PerChainQueries[idx].Query.Type() {
+		if pcr.Response.Type() != queryRequest.PerChainQueries[queryIdxs[idx]].Query.Type() {
 			return fmt.Errorf("type of response %d does not match the query", idx)
 		}
+		if ecr, ok := pcr.Response.(*EthCallQueryResponse); ok && (ecr.Statuses != nil) != queryRequest.AllowRevertedCalls {
+			return fmt.Errorf("presence of call statuses in response %d does not match the request", idx)
+		}
 	}
 	return nil
 }
@@ -336,6 +602,12 @@ func (left *QueryResponsePublication) Equal(right *QueryResponsePublication) boo
 	if !bytes.Equal(left.Request.QueryRequest, right.Request.QueryRequest) || !bytes.Equal(left.Request.Signature, right.Request.Signature) {
 		return false
 	}
+	if left.SchemaVersion.effective() != right.SchemaVersion.effective() {
+		return false
+	}
+	if left.HashAlgorithm != right.HashAlgorithm {
+		return false
+	}
 	if len(left.PerChainResponses) != len(right.PerChainResponses) {
 		return false
 	}
@@ -344,6 +616,14 @@ func (left *QueryResponsePublication) Equal(right *QueryResponsePublication) boo
 			return false
 		}
 	}
+	if len(left.Statuses) != len(right.Statuses) {
+		return false
+	}
+	for idx := range left.Statuses {
+		if left.Statuses[idx] != right.Statuses[idx] {
+			return false
+		}
+	}
 	return true
 }
 
@@ -419,47 +699,35 @@ func (perChainResponse *PerChainQueryResponse) UnmarshalFromReader(reader *bytes
 		return err
 	}
 
-	// Skip the response length.
 	var respLength uint32
 	if err := binary.Read(reader, binary.BigEndian, &respLength); err != nil {
 		return fmt.Errorf("failed to read response length: %w", err)
 	}
+	if int64(respLength) > int64(reader.Len()) {
+		return fmt.Errorf("response is truncated")
+	}
 
-	switch queryType {
-	case EthCallQueryRequestType:
-		r := EthCallQueryResponse{}
-		if err := r.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal eth call response: %w", err)
-		}
-		perChainResponse.Response = &r
-	case EthCallByTimestampQueryRequestType:
-		r := EthCallByTimestampQueryResponse{}
-		if err := r.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal eth call by timestamp response: %w", err)
-		}
-		perChainResponse.Response = &r
-	case EthCallWithFinalityQueryRequestType:
-		r := EthCallWithFinalityQueryResponse{}
-		if err := r.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal eth call with finality response: %w", err)
-		}
-		perChainResponse.Response = &r
-	case SolanaAccountQueryRequestType:
-		r := SolanaAccountQueryResponse{}
-		if err := r.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal sol_account response: %w", err)
-		}
-		perChainResponse.Response = &r
-	case SolanaPdaQueryRequestType:
-		r := SolanaPdaQueryResponse{}
-		if err := r.UnmarshalFromReader(reader); err != nil {
-			return fmt.Errorf("failed to unmarshal sol_account response: %w", err)
-		}
-		perChainResponse.Response = &r
-	default:
+	handler := lookUpQueryType(queryType)
+	if handler == nil {
 		return fmt.Errorf("unsupported query type: %d", queryType)
 	}
 
+	// The chain specific response is read from its own reader, so that it can tell where it ends.
+	respBytes := make([]byte, respLength)
+	if _, err := reader.Read(respBytes); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	respReader := bytes.NewReader(respBytes)
+
+	r := handler.NewResponse()
+	if err := r.UnmarshalFromReader(respReader); err != nil {
+		return fmt.Errorf("failed to unmarshal %s response: %w", handler.Name, err)
+	}
+	if respReader.Len() != 0 {
+		return fmt.Errorf("excess bytes in %s response", handler.Name)
+	}
+	perChainResponse.Response = r
+
 	return nil
 }
 
@@ -525,6 +793,55 @@ func (left *PerChainQueryResponse) Equal(right *PerChainQueryResponse) bool {
 		default:
 			panic("unsupported query type on right") // We checked this above!
 		}
+	case *EthCallWithPreconditionQueryResponse:
+		switch rightResp := right.Response.(type) {
+		case *EthCallWithPreconditionQueryResponse:
+			return leftResp.Equal(rightResp)
+		default:
+			panic("unsupported query type on right") // We checked this above!
+		}
+	case *EthCallByTimestampListQueryResponse:
+		switch rightResp := right.Response.(type) {
+		case *EthCallByTimestampListQueryResponse:
+			return leftResp.Equal(rightResp)
+		default:
+			panic("unsupported query type on right") // We checked this above!
+		}
+	case *EthTxProofQueryResponse:
+		switch rightResp := right.Response.(type) {
+		case *EthTxProofQueryResponse:
+			return leftResp.Equal(rightResp)
+		default:
+			panic("unsupported query type on right") // We checked this above!
+		}
+	case *EthStorageDiffQueryResponse:
+		switch rightResp := right.Response.(type) {
+		case *EthStorageDiffQueryResponse:
+			return leftResp.Equal(rightResp)
+		default:
+			panic("unsupported query type on right") // We checked this above!
+		}
+	case *EthBlockProbeQueryResponse:
+		switch rightResp := right.Response.(type) {
+		case *EthBlockProbeQueryResponse:
+			return leftResp.Equal(rightResp)
+		default:
+			panic("unsupported query type on right") // We checked this above!
+		}
+	case *EthLogsQueryResponse:
+		switch rightResp := right.Response.(type) {
+		case *EthLogsQueryResponse:
+			return leftResp.Equal(rightResp)
+		default:
+			panic("unsupported query type on right") // We checked this above!
+		}
+	case *EthStorageQueryResponse:
+		switch rightResp := right.Response.(type) {
+		case *EthStorageQueryResponse:
+			return leftResp.Equal(rightResp)
+		default:
+			panic("unsupported query type on right") // We checked this above!
+		}
 	case *SolanaAccountQueryResponse:
 		switch rightResp := right.Response.(type) {
 		case *SolanaAccountQueryResponse:
@@ -540,7 +857,8 @@ func (left *PerChainQueryResponse) Equal(right *PerChainQueryResponse) bool {
 			panic("unsupported query type on right") // We checked this above!
 		}
 	default:
-		panic("unsupported query type on left") // We checked this above!
+		// This is a registered query type, so compare the serialized responses.
+		return marshaledEqual(left.Response, right.Response)
 	}
 }
 
@@ -570,6 +888,10 @@ func (ecr *EthCallQueryResponse) Marshal() ([]byte, error) {
 		buf.Write(ecr.Results[idx])
 	}
 
+	for _, status := range ecr.Statuses {
+		vaa.MustWrite(buf, binary.BigEndian, status)
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -615,6 +937,19 @@ func (ecr *EthCallQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error
 		ecr.Results = append(ecr.Results, result)
 	}
 
+	// The statuses are only present if the request allows reverted calls. The per chain response is length delimited, so any bytes left
+	// in the reader belong to this response.
+	if reader.Len() == 0 {
+		return nil
+	}
+	for count := 0; count < int(numResults); count++ {
+		var status EthCallResultStatus
+		if err := binary.Read(reader, binary.BigEndian, &status); err != nil {
+			return fmt.Errorf("failed to read result status: %w", err)
+		}
+		ecr.Statuses = append(ecr.Statuses, status)
+	}
+
 	return nil
 }
 
@@ -637,6 +972,16 @@ func (ecr *EthCallQueryResponse) Validate() error {
 			return fmt.Errorf("result too long")
 		}
 	}
+	if ecr.Statuses != nil {
+		if len(ecr.Statuses) != len(ecr.Results) {
+			return fmt.Errorf("number of statuses does not match number of results")
+		}
+		for idx, status := range ecr.Statuses {
+			if status != EthCallResultExecuted && status != EthCallReverted {
+				return fmt.Errorf("invalid status for result %d: %d", idx, status)
+			}
+		}
+	}
 	return nil
 }
 
@@ -663,6 +1008,15 @@ func (left *EthCallQueryResponse) Equal(right *EthCallQueryResponse) bool {
 		}
 	}
 
+	if len(left.Statuses) != len(right.Statuses) {
+		return false
+	}
+	for idx := range left.Statuses {
+		if left.Statuses[idx] != right.Statuses[idx] {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -946,6 +1300,908 @@ func (left *EthCallWithFinalityQueryResponse) Equal(right *EthCallWithFinalityQu
 	return true
 }
 
+//
+// Implementation of EthCallWithPreconditionQueryResponse, which implements the ChainSpecificResponse for an EVM eth_call_with_precondition query response.
+//
+
+func (e *EthCallWithPreconditionQueryResponse) Type() ChainSpecificQueryType {
+	return EthCallWithPreconditionQueryRequestType
+}
+
+// PreconditionMet returns true if the precondition held, meaning all of the calls were executed.
+func (ecr *EthCallWithPreconditionQueryResponse) PreconditionMet() bool {
+	for _, status := range ecr.Statuses {
+		if status == PreconditionFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// Marshal serializes the binary representation of an EVM eth_call_with_precondition response.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecr *EthCallWithPreconditionQueryResponse) Marshal() ([]byte, error) {
+	if err := ecr.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockNumber)
+	buf.Write(ecr.Hash[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.Time.UnixMicro())
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecr.Results)))
+	for idx := range ecr.Results {
+		vaa.MustWrite(buf, binary.BigEndian, ecr.Statuses[idx])
+		vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecr.Results[idx])))
+		buf.Write(ecr.Results[idx])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_call_with_precondition response from a byte array
+func (ecr *EthCallWithPreconditionQueryResponse) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecr.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_call_with_precondition response from a byte array
+func (ecr *EthCallWithPreconditionQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error {
+	if err := binary.Read(reader, binary.BigEndian, &ecr.BlockNumber); err != nil {
+		return fmt.Errorf("failed to read response number: %w", err)
+	}
+
+	responseHash := common.Hash{}
+	if n, err := reader.Read(responseHash[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read response hash [%d]: %w", n, err)
+	}
+	ecr.Hash = responseHash
+
+	unixMicros := int64(0)
+	if err := binary.Read(reader, binary.BigEndian, &unixMicros); err != nil {
+		return fmt.Errorf("failed to read response timestamp: %w", err)
+	}
+	ecr.Time = time.UnixMicro(unixMicros)
+
+	numResults := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numResults); err != nil {
+		return fmt.Errorf("failed to read number of results: %w", err)
+	}
+
+	for count := 0; count < int(numResults); count++ {
+		var status EthCallResultStatus
+		if err := binary.Read(reader, binary.BigEndian, &status); err != nil {
+			return fmt.Errorf("failed to read result status: %w", err)
+		}
+
+		resultLen := uint32(0)
+		if err := binary.Read(reader, binary.BigEndian, &resultLen); err != nil {
+			return fmt.Errorf("failed to read result len: %w", err)
+		}
+		result := make([]byte, resultLen)
+		if resultLen != 0 {
+			if n, err := reader.Read(result[:]); err != nil || n != int(resultLen) {
+				return fmt.Errorf("failed to read result [%d]: %w", n, err)
+			}
+		}
+
+		ecr.Statuses = append(ecr.Statuses, status)
+		ecr.Results = append(ecr.Results, result)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_call_with_precondition response.
+func (ecr *EthCallWithPreconditionQueryResponse) Validate() error {
+	// Not checking for BlockNumber == 0, because maybe that could happen??
+
+	if len(ecr.Hash) != 32 {
+		return fmt.Errorf("invalid length for block hash")
+	}
+
+	if len(ecr.Results) <= 0 {
+		return fmt.Errorf("does not contain any results")
+	}
+	if len(ecr.Results) > math.MaxUint8 {
+		return fmt.Errorf("too many results")
+	}
+	if len(ecr.Statuses) != len(ecr.Results) {
+		return fmt.Errorf("number of statuses does not match number of results")
+	}
+	if ecr.Statuses[0] != EthCallResultExecuted {
+		return fmt.Errorf("precondition call must always be executed")
+	}
+	for idx, result := range ecr.Results {
+		if len(result) > math.MaxUint32 {
+			return fmt.Errorf("result too long")
+		}
+		switch ecr.Statuses[idx] {
+		case EthCallResultExecuted:
+		case PreconditionFailed:
+			if len(result) != 0 {
+				return fmt.Errorf("result %d was skipped but is not empty", idx)
+			}
+		default:
+			return fmt.Errorf("invalid status for result %d: %d", idx, ecr.Statuses[idx])
+		}
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_call_with_precondition responses are equal.
+func (left *EthCallWithPreconditionQueryResponse) Equal(right *EthCallWithPreconditionQueryResponse) bool {
+	if left.BlockNumber != right.BlockNumber {
+		return false
+	}
+
+	if !bytes.Equal(left.Hash.Bytes(), right.Hash.Bytes()) {
+		return false
+	}
+
+	if left.Time != right.Time {
+		return false
+	}
+
+	if len(left.Results) != len(right.Results) || len(left.Statuses) != len(right.Statuses) {
+		return false
+	}
+	for idx := range left.Results {
+		if !bytes.Equal(left.Results[idx], right.Results[idx]) {
+			return false
+		}
+	}
+	for idx := range left.Statuses {
+		if left.Statuses[idx] != right.Statuses[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthCallByTimestampListQueryResponse, which implements the ChainSpecificResponse for an EVM eth_call_by_timestamp_list query response.
+//
+
+func (e *EthCallByTimestampListQueryResponse) Type() ChainSpecificQueryType {
+	return EthCallByTimestampListQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_call_by_timestamp_list response.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecr *EthCallByTimestampListQueryResponse) Marshal() ([]byte, error) {
+	if err := ecr.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecr.Responses)))
+	for idx, resp := range ecr.Responses {
+		respBuf, err := resp.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal response %d: %w", idx, err)
+		}
+		buf.Write(respBuf)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_call_by_timestamp_list response from a byte array
+func (ecr *EthCallByTimestampListQueryResponse) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecr.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_call_by_timestamp_list response from a byte array
+func (ecr *EthCallByTimestampListQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error {
+	numResponses := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numResponses); err != nil {
+		return fmt.Errorf("failed to read number of responses: %w", err)
+	}
+
+	for count := 0; count < int(numResponses); count++ {
+		resp := &EthCallByTimestampQueryResponse{}
+		if err := resp.UnmarshalFromReader(reader); err != nil {
+			return fmt.Errorf("failed to read response %d: %w", count, err)
+		}
+		ecr.Responses = append(ecr.Responses, resp)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_call_by_timestamp_list response.
+func (ecr *EthCallByTimestampListQueryResponse) Validate() error {
+	if len(ecr.Responses) <= 0 {
+		return fmt.Errorf("does not contain any responses")
+	}
+	if len(ecr.Responses) > EthCallByTimestampListMaxTimestamps {
+		return fmt.Errorf("too many responses")
+	}
+	for idx, resp := range ecr.Responses {
+		if resp == nil {
+			return fmt.Errorf("response %d is nil", idx)
+		}
+		if err := resp.Validate(); err != nil {
+			return fmt.Errorf("response %d is invalid: %w", idx, err)
+		}
+		if len(resp.Results) != len(ecr.Responses[0].Results) {
+			return fmt.Errorf("response %d has %d results, expected %d", idx, len(resp.Results), len(ecr.Responses[0].Results))
+		}
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_call_by_timestamp_list responses are equal.
+func (left *EthCallByTimestampListQueryResponse) Equal(right *EthCallByTimestampListQueryResponse) bool {
+	if len(left.Responses) != len(right.Responses) {
+		return false
+	}
+	for idx := range left.Responses {
+		if !left.Responses[idx].Equal(right.Responses[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthTxProofQueryResponse, which implements the ChainSpecificResponse for an EVM eth_tx_proof query response.
+//
+
+func (e *EthTxProofQueryResponse) Type() ChainSpecificQueryType {
+	return EthTxProofQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_tx_proof response.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecr *EthTxProofQueryResponse) Marshal() ([]byte, error) {
+	if err := ecr.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockNumber)
+	buf.Write(ecr.BlockHash[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockTime.UnixMicro())
+	buf.Write(ecr.TransactionsRoot[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.TxIndex)
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecr.Proof)))
+	for _, node := range ecr.Proof {
+		vaa.MustWrite(buf, binary.BigEndian, uint32(len(node)))
+		buf.Write(node)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_tx_proof response from a byte array
+func (ecr *EthTxProofQueryResponse) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecr.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_tx_proof response from a byte array
+func (ecr *EthTxProofQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error {
+	if err := binary.Read(reader, binary.BigEndian, &ecr.BlockNumber); err != nil {
+		return fmt.Errorf("failed to read response number: %w", err)
+	}
+
+	blockHash := common.Hash{}
+	if n, err := reader.Read(blockHash[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read response hash [%d]: %w", n, err)
+	}
+	ecr.BlockHash = blockHash
+
+	unixMicros := int64(0)
+	if err := binary.Read(reader, binary.BigEndian, &unixMicros); err != nil {
+		return fmt.Errorf("failed to read response timestamp: %w", err)
+	}
+	ecr.BlockTime = time.UnixMicro(unixMicros)
+
+	txRoot := common.Hash{}
+	if n, err := reader.Read(txRoot[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read transactions root [%d]: %w", n, err)
+	}
+	ecr.TransactionsRoot = txRoot
+
+	if err := binary.Read(reader, binary.BigEndian, &ecr.TxIndex); err != nil {
+		return fmt.Errorf("failed to read tx index: %w", err)
+	}
+
+	numNodes := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numNodes); err != nil {
+		return fmt.Errorf("failed to read number of proof nodes: %w", err)
+	}
+
+	for count := 0; count < int(numNodes); count++ {
+		nodeLen := uint32(0)
+		if err := binary.Read(reader, binary.BigEndian, &nodeLen); err != nil {
+			return fmt.Errorf("failed to read proof node len: %w", err)
+		}
+		node := make([]byte, nodeLen)
+		if n, err := reader.Read(node[:]); err != nil || n != int(nodeLen) {
+			return fmt.Errorf("failed to read proof node [%d]: %w", n, err)
+		}
+
+		ecr.Proof = append(ecr.Proof, node)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_tx_proof response.
+func (ecr *EthTxProofQueryResponse) Validate() error {
+	if len(ecr.Proof) <= 0 {
+		return fmt.Errorf("does not contain any proof nodes")
+	}
+	if len(ecr.Proof) > math.MaxUint8 {
+		return fmt.Errorf("too many proof nodes")
+	}
+	for _, node := range ecr.Proof {
+		if len(node) <= 0 {
+			return fmt.Errorf("proof node may not be empty")
+		}
+		if len(node) > math.MaxUint32 {
+			return fmt.Errorf("proof node too long")
+		}
+	}
+	return nil
+}
+
+// Verify verifies the proof against TransactionsRoot, and that the transaction it proves has the specified hash. It returns the binary encoding
+// of the transaction. Note that it does not verify that TransactionsRoot belongs to the block, which requires the block header.
+func (ecr *EthTxProofQueryResponse) Verify(txHash common.Hash) ([]byte, error) {
+	proofDb := memorydb.New()
+	for _, node := range ecr.Proof {
+		if err := proofDb.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, fmt.Errorf("failed to load proof node: %w", err)
+		}
+	}
+
+	key := rlp.AppendUint64(nil, uint64(ecr.TxIndex))
+	tx, err := trie.VerifyProof(ecr.TransactionsRoot, key, proofDb)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proof: %w", err)
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("proof does not contain a transaction at index %d", ecr.TxIndex)
+	}
+	if crypto.Keccak256Hash(tx) != txHash {
+		return nil, fmt.Errorf("proven transaction does not match the tx hash")
+	}
+	return tx, nil
+}
+
+// Equal verifies that two EVM eth_tx_proof responses are equal.
+func (left *EthTxProofQueryResponse) Equal(right *EthTxProofQueryResponse) bool {
+	if left.BlockNumber != right.BlockNumber {
+		return false
+	}
+
+	if !bytes.Equal(left.BlockHash.Bytes(), right.BlockHash.Bytes()) {
+		return false
+	}
+
+	if left.BlockTime != right.BlockTime {
+		return false
+	}
+
+	if left.TransactionsRoot != right.TransactionsRoot || left.TxIndex != right.TxIndex {
+		return false
+	}
+
+	if len(left.Proof) != len(right.Proof) {
+		return false
+	}
+	for idx := range left.Proof {
+		if !bytes.Equal(left.Proof[idx], right.Proof[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthStorageDiffQueryResponse, which implements the ChainSpecificResponse for an EVM eth_storage_diff query response.
+//
+
+func (e *EthStorageDiffQueryResponse) Type() ChainSpecificQueryType {
+	return EthStorageDiffQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_storage_diff response.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecr *EthStorageDiffQueryResponse) Marshal() ([]byte, error) {
+	if err := ecr.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, ecr.FromBlockNumber)
+	buf.Write(ecr.FromBlockHash[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.FromBlockTime.UnixMicro())
+	vaa.MustWrite(buf, binary.BigEndian, ecr.ToBlockNumber)
+	buf.Write(ecr.ToBlockHash[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.ToBlockTime.UnixMicro())
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecr.Slots)))
+	for _, slot := range ecr.Slots {
+		if slot.Changed {
+			vaa.MustWrite(buf, binary.BigEndian, uint8(1))
+			buf.Write(slot.OldValue[:])
+			buf.Write(slot.NewValue[:])
+		} else {
+			vaa.MustWrite(buf, binary.BigEndian, uint8(0))
+			buf.Write(slot.OldValue[:])
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_storage_diff response from a byte array
+func (ecr *EthStorageDiffQueryResponse) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecr.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_storage_diff response from a byte array
+func (ecr *EthStorageDiffQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error {
+	if err := binary.Read(reader, binary.BigEndian, &ecr.FromBlockNumber); err != nil {
+		return fmt.Errorf("failed to read from block number: %w", err)
+	}
+
+	fromBlockHash := common.Hash{}
+	if n, err := reader.Read(fromBlockHash[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read from block hash [%d]: %w", n, err)
+	}
+	ecr.FromBlockHash = fromBlockHash
+
+	unixMicros := int64(0)
+	if err := binary.Read(reader, binary.BigEndian, &unixMicros); err != nil {
+		return fmt.Errorf("failed to read from block timestamp: %w", err)
+	}
+	ecr.FromBlockTime = time.UnixMicro(unixMicros)
+
+	if err := binary.Read(reader, binary.BigEndian, &ecr.ToBlockNumber); err != nil {
+		return fmt.Errorf("failed to read to block number: %w", err)
+	}
+
+	toBlockHash := common.Hash{}
+	if n, err := reader.Read(toBlockHash[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read to block hash [%d]: %w", n, err)
+	}
+	ecr.ToBlockHash = toBlockHash
+
+	if err := binary.Read(reader, binary.BigEndian, &unixMicros); err != nil {
+		return fmt.Errorf("failed to read to block timestamp: %w", err)
+	}
+	ecr.ToBlockTime = time.UnixMicro(unixMicros)
+
+	numSlots := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numSlots); err != nil {
+		return fmt.Errorf("failed to read number of slots: %w", err)
+	}
+
+	for count := 0; count < int(numSlots); count++ {
+		changed := uint8(0)
+		if err := binary.Read(reader, binary.BigEndian, &changed); err != nil {
+			return fmt.Errorf("failed to read slot changed flag: %w", err)
+		}
+		if changed > 1 {
+			return fmt.Errorf("invalid slot changed flag: %d", changed)
+		}
+
+		slot := &EthStorageSlotDiff{Changed: changed == 1}
+		if n, err := reader.Read(slot.OldValue[:]); err != nil || n != 32 {
+			return fmt.Errorf("failed to read slot old value [%d]: %w", n, err)
+		}
+		if slot.Changed {
+			if n, err := reader.Read(slot.NewValue[:]); err != nil || n != 32 {
+				return fmt.Errorf("failed to read slot new value [%d]: %w", n, err)
+			}
+		} else {
+			slot.NewValue = slot.OldValue
+		}
+
+		ecr.Slots = append(ecr.Slots, slot)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_storage_diff response.
+func (ecr *EthStorageDiffQueryResponse) Validate() error {
+	if len(ecr.Slots) <= 0 {
+		return fmt.Errorf("does not contain any slots")
+	}
+	if len(ecr.Slots) > math.MaxUint8 {
+		return fmt.Errorf("too many slots")
+	}
+	for idx, slot := range ecr.Slots {
+		if slot.Changed != (slot.OldValue != slot.NewValue) {
+			return fmt.Errorf("changed flag of slot %d does not match its values", idx)
+		}
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_storage_diff responses are equal.
+func (left *EthStorageDiffQueryResponse) Equal(right *EthStorageDiffQueryResponse) bool {
+	if left.FromBlockNumber != right.FromBlockNumber || left.FromBlockHash != right.FromBlockHash || left.FromBlockTime != right.FromBlockTime {
+		return false
+	}
+
+	if left.ToBlockNumber != right.ToBlockNumber || left.ToBlockHash != right.ToBlockHash || left.ToBlockTime != right.ToBlockTime {
+		return false
+	}
+
+	if len(left.Slots) != len(right.Slots) {
+		return false
+	}
+	for idx := range left.Slots {
+		if *left.Slots[idx] != *right.Slots[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Implementation of EthBlockProbeQueryResponse, which implements the ChainSpecificResponse for an EVM eth_block_probe query response.
+//
+
+func (e *EthBlockProbeQueryResponse) Type() ChainSpecificQueryType {
+	return EthBlockProbeQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_block_probe response.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecr *EthBlockProbeQueryResponse) Marshal() ([]byte, error) {
+	if err := ecr.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockNumber)
+	buf.Write(ecr.BlockHash[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockTime.UnixMicro())
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_block_probe response from a byte array
+func (ecr *EthBlockProbeQueryResponse) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecr.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_block_probe response from a byte array
+func (ecr *EthBlockProbeQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error {
+	if err := binary.Read(reader, binary.BigEndian, &ecr.BlockNumber); err != nil {
+		return fmt.Errorf("failed to read response number: %w", err)
+	}
+
+	blockHash := common.Hash{}
+	if n, err := reader.Read(blockHash[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read response hash [%d]: %w", n, err)
+	}
+	ecr.BlockHash = blockHash
+
+	unixMicros := int64(0)
+	if err := binary.Read(reader, binary.BigEndian, &unixMicros); err != nil {
+		return fmt.Errorf("failed to read response timestamp: %w", err)
+	}
+	ecr.BlockTime = time.UnixMicro(unixMicros)
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_block_probe response.
+func (ecr *EthBlockProbeQueryResponse) Validate() error {
+	if ecr.BlockHash == (common.Hash{}) {
+		return fmt.Errorf("block hash is required")
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_block_probe responses are equal.
+func (left *EthBlockProbeQueryResponse) Equal(right *EthBlockProbeQueryResponse) bool {
+	return left.BlockNumber == right.BlockNumber && left.BlockHash == right.BlockHash && left.BlockTime == right.BlockTime
+}
+
+//
+// Implementation of EthLogsQueryResponse, which implements the ChainSpecificResponse for an EVM eth_logs query response.
+//
+
+func (e *EthLogsQueryResponse) Type() ChainSpecificQueryType {
+	return EthLogsQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_logs response.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecr *EthLogsQueryResponse) Marshal() ([]byte, error) {
+	if err := ecr.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockNumber)
+	buf.Write(ecr.BlockHash[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockTime.UnixMicro())
+	vaa.MustWrite(buf, binary.BigEndian, ecr.FromBlock)
+
+	vaa.MustWrite(buf, binary.BigEndian, uint32(len(ecr.Logs)))
+	for _, log := range ecr.Logs {
+		vaa.MustWrite(buf, binary.BigEndian, log.BlockNumber)
+		buf.Write(log.TxHash[:])
+		vaa.MustWrite(buf, binary.BigEndian, log.LogIndex)
+		vaa.MustWrite(buf, binary.BigEndian, uint8(len(log.Topics)))
+		for _, topic := range log.Topics {
+			buf.Write(topic[:])
+		}
+		vaa.MustWrite(buf, binary.BigEndian, uint32(len(log.Data)))
+		buf.Write(log.Data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// resultOffsets implements the resultSplitter interface. It returns the offset of each log in the serialized response, so a large set of logs
+// may be delivered in chunks.
+func (ecr *EthLogsQueryResponse) resultOffsets() []int {
+	offsets := make([]int, 0, len(ecr.Logs))
+	offset := 8 + 32 + 8 + 8 + 4 // The block number, hash and time, the from block and the number of logs.
+	for _, log := range ecr.Logs {
+		offsets = append(offsets, offset)
+		offset += 8 + 32 + 4 + 1 + len(log.Topics)*32 + 4 + len(log.Data)
+	}
+	return offsets
+}
+
+// Unmarshal deserializes an EVM eth_logs response from a byte array
+func (ecr *EthLogsQueryResponse) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecr.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_logs response from a byte array
+func (ecr *EthLogsQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error {
+	if err := binary.Read(reader, binary.BigEndian, &ecr.BlockNumber); err != nil {
+		return fmt.Errorf("failed to read response number: %w", err)
+	}
+
+	blockHash := common.Hash{}
+	if n, err := reader.Read(blockHash[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read response hash [%d]: %w", n, err)
+	}
+	ecr.BlockHash = blockHash
+
+	unixMicros := int64(0)
+	if err := binary.Read(reader, binary.BigEndian, &unixMicros); err != nil {
+		return fmt.Errorf("failed to read response timestamp: %w", err)
+	}
+	ecr.BlockTime = time.UnixMicro(unixMicros)
+
+	if err := binary.Read(reader, binary.BigEndian, &ecr.FromBlock); err != nil {
+		return fmt.Errorf("failed to read response from block: %w", err)
+	}
+
+	numLogs := uint32(0)
+	if err := binary.Read(reader, binary.BigEndian, &numLogs); err != nil {
+		return fmt.Errorf("failed to read number of logs: %w", err)
+	}
+
+	ecr.Logs = nil
+	for count := 0; count < int(numLogs); count++ {
+		log := &EthLog{}
+		if err := binary.Read(reader, binary.BigEndian, &log.BlockNumber); err != nil {
+			return fmt.Errorf("failed to read log block number: %w", err)
+		}
+		if n, err := reader.Read(log.TxHash[:]); err != nil || n != 32 {
+			return fmt.Errorf("failed to read log tx hash [%d]: %w", n, err)
+		}
+		if err := binary.Read(reader, binary.BigEndian, &log.LogIndex); err != nil {
+			return fmt.Errorf("failed to read log index: %w", err)
+		}
+
+		numTopics := uint8(0)
+		if err := binary.Read(reader, binary.BigEndian, &numTopics); err != nil {
+			return fmt.Errorf("failed to read number of log topics: %w", err)
+		}
+		if numTopics > MaxEthLogsTopics {
+			return fmt.Errorf("too many log topics: %d", numTopics)
+		}
+		for idx := 0; idx < int(numTopics); idx++ {
+			topic := common.Hash{}
+			if n, err := reader.Read(topic[:]); err != nil || n != 32 {
+				return fmt.Errorf("failed to read log topic [%d]: %w", n, err)
+			}
+			log.Topics = append(log.Topics, topic)
+		}
+
+		dataLen := uint32(0)
+		if err := binary.Read(reader, binary.BigEndian, &dataLen); err != nil {
+			return fmt.Errorf("failed to read log data len: %w", err)
+		}
+		if int64(dataLen) > int64(reader.Len()) {
+			return fmt.Errorf("log data is truncated")
+		}
+		log.Data = make([]byte, dataLen)
+		if dataLen != 0 {
+			if n, err := reader.Read(log.Data); err != nil || n != int(dataLen) {
+				return fmt.Errorf("failed to read log data [%d]: %w", n, err)
+			}
+		}
+
+		ecr.Logs = append(ecr.Logs, log)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_logs response.
+func (ecr *EthLogsQueryResponse) Validate() error {
+	if ecr.BlockHash == (common.Hash{}) {
+		return fmt.Errorf("block hash is required")
+	}
+	if ecr.FromBlock > ecr.BlockNumber {
+		return fmt.Errorf("from block is after the block the logs were observed at")
+	}
+	if len(ecr.Logs) > math.MaxUint32 {
+		return fmt.Errorf("too many logs")
+	}
+	for idx, log := range ecr.Logs {
+		if log.BlockNumber > ecr.BlockNumber {
+			return fmt.Errorf("log %d is after the block it was observed at", idx)
+		}
+		if log.BlockNumber < ecr.FromBlock {
+			return fmt.Errorf("log %d is before the from block", idx)
+		}
+		if len(log.Topics) > MaxEthLogsTopics {
+			return fmt.Errorf("log %d has too many topics", idx)
+		}
+		if len(log.Data) > math.MaxUint32 {
+			return fmt.Errorf("log %d data too long", idx)
+		}
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_logs responses are equal.
+func (left *EthLogsQueryResponse) Equal(right *EthLogsQueryResponse) bool {
+	if left.BlockNumber != right.BlockNumber || left.BlockHash != right.BlockHash || left.BlockTime != right.BlockTime || left.FromBlock != right.FromBlock {
+		return false
+	}
+	if len(left.Logs) != len(right.Logs) {
+		return false
+	}
+	for idx := range left.Logs {
+		if !left.Logs[idx].Equal(right.Logs[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal verifies that two EVM logs are equal.
+func (left *EthLog) Equal(right *EthLog) bool {
+	if left.BlockNumber != right.BlockNumber || left.TxHash != right.TxHash || left.LogIndex != right.LogIndex {
+		return false
+	}
+	if len(left.Topics) != len(right.Topics) {
+		return false
+	}
+	for idx := range left.Topics {
+		if left.Topics[idx] != right.Topics[idx] {
+			return false
+		}
+	}
+	return bytes.Equal(left.Data, right.Data)
+}
+
+//
+// Implementation of EthStorageQueryResponse, which implements the ChainSpecificResponse for an EVM eth_storage query response.
+//
+
+func (e *EthStorageQueryResponse) Type() ChainSpecificQueryType {
+	return EthStorageQueryRequestType
+}
+
+// Marshal serializes the binary representation of an EVM eth_storage response.
+// This method calls Validate() and relies on it to range checks lengths, etc.
+func (ecr *EthStorageQueryResponse) Marshal() ([]byte, error) {
+	if err := ecr.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockNumber)
+	buf.Write(ecr.BlockHash[:])
+	vaa.MustWrite(buf, binary.BigEndian, ecr.BlockTime.UnixMicro())
+
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(ecr.Values)))
+	for _, value := range ecr.Values {
+		buf.Write(value[:])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal deserializes an EVM eth_storage response from a byte array
+func (ecr *EthStorageQueryResponse) Unmarshal(data []byte) error {
+	reader := bytes.NewReader(data[:])
+	return ecr.UnmarshalFromReader(reader)
+}
+
+// UnmarshalFromReader  deserializes an EVM eth_storage response from a byte array
+func (ecr *EthStorageQueryResponse) UnmarshalFromReader(reader *bytes.Reader) error {
+	if err := binary.Read(reader, binary.BigEndian, &ecr.BlockNumber); err != nil {
+		return fmt.Errorf("failed to read block number: %w", err)
+	}
+
+	blockHash := common.Hash{}
+	if n, err := reader.Read(blockHash[:]); err != nil || n != 32 {
+		return fmt.Errorf("failed to read block hash [%d]: %w", n, err)
+	}
+	ecr.BlockHash = blockHash
+
+	unixMicros := int64(0)
+	if err := binary.Read(reader, binary.BigEndian, &unixMicros); err != nil {
+		return fmt.Errorf("failed to read block timestamp: %w", err)
+	}
+	ecr.BlockTime = time.UnixMicro(unixMicros)
+
+	numValues := uint8(0)
+	if err := binary.Read(reader, binary.BigEndian, &numValues); err != nil {
+		return fmt.Errorf("failed to read number of values: %w", err)
+	}
+
+	for count := 0; count < int(numValues); count++ {
+		value := common.Hash{}
+		if n, err := reader.Read(value[:]); err != nil || n != 32 {
+			return fmt.Errorf("failed to read value [%d]: %w", n, err)
+		}
+		ecr.Values = append(ecr.Values, value)
+	}
+
+	return nil
+}
+
+// Validate does basic validation on an EVM eth_storage response.
+func (ecr *EthStorageQueryResponse) Validate() error {
+	if len(ecr.Values) <= 0 {
+		return fmt.Errorf("does not contain any values")
+	}
+	if len(ecr.Values) > math.MaxUint8 {
+		return fmt.Errorf("too many values")
+	}
+	return nil
+}
+
+// Equal verifies that two EVM eth_storage responses are equal.
+func (left *EthStorageQueryResponse) Equal(right *EthStorageQueryResponse) bool {
+	if left.BlockNumber != right.BlockNumber || left.BlockHash != right.BlockHash || left.BlockTime != right.BlockTime {
+		return false
+	}
+	if len(left.Values) != len(right.Values) {
+		return false
+	}
+	for idx := range left.Values {
+		if left.Values[idx] != right.Values[idx] {
+			return false
+		}
+	}
+	return true
+}
+
 //
 // Implementation of SolanaAccountQueryResponse, which implements the ChainSpecificResponse for a Solana sol_account query response.
 //
@@ -0,0 +1,158 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// ResponseBatchVersion is the first byte of a batch of signed query responses carried in a single SignedQueryResponse. It can not be mistaken for
+// a single response, whose first byte is its ResponseSchemaVersion.
+const ResponseBatchVersion uint8 = 0xFF
+
+// MaxResponseBatchSize is the maximum number of query responses carried in a single batch.
+const MaxResponseBatchSize = math.MaxUint8
+
+// MaxResponseBatchBytes is the maximum size of a marshaled batch. It is kept well under the 1 MiB default maximum message size of libp2p pubsub,
+// which the CCQ response topic uses, to leave room for the gossip envelope and the pubsub message fields. A batch over that size can not be
+// published, which would lose every response in it.
+const MaxResponseBatchBytes = 960 * 1024
+
+// responseBatchHeaderBytes is the size of the version and the number of responses at the start of a marshaled batch.
+const responseBatchHeaderBytes = 2
+
+// CollectResponseBatch returns the first response along with any further responses read from readC within the window after it, up to
+// MaxResponseBatchSize, so they can be published to p2p in a single message.
+func CollectResponseBatch(ctx context.Context, first *QueryResponsePublication, readC <-chan *QueryResponsePublication, window time.Duration) []*QueryResponsePublication {
+	batch := []*QueryResponsePublication{first}
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	for len(batch) < MaxResponseBatchSize {
+		select {
+		case <-ctx.Done():
+			return batch
+		case <-timer.C:
+			return batch
+		case msg, ok := <-readC:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, msg)
+		}
+	}
+	return batch
+}
+
+// SplitResponseBatch splits a set of signed query responses into consecutive groups, in order, that can each be published as a single message.
+// A group ends before the response that would take its marshaled batch past maxBytes or past MaxResponseBatchSize responses. A response that
+// is too large to be batched even on its own is put in a group of its own, so that it can be published as is.
+func SplitResponseBatch(responses []*gossipv1.SignedQueryResponse, maxBytes int) [][]*gossipv1.SignedQueryResponse {
+	groups := [][]*gossipv1.SignedQueryResponse{}
+	var group []*gossipv1.SignedQueryResponse
+	groupBytes := responseBatchHeaderBytes
+	for _, resp := range responses {
+		entryBytes := responseBatchEntryBytes(resp)
+		if len(group) != 0 && (len(group) >= MaxResponseBatchSize || groupBytes+entryBytes > maxBytes) {
+			groups = append(groups, group)
+			group, groupBytes = nil, responseBatchHeaderBytes
+		}
+		group = append(group, resp)
+		groupBytes += entryBytes
+	}
+	if len(group) != 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// responseBatchEntryBytes returns the number of bytes a signed query response takes up in a marshaled batch.
+func responseBatchEntryBytes(resp *gossipv1.SignedQueryResponse) int {
+	return 4 + len(resp.QueryResponse) + 1 + len(resp.Signature)
+}
+
+// MarshalResponseBatch packs a set of signed query responses into a single SignedQueryResponse. Each response keeps its own signature, so consumers
+// can verify and aggregate them exactly as if they had been published separately. The batch itself is not signed. See UnbatchResponses.
+func MarshalResponseBatch(responses []*gossipv1.SignedQueryResponse) (*gossipv1.SignedQueryResponse, error) {
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("batch does not contain any responses")
+	}
+	if len(responses) > MaxResponseBatchSize {
+		return nil, fmt.Errorf("too many responses in batch: %d", len(responses))
+	}
+
+	buf := new(bytes.Buffer)
+	vaa.MustWrite(buf, binary.BigEndian, ResponseBatchVersion)
+	vaa.MustWrite(buf, binary.BigEndian, uint8(len(responses)))
+	for idx, resp := range responses {
+		if len(resp.QueryResponse) > math.MaxUint32 {
+			return nil, fmt.Errorf("response %d too long", idx)
+		}
+		if len(resp.Signature) > math.MaxUint8 {
+			return nil, fmt.Errorf("signature of response %d too long", idx)
+		}
+		vaa.MustWrite(buf, binary.BigEndian, uint32(len(resp.QueryResponse)))
+		buf.Write(resp.QueryResponse)
+		vaa.MustWrite(buf, binary.BigEndian, uint8(len(resp.Signature)))
+		buf.Write(resp.Signature)
+	}
+
+	return &gossipv1.SignedQueryResponse{QueryResponse: buf.Bytes()}, nil
+}
+
+// UnbatchResponses returns the signed query responses carried in a SignedQueryResponse read from p2p. If it is not a batch, it is returned as is.
+func UnbatchResponses(msg *gossipv1.SignedQueryResponse) ([]*gossipv1.SignedQueryResponse, error) {
+	if len(msg.QueryResponse) == 0 || msg.QueryResponse[0] != ResponseBatchVersion {
+		return []*gossipv1.SignedQueryResponse{msg}, nil
+	}
+
+	reader := bytes.NewReader(msg.QueryResponse[1:])
+
+	var numResponses uint8
+	if err := binary.Read(reader, binary.BigEndian, &numResponses); err != nil {
+		return nil, fmt.Errorf("failed to read number of responses: %w", err)
+	}
+	if numResponses == 0 {
+		return nil, fmt.Errorf("batch does not contain any responses")
+	}
+
+	responses := make([]*gossipv1.SignedQueryResponse, 0, numResponses)
+	for idx := 0; idx < int(numResponses); idx++ {
+		var respLen uint32
+		if err := binary.Read(reader, binary.BigEndian, &respLen); err != nil {
+			return nil, fmt.Errorf("failed to read length of response %d: %w", idx, err)
+		}
+		if int64(respLen) > int64(reader.Len()) {
+			return nil, fmt.Errorf("response %d is truncated", idx)
+		}
+		resp := make([]byte, respLen)
+		if _, err := reader.Read(resp); err != nil {
+			return nil, fmt.Errorf("failed to read response %d: %w", idx, err)
+		}
+
+		var sigLen uint8
+		if err := binary.Read(reader, binary.BigEndian, &sigLen); err != nil {
+			return nil, fmt.Errorf("failed to read signature length of response %d: %w", idx, err)
+		}
+		if int(sigLen) > reader.Len() {
+			return nil, fmt.Errorf("signature of response %d is truncated", idx)
+		}
+		sig := make([]byte, sigLen)
+		if _, err := reader.Read(sig); err != nil {
+			return nil, fmt.Errorf("failed to read signature of response %d: %w", idx, err)
+		}
+
+		responses = append(responses, &gossipv1.SignedQueryResponse{QueryResponse: resp, Signature: sig})
+	}
+
+	if reader.Len() != 0 {
+		return nil, fmt.Errorf("excess bytes in batch")
+	}
+
+	return responses, nil
+}
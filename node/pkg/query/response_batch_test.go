@@ -0,0 +1,150 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+func signResponseForTest(t *testing.T, signer ResponseSigner, msg *QueryResponsePublication) *gossipv1.SignedQueryResponse {
+	t.Helper()
+	msgBytes, err := msg.Marshal()
+	require.NoError(t, err)
+	sig, err := signer.Sign(GetQueryResponseDigestFromBytes(msgBytes))
+	require.NoError(t, err)
+	return &gossipv1.SignedQueryResponse{QueryResponse: msgBytes, Signature: sig}
+}
+
+func TestResponsesReadyWithinWindowArePublishedAsOneBatch(t *testing.T) {
+	ctx := context.Background()
+	sk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	signer := NewSecp256k1ResponseSigner(sk)
+
+	chains := []vaa.ChainID{vaa.ChainIDPolygon, vaa.ChainIDEthereum, vaa.ChainIDArbitrum}
+	readC := make(chan *QueryResponsePublication, len(chains)+1)
+	msgs := []*QueryResponsePublication{}
+	for _, chainID := range chains {
+		msg := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, chainID))
+		msgs = append(msgs, msg)
+	}
+	for _, msg := range msgs[1:] {
+		readC <- msg
+	}
+
+	batch := CollectResponseBatch(ctx, msgs[0], readC, 50*time.Millisecond)
+	require.Len(t, batch, len(msgs))
+
+	signedResponses := []*gossipv1.SignedQueryResponse{}
+	for _, msg := range batch {
+		signedResponses = append(signedResponses, signResponseForTest(t, signer, msg))
+	}
+	batched, err := MarshalResponseBatch(signedResponses)
+	require.NoError(t, err)
+	assert.Nil(t, batched.Signature)
+
+	// The consumer gets back each response with its own valid signature.
+	unbatched, err := UnbatchResponses(batched)
+	require.NoError(t, err)
+	require.Len(t, unbatched, len(msgs))
+	for idx, signedResponse := range unbatched {
+		var resp QueryResponsePublication
+		require.NoError(t, resp.Unmarshal(signedResponse.QueryResponse))
+		assert.True(t, msgs[idx].Equal(&resp))
+
+		digest := GetQueryResponseDigestFromBytes(signedResponse.QueryResponse)
+		pubKey, err := ethCrypto.Ecrecover(digest.Bytes(), signedResponse.Signature)
+		require.NoError(t, err)
+		assert.Equal(t, ethCrypto.PubkeyToAddress(sk.PublicKey), ethCommon.BytesToAddress(ethCrypto.Keccak256(pubKey[1:])[12:]))
+	}
+}
+
+func TestResponseBatchIsClosedAtEndOfWindow(t *testing.T) {
+	ctx := context.Background()
+	readC := make(chan *QueryResponsePublication, 1)
+	first := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+
+	start := time.Now()
+	batch := CollectResponseBatch(ctx, first, readC, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	require.Len(t, batch, 1)
+	assert.Equal(t, first, batch[0])
+}
+
+func TestUnbatchResponsesPassesThroughSingleResponse(t *testing.T) {
+	sk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	msg := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+	signedResponse := signResponseForTest(t, NewSecp256k1ResponseSigner(sk), msg)
+
+	unbatched, err := UnbatchResponses(signedResponse)
+	require.NoError(t, err)
+	require.Len(t, unbatched, 1)
+	assert.Equal(t, signedResponse, unbatched[0])
+}
+
+func TestUnbatchResponsesWithTruncatedBatchShouldFail(t *testing.T) {
+	batched, err := MarshalResponseBatch([]*gossipv1.SignedQueryResponse{
+		{QueryResponse: []byte{0x01, 0x02, 0x03}, Signature: make([]byte, 65)},
+	})
+	require.NoError(t, err)
+
+	_, err = UnbatchResponses(&gossipv1.SignedQueryResponse{QueryResponse: batched.QueryResponse[:len(batched.QueryResponse)-1]})
+	require.ErrorContains(t, err, "truncated")
+
+	_, err = UnbatchResponses(&gossipv1.SignedQueryResponse{QueryResponse: append(batched.QueryResponse, 0x00)})
+	require.ErrorContains(t, err, "excess bytes")
+}
+
+func TestResponseBatchesEndAtTheByteLimit(t *testing.T) {
+	sig := make([]byte, 65)
+	responses := []*gossipv1.SignedQueryResponse{}
+	for idx := 0; idx < 5; idx++ {
+		responses = append(responses, &gossipv1.SignedQueryResponse{QueryResponse: make([]byte, 1000), Signature: sig})
+	}
+
+	// Each entry takes 4+1000+1+65 bytes, so only two fit in a batch, with its header, under the limit.
+	maxBytes := responseBatchHeaderBytes + 2*responseBatchEntryBytes(responses[0])
+	groups := SplitResponseBatch(responses, maxBytes)
+	require.Len(t, groups, 3)
+	assert.Equal(t, responses[0:2], groups[0])
+	assert.Equal(t, responses[2:4], groups[1])
+	assert.Equal(t, responses[4:5], groups[2])
+
+	for _, group := range groups {
+		batched, err := MarshalResponseBatch(group)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(batched.QueryResponse), maxBytes)
+	}
+}
+
+func TestOversizedResponseIsPublishedOnItsOwn(t *testing.T) {
+	sig := make([]byte, 65)
+	small := &gossipv1.SignedQueryResponse{QueryResponse: make([]byte, 100), Signature: sig}
+	large := &gossipv1.SignedQueryResponse{QueryResponse: make([]byte, MaxResponseBatchBytes), Signature: sig}
+
+	groups := SplitResponseBatch([]*gossipv1.SignedQueryResponse{small, large, small}, MaxResponseBatchBytes)
+	require.Len(t, groups, 3)
+	assert.Equal(t, []*gossipv1.SignedQueryResponse{small}, groups[0])
+	assert.Equal(t, []*gossipv1.SignedQueryResponse{large}, groups[1])
+	assert.Equal(t, []*gossipv1.SignedQueryResponse{small}, groups[2])
+}
+
+func TestResponseBatchesEndAtTheMaxBatchSize(t *testing.T) {
+	responses := []*gossipv1.SignedQueryResponse{}
+	for idx := 0; idx < MaxResponseBatchSize+1; idx++ {
+		responses = append(responses, &gossipv1.SignedQueryResponse{QueryResponse: []byte{0x01}, Signature: []byte{0x02}})
+	}
+
+	groups := SplitResponseBatch(responses, MaxResponseBatchBytes)
+	require.Len(t, groups, 2)
+	assert.Len(t, groups[0], MaxResponseBatchSize)
+	assert.Len(t, groups[1], 1)
+}
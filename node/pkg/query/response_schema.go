@@ -0,0 +1,39 @@
+package query
+
+import (
+	"fmt"
+)
+
+// ResponseSchemaVersion identifies the layout of a marshaled query response. A requester may pin a version in the query request,
+// so that it keeps receiving a layout it understands as new versions are added.
+type ResponseSchemaVersion uint8
+
+const (
+	// DefaultResponseSchemaVersion means the requester did not pin a version. The response is marshaled using ResponseSchemaVersion1,
+	// so requesters that predate schema versioning are not affected.
+	DefaultResponseSchemaVersion ResponseSchemaVersion = 0
+
+	// ResponseSchemaVersion1 is the original response layout.
+	ResponseSchemaVersion1 ResponseSchemaVersion = 1
+
+	// ResponseSchemaVersion2 is the version 1 layout followed by the Merkle root of the per chain responses, so the root is covered by the signature.
+	ResponseSchemaVersion2 ResponseSchemaVersion = 2
+)
+
+// Validate verifies that the version is one that is supported.
+func (v ResponseSchemaVersion) Validate() error {
+	switch v {
+	case DefaultResponseSchemaVersion, ResponseSchemaVersion1, ResponseSchemaVersion2:
+		return nil
+	default:
+		return fmt.Errorf("unsupported response schema version: %d", v)
+	}
+}
+
+// effective returns the version that is actually used to marshal the response.
+func (v ResponseSchemaVersion) effective() ResponseSchemaVersion {
+	if v == DefaultResponseSchemaVersion {
+		return ResponseSchemaVersion1
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+package query
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// ResponseSigningSchemeSecp256k1 signs responses with the guardian key, producing a 65 byte recoverable signature. This is the default.
+	ResponseSigningSchemeSecp256k1 = "secp256k1"
+
+	// ResponseSigningSchemeEd25519 signs responses with a separate ed25519 key, producing a 64 byte signature.
+	ResponseSigningSchemeEd25519 = "ed25519"
+)
+
+// ResponseSigner signs the digest of a query response before it is published.
+type ResponseSigner interface {
+	// Scheme returns the name of the signature scheme.
+	Scheme() string
+
+	// Sign signs the digest returned by QueryResponsePublication.SigningDigest.
+	Sign(digest common.Hash) ([]byte, error)
+}
+
+// SignerAvailability reports whether the key used to sign responses can currently be accessed. It may be implemented by a ResponseSigner
+// whose key can become temporarily unavailable, such as one backed by a remote signer.
+type SignerAvailability interface {
+	// Available returns an error if the signing key can not currently be accessed.
+	Available() error
+}
+
+// NewResponseSigner creates a response signer for the named scheme. The secp256k1 scheme uses the guardian key, and does not take a key file.
+// The ed25519 scheme loads its key from the key file, which must contain the hex encoded 32 byte seed.
+func NewResponseSigner(scheme string, guardianKey *ecdsa.PrivateKey, keyPath string) (ResponseSigner, error) {
+	switch scheme {
+	case "", ResponseSigningSchemeSecp256k1:
+		if keyPath != "" {
+			return nil, fmt.Errorf("the %s response signing scheme uses the guardian key and does not take a key file", ResponseSigningSchemeSecp256k1)
+		}
+		return NewSecp256k1ResponseSigner(guardianKey), nil
+	case ResponseSigningSchemeEd25519:
+		if keyPath == "" {
+			return nil, fmt.Errorf("the %s response signing scheme requires a key file", ResponseSigningSchemeEd25519)
+		}
+		key, err := loadEd25519Key(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		return NewEd25519ResponseSigner(key), nil
+	default:
+		return nil, fmt.Errorf("unsupported response signing scheme: %s", scheme)
+	}
+}
+
+// loadEd25519Key reads an ed25519 private key from a file containing the hex encoded seed.
+func loadEd25519Key(keyPath string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ed25519 key file: %w", err)
+	}
+
+	seed, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ed25519 key file: %w", err)
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ed25519 key file must contain a %d byte seed, it contains %d bytes", ed25519.SeedSize, len(seed))
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// secp256k1ResponseSigner signs responses with a secp256k1 key.
+type secp256k1ResponseSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewSecp256k1ResponseSigner creates a response signer that uses a secp256k1 key, normally the guardian key.
+func NewSecp256k1ResponseSigner(key *ecdsa.PrivateKey) ResponseSigner {
+	return &secp256k1ResponseSigner{key: key}
+}
+
+func (s *secp256k1ResponseSigner) Scheme() string {
+	return ResponseSigningSchemeSecp256k1
+}
+
+func (s *secp256k1ResponseSigner) Sign(digest common.Hash) ([]byte, error) {
+	return crypto.Sign(digest.Bytes(), s.key)
+}
+
+// ed25519ResponseSigner signs responses with an ed25519 key.
+type ed25519ResponseSigner struct {
+	key ed25519.PrivateKey
+}
+
+// NewEd25519ResponseSigner creates a response signer that uses an ed25519 key.
+func NewEd25519ResponseSigner(key ed25519.PrivateKey) ResponseSigner {
+	return &ed25519ResponseSigner{key: key}
+}
+
+func (s *ed25519ResponseSigner) Scheme() string {
+	return ResponseSigningSchemeEd25519
+}
+
+func (s *ed25519ResponseSigner) Sign(digest common.Hash) ([]byte, error) {
+	return ed25519.Sign(s.key, digest.Bytes()), nil
+}
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEd25519ResponseSignature(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	for idx := range seed {
+		seed[idx] = byte(idx)
+	}
+	keyPath := filepath.Join(t.TempDir(), "ccq_ed25519.key")
+	require.NoError(t, os.WriteFile(keyPath, []byte(hex.EncodeToString(seed)+"\n"), 0600))
+
+	signer, err := NewResponseSigner(ResponseSigningSchemeEd25519, nil, keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, ResponseSigningSchemeEd25519, signer.Scheme())
+
+	respPub := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+	digest, err := respPub.SigningDigest()
+	require.NoError(t, err)
+
+	sig, err := signer.Sign(digest)
+	require.NoError(t, err)
+	require.Equal(t, ed25519.SignatureSize, len(sig))
+
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	assert.True(t, ed25519.Verify(publicKey, digest.Bytes(), sig))
+
+	// The signature should not be valid for a different response.
+	respPub.PerChainResponses[0].Response.(*EthCallQueryResponse).BlockNumber++
+	digest2, err := respPub.SigningDigest()
+	require.NoError(t, err)
+	assert.False(t, ed25519.Verify(publicKey, digest2.Bytes(), sig))
+}
+
+func TestSecp256k1IsTheDefaultResponseSignature(t *testing.T) {
+	gk, err := common.LoadGuardianKey("dev.guardian.key", true)
+	require.NoError(t, err)
+
+	signer, err := NewResponseSigner("", gk, "")
+	require.NoError(t, err)
+	assert.Equal(t, ResponseSigningSchemeSecp256k1, signer.Scheme())
+
+	respPub := createQueryResponseFromRequest(t, createQueryRequestForTesting(t, vaa.ChainIDPolygon))
+	digest, err := respPub.SigningDigest()
+	require.NoError(t, err)
+
+	sig, err := signer.Sign(digest)
+	require.NoError(t, err)
+
+	publicKey, err := ethCrypto.SigToPub(digest.Bytes(), sig)
+	require.NoError(t, err)
+	assert.Equal(t, ethCrypto.PubkeyToAddress(gk.PublicKey), ethCrypto.PubkeyToAddress(*publicKey))
+}
+
+func TestNewResponseSignerWithInvalidConfigShouldFail(t *testing.T) {
+	gk, err := common.LoadGuardianKey("dev.guardian.key", true)
+	require.NoError(t, err)
+
+	shortKeyPath := filepath.Join(t.TempDir(), "short.key")
+	require.NoError(t, os.WriteFile(shortKeyPath, []byte("0102"), 0600))
+
+	tests := []struct {
+		label    string
+		scheme   string
+		keyPath  string
+		errorStr string
+	}{
+		{"unsupported scheme", "bls", "", "unsupported response signing scheme: bls"},
+		{"secp256k1 with key file", ResponseSigningSchemeSecp256k1, shortKeyPath, "the secp256k1 response signing scheme uses the guardian key and does not take a key file"},
+		{"ed25519 without key file", ResponseSigningSchemeEd25519, "", "the ed25519 response signing scheme requires a key file"},
+		{"ed25519 with short key", ResponseSigningSchemeEd25519, shortKeyPath, "ed25519 key file must contain a 32 byte seed, it contains 2 bytes"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			_, err := NewResponseSigner(tc.scheme, gk, tc.keyPath)
+			assert.EqualError(t, err, tc.errorStr)
+		})
+	}
+}
@@ -12,6 +12,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 func createQueryResponseFromRequest(t *testing.T, queryRequest *QueryRequest) *QueryResponsePublication {
@@ -105,6 +109,50 @@ func TestQueryResponseMarshalUnmarshal(t *testing.T) {
 	assert.True(t, respPub.Equal(&respPub2))
 }
 
+func TestQueryResponseWithSchemaVersion2MarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.ResponseSchemaVersion = ResponseSchemaVersion2
+	respPub := createQueryResponseFromRequest(t, queryRequest)
+	respPub.SchemaVersion = ResponseSchemaVersion2
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	// Version 2 is the version 1 layout with the merkle root appended.
+	root, err := respPub.MerkleRoot()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(2), respPubBytes[0])
+	assert.Equal(t, root.Bytes(), respPubBytes[len(respPubBytes)-len(root):])
+
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(respPubBytes))
+	assert.Equal(t, ResponseSchemaVersion2, respPub2.SchemaVersion)
+	assert.True(t, respPub.Equal(&respPub2))
+
+	// A root that does not match the per chain responses should be rejected.
+	respPubBytes[len(respPubBytes)-1] ^= 0xff
+	var respPub3 QueryResponsePublication
+	assert.EqualError(t, respPub3.Unmarshal(respPubBytes), "merkle root does not match the per chain responses")
+}
+
+func TestQueryResponseSchemaVersionMustMatchRequest(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	respPub := createQueryResponseFromRequest(t, queryRequest)
+
+	// An explicit version 1 is the same as the default.
+	respPub.SchemaVersion = ResponseSchemaVersion1
+	_, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	respPub.SchemaVersion = ResponseSchemaVersion2
+	_, err = respPub.Marshal()
+	assert.EqualError(t, err, "response schema version 2 does not match the requested version 1")
+
+	respPub.SchemaVersion = 9
+	_, err = respPub.Marshal()
+	assert.EqualError(t, err, "unsupported response schema version: 9")
+}
+
 func TestQueryResponseUnmarshalWithExtraBytesShouldFail(t *testing.T) {
 	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
 	respPub := createQueryResponseFromRequest(t, queryRequest)
@@ -263,6 +311,551 @@ func TestMarshalUnmarshalQueryResponseWithTooManyResultsShouldFail(t *testing.T)
 	require.Error(t, err)
 }
 
+func TestQueryResponseWithRevertedCallsMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.AllowRevertedCalls = true
+	respPub := createQueryResponseFromRequest(t, queryRequest)
+	resp := respPub.PerChainResponses[0].Response.(*EthCallQueryResponse)
+	resp.Results[0] = []byte{0x08, 0xc3, 0x79, 0xa0}
+	resp.Statuses = []EthCallResultStatus{EthCallReverted}
+	for range resp.Results[1:] {
+		resp.Statuses = append(resp.Statuses, EthCallResultExecuted)
+	}
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(respPubBytes))
+	assert.True(t, respPub.Equal(&respPub2))
+	assert.Equal(t, resp.Statuses, respPub2.PerChainResponses[0].Response.(*EthCallQueryResponse).Statuses)
+
+	// The statuses must be present if and only if the request allows reverted calls.
+	resp.Statuses = nil
+	_, err = respPub.Marshal()
+	assert.EqualError(t, err, "presence of call statuses in response 0 does not match the request")
+
+	resp.Statuses = []EthCallResultStatus{PreconditionFailed}
+	_, err = respPub.Marshal()
+	assert.ErrorContains(t, err, "number of statuses does not match number of results")
+}
+
+func TestQueryResponseWithPreferSpeedMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.PreferSpeed = true
+	respPub := createQueryResponseFromRequest(t, queryRequest)
+
+	// The flag must match the request.
+	_, err := respPub.Marshal()
+	assert.EqualError(t, err, "response prefer speed flag does not match the request")
+
+	respPub.PreferSpeed = true
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(respPubBytes))
+	assert.True(t, respPub.Equal(&respPub2))
+	assert.True(t, respPub2.PreferSpeed)
+}
+
+func TestQueryResponseWithPartialResultsMarshalUnmarshal(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	queryRequest.AllowPartialResults = true
+	respPub := createQueryResponseFromRequest(t, queryRequest)
+
+	// Fail the second query, and drop its response, so the responses after it must be matched to the right queries.
+	require.Greater(t, len(queryRequest.PerChainQueries), 2)
+	for range queryRequest.PerChainQueries {
+		respPub.Statuses = append(respPub.Statuses, PerChainResultSucceeded)
+	}
+	respPub.Statuses[1] = PerChainResultFailed
+	respPub.PerChainResponses = append(respPub.PerChainResponses[:1], respPub.PerChainResponses[2:]...)
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	require.NoError(t, respPub2.Unmarshal(respPubBytes))
+	assert.True(t, respPub.Equal(&respPub2))
+	assert.Equal(t, respPub.Statuses, respPub2.Statuses)
+
+	// The statuses are covered by the signature.
+	respPub2.Statuses[0] = PerChainResultFailed
+	assert.False(t, respPub.Equal(&respPub2))
+
+	// There must be a status for every query, and a response for every query that succeeded.
+	statuses := respPub.Statuses
+	respPub.Statuses = nil
+	_, err = respPub.Marshal()
+	assert.EqualError(t, err, "number of statuses does not match number of queries")
+
+	respPub.Statuses = statuses
+	respPub.Statuses[0] = PerChainResultFailed
+	_, err = respPub.Marshal()
+	assert.EqualError(t, err, "number of responses does not match number of queries")
+
+	respPub.Statuses[0] = 2
+	_, err = respPub.Marshal()
+	assert.EqualError(t, err, "invalid status for query 0: 2")
+}
+
+func TestQueryResponseWithStatusesWhenPartialResultsNotAllowedShouldFail(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	respPub := createQueryResponseFromRequest(t, queryRequest)
+	for range queryRequest.PerChainQueries {
+		respPub.Statuses = append(respPub.Statuses, PerChainResultSucceeded)
+	}
+
+	_, err := respPub.Marshal()
+	assert.EqualError(t, err, "response contains statuses but the request does not allow partial results")
+}
+
+func TestQueryResponseWithTruncatedPerChainResponseShouldFail(t *testing.T) {
+	queryRequest := createQueryRequestForTesting(t, vaa.ChainIDPolygon)
+	respPub := createQueryResponseFromRequest(t, queryRequest)
+	perChainResponseBytes, err := respPub.PerChainResponses[0].Marshal()
+	require.NoError(t, err)
+
+	var perChainResponse PerChainQueryResponse
+	assert.EqualError(t, perChainResponse.Unmarshal(perChainResponseBytes[:len(perChainResponseBytes)-1]), "response is truncated")
+}
+
+///////////// Eth Call With Precondition Query tests /////////////////////
+
+func createEthCallWithPreconditionQueryResponseFromRequest(t *testing.T, queryRequest *QueryRequest, preconditionMet bool) *QueryResponsePublication {
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	sig := [65]byte{}
+	signedQueryRequest := &gossipv1.SignedQueryRequest{
+		QueryRequest: queryRequestBytes,
+		Signature:    sig[:],
+	}
+
+	perChainResponses := []*PerChainQueryResponse{}
+	for idx, pcr := range queryRequest.PerChainQueries {
+		req := pcr.Query.(*EthCallWithPreconditionQueryRequest)
+		results := [][]byte{}
+		statuses := []EthCallResultStatus{}
+		for cdIdx := range req.CallData {
+			switch {
+			case cdIdx == 0:
+				results = append(results, req.ExpectedResult)
+				statuses = append(statuses, EthCallResultExecuted)
+			case preconditionMet:
+				results = append(results, []byte(fmt.Sprintf("Result %d", cdIdx)))
+				statuses = append(statuses, EthCallResultExecuted)
+			default:
+				results = append(results, []byte{})
+				statuses = append(statuses, PreconditionFailed)
+			}
+		}
+		perChainResponses = append(perChainResponses, &PerChainQueryResponse{
+			ChainId: pcr.ChainId,
+			Response: &EthCallWithPreconditionQueryResponse{
+				BlockNumber: uint64(1000 + idx),
+				Hash:        ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				Time:        timeForTest(t, time.Now()),
+				Results:     results,
+				Statuses:    statuses,
+			},
+		})
+	}
+
+	return &QueryResponsePublication{
+		Request:           signedQueryRequest,
+		PerChainResponses: perChainResponses,
+	}
+}
+
+func TestEthCallWithPreconditionQueryResponseMarshalUnmarshal(t *testing.T) {
+	for _, preconditionMet := range []bool{true, false} {
+		queryRequest := createEthCallWithPreconditionQueryRequestForTesting(t)
+		respPub := createEthCallWithPreconditionQueryResponseFromRequest(t, queryRequest, preconditionMet)
+
+		respPubBytes, err := respPub.Marshal()
+		require.NoError(t, err)
+
+		var respPub2 QueryResponsePublication
+		err = respPub2.Unmarshal(respPubBytes)
+		require.NoError(t, err)
+		require.NotNil(t, respPub2)
+
+		assert.True(t, respPub.Equal(&respPub2))
+		resp := respPub2.PerChainResponses[0].Response.(*EthCallWithPreconditionQueryResponse)
+		assert.Equal(t, preconditionMet, resp.PreconditionMet())
+	}
+}
+
+func TestMarshalOfEthCallWithPreconditionQueryResponseWithResultForSkippedCallShouldFail(t *testing.T) {
+	queryRequest := createEthCallWithPreconditionQueryRequestForTesting(t)
+	respPub := createEthCallWithPreconditionQueryResponseFromRequest(t, queryRequest, false)
+	resp := respPub.PerChainResponses[0].Response.(*EthCallWithPreconditionQueryResponse)
+	resp.Results[1] = []byte("should not be here")
+
+	_, err := respPub.Marshal()
+	require.Error(t, err)
+}
+
+///////////// End of Eth Call With Precondition Query tests //////////////
+
+///////////// Eth Call By Timestamp List Query tests /////////////////////
+
+func TestEthCallByTimestampListQueryResponseMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthCallByTimestampListQueryRequestForTesting(t, []uint64{1700000000000000, 1700000060000000, 1700000120000000})
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	sig := [65]byte{}
+	respPub := &QueryResponsePublication{
+		Request: &gossipv1.SignedQueryRequest{
+			QueryRequest: queryRequestBytes,
+			Signature:    sig[:],
+		},
+	}
+
+	req := queryRequest.PerChainQueries[0].Query.(*EthCallByTimestampListQueryRequest)
+	resp := &EthCallByTimestampListQueryResponse{}
+	for idx, timestamp := range req.TargetTimestamps {
+		resp.Responses = append(resp.Responses, &EthCallByTimestampQueryResponse{
+			TargetBlockNumber:    uint64(1000 + idx),
+			TargetBlockHash:      ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+			TargetBlockTime:      timeForTest(t, time.UnixMicro(int64(timestamp))),
+			FollowingBlockNumber: uint64(1001 + idx),
+			FollowingBlockHash:   ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e3"),
+			FollowingBlockTime:   timeForTest(t, time.UnixMicro(int64(timestamp)).Add(10*time.Second)),
+			Results:              [][]byte{[]byte(fmt.Sprintf("Result %d", idx))},
+		})
+	}
+	respPub.PerChainResponses = []*PerChainQueryResponse{{ChainId: vaa.ChainIDPolygon, Response: resp}}
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	err = respPub2.Unmarshal(respPubBytes)
+	require.NoError(t, err)
+	require.NotNil(t, respPub2)
+
+	assert.True(t, respPub.Equal(&respPub2))
+}
+
+///////////// End of Eth Call By Timestamp List Query tests //////////////
+
+///////////// Eth Tx Proof Query tests ///////////////////////////////////
+
+// createEthTxProofQueryResponseForTesting builds a transactions trie from the raw transactions and returns a response proving the one at txIdx.
+func createEthTxProofQueryResponseForTesting(t *testing.T, rawTxs [][]byte, txIdx int) *EthTxProofQueryResponse {
+	t.Helper()
+	txTrie := trie.NewEmpty(trie.NewDatabase(memorydb.New()))
+	for idx, rawTx := range rawTxs {
+		txTrie.Update(rlp.AppendUint64(nil, uint64(idx)), rawTx)
+	}
+
+	proofDb := memorydb.New()
+	require.NoError(t, txTrie.Prove(rlp.AppendUint64(nil, uint64(txIdx)), 0, proofDb))
+	proof := [][]byte{}
+	it := proofDb.NewIterator(nil, nil)
+	for it.Next() {
+		proof = append(proof, ethCommon.CopyBytes(it.Value()))
+	}
+	it.Release()
+
+	return &EthTxProofQueryResponse{
+		BlockNumber:      1000,
+		BlockHash:        ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		BlockTime:        timeForTest(t, time.Now()),
+		TransactionsRoot: txTrie.Hash(),
+		TxIndex:          uint32(txIdx),
+		Proof:            proof,
+	}
+}
+
+func TestEthTxProofQueryResponseMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthTxProofQueryRequestForTesting(t, ethCrypto.Keccak256Hash([]byte("Transaction 1")))
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	sig := [65]byte{}
+	respPub := &QueryResponsePublication{
+		Request: &gossipv1.SignedQueryRequest{
+			QueryRequest: queryRequestBytes,
+			Signature:    sig[:],
+		},
+		PerChainResponses: []*PerChainQueryResponse{
+			{
+				ChainId:  vaa.ChainIDPolygon,
+				Response: createEthTxProofQueryResponseForTesting(t, [][]byte{[]byte("Transaction 0"), []byte("Transaction 1"), []byte("Transaction 2")}, 1),
+			},
+		},
+	}
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	err = respPub2.Unmarshal(respPubBytes)
+	require.NoError(t, err)
+	require.NotNil(t, respPub2)
+
+	assert.True(t, respPub.Equal(&respPub2))
+}
+
+func TestEthTxProofQueryResponseVerify(t *testing.T) {
+	rawTxs := [][]byte{}
+	for idx := 0; idx < 20; idx++ {
+		rawTxs = append(rawTxs, []byte(fmt.Sprintf("Transaction %d", idx)))
+	}
+	resp := createEthTxProofQueryResponseForTesting(t, rawTxs, 17)
+
+	tx, err := resp.Verify(ethCrypto.Keccak256Hash(rawTxs[17]))
+	require.NoError(t, err)
+	assert.Equal(t, rawTxs[17], tx)
+
+	// The proof should not verify a different transaction.
+	_, err = resp.Verify(ethCrypto.Keccak256Hash(rawTxs[16]))
+	require.ErrorContains(t, err, "proven transaction does not match the tx hash")
+
+	// The proof should not verify against a different root.
+	resp.TransactionsRoot = ethCommon.HexToHash("0x01")
+	_, err = resp.Verify(ethCrypto.Keccak256Hash(rawTxs[17]))
+	require.ErrorContains(t, err, "invalid proof")
+}
+
+///////////// End of Eth Tx Proof Query tests ////////////////////////////
+
+///////////// Eth Storage Diff Query tests ///////////////////////////////
+
+func TestEthStorageDiffQueryResponseMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthStorageDiffQueryRequestForTesting(t, "0x28d9630", "0x28d9631")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	resp := &EthStorageDiffQueryResponse{
+		FromBlockNumber: 0x28d9630,
+		FromBlockHash:   ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		FromBlockTime:   timeForTest(t, time.Now().Add(-2*time.Second)),
+		ToBlockNumber:   0x28d9631,
+		ToBlockHash:     ethCommon.HexToHash("7777bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		ToBlockTime:     timeForTest(t, time.Now()),
+		Slots: []*EthStorageSlotDiff{
+			{OldValue: ethCommon.HexToHash("0x2a"), NewValue: ethCommon.HexToHash("0x2a"), Changed: false},
+			{OldValue: ethCommon.HexToHash("0x2a"), NewValue: ethCommon.HexToHash("0x2b"), Changed: true},
+			{OldValue: ethCommon.Hash{}, NewValue: ethCommon.Hash{}, Changed: false},
+		},
+	}
+
+	sig := [65]byte{}
+	respPub := &QueryResponsePublication{
+		Request: &gossipv1.SignedQueryRequest{
+			QueryRequest: queryRequestBytes,
+			Signature:    sig[:],
+		},
+		PerChainResponses: []*PerChainQueryResponse{
+			{
+				ChainId:  vaa.ChainIDPolygon,
+				Response: resp,
+			},
+		},
+	}
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	err = respPub2.Unmarshal(respPubBytes)
+	require.NoError(t, err)
+	require.NotNil(t, respPub2)
+
+	assert.True(t, respPub.Equal(&respPub2))
+
+	// The new value of an unchanged slot is not encoded.
+	respBytes, err := resp.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, 2*(8+32+8)+1+(1+32)+(1+64)+(1+32), len(respBytes))
+}
+
+func TestEthStorageDiffQueryResponseWithInconsistentChangedFlagShouldFail(t *testing.T) {
+	resp := &EthStorageDiffQueryResponse{
+		Slots: []*EthStorageSlotDiff{{OldValue: ethCommon.HexToHash("0x2a"), NewValue: ethCommon.HexToHash("0x2b"), Changed: false}},
+	}
+	_, err := resp.Marshal()
+	require.ErrorContains(t, err, "changed flag of slot 0 does not match its values")
+}
+
+///////////// End of Eth Storage Diff Query tests ////////////////////////
+
+///////////// Eth Block Probe Query tests ////////////////////////////////
+
+func TestEthBlockProbeQueryResponseMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthBlockProbeQueryRequestForTesting(t, "finalized")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	sig := [65]byte{}
+	respPub := &QueryResponsePublication{
+		Request: &gossipv1.SignedQueryRequest{
+			QueryRequest: queryRequestBytes,
+			Signature:    sig[:],
+		},
+		PerChainResponses: []*PerChainQueryResponse{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Response: &EthBlockProbeQueryResponse{
+					BlockNumber: 0x28d9630,
+					BlockHash:   ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+					BlockTime:   timeForTest(t, time.Now()),
+				},
+			},
+		},
+	}
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	err = respPub2.Unmarshal(respPubBytes)
+	require.NoError(t, err)
+	require.NotNil(t, respPub2)
+
+	assert.True(t, respPub.Equal(&respPub2))
+}
+
+func TestEthBlockProbeQueryResponseWithoutBlockHashShouldFail(t *testing.T) {
+	resp := &EthBlockProbeQueryResponse{BlockNumber: 0x28d9630}
+	_, err := resp.Marshal()
+	require.ErrorContains(t, err, "block hash is required")
+}
+
+///////////// End of Eth Block Probe Query tests /////////////////////////
+
+///////////// Eth Logs Query tests ///////////////////////////////////////
+
+func TestEthLogsQueryResponseMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthLogsQueryRequestForTesting(t, "0x28d9630", "0x28d9640")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	sig := [65]byte{}
+	respPub := &QueryResponsePublication{
+		Request: &gossipv1.SignedQueryRequest{
+			QueryRequest: queryRequestBytes,
+			Signature:    sig[:],
+		},
+		PerChainResponses: []*PerChainQueryResponse{
+			{
+				ChainId: vaa.ChainIDPolygon,
+				Response: &EthLogsQueryResponse{
+					BlockNumber: 0x28d9640,
+					BlockHash:   ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+					BlockTime:   timeForTest(t, time.Now()),
+					FromBlock:   0x28d9630,
+					Logs: []*EthLog{
+						{
+							BlockNumber: 0x28d9631,
+							TxHash:      ethCommon.HexToHash("0x1111bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+							LogIndex:    7,
+							Topics: []ethCommon.Hash{
+								ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+								ethCommon.HexToHash("0x42"),
+							},
+							Data: []byte{0x01, 0x02, 0x03},
+						},
+						{
+							BlockNumber: 0x28d9640,
+							TxHash:      ethCommon.HexToHash("0x2222bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+							LogIndex:    0,
+							Topics:      []ethCommon.Hash{ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")},
+							Data:        []byte{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	err = respPub2.Unmarshal(respPubBytes)
+	require.NoError(t, err)
+	require.NotNil(t, respPub2)
+
+	assert.True(t, respPub.Equal(&respPub2))
+}
+
+func TestEthLogsQueryResponseWithLogAfterBlockShouldFail(t *testing.T) {
+	resp := &EthLogsQueryResponse{
+		BlockNumber: 0x28d9640,
+		BlockHash:   ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		Logs:        []*EthLog{{BlockNumber: 0x28d9641}},
+	}
+	_, err := resp.Marshal()
+	require.ErrorContains(t, err, "log 0 is after the block it was observed at")
+}
+
+func TestEthLogsQueryResponseWithLogBeforeFromBlockShouldFail(t *testing.T) {
+	resp := &EthLogsQueryResponse{
+		BlockNumber: 0x28d9640,
+		BlockHash:   ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		FromBlock:   0x28d9630,
+		Logs:        []*EthLog{{BlockNumber: 0x28d962f}},
+	}
+	_, err := resp.Marshal()
+	require.ErrorContains(t, err, "log 0 is before the from block")
+}
+
+///////////// End of Eth Logs Query tests ////////////////////////////////
+
+///////////// Eth Storage Query tests ////////////////////////////////////
+
+func TestEthStorageQueryResponseMarshalUnmarshal(t *testing.T) {
+	queryRequest := createEthStorageQueryRequestForTesting(t, "0x28d9630")
+	queryRequestBytes, err := queryRequest.Marshal()
+	require.NoError(t, err)
+
+	resp := &EthStorageQueryResponse{
+		BlockNumber: 0x28d9630,
+		BlockHash:   ethCommon.HexToHash("9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		BlockTime:   timeForTest(t, time.Now()),
+		Values:      []ethCommon.Hash{ethCommon.HexToHash("0x2a"), ethCommon.HexToHash("0x2b"), {}},
+	}
+
+	sig := [65]byte{}
+	respPub := &QueryResponsePublication{
+		Request: &gossipv1.SignedQueryRequest{
+			QueryRequest: queryRequestBytes,
+			Signature:    sig[:],
+		},
+		PerChainResponses: []*PerChainQueryResponse{
+			{
+				ChainId:  vaa.ChainIDPolygon,
+				Response: resp,
+			},
+		},
+	}
+
+	respPubBytes, err := respPub.Marshal()
+	require.NoError(t, err)
+
+	var respPub2 QueryResponsePublication
+	err = respPub2.Unmarshal(respPubBytes)
+	require.NoError(t, err)
+	require.NotNil(t, respPub2)
+
+	assert.True(t, respPub.Equal(&respPub2))
+}
+
+func TestEthStorageQueryResponseWithNoValuesShouldFail(t *testing.T) {
+	resp := &EthStorageQueryResponse{BlockNumber: 0x28d9630}
+	_, err := resp.Marshal()
+	require.ErrorContains(t, err, "does not contain any values")
+}
+
+///////////// End of Eth Storage Query tests /////////////////////////////
+
 ///////////// Solana Account Query tests /////////////////////////////////
 
 func createSolanaAccountQueryResponseFromRequest(t *testing.T, queryRequest *QueryRequest) *QueryResponsePublication {
@@ -0,0 +1,138 @@
+package query
+
+import (
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// resultCache holds the most recent successful response to each per chain query. If a watcher returns a fatal error for a request that allows
+// cached results, a recent enough cached response is served in its place. It is only accessed from the query handler routine.
+type resultCache struct {
+	maxAge  time.Duration
+	entries map[string]*cachedResult // Key is the marshaled per chain query.
+}
+
+// cachedResult is a successful response to a per chain query and when it was received.
+type cachedResult struct {
+	response  ChainSpecificResponse
+	metadata  *PerChainResponseMetadata
+	storeTime time.Time
+
+	// preloaded is set if the response was stored by an operator preload, in which case it is served to identical queries without dispatching them.
+	preloaded bool
+}
+
+// servedResponse builds a response to a per chain query from a cache entry, with ServedFromCache set in the metadata.
+func (entry *cachedResult) servedResponse(requestID string, requestIdx int, chainID vaa.ChainID) *PerChainQueryResponseInternal {
+	cachedResp := CreatePerChainQueryResponseInternal(requestID, requestIdx, chainID, QuerySuccess, entry.response)
+	cachedResp.Metadata = &PerChainResponseMetadata{}
+	if entry.metadata != nil {
+		*cachedResp.Metadata = *entry.metadata
+	}
+	cachedResp.Metadata.ServedFromCache = true
+	return cachedResp
+}
+
+// newResultCache creates a result cache. It returns nil if the max age is zero, meaning results are not cached.
+func newResultCache(maxAge time.Duration) *resultCache {
+	if maxAge <= 0 {
+		return nil
+	}
+
+	return &resultCache{
+		maxAge:  maxAge,
+		entries: make(map[string]*cachedResult),
+	}
+}
+
+// resultCacheKey returns the key used to cache the response to a per chain query.
+func resultCacheKey(pcq *PerChainQueryRequest) (string, error) {
+	bytes, err := pcq.Marshal()
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// store caches a successful response to a per chain query. Responses that were themselves served from the cache are not stored again,
+// so that a result never appears to be newer than it is. Responses with per call statuses are not stored, since the key does not tell apart
+// requests that allow reverted calls. An entry stays preloaded if a later response replaces it. A nil object does nothing.
+func (rc *resultCache) store(pcq *PerChainQueryRequest, resp *PerChainQueryResponseInternal, now time.Time, preloaded bool) error {
+	if rc == nil || resp.Metadata.servedFromCache() || hasCallStatuses(resp.Response) {
+		return nil
+	}
+
+	key, err := resultCacheKey(pcq)
+	if err != nil {
+		return err
+	}
+
+	if entry, exists := rc.entries[key]; exists && entry.preloaded {
+		preloaded = true
+	}
+
+	rc.entries[key] = &cachedResult{response: resp.Response, metadata: resp.Metadata, storeTime: now, preloaded: preloaded}
+	return nil
+}
+
+// fallbackResponse returns a successful response built from the cache to be used in place of a fatal error response, or nil if there is none.
+// The request must allow cached results, and there must not be any failover watchers left for the per chain query. Requests that allow reverted
+// calls are never served from the cache. A nil object always returns nil.
+func (rc *resultCache) fallbackResponse(pq *pendingQuery, resp *PerChainQueryResponseInternal, now time.Time) *PerChainQueryResponseInternal {
+	if rc == nil || !pq.request.AllowCachedResults || pq.request.AllowRevertedCalls || len(pq.queries[resp.RequestIdx].failoverChannels) != 0 {
+		return nil
+	}
+
+	key, err := resultCacheKey(pq.request.PerChainQueries[resp.RequestIdx])
+	if err != nil {
+		return nil
+	}
+
+	entry, exists := rc.entries[key]
+	if !exists || now.Sub(entry.storeTime) > rc.maxAge {
+		return nil
+	}
+
+	return entry.servedResponse(resp.RequestID, resp.RequestIdx, resp.ChainId)
+}
+
+// preloadedResponse returns a successful response built from a preloaded cache entry for a newly received per chain query, or nil if there is none.
+// Queries with a max block age are never answered from the cache, since the preloaded block may be too old. Neither are queries that allow
+// reverted calls, since the preloaded response does not have per call statuses. A nil object always returns nil.
+func (rc *resultCache) preloadedResponse(req *PerChainQueryInternal, now time.Time) *PerChainQueryResponseInternal {
+	if rc == nil || req.Request.MaxBlockAge != 0 || req.AllowRevertedCalls {
+		return nil
+	}
+
+	key, err := resultCacheKey(req.Request)
+	if err != nil {
+		return nil
+	}
+
+	entry, exists := rc.entries[key]
+	if !exists || !entry.preloaded || now.Sub(entry.storeTime) > rc.maxAge {
+		return nil
+	}
+
+	return entry.servedResponse(req.RequestID, req.RequestIdx, req.Request.ChainId)
+}
+
+// hasCallStatuses returns true if the response is an eth_call response with a status for each call.
+func hasCallStatuses(response ChainSpecificResponse) bool {
+	resp, ok := response.(*EthCallQueryResponse)
+	return ok && resp.Statuses != nil
+}
+
+// prune drops the entries that are too old to be served. A nil object does nothing.
+func (rc *resultCache) prune(now time.Time) {
+	if rc == nil {
+		return
+	}
+
+	for key, entry := range rc.entries {
+		if now.Sub(entry.storeTime) > rc.maxAge {
+			delete(rc.entries, key)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package query
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxResultFilterLength is the maximum length of a PerChainQueryRequest.ResultFilter.
+const MaxResultFilterLength = math.MaxUint8
+
+// resultFilter is a parsed PerChainQueryRequest.ResultFilter. A log matches the filter if it satisfies every clause.
+type resultFilter []resultFilterClause
+
+// resultFilterClause compares a single 32 byte word of a log, either one of its topics or a word of its data, with a value.
+type resultFilterClause struct {
+	topic    bool
+	index    int
+	value    common.Hash
+	notEqual bool
+}
+
+// parseResultFilter parses a result filter expression. It consists of one or more clauses separated by "&&". Each clause is a field, an operator
+// and a value, such as "topic2 == 0x42". The field is topicN, the N'th topic of the log, or dataN, the N'th 32 byte word of the log data. The
+// operator is "==" or "!=". The value is a hex string starting with 0x of up to 32 bytes, which is left padded with zeros. A log that does not
+// have the field never equals the value. An empty expression returns nil, which matches every log.
+func parseResultFilter(expr string) (resultFilter, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	if len(expr) > MaxResultFilterLength {
+		return nil, fmt.Errorf("result filter is too long, may not be more than %d characters", MaxResultFilterLength)
+	}
+
+	filter := resultFilter{}
+	for _, clauseStr := range strings.Split(expr, "&&") {
+		clause := resultFilterClause{}
+		field, valueStr, found := strings.Cut(clauseStr, "!=")
+		if found {
+			clause.notEqual = true
+		} else if field, valueStr, found = strings.Cut(clauseStr, "=="); !found {
+			return nil, fmt.Errorf(`invalid result filter clause "%s", must be "field == value" or "field != value"`, strings.TrimSpace(clauseStr))
+		}
+
+		field = strings.TrimSpace(field)
+		var indexStr string
+		if indexStr, clause.topic = strings.CutPrefix(field, "topic"); !clause.topic {
+			if indexStr, found = strings.CutPrefix(field, "data"); !found {
+				return nil, fmt.Errorf(`invalid result filter field "%s", must be topicN or dataN`, field)
+			}
+		}
+		index, err := strconv.ParseUint(indexStr, 10, 8)
+		if err != nil || (clause.topic && index >= MaxEthLogsTopics) {
+			return nil, fmt.Errorf(`invalid index in result filter field "%s"`, field)
+		}
+		clause.index = int(index)
+
+		valueStr = strings.TrimSpace(valueStr)
+		value, err := parseResultFilterValue(valueStr)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value "%s" in result filter: %w`, valueStr, err)
+		}
+		clause.value = value
+
+		filter = append(filter, clause)
+	}
+
+	return filter, nil
+}
+
+// parseResultFilterValue parses a hex value of up to 32 bytes, left padding it with zeros.
+func parseResultFilterValue(str string) (common.Hash, error) {
+	hexStr, found := strings.CutPrefix(str, "0x")
+	if !found || len(hexStr) == 0 {
+		return common.Hash{}, fmt.Errorf("must be a hex string starting with 0x")
+	}
+	if len(hexStr) > 2*common.HashLength {
+		return common.Hash{}, fmt.Errorf("may not be more than %d bytes", common.HashLength)
+	}
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + hexStr
+	}
+	value, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(value), nil
+}
+
+// matches returns true if the log satisfies every clause of the filter.
+func (f resultFilter) matches(log *EthLog) bool {
+	for _, clause := range f {
+		var word common.Hash
+		present := false
+		if clause.topic {
+			if clause.index < len(log.Topics) {
+				word, present = log.Topics[clause.index], true
+			}
+		} else if end := (clause.index + 1) * common.HashLength; end <= len(log.Data) {
+			word, present = common.BytesToHash(log.Data[end-common.HashLength:end]), true
+		}
+
+		if (present && word == clause.value) == clause.notEqual {
+			return false
+		}
+	}
+	return true
+}
+
+// filterResponse returns a copy of an eth_logs response that only contains the logs that match the filter, so the response that is passed in
+// is not modified. Other responses, and every response if the filter is nil, are returned unchanged.
+func (f resultFilter) filterResponse(response ChainSpecificResponse) ChainSpecificResponse {
+	resp, ok := response.(*EthLogsQueryResponse)
+	if f == nil || !ok {
+		return response
+	}
+
+	filtered := *resp
+	filtered.Logs = []*EthLog{}
+	for _, log := range resp.Logs {
+		if f.matches(log) {
+			filtered.Logs = append(filtered.Logs, log)
+		}
+	}
+	return &filtered
+}
+
+// filterResponse applies the result filter of the per chain query, if any, to its response. The filter has already been validated.
+func (perChainQuery *PerChainQueryRequest) filterResponse(response ChainSpecificResponse) ChainSpecificResponse {
+	filter, err := parseResultFilter(perChainQuery.ResultFilter)
+	if err != nil {
+		return response
+	}
+	return filter.filterResponse(response)
+}
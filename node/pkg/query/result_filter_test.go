@@ -0,0 +1,106 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResultFilter(t *testing.T) {
+	filter, err := parseResultFilter("topic2 == 0x42 && data1 != 0x0102")
+	require.NoError(t, err)
+	assert.Equal(t, resultFilter{
+		{topic: true, index: 2, value: common.BytesToHash([]byte{0x42})},
+		{topic: false, index: 1, value: common.BytesToHash([]byte{0x01, 0x02}), notEqual: true},
+	}, filter)
+
+	// An odd number of hex digits is left padded.
+	filter, err = parseResultFilter("data0==0x123")
+	require.NoError(t, err)
+	assert.Equal(t, common.BytesToHash([]byte{0x01, 0x23}), filter[0].value)
+
+	filter, err = parseResultFilter("")
+	require.NoError(t, err)
+	assert.Nil(t, filter)
+}
+
+func TestParseResultFilterInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"topic0",
+		"topic0 < 0x42",
+		"topic4 == 0x42",
+		"topic == 0x42",
+		"topic-1 == 0x42",
+		"data256 == 0x42",
+		"block == 0x42",
+		"topic0 == 42",
+		"topic0 == 0x",
+		"topic0 == 0xzz",
+		"topic0 == 0x" + strings.Repeat("00", 33),
+		"topic0 == 0x42 &&",
+		"topic0 == 0x42 && " + strings.Repeat(" ", MaxResultFilterLength),
+	} {
+		_, err := parseResultFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestResultFilterMatches(t *testing.T) {
+	transferTopic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	log := &EthLog{
+		Topics: []common.Hash{transferTopic, common.BytesToHash([]byte{0x42})},
+		Data:   append(common.BytesToHash([]byte{0x01}).Bytes(), common.BytesToHash([]byte{0x02}).Bytes()...),
+	}
+
+	tests := []struct {
+		expr    string
+		matches bool
+	}{
+		{"topic0 == " + transferTopic.Hex(), true},
+		{"topic1 == 0x42", true},
+		{"topic1 == 0x43", false},
+		{"topic1 != 0x43", true},
+		{"data1 == 0x02", true},
+		{"data0 == 0x02", false},
+		{"topic1 == 0x42 && data0 == 0x01", true},
+		{"topic1 == 0x42 && data0 == 0x02", false},
+
+		// A log that does not have the field never equals the value.
+		{"topic2 == 0x00", false},
+		{"topic2 != 0x00", true},
+		{"data2 == 0x00", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			filter, err := parseResultFilter(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.matches, filter.matches(log))
+		})
+	}
+}
+
+func TestResultFilterDoesNotModifyOriginalResponse(t *testing.T) {
+	resp := &EthLogsQueryResponse{
+		BlockNumber: 42,
+		Logs: []*EthLog{
+			{LogIndex: 0, Topics: []common.Hash{common.BytesToHash([]byte{0x01})}},
+			{LogIndex: 1, Topics: []common.Hash{common.BytesToHash([]byte{0x02})}},
+		},
+	}
+
+	filter, err := parseResultFilter("topic0 == 0x02")
+	require.NoError(t, err)
+	filtered := filter.filterResponse(resp).(*EthLogsQueryResponse)
+	require.Len(t, filtered.Logs, 1)
+	assert.Equal(t, uint32(1), filtered.Logs[0].LogIndex)
+	assert.Equal(t, uint64(42), filtered.BlockNumber)
+	assert.Len(t, resp.Logs, 2)
+
+	// Responses to other query types are never filtered.
+	callResp := &EthCallQueryResponse{Results: [][]byte{{1}}}
+	assert.Same(t, callResp, filter.filterResponse(callResp))
+}
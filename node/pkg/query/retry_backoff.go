@@ -0,0 +1,45 @@
+package query
+
+import (
+	"math/rand"
+	"time"
+)
+
+// retryDue returns true once the query has waited long enough since it was last delivered to be retried. Until it has been retried on a timer,
+// that is the base retry interval.
+func (pcq *perChainQuery) retryDue(now time.Time, baseInterval time.Duration) bool {
+	wait := pcq.retryWait
+	if wait == 0 {
+		wait = baseInterval
+	}
+	return pcq.lastUpdateTime.Add(wait).Before(now)
+}
+
+// backOff doubles the interval before the next timed retry of the query, starting from the base retry interval and capped at maxInterval, so a
+// watcher whose RPC node is overloaded is not retried at the same cadence. Up to a quarter of the interval is added as jitter, so the retries of
+// queries that failed together are spread out.
+func (pcq *perChainQuery) backOff(baseInterval time.Duration, maxInterval time.Duration) {
+	interval := pcq.backoffInterval
+	if interval == 0 {
+		interval = baseInterval
+	}
+	interval *= 2
+	if interval > maxInterval {
+		interval = maxInterval
+	}
+	if interval < baseInterval {
+		interval = baseInterval
+	}
+
+	pcq.backoffInterval = interval
+	pcq.retryWait = interval
+	if jitter := int64(interval / 4); jitter > 0 {
+		pcq.retryWait += time.Duration(rand.Int63n(jitter)) // nolint:gosec
+	}
+}
+
+// resetBackoff goes back to retrying the query at the base retry interval.
+func (pcq *perChainQuery) resetBackoff() {
+	pcq.backoffInterval = 0
+	pcq.retryWait = 0
+}
@@ -0,0 +1,83 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SignerLogLevels overrides the log level used while processing the requests of specific signers, so that an operator can, for example,
+// turn on debug logging for a single integrator without flooding the logs for everyone else. Signers that are not listed use the level of the
+// handler logger.
+type SignerLogLevels map[ethCommon.Address]zapcore.Level
+
+// logger returns the logger to be used while processing a request from the specified signer. It may be called on a nil object.
+func (l SignerLogLevels) logger(qLogger *zap.Logger, signer ethCommon.Address) *zap.Logger {
+	level, exists := l[signer]
+	if !exists {
+		return qLogger
+	}
+
+	return qLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &levelOverrideCore{Core: core, level: level}
+	}))
+}
+
+// levelOverrideCore is a zapcore.Core that replaces the level of the core it wraps, which may be either higher or lower.
+type levelOverrideCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c *levelOverrideCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+func (c *levelOverrideCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelOverrideCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelOverrideCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// Do not defer to the wrapped core, since it would apply its own level.
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// ParseSignerLogLevels parses a comma separated list of "signer:level" entries, such as "0xbeFA429d57cD18b7F8A4d91A2da9AB4AF05d0FBe:debug".
+// The level is any level understood by zap. An empty string returns nil, meaning no signers have an override.
+func ParseSignerLogLevels(str string) (SignerLogLevels, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	levels := make(SignerLogLevels)
+	for _, entry := range strings.Split(str, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`invalid signer log level "%s", must be "signer:level"`, entry)
+		}
+
+		if !ethCommon.IsHexAddress(fields[0]) {
+			return nil, fmt.Errorf(`invalid signer in signer log level "%s"`, entry)
+		}
+		signer := ethCommon.HexToAddress(fields[0])
+
+		if _, exists := levels[signer]; exists {
+			return nil, fmt.Errorf(`duplicate signer in signer log level "%s"`, entry)
+		}
+
+		level, err := zapcore.ParseLevel(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid level in signer log level "%s": %w`, entry, err)
+		}
+
+		levels[signer] = level
+	}
+
+	return levels, nil
+}
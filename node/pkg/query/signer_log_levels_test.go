@@ -0,0 +1,85 @@
+package query
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"testing"
+
+	"github.com/certusone/wormhole/node/pkg/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSignerLogLevels(t *testing.T) {
+	levels, err := ParseSignerLogLevels("0x" + testSigner + ":debug, 0x0000000000000000000000000000000000000001:warn")
+	require.NoError(t, err)
+	assert.Equal(t, SignerLogLevels{
+		ethCommon.HexToAddress(testSigner): zapcore.DebugLevel,
+		ethCommon.HexToAddress("0x1"):      zapcore.WarnLevel,
+	}, levels)
+
+	levels, err = ParseSignerLogLevels("")
+	require.NoError(t, err)
+	assert.Nil(t, levels)
+}
+
+func TestParseSignerLogLevelsInvalidEntries(t *testing.T) {
+	for _, str := range []string{
+		testSigner,
+		testSigner + ":debug:info",
+		"0x1234:debug",
+		testSigner + ":verbose",
+		testSigner + ":debug," + testSigner + ":info",
+	} {
+		_, err := ParseSignerLogLevels(str)
+		assert.Error(t, err, str)
+	}
+}
+
+func TestSignerLogLevelEnablesDebugLogsForThatSignerOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The handler logs at info, except for the requests of the debug signer.
+	observedCore, observedLogs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(observedCore)
+
+	debugSk, err := ethCrypto.GenerateKey()
+	require.NoError(t, err)
+	debugSigner := ethCrypto.PubkeyToAddress(debugSk.PublicKey)
+
+	// Use the config from a real query handler so that both signers can be allowed.
+	qh := NewQueryHandler(logger, common.GoTest, testSigner, nil, nil, nil, nil, HandlerConfig{SignerLogLevels: SignerLogLevels{debugSigner: zapcore.DebugLevel}})
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, qh.config)
+	require.NoError(t, qh.UpdateAllowedRequesters(ctx, testSigner+","+debugSigner.Hex()))
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+
+	requestIDs := []string{}
+	for _, sk := range []*ecdsa.PrivateKey{md.sk, debugSk} {
+		md.resetState()
+		md.setExpectedResults(expectedResults)
+		signedQueryRequest, _ := createSignedQueryRequestForTesting(t, sk, perChainQueries)
+		md.signedQueryReqWriteC <- signedQueryRequest
+		require.NotNil(t, md.waitForResponse())
+		requestIDs = append(requestIDs, hex.EncodeToString(signedQueryRequest.Signature)+":"+QueryRequestDigest(common.GoTest, signedQueryRequest.QueryRequest).String())
+	}
+
+	// Both requests should have been logged at info, but only the debug signer's request should have been logged at debug.
+	for _, requestID := range requestIDs {
+		assert.NotZero(t, observedLogs.FilterLevelExact(zapcore.InfoLevel).FilterField(zap.String("requestID", requestID)).Len())
+	}
+	debugLogs := observedLogs.FilterLevelExact(zapcore.DebugLevel)
+	assert.Zero(t, debugLogs.FilterField(zap.String("requestID", requestIDs[0])).Len())
+	assert.NotZero(t, debugLogs.FilterField(zap.String("requestID", requestIDs[1])).Len())
+}
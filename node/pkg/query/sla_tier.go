@@ -0,0 +1,91 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// SlaTier is the latency a requester asks the guardian to meet. Per chain queries of requests with a tighter tier are retried before those
+// with a looser one. If the guardian is configured with a query time estimate, requests whose tier clearly cannot be met given the work
+// already waiting on the watchers are failed fast with SlaCannotBeMet, rather than being answered late.
+type SlaTier uint8
+
+const (
+	// SlaTierBestEffort means the requester did not ask for a latency. The request is only bounded by the request timeout.
+	SlaTierBestEffort SlaTier = 0
+
+	// SlaTierStandard asks for the request to be answered within ten seconds of being received.
+	SlaTierStandard SlaTier = 1
+
+	// SlaTierFast asks for the request to be answered within two seconds of being received.
+	SlaTierFast SlaTier = 2
+)
+
+// Validate verifies that the tier is one that is supported.
+func (t SlaTier) Validate() error {
+	switch t {
+	case SlaTierBestEffort, SlaTierStandard, SlaTierFast:
+		return nil
+	default:
+		return fmt.Errorf("unsupported sla tier: %d", t)
+	}
+}
+
+// Deadline returns how long after being received the request should be answered. Zero means there is no deadline.
+func (t SlaTier) Deadline() time.Duration {
+	switch t {
+	case SlaTierStandard:
+		return 10 * time.Second
+	case SlaTierFast:
+		return 2 * time.Second
+	default:
+		return 0
+	}
+}
+
+// chainBacklog returns the number of per chain queries that are still awaiting a response on each chain, across all of the pending queries.
+func chainBacklog(pendingQueries map[string]*pendingQuery) map[vaa.ChainID]int {
+	backlog := make(map[vaa.ChainID]int)
+	for _, pq := range pendingQueries {
+		if pq.leader != nil {
+			continue
+		}
+		for requestIdx, pcq := range pq.queries {
+			if pq.isPending(requestIdx) && pcq.leader == nil {
+				backlog[pcq.req.Request.ChainId]++
+			}
+		}
+	}
+	return backlog
+}
+
+// estimatedQueryTime returns how long a per chain query for the specified chain is expected to take to be answered, if the specified number
+// of queries are already waiting for the workers of its watcher.
+func estimatedQueryTime(chainID vaa.ChainID, backlog int, queryTimeEstimate time.Duration) time.Duration {
+	numWorkers := GetPerChainConfig(chainID).NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = 1
+	}
+	return time.Duration(backlog/numWorkers+1) * queryTimeEstimate
+}
+
+// slaCannotBeMet returns true if the request has an SLA tier, and one of the specified per chain queries, which are about to be dispatched,
+// is not expected to be answered before the deadline of the tier. It always returns false if the query time estimate is zero.
+func (pq *pendingQuery) slaCannotBeMet(toDispatch []*perChainQuery, backlog map[vaa.ChainID]int, queryTimeEstimate time.Duration, now time.Time) bool {
+	deadline := pq.request.SlaTier.Deadline()
+	if deadline == 0 || queryTimeEstimate == 0 {
+		return false
+	}
+
+	remaining := pq.receiveTime.Add(deadline).Sub(now)
+	for _, pcq := range toDispatch {
+		chainID := pcq.req.Request.ChainId
+		if estimatedQueryTime(chainID, backlog[chainID], queryTimeEstimate) > remaining {
+			return true
+		}
+	}
+
+	return false
+}
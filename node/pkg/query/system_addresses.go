@@ -0,0 +1,85 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+)
+
+// SystemAddresses lists, for each chain, addresses that may not be the target of a call, such as precompiles or other system contracts
+// that do not make sense to query. Requests with a call to one of them are rejected with SystemAddressNotQueryable.
+type SystemAddresses map[vaa.ChainID]map[ethCommon.Address]struct{}
+
+// EvmPrecompileAddresses are the addresses of the precompiled contracts that are standard across EVM chains, 0x01 through 0x0a.
+var EvmPrecompileAddresses = func() []ethCommon.Address {
+	addrs := []ethCommon.Address{}
+	for idx := 1; idx <= 10; idx++ {
+		addrs = append(addrs, ethCommon.BytesToAddress([]byte{byte(idx)}))
+	}
+	return addrs
+}()
+
+// deniedTarget returns the first call target in the per chain query that is a system address on its chain, and true. Query types
+// without calls never have a denied target. It may be called on a nil object.
+func (s SystemAddresses) deniedTarget(pcq *PerChainQueryRequest) (ethCommon.Address, bool) {
+	denied, exists := s[pcq.ChainId]
+	if !exists {
+		return ethCommon.Address{}, false
+	}
+
+	q, ok := pcq.Query.(interface{ CallDataList() []*EthCallData })
+	if !ok {
+		return ethCommon.Address{}, false
+	}
+
+	for _, cd := range q.CallDataList() {
+		to := ethCommon.BytesToAddress(cd.To)
+		if _, exists := denied[to]; exists {
+			return to, true
+		}
+	}
+
+	return ethCommon.Address{}, false
+}
+
+// ParseSystemAddresses parses a comma separated list of "chain:address" entries, such as "polygon:0x0000000000000000000000000000000000001010".
+// The chain is the chain name. The address may also be "precompiles", which stands for all of EvmPrecompileAddresses. An empty string returns nil,
+// meaning no addresses are denied.
+func ParseSystemAddresses(str string) (SystemAddresses, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	addrs := make(SystemAddresses)
+	for _, entry := range strings.Split(str, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf(`invalid system address "%s", must be "chain:address"`, entry)
+		}
+
+		chainID, err := vaa.ChainIDFromString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf(`invalid chain in system address "%s": %w`, entry, err)
+		}
+
+		var entryAddrs []ethCommon.Address
+		if fields[1] == "precompiles" {
+			entryAddrs = EvmPrecompileAddresses
+		} else if ethCommon.IsHexAddress(fields[1]) {
+			entryAddrs = []ethCommon.Address{ethCommon.HexToAddress(fields[1])}
+		} else {
+			return nil, fmt.Errorf(`invalid address in system address "%s"`, entry)
+		}
+
+		if _, exists := addrs[chainID]; !exists {
+			addrs[chainID] = make(map[ethCommon.Address]struct{})
+		}
+		for _, addr := range entryAddrs {
+			addrs[chainID][addr] = struct{}{}
+		}
+	}
+
+	return addrs, nil
+}
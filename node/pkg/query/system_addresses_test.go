@@ -0,0 +1,78 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSystemAddresses(t *testing.T) {
+	addrs, err := ParseSystemAddresses("polygon:precompiles, polygon:0x0000000000000000000000000000000000001010,bsc:0x0000000000000000000000000000000000001000")
+	require.NoError(t, err)
+	assert.Equal(t, 11, len(addrs[vaa.ChainIDPolygon]))
+	assert.Contains(t, addrs[vaa.ChainIDPolygon], ethCommon.HexToAddress("0x01"))
+	assert.Contains(t, addrs[vaa.ChainIDPolygon], ethCommon.HexToAddress("0x0a"))
+	assert.Contains(t, addrs[vaa.ChainIDPolygon], ethCommon.HexToAddress("0x1010"))
+	assert.Equal(t, map[ethCommon.Address]struct{}{ethCommon.HexToAddress("0x1000"): {}}, addrs[vaa.ChainIDBSC])
+
+	addrs, err = ParseSystemAddresses("")
+	require.NoError(t, err)
+	assert.Nil(t, addrs)
+}
+
+func TestParseSystemAddressesInvalidEntries(t *testing.T) {
+	for _, str := range []string{
+		"polygon",
+		"polygon:precompiles:0x01",
+		"notAChain:precompiles",
+		"polygon:0x1234",
+		"polygon:everything",
+	} {
+		_, err := ParseSystemAddresses(str)
+		assert.Error(t, err, str)
+	}
+}
+
+func TestQueryToPrecompileAddressIsRejected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	addrs, err := ParseSystemAddresses("polygon:precompiles")
+	require.NoError(t, err)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, watcherChainsForTest, HandlerConfig{SystemAddresses: addrs})
+
+	// A call to the ecrecover precompile on polygon is rejected.
+	perChainQuery := createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2)
+	perChainQuery.Query.(*EthCallQueryRequest).CallData[1].To = ethCommon.HexToAddress("0x01").Bytes()
+	perChainQueries := []*PerChainQueryRequest{perChainQuery}
+	md.setExpectedResults(createExpectedResultsForTest(t, perChainQueries))
+	signedQueryRequest, _ := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, SystemAddressNotQueryable, failure.Reason)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+
+	// The same call on a chain without system addresses is allowed.
+	md.resetState()
+	perChainQuery = createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9630", 2)
+	perChainQuery.Query.(*EthCallQueryRequest).CallData[1].To = ethCommon.HexToAddress("0x01").Bytes()
+	perChainQueries = []*PerChainQueryRequest{perChainQuery}
+	expectedResults := createExpectedResultsForTest(t, perChainQueries)
+	md.setExpectedResults(expectedResults)
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+}
@@ -0,0 +1,157 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	gossipv1 "github.com/certusone/wormhole/node/pkg/proto/gossip/v1"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+// watcherRegistration adds the query request channel of a watcher that was not running when the handler started. See QueryHandler.RegisterWatcher.
+type watcherRegistration struct {
+	chainID   vaa.ChainID
+	queryReqC chan *PerChainQueryInternal
+}
+
+// heldRequest is a query request that is waiting for the watchers of some of its chains to register.
+type heldRequest struct {
+	signedRequest *gossipv1.SignedQueryRequest
+	requestID     string
+	signer        ethCommon.Address
+	missingChains []vaa.ChainID
+	deadline      time.Time
+}
+
+// registrationGrace holds query requests that target chains whose watchers have not registered yet, and hands them back to the handler to be
+// processed from the start once the watchers register. See HandlerConfig.WatcherRegistrationGracePeriod. A nil object means requests are never held.
+type registrationGrace struct {
+	period time.Duration
+	held   []*heldRequest
+
+	// releasedC passes the released requests back to the handler. Its capacity bounds the number of requests that may be held.
+	releasedC chan *gossipv1.SignedQueryRequest
+}
+
+// newRegistrationGrace returns the grace handling for the configured period, and the channel the handler should read inbound requests from,
+// which also carries the released requests. If the period is zero, it returns nil and the inbound channel itself.
+func newRegistrationGrace(ctx context.Context, period time.Duration, signedQueryReqC <-chan *gossipv1.SignedQueryRequest) (*registrationGrace, <-chan *gossipv1.SignedQueryRequest) {
+	if period == 0 {
+		return nil, signedQueryReqC
+	}
+
+	g := &registrationGrace{
+		period:    period,
+		releasedC: make(chan *gossipv1.SignedQueryRequest, SignedQueryRequestChannelSize),
+	}
+	mergedC := make(chan *gossipv1.SignedQueryRequest)
+	go g.forward(ctx, signedQueryReqC, mergedC)
+	return g, mergedC
+}
+
+// forward passes both the inbound requests and the released requests to the handler.
+func (g *registrationGrace) forward(ctx context.Context, signedQueryReqC <-chan *gossipv1.SignedQueryRequest, mergedC chan<- *gossipv1.SignedQueryRequest) {
+	for {
+		var signedRequest *gossipv1.SignedQueryRequest
+		select {
+		case <-ctx.Done():
+			return
+		case signedRequest = <-signedQueryReqC:
+		case signedRequest = <-g.releasedC:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case mergedC <- signedRequest:
+		}
+	}
+}
+
+// hold holds a request until the watchers of the missing chains register, or the grace period passes. It returns false if the request is not
+// held, either because grace is disabled or because too many requests are already waiting, in which case it should be processed as normal.
+func (g *registrationGrace) hold(signedRequest *gossipv1.SignedQueryRequest, requestID string, signer ethCommon.Address, missingChains []vaa.ChainID, now time.Time) bool {
+	if g == nil || len(g.held)+len(g.releasedC) >= cap(g.releasedC) {
+		return false
+	}
+
+	g.held = append(g.held, &heldRequest{
+		signedRequest: signedRequest,
+		requestID:     requestID,
+		signer:        signer,
+		missingChains: missingChains,
+		deadline:      now.Add(g.period),
+	})
+	return true
+}
+
+// release hands the held requests whose watchers have all registered back to the handler. It never blocks, since hold leaves room for every
+// held request in the channel.
+func (g *registrationGrace) release(chainQueryReqC map[vaa.ChainID]chan *PerChainQueryInternal) {
+	if g == nil {
+		return
+	}
+
+	remaining := g.held[:0]
+	for _, hr := range g.held {
+		if len(hr.stillMissing(chainQueryReqC)) != 0 {
+			remaining = append(remaining, hr)
+			continue
+		}
+		g.releasedC <- hr.signedRequest
+	}
+	g.held = remaining
+}
+
+// expire removes and returns the held requests whose grace period has passed.
+func (g *registrationGrace) expire(now time.Time) []*heldRequest {
+	if g == nil {
+		return nil
+	}
+
+	var expired []*heldRequest
+	remaining := g.held[:0]
+	for _, hr := range g.held {
+		if hr.deadline.Before(now) {
+			expired = append(expired, hr)
+		} else {
+			remaining = append(remaining, hr)
+		}
+	}
+	g.held = remaining
+	return expired
+}
+
+// stillMissing returns the chains the request is waiting on that still do not have a watcher.
+func (hr *heldRequest) stillMissing(chainQueryReqC map[vaa.ChainID]chan *PerChainQueryInternal) []vaa.ChainID {
+	missingChains := []vaa.ChainID{}
+	for _, chainID := range hr.missingChains {
+		if _, exists := chainQueryReqC[chainID]; !exists {
+			missingChains = append(missingChains, chainID)
+		}
+	}
+	return missingChains
+}
+
+// unregisteredChains returns the chains targeted by the per chain queries that support queries, but whose watchers have not registered. Each
+// chain is only listed once. Chains that do not support queries are not listed, since waiting for them would never help.
+func unregisteredChains(perChainQueries []*PerChainQueryRequest, chainQueryReqC map[vaa.ChainID]chan *PerChainQueryInternal) []vaa.ChainID {
+	missingChains := []vaa.ChainID{}
+	seen := make(map[vaa.ChainID]struct{})
+	for _, pcq := range perChainQueries {
+		chainID := pcq.ChainId
+		if _, exists := seen[chainID]; exists {
+			continue
+		}
+		seen[chainID] = struct{}{}
+
+		_, watched := chainQueryReqC[chainID]
+		if !watched && GetPerChainConfig(chainID).NumWorkers > 0 {
+			missingChains = append(missingChains, chainID)
+		}
+	}
+
+	return missingChains
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
+	"go.uber.org/zap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createQueryHandlerForTestWithLateWatcher creates the mock environment with a Polygon watcher, with BSC watchers allowed to register later
+// using the returned channel.
+func createQueryHandlerForTestWithLateWatcher(t *testing.T, ctx context.Context, logger *zap.Logger, gracePeriod time.Duration) (*mockData, chan<- watcherRegistration) {
+	registrationC := make(chan watcherRegistration)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, []vaa.ChainID{vaa.ChainIDPolygon}, HandlerConfig{
+		WatcherRegistrationGracePeriod: gracePeriod,
+		watcherRegistrationC:           registrationC,
+	})
+	return md, registrationC
+}
+
+func TestRequestIsHeldUntilItsWatcherRegisters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md, registrationC := createQueryHandlerForTestWithLateWatcher(t, ctx, logger, 2*requestTimeoutForTest)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// Nothing should be dispatched while the BSC watcher is missing.
+	time.Sleep(requestTimeoutForTest / 2)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Nil(t, md.getQueryResponsePublication())
+
+	// Once the BSC watcher registers, the request should be processed.
+	bscQueryReqC := make(chan *PerChainQueryInternal)
+	md.startMockWatcher(t, ctx, logger, vaa.ChainIDBSC, bscQueryReqC)
+	registrationC <- watcherRegistration{chainID: vaa.ChainIDBSC, queryReqC: bscQueryReqC}
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDPolygon))
+	assert.Equal(t, 1, md.getRequestsPerChain(vaa.ChainIDBSC))
+	assert.Nil(t, md.getFailure())
+}
+
+func TestHeldRequestOnlyUsesUpOneRateLimitToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	// The bucket holds a single token, so the request would be rate limited when it is released if it had used one up when it was held.
+	_, restrictions, err := parseAllowedRequesters(testSigner + ":1")
+	require.NoError(t, err)
+	registrationC := make(chan watcherRegistration)
+	md := createQueryHandlerForTestWithConfig(t, ctx, logger, []vaa.ChainID{vaa.ChainIDPolygon}, HandlerConfig{
+		WatcherRegistrationGracePeriod: 2 * requestTimeoutForTest,
+		requesterRestrictions:          restrictions,
+		watcherRegistrationC:           registrationC,
+	})
+
+	perChainQueries := []*PerChainQueryRequest{createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3)}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	expectedResults := createExpectedResultsForTest(t, queryRequest.PerChainQueries)
+	md.setExpectedResults(expectedResults)
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	// Make sure the request is held before the BSC watcher registers.
+	time.Sleep(requestTimeoutForTest / 2)
+	bscQueryReqC := make(chan *PerChainQueryInternal)
+	md.startMockWatcher(t, ctx, logger, vaa.ChainIDBSC, bscQueryReqC)
+	registrationC <- watcherRegistration{chainID: vaa.ChainIDBSC, queryReqC: bscQueryReqC}
+
+	queryResponsePublication := md.waitForResponse()
+	require.NotNil(t, queryResponsePublication)
+	assert.True(t, validateResponseForTest(t, queryResponsePublication, signedQueryRequest, queryRequest, expectedResults))
+	assert.Nil(t, md.getFailure())
+}
+
+func TestRequestIsRejectedIfItsWatcherDoesNotRegisterInTime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := zap.NewNop()
+
+	md, _ := createQueryHandlerForTestWithLateWatcher(t, ctx, logger, requestTimeoutForTest/2)
+
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+	}
+	signedQueryRequest, queryRequest := createSignedQueryRequestForTesting(t, md.sk, perChainQueries)
+	md.setExpectedResults(createExpectedResultsForTest(t, queryRequest.PerChainQueries))
+
+	md.signedQueryReqWriteC <- signedQueryRequest
+
+	failure := md.waitForFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, ChainsNotWatched, failure.Reason)
+	assert.Equal(t, []vaa.ChainID{vaa.ChainIDBSC}, failure.MissingChains)
+	assert.Equal(t, 0, md.getRequestsPerChain(vaa.ChainIDPolygon))
+}
+
+func TestUnregisteredChains(t *testing.T) {
+	chainQueryReqC := map[vaa.ChainID]chan *PerChainQueryInternal{vaa.ChainIDPolygon: make(chan *PerChainQueryInternal)}
+	perChainQueries := []*PerChainQueryRequest{
+		createPerChainQueryForEthCall(t, vaa.ChainIDPolygon, "0x28d9630", 2),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9123", 3),
+		createPerChainQueryForEthCall(t, vaa.ChainIDBSC, "0x28d9124", 3),
+		createPerChainQueryForEthCall(t, vaa.ChainIDPythNet, "0x28d9124", 3),
+	}
+
+	// Chains that do not support queries are never waited for.
+	assert.Equal(t, []vaa.ChainID{vaa.ChainIDBSC}, unregisteredChains(perChainQueries, chainQueryReqC))
+}
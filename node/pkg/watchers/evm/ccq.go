@@ -1,11 +1,15 @@
 package evm
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/certusone/wormhole/node/pkg/watchers/evm/connectors"
@@ -28,9 +32,27 @@ func (w *Watcher) ccqStart(ctx context.Context, errC chan error) {
 	query.StartWorkers(ctx, w.ccqLogger, errC, w, w.queryReqC, w.ccqConfig, w.chainID.String())
 }
 
-// ccqSendQueryResponse sends a response back to the query handler. In the case of an error, the response parameter may be nil.
+// ccqSendQueryResponse sends a response back to the query handler. In the case of an error, the response parameter may be nil. For a successful
+// response, the metadata also reports how far the block used is behind the chain head.
 func (w *Watcher) ccqSendQueryResponse(req *query.PerChainQueryInternal, status query.QueryStatus, response query.ChainSpecificResponse) {
 	queryResponse := query.CreatePerChainQueryResponseInternal(req.RequestID, req.RequestIdx, req.Request.ChainId, status, response)
+	queryResponse.Metadata = &query.PerChainResponseMetadata{RpcNode: query.RpcNodeLabel(w.url)}
+	if status == query.QuerySuccess && response != nil {
+		queryResponse.Metadata.SetChainHeadLag(response, atomic.LoadUint64(&w.latestBlockNumber), time.Now())
+	}
+	w.ccqPublishQueryResponse(queryResponse)
+}
+
+// ccqSendQueryFailure sends an unsuccessful response back to the query handler, reporting the error in the response metadata. If the error
+// came from the RPC node, its error code is reported as well.
+func (w *Watcher) ccqSendQueryFailure(req *query.PerChainQueryInternal, status query.QueryStatus, err error) {
+	queryResponse := query.CreatePerChainQueryResponseInternal(req.RequestID, req.RequestIdx, req.Request.ChainId, status, nil)
+	queryResponse.Metadata = &query.PerChainResponseMetadata{RpcNode: query.RpcNodeLabel(w.url), Error: err.Error(), RpcError: query.NewRpcError(err)}
+	w.ccqPublishQueryResponse(queryResponse)
+}
+
+// ccqPublishQueryResponse sends a response back to the query handler. It never blocks.
+func (w *Watcher) ccqPublishQueryResponse(queryResponse *query.PerChainQueryResponseInternal) {
 	select {
 	case w.queryResponseC <- queryResponse:
 		w.ccqLogger.Debug("published query response to handler")
@@ -48,6 +70,12 @@ func (w *Watcher) QueryHandler(ctx context.Context, queryRequest *query.PerChain
 		panic("ccqevm: invalid chain ID")
 	}
 
+	if w.ccqNodeSyncing(ctx, w.ethConn) {
+		w.ccqLogger.Warn("node is syncing, not executing query request", zap.String("requestId", queryRequest.ID()), zap.String("requestId", queryRequest.CorrelationID))
+		w.ccqSendQueryFailure(queryRequest, query.QueryNodeSyncing, errors.New("node is syncing"))
+		return
+	}
+
 	start := time.Now()
 
 	switch req := queryRequest.Request.Query.(type) {
@@ -57,6 +85,20 @@ func (w *Watcher) QueryHandler(ctx context.Context, queryRequest *query.PerChain
 		w.ccqHandleEthCallByTimestampQueryRequest(ctx, queryRequest, req)
 	case *query.EthCallWithFinalityQueryRequest:
 		w.ccqHandleEthCallWithFinalityQueryRequest(ctx, queryRequest, req)
+	case *query.EthCallWithPreconditionQueryRequest:
+		w.ccqHandleEthCallWithPreconditionQueryRequest(ctx, queryRequest, req)
+	case *query.EthCallByTimestampListQueryRequest:
+		w.ccqHandleEthCallByTimestampListQueryRequest(ctx, queryRequest, req)
+	case *query.EthTxProofQueryRequest:
+		w.ccqHandleEthTxProofQueryRequest(ctx, queryRequest, req)
+	case *query.EthStorageDiffQueryRequest:
+		w.ccqHandleEthStorageDiffQueryRequest(ctx, queryRequest, req)
+	case *query.EthBlockProbeQueryRequest:
+		w.ccqHandleEthBlockProbeQueryRequest(ctx, queryRequest, req)
+	case *query.EthLogsQueryRequest:
+		w.ccqHandleEthLogsQueryRequest(ctx, queryRequest, req)
+	case *query.EthStorageQueryRequest:
+		w.ccqHandleEthStorageQueryRequest(ctx, queryRequest, req)
 	default:
 		w.ccqLogger.Warn("received unsupported request type",
 			zap.Uint8("payload", uint8(queryRequest.Request.Query.Type())),
@@ -67,6 +109,40 @@ func (w *Watcher) QueryHandler(ctx context.Context, queryRequest *query.PerChain
 	query.TotalWatcherTime.WithLabelValues(w.chainID.String()).Observe(float64(time.Since(start).Milliseconds()))
 }
 
+// ccqSyncStatusInterval is how long the sync status of the node is cached.
+const ccqSyncStatusInterval = 30 * time.Second
+
+// ccqSyncConn is the subset of the connector interface needed to read the sync status of the node.
+type ccqSyncConn interface {
+	RawCallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// ccqNodeSyncing returns true if the node is still syncing, as reported by eth_syncing, in which case the state needed by a query may not be
+// available yet. The status is cached for ccqSyncStatusInterval. If it cannot be read, the node is assumed not to be syncing, so that queries
+// still go ahead.
+func (w *Watcher) ccqNodeSyncing(ctx context.Context, conn ccqSyncConn) bool {
+	w.ccqSyncMutex.Lock()
+	defer w.ccqSyncMutex.Unlock()
+	if time.Since(w.ccqSyncCheckTime) < ccqSyncStatusInterval {
+		return w.ccqSyncing
+	}
+
+	// eth_syncing returns false if the node is not syncing, and an object describing its progress otherwise.
+	var result interface{}
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := conn.RawCallContext(timeout, &result, "eth_syncing"); err != nil {
+		w.ccqLogger.Warn("failed to read sync status of node, assuming it is not syncing", zap.Error(err))
+		w.ccqSyncing = false
+	} else {
+		syncing, isBool := result.(bool)
+		w.ccqSyncing = !isBool || syncing
+	}
+
+	w.ccqSyncCheckTime = time.Now()
+	return w.ccqSyncing
+}
+
 // EvmCallData contains the details of a single call in the batch.
 type EvmCallData struct {
 	To         eth_common.Address
@@ -90,22 +166,48 @@ func (ecd EvmCallData) String() string {
 // ccqHandleEthCallQueryRequest is the query handler for an eth_call request.
 func (w *Watcher) ccqHandleEthCallQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthCallQueryRequest) {
 	requestId := "eth_call:" + queryRequest.ID()
-	block := req.BlockId
+	block := query.NormalizeBlockId(req.BlockId)
 	w.ccqLogger.Info("received eth_call query request",
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 		zap.String("block", block),
+		zap.String("finality", req.Finality),
+		zap.Bool("preferSpeed", queryRequest.PreferSpeed),
 		zap.Int("numRequests", len(req.CallData)),
 	)
 
-	// Create the block query args.
-	blockMethod, callBlockArg, err := ccqCreateBlockRequest(block)
+	// If the request specifies a finality rather than a block, resolve it to a specific block first.
+	if req.Finality != "" {
+		timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+		resolvedBlock, status, err := w.ccqResolveFinality(timeout, w.ethConn, req.Finality, queryRequest.PreferSpeed)
+		cancel()
+		if err != nil {
+			w.ccqLogger.Error("failed to resolve finality in eth_call query request",
+				zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+				zap.String("finality", req.Finality),
+				zap.Error(err),
+			)
+			w.ccqSendQueryFailure(queryRequest, status, err)
+			return
+		}
+		block = resolvedBlock
+	}
+
+	// Create the block query args. A finality that was left unresolved is passed to the node as a block tag.
+	var blockMethod string
+	var callBlockArg interface{}
+	var err error
+	if req.Finality != "" && block == req.Finality {
+		blockMethod, callBlockArg = "eth_getBlockByNumber", block
+	} else {
+		blockMethod, callBlockArg, err = ccqCreateBlockRequest(block)
+	}
 	if err != nil {
 		w.ccqLogger.Error("invalid block id in eth_call query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryFatalError, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
 		return
 	}
 
@@ -125,36 +227,48 @@ func (w *Watcher) ccqHandleEthCallQueryRequest(ctx context.Context, queryRequest
 		Error:  blockError,
 	})
 
+	// Make sure nothing in the batch could possibly mutate state.
+	if err := ccqVerifyReadOnlyBatch(batch); err != nil {
+		w.ccqLogger.Error("refusing to submit query batch that is not read-only",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.Any("batch", batch),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
+		return
+	}
+
 	// Query the RPC.
 	start := time.Now()
 	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	err = w.ethConn.RawBatchCallContext(timeout, batch)
+	err = w.ccqBatchCall(timeout, w.ethConn, batch)
 	if err != nil {
 		w.ccqLogger.Error("failed to process eth_call query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
+	ccqSetCallErrors(batch, evmCallData)
 
 	// Verify that the block read was successful.
 	if err := w.ccqVerifyBlockResult(blockError, blockResult); err != nil {
 		w.ccqLogger.Debug("failed to verify block for eth_call query",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
 	w.ccqLogger.Info("query complete for eth_call",
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 		zap.String("block", block),
 		zap.String("blockNumber", blockResult.Number.String()),
 		zap.String("blockHash", blockResult.Hash.Hex()),
@@ -162,16 +276,22 @@ func (w *Watcher) ccqHandleEthCallQueryRequest(ctx context.Context, queryRequest
 		zap.Int64("duration", time.Since(start).Milliseconds()),
 	)
 
-	// Verify all the call results and build the batch of results.
-	results, err := w.ccqVerifyAndExtractQueryResults(requestId, evmCallData)
+	// Verify all the call results and build the batch of results. If the request allows it, reverted calls are returned with their own status.
+	var results [][]byte
+	var statuses []query.EthCallResultStatus
+	if queryRequest.AllowRevertedCalls {
+		results, statuses, err = w.ccqVerifyAndExtractQueryResultsAllowingReverts(requestId, evmCallData)
+	} else {
+		results, err = w.ccqVerifyAndExtractQueryResults(requestId, evmCallData)
+	}
 	if err != nil {
 		w.ccqLogger.Debug("failed to process eth_call query call request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
@@ -181,18 +301,39 @@ func (w *Watcher) ccqHandleEthCallQueryRequest(ctx context.Context, queryRequest
 		Hash:        blockResult.Hash,
 		Time:        time.Unix(int64(blockResult.Time), 0),
 		Results:     results,
+		Statuses:    statuses,
 	}
 
 	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, &resp)
 }
 
+// ccqResolveFinality resolves the finality of an eth_call query to the hash of the block it currently refers to, so that the calls and the block
+// read that follow are guaranteed to use the same block. If the requester prefers speed, that extra round trip is skipped and the finality is
+// returned unchanged, so the node may resolve it to a different block for each call. If the chain does not support the requested block tag, it
+// returns QueryFatalError rather than falling back to the latest block.
+func (w *Watcher) ccqResolveFinality(ctx context.Context, conn ccqBatchConn, finality string, preferSpeed bool) (string, query.QueryStatus, error) {
+	if (finality == "finalized" && !w.ccqFinalizedTagSupported) || (finality == "safe" && !w.ccqSafeTagSupported) {
+		return "", query.QueryFatalError, fmt.Errorf("%s does not support the %s block tag", w.chainID, finality)
+	}
+	if preferSpeed {
+		return finality, query.QuerySuccess, nil
+	}
+
+	resp, err := w.ccqProbeBlock(ctx, conn, finality)
+	if err != nil {
+		return "", query.QueryRetryNeeded, fmt.Errorf("failed to resolve the %s block: %w", finality, err)
+	}
+
+	return resp.BlockHash.Hex(), query.QuerySuccess, nil
+}
+
 // ccqHandleEthCallByTimestampQueryRequest is the query handler for an eth_call_by_timestamp request.
 func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthCallByTimestampQueryRequest) {
 	requestId := "eth_call_by_timestamp:" + queryRequest.ID()
-	block := req.TargetBlockIdHint
-	nextBlock := req.FollowingBlockIdHint
+	block := query.NormalizeBlockId(req.TargetBlockIdHint)
+	nextBlock := query.NormalizeBlockId(req.FollowingBlockIdHint)
 	w.ccqLogger.Info("received eth_call_by_timestamp query request",
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 		zap.Uint64("timestamp", req.TargetTimestamp),
 		zap.String("block", block),
 		zap.String("nextBlock", nextBlock),
@@ -202,7 +343,7 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 	// Verify that the two block hints are consistent, either both set, or both unset.
 	if (block == "") != (nextBlock == "") {
 		w.ccqLogger.Error("invalid block id hints in eth_call_by_timestamp query request, both should be either set or unset",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Uint64("timestamp", req.TargetTimestamp),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
@@ -213,56 +354,8 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 
 	// Look up the blocks based on the timestamp if necessary.
 	if block == "" {
-		if w.ccqTimestampCache == nil {
-			w.ccqLogger.Error("error in block id hints in eth_call_by_timestamp query request, they are unset and chain does not support timestamp caching")
-			w.ccqSendQueryResponse(queryRequest, query.QueryFatalError, nil)
-			return
-		}
-
-		// Look the timestamp up in the cache. Note that the cache uses native EVM time, which is seconds, but CCQ uses microseconds, so we have to convert.
-		blockNum, nextBlockNum, found := w.ccqTimestampCache.LookUp(req.TargetTimestamp / 1000000)
-		if !found {
-			status := query.QueryRetryNeeded
-			if nextBlockNum == 0 {
-				w.ccqLogger.Warn("block look up failed in eth_call_by_timestamp query request, timestamp beyond the end of the cache, will wait and retry",
-					zap.String("requestId", requestId),
-					zap.Uint64("timestamp", req.TargetTimestamp),
-					zap.String("block", block),
-					zap.String("nextBlock", nextBlock),
-					zap.Uint64("blockNum", blockNum),
-					zap.Uint64("nextBlockNum", nextBlockNum),
-				)
-			} else if blockNum == 0 {
-				w.ccqLogger.Error("block look up failed in eth_call_by_timestamp query request, timestamp too old, failing request",
-					zap.String("requestId", requestId),
-					zap.Uint64("timestamp", req.TargetTimestamp),
-					zap.String("block", block),
-					zap.String("nextBlock", nextBlock),
-					zap.Uint64("blockNum", blockNum),
-					zap.Uint64("nextBlockNum", nextBlockNum),
-				)
-				status = query.QueryFatalError
-			} else if w.ccqBackfillCache {
-				w.ccqLogger.Warn("block look up failed in eth_call_by_timestamp query request, timestamp is in a gap in the cache, will request a backfill and retry",
-					zap.String("requestId", requestId),
-					zap.Uint64("timestamp", req.TargetTimestamp),
-					zap.String("block", block),
-					zap.String("nextBlock", nextBlock),
-					zap.Uint64("blockNum", blockNum),
-					zap.Uint64("nextBlockNum", nextBlockNum),
-				)
-				w.ccqRequestBackfill(req.TargetTimestamp / 1000000)
-			} else {
-				w.ccqLogger.Error("block look up failed in eth_call_by_timestamp query request, timestamp is in a gap in the cache, failing request",
-					zap.String("requestId", requestId),
-					zap.Uint64("timestamp", req.TargetTimestamp),
-					zap.String("block", block),
-					zap.String("nextBlock", nextBlock),
-					zap.Uint64("blockNum", blockNum),
-					zap.Uint64("nextBlockNum", nextBlockNum),
-				)
-				status = query.QueryFatalError
-			}
+		blockNum, nextBlockNum, status, ok := w.ccqLookUpBlocksForTimestamp(requestId, req.TargetTimestamp)
+		if !ok {
 			w.ccqSendQueryResponse(queryRequest, status, nil)
 			return
 		}
@@ -271,7 +364,7 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 		nextBlock = fmt.Sprintf("0x%x", nextBlockNum)
 
 		w.ccqLogger.Info("cache look up in eth_call_by_timestamp query request mapped timestamp to blocks",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Uint64("timestamp", req.TargetTimestamp),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
@@ -284,24 +377,24 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 	blockMethod, callBlockArg, err := ccqCreateBlockRequest(block)
 	if err != nil {
 		w.ccqLogger.Error("invalid target block id hint in eth_call_by_timestamp query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryFatalError, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
 		return
 	}
 
 	nextBlockMethod, _, err := ccqCreateBlockRequest(nextBlock)
 	if err != nil {
 		w.ccqLogger.Error("invalid following block id hint in eth_call_by_timestamp query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryFatalError, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
 		return
 	}
 
@@ -334,55 +427,60 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 		Error:  nextBlockError,
 	})
 
+	// Make sure nothing in the batch could possibly mutate state.
+	if err := ccqVerifyReadOnlyBatch(batch); err != nil {
+		w.ccqLogger.Error("refusing to submit query batch that is not read-only",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.Any("batch", batch),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
+		return
+	}
+
 	// Query the RPC.
 	start := time.Now()
 	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	err = w.ethConn.RawBatchCallContext(timeout, batch)
+	err = w.ccqBatchCall(timeout, w.ethConn, batch)
 	if err != nil {
 		w.ccqLogger.Error("failed to process eth_call_by_timestamp query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
 	// Verify the target block read was successful.
 	if err := w.ccqVerifyBlockResult(blockError, blockResult); err != nil {
 		w.ccqLogger.Debug("failed to verify target block for eth_call_by_timestamp query",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
 	// Verify the following block read was successful.
 	if err := w.ccqVerifyBlockResult(nextBlockError, nextBlockResult); err != nil {
 		w.ccqLogger.Debug("failed to verify next block for eth_call_by_timestamp query",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
-	/*
-		target_block.timestamp <= target_time < following_block.timestamp
-		and
-		following_block_num - 1 == target_block_num
-	*/
-
 	targetBlockNum := blockResult.Number.ToInt().Uint64()
 	followingBlockNum := nextBlockResult.Number.ToInt().Uint64()
 
@@ -390,26 +488,9 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 	targetTimestamp := uint64(blockResult.Time * 1000000)
 	followingTimestamp := uint64(nextBlockResult.Time * 1000000)
 
-	if targetBlockNum+1 != followingBlockNum {
-		w.ccqLogger.Error("eth_call_by_timestamp query blocks are not adjacent",
-			zap.String("requestId", requestId),
-			zap.Uint64("desiredTimestamp", req.TargetTimestamp),
-			zap.Uint64("targetTimestamp", targetTimestamp),
-			zap.Uint64("followingTimestamp", followingTimestamp),
-			zap.String("targetBlockNumber", blockResult.Number.String()),
-			zap.String("followingBlockNumber", nextBlockResult.Number.String()),
-			zap.String("targetBlockHash", blockResult.Hash.Hex()),
-			zap.String("followingBlockHash", nextBlockResult.Hash.Hex()),
-			zap.String("targetBlockTime", blockResult.Time.String()),
-			zap.String("followingBlockTime", nextBlockResult.Time.String()),
-		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryFatalError, nil)
-		return
-	}
-
-	if req.TargetTimestamp < targetTimestamp || req.TargetTimestamp >= followingTimestamp {
-		w.ccqLogger.Error("eth_call_by_timestamp desired timestamp falls outside of block range",
-			zap.String("requestId", requestId),
+	if err := ccqVerifyBlocksForTimestamp(req.TargetTimestamp, blockResult, nextBlockResult); err != nil {
+		w.ccqLogger.Error("eth_call_by_timestamp query blocks are not valid for the desired timestamp",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Uint64("desiredTimestamp", req.TargetTimestamp),
 			zap.Uint64("targetTimestamp", targetTimestamp),
 			zap.Uint64("followingTimestamp", followingTimestamp),
@@ -419,13 +500,14 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 			zap.String("followingBlockHash", nextBlockResult.Hash.Hex()),
 			zap.String("targetBlockTime", blockResult.Time.String()),
 			zap.String("followingBlockTime", nextBlockResult.Time.String()),
+			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryFatalError, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
 		return
 	}
 
 	w.ccqLogger.Info("query complete for eth_call_by_timestamp",
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 		zap.Uint64("desiredTimestamp", req.TargetTimestamp),
 		zap.Uint64("targetTimestamp", targetTimestamp),
 		zap.Uint64("followingTimestamp", followingTimestamp),
@@ -442,13 +524,13 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 	results, err := w.ccqVerifyAndExtractQueryResults(requestId, evmCallData)
 	if err != nil {
 		w.ccqLogger.Debug("failed to process eth_call_by_timestamp query call request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.String("nextBlock", nextBlock),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
@@ -466,12 +548,254 @@ func (w *Watcher) ccqHandleEthCallByTimestampQueryRequest(ctx context.Context, q
 	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, &resp)
 }
 
+// ccqHandleEthCallByTimestampListQueryRequest is the query handler for an eth_call_by_timestamp_list request. The timestamps are always
+// resolved using the timestamp cache, and all of the calls for all of the timestamps are submitted in a single batch.
+func (w *Watcher) ccqHandleEthCallByTimestampListQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthCallByTimestampListQueryRequest) {
+	requestId := "eth_call_by_timestamp_list:" + queryRequest.ID()
+	w.ccqLogger.Info("received eth_call_by_timestamp_list query request",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.Uint64s("timestamps", req.TargetTimestamps),
+		zap.Int("numRequests", len(req.CallData)),
+	)
+
+	// Look up the blocks for each timestamp and add the calls and the two block queries for each timestamp to the batch.
+	batch := []rpc.BatchElem{}
+	entries := make([]*ccqTimestampListEntry, len(req.TargetTimestamps))
+	for idx, timestamp := range req.TargetTimestamps {
+		blockNum, nextBlockNum, status, ok := w.ccqLookUpBlocksForTimestamp(requestId, timestamp)
+		if !ok {
+			w.ccqSendQueryResponse(queryRequest, status, nil)
+			return
+		}
+
+		entry := &ccqTimestampListEntry{
+			block:     fmt.Sprintf("0x%x", blockNum),
+			nextBlock: fmt.Sprintf("0x%x", nextBlockNum),
+		}
+		entries[idx] = entry
+
+		blockMethod, callBlockArg, err := ccqCreateBlockRequest(entry.block)
+		if err != nil {
+			w.ccqLogger.Error("failed to create block request in eth_call_by_timestamp_list query request",
+				zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+				zap.Uint64("timestamp", timestamp),
+				zap.String("block", entry.block),
+				zap.Error(err),
+			)
+			w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
+			return
+		}
+
+		var callBatch []rpc.BatchElem
+		callBatch, entry.evmCallData = ccqBuildBatchFromCallData(req, callBlockArg)
+		batch = append(batch, callBatch...)
+		batch = append(batch,
+			rpc.BatchElem{
+				Method: blockMethod,
+				Args: []interface{}{
+					entry.block,
+					false, // no full transaction details
+				},
+				Result: &entry.blockResult,
+				Error:  entry.blockError,
+			},
+			rpc.BatchElem{
+				Method: blockMethod,
+				Args: []interface{}{
+					entry.nextBlock,
+					false, // no full transaction details
+				},
+				Result: &entry.nextBlockResult,
+				Error:  entry.nextBlockError,
+			},
+		)
+	}
+
+	// Make sure nothing in the batch could possibly mutate state.
+	if err := ccqVerifyReadOnlyBatch(batch); err != nil {
+		w.ccqLogger.Error("refusing to submit query batch that is not read-only",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.Any("batch", batch),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
+		return
+	}
+
+	// Query the RPC.
+	start := time.Now()
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := w.ccqBatchCall(timeout, w.ethConn, batch); err != nil {
+		w.ccqLogger.Error("failed to process eth_call_by_timestamp_list query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.Any("batch", batch),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
+		return
+	}
+
+	// Verify the results for each timestamp and build the response.
+	resp := query.EthCallByTimestampListQueryResponse{}
+	for idx, entry := range entries {
+		timestamp := req.TargetTimestamps[idx]
+		if err := w.ccqVerifyBlockResult(entry.blockError, entry.blockResult); err != nil {
+			w.ccqLogger.Debug("failed to verify target block for eth_call_by_timestamp_list query",
+				zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+				zap.Uint64("timestamp", timestamp),
+				zap.String("block", entry.block),
+				zap.Error(err),
+			)
+			w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
+			return
+		}
+
+		if err := w.ccqVerifyBlockResult(entry.nextBlockError, entry.nextBlockResult); err != nil {
+			w.ccqLogger.Debug("failed to verify next block for eth_call_by_timestamp_list query",
+				zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+				zap.Uint64("timestamp", timestamp),
+				zap.String("nextBlock", entry.nextBlock),
+				zap.Error(err),
+			)
+			w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
+			return
+		}
+
+		if err := ccqVerifyBlocksForTimestamp(timestamp, entry.blockResult, entry.nextBlockResult); err != nil {
+			w.ccqLogger.Error("eth_call_by_timestamp_list query blocks are not valid for the desired timestamp",
+				zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+				zap.Uint64("timestamp", timestamp),
+				zap.String("block", entry.block),
+				zap.String("nextBlock", entry.nextBlock),
+				zap.Error(err),
+			)
+			w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
+			return
+		}
+
+		results, err := w.ccqVerifyAndExtractQueryResults(requestId, entry.evmCallData)
+		if err != nil {
+			w.ccqLogger.Debug("failed to process eth_call_by_timestamp_list query call request",
+				zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+				zap.Uint64("timestamp", timestamp),
+				zap.String("block", entry.block),
+				zap.Error(err),
+			)
+			w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
+			return
+		}
+
+		resp.Responses = append(resp.Responses, &query.EthCallByTimestampQueryResponse{
+			TargetBlockNumber:    entry.blockResult.Number.ToInt().Uint64(),
+			TargetBlockHash:      entry.blockResult.Hash,
+			TargetBlockTime:      time.Unix(int64(entry.blockResult.Time), 0),
+			FollowingBlockNumber: entry.nextBlockResult.Number.ToInt().Uint64(),
+			FollowingBlockHash:   entry.nextBlockResult.Hash,
+			FollowingBlockTime:   time.Unix(int64(entry.nextBlockResult.Time), 0),
+			Results:              results,
+		})
+	}
+
+	w.ccqLogger.Info("query complete for eth_call_by_timestamp_list",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.Uint64s("timestamps", req.TargetTimestamps),
+		zap.Int64("duration", time.Since(start).Milliseconds()),
+	)
+
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, &resp)
+}
+
+// ccqTimestampListEntry holds the batch data for a single timestamp in an eth_call_by_timestamp_list request.
+type ccqTimestampListEntry struct {
+	block           string
+	nextBlock       string
+	evmCallData     []EvmCallData
+	blockResult     connectors.BlockMarshaller
+	blockError      error
+	nextBlockResult connectors.BlockMarshaller
+	nextBlockError  error
+}
+
+// ccqLookUpBlocksForTimestamp uses the timestamp cache to map a timestamp (in microseconds) to the target block and the block following it.
+// If the look up fails, it returns false along with the status that should be returned to the query handler. It may request a backfill of the cache.
+func (w *Watcher) ccqLookUpBlocksForTimestamp(requestId string, timestamp uint64) (uint64, uint64, query.QueryStatus, bool) {
+	if w.ccqTimestampCache == nil {
+		w.ccqLogger.Error("error in timestamp query request, the block id hints are unset and chain does not support timestamp caching", zap.String("requestId", requestId))
+		return 0, 0, query.QueryFatalError, false
+	}
+
+	// Look the timestamp up in the cache. Note that the cache uses native EVM time, which is seconds, but CCQ uses microseconds, so we have to convert.
+	blockNum, nextBlockNum, found := w.ccqTimestampCache.LookUp(timestamp / 1000000)
+	if found {
+		return blockNum, nextBlockNum, query.QuerySuccess, true
+	}
+
+	status := query.QueryRetryNeeded
+	if nextBlockNum == 0 {
+		w.ccqLogger.Warn("block look up failed in timestamp query request, timestamp beyond the end of the cache, will wait and retry",
+			zap.String("requestId", requestId),
+			zap.Uint64("timestamp", timestamp),
+			zap.Uint64("blockNum", blockNum),
+			zap.Uint64("nextBlockNum", nextBlockNum),
+		)
+	} else if blockNum == 0 {
+		w.ccqLogger.Error("block look up failed in timestamp query request, timestamp too old, failing request",
+			zap.String("requestId", requestId),
+			zap.Uint64("timestamp", timestamp),
+			zap.Uint64("blockNum", blockNum),
+			zap.Uint64("nextBlockNum", nextBlockNum),
+		)
+		status = query.QueryFatalError
+	} else if w.ccqBackfillCache {
+		w.ccqLogger.Warn("block look up failed in timestamp query request, timestamp is in a gap in the cache, will request a backfill and retry",
+			zap.String("requestId", requestId),
+			zap.Uint64("timestamp", timestamp),
+			zap.Uint64("blockNum", blockNum),
+			zap.Uint64("nextBlockNum", nextBlockNum),
+		)
+		w.ccqRequestBackfill(timestamp / 1000000)
+	} else {
+		w.ccqLogger.Error("block look up failed in timestamp query request, timestamp is in a gap in the cache, failing request",
+			zap.String("requestId", requestId),
+			zap.Uint64("timestamp", timestamp),
+			zap.Uint64("blockNum", blockNum),
+			zap.Uint64("nextBlockNum", nextBlockNum),
+		)
+		status = query.QueryFatalError
+	}
+
+	return blockNum, nextBlockNum, status, false
+}
+
+// ccqVerifyBlocksForTimestamp verifies that the target and following blocks are adjacent, and that the desired timestamp (in microseconds) falls between them:
+//
+//	target_block.timestamp <= target_time < following_block.timestamp
+//	and
+//	following_block_num - 1 == target_block_num
+func ccqVerifyBlocksForTimestamp(desiredTimestamp uint64, blockResult connectors.BlockMarshaller, nextBlockResult connectors.BlockMarshaller) error {
+	targetBlockNum := blockResult.Number.ToInt().Uint64()
+	followingBlockNum := nextBlockResult.Number.ToInt().Uint64()
+	if targetBlockNum+1 != followingBlockNum {
+		return fmt.Errorf("blocks are not adjacent, target block %d, following block %d", targetBlockNum, followingBlockNum)
+	}
+
+	// The desired timestamp is in microseconds but EVM returns seconds. Convert to microseconds.
+	targetTimestamp := uint64(blockResult.Time * 1000000)
+	followingTimestamp := uint64(nextBlockResult.Time * 1000000)
+	if desiredTimestamp < targetTimestamp || desiredTimestamp >= followingTimestamp {
+		return fmt.Errorf("desired timestamp %d falls outside of block range [%d, %d)", desiredTimestamp, targetTimestamp, followingTimestamp)
+	}
+
+	return nil
+}
+
 // ccqHandleEthCallWithFinalityQueryRequest is the query handler for an eth_call_with_finality request.
 func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthCallWithFinalityQueryRequest) {
 	requestId := "eth_call:" + queryRequest.ID()
-	block := req.BlockId
+	block := query.NormalizeBlockId(req.BlockId)
 	w.ccqLogger.Info("received eth_call_with_finality query request",
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 		zap.String("block", block),
 		zap.String("finality", req.Finality),
 		zap.Int("numRequests", len(req.CallData)),
@@ -481,7 +805,7 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 	safeMode := req.Finality == "safe"
 	if req.Finality != "finalized" && !safeMode {
 		w.ccqLogger.Error("invalid finality in eth_call_with_finality query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.String("finality", req.Finality),
 		)
@@ -493,11 +817,11 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 	blockMethod, callBlockArg, err := ccqCreateBlockRequest(block)
 	if err != nil {
 		w.ccqLogger.Error("invalid block id in eth_call_with_finality query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryFatalError, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
 		return
 	}
 
@@ -517,31 +841,42 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 		Error:  blockError,
 	})
 
+	// Make sure nothing in the batch could possibly mutate state.
+	if err := ccqVerifyReadOnlyBatch(batch); err != nil {
+		w.ccqLogger.Error("refusing to submit query batch that is not read-only",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.Any("batch", batch),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
+		return
+	}
+
 	// Query the RPC.
 	start := time.Now()
 	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	err = w.ethConn.RawBatchCallContext(timeout, batch)
+	err = w.ccqBatchCall(timeout, w.ethConn, batch)
 	if err != nil {
 		w.ccqLogger.Error("failed to process eth_call_with_finality query request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
 	// Verify that the block read was successful.
 	if err := w.ccqVerifyBlockResult(blockError, blockResult); err != nil {
 		w.ccqLogger.Debug("failed to verify block for eth_call_with_finality query",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("block", block),
 			zap.Any("batch", batch),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
@@ -557,7 +892,7 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 	blockNumber := blockResult.Number.ToInt().Uint64()
 	if blockNumber > latestBlockNum {
 		w.ccqLogger.Info("requested block for eth_call_with_finality has not yet reached the requested finality",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("finality", req.Finality),
 			zap.Uint64("requestedBlockNumber", blockNumber),
 			zap.Uint64("latestBlockNumber", latestBlockNum),
@@ -569,7 +904,7 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 	}
 
 	w.ccqLogger.Info("query complete for eth_call_with_finality",
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 		zap.String("finality", req.Finality),
 		zap.Uint64("requestedBlockNumber", blockNumber),
 		zap.Uint64("latestBlockNumber", latestBlockNum),
@@ -582,7 +917,7 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 	results, err := w.ccqVerifyAndExtractQueryResults(requestId, evmCallData)
 	if err != nil {
 		w.ccqLogger.Debug("failed to process eth_call_with_finality query call request",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.String("finality", req.Finality),
 			zap.Uint64("requestedBlockNumber", blockNumber),
 			zap.Uint64("latestBlockNumber", latestBlockNum),
@@ -590,7 +925,7 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 			zap.String("blockTime", blockResult.Time.String()),
 			zap.Error(err),
 		)
-		w.ccqSendQueryResponse(queryRequest, query.QueryRetryNeeded, nil)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
 		return
 	}
 
@@ -605,6 +940,189 @@ func (w *Watcher) ccqHandleEthCallWithFinalityQueryRequest(ctx context.Context,
 	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, &resp)
 }
 
+// ccqHandleEthCallWithPreconditionQueryRequest is the query handler for an eth_call_with_precondition request.
+func (w *Watcher) ccqHandleEthCallWithPreconditionQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthCallWithPreconditionQueryRequest) {
+	requestId := "eth_call_with_precondition:" + queryRequest.ID()
+	block := query.NormalizeBlockId(req.BlockId)
+	w.ccqLogger.Info("received eth_call_with_precondition query request",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("block", block),
+		zap.Int("numRequests", len(req.CallData)),
+	)
+
+	blockMethod, callBlockArg, err := ccqCreateBlockRequest(block)
+	if err != nil {
+		w.ccqLogger.Error("invalid block id in eth_call_with_precondition query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.String("block", block),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, query.QueryFatalError, err)
+		return
+	}
+
+	start := time.Now()
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, status, err := w.ccqExecuteWithPrecondition(timeout, w.ethConn, requestId, req, blockMethod, block, callBlockArg)
+	if err != nil {
+		w.ccqLogger.Error("failed to process eth_call_with_precondition query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.String("block", block),
+			zap.Int("status", int(status)),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, status, err)
+		return
+	}
+
+	w.ccqLogger.Info("query complete for eth_call_with_precondition",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("block", block),
+		zap.Uint64("blockNumber", resp.BlockNumber),
+		zap.String("blockHash", resp.Hash.Hex()),
+		zap.String("blockTime", resp.Time.String()),
+		zap.Bool("preconditionMet", resp.PreconditionMet()),
+		zap.Int64("duration", time.Since(start).Milliseconds()),
+	)
+
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, resp)
+}
+
+// ccqBatchConn is the subset of the connector interface needed to execute a query batch.
+type ccqBatchConn interface {
+	RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// ccqBatchCall executes a query batch. Many RPC providers execute the elements of a batch one after the other, so if call concurrency is
+// configured, the batch is split into up to that many sub-batches, which are executed concurrently. The elements are updated in place,
+// so the results stay in the order of the batch. If any of the sub-batches fail, the first error is returned.
+func (w *Watcher) ccqBatchCall(ctx context.Context, conn ccqBatchConn, batch []rpc.BatchElem) error {
+	if w.ccqCallConcurrency <= 1 || len(batch) <= 1 {
+		return conn.RawBatchCallContext(ctx, batch)
+	}
+
+	chunkSize := (len(batch) + w.ccqCallConcurrency - 1) / w.ccqCallConcurrency
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	var firstErr error
+	for start := 0; start < len(batch); start += chunkSize {
+		end := start + chunkSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+
+		wg.Add(1)
+		go func(chunk []rpc.BatchElem) {
+			defer wg.Done()
+			if err := conn.RawBatchCallContext(ctx, chunk); err != nil {
+				errMutex.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errMutex.Unlock()
+			}
+		}(batch[start:end])
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// ccqExecuteWithPrecondition executes the precondition call in the same batch as the block query. If the result matches the expected value, the dependent
+// calls are then executed against the same block, identified by hash. Otherwise, they are not executed and are marked as PreconditionFailed. On error, it
+// returns the status that should be sent back to the query handler.
+func (w *Watcher) ccqExecuteWithPrecondition(
+	ctx context.Context,
+	conn ccqBatchConn,
+	requestId string,
+	req *query.EthCallWithPreconditionQueryRequest,
+	blockMethod string,
+	block string,
+	callBlockArg interface{},
+) (*query.EthCallWithPreconditionQueryResponse, query.QueryStatus, error) {
+	// Create a batch with the precondition call and the block query.
+	batch, evmCallData := ccqBuildBatchFromCallData(&query.EthCallQueryRequest{CallData: req.CallData[:1]}, callBlockArg)
+
+	var blockResult connectors.BlockMarshaller
+	var blockError error
+	batch = append(batch, rpc.BatchElem{
+		Method: blockMethod,
+		Args: []interface{}{
+			block,
+			false, // no full transaction details
+		},
+		Result: &blockResult,
+		Error:  blockError,
+	})
+
+	if err := ccqVerifyReadOnlyBatch(batch); err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("precondition batch is not read-only: %w", err)
+	}
+
+	if err := w.ccqBatchCall(ctx, conn, batch); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("precondition batch failed: %w", err)
+	}
+
+	if err := w.ccqVerifyBlockResult(blockError, blockResult); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("failed to verify block: %w", err)
+	}
+
+	preconditionResults, err := w.ccqVerifyAndExtractQueryResults(requestId, evmCallData)
+	if err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("precondition call failed: %w", err)
+	}
+
+	resp := &query.EthCallWithPreconditionQueryResponse{
+		BlockNumber: blockResult.Number.ToInt().Uint64(),
+		Hash:        blockResult.Hash,
+		Time:        time.Unix(int64(blockResult.Time), 0),
+		Results:     [][]byte{preconditionResults[0]},
+		Statuses:    []query.EthCallResultStatus{query.EthCallResultExecuted},
+	}
+
+	// If the precondition does not hold, skip the dependent calls.
+	if !bytes.Equal(preconditionResults[0], req.ExpectedResult) {
+		w.ccqLogger.Info("precondition not met for eth_call_with_precondition query, skipping dependent calls",
+			zap.String("requestId", requestId),
+			zap.String("expectedResult", hex.EncodeToString(req.ExpectedResult)),
+			zap.String("actualResult", hex.EncodeToString(preconditionResults[0])),
+		)
+		for range req.CallData[1:] {
+			resp.Results = append(resp.Results, []byte{})
+			resp.Statuses = append(resp.Statuses, query.PreconditionFailed)
+		}
+		return resp, query.QuerySuccess, nil
+	}
+
+	// The precondition holds, so execute the dependent calls pinned to the block returned above.
+	_, dependentBlockArg, err := ccqCreateBlockRequest(blockResult.Hash.Hex())
+	if err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("failed to create block request for dependent calls: %w", err)
+	}
+
+	batch, evmCallData = ccqBuildBatchFromCallData(&query.EthCallQueryRequest{CallData: req.CallData[1:]}, dependentBlockArg)
+	if err := ccqVerifyReadOnlyBatch(batch); err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("dependent batch is not read-only: %w", err)
+	}
+
+	if err := w.ccqBatchCall(ctx, conn, batch); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("dependent batch failed: %w", err)
+	}
+
+	dependentResults, err := w.ccqVerifyAndExtractQueryResults(requestId, evmCallData)
+	if err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("dependent call failed: %w", err)
+	}
+
+	for _, result := range dependentResults {
+		resp.Results = append(resp.Results, result)
+		resp.Statuses = append(resp.Statuses, query.EthCallResultExecuted)
+	}
+
+	return resp, query.QuerySuccess, nil
+}
+
 // ccqCreateBlockRequest creates a block query. It parses the block string, allowing for both a block number or a block hash. Note that for now, strings like "latest", "finalized" or "safe"
 // are not supported, and the block must be a hex string starting with 0x. The determination of whether it is a block number or a block hash is based on the overall length of the string,
 // since a hash is 32 bytes (64 hex digits).
@@ -655,8 +1173,60 @@ type EthCallDataIntf interface {
 	CallDataList() []*query.EthCallData
 }
 
-// ccqBuildBatchFromCallData builds two slices. The first is the batch submitted to the RPC call. It contains one entry for each query plus one to query the block.
-// The second is the data associated with each request (but not the block request). The index into both is the index into the request call data.
+// ccqStaticCallMethod is the only RPC method used to execute query calls. Queries must never be able to mutate state,
+// so nothing like eth_sendTransaction or eth_sendRawTransaction may ever be used.
+const ccqStaticCallMethod = "eth_call"
+
+// ccqReadOnlyMethods are the only RPC methods that may appear in a query batch.
+var ccqReadOnlyMethods = map[string]struct{}{
+	ccqStaticCallMethod:        {},
+	"eth_getBlockByNumber":     {},
+	"eth_getBlockByHash":       {},
+	ccqGetTransactionMethod:    {},
+	ccqGetRawTransactionMethod: {},
+	ccqGetStorageAtMethod:      {},
+	ccqGetLogsMethod:           {},
+}
+
+// ccqAllowedCallArgs are the only transaction fields that may be passed to an eth_call. In particular, fields like from, value, nonce and gas
+// are never set, since they only have meaning for a state changing transaction.
+var ccqAllowedCallArgs = map[string]struct{}{
+	"to":   {},
+	"data": {},
+}
+
+// ccqVerifyReadOnlyBatch verifies that every entry in the batch is a read-only RPC call. It is the last check before the batch is submitted.
+func ccqVerifyReadOnlyBatch(batch []rpc.BatchElem) error {
+	for idx, elem := range batch {
+		if _, exists := ccqReadOnlyMethods[elem.Method]; !exists {
+			return fmt.Errorf("batch entry %d uses method %s which is not read-only", idx, elem.Method)
+		}
+
+		if elem.Method != ccqStaticCallMethod {
+			continue
+		}
+
+		if len(elem.Args) == 0 {
+			return fmt.Errorf("batch entry %d does not contain call arguments", idx)
+		}
+
+		callArgs, ok := elem.Args[0].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("batch entry %d has call arguments of unexpected type %T", idx, elem.Args[0])
+		}
+
+		for field := range callArgs {
+			if _, exists := ccqAllowedCallArgs[field]; !exists {
+				return fmt.Errorf("batch entry %d sets call field %s which is not allowed in a static call", idx, field)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ccqBuildBatchFromCallData builds two slices. The first is the batch submitted to the RPC call. It contains one entry for each query. The caller
+// adds the block query. The second is the data associated with each request. The index into both is the index into the request call data.
 func ccqBuildBatchFromCallData(req EthCallDataIntf, callBlockArg interface{}) ([]rpc.BatchElem, []EvmCallData) {
 	batch := []rpc.BatchElem{}
 	evmCallData := []EvmCallData{}
@@ -677,7 +1247,7 @@ func ccqBuildBatchFromCallData(req EthCallDataIntf, callBlockArg interface{}) ([
 		evmCallData = append(evmCallData, ecd)
 
 		batch = append(batch, rpc.BatchElem{
-			Method: "eth_call",
+			Method: ccqStaticCallMethod,
 			Args: []interface{}{
 				ecd.callTransactionArg,
 				callBlockArg,
@@ -690,6 +1260,14 @@ func ccqBuildBatchFromCallData(req EthCallDataIntf, callBlockArg interface{}) ([
 	return batch, evmCallData
 }
 
+// ccqSetCallErrors copies the error returned for each call in a batch built by ccqBuildBatchFromCallData to its call data, so that calls that
+// reverted can be told apart from calls that returned an empty result.
+func ccqSetCallErrors(batch []rpc.BatchElem, evmCallData []EvmCallData) {
+	for idx := range evmCallData {
+		evmCallData[idx].callErr = batch[idx].Error
+	}
+}
+
 // ccqVerifyBlockResult does basic verification on the results of the block query.
 func (w *Watcher) ccqVerifyBlockResult(blockError error, blockResult connectors.BlockMarshaller) error { //nolint:unparam
 	if blockError != nil {
@@ -733,6 +1311,73 @@ func (w *Watcher) ccqVerifyAndExtractQueryResults(requestId string, evmCallData
 	return results, err
 }
 
+// ccqVerifyAndExtractQueryResultsAllowingReverts is like ccqVerifyAndExtractQueryResults, except that a call that reverted does not cause an error.
+// Instead, its result is the revert data, and it is flagged as EthCallReverted in the returned statuses, which are parallel to the results.
+func (w *Watcher) ccqVerifyAndExtractQueryResultsAllowingReverts(requestId string, evmCallData []EvmCallData) ([][]byte, []query.EthCallResultStatus, error) {
+	results := [][]byte{}
+	statuses := []query.EthCallResultStatus{}
+	for idx, evmCD := range evmCallData {
+		if evmCD.callErr != nil {
+			revertData, reverted := ccqRevertData(evmCD.callErr)
+			if !reverted {
+				return nil, nil, fmt.Errorf("call %d failed: %w", idx, evmCD.callErr)
+			}
+
+			w.ccqLogger.Info("query call reverted",
+				zap.String("requestId", requestId),
+				zap.Int("idx", idx),
+				zap.Stringer("callData", evmCD),
+				zap.Error(evmCD.callErr),
+			)
+
+			results = append(results, revertData)
+			statuses = append(statuses, query.EthCallReverted)
+			continue
+		}
+
+		// Nil or Empty results are not valid eth_call will return empty when the state doesn't exist for a block
+		if len(*evmCD.CallResult) == 0 {
+			return nil, nil, fmt.Errorf("call %d failed: result is empty", idx)
+		}
+
+		w.ccqLogger.Info("query call data result",
+			zap.String("requestId", requestId),
+			zap.Int("idx", idx),
+			zap.Stringer("callData", evmCD),
+		)
+
+		results = append(results, *evmCD.CallResult)
+		statuses = append(statuses, query.EthCallResultExecuted)
+	}
+
+	return results, statuses, nil
+}
+
+// ccqRevertErrorCode is the JSON-RPC error code returned by eth_call when the call reverts.
+const ccqRevertErrorCode = 3
+
+// ccqRevertData returns the revert data carried by a call error, and false if the error is not a revert. Some nodes do not use the revert error
+// code, so the message is checked as well. If the node does not return the revert data, it is empty.
+func ccqRevertData(callErr error) ([]byte, bool) {
+	var rpcErr rpc.Error
+	if !errors.As(callErr, &rpcErr) {
+		return nil, false
+	}
+	if rpcErr.ErrorCode() != ccqRevertErrorCode && !strings.Contains(rpcErr.Error(), "execution reverted") {
+		return nil, false
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(callErr, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if revertData, err := eth_hexutil.Decode(data); err == nil {
+				return revertData, true
+			}
+		}
+	}
+	return []byte{}, true
+}
+
 // ccqAddLatestBlock adds the latest block to the timestamp cache. The cache handles rollbacks.
 func (w *Watcher) ccqAddLatestBlock(ev *connectors.NewBlock) {
 	if w.ccqTimestampCache != nil {
@@ -0,0 +1,69 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/query"
+	"github.com/certusone/wormhole/node/pkg/watchers/evm/connectors"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+// ccqHandleEthBlockProbeQueryRequest is the query handler for an eth_block_probe request.
+func (w *Watcher) ccqHandleEthBlockProbeQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthBlockProbeQueryRequest) {
+	requestId := "eth_block_probe:" + queryRequest.ID()
+	w.ccqLogger.Info("received eth_block_probe query request",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("blockTag", req.BlockTag),
+	)
+
+	start := time.Now()
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, err := w.ccqProbeBlock(timeout, w.ethConn, req.BlockTag)
+	if err != nil {
+		w.ccqLogger.Error("failed to process eth_block_probe query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.String("blockTag", req.BlockTag),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, query.QueryRetryNeeded, err)
+		return
+	}
+
+	w.ccqLogger.Info("query complete for eth_block_probe",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("blockTag", req.BlockTag),
+		zap.Uint64("blockNumber", resp.BlockNumber),
+		zap.String("blockHash", resp.BlockHash.Hex()),
+		zap.Int64("duration", time.Since(start).Milliseconds()),
+	)
+
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, resp)
+}
+
+// ccqProbeBlock resolves a block tag to the header of the block it currently refers to, using a single eth_getBlockByNumber read.
+// It never executes a call.
+func (w *Watcher) ccqProbeBlock(ctx context.Context, conn ccqBatchConn, blockTag string) (*query.EthBlockProbeQueryResponse, error) {
+	var blockResult connectors.BlockMarshaller
+	batch := []rpc.BatchElem{
+		{Method: "eth_getBlockByNumber", Args: []interface{}{blockTag, false}, Result: &blockResult},
+	}
+
+	if err := ccqExecuteReadOnlyBatch(ctx, conn, batch); err != nil {
+		return nil, err
+	}
+
+	if err := w.ccqVerifyBlockResult(nil, blockResult); err != nil {
+		return nil, fmt.Errorf("failed to verify block: %w", err)
+	}
+
+	return &query.EthBlockProbeQueryResponse{
+		BlockNumber: blockResult.Number.ToInt().Uint64(),
+		BlockHash:   blockResult.Hash,
+		BlockTime:   time.Unix(int64(blockResult.Time), 0),
+	}, nil
+}
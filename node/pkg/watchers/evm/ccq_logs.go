@@ -0,0 +1,224 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/query"
+	"github.com/certusone/wormhole/node/pkg/watchers/evm/connectors"
+
+	eth_common "github.com/ethereum/go-ethereum/common"
+	eth_hexutil "github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+const ccqGetLogsMethod = "eth_getLogs"
+
+// ccqHandleEthLogsQueryRequest is the query handler for an eth_logs request.
+func (w *Watcher) ccqHandleEthLogsQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthLogsQueryRequest) {
+	requestId := "eth_logs:" + queryRequest.ID()
+	w.ccqLogger.Info("received eth_logs query request",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("fromBlock", req.FromBlock),
+		zap.String("toBlock", req.ToBlock),
+		zap.String("address", eth_common.BytesToAddress(req.Address).Hex()),
+		zap.Int("numTopics", len(req.Topics)),
+		zap.Bool("preferSpeed", queryRequest.PreferSpeed),
+	)
+
+	start := time.Now()
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, status, err := w.ccqGetLogs(timeout, w.ethConn, req, queryRequest.PreferSpeed)
+	if err != nil {
+		w.ccqLogger.Error("failed to process eth_logs query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.String("fromBlock", req.FromBlock),
+			zap.String("toBlock", req.ToBlock),
+			zap.Int("status", int(status)),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, status, err)
+		return
+	}
+
+	w.ccqLogger.Info("query complete for eth_logs",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.Uint64("fromBlock", resp.FromBlock),
+		zap.Uint64("blockNumber", resp.BlockNumber),
+		zap.String("blockHash", resp.BlockHash.Hex()),
+		zap.Int("numLogs", len(resp.Logs)),
+		zap.Int64("duration", time.Since(start).Milliseconds()),
+	)
+
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, resp)
+}
+
+// ccqGetLogs reads the logs in the requested range, along with the last block of the range, in a single batch. Ranges longer than
+// query.MaxEthLogsBlockRange are failed with QueryFatalError, since retrying them will never succeed. On error, it returns the status that
+// should be sent back to the query handler. If the requester prefers speed, logs that may have been reorged out between the two reads are
+// returned rather than retried. If the range contains no logs and the request allows it, the range is widened once, as described in
+// query.EthLogsQueryRequest. If the request sets a minimum number of logs and fewer are found, the query is retried, in case the node is
+// behind, or failed if the requester asked for that.
+func (w *Watcher) ccqGetLogs(ctx context.Context, conn ccqBatchConn, req *query.EthLogsQueryRequest, preferSpeed bool) (*query.EthLogsQueryResponse, query.QueryStatus, error) {
+	fromBlockNum, err := query.ParseBlockNumber(req.FromBlock)
+	if err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("invalid from block: %w", err)
+	}
+	toBlockNum, err := query.ParseBlockNumber(req.ToBlock)
+	if err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("invalid to block: %w", err)
+	}
+	if toBlockNum < fromBlockNum {
+		return nil, query.QueryFatalError, fmt.Errorf("to block %d is before from block %d", toBlockNum, fromBlockNum)
+	}
+	if toBlockNum-fromBlockNum >= query.MaxEthLogsBlockRange {
+		return nil, query.QueryFatalError, fmt.Errorf("block range of %d blocks exceeds the maximum of %d", toBlockNum-fromBlockNum+1, query.MaxEthLogsBlockRange)
+	}
+
+	address := eth_common.BytesToAddress(req.Address)
+	topics := make([]interface{}, len(req.Topics))
+	for idx, topic := range req.Topics {
+		if len(topic) != 0 {
+			topics[idx] = eth_common.BytesToHash(topic)
+		}
+	}
+
+	resp, status, err := w.ccqReadLogs(ctx, conn, fromBlockNum, toBlockNum, address, topics, preferSpeed)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if len(resp.Logs) == 0 && req.MaxWidenedSpan != 0 {
+		// Widen the range backwards so that it spans the requested number of blocks, stopping at the genesis block.
+		widenedFromBlockNum := uint64(0)
+		if toBlockNum+1 > uint64(req.MaxWidenedSpan) {
+			widenedFromBlockNum = toBlockNum + 1 - uint64(req.MaxWidenedSpan)
+		}
+		if widenedFromBlockNum < fromBlockNum {
+			if toBlockNum-widenedFromBlockNum >= query.MaxEthLogsBlockRange {
+				return nil, query.QueryFatalError, fmt.Errorf("widened block range of %d blocks exceeds the maximum of %d", toBlockNum-widenedFromBlockNum+1, query.MaxEthLogsBlockRange)
+			}
+			resp, status, err = w.ccqReadLogs(ctx, conn, widenedFromBlockNum, toBlockNum, address, topics, preferSpeed)
+			if err != nil {
+				return nil, status, err
+			}
+		}
+	}
+
+	if uint64(len(resp.Logs)) < uint64(req.MinResults) {
+		status = query.QueryRetryNeeded
+		if req.FailBelowMinResults {
+			status = query.QueryFatalError
+		}
+		return nil, status, fmt.Errorf("found %d logs, fewer than the minimum of %d", len(resp.Logs), req.MinResults)
+	}
+
+	return resp, query.QuerySuccess, nil
+}
+
+// ccqReadLogs reads the logs in a range, which has already been validated, along with the last block of the range, in a single batch, and verifies them.
+// If the guardian limits the number of blocks read by a single eth_getLogs call on this chain, because its provider caps the size of a log response,
+// the range is split into pages, which are read in the same batch and concatenated in order.
+func (w *Watcher) ccqReadLogs(
+	ctx context.Context,
+	conn ccqBatchConn,
+	fromBlockNum uint64,
+	toBlockNum uint64,
+	address eth_common.Address,
+	topics []interface{},
+	preferSpeed bool,
+) (*query.EthLogsQueryResponse, query.QueryStatus, error) {
+	pageSize := toBlockNum - fromBlockNum + 1
+	if w.ccqLogsPageSize != 0 && w.ccqLogsPageSize < pageSize {
+		pageSize = w.ccqLogsPageSize
+	}
+
+	numPages := (toBlockNum - fromBlockNum + pageSize) / pageSize
+	pages := make([]ccqLogsPage, numPages)
+	batch := make([]rpc.BatchElem, 0, numPages+1)
+	for idx := range pages {
+		page := &pages[idx]
+		page.fromBlockNum = fromBlockNum + uint64(idx)*pageSize
+		page.toBlockNum = page.fromBlockNum + pageSize - 1
+		if page.toBlockNum > toBlockNum {
+			page.toBlockNum = toBlockNum
+		}
+
+		filter := map[string]interface{}{
+			"fromBlock": eth_hexutil.EncodeUint64(page.fromBlockNum),
+			"toBlock":   eth_hexutil.EncodeUint64(page.toBlockNum),
+			"address":   address,
+			"topics":    topics,
+		}
+		batch = append(batch, rpc.BatchElem{Method: ccqGetLogsMethod, Args: []interface{}{filter}, Result: &page.logs})
+	}
+
+	toBlock := eth_hexutil.EncodeUint64(toBlockNum)
+	var blockResult connectors.BlockMarshaller
+	batch = append(batch, rpc.BatchElem{Method: "eth_getBlockByNumber", Args: []interface{}{toBlock, false}, Result: &blockResult})
+
+	if err := ccqExecuteReadOnlyBatch(ctx, conn, batch); err != nil {
+		return nil, query.QueryRetryNeeded, err
+	}
+
+	if err := w.ccqVerifyBlockResult(nil, blockResult); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("failed to verify block: %w", err)
+	}
+	if blockResult.Number.ToInt().Uint64() != toBlockNum {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("block number %d does not match the requested to block %d", blockResult.Number.ToInt().Uint64(), toBlockNum)
+	}
+
+	resp := &query.EthLogsQueryResponse{
+		BlockNumber: toBlockNum,
+		BlockHash:   blockResult.Hash,
+		BlockTime:   time.Unix(int64(blockResult.Time), 0),
+		FromBlock:   fromBlockNum,
+	}
+
+	idx := 0
+	for _, page := range pages {
+		for _, log := range page.logs {
+			// Make sure the node honored the filter, and that the logs were read from the same chain as the block, in case of a reorg between the two reads.
+			if log.Removed && !preferSpeed {
+				return nil, query.QueryRetryNeeded, fmt.Errorf("log %d has been removed", idx)
+			}
+			if log.BlockNumber < page.fromBlockNum || log.BlockNumber > page.toBlockNum {
+				return nil, query.QueryRetryNeeded, fmt.Errorf("log %d is in block %d, which is outside of the requested range", idx, log.BlockNumber)
+			}
+			if log.BlockNumber == toBlockNum && log.BlockHash != blockResult.Hash && !preferSpeed {
+				return nil, query.QueryRetryNeeded, fmt.Errorf("log %d is in block %s, which does not match the to block %s", idx, log.BlockHash.Hex(), blockResult.Hash.Hex())
+			}
+			if log.Address != address {
+				return nil, query.QueryRetryNeeded, fmt.Errorf("log %d was emitted by %s, not the requested address", idx, log.Address.Hex())
+			}
+			if len(log.Topics) > query.MaxEthLogsTopics {
+				return nil, query.QueryRetryNeeded, fmt.Errorf("log %d has too many topics", idx)
+			}
+			if log.Index > uint(^uint32(0)) {
+				return nil, query.QueryRetryNeeded, fmt.Errorf("log %d has an index that is too large", idx)
+			}
+
+			resp.Logs = append(resp.Logs, &query.EthLog{
+				BlockNumber: log.BlockNumber,
+				TxHash:      log.TxHash,
+				LogIndex:    uint32(log.Index),
+				Topics:      log.Topics,
+				Data:        log.Data,
+			})
+			idx++
+		}
+	}
+
+	return resp, query.QuerySuccess, nil
+}
+
+// ccqLogsPage is a sub-range of an eth_logs query that is read with a single eth_getLogs call, and the logs that were read.
+type ccqLogsPage struct {
+	fromBlockNum uint64
+	toBlockNum   uint64
+	logs         []ethTypes.Log
+}
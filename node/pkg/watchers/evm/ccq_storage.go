@@ -0,0 +1,106 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/query"
+	"github.com/certusone/wormhole/node/pkg/watchers/evm/connectors"
+
+	eth_common "github.com/ethereum/go-ethereum/common"
+	eth_hexutil "github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+// ccqHandleEthStorageQueryRequest is the query handler for an eth_storage request.
+func (w *Watcher) ccqHandleEthStorageQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthStorageQueryRequest) {
+	requestId := "eth_storage:" + queryRequest.ID()
+	block := query.NormalizeBlockId(req.BlockId)
+	w.ccqLogger.Info("received eth_storage query request",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("block", block),
+		zap.String("contract", eth_common.BytesToAddress(req.Contract).Hex()),
+		zap.Int("numSlots", len(req.Slots)),
+	)
+
+	start := time.Now()
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, status, err := w.ccqReadStorage(timeout, w.ethConn, block, req)
+	if err != nil {
+		w.ccqLogger.Error("failed to process eth_storage query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.String("block", block),
+			zap.Int("status", int(status)),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, status, err)
+		return
+	}
+
+	w.ccqLogger.Info("query complete for eth_storage",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.Uint64("blockNumber", resp.BlockNumber),
+		zap.String("blockHash", resp.BlockHash.Hex()),
+		zap.Int("numSlots", len(resp.Values)),
+		zap.Int64("duration", time.Since(start).Milliseconds()),
+	)
+
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, resp)
+}
+
+// ccqReadStorage reads the block and the value of each slot at that block in a single batch. The block id must already be normalized.
+// On error, it returns the status that should be sent back to the query handler.
+func (w *Watcher) ccqReadStorage(
+	ctx context.Context,
+	conn ccqBatchConn,
+	block string,
+	req *query.EthStorageQueryRequest,
+) (*query.EthStorageQueryResponse, query.QueryStatus, error) {
+	// The watcher should never see a query without any slots, since the request fails validation, but don't publish an empty response if it does.
+	if len(req.Slots) == 0 {
+		return nil, query.QueryFatalError, fmt.Errorf("query does not contain any slots")
+	}
+
+	blockMethod, blockArg, err := ccqCreateBlockRequest(block)
+	if err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("invalid block id: %w", err)
+	}
+
+	// The batch contains the block, followed by the value of each slot.
+	var blockResult connectors.BlockMarshaller
+	batch := []rpc.BatchElem{
+		{Method: blockMethod, Args: []interface{}{block, false}, Result: &blockResult},
+	}
+
+	contract := eth_common.BytesToAddress(req.Contract)
+	values := make([]eth_hexutil.Bytes, len(req.Slots))
+	for idx, slot := range req.Slots {
+		batch = append(batch, rpc.BatchElem{Method: ccqGetStorageAtMethod, Args: []interface{}{contract, slot, blockArg}, Result: &values[idx]})
+	}
+
+	if err := ccqExecuteReadOnlyBatch(ctx, conn, batch); err != nil {
+		return nil, query.QueryRetryNeeded, err
+	}
+
+	if err := w.ccqVerifyBlockResult(nil, blockResult); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("failed to verify block: %w", err)
+	}
+
+	resp := &query.EthStorageQueryResponse{
+		BlockNumber: blockResult.Number.ToInt().Uint64(),
+		BlockHash:   blockResult.Hash,
+		BlockTime:   time.Unix(int64(blockResult.Time), 0),
+	}
+
+	for idx, value := range values {
+		if len(value) > eth_common.HashLength {
+			return nil, query.QueryRetryNeeded, fmt.Errorf("storage value of slot %d is longer than %d bytes", idx, eth_common.HashLength)
+		}
+		resp.Values = append(resp.Values, eth_common.BytesToHash(value))
+	}
+
+	return resp, query.QuerySuccess, nil
+}
@@ -0,0 +1,137 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/query"
+	"github.com/certusone/wormhole/node/pkg/watchers/evm/connectors"
+
+	eth_common "github.com/ethereum/go-ethereum/common"
+	eth_hexutil "github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+const ccqGetStorageAtMethod = "eth_getStorageAt"
+
+// ccqHandleEthStorageDiffQueryRequest is the query handler for an eth_storage_diff request.
+func (w *Watcher) ccqHandleEthStorageDiffQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthStorageDiffQueryRequest) {
+	requestId := "eth_storage_diff:" + queryRequest.ID()
+	fromBlock := query.NormalizeBlockId(req.FromBlockId)
+	toBlock := query.NormalizeBlockId(req.ToBlockId)
+	w.ccqLogger.Info("received eth_storage_diff query request",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("fromBlock", fromBlock),
+		zap.String("toBlock", toBlock),
+		zap.String("contract", eth_common.BytesToAddress(req.Contract).Hex()),
+		zap.Int("numSlots", len(req.Slots)),
+	)
+
+	start := time.Now()
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, status, err := w.ccqBuildStorageDiff(timeout, w.ethConn, fromBlock, toBlock, req)
+	if err != nil {
+		w.ccqLogger.Error("failed to process eth_storage_diff query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.String("fromBlock", fromBlock),
+			zap.String("toBlock", toBlock),
+			zap.Int("status", int(status)),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, status, err)
+		return
+	}
+
+	numChanged := 0
+	for _, slot := range resp.Slots {
+		if slot.Changed {
+			numChanged++
+		}
+	}
+
+	w.ccqLogger.Info("query complete for eth_storage_diff",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.Uint64("fromBlockNumber", resp.FromBlockNumber),
+		zap.String("fromBlockHash", resp.FromBlockHash.Hex()),
+		zap.Uint64("toBlockNumber", resp.ToBlockNumber),
+		zap.String("toBlockHash", resp.ToBlockHash.Hex()),
+		zap.Int("numSlots", len(resp.Slots)),
+		zap.Int("numChanged", numChanged),
+		zap.Int64("duration", time.Since(start).Milliseconds()),
+	)
+
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, resp)
+}
+
+// ccqBuildStorageDiff reads both blocks and the value of each slot at both blocks in a single batch, and returns the diff. The block ids must already
+// be normalized. On error, it returns the status that should be sent back to the query handler.
+func (w *Watcher) ccqBuildStorageDiff(
+	ctx context.Context,
+	conn ccqBatchConn,
+	fromBlock string,
+	toBlock string,
+	req *query.EthStorageDiffQueryRequest,
+) (*query.EthStorageDiffQueryResponse, query.QueryStatus, error) {
+	fromBlockMethod, fromBlockArg, err := ccqCreateBlockRequest(fromBlock)
+	if err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("invalid from block id: %w", err)
+	}
+	toBlockMethod, toBlockArg, err := ccqCreateBlockRequest(toBlock)
+	if err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("invalid to block id: %w", err)
+	}
+
+	// The batch contains both blocks, followed by the old and new value of each slot.
+	var fromBlockResult, toBlockResult connectors.BlockMarshaller
+	batch := []rpc.BatchElem{
+		{Method: fromBlockMethod, Args: []interface{}{fromBlock, false}, Result: &fromBlockResult},
+		{Method: toBlockMethod, Args: []interface{}{toBlock, false}, Result: &toBlockResult},
+	}
+
+	contract := eth_common.BytesToAddress(req.Contract)
+	oldValues := make([]eth_hexutil.Bytes, len(req.Slots))
+	newValues := make([]eth_hexutil.Bytes, len(req.Slots))
+	for idx, slot := range req.Slots {
+		batch = append(batch,
+			rpc.BatchElem{Method: ccqGetStorageAtMethod, Args: []interface{}{contract, slot, fromBlockArg}, Result: &oldValues[idx]},
+			rpc.BatchElem{Method: ccqGetStorageAtMethod, Args: []interface{}{contract, slot, toBlockArg}, Result: &newValues[idx]},
+		)
+	}
+
+	if err := ccqExecuteReadOnlyBatch(ctx, conn, batch); err != nil {
+		return nil, query.QueryRetryNeeded, err
+	}
+
+	if err := w.ccqVerifyBlockResult(nil, fromBlockResult); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("failed to verify from block: %w", err)
+	}
+	if err := w.ccqVerifyBlockResult(nil, toBlockResult); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("failed to verify to block: %w", err)
+	}
+
+	resp := &query.EthStorageDiffQueryResponse{
+		FromBlockNumber: fromBlockResult.Number.ToInt().Uint64(),
+		FromBlockHash:   fromBlockResult.Hash,
+		FromBlockTime:   time.Unix(int64(fromBlockResult.Time), 0),
+		ToBlockNumber:   toBlockResult.Number.ToInt().Uint64(),
+		ToBlockHash:     toBlockResult.Hash,
+		ToBlockTime:     time.Unix(int64(toBlockResult.Time), 0),
+	}
+
+	for idx := range req.Slots {
+		if len(oldValues[idx]) > eth_common.HashLength || len(newValues[idx]) > eth_common.HashLength {
+			return nil, query.QueryRetryNeeded, fmt.Errorf("storage value of slot %d is longer than %d bytes", idx, eth_common.HashLength)
+		}
+		slot := &query.EthStorageSlotDiff{
+			OldValue: eth_common.BytesToHash(oldValues[idx]),
+			NewValue: eth_common.BytesToHash(newValues[idx]),
+		}
+		slot.Changed = slot.OldValue != slot.NewValue
+		resp.Slots = append(resp.Slots, slot)
+	}
+
+	return resp, query.QuerySuccess, nil
+}
@@ -1,11 +1,28 @@
 package evm
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/query"
+	"github.com/certusone/wormhole/node/pkg/watchers/evm/connectors"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethHexUtil "github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/wormhole-foundation/wormhole/sdk/vaa"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"go.uber.org/zap"
 )
 
 func TestCcqCreateBlockRequest(t *testing.T) {
@@ -44,3 +61,1161 @@ func TestCcqCreateBlockRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestCcqCreateBlockRequestWithDecimalBlockId(t *testing.T) {
+	hexMethod, hexArg, err := ccqCreateBlockRequest(query.NormalizeBlockId("0x28d9630"))
+	require.NoError(t, err)
+	decimalMethod, decimalArg, err := ccqCreateBlockRequest(query.NormalizeBlockId("d:42833456"))
+	require.NoError(t, err)
+
+	assert.Equal(t, hexMethod, decimalMethod)
+	assert.Equal(t, hexArg, decimalArg)
+}
+
+func TestCcqVerifyReadOnlyBatch(t *testing.T) {
+	type test struct {
+		label  string
+		batch  []rpc.BatchElem
+		errMsg string
+	}
+
+	tests := []test{
+		// Failure cases:
+		{label: "sendTransaction", batch: []rpc.BatchElem{{Method: "eth_sendTransaction", Args: []interface{}{map[string]interface{}{"to": "0x00"}}}}, errMsg: "batch entry 0 uses method eth_sendTransaction which is not read-only"},
+		{label: "sendRawTransaction", batch: []rpc.BatchElem{{Method: "eth_sendRawTransaction", Args: []interface{}{"0x00"}}}, errMsg: "batch entry 0 uses method eth_sendRawTransaction which is not read-only"},
+		{label: "value", batch: []rpc.BatchElem{{Method: "eth_call", Args: []interface{}{map[string]interface{}{"to": "0x00", "data": "0x00", "value": "0x1"}, "latest"}}}, errMsg: "batch entry 0 sets call field value which is not allowed in a static call"},
+		{label: "nonce", batch: []rpc.BatchElem{{Method: "eth_call", Args: []interface{}{map[string]interface{}{"to": "0x00", "data": "0x00", "nonce": "0x1"}, "latest"}}}, errMsg: "batch entry 0 sets call field nonce which is not allowed in a static call"},
+		{label: "noArgs", batch: []rpc.BatchElem{{Method: "eth_call"}}, errMsg: "batch entry 0 does not contain call arguments"},
+		{label: "badArgs", batch: []rpc.BatchElem{{Method: "eth_call", Args: []interface{}{"0x00"}}}, errMsg: "batch entry 0 has call arguments of unexpected type string"},
+
+		// Success cases:
+		{label: "empty", batch: []rpc.BatchElem{}, errMsg: ""},
+		{label: "callAndBlock", batch: []rpc.BatchElem{
+			{Method: "eth_call", Args: []interface{}{map[string]interface{}{"to": "0x00", "data": "0x00"}, "0xb96d7a"}},
+			{Method: "eth_getBlockByNumber", Args: []interface{}{"0xb96d7a", false}},
+		}, errMsg: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			err := ccqVerifyReadOnlyBatch(tc.batch)
+			if tc.errMsg == "" {
+				require.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.errMsg)
+			}
+		})
+	}
+}
+
+func TestCcqBuildBatchFromCallDataIsReadOnly(t *testing.T) {
+	req := &query.EthCallQueryRequest{
+		BlockId: "0xb96d7a",
+		CallData: []*query.EthCallData{
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x06, 0xfd, 0xde, 0x03}},
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x18, 0x16, 0x0d, 0xdd}},
+		},
+	}
+
+	_, callBlockArg, err := ccqCreateBlockRequest(req.BlockId)
+	require.NoError(t, err)
+
+	batch, _ := ccqBuildBatchFromCallData(req, callBlockArg)
+	require.Equal(t, len(req.CallData), len(batch))
+	assert.NoError(t, ccqVerifyReadOnlyBatch(batch))
+}
+
+// mockPreconditionConn simulates a batch query. It returns the configured result for each eth_call, based on the call data, and records the calls made.
+type mockPreconditionConn struct {
+	results   map[string][]byte
+	block     connectors.BlockMarshaller
+	callData  []string
+	callBlock []interface{}
+}
+
+func (conn *mockPreconditionConn) RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for _, b := range b {
+		var result interface{}
+		switch b.Method {
+		case ccqStaticCallMethod:
+			data := b.Args[0].(map[string]interface{})["data"].(string)
+			conn.callData = append(conn.callData, data)
+			conn.callBlock = append(conn.callBlock, b.Args[1])
+			result = ethHexUtil.Bytes(conn.results[data])
+		case "eth_getBlockByNumber", "eth_getBlockByHash":
+			result = conn.block
+		default:
+			return fmt.Errorf("unexpected method: %s", b.Method)
+		}
+
+		bytes, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		err = json.Unmarshal(bytes, b.Result)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+	}
+	return nil
+}
+
+func createPreconditionTest(t *testing.T, preconditionResult []byte) (*Watcher, *mockPreconditionConn, *query.EthCallWithPreconditionQueryRequest) {
+	t.Helper()
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	req := &query.EthCallWithPreconditionQueryRequest{
+		BlockId:        "0xb96d7a",
+		ExpectedResult: []byte{0x01},
+		CallData: []*query.EthCallData{
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x01}},
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x02}},
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x03}},
+		},
+	}
+
+	conn := &mockPreconditionConn{
+		results: map[string][]byte{
+			"0x01": preconditionResult,
+			"0x02": []byte("dependent 1"),
+			"0x03": []byte("dependent 2"),
+		},
+		block: connectors.BlockMarshaller{
+			Number: (*ethHexUtil.Big)(big.NewInt(0xb96d7a)),
+			Hash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+			Time:   ethHexUtil.Uint64(1700000000),
+		},
+	}
+
+	return w, conn, req
+}
+
+func TestCcqExecuteWithPreconditionSkipsDependentCallsIfPreconditionFails(t *testing.T) {
+	w, conn, req := createPreconditionTest(t, []byte{0x00})
+	blockMethod, callBlockArg, err := ccqCreateBlockRequest(req.BlockId)
+	require.NoError(t, err)
+
+	resp, status, err := w.ccqExecuteWithPrecondition(context.Background(), conn, "test", req, blockMethod, req.BlockId, callBlockArg)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+
+	// Only the precondition should have been executed.
+	assert.Equal(t, []string{"0x01"}, conn.callData)
+
+	assert.False(t, resp.PreconditionMet())
+	assert.Equal(t, []query.EthCallResultStatus{query.EthCallResultExecuted, query.PreconditionFailed, query.PreconditionFailed}, resp.Statuses)
+	assert.Equal(t, [][]byte{{0x00}, {}, {}}, resp.Results)
+	assert.NoError(t, resp.Validate())
+}
+
+func TestCcqExecuteWithPreconditionExecutesDependentCallsIfPreconditionHolds(t *testing.T) {
+	w, conn, req := createPreconditionTest(t, []byte{0x01})
+	blockMethod, callBlockArg, err := ccqCreateBlockRequest(req.BlockId)
+	require.NoError(t, err)
+
+	resp, status, err := w.ccqExecuteWithPrecondition(context.Background(), conn, "test", req, blockMethod, req.BlockId, callBlockArg)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+
+	assert.Equal(t, []string{"0x01", "0x02", "0x03"}, conn.callData)
+
+	// The dependent calls should be pinned to the hash of the block used for the precondition.
+	_, hashBlockArg, err := ccqCreateBlockRequest(conn.block.Hash.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, hashBlockArg, conn.callBlock[1])
+	assert.Equal(t, hashBlockArg, conn.callBlock[2])
+
+	assert.True(t, resp.PreconditionMet())
+	assert.Equal(t, uint64(0xb96d7a), resp.BlockNumber)
+	assert.Equal(t, [][]byte{{0x01}, []byte("dependent 1"), []byte("dependent 2")}, resp.Results)
+	assert.NoError(t, resp.Validate())
+}
+
+func TestCcqResponseMetadataContainsChainHeadLag(t *testing.T) {
+	// The query is for a historical block, well behind the latest block seen by the watcher.
+	w, conn, req := createPreconditionTest(t, []byte{0x01})
+	w.latestBlockNumber = 0xb96d7a + 250
+	queryResponseC := make(chan *query.PerChainQueryResponseInternal, 1)
+	w.queryResponseC = queryResponseC
+
+	blockMethod, callBlockArg, err := ccqCreateBlockRequest(req.BlockId)
+	require.NoError(t, err)
+	resp, status, err := w.ccqExecuteWithPrecondition(context.Background(), conn, "test", req, blockMethod, req.BlockId, callBlockArg)
+	require.NoError(t, err)
+	require.Equal(t, query.QuerySuccess, status)
+
+	queryRequest := &query.PerChainQueryInternal{RequestID: "test", Request: &query.PerChainQueryRequest{ChainId: w.chainID, Query: req}}
+	before := time.Now()
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, resp)
+	after := time.Now()
+
+	queryResponse := <-queryResponseC
+	require.NotNil(t, queryResponse.Metadata)
+	assert.Equal(t, uint64(250), queryResponse.Metadata.BlocksBehindHead)
+
+	blockTime := time.Unix(1700000000, 0)
+	assert.GreaterOrEqual(t, queryResponse.Metadata.BlockTimeLag, before.Sub(blockTime))
+	assert.LessOrEqual(t, queryResponse.Metadata.BlockTimeLag, after.Sub(blockTime))
+}
+
+func TestCcqVerifyBlocksForTimestamp(t *testing.T) {
+	block := connectors.BlockMarshaller{Number: (*ethHexUtil.Big)(big.NewInt(100)), Time: ethHexUtil.Uint64(1000)}
+	nextBlock := connectors.BlockMarshaller{Number: (*ethHexUtil.Big)(big.NewInt(101)), Time: ethHexUtil.Uint64(1010)}
+
+	assert.NoError(t, ccqVerifyBlocksForTimestamp(1000000000, block, nextBlock))
+	assert.NoError(t, ccqVerifyBlocksForTimestamp(1009999999, block, nextBlock))
+	assert.ErrorContains(t, ccqVerifyBlocksForTimestamp(999999999, block, nextBlock), "outside of block range")
+	assert.ErrorContains(t, ccqVerifyBlocksForTimestamp(1010000000, block, nextBlock), "outside of block range")
+
+	nextBlock.Number = (*ethHexUtil.Big)(big.NewInt(102))
+	assert.ErrorContains(t, ccqVerifyBlocksForTimestamp(1000000000, block, nextBlock), "not adjacent")
+}
+
+// mockTxProofConn simulates the RPC node for an eth_tx_proof query, serving a single block containing the configured transactions.
+type mockTxProofConn struct {
+	block connectors.BlockMarshaller
+	root  ethCommon.Hash
+	txs   ethTypes.Transactions
+}
+
+func (conn *mockTxProofConn) RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for _, b := range b {
+		var result interface{}
+		switch b.Method {
+		case ccqGetTransactionMethod:
+			for idx, tx := range conn.txs {
+				if tx.Hash() == b.Args[0].(ethCommon.Hash) {
+					result = map[string]interface{}{
+						"blockHash":        conn.block.Hash,
+						"blockNumber":      conn.block.Number,
+						"transactionIndex": ethHexUtil.Uint64(idx),
+					}
+				}
+			}
+		case "eth_getBlockByHash":
+			hashes := []ethCommon.Hash{}
+			for _, tx := range conn.txs {
+				hashes = append(hashes, tx.Hash())
+			}
+			result = map[string]interface{}{
+				"number":           conn.block.Number,
+				"hash":             conn.block.Hash,
+				"timestamp":        conn.block.Time,
+				"transactionsRoot": conn.root,
+				"transactions":     hashes,
+			}
+		case ccqGetRawTransactionMethod:
+			raw, err := conn.txs[b.Args[1].(ethHexUtil.Uint64)].MarshalBinary()
+			if err != nil {
+				return fmt.Errorf("failed to marshal transaction: %w", err)
+			}
+			result = ethHexUtil.Bytes(raw)
+		default:
+			return fmt.Errorf("unexpected method: %s", b.Method)
+		}
+
+		bytes, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		err = json.Unmarshal(bytes, b.Result)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+	}
+	return nil
+}
+
+func createTxProofTest(t *testing.T, numTxs int) (*Watcher, *mockTxProofConn) {
+	t.Helper()
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	txs := ethTypes.Transactions{}
+	for idx := 0; idx < numTxs; idx++ {
+		txs = append(txs, ethTypes.NewTransaction(uint64(idx), ethCommon.BigToAddress(big.NewInt(int64(idx+1))), big.NewInt(1000), 21000, big.NewInt(1), nil))
+	}
+
+	conn := &mockTxProofConn{
+		block: connectors.BlockMarshaller{
+			Number: (*ethHexUtil.Big)(big.NewInt(0xb96d7a)),
+			Hash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+			Time:   ethHexUtil.Uint64(1700000000),
+		},
+		root: ethTypes.DeriveSha(txs, trie.NewStackTrie(nil)),
+		txs:  txs,
+	}
+
+	return w, conn
+}
+
+func TestCcqLookUpBlocksForTimestamp(t *testing.T) {
+	w := &Watcher{ccqLogger: zap.NewNop(), ccqTimestampCache: NewBlocksByTimestamp(BTS_MAX_BLOCKS, false)}
+	w.ccqTimestampCache.AddLatest(w.ccqLogger, 1000, 100)
+	w.ccqTimestampCache.AddLatest(w.ccqLogger, 1010, 101)
+	w.ccqTimestampCache.AddLatest(w.ccqLogger, 1020, 102)
+
+	// A timestamp bracketed by two blocks maps to them.
+	blockNum, nextBlockNum, status, ok := w.ccqLookUpBlocksForTimestamp("test", 1015000000)
+	require.True(t, ok)
+	assert.Equal(t, query.QuerySuccess, status)
+	assert.Equal(t, uint64(101), blockNum)
+	assert.Equal(t, uint64(102), nextBlockNum)
+
+	// A timestamp at or after the latest block is retried, since the following block has not been produced yet.
+	for _, timestamp := range []uint64{1020000000, 2000000000} {
+		_, _, status, ok = w.ccqLookUpBlocksForTimestamp("test", timestamp)
+		assert.False(t, ok)
+		assert.Equal(t, query.QueryRetryNeeded, status)
+	}
+
+	// A timestamp before the start of the cache can never be answered.
+	_, _, status, ok = w.ccqLookUpBlocksForTimestamp("test", 999000000)
+	assert.False(t, ok)
+	assert.Equal(t, query.QueryFatalError, status)
+}
+
+func TestCcqBuildTxProof(t *testing.T) {
+	w, conn := createTxProofTest(t, 150)
+
+	for _, txIdx := range []int{0, 1, 127, 128, 149} {
+		txHash := conn.txs[txIdx].Hash()
+		resp, status, err := w.ccqBuildTxProof(context.Background(), conn, txHash)
+		require.NoError(t, err)
+		require.Equal(t, query.QuerySuccess, status)
+
+		assert.Equal(t, uint64(0xb96d7a), resp.BlockNumber)
+		assert.Equal(t, conn.block.Hash, resp.BlockHash)
+		assert.Equal(t, time.Unix(1700000000, 0), resp.BlockTime)
+		assert.Equal(t, conn.root, resp.TransactionsRoot)
+		assert.Equal(t, uint32(txIdx), resp.TxIndex)
+		require.NoError(t, resp.Validate())
+
+		tx, err := resp.Verify(txHash)
+		require.NoError(t, err)
+		expectedTx, err := conn.txs[txIdx].MarshalBinary()
+		require.NoError(t, err)
+		assert.Equal(t, expectedTx, tx)
+	}
+}
+
+func TestCcqBuildTxProofRetriesIfTxNotFound(t *testing.T) {
+	w, conn := createTxProofTest(t, 3)
+
+	_, status, err := w.ccqBuildTxProof(context.Background(), conn, ethCommon.HexToHash("0x01"))
+	require.Error(t, err)
+	assert.Equal(t, query.QueryRetryNeeded, status)
+}
+
+func TestCcqBuildTxProofFailsIfRootDoesNotMatch(t *testing.T) {
+	w, conn := createTxProofTest(t, 3)
+	conn.root = ethCommon.HexToHash("0x01")
+
+	_, status, err := w.ccqBuildTxProof(context.Background(), conn, conn.txs[1].Hash())
+	require.ErrorContains(t, err, "transactions root does not match the block header")
+	assert.Equal(t, query.QueryFatalError, status)
+}
+
+// mockSlowBatchConn simulates an RPC provider that executes the elements of a batch one after the other. Each eth_call returns its call data.
+type mockSlowBatchConn struct {
+	perElementDelay time.Duration
+	numBatches      atomic.Int32
+}
+
+func (conn *mockSlowBatchConn) RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	conn.numBatches.Add(1)
+	for _, b := range b {
+		time.Sleep(conn.perElementDelay)
+		data := b.Args[0].(map[string]interface{})["data"].(string)
+		*b.Result.(*ethHexUtil.Bytes) = ethHexUtil.MustDecode(data)
+	}
+	return nil
+}
+
+func createBatchForConcurrencyTest(numCalls int) ([]rpc.BatchElem, []*ethHexUtil.Bytes) {
+	batch := []rpc.BatchElem{}
+	results := []*ethHexUtil.Bytes{}
+	for idx := 0; idx < numCalls; idx++ {
+		result := &ethHexUtil.Bytes{}
+		results = append(results, result)
+		batch = append(batch, rpc.BatchElem{
+			Method: ccqStaticCallMethod,
+			Args:   []interface{}{map[string]interface{}{"data": fmt.Sprintf("0x%04x", idx)}, "0x1"},
+			Result: result,
+		})
+	}
+	return batch, results
+}
+
+func TestCcqBatchCallWithConcurrencyPreservesOrderAndIsFaster(t *testing.T) {
+	const numCalls = 40
+	const perElementDelay = 5 * time.Millisecond
+
+	var durations []time.Duration
+	for _, concurrency := range []int{1, 8} {
+		w := &Watcher{ccqLogger: zap.NewNop(), ccqCallConcurrency: concurrency}
+		conn := &mockSlowBatchConn{perElementDelay: perElementDelay}
+		batch, results := createBatchForConcurrencyTest(numCalls)
+
+		start := time.Now()
+		require.NoError(t, w.ccqBatchCall(context.Background(), conn, batch))
+		durations = append(durations, time.Since(start))
+
+		assert.Equal(t, int32(concurrency), conn.numBatches.Load())
+		for idx, result := range results {
+			assert.Equal(t, fmt.Sprintf("0x%04x", idx), result.String())
+		}
+	}
+
+	// Sequentially, the batch takes at least numCalls * perElementDelay. Split eight ways, it should take well under half that.
+	assert.GreaterOrEqual(t, durations[0], numCalls*perElementDelay)
+	assert.Less(t, durations[1], numCalls*perElementDelay/2)
+}
+
+// mockFailingBatchConn fails any batch that contains the specified call data.
+type mockFailingBatchConn struct {
+	failOn string
+}
+
+func (conn *mockFailingBatchConn) RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for _, b := range b {
+		if b.Args[0].(map[string]interface{})["data"].(string) == conn.failOn {
+			return fmt.Errorf("rpc failed")
+		}
+	}
+	return nil
+}
+
+func TestCcqBatchCallWithConcurrencyReturnsSubBatchError(t *testing.T) {
+	w := &Watcher{ccqLogger: zap.NewNop(), ccqCallConcurrency: 4}
+	batch, _ := createBatchForConcurrencyTest(10)
+	assert.EqualError(t, w.ccqBatchCall(context.Background(), &mockFailingBatchConn{failOn: "0x0009"}, batch), "rpc failed")
+	assert.NoError(t, w.ccqBatchCall(context.Background(), &mockFailingBatchConn{failOn: "0x1234"}, batch))
+}
+
+func BenchmarkCcqBatchCallWithConcurrency(b *testing.B) {
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency_%d", concurrency), func(b *testing.B) {
+			w := &Watcher{ccqLogger: zap.NewNop(), ccqCallConcurrency: concurrency}
+			conn := &mockSlowBatchConn{perElementDelay: 100 * time.Microsecond}
+			for i := 0; i < b.N; i++ {
+				batch, _ := createBatchForConcurrencyTest(64)
+				if err := w.ccqBatchCall(context.Background(), conn, batch); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// mockSyncConn returns the configured result for eth_syncing, and counts the calls.
+type mockSyncConn struct {
+	result   string
+	err      error
+	numCalls int
+}
+
+func (conn *mockSyncConn) RawCallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	conn.numCalls++
+	if method != "eth_syncing" {
+		return fmt.Errorf("unexpected method: %s", method)
+	}
+	if conn.err != nil {
+		return conn.err
+	}
+	return json.Unmarshal([]byte(conn.result), result)
+}
+
+func TestCcqNodeSyncing(t *testing.T) {
+	type test struct {
+		label   string
+		result  string
+		err     error
+		syncing bool
+	}
+
+	tests := []test{
+		{label: "not syncing", result: "false", syncing: false},
+		{label: "syncing", result: `{"startingBlock":"0x0","currentBlock":"0x1000","highestBlock":"0x2000"}`, syncing: true},
+		{label: "rpc error", err: fmt.Errorf("rpc failed"), syncing: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.label, func(t *testing.T) {
+			w := &Watcher{ccqLogger: zap.NewNop()}
+			conn := &mockSyncConn{result: tc.result, err: tc.err}
+			assert.Equal(t, tc.syncing, w.ccqNodeSyncing(context.Background(), conn))
+
+			// The status is cached.
+			assert.Equal(t, tc.syncing, w.ccqNodeSyncing(context.Background(), conn))
+			assert.Equal(t, 1, conn.numCalls)
+
+			// Once the cache expires, it is read again.
+			w.ccqSyncCheckTime = time.Now().Add(-ccqSyncStatusInterval)
+			conn.result = "false"
+			conn.err = nil
+			assert.False(t, w.ccqNodeSyncing(context.Background(), conn))
+			assert.Equal(t, 2, conn.numCalls)
+		})
+	}
+}
+
+// mockStorageDiffConn simulates the RPC node for an eth_storage_diff query. It serves two blocks, and the storage of a single contract at each of them.
+type mockStorageDiffConn struct {
+	blocks   []connectors.BlockMarshaller
+	contract ethCommon.Address
+	storage  map[ethCommon.Hash]map[ethCommon.Hash]ethCommon.Hash
+}
+
+// lookUpBlock returns the block with the specified number or hash.
+func (conn *mockStorageDiffConn) lookUpBlock(blockArg interface{}) (connectors.BlockMarshaller, error) {
+	for _, block := range conn.blocks {
+		switch arg := blockArg.(type) {
+		case string:
+			if arg == block.Number.String() || arg == block.Hash.Hex() {
+				return block, nil
+			}
+		case rpc.BlockNumberOrHash:
+			if arg.BlockHash != nil && *arg.BlockHash == block.Hash {
+				return block, nil
+			}
+		}
+	}
+	return connectors.BlockMarshaller{}, fmt.Errorf("unknown block: %v", blockArg)
+}
+
+func (conn *mockStorageDiffConn) RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for _, b := range b {
+		var result interface{}
+		switch b.Method {
+		case "eth_getBlockByNumber", "eth_getBlockByHash":
+			block, err := conn.lookUpBlock(b.Args[0])
+			if err != nil {
+				return err
+			}
+			result = block
+		case ccqGetStorageAtMethod:
+			if b.Args[0].(ethCommon.Address) != conn.contract {
+				return fmt.Errorf("unexpected contract: %v", b.Args[0])
+			}
+			block, err := conn.lookUpBlock(b.Args[2])
+			if err != nil {
+				return err
+			}
+			value := conn.storage[block.Hash][b.Args[1].(ethCommon.Hash)]
+			result = ethHexUtil.Bytes(value.Bytes())
+		default:
+			return fmt.Errorf("unexpected method: %s", b.Method)
+		}
+
+		bytes, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		err = json.Unmarshal(bytes, b.Result)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+	}
+	return nil
+}
+
+func TestCcqBuildStorageDiff(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	fromBlock := connectors.BlockMarshaller{
+		Number: (*ethHexUtil.Big)(big.NewInt(0xb96d7a)),
+		Hash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		Time:   ethHexUtil.Uint64(1700000000),
+	}
+	toBlock := connectors.BlockMarshaller{
+		Number: (*ethHexUtil.Big)(big.NewInt(0xb96e00)),
+		Hash:   ethCommon.HexToHash("0x7777bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		Time:   ethHexUtil.Uint64(1700000300),
+	}
+	conn := &mockStorageDiffConn{
+		blocks:   []connectors.BlockMarshaller{fromBlock, toBlock},
+		contract: ethCommon.HexToAddress("0x0d500b1d8e8ef31e21c99d1db9a6444d3adf1270"),
+		storage: map[ethCommon.Hash]map[ethCommon.Hash]ethCommon.Hash{
+			fromBlock.Hash: {ethCommon.HexToHash("0x00"): ethCommon.HexToHash("0x2a"), ethCommon.HexToHash("0x01"): ethCommon.HexToHash("0x10")},
+			toBlock.Hash:   {ethCommon.HexToHash("0x00"): ethCommon.HexToHash("0x2a"), ethCommon.HexToHash("0x01"): ethCommon.HexToHash("0x11")},
+		},
+	}
+
+	// The from block is given by number, and the to block by hash. Slot 2 is never set, so it is zero at both blocks.
+	req := &query.EthStorageDiffQueryRequest{
+		FromBlockId: fromBlock.Number.String(),
+		ToBlockId:   toBlock.Hash.Hex(),
+		Contract:    conn.contract.Bytes(),
+		Slots:       []ethCommon.Hash{ethCommon.HexToHash("0x00"), ethCommon.HexToHash("0x01"), ethCommon.HexToHash("0x02")},
+	}
+
+	resp, status, err := w.ccqBuildStorageDiff(context.Background(), conn, req.FromBlockId, req.ToBlockId, req)
+	require.NoError(t, err)
+	require.Equal(t, query.QuerySuccess, status)
+	require.NoError(t, resp.Validate())
+
+	assert.Equal(t, uint64(0xb96d7a), resp.FromBlockNumber)
+	assert.Equal(t, fromBlock.Hash, resp.FromBlockHash)
+	assert.Equal(t, time.Unix(1700000000, 0), resp.FromBlockTime)
+	assert.Equal(t, uint64(0xb96e00), resp.ToBlockNumber)
+	assert.Equal(t, toBlock.Hash, resp.ToBlockHash)
+	assert.Equal(t, time.Unix(1700000300, 0), resp.ToBlockTime)
+	assert.Equal(t, []*query.EthStorageSlotDiff{
+		{OldValue: ethCommon.HexToHash("0x2a"), NewValue: ethCommon.HexToHash("0x2a"), Changed: false},
+		{OldValue: ethCommon.HexToHash("0x10"), NewValue: ethCommon.HexToHash("0x11"), Changed: true},
+		{OldValue: ethCommon.Hash{}, NewValue: ethCommon.Hash{}, Changed: false},
+	}, resp.Slots)
+
+	// An unknown block should be retried.
+	_, status, err = w.ccqBuildStorageDiff(context.Background(), conn, "0x01", req.ToBlockId, req)
+	require.Error(t, err)
+	assert.Equal(t, query.QueryRetryNeeded, status)
+}
+
+func TestCcqReadStorage(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	block := connectors.BlockMarshaller{
+		Number: (*ethHexUtil.Big)(big.NewInt(0xb96d7a)),
+		Hash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+		Time:   ethHexUtil.Uint64(1700000000),
+	}
+	conn := &mockStorageDiffConn{
+		blocks:   []connectors.BlockMarshaller{block},
+		contract: ethCommon.HexToAddress("0x0d500b1d8e8ef31e21c99d1db9a6444d3adf1270"),
+		storage: map[ethCommon.Hash]map[ethCommon.Hash]ethCommon.Hash{
+			block.Hash: {ethCommon.HexToHash("0x00"): ethCommon.HexToHash("0x2a"), ethCommon.HexToHash("0x01"): ethCommon.HexToHash("0x10")},
+		},
+	}
+
+	// Slot 2 is never set, so it is zero.
+	req := &query.EthStorageQueryRequest{
+		BlockId:  block.Hash.Hex(),
+		Contract: conn.contract.Bytes(),
+		Slots:    []ethCommon.Hash{ethCommon.HexToHash("0x00"), ethCommon.HexToHash("0x01"), ethCommon.HexToHash("0x02")},
+	}
+
+	resp, status, err := w.ccqReadStorage(context.Background(), conn, req.BlockId, req)
+	require.NoError(t, err)
+	require.Equal(t, query.QuerySuccess, status)
+	require.NoError(t, resp.Validate())
+
+	assert.Equal(t, uint64(0xb96d7a), resp.BlockNumber)
+	assert.Equal(t, block.Hash, resp.BlockHash)
+	assert.Equal(t, time.Unix(1700000000, 0), resp.BlockTime)
+	assert.Equal(t, []ethCommon.Hash{ethCommon.HexToHash("0x2a"), ethCommon.HexToHash("0x10"), {}}, resp.Values)
+
+	// A query without any slots is failed without contacting the node.
+	_, status, err = w.ccqReadStorage(context.Background(), &mockStorageDiffConn{}, req.BlockId, &query.EthStorageQueryRequest{BlockId: req.BlockId, Contract: req.Contract})
+	assert.EqualError(t, err, "query does not contain any slots")
+	assert.Equal(t, query.QueryFatalError, status)
+}
+
+// mockBlockProbeConn simulates the RPC node for an eth_block_probe query. It serves a single block for any tag, and records the methods it was asked for.
+type mockBlockProbeConn struct {
+	block   connectors.BlockMarshaller
+	methods []string
+}
+
+func (conn *mockBlockProbeConn) RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for _, b := range b {
+		conn.methods = append(conn.methods, b.Method)
+		if b.Method != "eth_getBlockByNumber" {
+			return fmt.Errorf("unexpected method: %s", b.Method)
+		}
+
+		bytes, err := json.Marshal(conn.block)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		err = json.Unmarshal(bytes, b.Result)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+	}
+	return nil
+}
+
+func TestCcqProbeBlock(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	conn := &mockBlockProbeConn{
+		block: connectors.BlockMarshaller{
+			Number: (*ethHexUtil.Big)(big.NewInt(0xb96d7a)),
+			Hash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+			Time:   ethHexUtil.Uint64(1700000000),
+		},
+	}
+
+	resp, err := w.ccqProbeBlock(context.Background(), conn, "finalized")
+	require.NoError(t, err)
+	require.NoError(t, resp.Validate())
+	assert.Equal(t, uint64(0xb96d7a), resp.BlockNumber)
+	assert.Equal(t, conn.block.Hash, resp.BlockHash)
+	assert.Equal(t, time.Unix(1700000000, 0), resp.BlockTime)
+
+	// The probe only reads the block header, it never makes a call.
+	assert.Equal(t, []string{"eth_getBlockByNumber"}, conn.methods)
+
+	// A block that is too new should be retried.
+	w.ccqMaxBlockNumber = big.NewInt(0xb96d79)
+	_, err = w.ccqProbeBlock(context.Background(), conn, "latest")
+	require.ErrorContains(t, err, "block number is too large")
+}
+
+func TestCcqResolveFinality(t *testing.T) {
+	w := &Watcher{
+		chainID:                  vaa.ChainIDPolygon,
+		ccqLogger:                zap.NewNop(),
+		ccqMaxBlockNumber:        big.NewInt(0).SetUint64(math.MaxUint64),
+		ccqFinalizedTagSupported: true,
+	}
+
+	conn := &mockBlockProbeConn{
+		block: connectors.BlockMarshaller{
+			Number: (*ethHexUtil.Big)(big.NewInt(0xb96d7a)),
+			Hash:   ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+			Time:   ethHexUtil.Uint64(1700000000),
+		},
+	}
+
+	// The finality is resolved to the hash of the block, so the calls are made against exactly that block.
+	for _, finality := range []string{"latest", "finalized"} {
+		block, status, err := w.ccqResolveFinality(context.Background(), conn, finality, false)
+		require.NoError(t, err)
+		assert.Equal(t, query.QuerySuccess, status)
+		assert.Equal(t, conn.block.Hash.Hex(), block)
+	}
+	assert.Equal(t, []string{"eth_getBlockByNumber", "eth_getBlockByNumber"}, conn.methods)
+
+	// Polygon does not support the safe tag, which is a fatal error rather than a fallback to latest.
+	conn.methods = nil
+	_, status, err := w.ccqResolveFinality(context.Background(), conn, "safe", false)
+	require.ErrorContains(t, err, "does not support the safe block tag")
+	assert.Equal(t, query.QueryFatalError, status)
+	assert.Empty(t, conn.methods)
+
+	// A block that cannot be read should be retried.
+	w.ccqMaxBlockNumber = big.NewInt(0xb96d79)
+	_, status, err = w.ccqResolveFinality(context.Background(), conn, "latest", false)
+	require.ErrorContains(t, err, "block number is too large")
+	assert.Equal(t, query.QueryRetryNeeded, status)
+
+	// If the requester prefers speed, the finality is passed to the node as is, without reading the block first. Unsupported tags are still fatal.
+	conn.methods = nil
+	block, status, err := w.ccqResolveFinality(context.Background(), conn, "finalized", true)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+	assert.Equal(t, "finalized", block)
+	assert.Empty(t, conn.methods)
+
+	_, status, err = w.ccqResolveFinality(context.Background(), conn, "safe", true)
+	require.ErrorContains(t, err, "does not support the safe block tag")
+	assert.Equal(t, query.QueryFatalError, status)
+}
+
+// mockLogsConn simulates the RPC node for an eth_logs query. It serves the specified logs that are in the requested range and the specified block,
+// and records the methods and log ranges it was asked for. If maxRange is set, it simulates a provider that fails eth_getLogs calls over longer ranges.
+type mockLogsConn struct {
+	logs     []ethTypes.Log
+	block    connectors.BlockMarshaller
+	maxRange uint64
+	methods  []string
+	ranges   [][2]uint64
+}
+
+func (conn *mockLogsConn) RawBatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	for idx, b := range batch {
+		conn.methods = append(conn.methods, b.Method)
+		var result interface{}
+		switch b.Method {
+		case "eth_getLogs":
+			filter := b.Args[0].(map[string]interface{})
+			fromBlockNum := ethHexUtil.MustDecodeUint64(filter["fromBlock"].(string))
+			toBlockNum := ethHexUtil.MustDecodeUint64(filter["toBlock"].(string))
+			conn.ranges = append(conn.ranges, [2]uint64{fromBlockNum, toBlockNum})
+			if conn.maxRange != 0 && toBlockNum-fromBlockNum+1 > conn.maxRange {
+				batch[idx].Error = fmt.Errorf("block range is too large, the maximum is %d", conn.maxRange)
+				continue
+			}
+			logs := []ethTypes.Log{}
+			for _, log := range conn.logs {
+				if log.BlockNumber >= fromBlockNum && log.BlockNumber <= toBlockNum {
+					logs = append(logs, log)
+				}
+			}
+			result = logs
+		case "eth_getBlockByNumber":
+			result = conn.block
+		default:
+			return fmt.Errorf("unexpected method: %s", b.Method)
+		}
+
+		bytes, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+
+		err = json.Unmarshal(bytes, b.Result)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+	}
+	return nil
+}
+
+func createLogsConnForTest(address ethCommon.Address, transferTopic ethCommon.Hash) *mockLogsConn {
+	blockHash := ethCommon.HexToHash("0x9999bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2")
+	return &mockLogsConn{
+		logs: []ethTypes.Log{
+			{
+				Address:     address,
+				Topics:      []ethCommon.Hash{transferTopic, ethCommon.HexToHash("0x42")},
+				Data:        []byte{0x01, 0x02},
+				BlockNumber: 0xb96d75,
+				TxHash:      ethCommon.HexToHash("0x1111bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				BlockHash:   ethCommon.HexToHash("0x8888bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				Index:       3,
+			},
+			{
+				Address:     address,
+				Topics:      []ethCommon.Hash{transferTopic},
+				Data:        []byte{},
+				BlockNumber: 0xb96d7a,
+				TxHash:      ethCommon.HexToHash("0x2222bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2"),
+				BlockHash:   blockHash,
+				Index:       0,
+			},
+		},
+		block: connectors.BlockMarshaller{
+			Number: (*ethHexUtil.Big)(big.NewInt(0xb96d7a)),
+			Hash:   blockHash,
+			Time:   ethHexUtil.Uint64(1700000000),
+		},
+	}
+}
+
+func TestCcqGetLogs(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	address := ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	transferTopic := ethCommon.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	conn := createLogsConnForTest(address, transferTopic)
+	req := &query.EthLogsQueryRequest{
+		FromBlock: "0xb96d70",
+		ToBlock:   "d:12152186",
+		Address:   address.Bytes(),
+		Topics:    [][]byte{transferTopic.Bytes()},
+	}
+
+	resp, status, err := w.ccqGetLogs(context.Background(), conn, req, false)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+	require.NoError(t, resp.Validate())
+	assert.Equal(t, []string{"eth_getLogs", "eth_getBlockByNumber"}, conn.methods)
+
+	assert.Equal(t, uint64(0xb96d70), resp.FromBlock)
+	assert.Equal(t, uint64(0xb96d7a), resp.BlockNumber)
+	assert.Equal(t, conn.block.Hash, resp.BlockHash)
+	assert.Equal(t, time.Unix(1700000000, 0), resp.BlockTime)
+	require.Len(t, resp.Logs, 2)
+	for idx, log := range conn.logs {
+		assert.Equal(t, log.BlockNumber, resp.Logs[idx].BlockNumber)
+		assert.Equal(t, log.TxHash, resp.Logs[idx].TxHash)
+		assert.Equal(t, uint32(log.Index), resp.Logs[idx].LogIndex)
+		assert.Equal(t, log.Topics, resp.Logs[idx].Topics)
+		assert.Equal(t, []byte(log.Data), resp.Logs[idx].Data)
+	}
+}
+
+func TestCcqGetLogsFailsIfRangeIsTooLarge(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	address := ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	conn := createLogsConnForTest(address, ethCommon.Hash{})
+	req := &query.EthLogsQueryRequest{
+		FromBlock: fmt.Sprintf("d:%d", 12152186-query.MaxEthLogsBlockRange),
+		ToBlock:   "d:12152186",
+		Address:   address.Bytes(),
+	}
+
+	_, status, err := w.ccqGetLogs(context.Background(), conn, req, false)
+	require.ErrorContains(t, err, "exceeds the maximum")
+	assert.Equal(t, query.QueryFatalError, status)
+	assert.Empty(t, conn.methods)
+
+	// The largest allowed range is accepted.
+	req.FromBlock = fmt.Sprintf("d:%d", 12152186-query.MaxEthLogsBlockRange+1)
+	_, status, err = w.ccqGetLogs(context.Background(), conn, req, false)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+}
+
+func TestCcqGetLogsRetriesIfLogsDoNotMatchBlock(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	address := ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	conn := createLogsConnForTest(address, ethCommon.Hash{})
+	conn.logs[1].BlockHash = ethCommon.HexToHash("0x7777bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2")
+	req := &query.EthLogsQueryRequest{
+		FromBlock: "0xb96d70",
+		ToBlock:   "0xb96d7a",
+		Address:   address.Bytes(),
+	}
+
+	_, status, err := w.ccqGetLogs(context.Background(), conn, req, false)
+	require.ErrorContains(t, err, "does not match the to block")
+	assert.Equal(t, query.QueryRetryNeeded, status)
+}
+
+func TestCcqGetLogsDoesNotRetryIfLogsDoNotMatchBlockWhenPreferringSpeed(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	address := ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	conn := createLogsConnForTest(address, ethCommon.Hash{})
+	conn.logs[0].Removed = true
+	conn.logs[1].BlockHash = ethCommon.HexToHash("0x7777bac44d09a7f69ee7941819b0a19c59ccb1969640cc513be09ef95ed2d8e2")
+	req := &query.EthLogsQueryRequest{
+		FromBlock: "0xb96d70",
+		ToBlock:   "0xb96d7a",
+		Address:   address.Bytes(),
+	}
+
+	// The logs may have been reorged out between the two reads, but the requester accepts that rather than waiting for a retry.
+	resp, status, err := w.ccqGetLogs(context.Background(), conn, req, true)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+	assert.Equal(t, conn.block.Hash, resp.BlockHash)
+	assert.Len(t, resp.Logs, 2)
+	assert.Equal(t, []string{"eth_getLogs", "eth_getBlockByNumber"}, conn.methods)
+}
+
+func TestCcqGetLogsWidensAnEmptyRange(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	address := ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	conn := createLogsConnForTest(address, ethCommon.Hash{})
+	conn.block.Number = (*ethHexUtil.Big)(big.NewInt(0xb96d79))
+	req := &query.EthLogsQueryRequest{
+		FromBlock:      "0xb96d76",
+		ToBlock:        "0xb96d79",
+		Address:        address.Bytes(),
+		MaxWidenedSpan: 16,
+	}
+
+	// Neither log is in the requested range, but the first one is in the widened range.
+	resp, status, err := w.ccqGetLogs(context.Background(), conn, req, false)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+	require.NoError(t, resp.Validate())
+	assert.Equal(t, [][2]uint64{{0xb96d76, 0xb96d79}, {0xb96d6a, 0xb96d79}}, conn.ranges)
+
+	assert.Equal(t, uint64(0xb96d6a), resp.FromBlock)
+	assert.Equal(t, uint64(0xb96d79), resp.BlockNumber)
+	require.Len(t, resp.Logs, 1)
+	assert.Equal(t, conn.logs[0].TxHash, resp.Logs[0].TxHash)
+
+	// Without the option, the empty range is returned as is.
+	conn.ranges = nil
+	req.MaxWidenedSpan = 0
+	resp, status, err = w.ccqGetLogs(context.Background(), conn, req, false)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+	assert.Equal(t, [][2]uint64{{0xb96d76, 0xb96d79}}, conn.ranges)
+	assert.Equal(t, uint64(0xb96d76), resp.FromBlock)
+	assert.Empty(t, resp.Logs)
+}
+
+func TestCcqGetLogsEnforcesMinResults(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	address := ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	conn := createLogsConnForTest(address, ethCommon.Hash{})
+	req := &query.EthLogsQueryRequest{
+		FromBlock:  "0xb96d70",
+		ToBlock:    "0xb96d7a",
+		Address:    address.Bytes(),
+		MinResults: 3,
+	}
+
+	// The node only returns two logs, so it may be behind, and the query should be retried.
+	resp, status, err := w.ccqGetLogs(context.Background(), conn, req, false)
+	require.ErrorContains(t, err, "found 2 logs, fewer than the minimum of 3")
+	assert.Equal(t, query.QueryRetryNeeded, status)
+	assert.Nil(t, resp)
+
+	// The requester may ask for the query to be failed instead.
+	req.FailBelowMinResults = true
+	_, status, err = w.ccqGetLogs(context.Background(), conn, req, false)
+	require.ErrorContains(t, err, "found 2 logs, fewer than the minimum of 3")
+	assert.Equal(t, query.QueryFatalError, status)
+
+	// Enough logs are found.
+	req.MinResults = 2
+	resp, status, err = w.ccqGetLogs(context.Background(), conn, req, false)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+	assert.Len(t, resp.Logs, 2)
+}
+
+func TestCcqGetLogsPaginatesForACappedProvider(t *testing.T) {
+	w := &Watcher{
+		ccqLogger:         zap.NewNop(),
+		ccqMaxBlockNumber: big.NewInt(0).SetUint64(math.MaxUint64),
+	}
+
+	address := ethCommon.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599")
+	conn := createLogsConnForTest(address, ethCommon.Hash{})
+	conn.maxRange = 4
+	conn.logs = nil
+	for blockNum := uint64(0xb96d70); blockNum < 0xb96d7a; blockNum++ {
+		for logIdx := uint(0); logIdx < 2; logIdx++ {
+			conn.logs = append(conn.logs, ethTypes.Log{
+				Address:     address,
+				Topics:      []ethCommon.Hash{},
+				Data:        []byte{byte(blockNum), byte(logIdx)},
+				BlockNumber: blockNum,
+				TxHash:      ethCommon.BigToHash(big.NewInt(int64(blockNum))),
+				Index:       logIdx,
+			})
+		}
+	}
+	req := &query.EthLogsQueryRequest{
+		FromBlock: "0xb96d70",
+		ToBlock:   "0xb96d7a",
+		Address:   address.Bytes(),
+	}
+
+	// Without pagination, the provider rejects the range.
+	_, status, err := w.ccqGetLogs(context.Background(), conn, req, false)
+	require.ErrorContains(t, err, "block range is too large")
+	assert.Equal(t, query.QueryRetryNeeded, status)
+
+	// With pagination, the range is read in sub-ranges that the provider accepts, in a single batch.
+	w.ccqLogsPageSize = 4
+	conn.methods = nil
+	conn.ranges = nil
+	resp, status, err := w.ccqGetLogs(context.Background(), conn, req, false)
+	require.NoError(t, err)
+	assert.Equal(t, query.QuerySuccess, status)
+	require.NoError(t, resp.Validate())
+	assert.Equal(t, []string{"eth_getLogs", "eth_getLogs", "eth_getLogs", "eth_getBlockByNumber"}, conn.methods)
+	assert.Equal(t, [][2]uint64{{0xb96d70, 0xb96d73}, {0xb96d74, 0xb96d77}, {0xb96d78, 0xb96d7a}}, conn.ranges)
+
+	// The results should be complete and in order, as if they had been read with a single call.
+	assert.Equal(t, uint64(0xb96d70), resp.FromBlock)
+	assert.Equal(t, uint64(0xb96d7a), resp.BlockNumber)
+	require.Len(t, resp.Logs, len(conn.logs))
+	for idx, log := range conn.logs {
+		assert.Equal(t, log.BlockNumber, resp.Logs[idx].BlockNumber)
+		assert.Equal(t, uint32(log.Index), resp.Logs[idx].LogIndex)
+		assert.Equal(t, []byte(log.Data), resp.Logs[idx].Data)
+	}
+}
+
+// mockRevertError is the error returned by the node for an eth_call that reverts.
+type mockRevertError struct {
+	data string
+}
+
+func (e *mockRevertError) Error() string          { return "execution reverted" }
+func (e *mockRevertError) ErrorCode() int         { return ccqRevertErrorCode }
+func (e *mockRevertError) ErrorData() interface{} { return e.data }
+
+type mockRevertingCallConn struct {
+	results map[string][]byte
+	reverts map[string]string
+}
+
+func (conn *mockRevertingCallConn) RawBatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	for idx := range b {
+		// The gas estimates are left at zero.
+		if b[idx].Method != ccqStaticCallMethod {
+			continue
+		}
+
+		data := b[idx].Args[0].(map[string]interface{})["data"].(string)
+		if revertData, exists := conn.reverts[data]; exists {
+			b[idx].Error = &mockRevertError{data: revertData}
+			continue
+		}
+		*b[idx].Result.(*ethHexUtil.Bytes) = conn.results[data]
+	}
+	return nil
+}
+
+func TestCcqAllowRevertedCallsFlagsRevertedCall(t *testing.T) {
+	w := &Watcher{ccqLogger: zap.NewNop()}
+	req := &query.EthCallQueryRequest{
+		BlockId: "0xb96d7a",
+		CallData: []*query.EthCallData{
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x01}},
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x02}},
+			{To: make([]byte, query.EvmContractAddressLength), Data: []byte{0x03}},
+		},
+	}
+	conn := &mockRevertingCallConn{
+		results: map[string][]byte{
+			"0x01": []byte("result 1"),
+			"0x03": []byte("result 3"),
+		},
+		reverts: map[string]string{"0x02": "0x08c379a0"},
+	}
+
+	_, callBlockArg, err := ccqCreateBlockRequest(req.BlockId)
+	require.NoError(t, err)
+	batch, evmCallData := ccqBuildBatchFromCallData(req, callBlockArg)
+	require.NoError(t, w.ccqBatchCall(context.Background(), conn, batch))
+	ccqSetCallErrors(batch, evmCallData)
+
+	results, statuses, err := w.ccqVerifyAndExtractQueryResultsAllowingReverts("test", evmCallData)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("result 1"), {0x08, 0xc3, 0x79, 0xa0}, []byte("result 3")}, results)
+	assert.Equal(t, []query.EthCallResultStatus{query.EthCallResultExecuted, query.EthCallReverted, query.EthCallResultExecuted}, statuses)
+
+	// Unless the request allows it, the reverted call fails the whole query.
+	_, err = w.ccqVerifyAndExtractQueryResults("test", evmCallData)
+	assert.EqualError(t, err, "call 1 failed: execution reverted")
+
+	// Errors other than reverts still fail the query.
+	evmCallData[1].callErr = fmt.Errorf("connection reset")
+	_, _, err = w.ccqVerifyAndExtractQueryResultsAllowingReverts("test", evmCallData)
+	assert.EqualError(t, err, "call 1 failed: connection reset")
+}
@@ -0,0 +1,191 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/certusone/wormhole/node/pkg/query"
+
+	eth_common "github.com/ethereum/go-ethereum/common"
+	eth_hexutil "github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"go.uber.org/zap"
+)
+
+const (
+	ccqGetTransactionMethod    = "eth_getTransactionByHash"
+	ccqGetRawTransactionMethod = "eth_getRawTransactionByBlockHashAndIndex"
+)
+
+type (
+	// ccqTxLocation is the subset of the eth_getTransactionByHash result needed to locate the transaction. The block fields are nil if it is still pending.
+	ccqTxLocation struct {
+		BlockHash        *eth_common.Hash    `json:"blockHash"`
+		BlockNumber      *eth_hexutil.Big    `json:"blockNumber"`
+		TransactionIndex *eth_hexutil.Uint64 `json:"transactionIndex"`
+	}
+
+	// ccqTxProofBlock is the subset of the eth_getBlockByHash result needed to build the proof, including the hashes of all of the transactions.
+	ccqTxProofBlock struct {
+		Number           *eth_hexutil.Big   `json:"number"`
+		Hash             eth_common.Hash    `json:"hash"`
+		Time             eth_hexutil.Uint64 `json:"timestamp"`
+		TransactionsRoot eth_common.Hash    `json:"transactionsRoot"`
+		Transactions     []eth_common.Hash  `json:"transactions"`
+	}
+
+	// ccqProofList collects the trie nodes written by trie.Prove, in the order they are written, which is from the root to the leaf.
+	ccqProofList [][]byte
+)
+
+func (p *ccqProofList) Put(key []byte, value []byte) error {
+	*p = append(*p, value)
+	return nil
+}
+
+func (p *ccqProofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// ccqHandleEthTxProofQueryRequest is the query handler for an eth_tx_proof request.
+func (w *Watcher) ccqHandleEthTxProofQueryRequest(ctx context.Context, queryRequest *query.PerChainQueryInternal, req *query.EthTxProofQueryRequest) {
+	requestId := "eth_tx_proof:" + queryRequest.ID()
+	w.ccqLogger.Info("received eth_tx_proof query request",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("txHash", req.TxHash.Hex()),
+	)
+
+	start := time.Now()
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, status, err := w.ccqBuildTxProof(timeout, w.ethConn, req.TxHash)
+	if err != nil {
+		w.ccqLogger.Error("failed to process eth_tx_proof query request",
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+			zap.String("txHash", req.TxHash.Hex()),
+			zap.Int("status", int(status)),
+			zap.Error(err),
+		)
+		w.ccqSendQueryFailure(queryRequest, status, err)
+		return
+	}
+
+	w.ccqLogger.Info("query complete for eth_tx_proof",
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
+		zap.String("txHash", req.TxHash.Hex()),
+		zap.Uint64("blockNumber", resp.BlockNumber),
+		zap.String("blockHash", resp.BlockHash.Hex()),
+		zap.String("blockTime", resp.BlockTime.String()),
+		zap.Uint32("txIndex", resp.TxIndex),
+		zap.Int("numProofNodes", len(resp.Proof)),
+		zap.Int64("duration", time.Since(start).Milliseconds()),
+	)
+
+	w.ccqSendQueryResponse(queryRequest, query.QuerySuccess, resp)
+}
+
+// ccqBuildTxProof looks up the block containing the transaction, rebuilds the transactions trie of that block from the raw transactions, verifies
+// that it matches the root in the block header, and returns a proof of the transaction. On error, it returns the status that should be sent back
+// to the query handler. Chains whose transactions trie is not built the standard way fail with a fatal error, since retrying would not help.
+func (w *Watcher) ccqBuildTxProof(ctx context.Context, conn ccqBatchConn, txHash eth_common.Hash) (*query.EthTxProofQueryResponse, query.QueryStatus, error) {
+	// Locate the transaction.
+	var txLocation *ccqTxLocation
+	batch := []rpc.BatchElem{{Method: ccqGetTransactionMethod, Args: []interface{}{txHash}, Result: &txLocation}}
+	if err := ccqExecuteReadOnlyBatch(ctx, conn, batch); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("failed to look up transaction: %w", err)
+	}
+	if txLocation == nil || txLocation.BlockHash == nil || txLocation.TransactionIndex == nil {
+		// The transaction may still be pending, or this node may not have seen it yet.
+		return nil, query.QueryRetryNeeded, fmt.Errorf("transaction is not in a block")
+	}
+	txIndex := uint64(*txLocation.TransactionIndex)
+
+	// Get the block header and the hashes of all of the transactions in it.
+	var block ccqTxProofBlock
+	batch = []rpc.BatchElem{{Method: "eth_getBlockByHash", Args: []interface{}{*txLocation.BlockHash, false}, Result: &block}}
+	if err := ccqExecuteReadOnlyBatch(ctx, conn, batch); err != nil {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("failed to look up block: %w", err)
+	}
+	if block.Number == nil || block.Hash != *txLocation.BlockHash {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("block %s not found", txLocation.BlockHash.Hex())
+	}
+	if block.Number.ToInt().Cmp(w.ccqMaxBlockNumber) > 0 {
+		return nil, query.QueryRetryNeeded, fmt.Errorf("block number is too large")
+	}
+	if txIndex >= uint64(len(block.Transactions)) || block.Transactions[txIndex] != txHash {
+		// The block may have been reorged out since the transaction was looked up.
+		return nil, query.QueryRetryNeeded, fmt.Errorf("transaction is not at index %d of block %s", txIndex, block.Hash.Hex())
+	}
+
+	// Get the raw transactions, in batches of a size the RPC node should accept.
+	rawTxs := make([]*eth_hexutil.Bytes, len(block.Transactions))
+	for batchStart := 0; batchStart < len(rawTxs); batchStart += int(CCQ_MAX_BATCH_SIZE) {
+		batch = []rpc.BatchElem{}
+		for idx := batchStart; idx < len(rawTxs) && idx < batchStart+int(CCQ_MAX_BATCH_SIZE); idx++ {
+			batch = append(batch, rpc.BatchElem{
+				Method: ccqGetRawTransactionMethod,
+				Args:   []interface{}{block.Hash, eth_hexutil.Uint64(idx)},
+				Result: &rawTxs[idx],
+			})
+		}
+		if err := ccqExecuteReadOnlyBatch(ctx, conn, batch); err != nil {
+			return nil, query.QueryRetryNeeded, fmt.Errorf("failed to get raw transactions: %w", err)
+		}
+	}
+
+	// Rebuild the transactions trie. The key of each entry is the RLP encoding of its index.
+	txTrie := trie.NewEmpty(trie.NewDatabase(memorydb.New()))
+	for idx, rawTx := range rawTxs {
+		if rawTx == nil {
+			return nil, query.QueryRetryNeeded, fmt.Errorf("raw transaction %d is missing", idx)
+		}
+		txTrie.Update(rlp.AppendUint64(nil, uint64(idx)), *rawTx)
+	}
+	if txTrie.Hash() != block.TransactionsRoot {
+		return nil, query.QueryFatalError, fmt.Errorf("transactions root does not match the block header, computed %s, expected %s", txTrie.Hash().Hex(), block.TransactionsRoot.Hex())
+	}
+
+	proof := ccqProofList{}
+	if err := txTrie.Prove(rlp.AppendUint64(nil, txIndex), 0, &proof); err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("failed to build proof: %w", err)
+	}
+
+	resp := &query.EthTxProofQueryResponse{
+		BlockNumber:      block.Number.ToInt().Uint64(),
+		BlockHash:        block.Hash,
+		BlockTime:        time.Unix(int64(block.Time), 0),
+		TransactionsRoot: block.TransactionsRoot,
+		TxIndex:          uint32(txIndex),
+		Proof:            proof,
+	}
+
+	// This should never fail, but make sure before it gets signed.
+	if _, err := resp.Verify(txHash); err != nil {
+		return nil, query.QueryFatalError, fmt.Errorf("generated proof does not verify: %w", err)
+	}
+
+	return resp, query.QuerySuccess, nil
+}
+
+// ccqExecuteReadOnlyBatch verifies that the batch is read-only, submits it and returns the first error, including the per entry errors.
+func ccqExecuteReadOnlyBatch(ctx context.Context, conn ccqBatchConn, batch []rpc.BatchElem) error {
+	if err := ccqVerifyReadOnlyBatch(batch); err != nil {
+		return fmt.Errorf("batch is not read-only: %w", err)
+	}
+
+	if err := conn.RawBatchCallContext(ctx, batch); err != nil {
+		return err
+	}
+
+	for idx := range batch {
+		if batch[idx].Error != nil {
+			return fmt.Errorf("%s failed: %w", batch[idx].Method, batch[idx].Error)
+		}
+	}
+
+	return nil
+}
@@ -22,6 +22,8 @@ type WatcherConfig struct {
 	L1FinalizerRequired    watchers.NetworkID // (optional)
 	l1Finalizer            interfaces.L1Finalizer
 	CcqBackfillCache       bool
+	CcqCallConcurrency     int    // (optional) maximum number of sub-batches the calls of a CCQ query are split into and executed concurrently
+	CcqLogsPageSize        uint64 // (optional) maximum number of blocks read by a single eth_getLogs call of a CCQ eth_logs query
 
 	// These parameters are currently only used for Linea and should be set via SetLineaParams()
 	LineaRollUpUrl      string
@@ -61,7 +63,7 @@ func (wc *WatcherConfig) Create(
 
 	var devMode bool = (env == common.UnsafeDevNet)
 
-	watcher := NewEthWatcher(wc.Rpc, eth_common.HexToAddress(wc.Contract), string(wc.NetworkID), wc.ChainID, msgC, setWriteC, obsvReqC, queryReqC, queryResponseC, devMode, wc.CcqBackfillCache)
+	watcher := NewEthWatcher(wc.Rpc, eth_common.HexToAddress(wc.Contract), string(wc.NetworkID), wc.ChainID, msgC, setWriteC, obsvReqC, queryReqC, queryResponseC, devMode, wc.CcqBackfillCache, wc.CcqCallConcurrency, wc.CcqLogsPageSize)
 	watcher.SetL1Finalizer(wc.l1Finalizer)
 	if wc.ChainID == vaa.ChainIDLinea {
 		if err := watcher.SetLineaParams(wc.LineaRollUpUrl, wc.LineaRollUpContract); err != nil {
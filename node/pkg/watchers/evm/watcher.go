@@ -136,8 +136,19 @@ type (
 		ccqBackfillChannel chan *ccqBackfillRequest
 		ccqBatchSize       int64
 		ccqBackfillCache   bool
+		ccqCallConcurrency int
+		ccqLogsPageSize    uint64
 		ccqLogger          *zap.Logger
 
+		// ccqSyncMutex protects the cached sync status of the node, see ccqNodeSyncing.
+		ccqSyncMutex     sync.Mutex
+		ccqSyncCheckTime time.Time
+		ccqSyncing       bool
+
+		// ccqFinalizedTagSupported and ccqSafeTagSupported are set if the node supports the finalized and safe block tags, see getFinality.
+		ccqFinalizedTagSupported bool
+		ccqSafeTagSupported      bool
+
 		// These parameters are currently only used for Linea and should be set via SetLineaParams()
 		lineaRollUpUrl      string
 		lineaRollUpContract string
@@ -171,6 +182,8 @@ func NewEthWatcher(
 	queryResponseC chan<- *query.PerChainQueryResponseInternal,
 	unsafeDevMode bool,
 	ccqBackfillCache bool,
+	ccqCallConcurrency int,
+	ccqLogsPageSize uint64,
 ) *Watcher {
 	return &Watcher{
 		url:                url,
@@ -188,6 +201,8 @@ func NewEthWatcher(
 		ccqConfig:          query.GetPerChainConfig(chainID),
 		ccqMaxBlockNumber:  big.NewInt(0).SetUint64(math.MaxUint64),
 		ccqBackfillCache:   ccqBackfillCache,
+		ccqCallConcurrency: ccqCallConcurrency,
+		ccqLogsPageSize:    ccqLogsPageSize,
 		ccqBackfillChannel: make(chan *ccqBackfillRequest, 50),
 	}
 }
@@ -224,6 +239,7 @@ func (w *Watcher) Run(parentCtx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to determine finality: %w", err)
 	}
+	w.ccqFinalizedTagSupported, w.ccqSafeTagSupported = finalizedPollingSupported, safePollingSupported
 
 	if finalizedPollingSupported {
 		if safePollingSupported {
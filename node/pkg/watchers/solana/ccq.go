@@ -36,6 +36,10 @@ func (w *SolanaWatcher) ccqStart(ctx context.Context) {
 
 // ccqSendQueryResponse sends a response back to the query handler.
 func (w *SolanaWatcher) ccqSendQueryResponse(queryResponse *query.PerChainQueryResponseInternal) {
+	queryResponse.Metadata = &query.PerChainResponseMetadata{RpcNode: query.RpcNodeLabel(w.rpcUrl)}
+	if queryResponse.Status == query.QuerySuccess && queryResponse.Response != nil {
+		queryResponse.Metadata.SetChainHeadLag(queryResponse.Response, w.GetLatestFinalizedBlockNumber(), time.Now())
+	}
 	select {
 	case w.queryResponseC <- queryResponse:
 		w.ccqLogger.Debug("published query response to handler")
@@ -129,7 +133,7 @@ func (w *SolanaWatcher) ccqBaseHandleSolanaAccountQueryRequest(
 			return
 		}
 		w.ccqLogger.Error(fmt.Sprintf("read failed for %s query request", tag),
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Any("accounts", accounts),
 			zap.Any("params", params),
 			zap.Error(err),
@@ -149,7 +153,7 @@ func (w *SolanaWatcher) ccqBaseHandleSolanaAccountQueryRequest(
 	})
 	if err != nil {
 		w.ccqLogger.Error(fmt.Sprintf("failed to read block time for %s query request", tag),
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Uint64("slotNumber", info.Context.Slot),
 			zap.Error(err),
 		)
@@ -159,20 +163,20 @@ func (w *SolanaWatcher) ccqBaseHandleSolanaAccountQueryRequest(
 	}
 
 	if info == nil {
-		w.ccqLogger.Error(fmt.Sprintf("read for %s query request returned nil info", tag), zap.String("requestId", requestId))
+		w.ccqLogger.Error(fmt.Sprintf("read for %s query request returned nil info", tag), zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID))
 		w.ccqSendErrorResponse(queryRequest, query.QueryFatalError)
 		return
 	}
 
 	if info.Value == nil {
-		w.ccqLogger.Error(fmt.Sprintf("read for %s query request returned nil value", tag), zap.String("requestId", requestId))
+		w.ccqLogger.Error(fmt.Sprintf("read for %s query request returned nil value", tag), zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID))
 		w.ccqSendErrorResponse(queryRequest, query.QueryFatalError)
 		return
 	}
 
 	if len(info.Value) != len(req.Accounts) {
 		w.ccqLogger.Error(fmt.Sprintf("read for %s query request returned unexpected number of results", tag),
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Int("numAccounts", len(req.Accounts)),
 			zap.Int("numValues", len(info.Value)),
 		)
@@ -184,13 +188,13 @@ func (w *SolanaWatcher) ccqBaseHandleSolanaAccountQueryRequest(
 	// Extract the results.
 	results := make([]query.SolanaAccountResult, 0, len(req.Accounts))
 	for idx, val := range info.Value {
-		if val == nil { // This can happen for an invalid account.
-			w.ccqLogger.Error(fmt.Sprintf("read of account for %s query request failed, val is nil", tag), zap.String("requestId", requestId), zap.Any("account", req.Accounts[idx]))
-			w.ccqSendErrorResponse(queryRequest, query.QueryFatalError)
+		if val == nil { // This happens for an account that does not exist.
+			w.ccqLogger.Error(fmt.Sprintf("read of account for %s query request failed, account does not exist", tag), zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID), zap.Any("account", req.Accounts[idx]))
+			w.ccqSendErrorResponse(queryRequest, query.QueryNotFound)
 			return
 		}
 		if val.Data == nil {
-			w.ccqLogger.Error(fmt.Sprintf("read of account for %s query request failed, data is nil", tag), zap.String("requestId", requestId), zap.Any("account", req.Accounts[idx]))
+			w.ccqLogger.Error(fmt.Sprintf("read of account for %s query request failed, data is nil", tag), zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID), zap.Any("account", req.Accounts[idx]))
 			w.ccqSendErrorResponse(queryRequest, query.QueryFatalError)
 			return
 		}
@@ -212,7 +216,7 @@ func (w *SolanaWatcher) ccqBaseHandleSolanaAccountQueryRequest(
 	}
 
 	w.ccqLogger.Info(fmt.Sprintf("account read for %s query succeeded", tag),
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 		zap.Uint64("slotNumber", info.Context.Slot),
 		zap.Uint64("blockTime", uint64(*block.BlockTime)),
 		zap.String("blockHash", hex.EncodeToString(block.Blockhash[:])),
@@ -245,7 +249,7 @@ func (w *SolanaWatcher) ccqCheckForMinSlotContext(
 	}
 
 	if time.Now().After(giveUpTime) {
-		w.ccqLogger.Info("giving up on fast retry", zap.String("requestId", requestId))
+		w.ccqLogger.Info("giving up on fast retry", zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID))
 		return false
 	}
 
@@ -267,7 +271,7 @@ func (w *SolanaWatcher) ccqCheckForMinSlotContext(
 	// If the requested slot is definitively more than the retry interval, use the regular retry mechanism.
 	if futureSlotEstimate > query.RetryInterval*2 {
 		w.ccqLogger.Info("minimum context slot is too far in the future, requesting slow retry",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Uint64("currentSlot", currentSlot),
 			zap.Uint64("currentSlotFromError", currentSlotFromError),
 			zap.Uint64("minContextSlot", req.MinContextSlot),
@@ -297,7 +301,7 @@ func (w *SolanaWatcher) ccqSleepAndRetryAccountQuery(
 ) {
 	if log {
 		w.ccqLogger.Info("minimum context slot has not been reached, will retry shortly",
-			zap.String("requestId", requestId),
+			zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 			zap.Uint64("currentSlot", currentSlot),
 			zap.Uint64("currentSlotFromError", currentSlotFromError),
 			zap.Uint64("minContextSlot", req.MinContextSlot),
@@ -308,7 +312,7 @@ func (w *SolanaWatcher) ccqSleepAndRetryAccountQuery(
 	time.Sleep(CCQ_FAST_RETRY_INTERVAL)
 
 	if log {
-		w.ccqLogger.Info("initiating fast retry", zap.String("requestId", requestId))
+		w.ccqLogger.Info("initiating fast retry", zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID))
 	}
 
 	w.ccqBaseHandleSolanaAccountQueryRequest(ctx, queryRequest, req, giveUpTime, tag, requestId, true, publisher, numFastRetries+1)
@@ -365,7 +369,7 @@ func (w *SolanaWatcher) ccqHandleSolanaAccountQueryRequest(ctx context.Context,
 		zap.Uint64("dataSliceOffset", req.DataSliceOffset),
 		zap.Uint64("dataSliceLength", req.DataSliceLength),
 		zap.Int("numAccounts", len(req.Accounts)),
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 	)
 
 	publisher := ccqSolanaAccountPublisher{w}
@@ -389,7 +393,7 @@ func (w *SolanaWatcher) ccqHandleSolanaPdaQueryRequest(ctx context.Context, quer
 		zap.Uint64("dataSliceOffset", req.DataSliceOffset),
 		zap.Uint64("dataSliceLength", req.DataSliceLength),
 		zap.Int("numPdas", len(req.PDAs)),
-		zap.String("requestId", requestId),
+		zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 	)
 
 	// Derive the list of accounts from the PDAs and save those along with the bumps.
@@ -399,7 +403,7 @@ func (w *SolanaWatcher) ccqHandleSolanaPdaQueryRequest(ctx context.Context, quer
 		account, bump, err := solana.FindProgramAddress(pda.Seeds, pda.ProgramAddress)
 		if err != nil {
 			w.ccqLogger.Error("failed to derive account from pda for sol_pda query",
-				zap.String("requestId", requestId),
+				zap.String("requestId", requestId), zap.String("requestId", queryRequest.CorrelationID),
 				zap.String("programAddress", hex.EncodeToString(pda.ProgramAddress[:])),
 				zap.Any("seeds", pda.Seeds),
 				zap.Error(err),
@@ -445,26 +449,26 @@ type ccqPdaPublisher struct {
 
 func (pub ccqPdaPublisher) publish(pcrResp *query.PerChainQueryResponseInternal, acctResp *query.SolanaAccountQueryResponse) {
 	if pcrResp == nil {
-		pub.w.ccqLogger.Error("sol_pda query failed, pcrResp is nil", zap.String("requestId", pub.requestId))
+		pub.w.ccqLogger.Error("sol_pda query failed, pcrResp is nil", zap.String("requestId", pub.requestId), zap.String("requestId", pub.queryRequest.CorrelationID))
 		pub.w.ccqSendErrorResponse(pub.queryRequest, query.QueryFatalError)
 		return
 	}
 
 	if pcrResp.Status != query.QuerySuccess {
 		// publish() should only get called in success cases.
-		pub.w.ccqLogger.Error("received an unexpected query response for sol_pda query", zap.String("requestId", pub.requestId), zap.Any("pcrResp", pcrResp))
+		pub.w.ccqLogger.Error("received an unexpected query response for sol_pda query", zap.String("requestId", pub.requestId), zap.String("requestId", pub.queryRequest.CorrelationID), zap.Any("pcrResp", pcrResp))
 		pub.w.ccqSendErrorResponse(pub.queryRequest, query.QueryFatalError)
 		return
 	}
 
 	if acctResp == nil {
-		pub.w.ccqLogger.Error("sol_pda query failed, acctResp is nil", zap.String("requestId", pub.requestId))
+		pub.w.ccqLogger.Error("sol_pda query failed, acctResp is nil", zap.String("requestId", pub.requestId), zap.String("requestId", pub.queryRequest.CorrelationID))
 		pub.w.ccqSendErrorResponse(pub.queryRequest, query.QueryFatalError)
 		return
 	}
 
 	if len(acctResp.Results) != len(pub.accounts) {
-		pub.w.ccqLogger.Error("sol_pda query failed, unexpected number of results", zap.String("requestId", pub.requestId), zap.Int("numResults", len(acctResp.Results)), zap.Int("expectedResults", len(pub.accounts)))
+		pub.w.ccqLogger.Error("sol_pda query failed, unexpected number of results", zap.String("requestId", pub.requestId), zap.String("requestId", pub.queryRequest.CorrelationID), zap.Int("numResults", len(acctResp.Results)), zap.Int("expectedResults", len(pub.accounts)))
 		pub.w.ccqSendErrorResponse(pub.queryRequest, query.QueryFatalError)
 		return
 	}